@@ -10,32 +10,58 @@ import (
 	"time"
 
 	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/egress"
+	"github.com/coderunr/api/internal/grpc"
 	"github.com/coderunr/api/internal/handler"
 	"github.com/coderunr/api/internal/job"
 	"github.com/coderunr/api/internal/middleware"
 	"github.com/coderunr/api/internal/runtime"
 	"github.com/coderunr/api/internal/service"
+	"github.com/coderunr/api/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	// The job manager starts isolate through the server binary to harden its process first
+	if len(os.Args) > 1 && os.Args[1] == job.HardenCommand {
+		err := job.RunHardened(os.Args[2:])
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to load configuration")
 	}
 
-	// Set up logging
-	logger := logrus.New()
+	// Set up logging on the standard logger so package-level component loggers share the format
+	logger := logrus.StandardLogger()
 	logger.SetLevel(cfg.GetLogLevel())
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
+	logger.SetFormatter(cfg.GetLogFormatter())
 
 	logger.Info("Starting CodeRunr API Server")
 
+	// Tracing is set up first so every component's spans are exported
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to set up tracing")
+	}
+
+	// Fail fast if isolate or the kernel features it needs are missing
+	if cfg.SandboxBackend == "isolate" {
+		isolate, err := job.DetectIsolate(cfg.IsolatePath)
+		if err != nil {
+			logger.WithError(err).Fatal("Sandbox is unavailable")
+		}
+		logger.Infof("Using %s (%s, cgroup %s)", isolate.Path, isolate.Version, isolate.CgroupMode)
+	} else {
+		logger.Warnf("Using the %s sandbox backend: submitted programs run unisolated as this user, "+
+			"never expose this server", cfg.SandboxBackend)
+	}
+
 	// Ensure data directories exist
 	if err := ensureDataDirectories(cfg); err != nil {
 		logger.WithError(err).Fatal("Failed to create data directories")
@@ -46,28 +72,45 @@ func main() {
 	if err := runtimeManager.LoadPackages(); err != nil {
 		logger.WithError(err).Fatal("Failed to load packages")
 	}
+	if cfg.RuntimeWatchEnabled {
+		if err := runtimeManager.Watch(); err != nil {
+			logger.WithError(err).Warn("Failed to watch packages directory, hot-reload disabled")
+		}
+	}
 
 	// Initialize job manager
-	jobManager := job.NewManager(cfg)
+	jobManager := job.NewManager(cfg, runtimeManager)
 
 	// Initialize package service
 	packageService := service.NewPackageService(cfg, logger, runtimeManager)
 
+	// Initialize API key store
+	apiKeys, err := middleware.NewAPIKeyStore(cfg)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load API keys")
+	}
+
+	// Initialize rate limiter shared by the execute and connect routes
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimitRequestsPerMinute, cfg.RateLimitBurst)
+
 	// Initialize handlers
-	h := handler.NewHandler(jobManager, runtimeManager, logger)
+	h := handler.NewHandler(cfg, jobManager, runtimeManager, apiKeys, logger)
 	packageHandler := handler.NewPackageHandler(packageService, logger)
+	adminHandler := handler.NewAdminHandler(cfg, jobManager, runtimeManager, logger)
 
 	// Set up router
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(chiMiddleware.RequestID)
+	r.Use(middleware.Tracing)
 	r.Use(chiMiddleware.RealIP)
 	r.Use(middleware.Logger(logger))
 	r.Use(middleware.Recovery(logger))
 	r.Use(middleware.CORS())
-	// Limit POST/DELETE body size
-	r.Use(middleware.BodyLimit(cfg.RequestBodyLimit))
+	if cfg.CompressionLevel > 0 {
+		r.Use(chiMiddleware.Compress(cfg.CompressionLevel, "application/json"))
+	}
 
 	// API routes
 	r.Route("/api/v2", func(r chi.Router) {
@@ -76,32 +119,86 @@ func main() {
 			r.Use(middleware.JSON)
 			// Short timeout group (execute)
 			r.Group(func(r chi.Router) {
+				r.Use(bodyLimits(cfg.ExecuteBodyLimit, cfg.DecompressedBodyLimit)...)
 				r.Use(chiMiddleware.Timeout(60 * time.Second))
-				r.Post("/execute", h.ExecuteCode)
+				r.Use(middleware.Auth(apiKeys))
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.RateLimit(rateLimiter, apiKeys))
+					r.Post("/execute", h.ExecuteCode)
+					r.Get("/execute/stream", h.ExecuteStream)
+					r.Post("/execute/stream", h.ExecuteStream)
+					r.Post("/jobs", h.SubmitJob)
+					r.Post("/format", h.FormatCode)
+					r.Post("/lint", h.LintCode)
+				})
+				r.Get("/jobs/{id}", h.GetJob)
+				r.Delete("/jobs/{id}", h.CancelJob)
+				r.Get("/usage", h.GetUsage)
 			})
 			// Long timeout group (packages install/uninstall/list)
 			r.Group(func(r chi.Router) {
+				r.Use(bodyLimits(cfg.PackagesBodyLimit, cfg.DecompressedBodyLimit)...)
 				r.Use(chiMiddleware.Timeout(10 * time.Minute))
 				packageHandler.RegisterRoutes(r)
 			})
+			// Admin routes, only served when an admin token is configured
+			if cfg.AdminToken != "" {
+				r.Group(func(r chi.Router) {
+					r.Use(bodyLimits(cfg.RequestBodyLimit, cfg.DecompressedBodyLimit)...)
+					r.Use(middleware.AdminAuth(cfg.AdminToken))
+					adminHandler.RegisterRoutes(r)
+				})
+			}
 		})
 
-		// WebSocket route (no JSON middleware)
-		r.HandleFunc("/connect", h.HandleWebSocket)
+		// Package uploads are multipart and admin-only. Their bodies are not inflated: the
+		// tarball is already compressed.
+		if cfg.AdminToken != "" {
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.BodyLimit(cfg.PackageUploadLimit))
+				r.Use(chiMiddleware.Timeout(10 * time.Minute))
+				r.Use(middleware.AdminAuth(cfg.AdminToken))
+				r.Post("/packages/local", packageHandler.InstallLocalPackage)
+			})
+			// Stdin fixtures are raw bodies up to stdin_max_size
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.BodyLimit(cfg.StdinMaxSize))
+				r.Use(chiMiddleware.Timeout(10 * time.Minute))
+				r.Use(middleware.AdminAuth(cfg.AdminToken))
+				r.Put("/admin/fixtures/{name}", adminHandler.PutFixture)
+			})
+		}
+
+		// WebSocket route (no JSON middleware; authenticates during the handshake)
+		r.With(middleware.RateLimit(rateLimiter, apiKeys)).HandleFunc("/connect", h.HandleWebSocket)
 
 		// GET routes
 		r.Get("/runtimes", h.GetRuntimes)
+		r.Get("/runtimes/stats", h.GetRuntimeStats)
+		r.Get("/runtimes/{language}", h.GetLanguage)
+		r.Get("/runtimes/{language}/{version}", h.GetRuntime)
+		r.Get("/metrics", h.GetMetrics)
+		r.Get("/openapi.json", h.GetOpenAPI)
 	})
 
 	// Root route
 	r.Get("/", h.GetVersion)
 
-	// Health check
+	// Health check; fails while draining so load balancers stop routing here
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		if jobManager.Draining() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Draining"))
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	// Kubernetes probes: liveness, and readiness with per-component checks
+	r.Get("/healthz", h.Healthz)
+	r.Get("/readyz", h.Readyz)
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:    cfg.GetBindAddress(),
@@ -121,6 +218,33 @@ func main() {
 		}
 	}()
 
+	// Start the egress proxy for jobs limited to allowlisted hosts
+	var egressServer *http.Server
+	if len(cfg.NetworkAllowedHosts) > 0 {
+		egressServer = &http.Server{
+			Addr:              cfg.NetworkProxyBind,
+			Handler:           egress.NewProxy(cfg.NetworkAllowedHosts),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			logger.Infof("Egress proxy starting on %s for %d allowed hosts", cfg.NetworkProxyBind, len(cfg.NetworkAllowedHosts))
+			if err := egressServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.WithError(err).Fatal("Egress proxy failed to start")
+			}
+		}()
+	}
+
+	// Start gRPC server alongside HTTP
+	var grpcServer *grpc.Server
+	if cfg.GRPCEnabled {
+		grpcServer = grpc.NewServer(cfg, jobManager, runtimeManager, packageService, apiKeys, logger)
+		go func() {
+			if err := grpcServer.Serve(cfg.GRPCBindAddress); err != nil {
+				logger.WithError(err).Fatal("gRPC server failed to start")
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -128,15 +252,39 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
+	runtimeManager.StopWatching()
+
+	// Reject new executions and let in-flight jobs finish, killing them after the drain timeout
+	// or on a second signal
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	go func() {
+		<-quit
+		cancelDrain()
+	}()
+	jobManager.Drain(drainCtx)
+	cancelDrain()
+
 	// Create a deadline for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Shutdown server
+	// Shutdown servers
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	if egressServer != nil {
+		egressServer.Close()
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		logger.WithError(err).Error("Server forced to shutdown")
 		os.Exit(1)
 	}
+	if err := jobManager.Close(); err != nil {
+		logger.WithError(err).Error("Failed to close execution history")
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.WithError(err).Error("Failed to flush traces")
+	}
 
 	logger.Info("Server exited")
 }
@@ -156,3 +304,12 @@ func ensureDataDirectories(cfg *config.Config) error {
 
 	return nil
 }
+
+// bodyLimits caps a route group's POST/PATCH/DELETE bodies at limit, then inflates gzip bodies
+// within the decompressed limit
+func bodyLimits(limit, decompressedLimit int64) []func(http.Handler) http.Handler {
+	return []func(http.Handler) http.Handler{
+		middleware.BodyLimit(limit),
+		middleware.Decompress(decompressedLimit),
+	}
+}
@@ -36,8 +36,8 @@ func TestAPIEndpoints(t *testing.T) {
 	logger.SetLevel(logrus.ErrorLevel)
 
 	runtimeManager := runtime.NewManager(cfg)
-	jobManager := job.NewManager(cfg)
-	h := handler.NewHandler(jobManager, runtimeManager, logger)
+	jobManager := job.NewManager(cfg, runtimeManager)
+	h := handler.NewHandler(cfg, jobManager, runtimeManager, nil, logger)
 
 	// Set up router
 	r := chi.NewRouter()
@@ -0,0 +1,121 @@
+// Package artifact uploads job output files to an object store and hands out pre-signed
+// download URLs, so large artifacts need not be inlined in results.
+package artifact
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+// gcsEndpoint is Google Cloud Storage's S3-compatible XML API, used with HMAC keys
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// Sink stores artifacts in an S3-compatible bucket using path-style requests
+type Sink struct {
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	urlTTL    time.Duration
+	client    *http.Client
+	now       func() time.Time
+}
+
+// Open creates the sink for the configured artifact_sink, or returns nil when it is not set
+func Open(cfg *config.Config) (*Sink, error) {
+	if cfg.ArtifactSink == "" {
+		return nil, nil
+	}
+
+	sink, err := url.Parse(cfg.ArtifactSink)
+	if err != nil || sink.Host == "" {
+		return nil, fmt.Errorf("invalid artifact_sink %q", cfg.ArtifactSink)
+	}
+
+	endpoint := cfg.ArtifactEndpoint
+	region := cfg.ArtifactRegion
+	switch sink.Scheme {
+	case "s3":
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+	case "gs":
+		if endpoint == "" {
+			endpoint = gcsEndpoint
+		}
+		region = "auto"
+	default:
+		return nil, fmt.Errorf("artifact_sink must be an s3:// or gs:// URL")
+	}
+
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid artifact_endpoint %q", endpoint)
+	}
+
+	return &Sink{
+		endpoint:  parsed,
+		bucket:    sink.Host,
+		prefix:    strings.Trim(sink.Path, "/"),
+		region:    region,
+		accessKey: cfg.ArtifactAccessKey,
+		secretKey: cfg.ArtifactSecretKey,
+		urlTTL:    cfg.ArtifactURLTTL,
+		client:    &http.Client{Timeout: cfg.ArtifactTimeout},
+		now:       time.Now,
+	}, nil
+}
+
+// Upload stores size bytes from body under name in the sink's prefix and returns a
+// pre-signed URL to download it, valid for artifact_url_ttl
+func (s *Sink) Upload(ctx context.Context, name string, body io.Reader, size int64) (string, error) {
+	key := s.key(name)
+	now := s.now().UTC()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), body)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	s.sign(req, unsignedPayload, now)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("failed to upload %s: %s: %s", key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+
+	return s.presign(http.MethodGet, key, now), nil
+}
+
+// key returns the object key for name
+func (s *Sink) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
+
+// objectPath is the escaped path-style path of key
+func (s *Sink) objectPath(key string) string {
+	return strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + "/" + uriEncode(s.bucket, true) + "/" + uriEncode(key, false)
+}
+
+// objectURL returns the URL of key
+func (s *Sink) objectURL(key string) string {
+	return s.endpoint.Scheme + "://" + s.endpoint.Host + s.objectPath(key)
+}
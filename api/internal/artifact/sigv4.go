@@ -0,0 +1,142 @@
+package artifact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AWS Signature Version 4, as accepted by S3, GCS interoperability and S3-compatible stores
+const (
+	signAlgorithm   = "AWS4-HMAC-SHA256"
+	signService     = "s3"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+)
+
+// sign adds the x-amz-date and x-amz-content-sha256 headers to req and an Authorization header
+// covering the host and every header already set
+func (s *Sink) sign(req *http.Request, payloadHash string, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(strings.Split(req.URL.RawQuery, "&")),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signAlgorithm, s.accessKey, s.scope(now), signedHeaders, s.signature(canonicalRequest, now)))
+}
+
+// presign returns a URL for method on key that is valid for the sink's URL TTL without credentials
+func (s *Sink) presign(method, key string, now time.Time) string {
+	return s.objectURL(key) + "?" + s.presignQuery(method, s.endpoint.Host, s.objectPath(key), now)
+}
+
+// presignQuery returns the signed query string for method on the escaped path at host
+func (s *Sink) presignQuery(method, host, path string, now time.Time) string {
+	query := []string{
+		"X-Amz-Algorithm=" + signAlgorithm,
+		"X-Amz-Credential=" + uriEncode(s.accessKey+"/"+s.scope(now), true),
+		"X-Amz-Date=" + now.Format(amzDateFormat),
+		"X-Amz-Expires=" + strconv.Itoa(int(s.urlTTL/time.Second)),
+		"X-Amz-SignedHeaders=host",
+	}
+	canonical := canonicalQuery(query)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		canonical,
+		"host:" + host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	return canonical + "&X-Amz-Signature=" + s.signature(canonicalRequest, now)
+}
+
+// scope is the credential scope for requests signed at now
+func (s *Sink) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/" + signService + "/aws4_request"
+}
+
+// signature signs canonicalRequest with a key derived from the secret key, date and region
+func (s *Sink) signature(canonicalRequest string, now time.Time) string {
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		signAlgorithm,
+		now.Format(amzDateFormat),
+		s.scope(now),
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, signService)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery sorts already encoded name=value pairs, dropping empty ones
+func canonicalQuery(pairs []string) string {
+	sorted := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair != "" {
+			sorted = append(sorted, pair)
+		}
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and "/" unless encodeSlash
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -2,10 +2,17 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -14,9 +21,20 @@ import (
 type Config struct {
 	// Server configuration
 	LogLevel      string `mapstructure:"log_level"`
+	LogFormat     string `mapstructure:"log_format"`
 	BindAddress   string `mapstructure:"bind_address"`
 	DataDirectory string `mapstructure:"data_directory"`
 
+	// gRPC server (served alongside HTTP when enabled)
+	GRPCEnabled     bool   `mapstructure:"grpc_enabled"`
+	GRPCBindAddress string `mapstructure:"grpc_bind_address"`
+
+	// Sandbox backend jobs run in: "isolate", or "unsafe_local" to run programs as plain
+	// subprocesses without any isolation, for development machines only
+	SandboxBackend string `mapstructure:"sandbox_backend"`
+	// Path to the isolate sandbox binary
+	IsolatePath string `mapstructure:"isolate_path"`
+
 	// Job execution limits
 	MaxConcurrentJobs  int           `mapstructure:"max_concurrent_jobs"`
 	CompileTimeout     time.Duration `mapstructure:"compile_timeout"`
@@ -25,58 +43,451 @@ type Config struct {
 	RunCPUTime         time.Duration `mapstructure:"run_cpu_time"`
 	CompileMemoryLimit int64         `mapstructure:"compile_memory_limit"`
 	RunMemoryLimit     int64         `mapstructure:"run_memory_limit"`
+	// Step that wall-clock and CPU time limits are rounded up to; isolate honors milliseconds
+	TimeLimitResolution time.Duration `mapstructure:"time_limit_resolution"`
+	// How long past its wall-time limit a stage may run before the server kills the sandbox
+	// itself, in case isolate fails to (0 disables the watchdog)
+	WatchdogGrace time.Duration `mapstructure:"watchdog_grace"`
+
+	// Maximum number of jobs waiting for a slot before new jobs are rejected (0 means unlimited)
+	MaxQueueDepth int `mapstructure:"max_queue_depth"`
+
+	// Number of pre-initialized isolate boxes kept ready for new jobs (0 disables pooling)
+	BoxPoolSize int `mapstructure:"box_pool_size"`
+
+	// How often orphaned isolate boxes are cleaned up (0 disables the reaper)
+	BoxReapInterval time.Duration `mapstructure:"box_reap_interval"`
+
+	// Interpreter processes kept started per installed version of a language (language -> count),
+	// for runtimes that ship a warm script. Idle processes are replaced after the idle timeout.
+	WarmPool            map[string]int `mapstructure:"warm_pool"`
+	WarmPoolIdleTimeout time.Duration  `mapstructure:"warm_pool_idle_timeout"`
+
+	// How long streamed output is coalesced before it is sent (0 sends every read at once)
+	StreamFlushInterval time.Duration `mapstructure:"stream_flush_interval"`
+
+	// How often streaming jobs report the run stage's resource usage (0 disables stats events)
+	StatsInterval time.Duration `mapstructure:"stats_interval"`
+	// isolate's cg_root, read for live stats; "auto:<file>" reads the path from file like isolate
+	IsolateCgroupRoot string `mapstructure:"isolate_cgroup_root"`
 
 	// Process limits
 	MaxProcessCount int   `mapstructure:"max_process_count"`
 	MaxOpenFiles    int   `mapstructure:"max_open_files"`
 	MaxFileSize     int64 `mapstructure:"max_file_size"`
 	OutputMaxSize   int   `mapstructure:"output_max_size"`
+	// What happens when a stage exceeds output_max_size: "kill" or "truncate"
+	OutputLimitAction string `mapstructure:"output_limit_action"`
+
+	// Per-box disk quota in bytes (-1 means unlimited) and inode limit, enforced by isolate --quota.
+	// Requires isolate built with quota support and a box root on a filesystem with quotas enabled.
+	DiskQuota       int64 `mapstructure:"disk_quota"`
+	DiskQuotaInodes int   `mapstructure:"disk_quota_inodes"`
+
+	// Total bytes of output_files returned per job (0 means unlimited)
+	OutputFilesMaxSize int64 `mapstructure:"output_files_max_size"`
+	// Directory whose subdirectories requests may mount read-only as datasets (empty disables them)
+	DatasetDirectory string `mapstructure:"dataset_directory"`
+	// Largest scratch mount a request may ask for, in bytes (0 disables scratch mounts)
+	ScratchMaxSize int64 `mapstructure:"scratch_max_size"`
+	// Largest stdin a request may fetch from stdin_url, and largest fixture an admin may upload
+	StdinMaxSize int64 `mapstructure:"stdin_max_size"`
+	// Hosts stdin_url may fetch from, exactly or as "*.example.com" (empty disables stdin_url)
+	StdinURLHosts []string `mapstructure:"stdin_url_hosts"`
+	// How long fetching a request's stdin_url may take
+	StdinURLTimeout time.Duration `mapstructure:"stdin_url_timeout"`
+
+	// Extracted size and file count of a request's archive (0 means unlimited)
+	ArchiveMaxSize  int64 `mapstructure:"archive_max_size"`
+	ArchiveMaxFiles int   `mapstructure:"archive_max_files"`
+
+	// Compile artifact cache
+	CompileCacheEnabled bool          `mapstructure:"compile_cache_enabled"`
+	CompileCacheMaxSize int64         `mapstructure:"compile_cache_max_size"`
+	CompileCacheTTL     time.Duration `mapstructure:"compile_cache_ttl"`
+
+	// Execution result cache, answering identical submissions with an earlier result
+	ResultCacheEnabled    bool          `mapstructure:"result_cache_enabled"`
+	ResultCacheTTL        time.Duration `mapstructure:"result_cache_ttl"`
+	ResultCacheMaxEntries int           `mapstructure:"result_cache_max_entries"`
 
-	// HTTP request limits
-	RequestBodyLimit int64 `mapstructure:"request_body_limit"`
+	// Interactive REPL sessions
+	SessionTimeout     time.Duration `mapstructure:"session_timeout"`
+	SessionIdleTimeout time.Duration `mapstructure:"session_idle_timeout"`
+	SessionCPUTime     time.Duration `mapstructure:"session_cpu_time"`
+
+	// Async job results
+	JobResultTTL time.Duration `mapstructure:"job_result_ttl"`
+
+	// HTTP request limits: execute routes (including WebSocket messages and gRPC), package
+	// routes, and every other route
+	ExecuteBodyLimit  int64 `mapstructure:"execute_body_limit"`
+	PackagesBodyLimit int64 `mapstructure:"packages_body_limit"`
+	RequestBodyLimit  int64 `mapstructure:"request_body_limit"`
+	// Size of a package tarball uploaded to /packages/local
+	PackageUploadLimit int64 `mapstructure:"package_upload_limit"`
+	// Size a gzip-encoded request body may inflate to (0 means unlimited)
+	DecompressedBodyLimit int64 `mapstructure:"decompressed_body_limit"`
+
+	// gzip level for JSON responses to clients that accept it (0 disables, 1-9)
+	CompressionLevel int `mapstructure:"compression_level"`
+	// Negotiate permessage-deflate on WebSocket connections
+	WSCompression bool `mapstructure:"ws_compression"`
+	// WebSocket keepalive: the server pings every ws_ping_interval and closes connections that send
+	// nothing, not even a pong, for ws_idle_timeout; ws_max_duration caps a connection (0 is unlimited)
+	WSPingInterval time.Duration `mapstructure:"ws_ping_interval"`
+	WSIdleTimeout  time.Duration `mapstructure:"ws_idle_timeout"`
+	WSMaxDuration  time.Duration `mapstructure:"ws_max_duration"`
+	// Jobs one WebSocket connection may run at once under distinct session_ids
+	WSMaxSessions int `mapstructure:"ws_max_sessions"`
+	// How long a resumable WebSocket job keeps running after its client disconnects (0 disables
+	// resuming), and how many of its latest messages are kept for the client to catch up on
+	WSResumeGrace  time.Duration `mapstructure:"ws_resume_grace"`
+	WSResumeBuffer int           `mapstructure:"ws_resume_buffer"`
+
+	// How long shutdown waits for in-flight jobs before killing them
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
 
 	// Security settings
 	DisableNetworking bool `mapstructure:"disable_networking"`
-	RunnerUIDMin      int  `mapstructure:"runner_uid_min"`
-	RunnerUIDMax      int  `mapstructure:"runner_uid_max"`
-	RunnerGIDMin      int  `mapstructure:"runner_gid_min"`
-	RunnerGIDMax      int  `mapstructure:"runner_gid_max"`
 
-	// Package management
-	RepoURL string `mapstructure:"repo_url"`
+	// Sandbox hardening. no_new_privs and block_ptrace are applied to isolate's process, and so
+	// inherited by the program, through a helper the server re-executes itself as.
+	SandboxNoNewPrivs  bool `mapstructure:"sandbox_no_new_privs"`
+	SandboxBlockPtrace bool `mapstructure:"sandbox_block_ptrace"`
+	// Do not mount /proc in the sandbox
+	SandboxHideProc bool `mapstructure:"sandbox_hide_proc"`
+	// Bind the host /etc read-only and noexec; false leaves it out of the sandbox
+	SandboxMountEtc bool `mapstructure:"sandbox_mount_etc"`
+	// Extra isolate --run options per language ("*" applies to every language)
+	SandboxExtraArgs map[string][]string `mapstructure:"sandbox_extra_args"`
+
+	// Per-language networking (language -> enabled), overriding disable_networking
+	NetworkOverrides map[string]bool `mapstructure:"network_overrides"`
+	// API key names allowed to set enable_network on a request ("*" allows every caller)
+	NetworkAllowlist []string `mapstructure:"network_allowlist"`
+	// Proxy URL given to jobs with networking as HTTP(S)_PROXY. When network_allowed_hosts is set,
+	// a built-in proxy on network_proxy_bind only permits those hosts and is used instead.
+	NetworkProxy        string   `mapstructure:"network_proxy"`
+	NetworkAllowedHosts []string `mapstructure:"network_allowed_hosts"`
+	NetworkProxyBind    string   `mapstructure:"network_proxy_bind"`
+
+	// Request environment variables (patterns may end with "*" to match a prefix)
+	EnvAllowlist []string `mapstructure:"env_allowlist"`
+	EnvDenylist  []string `mapstructure:"env_denylist"`
+
+	// Named limit presets a request selects with its profile field, e.g. "contest" or "notebook"
+	Profiles map[string]Profile `mapstructure:"profiles"`
+
+	// Signals clients may send to a running program, by name such as "SIGUSR1"
+	AllowedSignals []string `mapstructure:"allowed_signals"`
+
+	// Request compile_flags and run_flags each language accepts ("*" for every language); patterns
+	// may end with "*" to match a prefix. Flags are rejected unless a pattern matches.
+	BuildFlagAllowlist map[string][]string `mapstructure:"build_flag_allowlist"`
+
+	// Package repository index URLs (http, https or file), merged in order; earlier entries win
+	RepoURLs []string `mapstructure:"repo_url"`
+	// How long a fetched http(s) index is used before it is revalidated (0 revalidates every time)
+	PackageIndexTTL time.Duration `mapstructure:"package_index_ttl"`
+
+	// Package downloads (an empty download directory means <data_directory>/downloads)
+	DownloadDirectory   string `mapstructure:"download_directory"`
+	DownloadConcurrency int    `mapstructure:"download_concurrency"`
+	DownloadChunkSize   int64  `mapstructure:"download_chunk_size"`
+	// Package installs running at once; further installs wait for a slot
+	PackageInstallConcurrency int `mapstructure:"package_install_concurrency"`
+	// Bytes installed packages may take up before the least recently used are evicted (0 means unlimited)
+	MaxPackagesDiskUsage int64 `mapstructure:"max_packages_disk_usage"`
+
+	// Package signatures: a GPG keyring for <download>.sig and a minisign public key for
+	// <download>.minisig. Unsigned packages are rejected when require_signed_packages is set.
+	PackageKeyring        string `mapstructure:"package_keyring"`
+	PackageMinisignKey    string `mapstructure:"package_minisign_key"`
+	RequireSignedPackages bool   `mapstructure:"require_signed_packages"`
+
+	// Reload runtimes when packages are added to or removed from the packages directory
+	RuntimeWatchEnabled bool `mapstructure:"runtime_watch_enabled"`
 
 	// Limit overrides (JSON map)
 	LimitOverrides map[string]map[string]interface{} `mapstructure:"limit_overrides"`
+
+	// Version constraint used per language when a request asks for no version, "*" or "latest"
+	DefaultVersions map[string]string `mapstructure:"default_versions"`
+
+	// Origins allowed to open WebSocket connections ("*" allows any; empty allows same-origin only)
+	WSAllowedOrigins []string `mapstructure:"ws_allowed_origins"`
+
+	// Async job result webhooks
+	WebhookSecret      string        `mapstructure:"webhook_secret"`
+	WebhookWorkers     int           `mapstructure:"webhook_workers"`
+	WebhookMaxAttempts int           `mapstructure:"webhook_max_attempts"`
+	WebhookTimeout     time.Duration `mapstructure:"webhook_timeout"`
+
+	// Upload output files to an object store ("s3://bucket/prefix" or "gs://bucket/prefix") and
+	// return pre-signed URLs instead of inline content. Files smaller than artifact_min_size stay
+	// inline; uploads are limited to artifact_max_size bytes per job.
+	ArtifactSink      string        `mapstructure:"artifact_sink"`
+	ArtifactEndpoint  string        `mapstructure:"artifact_endpoint"`
+	ArtifactRegion    string        `mapstructure:"artifact_region"`
+	ArtifactAccessKey string        `mapstructure:"artifact_access_key"`
+	ArtifactSecretKey string        `mapstructure:"artifact_secret_key"`
+	ArtifactURLTTL    time.Duration `mapstructure:"artifact_url_ttl"`
+	ArtifactTimeout   time.Duration `mapstructure:"artifact_timeout"`
+	ArtifactMinSize   int64         `mapstructure:"artifact_min_size"`
+	ArtifactMaxSize   int64         `mapstructure:"artifact_max_size"`
+
+	// Rate limiting for execute and connect routes (0 requests per minute disables the default limit)
+	RateLimitRequestsPerMinute int `mapstructure:"rate_limit_requests_per_minute"`
+	RateLimitBurst             int `mapstructure:"rate_limit_burst"`
+
+	// Authentication
+	AuthEnabled bool     `mapstructure:"auth_enabled"`
+	APIKeys     []APIKey `mapstructure:"api_keys"`
+	APIKeysFile string   `mapstructure:"api_keys_file"`
+
+	// Tenants group API keys under shared job quotas; keys without a tenant form their own
+	Tenants []Tenant `mapstructure:"tenants"`
+
+	// Execution history ("" disables it, "file" or "sql"), kept for history_retention (0 keeps forever)
+	HistoryBackend     string        `mapstructure:"history_backend"`
+	HistoryPath        string        `mapstructure:"history_path"`
+	HistoryDriver      string        `mapstructure:"history_driver"`
+	HistoryDSN         string        `mapstructure:"history_dsn"`
+	HistoryRetention   time.Duration `mapstructure:"history_retention"`
+	HistoryOutputLimit int           `mapstructure:"history_output_limit"`
+	// Metadata keys whose values GET /metrics counts executions by, each tracking at most
+	// MetricsMetadataMaxValues values
+	MetricsMetadataKeys      []string `mapstructure:"metrics_metadata_keys"`
+	MetricsMetadataMaxValues int      `mapstructure:"metrics_metadata_max_values"`
+
+	// HistorySubmissions also records each request's files and stdin so it can be replayed
+	HistorySubmissions bool `mapstructure:"history_submissions"`
+
+	// OpenTelemetry tracing exported over OTLP/HTTP (an empty endpoint uses OTEL_EXPORTER_OTLP_ENDPOINT)
+	TracingEnabled     bool    `mapstructure:"tracing_enabled"`
+	TracingEndpoint    string  `mapstructure:"tracing_endpoint"`
+	TracingServiceName string  `mapstructure:"tracing_service_name"`
+	TracingSampleRatio float64 `mapstructure:"tracing_sample_ratio"`
+
+	// Token for the /api/v2/admin endpoints (empty disables them)
+	AdminToken string `mapstructure:"admin_token"`
+
+	// mutex guards the settings the admin API can change at runtime
+	mutex sync.RWMutex
+}
+
+// LiveSettings are the settings the admin API can change without a restart
+type LiveSettings struct {
+	LimitOverrides    map[string]map[string]interface{} `json:"limit_overrides"`
+	MaxConcurrentJobs int                               `json:"max_concurrent_jobs"`
+	DisableNetworking bool                              `json:"disable_networking"`
+}
+
+// limitNames are the limits that limit_overrides may set
+var limitNames = []string{
+	"compile_timeout", "run_timeout", "compile_cpu_time", "run_cpu_time",
+	"compile_memory_limit", "run_memory_limit", "max_process_count", "max_open_files",
+	"max_file_size", "output_max_size", "disk_quota", "memory_overhead",
+}
+
+// signalName matches the names allowed_signals takes
+var signalName = regexp.MustCompile(`^SIG[A-Z0-9]+$`)
+
+// SandboxBackends are the values of sandbox_backend
+var SandboxBackends = []string{"isolate", "unsafe_local"}
+
+// reservedIsolateArgs are isolate options the server sets itself, which sandbox_extra_args may
+// not override
+var reservedIsolateArgs = []string{
+	"--run", "--init", "--cleanup", "--version", "-b", "--box-id", "-M", "--meta", "--cg", "--as-uid", "--as-gid",
+}
+
+// APIKey represents a client API key and the limits attached to it
+type APIKey struct {
+	Key  string `mapstructure:"key" json:"key"`
+	Name string `mapstructure:"name" json:"name"`
+
+	// Rate limits (0 means use the server defaults)
+	RequestsPerMinute int `mapstructure:"requests_per_minute" json:"requests_per_minute"`
+	Burst             int `mapstructure:"burst" json:"burst"`
+
+	// Maximum number of in-flight requests for this key (0 means unlimited)
+	MaxConcurrentJobs int `mapstructure:"max_concurrent_jobs" json:"max_concurrent_jobs"`
+
+	// Tenant whose quotas and usage the key's jobs count against (defaults to the key's name)
+	Tenant string `mapstructure:"tenant" json:"tenant,omitempty"`
+}
+
+// TenantName returns the tenant the key's jobs belong to
+func (k *APIKey) TenantName() string {
+	if k.Tenant != "" {
+		return k.Tenant
+	}
+	return k.Name
+}
+
+// Tenant holds the job quotas shared by a tenant's API keys (0 means unlimited)
+type Tenant struct {
+	Name string `mapstructure:"name" json:"name"`
+	// Jobs running or queued at once across all of the tenant's keys
+	MaxConcurrentJobs int `mapstructure:"max_concurrent_jobs" json:"max_concurrent_jobs"`
+	// Executions started per UTC day
+	DailyExecutions int `mapstructure:"daily_executions" json:"daily_executions"`
+}
+
+// Profile is a named set of limits that replaces a runtime's own for the requests selecting it.
+// Zero limits keep the runtime's; a request may still lower any limit below the profile's.
+type Profile struct {
+	CompileTimeout     time.Duration `mapstructure:"compile_timeout" json:"compile_timeout"`
+	RunTimeout         time.Duration `mapstructure:"run_timeout" json:"run_timeout"`
+	CompileCPUTime     time.Duration `mapstructure:"compile_cpu_time" json:"compile_cpu_time"`
+	RunCPUTime         time.Duration `mapstructure:"run_cpu_time" json:"run_cpu_time"`
+	CompileMemoryLimit int64         `mapstructure:"compile_memory_limit" json:"compile_memory_limit"`
+	RunMemoryLimit     int64         `mapstructure:"run_memory_limit" json:"run_memory_limit"`
+	MaxProcessCount    int           `mapstructure:"max_process_count" json:"max_process_count"`
+	OutputMaxSize      int           `mapstructure:"output_max_size" json:"output_max_size"`
+	DiskQuota          int64         `mapstructure:"disk_quota" json:"disk_quota"`
+	// Network turns networking on or off for the profile's jobs; unset keeps the language default.
+	// Selecting a profile that turns it on needs no entry in network_allowlist.
+	Network *bool `mapstructure:"network" json:"network,omitempty"`
+	// API key names that may select the profile ("*" allows every caller)
+	Allow []string `mapstructure:"allow" json:"allow"`
 }
 
 // Load loads configuration from environment variables and config files
 func Load() (*Config, error) {
 	// Set default values
 	viper.SetDefault("log_level", "INFO")
+	viper.SetDefault("log_format", "text")
 	viper.SetDefault("bind_address", getEnvOrDefault("PORT", "2000"))
 	viper.SetDefault("data_directory", "/coderunr")
+	viper.SetDefault("grpc_enabled", false)
+	viper.SetDefault("grpc_bind_address", "0.0.0.0:2001")
+	viper.SetDefault("sandbox_backend", "isolate")
+	viper.SetDefault("isolate_path", "/usr/local/bin/isolate")
 	viper.SetDefault("max_concurrent_jobs", 64)
 	viper.SetDefault("compile_timeout", "10s")
 	viper.SetDefault("run_timeout", "3s")
 	viper.SetDefault("compile_cpu_time", "10s")
 	viper.SetDefault("run_cpu_time", "3s")
+	viper.SetDefault("time_limit_resolution", "1ms")
+	viper.SetDefault("watchdog_grace", "2s")
 	viper.SetDefault("compile_memory_limit", -1)
 	viper.SetDefault("run_memory_limit", -1)
+	viper.SetDefault("max_queue_depth", 256)
+	viper.SetDefault("box_pool_size", 16)
+	viper.SetDefault("box_reap_interval", "5m")
+	viper.SetDefault("warm_pool", map[string]int{})
+	viper.SetDefault("warm_pool_idle_timeout", "5m")
+	viper.SetDefault("stats_interval", "500ms")
+	viper.SetDefault("stream_flush_interval", "10ms")
+	viper.SetDefault("isolate_cgroup_root", "auto:/run/isolate/cgroup")
 	viper.SetDefault("max_process_count", 64)
 	viper.SetDefault("max_open_files", 2048)
 	viper.SetDefault("max_file_size", 10000000) // 10MB
 	viper.SetDefault("output_max_size", 1024)
-	viper.SetDefault("request_body_limit", 1048576) // 1MB default for JSON POST/DELETE
+	viper.SetDefault("output_limit_action", "kill")
+	viper.SetDefault("disk_quota", -1)
+	viper.SetDefault("disk_quota_inodes", 10000)
+	viper.SetDefault("output_files_max_size", 10485760)   // 10MB
+	viper.SetDefault("request_body_limit", 1048576)       // 1MB default for JSON POST/DELETE
+	viper.SetDefault("execute_body_limit", 1048576)       // 1MB
+	viper.SetDefault("packages_body_limit", 8388608)      // 8MB
+	viper.SetDefault("package_upload_limit", 1073741824)  // 1GB
+	viper.SetDefault("decompressed_body_limit", 10485760) // 10MB
+	viper.SetDefault("archive_max_size", 67108864)        // 64MB
+	viper.SetDefault("archive_max_files", 4096)
+	viper.SetDefault("dataset_directory", "")
+	viper.SetDefault("scratch_max_size", 268435456) // 256MB
+	viper.SetDefault("stdin_max_size", 268435456)   // 256MB
+	viper.SetDefault("stdin_url_timeout", "2m")
+	viper.SetDefault("compression_level", 5)
+	viper.SetDefault("ws_compression", true)
+	viper.SetDefault("ws_ping_interval", "20s")
+	viper.SetDefault("ws_idle_timeout", "60s")
+	viper.SetDefault("ws_max_duration", "1h")
+	viper.SetDefault("ws_max_sessions", 8)
+	viper.SetDefault("ws_resume_grace", "30s")
+	viper.SetDefault("ws_resume_buffer", 1024)
+	viper.SetDefault("session_timeout", "30m")
+	viper.SetDefault("session_idle_timeout", "5m")
+	viper.SetDefault("session_cpu_time", "5m")
+	viper.SetDefault("job_result_ttl", "10m")
+	viper.SetDefault("drain_timeout", "60s")
+	viper.SetDefault("compile_cache_enabled", true)
+	viper.SetDefault("compile_cache_max_size", 536870912) // 512MB
+	viper.SetDefault("compile_cache_ttl", "1h")
+	viper.SetDefault("result_cache_enabled", false)
+	viper.SetDefault("result_cache_ttl", "5m")
+	viper.SetDefault("result_cache_max_entries", 1000)
 	viper.SetDefault("disable_networking", true)
-	viper.SetDefault("runner_uid_min", 1001)
-	viper.SetDefault("runner_uid_max", 1500)
-	viper.SetDefault("runner_gid_min", 1001)
-	viper.SetDefault("runner_gid_max", 1500)
+	viper.SetDefault("network_overrides", map[string]bool{})
+	viper.SetDefault("network_allowlist", []string{})
+	viper.SetDefault("network_proxy", "")
+	viper.SetDefault("network_allowed_hosts", []string{})
+	viper.SetDefault("network_proxy_bind", "127.0.0.1:2003")
+	viper.SetDefault("sandbox_no_new_privs", false)
+	viper.SetDefault("sandbox_block_ptrace", false)
+	viper.SetDefault("sandbox_hide_proc", false)
+	viper.SetDefault("sandbox_mount_etc", true)
+	viper.SetDefault("sandbox_extra_args", map[string][]string{})
+	viper.SetDefault("env_allowlist", []string{})
+	viper.SetDefault("build_flag_allowlist", map[string][]string{})
+	viper.SetDefault("allowed_signals", []string{
+		"SIGINT", "SIGTERM", "SIGKILL", "SIGHUP", "SIGQUIT", "SIGUSR1", "SIGUSR2", "SIGTSTP", "SIGCONT", "SIGWINCH",
+	})
+	viper.SetDefault("env_denylist", []string{
+		"PATH", "HOME", "LD_*", "BASH_ENV", "ENV", "IFS", "SHELLOPTS", "BASHOPTS", "CODERUNR_*",
+	})
 	// Default package repository index (direct asset URL). If you want to refer to the tag page,
 	// use https://github.com/hellobyte-dev/coderunr/releases/tag/packages, but the service needs
 	// the raw index file, which is available at the download URL below.
-	viper.SetDefault("repo_url", "https://github.com/hellobyte-dev/coderunr/releases/download/packages/index")
+	viper.SetDefault("repo_url", []string{"https://github.com/hellobyte-dev/coderunr/releases/download/packages/index"})
+	viper.SetDefault("package_index_ttl", "5m")
+	viper.SetDefault("download_directory", "")
+	viper.SetDefault("download_concurrency", 4)
+	viper.SetDefault("download_chunk_size", 16777216) // 16MB
+	viper.SetDefault("package_install_concurrency", 2)
+	viper.SetDefault("max_packages_disk_usage", 0)
+	viper.SetDefault("package_keyring", "")
+	viper.SetDefault("package_minisign_key", "")
+	viper.SetDefault("require_signed_packages", false)
+	viper.SetDefault("runtime_watch_enabled", true)
 	viper.SetDefault("limit_overrides", map[string]map[string]interface{}{})
+	viper.SetDefault("default_versions", map[string]string{})
+	viper.SetDefault("ws_allowed_origins", []string{})
+	viper.SetDefault("webhook_secret", "")
+	viper.SetDefault("webhook_workers", 4)
+	viper.SetDefault("webhook_max_attempts", 5)
+	viper.SetDefault("webhook_timeout", "10s")
+	viper.SetDefault("artifact_sink", "")
+	viper.SetDefault("artifact_endpoint", "")
+	viper.SetDefault("artifact_region", "us-east-1")
+	viper.SetDefault("artifact_access_key", "")
+	viper.SetDefault("artifact_secret_key", "")
+	viper.SetDefault("artifact_url_ttl", "1h")
+	viper.SetDefault("artifact_timeout", "1m")
+	viper.SetDefault("artifact_min_size", 0)
+	viper.SetDefault("artifact_max_size", 1073741824) // 1GB
+	viper.SetDefault("rate_limit_requests_per_minute", 0)
+	viper.SetDefault("rate_limit_burst", 10)
+	viper.SetDefault("auth_enabled", false)
+	viper.SetDefault("api_keys_file", "")
+	viper.SetDefault("admin_token", "")
+	viper.SetDefault("tracing_enabled", false)
+	viper.SetDefault("tracing_endpoint", "")
+	viper.SetDefault("tracing_service_name", "coderunr-api")
+	viper.SetDefault("tracing_sample_ratio", 1.0)
+	viper.SetDefault("history_backend", "")
+	viper.SetDefault("history_path", "")
+	viper.SetDefault("history_driver", "")
+	viper.SetDefault("history_dsn", "")
+	viper.SetDefault("history_retention", "720h")
+	viper.SetDefault("history_output_limit", 1024)
+	viper.SetDefault("history_submissions", false)
+	viper.SetDefault("metrics_metadata_keys", []string{})
+	viper.SetDefault("metrics_metadata_max_values", 100)
 
 	// Set environment variable prefix
 	viper.SetEnvPrefix("CODERUNR")
@@ -121,17 +532,274 @@ func validate(config *Config) error {
 		return fmt.Errorf("invalid log level: %s", config.LogLevel)
 	}
 
+	if config.LogFormat != "text" && config.LogFormat != "json" {
+		return fmt.Errorf("log_format must be text or json")
+	}
+
+	if !slices.Contains(SandboxBackends, config.SandboxBackend) {
+		return fmt.Errorf("sandbox_backend must be one of %s", strings.Join(SandboxBackends, ", "))
+	}
+	if config.IsolatePath == "" {
+		return fmt.Errorf("isolate_path must not be empty")
+	}
+
 	// Validate numeric ranges
 	if config.MaxConcurrentJobs <= 0 {
 		return fmt.Errorf("max_concurrent_jobs must be positive")
 	}
 
-	if config.RunnerUIDMin >= config.RunnerUIDMax {
-		return fmt.Errorf("runner_uid_min must be less than runner_uid_max")
+	if config.MaxQueueDepth < 0 {
+		return fmt.Errorf("max_queue_depth must not be negative")
+	}
+
+	if config.DecompressedBodyLimit < 0 {
+		return fmt.Errorf("decompressed_body_limit must not be negative")
+	}
+
+	if config.ScratchMaxSize < 0 {
+		return fmt.Errorf("scratch_max_size must not be negative")
+	}
+
+	if config.StdinMaxSize <= 0 {
+		return fmt.Errorf("stdin_max_size must be positive")
+	}
+	if config.StdinURLTimeout <= 0 {
+		return fmt.Errorf("stdin_url_timeout must be positive")
+	}
+	for _, host := range config.StdinURLHosts {
+		if strings.TrimPrefix(host, "*.") == "" || strings.ContainsAny(host, "/:") {
+			return fmt.Errorf("stdin_url_hosts: invalid host %q", host)
+		}
+	}
+
+	if config.ArchiveMaxSize < 0 || config.ArchiveMaxFiles < 0 {
+		return fmt.Errorf("archive_max_size and archive_max_files must not be negative")
+	}
+
+	if config.CompressionLevel < 0 || config.CompressionLevel > 9 {
+		return fmt.Errorf("compression_level must be between 0 and 9")
+	}
+
+	if config.BoxPoolSize < 0 || config.BoxPoolSize > 256 {
+		return fmt.Errorf("box_pool_size must be between 0 and 256")
+	}
+
+	if config.NetworkProxy != "" {
+		u, err := url.Parse(config.NetworkProxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("network_proxy must be an http or https URL")
+		}
+		if len(config.NetworkAllowedHosts) > 0 {
+			return fmt.Errorf("network_proxy and network_allowed_hosts cannot both be set")
+		}
+	}
+
+	if len(config.NetworkAllowedHosts) > 0 && config.NetworkProxyBind == "" {
+		return fmt.Errorf("network_proxy_bind is required when network_allowed_hosts is set")
+	}
+
+	if config.DiskQuota > 0 && config.DiskQuotaInodes <= 0 {
+		return fmt.Errorf("disk_quota_inodes must be positive when disk_quota is set")
+	}
+
+	if config.GRPCEnabled && config.GRPCBindAddress == "" {
+		return fmt.Errorf("grpc_bind_address is required when grpc is enabled")
+	}
+
+	if config.CompileCacheEnabled && config.CompileCacheTTL <= 0 {
+		return fmt.Errorf("compile_cache_ttl must be positive")
+	}
+
+	if len(config.MetricsMetadataKeys) > 0 && config.MetricsMetadataMaxValues <= 0 {
+		return fmt.Errorf("metrics_metadata_max_values must be positive")
+	}
+
+	if config.ResultCacheEnabled && (config.ResultCacheTTL <= 0 || config.ResultCacheMaxEntries <= 0) {
+		return fmt.Errorf("result_cache_ttl and result_cache_max_entries must be positive")
+	}
+
+	if config.SessionTimeout <= 0 || config.SessionIdleTimeout <= 0 || config.SessionCPUTime <= 0 {
+		return fmt.Errorf("session_timeout, session_idle_timeout and session_cpu_time must be positive")
+	}
+
+	if config.WSPingInterval <= 0 || config.WSIdleTimeout <= config.WSPingInterval {
+		return fmt.Errorf("ws_ping_interval must be positive and shorter than ws_idle_timeout")
+	}
+
+	if config.WSMaxDuration < 0 {
+		return fmt.Errorf("ws_max_duration must not be negative")
+	}
+	if config.WSMaxSessions < 1 {
+		return fmt.Errorf("ws_max_sessions must be at least 1")
+	}
+	if config.WSResumeGrace < 0 {
+		return fmt.Errorf("ws_resume_grace must not be negative")
+	}
+	if config.WSResumeBuffer < 1 {
+		return fmt.Errorf("ws_resume_buffer must be at least 1")
+	}
+
+	if config.BoxReapInterval < 0 {
+		return fmt.Errorf("box_reap_interval must not be negative")
+	}
+
+	for language, size := range config.WarmPool {
+		if size < 0 || size > 64 {
+			return fmt.Errorf("warm_pool.%s must be between 0 and 64", language)
+		}
+	}
+	if len(config.WarmPool) > 0 && config.WarmPoolIdleTimeout <= 0 {
+		return fmt.Errorf("warm_pool_idle_timeout must be positive")
+	}
+
+	if config.OutputLimitAction != "kill" && config.OutputLimitAction != "truncate" {
+		return fmt.Errorf("output_limit_action must be kill or truncate")
+	}
+
+	if config.StreamFlushInterval < 0 {
+		return fmt.Errorf("stream_flush_interval must not be negative")
+	}
+
+	if config.StatsInterval < 0 {
+		return fmt.Errorf("stats_interval must not be negative")
+	}
+
+	if config.TimeLimitResolution < time.Millisecond {
+		return fmt.Errorf("time_limit_resolution must be at least 1ms")
+	}
+	if config.WatchdogGrace < 0 {
+		return fmt.Errorf("watchdog_grace must not be negative")
+	}
+	if config.DrainTimeout < 0 {
+		return fmt.Errorf("drain_timeout must not be negative")
+	}
+
+	if config.JobResultTTL <= 0 {
+		return fmt.Errorf("job_result_ttl must be positive")
+	}
+
+	for language, args := range config.SandboxExtraArgs {
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") || arg == "--" {
+				return fmt.Errorf("sandbox_extra_args.%s: %q is not an isolate option", language, arg)
+			}
+			name, _, _ := strings.Cut(arg, "=")
+			if slices.Contains(reservedIsolateArgs, name) {
+				return fmt.Errorf("sandbox_extra_args.%s: %s is set by the server", language, name)
+			}
+		}
+	}
+
+	for _, name := range config.AllowedSignals {
+		if !signalName.MatchString(name) {
+			return fmt.Errorf("allowed_signals: %q is not a signal name such as SIGUSR1", name)
+		}
+	}
+
+	for language, version := range config.DefaultVersions {
+		if _, err := semver.NewConstraint(version); err != nil {
+			return fmt.Errorf("default_versions.%s: invalid version constraint %q", language, version)
+		}
+	}
+
+	if config.WebhookWorkers <= 0 || config.WebhookMaxAttempts <= 0 || config.WebhookTimeout <= 0 {
+		return fmt.Errorf("webhook_workers, webhook_max_attempts and webhook_timeout must be positive")
+	}
+
+	if config.ArtifactSink != "" {
+		if config.ArtifactAccessKey == "" || config.ArtifactSecretKey == "" {
+			return fmt.Errorf("artifact_access_key and artifact_secret_key are required with artifact_sink")
+		}
+		// Pre-signed URLs cannot be valid for longer than a week
+		if config.ArtifactURLTTL < time.Second || config.ArtifactURLTTL > 7*24*time.Hour {
+			return fmt.Errorf("artifact_url_ttl must be between 1s and 168h")
+		}
+		if config.ArtifactTimeout <= 0 || config.ArtifactMinSize < 0 || config.ArtifactMaxSize <= 0 {
+			return fmt.Errorf("artifact_timeout and artifact_max_size must be positive and artifact_min_size not negative")
+		}
+	}
+
+	if config.RateLimitRequestsPerMinute < 0 || config.RateLimitBurst < 0 {
+		return fmt.Errorf("rate_limit_requests_per_minute and rate_limit_burst must not be negative")
+	}
+
+	if len(config.RepoURLs) == 0 {
+		return fmt.Errorf("repo_url must list at least one repository")
+	}
+	for _, repoURL := range config.RepoURLs {
+		parsed, err := url.Parse(repoURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https" && parsed.Scheme != "file") {
+			return fmt.Errorf("invalid repo_url %q: must be an http, https or file URL", repoURL)
+		}
+	}
+
+	if config.PackageIndexTTL < 0 {
+		return fmt.Errorf("package_index_ttl must not be negative")
+	}
+
+	if config.DownloadConcurrency <= 0 || config.DownloadChunkSize <= 0 {
+		return fmt.Errorf("download_concurrency and download_chunk_size must be positive")
+	}
+
+	if config.PackageInstallConcurrency <= 0 {
+		return fmt.Errorf("package_install_concurrency must be positive")
 	}
 
-	if config.RunnerGIDMin >= config.RunnerGIDMax {
-		return fmt.Errorf("runner_gid_min must be less than runner_gid_max")
+	if config.MaxPackagesDiskUsage < 0 {
+		return fmt.Errorf("max_packages_disk_usage must not be negative")
+	}
+
+	if config.RequireSignedPackages && config.PackageKeyring == "" && config.PackageMinisignKey == "" {
+		return fmt.Errorf("require_signed_packages needs package_keyring or package_minisign_key")
+	}
+
+	switch config.HistoryBackend {
+	case "", "file":
+	case "sql":
+		if config.HistoryDriver == "" || config.HistoryDSN == "" {
+			return fmt.Errorf("history_driver and history_dsn are required for the sql history backend")
+		}
+	default:
+		return fmt.Errorf("history_backend must be empty, file or sql")
+	}
+
+	if config.HistoryRetention < 0 || config.HistoryOutputLimit < 0 {
+		return fmt.Errorf("history_retention and history_output_limit must not be negative")
+	}
+
+	for name, profile := range config.Profiles {
+		if profile.CompileTimeout < 0 || profile.RunTimeout < 0 || profile.CompileCPUTime < 0 || profile.RunCPUTime < 0 ||
+			profile.CompileMemoryLimit < 0 || profile.RunMemoryLimit < 0 || profile.MaxProcessCount < 0 ||
+			profile.OutputMaxSize < 0 || profile.DiskQuota < 0 {
+			return fmt.Errorf("profiles.%s limits must not be negative", name)
+		}
+	}
+
+	tenants := make(map[string]bool, len(config.Tenants))
+	for i, tenant := range config.Tenants {
+		if tenant.Name == "" || tenants[tenant.Name] {
+			return fmt.Errorf("tenants[%d].name must be set and unique", i)
+		}
+		if tenant.MaxConcurrentJobs < 0 || tenant.DailyExecutions < 0 {
+			return fmt.Errorf("tenants[%d] quotas must not be negative", i)
+		}
+		tenants[tenant.Name] = true
+	}
+
+	if config.TracingSampleRatio < 0 || config.TracingSampleRatio > 1 {
+		return fmt.Errorf("tracing_sample_ratio must be between 0 and 1")
+	}
+
+	for i, key := range config.APIKeys {
+		if key.Key == "" {
+			return fmt.Errorf("api_keys[%d].key must not be empty", i)
+		}
+		if key.Tenant != "" && !tenants[key.Tenant] {
+			return fmt.Errorf("api_keys[%d].tenant %q is not listed in tenants", i, key.Tenant)
+		}
+		if key.RequestsPerMinute < 0 || key.Burst < 0 {
+			return fmt.Errorf("api_keys[%d] rate limits must not be negative", i)
+		}
 	}
 
 	return nil
@@ -162,8 +830,125 @@ func (c *Config) GetLogLevel() logrus.Level {
 	return level
 }
 
+// GetLogFormatter returns the log formatter for the configured log format
+func (c *Config) GetLogFormatter() logrus.Formatter {
+	if c.LogFormat == "json" {
+		return &logrus.JSONFormatter{}
+	}
+	return &logrus.TextFormatter{FullTimestamp: true}
+}
+
+// GetDownloadDirectory returns the directory package tarballs are downloaded to
+func (c *Config) GetDownloadDirectory() string {
+	if c.DownloadDirectory == "" {
+		return filepath.Join(c.DataDirectory, "downloads")
+	}
+	return c.DownloadDirectory
+}
+
+// GetLiveSettings returns a copy of the settings changeable at runtime
+func (c *Config) GetLiveSettings() LiveSettings {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.liveSettings()
+}
+
+// liveSettings copies the live settings; the caller holds the mutex
+func (c *Config) liveSettings() LiveSettings {
+	overrides := make(map[string]map[string]interface{}, len(c.LimitOverrides))
+	for language, limits := range c.LimitOverrides {
+		overrides[language] = make(map[string]interface{}, len(limits))
+		for name, value := range limits {
+			overrides[language][name] = value
+		}
+	}
+
+	return LiveSettings{
+		LimitOverrides:    overrides,
+		MaxConcurrentJobs: c.MaxConcurrentJobs,
+		DisableNetworking: c.DisableNetworking,
+	}
+}
+
+// PatchLiveSettings applies patch to a copy of the live settings, then validates and stores
+// the result, all under the config's lock so concurrent patches are not lost. Limit override
+// values decoded from JSON are converted to whole numbers of milliseconds or bytes. It returns
+// the settings now in effect.
+func (c *Config) PatchLiveSettings(patch func(*LiveSettings)) (LiveSettings, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	settings := c.liveSettings()
+	patch(&settings)
+
+	if settings.MaxConcurrentJobs <= 0 {
+		return LiveSettings{}, fmt.Errorf("max_concurrent_jobs must be positive")
+	}
+
+	overrides := make(map[string]map[string]interface{}, len(settings.LimitOverrides))
+	for language, limits := range settings.LimitOverrides {
+		overrides[language] = make(map[string]interface{}, len(limits))
+		for name, value := range limits {
+			if !slices.Contains(limitNames, name) {
+				return LiveSettings{}, fmt.Errorf("unknown limit %q for %s", name, language)
+			}
+			switch v := value.(type) {
+			case int:
+				overrides[language][name] = v
+			case float64:
+				if v != float64(int(v)) {
+					return LiveSettings{}, fmt.Errorf("limit %s for %s must be a whole number", name, language)
+				}
+				overrides[language][name] = int(v)
+			default:
+				return LiveSettings{}, fmt.Errorf("limit %s for %s must be a number", name, language)
+			}
+		}
+	}
+
+	c.LimitOverrides = overrides
+	c.MaxConcurrentJobs = settings.MaxConcurrentJobs
+	c.DisableNetworking = settings.DisableNetworking
+	return c.liveSettings(), nil
+}
+
+// NetworkEnabled reports whether jobs for language get network access by default
+func (c *Config) NetworkEnabled(language string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if enabled, ok := c.NetworkOverrides[language]; ok {
+		return enabled
+	}
+	return !c.DisableNetworking
+}
+
+// GetNetworkProxy returns the proxy URL for jobs with network access, or "" for direct access
+func (c *Config) GetNetworkProxy() string {
+	if len(c.NetworkAllowedHosts) > 0 {
+		return "http://" + c.NetworkProxyBind
+	}
+	return c.NetworkProxy
+}
+
+// GetHistoryPath returns the file used by the file history backend
+func (c *Config) GetHistoryPath() string {
+	if c.HistoryPath == "" {
+		return filepath.Join(c.DataDirectory, "history", "executions.jsonl")
+	}
+	return c.HistoryPath
+}
+
+// DefaultVersion returns the configured default version constraint for language, or ""
+func (c *Config) DefaultVersion(language string) string {
+	return c.DefaultVersions[language]
+}
+
 // GetLimitOverride returns the limit override for a specific language and limit type
 func (c *Config) GetLimitOverride(language, limitType string) (interface{}, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	if langOverrides, exists := c.LimitOverrides[language]; exists {
 		if value, exists := langOverrides[limitType]; exists {
 			return value, true
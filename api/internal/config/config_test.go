@@ -0,0 +1,53 @@
+package config
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPatchLiveSettings(t *testing.T) {
+	c := &Config{MaxConcurrentJobs: 4}
+
+	// Concurrent patches to different fields must not overwrite each other
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.PatchLiveSettings(func(s *LiveSettings) { s.MaxConcurrentJobs = 8 })
+		}()
+		go func() {
+			defer wg.Done()
+			c.PatchLiveSettings(func(s *LiveSettings) {
+				s.LimitOverrides = map[string]map[string]interface{}{"python": {"run_timeout": float64(5000)}}
+			})
+		}()
+	}
+	wg.Wait()
+
+	settings := c.GetLiveSettings()
+	if settings.MaxConcurrentJobs != 8 || settings.LimitOverrides["python"]["run_timeout"] != 5000 {
+		t.Errorf("Expected both patches applied, got %+v", settings)
+	}
+
+	tests := []struct {
+		name  string
+		patch func(*LiveSettings)
+	}{
+		{"non-positive max_concurrent_jobs", func(s *LiveSettings) { s.MaxConcurrentJobs = 0 }},
+		{"unknown limit", func(s *LiveSettings) {
+			s.LimitOverrides = map[string]map[string]interface{}{"python": {"bogus": 1}}
+		}},
+		{"fractional limit", func(s *LiveSettings) {
+			s.LimitOverrides = map[string]map[string]interface{}{"python": {"run_timeout": 1.5}}
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := c.PatchLiveSettings(tt.patch); err == nil {
+			t.Errorf("PatchLiveSettings() with %s succeeded, want an error", tt.name)
+		}
+	}
+	if after := c.GetLiveSettings(); after.MaxConcurrentJobs != 8 || len(after.LimitOverrides) != 1 {
+		t.Errorf("Expected rejected patches to leave the settings unchanged, got %+v", after)
+	}
+}
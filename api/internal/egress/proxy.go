@@ -0,0 +1,144 @@
+// Package egress provides a forward proxy that only lets sandboxed jobs reach allowlisted hosts.
+package egress
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dialTimeout bounds connecting to an upstream host
+const dialTimeout = 10 * time.Second
+
+// hopHeaders are connection-specific headers that must not be forwarded
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Proxy is an HTTP forward proxy supporting CONNECT tunnels and plain HTTP requests to
+// allowlisted hosts. Patterns match a host exactly, or any subdomain when written as "*.domain".
+type Proxy struct {
+	allowed   []string
+	transport *http.Transport
+	logger    *logrus.Entry
+}
+
+// NewProxy creates a proxy permitting the given host patterns
+func NewProxy(allowed []string) *Proxy {
+	patterns := make([]string, len(allowed))
+	for i, pattern := range allowed {
+		patterns[i] = strings.ToLower(strings.TrimSpace(pattern))
+	}
+
+	return &Proxy{
+		allowed: patterns,
+		transport: &http.Transport{
+			Proxy:       nil,
+			DialContext: (&net.Dialer{Timeout: dialTimeout}).DialContext,
+		},
+		logger: logrus.WithField("component", "egress_proxy"),
+	}
+}
+
+// Allowed reports whether host (without port) matches an allowlisted pattern
+func (p *Proxy) Allowed(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range p.allowed {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP proxies a CONNECT tunnel or an absolute-URI HTTP request
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Hostname()
+	if r.Method == http.MethodConnect {
+		host, _, _ = net.SplitHostPort(r.Host)
+	}
+	if host == "" || !p.Allowed(host) {
+		p.logger.WithField("host", host).Warn("Blocked egress request")
+		http.Error(w, "host is not in the network allowlist", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	p.forward(w, r)
+}
+
+// tunnel connects to the target and relays bytes in both directions
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, dialTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	go relay(upstream, client)
+	relay(client, upstream)
+}
+
+// relay copies src to dst and closes both when done
+func relay(dst, src net.Conn) {
+	defer dst.Close()
+	defer src.Close()
+	_, _ = io.Copy(dst, src)
+}
+
+// forward sends a plain HTTP request upstream and copies the response back
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, header := range hopHeaders {
+		out.Header.Del(header)
+	}
+
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range hopHeaders {
+		resp.Header.Del(header)
+	}
+	for name, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
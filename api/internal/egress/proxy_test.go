@@ -0,0 +1,68 @@
+package egress
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestProxyAllowed(t *testing.T) {
+	p := NewProxy([]string{"pypi.org", "*.pythonhosted.org", " Registry.NPMJS.org "})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"pypi.org", true},
+		{"PyPI.org.", true},
+		{"evil-pypi.org", false},
+		{"files.pythonhosted.org", true},
+		{"pythonhosted.org", false},
+		{"registry.npmjs.org", true},
+		{"example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := p.Allowed(tt.host); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestProxyForward(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer upstream.Close()
+
+	proxy := httptest.NewServer(NewProxy([]string{"127.0.0.1"}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("GET through proxy failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("allowed request = %d %q, want 200 \"hello\"", resp.StatusCode, body)
+	}
+
+	blocked := httptest.NewServer(NewProxy([]string{"example.com"}))
+	defer blocked.Close()
+	blockedURL, _ := url.Parse(blocked.URL)
+	client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(blockedURL)}}
+
+	resp, err = client.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("GET through proxy failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("blocked request status = %d, want 403", resp.StatusCode)
+	}
+}
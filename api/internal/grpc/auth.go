@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/coderunr/api/internal/grpc/pb"
+	"github.com/coderunr/api/internal/middleware"
+)
+
+// authenticatedMethods lists the RPCs that require an API key, matching the HTTP routes
+var authenticatedMethods = map[string]bool{
+	pb.CodeRunr_Execute_FullMethodName:       true,
+	pb.CodeRunr_ExecuteStream_FullMethodName: true,
+}
+
+// authenticator enforces API key authentication on gRPC calls
+type authenticator struct {
+	store *middleware.APIKeyStore
+}
+
+// unary authenticates unary RPCs
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo,
+	handler gogrpc.UnaryHandler) (interface{}, error) {
+	ctx, release, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return handler(ctx, req)
+}
+
+// stream authenticates streaming RPCs
+func (a *authenticator) stream(srv interface{}, ss gogrpc.ServerStream, info *gogrpc.StreamServerInfo,
+	handler gogrpc.StreamHandler) error {
+	ctx, release, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate validates the API key in the call metadata and reserves a concurrency slot.
+// The returned release function must be called once the call completes.
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, func(), error) {
+	if !a.store.Enabled() || !authenticatedMethods[method] {
+		return ctx, func() {}, nil
+	}
+
+	key, ok := a.store.Lookup(extractAPIKey(ctx))
+	if !ok {
+		return nil, nil, status.Error(codes.Unauthenticated, "missing or invalid api key")
+	}
+
+	if !a.store.Acquire(key) {
+		return nil, nil, status.Error(codes.ResourceExhausted, "too many concurrent requests for api key")
+	}
+
+	return middleware.WithAPIKey(ctx, key), func() { a.store.Release(key) }, nil
+}
+
+// extractAPIKey reads the key from the authorization or x-api-key metadata
+func extractAPIKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, auth := range md.Get("authorization") {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+	}
+	if keys := md.Get("x-api-key"); len(keys) > 0 {
+		return strings.TrimSpace(keys[0])
+	}
+	return ""
+}
+
+// contextStream overrides the stream context with one carrying request values
+type contextStream struct {
+	gogrpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the overridden context
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// recoverUnary converts handler panics into internal errors
+func (s *Server) recoverUnary(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo,
+	handler gogrpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.WithField("method", info.FullMethod).WithField("panic", r).
+				WithField("stack", string(debug.Stack())).Error("RPC panicked")
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// recoverStream converts stream handler panics into internal errors
+func (s *Server) recoverStream(srv interface{}, ss gogrpc.ServerStream, info *gogrpc.StreamServerInfo,
+	handler gogrpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.WithField("method", info.FullMethod).WithField("panic", r).
+				WithField("stack", string(debug.Stack())).Error("RPC panicked")
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(srv, ss)
+}
@@ -0,0 +1,67 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/grpc/pb"
+	"github.com/coderunr/api/internal/middleware"
+)
+
+func TestAuthenticate(t *testing.T) {
+	store, err := middleware.NewAPIKeyStore(&config.Config{
+		AuthEnabled: true,
+		APIKeys:     []config.APIKey{{Key: "secret", Name: "grader", MaxConcurrentJobs: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+	auth := &authenticator{store: store}
+
+	tests := []struct {
+		name     string
+		method   string
+		md       metadata.MD
+		expected codes.Code
+	}{
+		{"Missing Key", pb.CodeRunr_Execute_FullMethodName, metadata.MD{}, codes.Unauthenticated},
+		{"Invalid Key", pb.CodeRunr_Execute_FullMethodName, metadata.Pairs("x-api-key", "wrong"), codes.Unauthenticated},
+		{"Bearer Key", pb.CodeRunr_Execute_FullMethodName, metadata.Pairs("authorization", "Bearer secret"), codes.OK},
+		{"Header Key", pb.CodeRunr_ExecuteStream_FullMethodName, metadata.Pairs("x-api-key", "secret"), codes.OK},
+		{"Public Method", pb.CodeRunr_ListRuntimes_FullMethodName, metadata.MD{}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			_, release, err := auth.authenticate(ctx, tt.method)
+			if code := status.Code(err); code != tt.expected {
+				t.Fatalf("Expected %s, got %s", tt.expected, code)
+			}
+			if err == nil {
+				release()
+			}
+		})
+	}
+
+	t.Run("Concurrency Limit", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "secret"))
+		authed, release, err := auth.authenticate(ctx, pb.CodeRunr_Execute_FullMethodName)
+		if err != nil {
+			t.Fatalf("Expected first call to succeed, got %v", err)
+		}
+		if key, ok := middleware.APIKeyFromContext(authed); !ok || key.Name != "grader" {
+			t.Errorf("Expected authenticated key in context")
+		}
+
+		if _, _, err := auth.authenticate(ctx, pb.CodeRunr_Execute_FullMethodName); status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("Expected %s, got %v", codes.ResourceExhausted, err)
+		}
+		release()
+	})
+}
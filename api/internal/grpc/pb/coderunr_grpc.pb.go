@@ -0,0 +1,328 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.27.1
+// source: coderunr.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CodeRunr_Execute_FullMethodName          = "/coderunr.v1.CodeRunr/Execute"
+	CodeRunr_ExecuteStream_FullMethodName    = "/coderunr.v1.CodeRunr/ExecuteStream"
+	CodeRunr_ListRuntimes_FullMethodName     = "/coderunr.v1.CodeRunr/ListRuntimes"
+	CodeRunr_ListPackages_FullMethodName     = "/coderunr.v1.CodeRunr/ListPackages"
+	CodeRunr_InstallPackage_FullMethodName   = "/coderunr.v1.CodeRunr/InstallPackage"
+	CodeRunr_UninstallPackage_FullMethodName = "/coderunr.v1.CodeRunr/UninstallPackage"
+)
+
+// CodeRunrClient is the client API for CodeRunr service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CodeRunr exposes code execution and package management over gRPC.
+// It mirrors the /api/v2 HTTP endpoints.
+type CodeRunrClient interface {
+	// Execute runs a job to completion and returns the stage results.
+	Execute(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (*ExecuteResponse, error)
+	// ExecuteStream runs a job interactively. The first client message must be
+	// an init carrying the job; stdin and signals may follow. The server streams
+	// stage, output and exit events until the job completes.
+	ExecuteStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecuteStreamRequest, ExecuteStreamResponse], error)
+	// ListRuntimes returns the installed runtimes.
+	ListRuntimes(ctx context.Context, in *ListRuntimesRequest, opts ...grpc.CallOption) (*ListRuntimesResponse, error)
+	// ListPackages returns the packages available in the repository.
+	ListPackages(ctx context.Context, in *ListPackagesRequest, opts ...grpc.CallOption) (*ListPackagesResponse, error)
+	// InstallPackage installs a package from the repository.
+	InstallPackage(ctx context.Context, in *InstallPackageRequest, opts ...grpc.CallOption) (*InstallPackageResponse, error)
+	// UninstallPackage removes an installed package.
+	UninstallPackage(ctx context.Context, in *UninstallPackageRequest, opts ...grpc.CallOption) (*UninstallPackageResponse, error)
+}
+
+type codeRunrClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCodeRunrClient(cc grpc.ClientConnInterface) CodeRunrClient {
+	return &codeRunrClient{cc}
+}
+
+func (c *codeRunrClient) Execute(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (*ExecuteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteResponse)
+	err := c.cc.Invoke(ctx, CodeRunr_Execute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *codeRunrClient) ExecuteStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecuteStreamRequest, ExecuteStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CodeRunr_ServiceDesc.Streams[0], CodeRunr_ExecuteStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExecuteStreamRequest, ExecuteStreamResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CodeRunr_ExecuteStreamClient = grpc.BidiStreamingClient[ExecuteStreamRequest, ExecuteStreamResponse]
+
+func (c *codeRunrClient) ListRuntimes(ctx context.Context, in *ListRuntimesRequest, opts ...grpc.CallOption) (*ListRuntimesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRuntimesResponse)
+	err := c.cc.Invoke(ctx, CodeRunr_ListRuntimes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *codeRunrClient) ListPackages(ctx context.Context, in *ListPackagesRequest, opts ...grpc.CallOption) (*ListPackagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPackagesResponse)
+	err := c.cc.Invoke(ctx, CodeRunr_ListPackages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *codeRunrClient) InstallPackage(ctx context.Context, in *InstallPackageRequest, opts ...grpc.CallOption) (*InstallPackageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InstallPackageResponse)
+	err := c.cc.Invoke(ctx, CodeRunr_InstallPackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *codeRunrClient) UninstallPackage(ctx context.Context, in *UninstallPackageRequest, opts ...grpc.CallOption) (*UninstallPackageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UninstallPackageResponse)
+	err := c.cc.Invoke(ctx, CodeRunr_UninstallPackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CodeRunrServer is the server API for CodeRunr service.
+// All implementations must embed UnimplementedCodeRunrServer
+// for forward compatibility.
+//
+// CodeRunr exposes code execution and package management over gRPC.
+// It mirrors the /api/v2 HTTP endpoints.
+type CodeRunrServer interface {
+	// Execute runs a job to completion and returns the stage results.
+	Execute(context.Context, *ExecuteRequest) (*ExecuteResponse, error)
+	// ExecuteStream runs a job interactively. The first client message must be
+	// an init carrying the job; stdin and signals may follow. The server streams
+	// stage, output and exit events until the job completes.
+	ExecuteStream(grpc.BidiStreamingServer[ExecuteStreamRequest, ExecuteStreamResponse]) error
+	// ListRuntimes returns the installed runtimes.
+	ListRuntimes(context.Context, *ListRuntimesRequest) (*ListRuntimesResponse, error)
+	// ListPackages returns the packages available in the repository.
+	ListPackages(context.Context, *ListPackagesRequest) (*ListPackagesResponse, error)
+	// InstallPackage installs a package from the repository.
+	InstallPackage(context.Context, *InstallPackageRequest) (*InstallPackageResponse, error)
+	// UninstallPackage removes an installed package.
+	UninstallPackage(context.Context, *UninstallPackageRequest) (*UninstallPackageResponse, error)
+	mustEmbedUnimplementedCodeRunrServer()
+}
+
+// UnimplementedCodeRunrServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCodeRunrServer struct{}
+
+func (UnimplementedCodeRunrServer) Execute(context.Context, *ExecuteRequest) (*ExecuteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Execute not implemented")
+}
+func (UnimplementedCodeRunrServer) ExecuteStream(grpc.BidiStreamingServer[ExecuteStreamRequest, ExecuteStreamResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteStream not implemented")
+}
+func (UnimplementedCodeRunrServer) ListRuntimes(context.Context, *ListRuntimesRequest) (*ListRuntimesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRuntimes not implemented")
+}
+func (UnimplementedCodeRunrServer) ListPackages(context.Context, *ListPackagesRequest) (*ListPackagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPackages not implemented")
+}
+func (UnimplementedCodeRunrServer) InstallPackage(context.Context, *InstallPackageRequest) (*InstallPackageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InstallPackage not implemented")
+}
+func (UnimplementedCodeRunrServer) UninstallPackage(context.Context, *UninstallPackageRequest) (*UninstallPackageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UninstallPackage not implemented")
+}
+func (UnimplementedCodeRunrServer) mustEmbedUnimplementedCodeRunrServer() {}
+func (UnimplementedCodeRunrServer) testEmbeddedByValue()                  {}
+
+// UnsafeCodeRunrServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CodeRunrServer will
+// result in compilation errors.
+type UnsafeCodeRunrServer interface {
+	mustEmbedUnimplementedCodeRunrServer()
+}
+
+func RegisterCodeRunrServer(s grpc.ServiceRegistrar, srv CodeRunrServer) {
+	// If the following call pancis, it indicates UnimplementedCodeRunrServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CodeRunr_ServiceDesc, srv)
+}
+
+func _CodeRunr_Execute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeRunrServer).Execute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeRunr_Execute_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeRunrServer).Execute(ctx, req.(*ExecuteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CodeRunr_ExecuteStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CodeRunrServer).ExecuteStream(&grpc.GenericServerStream[ExecuteStreamRequest, ExecuteStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CodeRunr_ExecuteStreamServer = grpc.BidiStreamingServer[ExecuteStreamRequest, ExecuteStreamResponse]
+
+func _CodeRunr_ListRuntimes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRuntimesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeRunrServer).ListRuntimes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeRunr_ListRuntimes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeRunrServer).ListRuntimes(ctx, req.(*ListRuntimesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CodeRunr_ListPackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPackagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeRunrServer).ListPackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeRunr_ListPackages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeRunrServer).ListPackages(ctx, req.(*ListPackagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CodeRunr_InstallPackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InstallPackageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeRunrServer).InstallPackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeRunr_InstallPackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeRunrServer).InstallPackage(ctx, req.(*InstallPackageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CodeRunr_UninstallPackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UninstallPackageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CodeRunrServer).UninstallPackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CodeRunr_UninstallPackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CodeRunrServer).UninstallPackage(ctx, req.(*UninstallPackageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CodeRunr_ServiceDesc is the grpc.ServiceDesc for CodeRunr service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CodeRunr_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "coderunr.v1.CodeRunr",
+	HandlerType: (*CodeRunrServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Execute",
+			Handler:    _CodeRunr_Execute_Handler,
+		},
+		{
+			MethodName: "ListRuntimes",
+			Handler:    _CodeRunr_ListRuntimes_Handler,
+		},
+		{
+			MethodName: "ListPackages",
+			Handler:    _CodeRunr_ListPackages_Handler,
+		},
+		{
+			MethodName: "InstallPackage",
+			Handler:    _CodeRunr_InstallPackage_Handler,
+		},
+		{
+			MethodName: "UninstallPackage",
+			Handler:    _CodeRunr_UninstallPackage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExecuteStream",
+			Handler:       _CodeRunr_ExecuteStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "coderunr.proto",
+}
@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"context"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDUnary tags unary RPCs with a request ID, as chi's RequestID middleware does for HTTP
+func requestIDUnary(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo,
+	handler gogrpc.UnaryHandler) (interface{}, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+// requestIDStream tags streaming RPCs with a request ID
+func requestIDStream(srv interface{}, ss gogrpc.ServerStream, info *gogrpc.StreamServerInfo,
+	handler gogrpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+// withRequestID stores the caller's x-request-id metadata, or a new ID, in the context
+// under the same key used by the HTTP server so job logs are correlated the same way
+func withRequestID(ctx context.Context) context.Context {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get("x-request-id"); len(ids) > 0 {
+			requestID = ids[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	return context.WithValue(ctx, chiMiddleware.RequestIDKey, requestID)
+}
@@ -0,0 +1,72 @@
+// Package grpc serves the CodeRunr API over gRPC alongside the HTTP server.
+package grpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pb/coderunr.proto
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+	gogrpc "google.golang.org/grpc"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/grpc/pb"
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/service"
+)
+
+// Server implements the CodeRunr gRPC service
+type Server struct {
+	pb.UnimplementedCodeRunrServer
+
+	jobManager     *job.Manager
+	runtimeManager *runtime.Manager
+	packageService *service.PackageService
+	server         *gogrpc.Server
+	logger         *logrus.Entry
+}
+
+// NewServer creates a gRPC server sharing the job manager, runtime manager and package service
+// with the HTTP API
+func NewServer(cfg *config.Config, jobManager *job.Manager, runtimeManager *runtime.Manager,
+	packageService *service.PackageService, apiKeys *middleware.APIKeyStore, logger *logrus.Logger) *Server {
+	s := &Server{
+		jobManager:     jobManager,
+		runtimeManager: runtimeManager,
+		packageService: packageService,
+		logger:         logger.WithField("component", "grpc"),
+	}
+
+	auth := &authenticator{store: apiKeys}
+	opts := []gogrpc.ServerOption{
+		gogrpc.ChainUnaryInterceptor(requestIDUnary, s.recoverUnary, auth.unary),
+		gogrpc.ChainStreamInterceptor(requestIDStream, s.recoverStream, auth.stream),
+	}
+	// Apply the execute body limit to incoming messages (non-positive keeps the gRPC default)
+	if cfg.ExecuteBodyLimit > 0 {
+		opts = append(opts, gogrpc.MaxRecvMsgSize(int(cfg.ExecuteBodyLimit)))
+	}
+	s.server = gogrpc.NewServer(opts...)
+	pb.RegisterCodeRunrServer(s.server, s)
+
+	return s
+}
+
+// Serve listens on address and serves until Stop is called
+func (s *Server) Serve(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	s.logger.Infof("gRPC server starting on %s", address)
+	return s.server.Serve(listener)
+}
+
+// Stop stops accepting new RPCs and waits for in-flight ones to finish
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
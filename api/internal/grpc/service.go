@@ -0,0 +1,363 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/coderunr/api/internal/grpc/pb"
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/types"
+)
+
+// Execute runs a job to completion
+func (s *Server) Execute(ctx context.Context, req *pb.ExecuteRequest) (*pb.ExecuteResponse, error) {
+	request, rt, err := s.resolveJobRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.jobManager.NewJob(ctx, rt, request).Execute(ctx)
+	if errors.Is(err, job.ErrQueueFull) {
+		stats := s.jobManager.QueueStats()
+		return nil, status.Errorf(codes.Unavailable, "job queue is full (%d running, %d queued)",
+			stats.Running, stats.Queued)
+	}
+	if errors.Is(err, job.ErrDraining) {
+		return nil, status.Error(codes.Unavailable, "server is shutting down")
+	}
+	var quotaErr *job.QuotaError
+	if errors.As(err, &quotaErr) {
+		return nil, status.Error(codes.ResourceExhausted, quotaErr.Error())
+	}
+	var sandboxErr *job.SandboxError
+	if errors.As(err, &sandboxErr) {
+		s.logger.WithError(err).Error("Sandbox failed")
+		return nil, status.Error(codes.Unavailable, sandboxErr.Error())
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Job execution failed")
+		return nil, status.Error(codes.Internal, "job execution failed")
+	}
+
+	// Handle backward compatibility (Piston behavior)
+	if result.Run == nil && result.Compile != nil {
+		result.Run = result.Compile
+	}
+
+	return &pb.ExecuteResponse{
+		Language: result.Language,
+		Version:  result.Version,
+		Compile:  toStageResult(result.Compile),
+		Run:      toStageResult(result.Run),
+		Files:    toOutputFiles(result.Files),
+	}, nil
+}
+
+// ExecuteStream runs a job interactively, forwarding stdin and signals from the client
+func (s *Server) ExecuteStream(stream gogrpc.BidiStreamingServer[pb.ExecuteStreamRequest, pb.ExecuteStreamResponse]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	init := first.GetInit()
+	if init == nil {
+		return status.Error(codes.FailedPrecondition, "first message must be init")
+	}
+
+	request, rt, err := s.resolveJobRequest(init)
+	if err != nil {
+		return err
+	}
+
+	j := s.jobManager.NewJob(stream.Context(), rt, request)
+	if err := stream.Send(&pb.ExecuteStreamResponse{
+		Type:     "runtime",
+		Language: rt.Language,
+		Version:  rt.Version.String(),
+	}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	go s.forwardClientMessages(ctx, stream, j)
+	go func() {
+		if err := j.ExecuteStream(ctx); err != nil {
+			s.logger.WithError(err).Debug("Streaming job failed")
+		}
+	}()
+
+	// The event channel is closed by ExecuteStream once the job finishes
+	var sendErr error
+	for event := range j.EventChannel {
+		if sendErr != nil {
+			// Keep draining until the job observes the cancelled context
+			continue
+		}
+		if msg, ok := toStreamResponse(event); ok {
+			if sendErr = stream.Send(msg); sendErr != nil {
+				cancel()
+			}
+		}
+	}
+
+	return sendErr
+}
+
+// forwardClientMessages relays stdin and signal messages to the running job
+func (s *Server) forwardClientMessages(ctx context.Context,
+	stream gogrpc.BidiStreamingServer[pb.ExecuteStreamRequest, pb.ExecuteStreamResponse], j *job.Job) {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				s.logger.WithError(err).Debug("Failed to receive stream message")
+			}
+			return
+		}
+
+		switch m := msg.Message.(type) {
+		case *pb.ExecuteStreamRequest_Stdin:
+			if err := j.WriteStdin(m.Stdin); err != nil {
+				s.logger.WithError(err).Warn("Failed to write to stdin")
+			}
+		case *pb.ExecuteStreamRequest_Signal:
+			if err := j.SendSignal(m.Signal); err != nil {
+				s.logger.WithError(err).Warn("Failed to send signal")
+			}
+		case *pb.ExecuteStreamRequest_Init:
+			s.logger.Warn("Ignoring repeated init message")
+		}
+	}
+}
+
+// ListRuntimes returns the installed runtimes
+func (s *Server) ListRuntimes(ctx context.Context, req *pb.ListRuntimesRequest) (*pb.ListRuntimesResponse, error) {
+	runtimes := s.runtimeManager.GetRuntimes()
+
+	response := &pb.ListRuntimesResponse{Runtimes: make([]*pb.Runtime, len(runtimes))}
+	for i, rt := range runtimes {
+		runtimeName := rt.Runtime
+		if runtimeName == "" {
+			runtimeName = rt.Language
+		}
+
+		response.Runtimes[i] = &pb.Runtime{
+			Language: rt.Language,
+			Version:  rt.Version.String(),
+			Aliases:  rt.Aliases,
+			Runtime:  runtimeName,
+			Platform: rt.Platform,
+			Os:       rt.OS,
+			Arch:     rt.Arch,
+		}
+	}
+
+	return response, nil
+}
+
+// ListPackages returns the packages available in the repository
+func (s *Server) ListPackages(ctx context.Context, req *pb.ListPackagesRequest) (*pb.ListPackagesResponse, error) {
+	packages, err := s.packageService.GetPackageList()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get package list")
+		return nil, status.Error(codes.Unavailable, "failed to get package list")
+	}
+
+	response := &pb.ListPackagesResponse{Packages: make([]*pb.Package, len(packages))}
+	for i, pkg := range packages {
+		response.Packages[i] = &pb.Package{
+			Language:        pkg.Language,
+			LanguageVersion: pkg.Version.String(),
+			Installed:       s.packageService.IsInstalled(pkg),
+		}
+	}
+
+	return response, nil
+}
+
+// InstallPackage installs a package from the repository
+func (s *Server) InstallPackage(ctx context.Context, req *pb.InstallPackageRequest) (*pb.InstallPackageResponse, error) {
+	pkg, err := s.lookupPackage(req.Language, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.packageService.InstallPackage(ctx, pkg, nil); err != nil {
+		s.logger.WithError(err).Errorf("Error while installing package %s-%s", pkg.Language, pkg.Version.String())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.InstallPackageResponse{Language: pkg.Language, Version: pkg.Version.String()}, nil
+}
+
+// UninstallPackage removes an installed package
+func (s *Server) UninstallPackage(ctx context.Context, req *pb.UninstallPackageRequest) (*pb.UninstallPackageResponse, error) {
+	pkg, err := s.lookupPackage(req.Language, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.packageService.UninstallPackage(ctx, pkg); err != nil {
+		s.logger.WithError(err).Errorf("Error while uninstalling package %s-%s", pkg.Language, pkg.Version.String())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.UninstallPackageResponse{Language: pkg.Language, Version: pkg.Version.String()}, nil
+}
+
+// lookupPackage resolves a package in the repository index
+func (s *Server) lookupPackage(language, version string) (*types.Package, error) {
+	if language == "" || version == "" {
+		return nil, status.Error(codes.InvalidArgument, "language and version are required")
+	}
+
+	pkg, err := s.packageService.GetPackage(language, version)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return pkg, nil
+}
+
+// resolveJobRequest converts and validates a job request and resolves its runtime
+func (s *Server) resolveJobRequest(req *pb.ExecuteRequest) (*types.JobRequest, *types.Runtime, error) {
+	request := toJobRequest(req)
+
+	if request.Language == "" {
+		language, err := s.runtimeManager.DetectLanguage(request.Files, request.Entrypoint)
+		if err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		request.Language = language
+	}
+
+	if err := job.ValidateRequest(request); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	rt, err := s.jobManager.ResolveRuntime(request.Language, request.Version)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument,
+			fmt.Sprintf("%s-%s runtime is unknown", request.Language, request.Version))
+	}
+
+	if err := job.ValidateConstraints(request, rt); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.jobManager.ValidateEnv(request.Env); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return request, rt, nil
+}
+
+// toJobRequest converts a protobuf execute request into a job request
+func toJobRequest(req *pb.ExecuteRequest) *types.JobRequest {
+	request := &types.JobRequest{
+		Language:           req.Language,
+		Version:            req.Version,
+		Files:              make([]types.CodeFile, len(req.Files)),
+		Args:               req.Args,
+		Stdin:              req.Stdin,
+		Env:                req.Env,
+		Entrypoint:         req.Entrypoint,
+		OutputFiles:        req.OutputFiles,
+		Priority:           int(req.Priority),
+		CompileMemoryLimit: req.CompileMemoryLimit,
+		RunMemoryLimit:     req.RunMemoryLimit,
+	}
+
+	for i, file := range req.Files {
+		request.Files[i] = types.CodeFile{
+			Name:     file.Name,
+			Content:  file.Content,
+			Encoding: file.Encoding,
+		}
+	}
+
+	toIntPtr := func(v *int32) *int {
+		if v == nil {
+			return nil
+		}
+		i := int(*v)
+		return &i
+	}
+	request.CompileTimeout = toIntPtr(req.CompileTimeout)
+	request.RunTimeout = toIntPtr(req.RunTimeout)
+	request.CompileCPUTime = toIntPtr(req.CompileCpuTime)
+	request.RunCPUTime = toIntPtr(req.RunCpuTime)
+
+	return request
+}
+
+// toStageResult converts a stage result into its protobuf form
+func toStageResult(result *types.StageResult) *pb.StageResult {
+	if result == nil {
+		return nil
+	}
+
+	stage := &pb.StageResult{
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+		Output:   result.Output,
+		Signal:   result.Signal,
+		Memory:   result.Memory,
+		Message:  result.Message,
+		Status:   result.Status,
+		CpuTime:  result.CPUTime,
+		WallTime: result.WallTime,
+	}
+	if result.Code != nil {
+		code := int32(*result.Code)
+		stage.Code = &code
+	}
+	return stage
+}
+
+// toOutputFiles converts collected output files, returning their raw bytes
+func toOutputFiles(files []types.OutputFile) []*pb.OutputFile {
+	if len(files) == 0 {
+		return nil
+	}
+
+	out := make([]*pb.OutputFile, 0, len(files))
+	for _, file := range files {
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			continue
+		}
+		out = append(out, &pb.OutputFile{Name: file.Name, Content: content, Size: file.Size})
+	}
+	return out
+}
+
+// toStreamResponse converts a job stream event into the protobuf event envelope
+func toStreamResponse(event types.StreamEvent) (*pb.ExecuteStreamResponse, bool) {
+	code := int32(event.Code)
+
+	switch event.Type {
+	case "stage_start":
+		return &pb.ExecuteStreamResponse{Type: "stage_start", Stage: event.Stage}, true
+	case "stage_end":
+		return &pb.ExecuteStreamResponse{Type: "stage_end", Stage: event.Stage, Code: &code}, true
+	case "data":
+		return &pb.ExecuteStreamResponse{Type: "data", Stage: event.Stage, Stream: event.Stream, Data: event.Data}, true
+	case "exit":
+		return &pb.ExecuteStreamResponse{Type: "exit", Stage: event.Stage, Code: &code}, true
+	case "error":
+		if event.Error != nil {
+			return &pb.ExecuteStreamResponse{Type: "error", Message: event.Error.Error()}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/history"
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/types"
+)
+
+// AdminHandler serves the admin endpoints for changing settings at runtime
+type AdminHandler struct {
+	cfg            *config.Config
+	jobManager     *job.Manager
+	runtimeManager *runtime.Manager
+	logger         *logrus.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(cfg *config.Config, jobManager *job.Manager, runtimeManager *runtime.Manager,
+	logger *logrus.Logger) *AdminHandler {
+	return &AdminHandler{
+		cfg:            cfg,
+		jobManager:     jobManager,
+		runtimeManager: runtimeManager,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes registers admin routes
+func (ah *AdminHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/admin/config", ah.GetConfig)
+	r.Patch("/admin/config", ah.UpdateConfig)
+	r.Get("/history", ah.GetHistory)
+	r.Post("/history/{id}/replay", ah.ReplayHistory)
+	r.Get("/admin/fixtures", ah.ListFixtures)
+	r.Delete("/admin/fixtures/{name}", ah.DeleteFixture)
+}
+
+// Page sizes for GET /history
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// adminConfigUpdate is a partial update of the live settings; omitted fields are left unchanged
+type adminConfigUpdate struct {
+	LimitOverrides    *map[string]map[string]interface{} `json:"limit_overrides"`
+	MaxConcurrentJobs *int                               `json:"max_concurrent_jobs"`
+	DisableNetworking *bool                              `json:"disable_networking"`
+}
+
+// GetConfig returns the live settings
+func (ah *AdminHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	ah.sendJSON(w, ah.cfg.GetLiveSettings(), http.StatusOK)
+}
+
+// UpdateConfig applies a partial update to the live settings. Changed limit overrides are
+// recomputed for every loaded runtime, and a new max_concurrent_jobs resizes the job queue.
+func (ah *AdminHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var update adminConfigUpdate
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&update); err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			middleware.WriteBodyTooLarge(w)
+			return
+		}
+		ah.sendJSON(w, types.ErrorResponse{Message: "Invalid request body", Code: http.StatusBadRequest}, http.StatusBadRequest)
+		return
+	}
+
+	settings, err := ah.cfg.PatchLiveSettings(func(settings *config.LiveSettings) {
+		if update.LimitOverrides != nil {
+			settings.LimitOverrides = *update.LimitOverrides
+		}
+		if update.MaxConcurrentJobs != nil {
+			settings.MaxConcurrentJobs = *update.MaxConcurrentJobs
+		}
+		if update.DisableNetworking != nil {
+			settings.DisableNetworking = *update.DisableNetworking
+		}
+	})
+	if err != nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: err.Error(), Code: http.StatusBadRequest}, http.StatusBadRequest)
+		return
+	}
+
+	if update.MaxConcurrentJobs != nil {
+		ah.jobManager.SetMaxConcurrentJobs(settings.MaxConcurrentJobs)
+	}
+	if update.LimitOverrides != nil {
+		if err := ah.runtimeManager.LoadPackages(); err != nil {
+			ah.logger.WithError(err).Error("Failed to recompute runtime limits")
+			ah.sendJSON(w, types.ErrorResponse{Message: "Failed to recompute runtime limits", Code: http.StatusInternalServerError}, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	ah.logger.WithField("request_id", chiMiddleware.GetReqID(r.Context())).
+		Infof("Live settings updated: max_concurrent_jobs=%d disable_networking=%t limit_overrides=%d languages",
+			settings.MaxConcurrentJobs, settings.DisableNetworking, len(settings.LimitOverrides))
+
+	ah.sendJSON(w, ah.cfg.GetLiveSettings(), http.StatusOK)
+}
+
+// GetHistory lists recorded executions, newest first. Filters: language, requester,
+// metadata.<key>, since and until (RFC 3339), limit and offset.
+func (ah *AdminHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	recorder := ah.jobManager.History()
+	if recorder == nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: "Execution history is disabled", Code: http.StatusNotFound}, http.StatusNotFound)
+		return
+	}
+
+	params := r.URL.Query()
+	query := history.Query{
+		Language:  params.Get("language"),
+		Requester: params.Get("requester"),
+		Limit:     defaultHistoryLimit,
+	}
+	for name, values := range params {
+		if key, ok := strings.CutPrefix(name, "metadata."); ok && len(values) > 0 {
+			if query.Metadata == nil {
+				query.Metadata = make(map[string]string)
+			}
+			query.Metadata[key] = values[0]
+		}
+	}
+
+	var err error
+	for name, dest := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := params.Get(name); value != "" {
+			if *dest, err = time.Parse(time.RFC3339, value); err != nil {
+				ah.sendJSON(w, types.ErrorResponse{Message: "Invalid " + name + " timestamp, expected RFC 3339", Code: http.StatusBadRequest}, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	for name, dest := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		if value := params.Get(name); value != "" {
+			if *dest, err = strconv.Atoi(value); err != nil || *dest < 0 {
+				ah.sendJSON(w, types.ErrorResponse{Message: "Invalid " + name + " parameter", Code: http.StatusBadRequest}, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if query.Limit == 0 || query.Limit > maxHistoryLimit {
+		query.Limit = maxHistoryLimit
+	}
+
+	records, err := recorder.List(query)
+	if err != nil {
+		ah.logger.WithError(err).Error("Failed to list execution history")
+		ah.sendJSON(w, types.ErrorResponse{Message: "Failed to list execution history", Code: http.StatusInternalServerError}, http.StatusInternalServerError)
+		return
+	}
+
+	ah.sendJSON(w, records, http.StatusOK)
+}
+
+// historyReplayRequest selects the runtime version of a replay; empty uses the original version
+type historyReplayRequest struct {
+	Version string `json:"version"`
+}
+
+// ReplayHistory runs a recorded execution again, on the same or another runtime version, and
+// returns both outcomes side by side
+func (ah *AdminHandler) ReplayHistory(w http.ResponseWriter, r *http.Request) {
+	recorder := ah.jobManager.History()
+	if recorder == nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: "Execution history is disabled", Code: http.StatusNotFound}, http.StatusNotFound)
+		return
+	}
+
+	var body historyReplayRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		if middleware.IsBodyTooLarge(err) {
+			middleware.WriteBodyTooLarge(w)
+			return
+		}
+		ah.sendJSON(w, types.ErrorResponse{Message: "Invalid request body", Code: http.StatusBadRequest}, http.StatusBadRequest)
+		return
+	}
+
+	original, err := recorder.Get(chi.URLParam(r, "id"))
+	if errors.Is(err, history.ErrNotFound) {
+		ah.sendJSON(w, types.ErrorResponse{Message: "History record not found", Code: http.StatusNotFound}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		ah.logger.WithError(err).Error("Failed to read execution history")
+		ah.sendJSON(w, types.ErrorResponse{Message: "Failed to read execution history", Code: http.StatusInternalServerError}, http.StatusInternalServerError)
+		return
+	}
+
+	request, err := job.ReplayRequest(original, body.Version)
+	if err != nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: err.Error(), Code: http.StatusConflict}, http.StatusConflict)
+		return
+	}
+	if err := job.ValidateRequest(request); err != nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: err.Error(), Code: http.StatusBadRequest}, http.StatusBadRequest)
+		return
+	}
+	rt, err := ah.jobManager.ResolveRuntime(request.Language, request.Version)
+	if err != nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: fmt.Sprintf("%s-%s runtime is unknown", request.Language, request.Version), Code: http.StatusBadRequest}, http.StatusBadRequest)
+		return
+	}
+
+	replay, err := ah.jobManager.Replay(r.Context(), original, rt, request)
+	if errors.Is(err, job.ErrQueueFull) || errors.Is(err, job.ErrDraining) {
+		ah.sendJSON(w, types.ErrorResponse{Message: err.Error(), Code: http.StatusServiceUnavailable}, http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, job.ErrStdinUnavailable) {
+		ah.sendJSON(w, types.ErrorResponse{Message: err.Error(), Code: http.StatusBadRequest}, http.StatusBadRequest)
+		return
+	}
+	var sandboxErr *job.SandboxError
+	if errors.As(err, &sandboxErr) {
+		ah.logger.WithError(err).Error("Sandbox failed")
+		ah.sendJSON(w, SandboxErrorResponse{
+			ErrorResponse: types.ErrorResponse{Message: "The sandbox failed to run the job", Code: http.StatusServiceUnavailable},
+			Sandbox:       sandboxErr,
+		}, http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		ah.logger.WithError(err).Error("Replay execution failed")
+		ah.sendJSON(w, types.ErrorResponse{Message: "Internal server error", Code: http.StatusInternalServerError}, http.StatusInternalServerError)
+		return
+	}
+
+	ah.sendJSON(w, replay, http.StatusOK)
+}
+
+// ListFixtures lists the uploaded stdin fixtures
+func (ah *AdminHandler) ListFixtures(w http.ResponseWriter, r *http.Request) {
+	fixtures := ah.jobManager.Fixtures()
+	if fixtures == nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: "Fixtures are unavailable", Code: http.StatusNotFound}, http.StatusNotFound)
+		return
+	}
+
+	list, err := fixtures.List()
+	if err != nil {
+		ah.logger.WithError(err).Error("Failed to list fixtures")
+		ah.sendJSON(w, types.ErrorResponse{Message: "Failed to list fixtures", Code: http.StatusInternalServerError}, http.StatusInternalServerError)
+		return
+	}
+	ah.sendJSON(w, list, http.StatusOK)
+}
+
+// PutFixture stores the raw request body as the named stdin fixture, replacing any fixture of
+// that name. Requests name it in stdin_fixture.
+func (ah *AdminHandler) PutFixture(w http.ResponseWriter, r *http.Request) {
+	fixtures := ah.jobManager.Fixtures()
+	if fixtures == nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: "Fixtures are unavailable", Code: http.StatusNotFound}, http.StatusNotFound)
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if !job.ValidFixtureName(name) {
+		ah.sendJSON(w, types.ErrorResponse{Message: "Invalid fixture name", Code: http.StatusBadRequest}, http.StatusBadRequest)
+		return
+	}
+
+	// Uploads outlast the server read and write timeouts; the route timeout still bounds them
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	fixture, err := fixtures.Put(name, r.Body)
+	if err != nil {
+		if middleware.IsBodyTooLarge(err) || errors.Is(err, job.ErrFixtureTooLarge) {
+			middleware.WriteBodyTooLarge(w)
+			return
+		}
+		ah.logger.WithError(err).Error("Failed to store fixture")
+		ah.sendJSON(w, types.ErrorResponse{Message: "Failed to store fixture", Code: http.StatusInternalServerError}, http.StatusInternalServerError)
+		return
+	}
+
+	ah.logger.WithField("request_id", chiMiddleware.GetReqID(r.Context())).
+		Infof("Fixture %s stored: %d bytes", fixture.Name, fixture.Size)
+	ah.sendJSON(w, fixture, http.StatusCreated)
+}
+
+// DeleteFixture removes the named stdin fixture. Jobs already reading it are not affected.
+func (ah *AdminHandler) DeleteFixture(w http.ResponseWriter, r *http.Request) {
+	fixtures := ah.jobManager.Fixtures()
+	if fixtures == nil {
+		ah.sendJSON(w, types.ErrorResponse{Message: "Fixtures are unavailable", Code: http.StatusNotFound}, http.StatusNotFound)
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	err := fixtures.Delete(name)
+	if errors.Is(err, job.ErrFixtureNotFound) {
+		ah.sendJSON(w, types.ErrorResponse{Message: "Fixture not found", Code: http.StatusNotFound}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		ah.logger.WithError(err).Error("Failed to delete fixture")
+		ah.sendJSON(w, types.ErrorResponse{Message: "Failed to delete fixture", Code: http.StatusInternalServerError}, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendJSON sends a JSON response
+func (ah *AdminHandler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
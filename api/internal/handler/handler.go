@@ -1,16 +1,25 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/coderunr/api/internal/config"
 	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/middleware"
 	"github.com/coderunr/api/internal/runtime"
 	"github.com/coderunr/api/internal/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,22 +27,41 @@ import (
 type Handler struct {
 	jobManager     *job.Manager
 	runtimeManager *runtime.Manager
+	apiKeys        *middleware.APIKeyStore
+	upgrader       websocket.Upgrader
+	wsKeepalive    wsKeepalive
+	wsReadLimit    int64
+	wsMaxSessions  int
+	wsStreams      *wsStreams
+	health         *healthChecker
 	logger         *logrus.Logger
 }
 
 // NewHandler creates a new handler instance
-func NewHandler(jobManager *job.Manager, runtimeManager *runtime.Manager, logger *logrus.Logger) *Handler {
+func NewHandler(cfg *config.Config, jobManager *job.Manager, runtimeManager *runtime.Manager,
+	apiKeys *middleware.APIKeyStore, logger *logrus.Logger) *Handler {
 	return &Handler{
 		jobManager:     jobManager,
 		runtimeManager: runtimeManager,
-		logger:         logger,
+		apiKeys:        apiKeys,
+		upgrader:       newUpgrader(cfg.WSAllowedOrigins, cfg.WSCompression),
+		wsKeepalive: wsKeepalive{
+			pingInterval: cfg.WSPingInterval,
+			idleTimeout:  cfg.WSIdleTimeout,
+			maxDuration:  cfg.WSMaxDuration,
+		},
+		wsReadLimit:   cfg.ExecuteBodyLimit,
+		wsMaxSessions: cfg.WSMaxSessions,
+		wsStreams:     newWSStreams(cfg.WSResumeGrace, cfg.WSResumeBuffer),
+		health:        newHealthChecker(cfg),
+		logger:        logger,
 	}
 }
 
 // GetVersion returns the API version
 func (h *Handler) GetVersion(w http.ResponseWriter, r *http.Request) {
 	response := map[string]string{
-		"message": "CodeRunr v1.0.0-go",
+		"message": "CodeRunr v" + apiVersion,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -43,77 +71,138 @@ func (h *Handler) GetVersion(w http.ResponseWriter, r *http.Request) {
 
 // ExecuteCode executes code synchronously
 func (h *Handler) ExecuteCode(w http.ResponseWriter, r *http.Request) {
+	request, runtime, ok := h.parseJobRequest(r.Context(), w, r.Body)
+	if !ok {
+		return
+	}
+
+	// Fields may also be omitted with ?omit=output,compile.stdout
+	if omit := r.URL.Query().Get("omit"); omit != "" {
+		fields := strings.Split(omit, ",")
+		if err := job.ValidateOmit(fields); err != nil {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request.Omit = append(request.Omit, fields...)
+	}
+
+	// Create and execute job
+	j := h.jobManager.NewJob(r.Context(), runtime, request)
+	result, err := j.Execute(r.Context())
+	if err != nil {
+		h.sendJobError(w, err)
+		return
+	}
+
+	// Handle backward compatibility (Piston behavior)
+	if result.Run == nil && result.Compile != nil {
+		result.Run = result.Compile
+	}
+
+	response, err := job.ShapeResult(result, request.Omit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to shape execution result")
+		h.sendError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseJobRequest decodes and validates a job request and resolves its runtime.
+// On failure an error response has already been written and ok is false.
+func (h *Handler) parseJobRequest(ctx context.Context, w http.ResponseWriter, body io.Reader) (*types.JobRequest, *types.Runtime, bool) {
 	var request types.JobRequest
-	dec := json.NewDecoder(r.Body)
+	dec := json.NewDecoder(body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(&request); err != nil {
-		var mbe *http.MaxBytesError
-		if errors.As(err, &mbe) {
-			h.sendError(w, "Request body too large", http.StatusRequestEntityTooLarge)
-			return
+		if middleware.IsBodyTooLarge(err) {
+			middleware.WriteBodyTooLarge(w)
+			return nil, nil, false
 		}
 		h.sendError(w, "Invalid JSON request", http.StatusBadRequest)
-		return
+		return nil, nil, false
+	}
+
+	// Without a language, infer it from the main file
+	if request.Language == "" {
+		language, err := h.runtimeManager.DetectLanguage(request.Files, request.Entrypoint)
+		if err != nil {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		request.Language = language
 	}
 
 	// Validate request
-	if err := h.validateJobRequest(&request); err != nil {
+	if err := job.ValidateRequest(&request); err != nil {
 		h.sendError(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, nil, false
 	}
 
 	// Find runtime
-	runtime, err := runtime.GetLatestRuntimeMatchingLanguageVersion(request.Language, request.Version)
+	rt, err := h.jobManager.ResolveRuntime(request.Language, request.Version)
 	if err != nil {
 		h.sendError(w, fmt.Sprintf("%s-%s runtime is unknown", request.Language, request.Version), http.StatusBadRequest)
-		return
+		return nil, nil, false
+	}
+
+	// A profile replaces the runtime's limits, which the request may only lower
+	rt, err = h.jobManager.ApplyProfile(ctx, &request, rt)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, job.ErrProfileNotAllowed) {
+			status = http.StatusForbidden
+		}
+		h.sendError(w, err.Error(), status)
+		return nil, nil, false
 	}
 
 	// Validate runtime constraints
-	if err := h.validateConstraints(&request, runtime); err != nil {
+	if err := job.ValidateConstraints(&request, rt); err != nil {
 		h.sendError(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, nil, false
 	}
 
-	// Create and execute job
-	job := h.jobManager.NewJob(runtime, &request)
-	result, err := job.Execute(r.Context())
-	if err != nil {
-		h.logger.WithError(err).Error("Job execution failed")
-		h.sendError(w, "Internal server error", http.StatusInternalServerError)
-		return
+	// Validate environment variables
+	if err := h.jobManager.ValidateEnv(request.Env); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
 	}
 
-	// Handle backward compatibility (Piston behavior)
-	if result.Run == nil && result.Compile != nil {
-		result.Run = result.Compile
+	if err := h.jobManager.ValidateMounts(request.Mounts); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(result)
+	if err := h.jobManager.ValidateStdin(&request); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	if err := h.jobManager.ValidateFlags(&request, rt); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	// Only allowlisted callers may turn networking on
+	if err := h.jobManager.ValidateNetwork(ctx, &request); err != nil {
+		h.sendError(w, err.Error(), http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	return &request, rt, true
 }
 
 // GetRuntimes returns available runtimes
 func (h *Handler) GetRuntimes(w http.ResponseWriter, r *http.Request) {
-	runtimes := runtime.GetRuntimes()
+	runtimes := h.runtimeManager.GetRuntimes()
 
 	response := make([]types.RuntimeInfo, len(runtimes))
 	for i, rt := range runtimes {
-		runtimeName := rt.Runtime
-		if runtimeName == "" {
-			runtimeName = rt.Language
-		}
-
-		response[i] = types.RuntimeInfo{
-			Language: rt.Language,
-			Version:  rt.Version.String(),
-			Aliases:  rt.Aliases,
-			Runtime:  runtimeName,
-			Platform: rt.Platform,
-			OS:       rt.OS,
-			Arch:     rt.Arch,
-		}
+		response[i] = runtimeInfo(&rt)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -121,106 +210,100 @@ func (h *Handler) GetRuntimes(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// validateJobRequest validates the incoming job request
-func (h *Handler) validateJobRequest(request *types.JobRequest) error {
-	if request.Language == "" {
-		return fmt.Errorf("language is required as a string")
-	}
-
-	if request.Version == "" {
-		return fmt.Errorf("version is required as a string")
-	}
-
-	if len(request.Files) == 0 {
-		return fmt.Errorf("files is required as an array")
+// GetRuntime returns a runtime and its effective limits. The language may be an alias and the
+// version a semver constraint or "latest", resolved the same way as for execution.
+func (h *Handler) GetRuntime(w http.ResponseWriter, r *http.Request) {
+	rt, err := h.jobManager.ResolveRuntime(chi.URLParam(r, "language"), chi.URLParam(r, "version"))
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusNotFound)
+		return
 	}
 
-	for i, file := range request.Files {
-		if file.Content == "" {
-			return fmt.Errorf("files[%d].content is required as a string", i)
-		}
+	response := types.RuntimeDetail{
+		RuntimeInfo: runtimeInfo(rt),
+		Compiled:    rt.Compiled,
+		Limits: types.RuntimeLimits{
+			CompileTimeout:     rt.Timeouts.Compile.Milliseconds(),
+			RunTimeout:         rt.Timeouts.Run.Milliseconds(),
+			CompileCPUTime:     rt.CPUTimes.Compile.Milliseconds(),
+			RunCPUTime:         rt.CPUTimes.Run.Milliseconds(),
+			CompileMemoryLimit: rt.MemoryLimits.Compile,
+			RunMemoryLimit:     rt.MemoryLimits.Run,
+			MaxProcessCount:    rt.MaxProcessCount,
+			MaxOpenFiles:       rt.MaxOpenFiles,
+			MaxFileSize:        rt.MaxFileSize,
+			OutputMaxSize:      rt.OutputMaxSize,
+			DiskQuota:          rt.DiskQuota,
+		},
 	}
 
-	return nil
+	h.sendJSON(w, response, http.StatusOK)
 }
 
-// validateConstraints validates resource constraints against runtime limits
-func (h *Handler) validateConstraints(request *types.JobRequest, rt *types.Runtime) error {
-	// Check if files include at least one utf8 encoded file (except for 'file' language)
-	if rt.Language != "file" {
-		hasUTF8 := false
-		for _, file := range request.Files {
-			if file.Encoding == "" || file.Encoding == "utf8" {
-				hasUTF8 = true
-				break
-			}
-		}
-		if !hasUTF8 {
-			return fmt.Errorf("files must include at least one utf8 encoded file")
-		}
+// GetLanguage lists the installed versions of a language, so clients can offer a version
+// choice without filtering the whole runtime list. The language may be an alias.
+func (h *Handler) GetLanguage(w http.ResponseWriter, r *http.Request) {
+	language := chi.URLParam(r, "language")
+	runtimes := h.runtimeManager.GetLanguageRuntimes(language)
+	if len(runtimes) == 0 {
+		h.sendError(w, fmt.Sprintf("no runtime found for %s", language), http.StatusNotFound)
+		return
 	}
 
-	// Validate constraints
-	constraints := []struct {
-		name        string
-		value       *int
-		configLimit int64
-	}{
-		{"compile_timeout", request.CompileTimeout, rt.Timeouts.Compile.Milliseconds()},
-		{"run_timeout", request.RunTimeout, rt.Timeouts.Run.Milliseconds()},
-		{"compile_cpu_time", request.CompileCPUTime, rt.CPUTimes.Compile.Milliseconds()},
-		{"run_cpu_time", request.RunCPUTime, rt.CPUTimes.Run.Milliseconds()},
+	response := types.LanguageRuntimes{
+		Language: runtimes[0].Language,
+		Aliases:  []string{},
+		Versions: make([]types.LanguageVersion, len(runtimes)),
 	}
-
-	for _, constraint := range constraints {
-		if constraint.value == nil {
-			continue
-		}
-
-		if constraint.configLimit <= 0 {
-			continue
+	for i, rt := range runtimes {
+		info := runtimeInfo(&rt)
+		aliases := rt.Aliases
+		if aliases == nil {
+			aliases = []string{}
 		}
-
-		if int64(*constraint.value) > constraint.configLimit {
-			return fmt.Errorf("%s cannot exceed the configured limit of %d",
-				constraint.name, constraint.configLimit)
+		response.Versions[i] = types.LanguageVersion{
+			Version:  info.Version,
+			Runtime:  info.Runtime,
+			Aliases:  aliases,
+			Compiled: rt.Compiled,
 		}
-
-		if *constraint.value < 0 {
-			return fmt.Errorf("%s must be non-negative", constraint.name)
+		for _, alias := range rt.Aliases {
+			if !slices.Contains(response.Aliases, alias) {
+				response.Aliases = append(response.Aliases, alias)
+			}
 		}
 	}
 
-	// Validate memory constraints
-	memoryConstraints := []struct {
-		name        string
-		value       *int64
-		configLimit int64
-	}{
-		{"compile_memory_limit", request.CompileMemoryLimit, rt.MemoryLimits.Compile},
-		{"run_memory_limit", request.RunMemoryLimit, rt.MemoryLimits.Run},
-	}
-
-	for _, constraint := range memoryConstraints {
-		if constraint.value == nil {
-			continue
-		}
-
-		if constraint.configLimit <= 0 {
-			continue
-		}
+	h.sendJSON(w, response, http.StatusOK)
+}
 
-		if *constraint.value > constraint.configLimit {
-			return fmt.Errorf("%s cannot exceed the configured limit of %d",
-				constraint.name, constraint.configLimit)
-		}
+// runtimeInfo converts a runtime into its API representation
+func runtimeInfo(rt *types.Runtime) types.RuntimeInfo {
+	runtimeName := rt.Runtime
+	if runtimeName == "" {
+		runtimeName = rt.Language
+	}
 
-		if *constraint.value < 0 {
-			return fmt.Errorf("%s must be non-negative", constraint.name)
-		}
+	stages := make([]string, 0, len(rt.Stages))
+	for _, stage := range rt.Stages {
+		stages = append(stages, stage.Name)
 	}
 
-	return nil
+	return types.RuntimeInfo{
+		Language:   rt.Language,
+		Version:    rt.Version.String(),
+		Aliases:    rt.Aliases,
+		Extensions: rt.Extensions,
+		Runtime:    runtimeName,
+		Platform:   rt.Platform,
+		OS:         rt.OS,
+		Arch:       rt.Arch,
+		REPL:       rt.REPL,
+		LSP:        rt.LSP,
+		Format:     rt.Format,
+		Lint:       rt.Lint,
+		Stages:     stages,
+	}
 }
 
 // sendError sends an error response
@@ -235,6 +318,84 @@ func (h *Handler) sendError(w http.ResponseWriter, message string, statusCode in
 	json.NewEncoder(w).Encode(response)
 }
 
+// QueueFullResponse is returned with 503 when the job queue is at its maximum depth
+type QueueFullResponse struct {
+	types.ErrorResponse
+	Queue job.QueueStats `json:"queue"`
+}
+
+// sendQueueFull rejects a job because the queue is full, reporting the queue state
+func (h *Handler) sendQueueFull(w http.ResponseWriter) {
+	h.sendJSON(w, QueueFullResponse{
+		ErrorResponse: types.ErrorResponse{
+			Message: "Job queue is full, try again later",
+			Code:    http.StatusServiceUnavailable,
+		},
+		Queue: h.jobManager.QueueStats(),
+	}, http.StatusServiceUnavailable)
+}
+
+// QuotaExceededResponse is returned with 429 or 403 when a tenant is over one of its quotas
+type QuotaExceededResponse struct {
+	types.ErrorResponse
+	Quota *job.QuotaError `json:"quota,omitempty"`
+}
+
+// sendQuotaExceeded rejects a job because its tenant is over a quota, reporting the quota
+func (h *Handler) sendQuotaExceeded(w http.ResponseWriter, err *job.QuotaError) {
+	if err.Reset != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(*err.Reset).Seconds()))))
+	}
+	h.sendJSON(w, QuotaExceededResponse{
+		ErrorResponse: types.ErrorResponse{
+			Message: err.Error(),
+			Code:    err.StatusCode(),
+		},
+		Quota: err,
+	}, err.StatusCode())
+}
+
+// SandboxErrorResponse is returned with 503 when the sandbox itself failed to run a job
+type SandboxErrorResponse struct {
+	types.ErrorResponse
+	Sandbox *job.SandboxError `json:"sandbox_error"`
+}
+
+// sendSandboxError reports a sandbox failure with isolate's diagnostic. The server cannot run
+// jobs until an operator fixes the sandbox, so it is logged as an error too.
+func (h *Handler) sendSandboxError(w http.ResponseWriter, err *job.SandboxError) {
+	h.logger.WithError(err).Error("Sandbox failed")
+	h.sendJSON(w, SandboxErrorResponse{
+		ErrorResponse: types.ErrorResponse{
+			Message: "The sandbox failed to run the job",
+			Code:    http.StatusServiceUnavailable,
+		},
+		Sandbox: err,
+	}, http.StatusServiceUnavailable)
+}
+
+// sendJobError reports why a job did not run: a full queue, a tenant over quota, a draining
+// server or a failed sandbox, otherwise an internal error
+func (h *Handler) sendJobError(w http.ResponseWriter, err error) {
+	var quotaErr *job.QuotaError
+	var sandboxErr *job.SandboxError
+	switch {
+	case errors.Is(err, job.ErrQueueFull):
+		h.sendQueueFull(w)
+	case errors.As(err, &quotaErr):
+		h.sendQuotaExceeded(w, quotaErr)
+	case errors.Is(err, job.ErrDraining):
+		h.sendError(w, "Server is shutting down", http.StatusServiceUnavailable)
+	case errors.As(err, &sandboxErr):
+		h.sendSandboxError(w, sandboxErr)
+	case errors.Is(err, job.ErrStdinUnavailable):
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.logger.WithError(err).Error("Job execution failed")
+		h.sendError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
 // sendJSON sends a JSON response
 func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
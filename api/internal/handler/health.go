@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+// Component states reported by /readyz
+const (
+	CheckOK   = "ok"
+	CheckFail = "fail"
+	// CheckWarn marks a failed check that does not make the server unready
+	CheckWarn = "warn"
+)
+
+// Package repositories are probed at most this often so probes do not hammer them
+const (
+	repoCheckInterval = 30 * time.Second
+	repoCheckTimeout  = 5 * time.Second
+)
+
+// ComponentCheck is the state of one dependency
+type ComponentCheck struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Count   *int   `json:"count,omitempty"`
+}
+
+// ReadinessResponse is returned by /readyz with 200 when every required component is ok and
+// 503 otherwise
+type ReadinessResponse struct {
+	Status string                    `json:"status"`
+	Checks map[string]ComponentCheck `json:"checks"`
+}
+
+// healthChecker runs the readiness checks, caching the repository probe
+type healthChecker struct {
+	// isolatePath is empty when jobs run in another sandbox backend
+	isolatePath   string
+	dataDirectory string
+	repoURLs      []string
+	client        *http.Client
+
+	repoMutex   sync.Mutex
+	repoChecked time.Time
+	repoResult  ComponentCheck
+}
+
+func newHealthChecker(cfg *config.Config) *healthChecker {
+	c := &healthChecker{
+		dataDirectory: cfg.DataDirectory,
+		repoURLs:      cfg.RepoURLs,
+		client:        &http.Client{Timeout: repoCheckTimeout},
+	}
+	if cfg.SandboxBackend == "isolate" {
+		c.isolatePath = cfg.IsolatePath
+	}
+	return c
+}
+
+// Healthz is the liveness probe: it succeeds whenever the process can serve requests, including
+// while draining, so an orchestrator does not restart a server that is shutting down cleanly
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	h.sendJSON(w, map[string]string{"status": CheckOK}, http.StatusOK)
+}
+
+// Readyz is the readiness probe. It reports each dependency and fails while draining, when
+// isolate is missing, the data directory is not writable or no runtimes are installed.
+// An unreachable package repository is reported as a warning only.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]ComponentCheck{
+		"jobs":           h.checkJobs(),
+		"data_directory": h.health.checkDataDirectory(),
+		"runtimes":       h.checkRuntimes(),
+		"repository":     h.health.checkRepository(r.Context()),
+	}
+	if h.health.isolatePath != "" {
+		checks["isolate"] = h.health.checkIsolate()
+	}
+
+	response := ReadinessResponse{Status: CheckOK, Checks: checks}
+	statusCode := http.StatusOK
+	for _, check := range checks {
+		if check.Status == CheckFail {
+			response.Status = CheckFail
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+	h.sendJSON(w, response, statusCode)
+}
+
+// checkJobs fails once the job manager is draining for shutdown
+func (h *Handler) checkJobs() ComponentCheck {
+	if h.jobManager.Draining() {
+		return ComponentCheck{Status: CheckFail, Message: "draining"}
+	}
+	return ComponentCheck{Status: CheckOK}
+}
+
+// checkIsolate verifies the isolate binary exists and is executable
+func (c *healthChecker) checkIsolate() ComponentCheck {
+	info, err := os.Stat(c.isolatePath)
+	if err != nil {
+		return ComponentCheck{Status: CheckFail, Message: err.Error()}
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return ComponentCheck{Status: CheckFail, Message: c.isolatePath + " is not executable"}
+	}
+	return ComponentCheck{Status: CheckOK}
+}
+
+// checkDataDirectory verifies a file can be created in the data directory
+func (c *healthChecker) checkDataDirectory() ComponentCheck {
+	file, err := os.CreateTemp(c.dataDirectory, ".readyz-*")
+	if err != nil {
+		return ComponentCheck{Status: CheckFail, Message: err.Error()}
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return ComponentCheck{Status: CheckOK}
+}
+
+// checkRuntimes fails until at least one runtime is loaded
+func (h *Handler) checkRuntimes() ComponentCheck {
+	count := len(h.runtimeManager.GetRuntimes())
+	if count == 0 {
+		return ComponentCheck{Status: CheckFail, Message: "no runtimes installed", Count: &count}
+	}
+	return ComponentCheck{Status: CheckOK, Count: &count}
+}
+
+// checkRepository probes the first package repository, reusing the last result for
+// repoCheckInterval
+func (c *healthChecker) checkRepository(ctx context.Context) ComponentCheck {
+	c.repoMutex.Lock()
+	defer c.repoMutex.Unlock()
+
+	if !c.repoChecked.IsZero() && time.Since(c.repoChecked) < repoCheckInterval {
+		return c.repoResult
+	}
+
+	c.repoResult = ComponentCheck{Status: CheckOK}
+	if err := c.probeRepository(ctx); err != nil {
+		c.repoResult = ComponentCheck{Status: CheckWarn, Message: err.Error()}
+	}
+	c.repoChecked = time.Now()
+	return c.repoResult
+}
+
+// probeRepository checks that the first repository index can be opened
+func (c *healthChecker) probeRepository(ctx context.Context) error {
+	if len(c.repoURLs) == 0 {
+		return fmt.Errorf("no repository configured")
+	}
+	repoURL := c.repoURLs[0]
+
+	parsed, err := url.Parse(repoURL)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme == "file" {
+		_, err := os.Stat(parsed.Path)
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repoURL, nil)
+	if err != nil {
+		return err
+	}
+	// Only the first byte is needed to know the index is served
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%s returned status: %d", repoURL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// SubmitJob enqueues an execution and returns its job ID immediately
+func (h *Handler) SubmitJob(w http.ResponseWriter, r *http.Request) {
+	request, runtime, ok := h.parseJobRequest(r.Context(), w, r.Body)
+	if !ok {
+		return
+	}
+
+	record, err := h.jobManager.Submit(r.Context(), runtime, request)
+	if errors.Is(err, job.ErrQueueFull) {
+		h.sendQueueFull(w)
+		return
+	}
+	var quotaErr *job.QuotaError
+	if errors.As(err, &quotaErr) {
+		h.sendQuotaExceeded(w, quotaErr)
+		return
+	}
+	if errors.Is(err, job.ErrDraining) {
+		h.sendError(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to submit async job")
+		h.sendError(w, "Async jobs are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v2/jobs/"+record.ID)
+	h.sendJSON(w, record, http.StatusAccepted)
+}
+
+// GetJob returns the status and, once finished, the result of an async job
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	record, ok := h.jobManager.GetAsyncJob(id, apiKeyName(r))
+	if !ok {
+		h.sendError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	h.sendJSON(w, record, http.StatusOK)
+}
+
+// CancelJob cancels a queued or running async job, killing its sandbox
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	record, err := h.jobManager.CancelAsyncJob(id, apiKeyName(r))
+	if errors.Is(err, job.ErrJobNotFound) {
+		h.sendError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, job.ErrJobFinished) {
+		h.sendError(w, "Job has already finished", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to cancel async job")
+		h.sendError(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, record, http.StatusOK)
+}
+
+// apiKeyName returns the name of the API key that authenticated the request, or "" without one
+func apiKeyName(r *http.Request) string {
+	if key, ok := middleware.APIKeyFromContext(r.Context()); ok {
+		return key.Name
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/middleware"
+)
+
+// MetricsResponse reports internal counters of the execution engine
+type MetricsResponse struct {
+	BoxPool  job.BoxPoolStats  `json:"box_pool"`
+	WarmPool job.WarmPoolStats `json:"warm_pool"`
+	Queue    job.QueueStats    `json:"queue"`
+	Tenants  []job.TenantUsage `json:"tenants,omitempty"`
+	// Metadata counts executions by the values of metrics_metadata_keys
+	Metadata []job.MetadataUsage `json:"metadata,omitempty"`
+}
+
+// GetMetrics returns execution engine metrics
+func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	h.sendJSON(w, MetricsResponse{
+		BoxPool:  h.jobManager.PoolStats(),
+		WarmPool: h.jobManager.WarmPoolStats(),
+		Queue:    h.jobManager.QueueStats(),
+		Tenants:  h.jobManager.TenantUsage(),
+		Metadata: h.jobManager.MetadataUsage(),
+	}, http.StatusOK)
+}
+
+// GetUsage returns the usage and quotas of the caller's tenant only
+func (h *Handler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	key, ok := middleware.APIKeyFromContext(r.Context())
+	if !ok {
+		h.sendError(w, "Usage requires an API key", http.StatusNotFound)
+		return
+	}
+
+	h.sendJSON(w, h.jobManager.TenantUsageOf(key.TenantName()), http.StatusOK)
+}
+
+// GetRuntimeStats returns execution counts, average duration and last use of every runtime
+// since startup
+func (h *Handler) GetRuntimeStats(w http.ResponseWriter, r *http.Request) {
+	h.sendJSON(w, h.jobManager.RuntimeUsage(), http.StatusOK)
+}
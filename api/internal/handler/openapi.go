@@ -0,0 +1,460 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/history"
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/openapi"
+	"github.com/coderunr/api/internal/types"
+)
+
+// apiVersion is reported by GET / and in the OpenAPI document
+const apiVersion = "1.0.0-go"
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  *openapi.Document
+)
+
+// GetOpenAPI serves the OpenAPI document describing the HTTP and WebSocket API
+func (h *Handler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIDoc = buildOpenAPI()
+	})
+	h.sendJSON(w, openAPIDoc, http.StatusOK)
+}
+
+// buildOpenAPI describes every route; schemas are derived from the types the handlers encode
+func buildOpenAPI() *openapi.Document {
+	g := openapi.NewGenerator()
+	g.Enum(types.StageOutcome(""), string(types.OutcomeOK), string(types.OutcomeRuntimeError),
+		string(types.OutcomeTimeout), string(types.OutcomeMemoryLimit), string(types.OutcomeOutputLimit),
+		string(types.OutcomeDiskLimit), string(types.OutcomeSandboxError))
+	g.Enum(types.Verdict(""), string(types.VerdictAccepted), string(types.VerdictWrongAnswer),
+		string(types.VerdictTimeLimit), string(types.VerdictMemoryLimit), string(types.VerdictRuntimeError),
+		string(types.VerdictOutputLimit), string(types.VerdictCompileError))
+	g.Enum(types.AsyncJobStatus(""), string(types.AsyncJobQueued), string(types.AsyncJobRunning),
+		string(types.AsyncJobFinished), string(types.AsyncJobExpired))
+
+	errorSchema := g.Ref(types.ErrorResponse{})
+	jobRequest := g.Ref(types.JobRequest{})
+	packageBody := g.Ref(types.PackageRequest{})
+	apiKey := []openapi.SecurityRequirement{{"bearerAuth": {}}, {"apiKeyHeader": {}}}
+	admin := []openapi.SecurityRequirement{{"adminToken": {}}}
+
+	errorResponse := func(description string) *openapi.Response {
+		return &openapi.Response{Description: description, Content: openapi.JSON(errorSchema)}
+	}
+	ok := func(description string, schema *openapi.Schema) *openapi.Response {
+		return &openapi.Response{Description: description, Content: openapi.JSON(schema)}
+	}
+	eventStream := func(description string) *openapi.Response {
+		return &openapi.Response{Description: description, Content: map[string]*openapi.MediaType{
+			"text/event-stream": {Schema: &openapi.Schema{Type: "string"}},
+		}}
+	}
+	// Responses shared by the execution endpoints
+	execErrors := func(responses map[string]*openapi.Response) map[string]*openapi.Response {
+		responses["400"] = errorResponse("Invalid request, unknown runtime or unknown profile")
+		responses["401"] = errorResponse("Missing or invalid API key")
+		responses["403"] = ok("Networking or a profile requested by a caller that is not allowed to use it, or the tenant's daily quota is used up", g.Ref(QuotaExceededResponse{}))
+		responses["413"] = errorResponse("Request body too large")
+		responses["429"] = ok("Rate limit or tenant concurrency quota exceeded; see Retry-After", g.Ref(QuotaExceededResponse{}))
+		responses["503"] = ok("Job queue is full or the server is shutting down; from /execute also when the sandbox failed, with sandbox_error in place of queue", g.Ref(QueueFullResponse{}))
+		return responses
+	}
+	jsonBody := func(schema *openapi.Schema) *openapi.RequestBody {
+		return &openapi.RequestBody{Required: true, Content: openapi.JSON(schema)}
+	}
+
+	paths := map[string]*openapi.PathItem{
+		"/": {Get: &openapi.Operation{
+			OperationID: "getVersion",
+			Summary:     "API version",
+			Responses: map[string]*openapi.Response{
+				"200": ok("Version banner", &openapi.Schema{Type: "object", AdditionalProperties: &openapi.Schema{Type: "string"}}),
+			},
+		}},
+		"/health": {Get: &openapi.Operation{
+			OperationID: "getHealth",
+			Summary:     "Health check",
+			Responses: map[string]*openapi.Response{
+				"200": {Description: "Serving"},
+				"503": {Description: "Draining before shutdown"},
+			},
+		}},
+		"/healthz": {Get: &openapi.Operation{
+			OperationID: "getHealthz",
+			Summary:     "Liveness probe",
+			Responses:   map[string]*openapi.Response{"200": {Description: "The process is serving"}},
+		}},
+		"/readyz": {Get: &openapi.Operation{
+			OperationID: "getReadyz",
+			Summary:     "Readiness probe with per-component checks",
+			Responses: map[string]*openapi.Response{
+				"200": ok("Ready", g.Ref(ReadinessResponse{})),
+				"503": ok("Draining or a required component failed", g.Ref(ReadinessResponse{})),
+			},
+		}},
+		"/api/v2/openapi.json": {Get: &openapi.Operation{
+			OperationID: "getOpenAPI",
+			Summary:     "This document",
+			Responses:   map[string]*openapi.Response{"200": ok("OpenAPI document", &openapi.Schema{Type: "object"})},
+		}},
+		"/api/v2/execute": {Post: &openapi.Operation{
+			OperationID: "execute",
+			Summary:     "Run code and wait for the result",
+			Tags:        []string{"execute"},
+			Parameters: []openapi.Parameter{
+				openapi.Query("omit", "string", "Comma-separated result fields to strip, e.g. output,compile.stdout"),
+			},
+			RequestBody: jsonBody(jobRequest),
+			Responses:   execErrors(map[string]*openapi.Response{"200": ok("Execution result", g.Ref(types.ExecutionResult{}))}),
+			Security:    apiKey,
+		}},
+		"/api/v2/execute/stream": {
+			Get: &openapi.Operation{
+				OperationID: "executeStreamGet",
+				Summary:     "Run code and stream events over SSE (EventSource variant)",
+				Description: "Events: runtime, stage_start, data, stage_end, error, done.",
+				Tags:        []string{"execute"},
+				Parameters: []openapi.Parameter{
+					openapi.Query("request", "string", "URL-encoded JobRequest JSON"),
+				},
+				Responses: execErrors(map[string]*openapi.Response{"200": eventStream("Execution events")}),
+				Security:  apiKey,
+			},
+			Post: &openapi.Operation{
+				OperationID: "executeStream",
+				Summary:     "Run code and stream events over SSE",
+				Description: "Events: runtime, stage_start, data, stage_end, error, done.",
+				Tags:        []string{"execute"},
+				RequestBody: jsonBody(jobRequest),
+				Responses:   execErrors(map[string]*openapi.Response{"200": eventStream("Execution events")}),
+				Security:    apiKey,
+			},
+		},
+		"/api/v2/jobs": {Post: &openapi.Operation{
+			OperationID: "submitJob",
+			Summary:     "Queue code for asynchronous execution",
+			Tags:        []string{"jobs"},
+			RequestBody: jsonBody(jobRequest),
+			Responses:   execErrors(map[string]*openapi.Response{"202": ok("Job accepted; see Location", g.Ref(types.AsyncJob{}))}),
+			Security:    apiKey,
+		}},
+		"/api/v2/jobs/{id}": {Get: &openapi.Operation{
+			OperationID: "getJob",
+			Summary:     "Status and result of an asynchronous job",
+			Tags:        []string{"jobs"},
+			Parameters:  []openapi.Parameter{openapi.Path("id", "Job ID")},
+			Responses: map[string]*openapi.Response{
+				"200": ok("Job state", g.Ref(types.AsyncJob{})),
+				"401": errorResponse("Missing or invalid API key"),
+				"404": errorResponse("Unknown or expired job"),
+			},
+			Security: apiKey,
+		}, Delete: &openapi.Operation{
+			OperationID: "cancelJob",
+			Summary:     "Cancel a queued or running asynchronous job",
+			Description: "A queued job is removed from the queue; a running job's sandbox is killed with SIGKILL. " +
+				"The job is returned with status cancelled.",
+			Tags:       []string{"jobs"},
+			Parameters: []openapi.Parameter{openapi.Path("id", "Job ID")},
+			Responses: map[string]*openapi.Response{
+				"200": ok("Cancelled job", g.Ref(types.AsyncJob{})),
+				"401": errorResponse("Missing or invalid API key"),
+				"404": errorResponse("Unknown or expired job"),
+				"409": errorResponse("Job has already finished"),
+			},
+			Security: apiKey,
+		}},
+		"/api/v2/format": {Post: &openapi.Operation{
+			OperationID: "format",
+			Summary:     "Format files with the runtime's formatter",
+			Description: "Runs the format script of a runtime with the format capability on the files, in the " +
+				"sandbox with the compile limits. files is left out when the formatter failed; format has its output.",
+			Tags:        []string{"execute"},
+			RequestBody: jsonBody(g.Ref(types.ToolRequest{})),
+			Responses:   execErrors(map[string]*openapi.Response{"200": ok("Formatted files", g.Ref(types.FormatResult{}))}),
+			Security:    apiKey,
+		}},
+		"/api/v2/lint": {Post: &openapi.Operation{
+			OperationID: "lint",
+			Summary:     "Check files with the runtime's linter",
+			Description: "Runs the lint script of a runtime with the lint capability on the files, in the sandbox " +
+				"with the compile limits, and parses file:line[:column]: [severity:] message lines from its output.",
+			Tags:        []string{"execute"},
+			RequestBody: jsonBody(g.Ref(types.ToolRequest{})),
+			Responses:   execErrors(map[string]*openapi.Response{"200": ok("Diagnostics", g.Ref(types.LintResult{}))}),
+			Security:    apiKey,
+		}},
+		"/api/v2/connect": {Get: &openapi.Operation{
+			OperationID: "connect",
+			Summary:     "Interactive execution over WebSocket",
+			Description: "Upgrade to a WebSocket. Every text frame is a WebSocketMessage: the client sends " +
+				"init (payload: JobRequest), session or lsp, then data (stdin), signal and resize; the server sends " +
+				"init_ack, runtime, stage_start, data, stage_end, exit and error. With binary negotiated, stdio " +
+				"travels in binary frames prefixed by a stream byte (0 stdin, 1 stdout, 2 stderr). In an lsp " +
+				"session, LSP JSON-RPC messages travel unwrapped as text frames of their own. Messages naming a " +
+				"session_id run several jobs over one connection; each such job ends with session_end. A job " +
+				"started with resumable gets a stream_token on init_ack; after a disconnect, resume (stream_token, " +
+				"seq) on a new connection replays the messages after seq and continues.",
+			Tags: []string{"execute"},
+			Responses: map[string]*openapi.Response{
+				"101": ok("Switching to WebSocket; messages use this schema", g.Ref(types.WebSocketMessage{})),
+				"401": errorResponse("Missing or invalid API key"),
+				"429": errorResponse("Rate limit exceeded; see Retry-After"),
+			},
+			Security: apiKey,
+		}},
+		"/api/v2/runtimes": {Get: &openapi.Operation{
+			OperationID: "listRuntimes",
+			Summary:     "Installed runtimes",
+			Tags:        []string{"runtimes"},
+			Responses:   map[string]*openapi.Response{"200": ok("Runtimes", g.ArrayOf(types.RuntimeInfo{}))},
+		}},
+		"/api/v2/runtimes/stats": {Get: &openapi.Operation{
+			OperationID: "getRuntimeStats",
+			Summary:     "Executions per runtime since startup",
+			Description: "Every installed runtime, including unused ones, and uninstalled runtimes that ran " +
+				"jobs since startup, sorted by language and newest version first.",
+			Tags:      []string{"runtimes"},
+			Responses: map[string]*openapi.Response{"200": ok("Runtime usage", g.ArrayOf(job.RuntimeUsage{}))},
+		}},
+		"/api/v2/runtimes/{language}": {Get: &openapi.Operation{
+			OperationID: "getLanguage",
+			Summary:     "Installed versions of a language",
+			Tags:        []string{"runtimes"},
+			Parameters:  []openapi.Parameter{openapi.Path("language", "Language or alias")},
+			Responses: map[string]*openapi.Response{
+				"200": ok("Versions, newest first", g.Ref(types.LanguageRuntimes{})),
+				"404": errorResponse("No runtime of the language"),
+			},
+		}},
+		"/api/v2/runtimes/{language}/{version}": {Get: &openapi.Operation{
+			OperationID: "getRuntime",
+			Summary:     "A runtime and its effective limits",
+			Tags:        []string{"runtimes"},
+			Parameters: []openapi.Parameter{
+				openapi.Path("language", "Language or alias"),
+				openapi.Path("version", "Version or semver constraint"),
+			},
+			Responses: map[string]*openapi.Response{
+				"200": ok("Runtime", g.Ref(types.RuntimeDetail{})),
+				"404": errorResponse("No matching runtime"),
+			},
+		}},
+		"/api/v2/usage": {Get: &openapi.Operation{
+			OperationID: "getUsage",
+			Summary:     "Usage and quotas of the caller's tenant",
+			Responses: map[string]*openapi.Response{
+				"200": ok("Tenant usage", g.Ref(job.TenantUsage{})),
+				"401": errorResponse("Missing or invalid API key"),
+				"404": errorResponse("The caller has no tenant"),
+			},
+			Security: apiKey,
+		}},
+		"/api/v2/metrics": {Get: &openapi.Operation{
+			OperationID: "getMetrics",
+			Summary:     "Execution engine counters",
+			Responses:   map[string]*openapi.Response{"200": ok("Metrics", g.Ref(MetricsResponse{}))},
+		}},
+		"/api/v2/packages": {
+			Get: &openapi.Operation{
+				OperationID: "listPackages",
+				Summary:     "Packages in the repository index",
+				Tags:        []string{"packages"},
+				Parameters: []openapi.Parameter{
+					openapi.Query("refresh", "boolean", "Revalidate cached repository indexes before listing"),
+				},
+				Responses: map[string]*openapi.Response{
+					"200": ok("Packages", g.ArrayOf(types.PackageInfo{})),
+					"500": errorResponse("Index unavailable"),
+				},
+			},
+			Post: &openapi.Operation{
+				OperationID: "installPackage",
+				Summary:     "Install a package",
+				Tags:        []string{"packages"},
+				RequestBody: jsonBody(packageBody),
+				Responses: map[string]*openapi.Response{
+					"201": ok("Installed", packageBody),
+					"400": errorResponse("Invalid request"),
+					"404": errorResponse("Unknown package"),
+					"500": errorResponse("Install failed"),
+					"507": errorResponse("Not enough disk space, or max_packages_disk_usage reached with nothing left to evict"),
+				},
+			},
+			Delete: &openapi.Operation{
+				OperationID: "uninstallPackage",
+				Summary:     "Uninstall a package",
+				Tags:        []string{"packages"},
+				RequestBody: jsonBody(packageBody),
+				Responses: map[string]*openapi.Response{
+					"204": {Description: "Uninstalled"},
+					"400": errorResponse("Invalid request"),
+					"404": errorResponse("Unknown package"),
+					"409": errorResponse("The package is being installed"),
+					"500": errorResponse("Uninstall failed"),
+				},
+			},
+		},
+		"/api/v2/packages/stream": {Post: &openapi.Operation{
+			OperationID: "installPackageStream",
+			Summary:     "Install a package, streaming progress over SSE",
+			Description: "Events: progress (InstallProgress), then installed or error.",
+			Tags:        []string{"packages"},
+			RequestBody: jsonBody(packageBody),
+			Responses: map[string]*openapi.Response{
+				"200": eventStream("Install progress"),
+				"400": errorResponse("Invalid request"),
+				"404": errorResponse("Unknown package"),
+			},
+		}},
+		"/api/v2/packages/bulk": {Post: &openapi.Operation{
+			OperationID: "installPackagesBulk",
+			Summary:     "Install several packages concurrently",
+			Tags:        []string{"packages"},
+			RequestBody: jsonBody(g.ArrayOf(types.PackageRequest{})),
+			Responses: map[string]*openapi.Response{
+				"200": ok("Every package installed or already installed", g.Ref(types.BulkPackageResponse{})),
+				"207": ok("Some packages failed or were not found", g.Ref(types.BulkPackageResponse{})),
+				"400": errorResponse("Invalid request"),
+				"500": errorResponse("Index unavailable"),
+			},
+		}},
+		"/api/v2/packages/local": {Post: &openapi.Operation{
+			OperationID: "installLocalPackage",
+			Summary:     "Install an uploaded package tarball",
+			Description: "The language and version are read from the archive's pkg-info.json.",
+			Tags:        []string{"packages"},
+			RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{
+				"multipart/form-data": {Schema: &openapi.Schema{
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"package":  {Type: "string", Format: "binary", Description: "Package tarball (.pkg.tar.gz)"},
+						"checksum": {Type: "string", Description: "SHA256 of the tarball, hex-encoded"},
+					},
+					Required: []string{"package", "checksum"},
+				}},
+			}},
+			Responses: map[string]*openapi.Response{
+				"201": ok("Installed", packageBody),
+				"400": errorResponse("Invalid upload, checksum mismatch or missing pkg-info.json"),
+				"401": errorResponse("Missing or invalid admin token"),
+				"409": errorResponse("The package is installed or being installed"),
+				"413": errorResponse("Upload too large"),
+				"500": errorResponse("Install failed"),
+				"507": errorResponse("Not enough disk space, or max_packages_disk_usage reached with nothing left to evict"),
+			},
+			Security: admin,
+		}},
+		"/api/v2/admin/config": {
+			Get: &openapi.Operation{
+				OperationID: "getLiveSettings",
+				Summary:     "Live settings",
+				Tags:        []string{"admin"},
+				Responses: map[string]*openapi.Response{
+					"200": ok("Settings", g.Ref(config.LiveSettings{})),
+					"401": errorResponse("Missing or invalid admin token"),
+				},
+				Security: admin,
+			},
+			Patch: &openapi.Operation{
+				OperationID: "updateLiveSettings",
+				Summary:     "Change live settings",
+				Tags:        []string{"admin"},
+				RequestBody: jsonBody(g.Named("LiveSettingsUpdate", adminConfigUpdate{})),
+				Responses: map[string]*openapi.Response{
+					"200": ok("Updated settings", g.Ref(config.LiveSettings{})),
+					"400": errorResponse("Invalid settings"),
+					"401": errorResponse("Missing or invalid admin token"),
+				},
+				Security: admin,
+			},
+		},
+		"/api/v2/history": {Get: &openapi.Operation{
+			OperationID: "listHistory",
+			Summary:     "Recorded executions, newest first",
+			Tags:        []string{"admin"},
+			Parameters: []openapi.Parameter{
+				openapi.Query("language", "string", "Only this language"),
+				openapi.Query("requester", "string", "Only this API key name"),
+				openapi.Query("since", "string", "RFC 3339 start time"),
+				openapi.Query("until", "string", "RFC 3339 end time"),
+				openapi.Query("limit", "integer", "Page size (default 100, max 1000)"),
+				openapi.Query("offset", "integer", "Records to skip"),
+			},
+			Responses: map[string]*openapi.Response{
+				"200": ok("Records", &openapi.Schema{Type: "array", Items: g.Named("HistoryRecord", history.Record{})}),
+				"400": errorResponse("Invalid filter"),
+				"401": errorResponse("Missing or invalid admin token"),
+				"404": errorResponse("History is disabled"),
+			},
+			Security: admin,
+		}},
+		"/api/v2/admin/fixtures": {Get: &openapi.Operation{
+			OperationID: "listFixtures",
+			Summary:     "Uploaded stdin fixtures",
+			Tags:        []string{"admin"},
+			Responses: map[string]*openapi.Response{
+				"200": ok("Fixtures, by name", &openapi.Schema{Type: "array", Items: g.Ref(types.Fixture{})}),
+				"401": errorResponse("Missing or invalid admin token"),
+				"404": errorResponse("Fixtures are unavailable"),
+			},
+			Security: admin,
+		}},
+		"/api/v2/admin/fixtures/{name}": {
+			Put: &openapi.Operation{
+				OperationID: "putFixture",
+				Summary:     "Upload a stdin fixture",
+				Description: "The raw body, up to stdin_max_size, replaces any fixture of that name. Requests read it as stdin by naming it in stdin_fixture.",
+				Tags:        []string{"admin"},
+				Parameters:  []openapi.Parameter{openapi.Path("name", "Fixture name: letters, digits, '.', '_' and '-', not starting with a punctuation character")},
+				RequestBody: &openapi.RequestBody{Required: true, Content: map[string]*openapi.MediaType{
+					"application/octet-stream": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+				}},
+				Responses: map[string]*openapi.Response{
+					"201": ok("Stored", g.Ref(types.Fixture{})),
+					"400": errorResponse("Invalid fixture name"),
+					"401": errorResponse("Missing or invalid admin token"),
+					"413": errorResponse("Fixture larger than stdin_max_size"),
+				},
+				Security: admin,
+			},
+			Delete: &openapi.Operation{
+				OperationID: "deleteFixture",
+				Summary:     "Delete a stdin fixture",
+				Tags:        []string{"admin"},
+				Parameters:  []openapi.Parameter{openapi.Path("name", "Fixture name")},
+				Responses: map[string]*openapi.Response{
+					"204": {Description: "Deleted"},
+					"401": errorResponse("Missing or invalid admin token"),
+					"404": errorResponse("Fixture not found"),
+				},
+				Security: admin,
+			},
+		},
+	}
+
+	return &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "CodeRunr API",
+			Version:     apiVersion,
+			Description: "Sandboxed code execution compatible with the Piston v2 API.",
+		},
+		Paths: paths,
+		Components: openapi.Components{
+			Schemas: g.Schemas(),
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				"bearerAuth":   {Type: "http", Scheme: "bearer"},
+				"apiKeyHeader": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				"adminToken":   {Type: "apiKey", In: "header", Name: "X-Admin-Token"},
+			},
+		},
+	}
+}
@@ -3,11 +3,14 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 
+	"github.com/coderunr/api/internal/middleware"
 	"github.com/coderunr/api/internal/service"
 	"github.com/coderunr/api/internal/types"
 )
@@ -30,14 +33,20 @@ func NewPackageHandler(packageService *service.PackageService, logger *logrus.Lo
 func (ph *PackageHandler) RegisterRoutes(r chi.Router) {
 	r.Get("/packages", ph.GetPackages)
 	r.Post("/packages", ph.InstallPackage)
+	r.Post("/packages/stream", ph.InstallPackageStream)
+	r.Post("/packages/bulk", ph.InstallPackagesBulk)
 	r.Delete("/packages", ph.UninstallPackage)
 }
 
-// GetPackages returns a list of all available packages
+// GetPackages returns a list of all available packages; ?refresh=true revalidates cached indexes
 func (ph *PackageHandler) GetPackages(w http.ResponseWriter, r *http.Request) {
 	ph.logger.Debug("Request to list packages")
 
-	packages, err := ph.packageService.GetPackageList()
+	list := ph.packageService.GetPackageList
+	if r.URL.Query().Get("refresh") == "true" {
+		list = ph.packageService.RefreshPackageList
+	}
+	packages, err := list()
 	if err != nil {
 		ph.logger.Errorf("Failed to get package list: %v", err)
 		w.Header().Set("Content-Type", "application/json")
@@ -70,101 +79,236 @@ func (ph *PackageHandler) GetPackages(w http.ResponseWriter, r *http.Request) {
 func (ph *PackageHandler) InstallPackage(w http.ResponseWriter, r *http.Request) {
 	ph.logger.Debug("Request to install package")
 
-	var req struct {
-		Language string `json:"language"`
-		Version  string `json:"version"`
+	pkg, ok := ph.resolvePackage(w, r)
+	if !ok {
+		return
+	}
+
+	if err := ph.packageService.InstallPackage(r.Context(), pkg, nil); err != nil {
+		ph.logger.Errorf("Error while installing package %s-%s: %v", pkg.Language, pkg.Version.String(), err)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInsufficientDiskSpace) {
+			statusCode = http.StatusInsufficientStorage
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	response := map[string]string{
+		"language": pkg.Language,
+		"version":  pkg.Version.String(),
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		ph.logger.Errorf("Failed to encode response: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Failed to encode response"})
+		return
+	}
+}
+
+// UninstallPackage uninstalls a specific package
+func (ph *PackageHandler) UninstallPackage(w http.ResponseWriter, r *http.Request) {
+	ph.logger.Debug("Request to uninstall package")
+
+	pkg, ok := ph.resolvePackage(w, r)
+	if !ok {
+		return
+	}
+
+	if err := ph.packageService.UninstallPackage(r.Context(), pkg); err != nil {
+		ph.logger.Errorf("Error while uninstalling package %s-%s: %v", pkg.Language, pkg.Version.String(), err)
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInstallInProgress) {
+			statusCode = http.StatusConflict
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	// No Content as per alignment
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxBulkPackages caps the number of entries in one bulk install request
+const maxBulkPackages = 64
+
+// InstallPackagesBulk installs a list of packages concurrently and reports each entry's status.
+// It answers 200 when every entry is installed, and 207 when any failed or was not found.
+func (ph *PackageHandler) InstallPackagesBulk(w http.ResponseWriter, r *http.Request) {
+	ph.logger.Debug("Request to install packages in bulk")
+
+	var requests []types.PackageRequest
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
-	if err := dec.Decode(&req); err != nil {
-		var mbe *http.MaxBytesError
-		if errors.As(err, &mbe) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusRequestEntityTooLarge)
-			_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Request body too large"})
+	if err := dec.Decode(&requests); err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			middleware.WriteBodyTooLarge(w)
 			return
 		}
 		ph.logger.Errorf("Invalid request body: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Invalid request body"})
+		ph.sendJSON(w, types.ErrorResponse{Message: "Invalid request body, expected an array of {language, version}"}, http.StatusBadRequest)
 		return
 	}
 
-	if req.Language == "" || req.Version == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Language and version are required"})
+	if len(requests) == 0 || len(requests) > maxBulkPackages {
+		ph.sendJSON(w, types.ErrorResponse{Message: fmt.Sprintf("Between 1 and %d packages are required", maxBulkPackages)}, http.StatusBadRequest)
 		return
 	}
+	for i, req := range requests {
+		if req.Language == "" || req.Version == "" {
+			ph.sendJSON(w, types.ErrorResponse{Message: fmt.Sprintf("Language and version are required (entry %d)", i)}, http.StatusBadRequest)
+			return
+		}
+	}
 
-	pkg, err := ph.packageService.GetPackage(req.Language, req.Version)
+	results, err := ph.packageService.InstallPackages(r.Context(), requests)
 	if err != nil {
-		ph.logger.Errorf("Package not found: %v", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": err.Error(),
-		})
+		ph.logger.Errorf("Failed to get package list: %v", err)
+		ph.sendJSON(w, types.ErrorResponse{Message: "Failed to get package list"}, http.StatusInternalServerError)
 		return
 	}
 
-	if err := ph.packageService.InstallPackage(pkg); err != nil {
-		ph.logger.Errorf("Error while installing package %s-%s: %v", pkg.Language, pkg.Version.String(), err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: err.Error()})
+	statusCode := http.StatusOK
+	for _, result := range results {
+		if result.Status == types.BulkPackageFailed || result.Status == types.BulkPackageNotFound {
+			ph.logger.Errorf("Bulk install of %s-%s %s: %s", result.Language, result.Version, result.Status, result.Error)
+			statusCode = http.StatusMultiStatus
+		}
+	}
+	ph.sendJSON(w, types.BulkPackageResponse{Results: results}, statusCode)
+}
+
+// localPackageMemory is how much of an uploaded package is held in memory before spilling to disk
+const localPackageMemory = 32 << 20
+
+// InstallLocalPackage installs a package tarball uploaded as multipart/form-data: the archive in
+// the "package" file field and its SHA256 in "checksum"
+func (ph *PackageHandler) InstallLocalPackage(w http.ResponseWriter, r *http.Request) {
+	ph.logger.Debug("Request to install uploaded package")
+
+	// Uploads outlast the server read and write timeouts; the route timeout still bounds them
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	if err := r.ParseMultipartForm(localPackageMemory); err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			middleware.WriteBodyTooLarge(w)
+			return
+		}
+		ph.logger.Errorf("Invalid upload: %v", err)
+		ph.sendJSON(w, types.ErrorResponse{Message: "Expected a multipart/form-data body"}, http.StatusBadRequest)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
-	response := map[string]string{
+	checksum := r.FormValue("checksum")
+	file, _, err := r.FormFile("package")
+	if checksum == "" || err != nil {
+		ph.sendJSON(w, types.ErrorResponse{Message: "A package file and its checksum are required"}, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	pkg, err := ph.packageService.InstallLocalPackage(r.Context(), file, checksum, nil)
+	if err != nil {
+		ph.logger.Errorf("Error while installing uploaded package: %v", err)
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidPackage):
+			statusCode = http.StatusBadRequest
+		case errors.Is(err, service.ErrPackageInstalled), errors.Is(err, service.ErrInstallInProgress):
+			statusCode = http.StatusConflict
+		case errors.Is(err, service.ErrInsufficientDiskSpace):
+			statusCode = http.StatusInsufficientStorage
+		}
+		ph.sendJSON(w, types.ErrorResponse{Message: err.Error()}, statusCode)
+		return
+	}
+
+	ph.sendJSON(w, map[string]string{
 		"language": pkg.Language,
 		"version":  pkg.Version.String(),
-	}
+	}, http.StatusCreated)
+}
 
+// sendJSON writes data as a JSON response
+func (ph *PackageHandler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		ph.logger.Errorf("Failed to encode response: %v", err)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// InstallPackageStream installs a package and streams its progress over SSE: "progress" events
+// carry the phase and downloaded bytes, followed by "installed" or "error"
+func (ph *PackageHandler) InstallPackageStream(w http.ResponseWriter, r *http.Request) {
+	ph.logger.Debug("Request to install package with progress")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Failed to encode response"})
+		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Streaming is not supported"})
 		return
 	}
-}
 
-// UninstallPackage uninstalls a specific package
-func (ph *PackageHandler) UninstallPackage(w http.ResponseWriter, r *http.Request) {
-	ph.logger.Debug("Request to uninstall package")
+	pkg, ok := ph.resolvePackage(w, r)
+	if !ok {
+		return
+	}
+
+	// Installs outlast the server write timeout; the route timeout still bounds them
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
 
-	var req struct {
-		Language string `json:"language"`
-		Version  string `json:"version"`
+	enc := newSSEEncoder(w, flusher)
+	progress := func(p types.InstallProgress) {
+		_ = enc.Encode("progress", p)
 	}
 
+	if err := ph.packageService.InstallPackage(r.Context(), pkg, progress); err != nil {
+		ph.logger.Errorf("Error while installing package %s-%s: %v", pkg.Language, pkg.Version.String(), err)
+		_ = enc.Encode("error", types.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	_ = enc.Encode("installed", map[string]string{
+		"language": pkg.Language,
+		"version":  pkg.Version.String(),
+	})
+}
+
+// resolvePackage decodes a {language, version} request body and looks the package up in the
+// repository index, writing the error response itself when that fails
+func (ph *PackageHandler) resolvePackage(w http.ResponseWriter, r *http.Request) (*types.Package, bool) {
+	var req types.PackageRequest
+
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(&req); err != nil {
-		var mbe *http.MaxBytesError
-		if errors.As(err, &mbe) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusRequestEntityTooLarge)
-			_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Request body too large"})
-			return
+		if middleware.IsBodyTooLarge(err) {
+			middleware.WriteBodyTooLarge(w)
+			return nil, false
 		}
 		ph.logger.Errorf("Invalid request body: %v", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Invalid request body"})
-		return
+		return nil, false
 	}
 
 	if req.Language == "" || req.Version == "" {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: "Language and version are required"})
-		return
+		return nil, false
 	}
 
 	pkg, err := ph.packageService.GetPackage(req.Language, req.Version)
@@ -172,20 +316,9 @@ func (ph *PackageHandler) UninstallPackage(w http.ResponseWriter, r *http.Reques
 		ph.logger.Errorf("Package not found: %v", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": err.Error(),
-		})
-		return
-	}
-
-	if err := ph.packageService.UninstallPackage(pkg); err != nil {
-		ph.logger.Errorf("Error while uninstalling package %s-%s: %v", pkg.Language, pkg.Version.String(), err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(types.ErrorResponse{Message: err.Error()})
-		return
+		return nil, false
 	}
 
-	// No Content as per alignment
-	w.WriteHeader(http.StatusNoContent)
+	return pkg, true
 }
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+	"github.com/google/uuid"
+)
+
+// wsStreams holds the resumable sessions by stream token. A resumable session outlives its
+// connection by the grace period, buffering its latest messages until a client resumes it.
+type wsStreams struct {
+	mutex    sync.Mutex
+	sessions map[string]*wsSession
+	// grace is how long a session waits for its client; 0 disables resuming
+	grace time.Duration
+	// bufferSize is the number of messages kept for a client to catch up on
+	bufferSize int
+}
+
+// newWSStreams creates an empty stream registry
+func newWSStreams(grace time.Duration, bufferSize int) *wsStreams {
+	return &wsStreams{
+		sessions:   make(map[string]*wsSession),
+		grace:      grace,
+		bufferSize: bufferSize,
+	}
+}
+
+// enabled reports whether jobs may be made resumable
+func (s *wsStreams) enabled() bool {
+	return s.grace > 0
+}
+
+// register makes a session resumable, returning its stream token
+func (s *wsStreams) register(session *wsSession, apiKey string) string {
+	token := uuid.New().String()
+	session.resume = &wsResume{streams: s, token: token, apiKey: apiKey}
+
+	s.mutex.Lock()
+	s.sessions[token] = session
+	s.mutex.Unlock()
+	return token
+}
+
+// lookup returns the resumable session with the given token, or nil when there is none or it
+// was started with another API key
+func (s *wsStreams) lookup(token, apiKey string) *wsSession {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	session := s.sessions[token]
+	if session == nil || session.resume.apiKey != apiKey {
+		return nil
+	}
+	return session
+}
+
+// remove forgets a stream token
+func (s *wsStreams) remove(token string) {
+	s.mutex.Lock()
+	delete(s.sessions, token)
+	s.mutex.Unlock()
+}
+
+// wsResume is the resume state of a session, guarded by the session's mutex. Every message the
+// session sends is numbered with seq, and the latest are kept in buffer.
+type wsResume struct {
+	streams *wsStreams
+	token   string
+	// apiKey names the key that started the session; only it may resume the session
+	apiKey string
+
+	seq    uint64
+	buffer []types.WebSocketMessage
+	// grace runs while no client is attached and ends the session when it fires
+	grace *time.Timer
+	// expired is set once the token is no longer accepted
+	expired bool
+}
+
+// record numbers a message and buffers it, dropping the oldest once the buffer is full
+func (r *wsResume) record(msg *types.WebSocketMessage) {
+	r.seq++
+	msg.Seq = r.seq
+	if len(r.buffer) == r.streams.bufferSize {
+		r.buffer = r.buffer[1:]
+	}
+	r.buffer = append(r.buffer, *msg)
+}
+
+// since returns the buffered messages after seq, and whether some of them were already dropped
+func (r *wsResume) since(seq uint64) ([]types.WebSocketMessage, bool) {
+	first := r.seq - uint64(len(r.buffer)) + 1
+	var messages []types.WebSocketMessage
+	for _, msg := range r.buffer {
+		if msg.Seq > seq {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, seq+1 < first
+}
+
+// expire stops accepting the token
+func (r *wsResume) expire() {
+	r.expired = true
+	if r.grace != nil {
+		r.grace.Stop()
+		r.grace = nil
+	}
+	r.streams.remove(r.token)
+}
+
+// send queues a frame about the session on the connection it is attached to. Messages of a
+// resumable session are also numbered and buffered, so a client that reconnects can catch up.
+func (session *wsSession) send(frame wsFrame) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if frame.data == nil && frame.text == nil {
+		frame.msg.SessionID = session.id
+		if session.resume != nil {
+			session.resume.record(&frame.msg)
+		}
+	}
+	if session.conn != nil {
+		session.conn.enqueue(frame)
+	}
+}
+
+// end ends a session whose job has returned, telling its client. A resumable session without a
+// client is told on resuming, unless the grace period runs out first.
+func (session *wsSession) end(reason string) {
+	session.cancel()
+
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.ended = true
+	session.reason = reason
+	if session.resume != nil {
+		if session.conn == nil || session.conn.isClosed() {
+			session.wait()
+			return
+		}
+		session.resume.expire()
+	}
+	session.conn.endSession(session)
+}
+
+// detach leaves a resumable session running without a client when conn goes away, starting the
+// grace period. Other sessions end with their connection.
+func (session *wsSession) detach(conn *WebSocketConnection) bool {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.resume == nil || session.conn != conn || session.ended {
+		return false
+	}
+	session.wait()
+	return true
+}
+
+// wait detaches a resumable session from its connection and starts the grace period, unless it
+// is already waiting
+func (session *wsSession) wait() {
+	session.conn = nil
+	if session.resume.grace == nil {
+		session.resume.grace = time.AfterFunc(session.resume.streams.grace, session.abandon)
+	}
+}
+
+// abandon kills a session whose client did not come back within the grace period
+func (session *wsSession) abandon() {
+	session.mutex.Lock()
+	if session.conn != nil || session.resume.expired {
+		session.mutex.Unlock()
+		return
+	}
+	session.resume.expire()
+	session.mutex.Unlock()
+
+	session.cancel()
+}
+
+// attach moves a resumable session to conn, taking it from any connection still holding it,
+// and sends the messages after seq. A session that ended meanwhile ends on conn once they are
+// sent. It returns false when the token has expired.
+func (session *wsSession) attach(conn *WebSocketConnection, seq uint64) bool {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	r := session.resume
+	if r.expired {
+		return false
+	}
+	if r.grace != nil {
+		r.grace.Stop()
+		r.grace = nil
+	}
+	if previous := session.conn; previous != nil && previous != conn {
+		previous.forget(session)
+	}
+	session.conn = conn
+	conn.adopt(session)
+
+	messages, truncated := r.since(seq)
+	conn.enqueue(wsFrame{msg: types.WebSocketMessage{
+		Type:      "resume_ack",
+		Language:  session.job.Runtime.Language,
+		Version:   session.job.Runtime.Version.String(),
+		SessionID: session.id,
+		Seq:       r.seq,
+		Truncated: truncated,
+	}})
+	if len(messages) > 0 {
+		conn.enqueue(wsFrame{batch: messages})
+	}
+
+	if session.ended {
+		r.expire()
+		conn.endSession(session)
+	}
+	return true
+}
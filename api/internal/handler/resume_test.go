@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/types"
+)
+
+// newTestConn returns a connection without a socket, whose queued frames are read from eventBus
+func newTestConn() *WebSocketConnection {
+	return &WebSocketConnection{
+		sessions:    make(map[string]*wsSession),
+		eventBus:    make(chan wsFrame, 64),
+		logger:      logrus.NewEntry(logrus.New()),
+		maxSessions: 8,
+	}
+}
+
+// newResumableSession registers a resumable session named id on conn
+func newResumableSession(streams *wsStreams, conn *WebSocketConnection, id, apiKey string) *wsSession {
+	rt := &types.Runtime{Language: "python", Version: semver.MustParse("3.12.0")}
+	session := &wsSession{id: id, job: &job.Job{Runtime: rt}, conn: conn}
+	session.ctx, session.cancel = context.WithCancel(context.Background())
+	streams.register(session, apiKey)
+	conn.adopt(session)
+	return session
+}
+
+// output sends a stdout message about the session
+func output(session *wsSession, data string) {
+	session.send(wsFrame{msg: types.WebSocketMessage{Type: "data", Stream: "stdout", Data: data}})
+}
+
+// queued drains the frames queued on conn
+func queued(conn *WebSocketConnection) []wsFrame {
+	var frames []wsFrame
+	for {
+		select {
+		case frame := <-conn.eventBus:
+			frames = append(frames, frame)
+		default:
+			return frames
+		}
+	}
+}
+
+func TestWSResumeRecordSince(t *testing.T) {
+	r := &wsResume{streams: newWSStreams(time.Minute, 3)}
+	for i := 0; i < 5; i++ {
+		msg := types.WebSocketMessage{Type: "data"}
+		r.record(&msg)
+		if msg.Seq != uint64(i+1) {
+			t.Fatalf("record() numbered message %d as %d", i+1, msg.Seq)
+		}
+	}
+
+	tests := []struct {
+		seq           uint64
+		wantFirst     uint64
+		wantCount     int
+		wantTruncated bool
+	}{
+		// Messages 1 and 2 were dropped from the buffer of three
+		{seq: 0, wantFirst: 3, wantCount: 3, wantTruncated: true},
+		{seq: 1, wantFirst: 3, wantCount: 3, wantTruncated: true},
+		{seq: 2, wantFirst: 3, wantCount: 3},
+		{seq: 4, wantFirst: 5, wantCount: 1},
+		{seq: 5},
+	}
+	for _, tt := range tests {
+		messages, truncated := r.since(tt.seq)
+		if len(messages) != tt.wantCount || truncated != tt.wantTruncated {
+			t.Errorf("since(%d) = %d messages, truncated %v; want %d, %v",
+				tt.seq, len(messages), truncated, tt.wantCount, tt.wantTruncated)
+			continue
+		}
+		if tt.wantCount > 0 && messages[0].Seq != tt.wantFirst {
+			t.Errorf("since(%d) starts at %d, want %d", tt.seq, messages[0].Seq, tt.wantFirst)
+		}
+	}
+}
+
+func TestWSSessionResume(t *testing.T) {
+	streams := newWSStreams(time.Minute, 16)
+	first := newTestConn()
+	session := newResumableSession(streams, first, "job", "")
+
+	output(session, "one")
+	output(session, "two")
+	if frames := queued(first); len(frames) != 2 || frames[1].msg.Seq != 2 || frames[1].msg.SessionID != "job" {
+		t.Fatalf("Expected two numbered messages on the first connection, got %+v", frames)
+	}
+
+	// Messages sent while detached are only buffered
+	if !session.detach(first) {
+		t.Fatal("detach() = false, want the session kept for its client")
+	}
+	output(session, "three")
+	if frames := queued(first); len(frames) != 0 {
+		t.Fatalf("Expected nothing sent to the closed connection, got %+v", frames)
+	}
+
+	second := newTestConn()
+	if !session.attach(second, 1) {
+		t.Fatal("attach() = false, want the session resumed")
+	}
+	frames := queued(second)
+	if len(frames) != 2 {
+		t.Fatalf("Expected resume_ack and a replay, got %+v", frames)
+	}
+	ack := frames[0].msg
+	if ack.Type != "resume_ack" || ack.Seq != 3 || ack.Truncated || ack.Language != "python" || ack.Version != "3.12.0" {
+		t.Errorf("Unexpected resume_ack %+v", ack)
+	}
+	if replay := frames[1].batch; len(replay) != 2 || replay[0].Data != "two" || replay[1].Data != "three" {
+		t.Errorf("Expected messages two and three replayed, got %+v", replay)
+	}
+	if second.session("job") != session {
+		t.Error("Expected the session to be adopted by the new connection")
+	}
+
+	output(session, "four")
+	if frames := queued(second); len(frames) != 1 || frames[0].msg.Seq != 4 {
+		t.Errorf("Expected later messages on the new connection, got %+v", frames)
+	}
+}
+
+func TestWSSessionEndedWhileDetached(t *testing.T) {
+	streams := newWSStreams(time.Minute, 16)
+	first := newTestConn()
+	session := newResumableSession(streams, first, "job", "")
+	session.detach(first)
+
+	output(session, "done")
+	session.end("Job Completed")
+	if session.ctx.Err() == nil {
+		t.Error("Expected the job's context to end with it")
+	}
+
+	second := newTestConn()
+	if !session.attach(second, 0) {
+		t.Fatal("attach() = false, want an ended session still resumable within the grace period")
+	}
+	frames := queued(second)
+	if len(frames) != 3 || frames[0].msg.Type != "resume_ack" || len(frames[1].batch) != 1 {
+		t.Fatalf("Expected resume_ack, a replay and session_end, got %+v", frames)
+	}
+	if end := frames[2].msg; end.Type != "session_end" || end.SessionID != "job" || end.Message != "Job Completed" {
+		t.Errorf("Unexpected last message %+v", end)
+	}
+
+	// The token is spent once the client has been told
+	if streams.lookup(session.token(), "") != nil || session.attach(newTestConn(), 0) {
+		t.Error("Expected the token to expire once the ended session was resumed")
+	}
+	if second.session("job") != nil {
+		t.Error("Expected the ended session to leave the connection")
+	}
+}
+
+func TestWSSessionGraceExpiry(t *testing.T) {
+	streams := newWSStreams(10*time.Millisecond, 16)
+	conn := newTestConn()
+	session := newResumableSession(streams, conn, "", "")
+	session.detach(conn)
+
+	select {
+	case <-session.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the job to be cancelled once the grace period ran out")
+	}
+	if streams.lookup(session.token(), "") != nil {
+		t.Error("Expected the token to be forgotten")
+	}
+	if session.attach(newTestConn(), 0) {
+		t.Error("attach() = true after the grace period, want false")
+	}
+}
+
+func TestWSStreamsLookupAPIKey(t *testing.T) {
+	streams := newWSStreams(time.Minute, 16)
+	session := newResumableSession(streams, newTestConn(), "", "team-a")
+
+	if streams.lookup(session.token(), "team-a") != session {
+		t.Error("Expected the key that started the session to find it")
+	}
+	if streams.lookup(session.token(), "team-b") != nil || streams.lookup(session.token(), "") != nil {
+		t.Error("Expected other keys to be refused the session")
+	}
+	if streams.lookup("unknown", "team-a") != nil {
+		t.Error("Expected an unknown token to find nothing")
+	}
+}
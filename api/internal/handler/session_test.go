@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/runtime"
+)
+
+// newSessionTestConn returns a test connection whose jobs run on the unsafe_local backend, with
+// a "shell" runtime whose repl script is repl, or that has none if repl is empty
+func newSessionTestConn(t *testing.T, repl string, idleTimeout time.Duration) *WebSocketConnection {
+	t.Helper()
+	cfg := &config.Config{
+		DataDirectory:      t.TempDir(),
+		SandboxBackend:     "unsafe_local",
+		MaxConcurrentJobs:  2,
+		SessionTimeout:     time.Minute,
+		SessionCPUTime:     time.Minute,
+		SessionIdleTimeout: idleTimeout,
+	}
+
+	pkgDir := filepath.Join(cfg.DataDirectory, "packages", "shell", "1.0.0")
+	files := map[string]string{
+		"pkg-info.json":    `{"language":"shell","version":"1.0.0"}`,
+		".ppman-installed": "0",
+		"run":              "exit 0\n",
+	}
+	if repl != "" {
+		files["repl"] = repl
+	}
+	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(pkgDir, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runtimes := runtime.NewManager(cfg)
+	if err := runtimes.LoadPackages(); err != nil {
+		t.Fatalf("Failed to load packages: %v", err)
+	}
+	conn := newTestConn()
+	conn.jobManager = job.NewManager(cfg, runtimes)
+	return conn
+}
+
+// nextFrame waits for the first queued frame on conn that match accepts, skipping the others
+func nextFrame(t *testing.T, conn *WebSocketConnection, match func(wsFrame) bool) wsFrame {
+	t.Helper()
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case frame := <-conn.eventBus:
+			if match(frame) {
+				return frame
+			}
+		case <-deadline:
+			t.Fatal("Timed out waiting for a message")
+		}
+	}
+}
+
+func TestHandleSessionRoundTrip(t *testing.T) {
+	conn := newSessionTestConn(t, "while read line; do echo \"= $line\"; done\n", time.Minute)
+
+	raw := map[string]interface{}{"type": "session", "session_id": "repl", "language": "shell"}
+	if err := conn.handleSessionRaw(context.Background(), raw); err != nil {
+		t.Fatalf("handleSessionRaw() error = %v", err)
+	}
+	if ack := nextFrame(t, conn, func(f wsFrame) bool { return f.msg.Type != "runtime" }); ack.msg.Type != "init_ack" {
+		t.Fatalf("Expected init_ack, got %+v", ack.msg)
+	}
+
+	session := conn.session("repl")
+	if session == nil {
+		t.Fatal("Expected the session to be registered on the connection")
+	}
+	if err := session.job.WriteStdin("1+1\n"); err != nil {
+		t.Fatal(err)
+	}
+	reply := nextFrame(t, conn, func(f wsFrame) bool { return f.msg.Type == "data" })
+	if reply.msg.Data != "= 1+1\n" || reply.msg.SessionID != "repl" {
+		t.Errorf("Expected the repl's reply to the input, got %+v", reply.msg)
+	}
+
+	session.job.CloseStdin()
+	end := nextFrame(t, conn, func(f wsFrame) bool { return f.msg.Type == "session_end" })
+	if end.msg.Message != "Session Ended" {
+		t.Errorf("Unexpected session_end %+v", end.msg)
+	}
+}
+
+func TestHandleSessionIdleTimeout(t *testing.T) {
+	conn := newSessionTestConn(t, "sleep 30\n", 100*time.Millisecond)
+
+	raw := map[string]interface{}{"type": "session", "session_id": "repl", "language": "shell"}
+	if err := conn.handleSessionRaw(context.Background(), raw); err != nil {
+		t.Fatalf("handleSessionRaw() error = %v", err)
+	}
+
+	started := time.Now()
+	idle := nextFrame(t, conn, func(f wsFrame) bool { return f.msg.Type == "error" })
+	if !strings.Contains(idle.msg.Message, "inactivity") {
+		t.Errorf("Expected the session to close for inactivity, got %+v", idle.msg)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected the idle session to be closed promptly, took %s", elapsed)
+	}
+	nextFrame(t, conn, func(f wsFrame) bool { return f.msg.Type == "session_end" })
+}
+
+func TestHandleSessionWithoutREPL(t *testing.T) {
+	conn := newSessionTestConn(t, "", time.Minute)
+
+	raw := map[string]interface{}{"type": "session", "session_id": "repl", "language": "shell"}
+	if err := conn.handleSessionRaw(context.Background(), raw); err != nil {
+		t.Fatalf("handleSessionRaw() error = %v", err)
+	}
+	refused := nextFrame(t, conn, func(wsFrame) bool { return true })
+	if refused.msg.Type != "error" || refused.msg.SessionID != "repl" ||
+		!strings.Contains(refused.msg.Message, "does not support interactive sessions") {
+		t.Errorf("Expected the session to be refused, got %+v", refused.msg)
+	}
+	if conn.session("repl") != nil {
+		t.Error("Expected no session to be registered")
+	}
+}
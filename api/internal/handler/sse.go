@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// sseEncoder writes Server-Sent Events to an HTTP response
+type sseEncoder struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+// newSSEEncoder prepares the response for an event stream
+func newSSEEncoder(w http.ResponseWriter, flusher http.Flusher) *sseEncoder {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Disable response buffering in nginx-style reverse proxies
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseEncoder{w: w, flusher: flusher}
+}
+
+// Encode writes a single named event with a JSON payload and flushes it
+func (e *sseEncoder) Encode(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", event)
+	// JSON never contains raw newlines, but keep the framing correct regardless
+	for _, line := range strings.Split(string(payload), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	if _, err := io.WriteString(e.w, b.String()); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+// ExecuteStream executes code and streams events over SSE.
+// POST accepts the job request as a JSON body; GET accepts it in the "request" query parameter
+// so that EventSource clients can use it.
+func (h *Handler) ExecuteStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.sendError(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Method == http.MethodGet {
+		body = strings.NewReader(r.URL.Query().Get("request"))
+	}
+
+	request, runtime, ok := h.parseJobRequest(r.Context(), w, body)
+	if !ok {
+		return
+	}
+
+	job := h.jobManager.NewJob(r.Context(), runtime, request)
+	enc := newSSEEncoder(w, flusher)
+
+	_ = enc.Encode("runtime", types.WebSocketMessage{
+		Type:     "runtime",
+		Language: runtime.Language,
+		Version:  runtime.Version.String(),
+	})
+
+	go func() {
+		if err := job.ExecuteStream(r.Context()); err != nil {
+			h.logger.WithError(err).Debug("Streaming job failed")
+		}
+	}()
+
+	// The event channel is closed by ExecuteStream once the job finishes
+	for event := range job.EventChannel {
+		msg, ok := streamEventToMessage(job, event)
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(msg.Type, msg); err != nil {
+			h.logger.WithError(err).Debug("SSE client went away")
+			// Keep draining until the job observes the cancelled context
+			continue
+		}
+	}
+
+	_ = enc.Encode("done", types.WebSocketMessage{Type: "done"})
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/types"
+)
+
+// FormatCode runs the runtime's formatter on the submitted files and returns them formatted
+func (h *Handler) FormatCode(w http.ResponseWriter, r *http.Request) {
+	request, rt, ok := h.parseToolRequest(w, r)
+	if !ok {
+		return
+	}
+	if !rt.Format {
+		h.sendError(w, fmt.Sprintf("%s-%s has no formatter", rt.Language, rt.Version.String()), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.jobManager.NewJob(r.Context(), rt, request).Format(r.Context())
+	if err != nil {
+		h.sendJobError(w, err)
+		return
+	}
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+// LintCode runs the runtime's linter on the submitted files and returns its diagnostics
+func (h *Handler) LintCode(w http.ResponseWriter, r *http.Request) {
+	request, rt, ok := h.parseToolRequest(w, r)
+	if !ok {
+		return
+	}
+	if !rt.Lint {
+		h.sendError(w, fmt.Sprintf("%s-%s has no linter", rt.Language, rt.Version.String()), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.jobManager.NewJob(r.Context(), rt, request).Lint(r.Context())
+	if err != nil {
+		h.sendJobError(w, err)
+		return
+	}
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+// parseToolRequest decodes and validates a format or lint request and resolves its runtime.
+// On failure an error response has already been written and ok is false.
+func (h *Handler) parseToolRequest(w http.ResponseWriter, r *http.Request) (*types.JobRequest, *types.Runtime, bool) {
+	var body types.ToolRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			middleware.WriteBodyTooLarge(w)
+			return nil, nil, false
+		}
+		h.sendError(w, "Invalid JSON request", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	request := &types.JobRequest{Language: body.Language, Version: body.Version, Files: body.Files}
+	if request.Language == "" {
+		language, err := h.runtimeManager.DetectLanguage(request.Files, "")
+		if err != nil {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		request.Language = language
+	}
+	if err := job.ValidateRequest(request); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	rt, err := h.jobManager.ResolveRuntime(request.Language, request.Version)
+	if err != nil {
+		h.sendError(w, fmt.Sprintf("%s-%s runtime is unknown", request.Language, request.Version), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return request, rt, true
+}
@@ -3,59 +3,216 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/coderunr/api/internal/config"
 	"github.com/coderunr/api/internal/job"
+	"github.com/coderunr/api/internal/middleware"
 	"github.com/coderunr/api/internal/runtime"
 	"github.com/coderunr/api/internal/types"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins in development
-	},
+// Close codes for authentication failures and the session duration limit
+const (
+	closeUnauthorized    = 4401
+	closeSessionExpired  = 4408
+	closeTooManyRequests = 4429
+)
+
+// wsKeepalive holds the ping interval, the idle timeout a pong or message resets, and the
+// maximum connection lifetime (0 for none)
+type wsKeepalive struct {
+	pingInterval time.Duration
+	idleTimeout  time.Duration
+	maxDuration  time.Duration
+}
+
+// Stream IDs prefixed to binary frames. In binary mode stdin, stdout and stderr travel as
+// binary frames of one stream ID byte followed by the raw bytes; control messages stay JSON.
+const (
+	binaryStdin  byte = 0
+	binaryStdout byte = 1
+	binaryStderr byte = 2
+)
+
+// wsFrame is a queued outgoing message: a JSON message, a binary frame when data is set, a
+// text frame sent as is when text is set, or several JSON messages when batch is set. A frame
+// with a closeCode closes the connection once the frames before it are written.
+type wsFrame struct {
+	msg   types.WebSocketMessage
+	data  []byte
+	text  []byte
+	batch []types.WebSocketMessage
+
+	closeCode   int
+	closeReason string
+}
+
+// newUpgrader creates a WebSocket upgrader that accepts the configured origins and optionally
+// negotiates compression.
+// With no origins configured the gorilla same-origin check applies.
+func newUpgrader(allowedOrigins []string, compression bool) websocket.Upgrader {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		// permessage-deflate is used only with clients that offer it
+		EnableCompression: compression,
+	}
+
+	if len(allowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return originAllowed(allowedOrigins, r.Header.Get("Origin"))
+		}
+	}
+
+	return upgrader
+}
+
+// originAllowed reports whether origin matches the allowlist; "*" matches any origin
+func originAllowed(allowedOrigins []string, origin string) bool {
+	// Non-browser clients do not send an Origin header
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
 }
 
 // WebSocketConnection represents a WebSocket connection
 type WebSocketConnection struct {
 	conn       *websocket.Conn
-	job        *job.Job
-	eventBus   chan types.WebSocketMessage
+	sessions   map[string]*wsSession
+	eventBus   chan wsFrame
 	jobManager *job.Manager
-	logger     *logrus.Entry
-	mutex      sync.Mutex
-	closed     bool
+	// runtimeManager detects the language of init requests that omit it
+	runtimeManager *runtime.Manager
+	logger         *logrus.Entry
+	mutex          sync.Mutex
+	closed         bool
+	// done is closed with the connection and stops the pinger
+	done chan struct{}
+	// cancel ends the context the job runs under; called when the connection closes
+	cancel context.CancelFunc
+
+	// initialized is set once the first job starts; maxSessions caps the jobs running at once
+	initialized bool
+	maxSessions int
+	// streams holds the resumable sessions of every connection
+	streams *wsStreams
+
+	// Authentication state; when auth is enabled an auth message must precede init
+	// unless a key was supplied with the upgrade request
+	apiKeys       *middleware.APIKeyStore
+	apiKey        *config.APIKey
+	authenticated bool
+
+	// readTimeout bounds the wait for the next client message or pong
+	readTimeout time.Duration
 }
 
+// wsSession is a job running over a connection. A client may run several at once by naming
+// each with a session_id, which every message to and from the job carries. The job of a client
+// that sends none has the id "", and its end closes the connection.
+type wsSession struct {
+	id     string
+	job    *job.Job
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// binary is negotiated in init and switches data messages to binary frames
+	binary bool
+	// lsp is set for a language server session, whose JSON-RPC messages pass through unwrapped
+	lsp bool
+
+	// mutex guards conn, the connection the session's messages go to, which is nil while a
+	// resumable session waits for its client, and the fields below
+	mutex sync.Mutex
+	conn  *WebSocketConnection
+	// ended is set with the reason once the job has returned
+	ended  bool
+	reason string
+	// resume is set for a resumable session
+	resume *wsResume
+}
+
+// maxSessionID bounds the length of a session_id
+const maxSessionID = 64
+
 // HandleWebSocket handles WebSocket connections for interactive execution
 func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		h.logger.WithError(err).Error("WebSocket upgrade failed")
 		return
 	}
 
 	wsConn := &WebSocketConnection{
-		conn:       conn,
-		eventBus:   make(chan types.WebSocketMessage, 100),
-		jobManager: h.jobManager,
-		logger:     h.logger.WithField("component", "websocket"),
-		closed:     false,
+		conn:           conn,
+		sessions:       make(map[string]*wsSession),
+		eventBus:       make(chan wsFrame, 100),
+		jobManager:     h.jobManager,
+		runtimeManager: h.runtimeManager,
+		logger: h.logger.WithFields(logrus.Fields{
+			"component":  "websocket",
+			"request_id": chiMiddleware.GetReqID(r.Context()),
+		}),
+		closed:        false,
+		done:          make(chan struct{}),
+		apiKeys:       h.apiKeys,
+		authenticated: !h.apiKeys.Enabled(),
+		maxSessions:   h.wsMaxSessions,
+		streams:       h.wsStreams,
+
+		readTimeout: h.wsKeepalive.idleTimeout,
 	}
+	defer wsConn.releaseAPIKey()
 
-	// Set connection timeouts
-	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	// Set connection timeouts; a pong counts as activity, so a quiet but live client stays connected
+	conn.SetReadDeadline(time.Now().Add(wsConn.readTimeout))
 	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsConn.readTimeout))
+	})
+	// Messages are held to the execute body limit, like the JSON request to /execute
+	if h.wsReadLimit > 0 {
+		conn.SetReadLimit(h.wsReadLimit)
+	}
 
-	// Start event sender goroutine
+	// Start event sender and keepalive goroutines
 	go wsConn.eventSender()
+	go wsConn.pinger(h.wsKeepalive.pingInterval)
+
+	if h.wsKeepalive.maxDuration > 0 {
+		expire := time.AfterFunc(h.wsKeepalive.maxDuration, func() {
+			wsConn.logger.Info("Maximum session duration reached, closing connection")
+			wsConn.close(closeSessionExpired, "Maximum Session Duration Reached")
+		})
+		defer expire.Stop()
+	}
+
+	// Browsers cannot set headers on WebSocket requests, so also accept a token query parameter
+	if !wsConn.authenticated {
+		secret := middleware.ExtractAPIKey(r)
+		if secret == "" {
+			secret = r.URL.Query().Get("token")
+		}
+		if secret != "" && !wsConn.authenticate(secret) {
+			return
+		}
+	}
 
 	// Set up initialization timeout (more tolerant for network/JSON delays)
 	initTimeout := time.NewTimer(5 * time.Second)
@@ -63,14 +220,28 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	go func() {
 		<-initTimeout.C
-		if wsConn.job == nil {
+		wsConn.mutex.Lock()
+		initialized := wsConn.initialized
+		wsConn.mutex.Unlock()
+		if !initialized {
 			wsConn.sendError("Initialization timeout")
 			wsConn.close(4001, "Initialization Timeout")
 		}
 	}()
 
+	// The job runs under the connection's lifetime: once the client goes away the context is
+	// cancelled, killing the sandboxed process instead of letting it run until its timeout
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	wsConn.mutex.Lock()
+	wsConn.cancel = cancel
+	wsConn.mutex.Unlock()
+
 	// Handle incoming messages
-	wsConn.handleMessages(r.Context())
+	wsConn.handleMessages(ctx)
+
+	// Resumable jobs keep running for a while in case the client comes back
+	wsConn.detachSessions()
 }
 
 // handleMessages handles incoming WebSocket messages
@@ -79,16 +250,31 @@ func (wsConn *WebSocketConnection) handleMessages(ctx context.Context) {
 
 	for {
 		// Read raw message to support both payload and top-level init formats
-		_, data, err := wsConn.conn.ReadMessage()
+		frameType, data, err := wsConn.conn.ReadMessage()
+		if errors.Is(err, websocket.ErrReadLimit) {
+			wsConn.logger.Warn("WebSocket message exceeds the body limit")
+			wsConn.close(websocket.CloseMessageTooBig, "Message Too Large")
+			return
+		}
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				wsConn.logger.WithError(err).Error("WebSocket read error")
 			}
+			if wsConn.running() > 0 {
+				wsConn.logger.Info("Client disconnected, killing jobs")
+			}
 			break
 		}
 
 		// Reset read deadline
-		wsConn.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		wsConn.conn.SetReadDeadline(time.Now().Add(wsConn.readTimeout))
+
+		if frameType == websocket.BinaryMessage {
+			if err := wsConn.handleBinary(data); err != nil {
+				return
+			}
+			continue
+		}
 
 		// Determine message type
 		var raw map[string]interface{}
@@ -98,13 +284,49 @@ func (wsConn *WebSocketConnection) handleMessages(ctx context.Context) {
 		}
 		msgType, _ := raw["type"].(string)
 
+		if msgType != "auth" && !wsConn.authenticated {
+			wsConn.sendError("Authentication required")
+			wsConn.close(closeUnauthorized, "Unauthorized")
+			return
+		}
+
+		// JSON-RPC messages of a language server session carry no type of their own
+		if _, ok := raw["jsonrpc"]; ok && msgType == "" {
+			if err := wsConn.handleLSPMessage(data); err != nil {
+				return
+			}
+			continue
+		}
+
 		switch msgType {
+		case "auth":
+			if wsConn.authenticated {
+				wsConn.sendError("Already authenticated")
+				continue
+			}
+			token, _ := raw["token"].(string)
+			if !wsConn.authenticate(token) {
+				return
+			}
+			wsConn.sendMessage(types.WebSocketMessage{Type: "auth_ack"})
 		case "init":
 			if err := wsConn.handleInitRaw(ctx, raw); err != nil {
 				wsConn.sendError(err.Error())
 				return
 			}
-		case "data", "signal":
+		case "session":
+			if err := wsConn.handleSessionRaw(ctx, raw); err != nil {
+				wsConn.sendError(err.Error())
+				return
+			}
+		case "lsp":
+			if err := wsConn.handleLSPRaw(ctx, raw); err != nil {
+				wsConn.sendError(err.Error())
+				return
+			}
+		case "resume":
+			wsConn.handleResumeRaw(raw)
+		case "data", "signal", "resize":
 			var msg types.WebSocketMessage
 			if err := json.Unmarshal(data, &msg); err != nil {
 				wsConn.sendError("Invalid message fields")
@@ -123,6 +345,42 @@ func (wsConn *WebSocketConnection) handleMessages(ctx context.Context) {
 	}
 }
 
+// authenticate validates an API key and reserves a concurrency slot for the connection.
+// On failure the connection is closed and false is returned.
+func (wsConn *WebSocketConnection) authenticate(secret string) bool {
+	key, ok := wsConn.apiKeys.Lookup(secret)
+	if !ok {
+		wsConn.sendError("Invalid or missing API key")
+		wsConn.close(closeUnauthorized, "Unauthorized")
+		return false
+	}
+
+	if !wsConn.apiKeys.Acquire(key) {
+		wsConn.sendError("Too many concurrent requests for API key")
+		wsConn.close(closeTooManyRequests, "Too Many Requests")
+		return false
+	}
+
+	wsConn.apiKey = key
+	wsConn.authenticated = true
+	return true
+}
+
+// releaseAPIKey frees the concurrency slot held by the connection's API key
+func (wsConn *WebSocketConnection) releaseAPIKey() {
+	if wsConn.apiKey != nil {
+		wsConn.apiKeys.Release(wsConn.apiKey)
+	}
+}
+
+// apiKeyName returns the name of the key the connection authenticated with, "" without one
+func (wsConn *WebSocketConnection) apiKeyName() string {
+	if wsConn.apiKey == nil {
+		return ""
+	}
+	return wsConn.apiKey.Name
+}
+
 // handleMessage handles a single WebSocket message
 func (wsConn *WebSocketConnection) handleMessage(ctx context.Context, msg types.WebSocketMessage) error {
 	switch msg.Type {
@@ -132,6 +390,8 @@ func (wsConn *WebSocketConnection) handleMessage(ctx context.Context, msg types.
 		return wsConn.handleData(msg)
 	case "signal":
 		return wsConn.handleSignal(msg)
+	case "resize":
+		return wsConn.handleResize(msg)
 	default:
 		return wsConn.sendError("Unknown message type: " + msg.Type)
 	}
@@ -139,54 +399,82 @@ func (wsConn *WebSocketConnection) handleMessage(ctx context.Context, msg types.
 
 // handleInit handles job initialization
 func (wsConn *WebSocketConnection) handleInit(ctx context.Context, msg types.WebSocketMessage) error {
-	if wsConn.job != nil {
-		wsConn.close(4000, "Already Initialized")
+	id := msg.SessionID
+	if !wsConn.canStart(id) {
 		return nil
 	}
 
 	// Parse job request from message payload
 	requestBytes, err := json.Marshal(msg.Payload)
 	if err != nil {
-		return wsConn.sendError("Invalid request payload")
+		return wsConn.sendSessionError(id, "Invalid request payload")
 	}
 
 	var request types.JobRequest
 	if err := json.Unmarshal(requestBytes, &request); err != nil {
-		return wsConn.sendError("Invalid job request")
+		return wsConn.sendSessionError(id, "Invalid job request")
 	}
 
 	// Validate request
-	if err := wsConn.validateJobRequest(&request); err != nil {
-		return wsConn.sendError(err.Error())
+	if err := job.ValidateRequest(&request); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
 	}
 
 	// Find runtime
-	rt, err := runtime.GetLatestRuntimeMatchingLanguageVersion(request.Language, request.Version)
+	rt, err := wsConn.jobManager.ResolveRuntime(request.Language, request.Version)
 	if err != nil {
-		return wsConn.sendError("Runtime not found: " + request.Language + "-" + request.Version)
+		return wsConn.sendSessionError(id, "Runtime not found: "+request.Language+"-"+request.Version)
+	}
+	rt, err = wsConn.jobManager.ApplyProfile(ctx, &request, rt)
+	if err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := job.ValidateConstraints(&request, rt); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+
+	// Validate environment variables
+	if err := wsConn.jobManager.ValidateEnv(request.Env); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateMounts(request.Mounts); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateStdin(&request); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateFlags(&request, rt); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateNetwork(ctx, &request); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
 	}
 
 	// Create job
-	wsConn.job = wsConn.jobManager.NewJob(rt, &request)
+	session := wsConn.addSession(ctx, id, wsConn.jobManager.NewJob(ctx, rt, &request), false)
 
 	// Send runtime info then init_ack to acknowledge initialization
 	wsConn.sendMessage(types.WebSocketMessage{
-		Type:     "runtime",
-		Language: rt.Language,
-		Version:  rt.Version.String(),
+		Type:      "runtime",
+		Language:  rt.Language,
+		Version:   rt.Version.String(),
+		SessionID: id,
 	})
-	wsConn.sendMessage(types.WebSocketMessage{Type: "init_ack"})
+	wsConn.sendMessage(types.WebSocketMessage{Type: "init_ack", SessionID: id})
 
 	// Execute job in background
-	go wsConn.executeJob(ctx)
+	go session.run(session.job.ExecuteStream, "Execution failed: ", "Job Completed")
 
 	return nil
 }
 
 // handleInitRaw handles init from a raw JSON map supporting both payload and top-level fields
 func (wsConn *WebSocketConnection) handleInitRaw(ctx context.Context, raw map[string]interface{}) error {
-	if wsConn.job != nil {
-		wsConn.close(4000, "Already Initialized")
+	id, err := sessionID(raw)
+	if err != nil {
+		return wsConn.sendError(err.Error())
+	}
+	if !wsConn.canStart(id) {
 		return nil
 	}
 
@@ -201,33 +489,401 @@ func (wsConn *WebSocketConnection) handleInitRaw(ctx context.Context, raw map[st
 		reqMap = raw
 	}
 
+	binary := initFlag(raw, reqMap, "binary")
+	if binary && id != "" {
+		return wsConn.sendSessionError(id, "binary frames cannot be used with session_id")
+	}
+	resumable := initFlag(raw, reqMap, "resumable")
+	if err := wsConn.checkResumable(resumable, binary); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+
 	// Build JobRequest
 	request, err := buildJobRequestFromMap(reqMap)
 	if err != nil {
-		return wsConn.sendError(err.Error())
+		return wsConn.sendSessionError(id, err.Error())
+	}
+
+	if request.Language == "" {
+		language, err := wsConn.runtimeManager.DetectLanguage(request.Files, request.Entrypoint)
+		if err != nil {
+			return wsConn.sendSessionError(id, err.Error())
+		}
+		request.Language = language
 	}
 
 	// Validate
-	if err := wsConn.validateJobRequest(request); err != nil {
-		return wsConn.sendError(err.Error())
+	if err := job.ValidateRequest(request); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
 	}
 
 	// Find runtime
-	rt, err := runtime.GetLatestRuntimeMatchingLanguageVersion(request.Language, request.Version)
+	rt, err := wsConn.jobManager.ResolveRuntime(request.Language, request.Version)
+	if err != nil {
+		return wsConn.sendSessionError(id, "Runtime not found: "+request.Language+"-"+request.Version)
+	}
+	rt, err = wsConn.jobManager.ApplyProfile(ctx, request, rt)
 	if err != nil {
-		return wsConn.sendError("Runtime not found: " + request.Language + "-" + request.Version)
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := job.ValidateConstraints(request, rt); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+
+	if err := wsConn.jobManager.ValidateEnv(request.Env); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateMounts(request.Mounts); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateStdin(request); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateFlags(request, rt); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	if err := wsConn.jobManager.ValidateNetwork(ctx, request); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
 	}
 
-	wsConn.job = wsConn.jobManager.NewJob(rt, request)
+	session := wsConn.addSession(ctx, id, wsConn.jobManager.NewJob(ctx, rt, request), resumable)
+	session.binary = binary
 
 	// Send runtime info (top-level fields) then init_ack
+	wsConn.sendMessage(types.WebSocketMessage{Type: "runtime", Language: rt.Language, Version: rt.Version.String(), SessionID: id})
+	wsConn.sendMessage(types.WebSocketMessage{Type: "init_ack", Binary: binary, SessionID: id, StreamToken: session.token()})
+
+	go session.run(session.job.ExecuteStream, "Execution failed: ", "Job Completed")
+	return nil
+}
+
+// handleSessionRaw starts an interactive REPL session. Files are optional; the
+// runtime's interpreter runs until the client disconnects or the session idles out.
+func (wsConn *WebSocketConnection) handleSessionRaw(ctx context.Context, raw map[string]interface{}) error {
+	id, err := sessionID(raw)
+	if err != nil {
+		return wsConn.sendError(err.Error())
+	}
+	if !wsConn.canStart(id) {
+		return nil
+	}
+
+	reqMap := raw
+	if p, ok := raw["payload"].(map[string]interface{}); ok {
+		reqMap = p
+	}
+
+	binary := initFlag(raw, reqMap, "binary")
+	if binary && id != "" {
+		return wsConn.sendSessionError(id, "binary frames cannot be used with session_id")
+	}
+	resumable := initFlag(raw, reqMap, "resumable")
+	if err := wsConn.checkResumable(resumable, binary); err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+
+	request, rt, err := wsConn.sessionRequest(ctx, reqMap)
+	if err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+
+	repl, err := wsConn.jobManager.NewSession(ctx, rt, request)
+	if err != nil {
+		return wsConn.sendSessionError(id, err.Error())
+	}
+	session := wsConn.addSession(ctx, id, repl, resumable)
+	session.binary = binary
+
+	wsConn.sendMessage(types.WebSocketMessage{Type: "runtime", Language: rt.Language, Version: rt.Version.String(), SessionID: id})
+	wsConn.sendMessage(types.WebSocketMessage{Type: "init_ack", Binary: binary, SessionID: id, StreamToken: session.token()})
+
+	go session.run(session.job.ExecuteSession, "Session failed: ", "Session Ended")
+	return nil
+}
+
+// handleLSPRaw starts a language server session. Files are optional and form the workspace the
+// server indexes; after init_ack, JSON-RPC messages pass through in both directions unwrapped.
+// Unwrapped messages name no session, so a language server is the connection's only job.
+func (wsConn *WebSocketConnection) handleLSPRaw(ctx context.Context, raw map[string]interface{}) error {
+	id, err := sessionID(raw)
+	if err != nil {
+		return wsConn.sendError(err.Error())
+	}
+	if id != "" {
+		return wsConn.sendSessionError(id, "language server sessions cannot be used with session_id")
+	}
+	if initFlag(raw, raw, "resumable") {
+		return wsConn.sendError("language server sessions cannot be resumed")
+	}
+	if !wsConn.canStart(id) {
+		return nil
+	}
+
+	reqMap := raw
+	if p, ok := raw["payload"].(map[string]interface{}); ok {
+		reqMap = p
+	}
+
+	request, rt, err := wsConn.sessionRequest(ctx, reqMap)
+	if err != nil {
+		return wsConn.sendError(err.Error())
+	}
+
+	server, err := wsConn.jobManager.NewLanguageServer(ctx, rt, request)
+	if err != nil {
+		return wsConn.sendError(err.Error())
+	}
+	session := wsConn.addSession(ctx, id, server, false)
+	session.lsp = true
+
 	wsConn.sendMessage(types.WebSocketMessage{Type: "runtime", Language: rt.Language, Version: rt.Version.String()})
 	wsConn.sendMessage(types.WebSocketMessage{Type: "init_ack"})
 
-	go wsConn.executeJob(ctx)
+	go session.run(session.job.ExecuteLanguageServer, "Language server failed: ", "Session Ended")
+	return nil
+}
+
+// sessionID returns the session_id of a client message, "" when it has none
+func sessionID(raw map[string]interface{}) (string, error) {
+	value, ok := raw["session_id"]
+	if !ok {
+		return "", nil
+	}
+	id, ok := value.(string)
+	if !ok || len(id) > maxSessionID {
+		return "", fmt.Errorf("session_id must be a string of at most %d bytes", maxSessionID)
+	}
+	return id, nil
+}
+
+// canStart reports whether a job may start under id. A second job on a single-job connection
+// closes it, as it always has; a taken session_id or a connection at ws_max_sessions is answered
+// with an error and the connection stays open.
+func (wsConn *WebSocketConnection) canStart(id string) bool {
+	wsConn.mutex.Lock()
+	_, taken := wsConn.sessions[id]
+	running := len(wsConn.sessions)
+	wsConn.mutex.Unlock()
+
+	switch {
+	case taken && id == "":
+		wsConn.close(4000, "Already Initialized")
+		return false
+	case taken:
+		wsConn.sendSessionError(id, "Session "+id+" is already running")
+		return false
+	case running >= wsConn.maxSessions:
+		wsConn.sendSessionError(id, fmt.Sprintf("Too many sessions, at most %d may run at once", wsConn.maxSessions))
+		return false
+	}
+	return true
+}
+
+// addSession registers a job under id. Its context ends with the session or the connection,
+// except that a resumable session outlives the connection until its grace period runs out.
+func (wsConn *WebSocketConnection) addSession(ctx context.Context, id string, j *job.Job, resumable bool) *wsSession {
+	session := &wsSession{id: id, job: j, conn: wsConn}
+	if resumable {
+		ctx = context.WithoutCancel(ctx)
+		wsConn.streams.register(session, wsConn.apiKeyName())
+	}
+	session.ctx, session.cancel = context.WithCancel(ctx)
+
+	wsConn.adopt(session)
+	return session
+}
+
+// adopt registers a session on the connection
+func (wsConn *WebSocketConnection) adopt(session *wsSession) {
+	wsConn.mutex.Lock()
+	wsConn.sessions[session.id] = session
+	wsConn.initialized = true
+	wsConn.mutex.Unlock()
+}
+
+// forget removes a session from the connection, which no longer receives its messages
+func (wsConn *WebSocketConnection) forget(session *wsSession) {
+	wsConn.mutex.Lock()
+	if wsConn.sessions[session.id] == session {
+		delete(wsConn.sessions, session.id)
+	}
+	wsConn.mutex.Unlock()
+}
+
+// session returns the running session with the given id, or nil
+func (wsConn *WebSocketConnection) session(id string) *wsSession {
+	wsConn.mutex.Lock()
+	defer wsConn.mutex.Unlock()
+	return wsConn.sessions[id]
+}
+
+// running returns the number of sessions running on the connection
+func (wsConn *WebSocketConnection) running() int {
+	wsConn.mutex.Lock()
+	defer wsConn.mutex.Unlock()
+	return len(wsConn.sessions)
+}
+
+// sessionFor returns the session a data, signal or resize message addresses. Without one, a
+// single-job connection is closed with 4003 as before, while a message naming an unknown
+// session_id is answered with an error, since that session may just have ended.
+func (wsConn *WebSocketConnection) sessionFor(id string) *wsSession {
+	if session := wsConn.session(id); session != nil {
+		return session
+	}
+	if id == "" {
+		wsConn.close(4003, "Not yet initialized")
+	} else {
+		wsConn.sendSessionError(id, "Session "+id+" is not running")
+	}
+	return nil
+}
+
+// run runs the session's job with execute, forwarding its events, and ends the session once
+// the job has returned and its last event has been queued
+func (session *wsSession) run(execute func(context.Context) error, failure, reason string) {
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for event := range session.job.EventChannel {
+			session.handleJobEvent(event)
+		}
+	}()
+
+	err := execute(session.ctx)
+	<-forwarded
+	if err != nil {
+		session.send(wsFrame{msg: errorMessage(failure + err.Error())})
+	}
+	session.end(reason)
+}
+
+// endSession tells the client a session has ended. The single-job protocol closes the
+// connection once the session's last messages are written; a named session is removed and
+// announced with session_end.
+func (wsConn *WebSocketConnection) endSession(session *wsSession) {
+	if session.id == "" {
+		wsConn.closeWhenSent(4999, session.reason)
+		return
+	}
+
+	wsConn.forget(session)
+	wsConn.sendMessage(types.WebSocketMessage{Type: "session_end", SessionID: session.id, Message: session.reason})
+}
+
+// detachSessions leaves the resumable sessions of a closed connection to wait for their client
+func (wsConn *WebSocketConnection) detachSessions() {
+	wsConn.mutex.Lock()
+	sessions := make([]*wsSession, 0, len(wsConn.sessions))
+	for _, session := range wsConn.sessions {
+		sessions = append(sessions, session)
+	}
+	wsConn.mutex.Unlock()
+
+	for _, session := range sessions {
+		if session.detach(wsConn) {
+			wsConn.logger.Infof("Keeping session %q for %s for the client to resume", session.id, wsConn.streams.grace)
+		}
+	}
+}
+
+// checkResumable checks that a job asking to be resumable can be
+func (wsConn *WebSocketConnection) checkResumable(resumable, binary bool) error {
+	if !resumable {
+		return nil
+	}
+	if !wsConn.streams.enabled() {
+		return errors.New("resumable jobs are disabled")
+	}
+	if binary {
+		return errors.New("binary frames cannot be used with resumable jobs")
+	}
 	return nil
 }
 
+// handleResumeRaw moves a resumable job to this connection and sends the messages the client
+// missed after seq. An unknown token is answered with an error.
+func (wsConn *WebSocketConnection) handleResumeRaw(raw map[string]interface{}) {
+	token, _ := raw["stream_token"].(string)
+	var seq uint64
+	if value, ok := raw["seq"].(float64); ok && value > 0 {
+		seq = uint64(value)
+	}
+
+	session := wsConn.streams.lookup(token, wsConn.apiKeyName())
+	if session == nil {
+		wsConn.sendError("Unknown or expired stream token")
+		return
+	}
+	if !wsConn.canStart(session.id) {
+		return
+	}
+	if !session.attach(wsConn, seq) {
+		wsConn.sendError("Unknown or expired stream token")
+		return
+	}
+	wsConn.logger.Infof("Resumed session %q after message %d", session.id, seq)
+}
+
+// token returns the stream token of a resumable session, "" for others
+func (session *wsSession) token() string {
+	if session.resume == nil {
+		return ""
+	}
+	return session.resume.token
+}
+
+// sessionRequest builds and validates the request of a repl or language server session, which
+// needs a language but no files
+func (wsConn *WebSocketConnection) sessionRequest(ctx context.Context, reqMap map[string]interface{}) (*types.JobRequest, *types.Runtime, error) {
+	request, err := buildJobRequestFromMap(reqMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	if request.Language == "" {
+		return nil, nil, errors.New("language is required")
+	}
+
+	rt, err := wsConn.jobManager.ResolveRuntime(request.Language, request.Version)
+	if err != nil {
+		return nil, nil, errors.New("Runtime not found: " + request.Language + "-" + request.Version)
+	}
+	rt, err = wsConn.jobManager.ApplyProfile(ctx, request, rt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := job.ValidateConstraints(request, rt); err != nil {
+		return nil, nil, err
+	}
+
+	if err := wsConn.jobManager.ValidateEnv(request.Env); err != nil {
+		return nil, nil, err
+	}
+	if err := wsConn.jobManager.ValidateMounts(request.Mounts); err != nil {
+		return nil, nil, err
+	}
+	if err := wsConn.jobManager.ValidateStdin(request); err != nil {
+		return nil, nil, err
+	}
+	if err := wsConn.jobManager.ValidateFlags(request, rt); err != nil {
+		return nil, nil, err
+	}
+	if err := wsConn.jobManager.ValidateNetwork(ctx, request); err != nil {
+		return nil, nil, err
+	}
+	return request, rt, nil
+}
+
+// initFlag reports whether the init message sets a boolean option, such as binary, at the top
+// level or in the payload
+func initFlag(raw, reqMap map[string]interface{}, name string) bool {
+	set, _ := raw[name].(bool)
+	if !set {
+		set, _ = reqMap[name].(bool)
+	}
+	return set
+}
+
 // buildJobRequestFromMap converts an init map into a JobRequest
 func buildJobRequestFromMap(m map[string]interface{}) (*types.JobRequest, error) {
 	jr := &types.JobRequest{}
@@ -240,6 +896,21 @@ func buildJobRequestFromMap(m map[string]interface{}) (*types.JobRequest, error)
 	if v, ok := m["stdin"].(string); ok {
 		jr.Stdin = v
 	}
+	if v, ok := m["stdin_url"].(string); ok {
+		jr.StdinURL = v
+	}
+	if v, ok := m["stdin_fixture"].(string); ok {
+		jr.StdinFixture = v
+	}
+	if v, ok := m["expected_output"].(string); ok {
+		jr.ExpectedOutput = &v
+	}
+	if v, ok := m["compare_mode"].(string); ok {
+		jr.CompareMode = v
+	}
+	if v, ok := m["epsilon"].(float64); ok {
+		jr.Epsilon = &v
+	}
 	if v, ok := m["args"].([]interface{}); ok {
 		args := make([]string, 0, len(v))
 		for _, a := range v {
@@ -249,6 +920,28 @@ func buildJobRequestFromMap(m map[string]interface{}) (*types.JobRequest, error)
 		}
 		jr.Args = args
 	}
+	if v, ok := m["env"].(map[string]interface{}); ok {
+		env := make(map[string]string, len(v))
+		for name, value := range v {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("env.%s must be string", name)
+			}
+			env[name] = s
+		}
+		jr.Env = env
+	}
+	if v, ok := m["output_files"].([]interface{}); ok {
+		patterns := make([]string, 0, len(v))
+		for _, value := range v {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("output_files must be an array of strings")
+			}
+			patterns = append(patterns, s)
+		}
+		jr.OutputFiles = patterns
+	}
 
 	// files: accept multiple slice element types
 	if rawFiles, ok := m["files"]; ok {
@@ -346,14 +1039,76 @@ func buildJobRequestFromMap(m map[string]interface{}) (*types.JobRequest, error)
 	jr.RunCPUTime = toIntPtr("run_cpu_time")
 	jr.CompileMemoryLimit = toInt64Ptr("compile_memory_limit")
 	jr.RunMemoryLimit = toInt64Ptr("run_memory_limit")
+	jr.DiskQuota = toInt64Ptr("disk_quota")
+	jr.MaxOutput = toIntPtr("max_output")
+	if action, ok := m["output_limit_action"].(string); ok {
+		jr.OutputLimitAction = action
+	}
+	if enable, ok := m["enable_network"].(bool); ok {
+		jr.EnableNetwork = &enable
+	}
+	pty, err := ptySizeFromMap(m["pty"])
+	if err != nil {
+		return nil, err
+	}
+	jr.PTY = pty
+	if entrypoint, ok := m["entrypoint"].(string); ok {
+		jr.Entrypoint = entrypoint
+	}
+	if archive, ok := m["archive"].(string); ok {
+		jr.Archive = archive
+	}
+	if mounts, ok := m["mounts"]; ok {
+		// Round-trip through JSON to reuse the struct tags
+		data, err := json.Marshal(mounts)
+		if err != nil {
+			return nil, fmt.Errorf("mounts must be an array")
+		}
+		if err := json.Unmarshal(data, &jr.Mounts); err != nil {
+			return nil, fmt.Errorf("mounts must be an array of {path, type, dataset, size}")
+		}
+	}
+	for name, dest := range map[string]*[]string{"omit": &jr.Omit, "compile_flags": &jr.CompileFlags, "run_flags": &jr.RunFlags} {
+		if values, ok := m[name].([]interface{}); ok {
+			for _, value := range values {
+				s, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s must be an array of strings", name)
+				}
+				*dest = append(*dest, s)
+			}
+		}
+	}
+	if locale, ok := m["locale"].(string); ok {
+		jr.Locale = locale
+	}
+	if timezone, ok := m["timezone"].(string); ok {
+		jr.Timezone = timezone
+	}
+	if profile, ok := m["profile"].(string); ok {
+		jr.Profile = profile
+	}
+	if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+		jr.Metadata = make(map[string]string, len(metadata))
+		for key, value := range metadata {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("metadata.%s must be string", key)
+			}
+			jr.Metadata[key] = s
+		}
+	}
+	if priority := toIntPtr("priority"); priority != nil {
+		jr.Priority = *priority
+	}
 
 	return jr, nil
 }
 
 // handleData handles stdin data
 func (wsConn *WebSocketConnection) handleData(msg types.WebSocketMessage) error {
-	if wsConn.job == nil {
-		wsConn.close(4003, "Not yet initialized")
+	session := wsConn.sessionFor(msg.SessionID)
+	if session == nil {
 		return nil
 	}
 
@@ -363,99 +1118,202 @@ func (wsConn *WebSocketConnection) handleData(msg types.WebSocketMessage) error
 	}
 
 	// Write to job's stdin channel
-	if err := wsConn.job.WriteStdin(msg.Data); err != nil {
+	if msg.Data != "" || !msg.EOF {
+		if err := session.job.WriteStdin(msg.Data); err != nil {
+			wsConn.logger.WithError(err).Error("Failed to write to stdin")
+			return wsConn.sessionFailed(session, "Failed to write to stdin: ", err)
+		}
+	}
+	if msg.EOF {
+		session.job.CloseStdin()
+	}
+
+	return nil
+}
+
+// Terminal size used when pty mode is requested without one
+const (
+	defaultPTYRows = 24
+	defaultPTYCols = 80
+)
+
+// ptySizeFromMap parses the init "pty" field: true for the default size, or {"rows", "cols"}
+func ptySizeFromMap(v interface{}) (*types.PTYSize, error) {
+	switch pty := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if !pty {
+			return nil, nil
+		}
+		return &types.PTYSize{Rows: defaultPTYRows, Cols: defaultPTYCols}, nil
+	case map[string]interface{}:
+		size := &types.PTYSize{Rows: defaultPTYRows, Cols: defaultPTYCols}
+		for name, dest := range map[string]*uint16{"rows": &size.Rows, "cols": &size.Cols} {
+			if raw, ok := pty[name]; ok {
+				n, ok := raw.(float64)
+				if !ok || n < 1 || n > 65535 || n != float64(int(n)) {
+					return nil, fmt.Errorf("pty.%s must be an integer between 1 and 65535", name)
+				}
+				*dest = uint16(n)
+			}
+		}
+		return size, nil
+	default:
+		return nil, fmt.Errorf("pty must be a boolean or an object with rows and cols")
+	}
+}
+
+// handleLSPMessage forwards a JSON-RPC message from the client to the language server
+func (wsConn *WebSocketConnection) handleLSPMessage(data []byte) error {
+	session := wsConn.session("")
+	if session == nil {
+		wsConn.close(4003, "Not yet initialized")
+		return fmt.Errorf("JSON-RPC message before init")
+	}
+	if !session.lsp {
+		wsConn.close(4007, "Not a language server session")
+		return fmt.Errorf("JSON-RPC message outside a language server session")
+	}
+
+	if err := session.job.WriteLSP(data); err != nil {
+		wsConn.logger.WithError(err).Error("Failed to write to the language server")
+		wsConn.sendError("Failed to write to the language server: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+// handleResize changes the terminal size of a pty-mode job
+func (wsConn *WebSocketConnection) handleResize(msg types.WebSocketMessage) error {
+	session := wsConn.sessionFor(msg.SessionID)
+	if session == nil {
+		return nil
+	}
+
+	if err := session.job.Resize(msg.Rows, msg.Cols); err != nil {
+		// Not fatal; the program keeps its current size
+		wsConn.sendSessionError(session.id, "Failed to resize terminal: "+err.Error())
+	}
+	return nil
+}
+
+// handleBinary handles a binary frame carrying raw stdin bytes. Binary frames name no session,
+// so they are only available to the single-job protocol.
+func (wsConn *WebSocketConnection) handleBinary(data []byte) error {
+	if !wsConn.authenticated {
+		wsConn.sendError("Authentication required")
+		wsConn.close(closeUnauthorized, "Unauthorized")
+		return fmt.Errorf("binary frame before authentication")
+	}
+	session := wsConn.session("")
+	if session == nil {
+		wsConn.close(4003, "Not yet initialized")
+		return fmt.Errorf("binary frame before init")
+	}
+	if !session.binary {
+		wsConn.close(4006, "Binary mode not negotiated")
+		return fmt.Errorf("binary mode not negotiated")
+	}
+	if len(data) == 0 || data[0] != binaryStdin {
+		wsConn.close(4004, "Can only write to stdin")
+		return fmt.Errorf("binary frame for a stream other than stdin")
+	}
+
+	if err := session.job.WriteStdin(string(data[1:])); err != nil {
 		wsConn.logger.WithError(err).Error("Failed to write to stdin")
 		wsConn.sendError("Failed to write to stdin: " + err.Error())
 		return err
 	}
-
 	return nil
 }
 
 // handleSignal handles process signals
 func (wsConn *WebSocketConnection) handleSignal(msg types.WebSocketMessage) error {
-	if wsConn.job == nil {
-		wsConn.close(4003, "Not yet initialized")
+	session := wsConn.sessionFor(msg.SessionID)
+	if session == nil {
 		return nil
 	}
 
-	// Validate signal
-	validSignals := []string{"SIGTERM", "SIGKILL", "SIGINT"}
-	valid := false
-	for _, sig := range validSignals {
-		if msg.Signal == sig {
-			valid = true
-			break
-		}
-	}
-
-	if !valid {
+	// Validate signal against allowed_signals
+	if !wsConn.jobManager.SignalAllowed(msg.Signal) {
 		wsConn.close(4005, "Invalid signal")
 		return nil
 	}
 
 	// Send signal to running process
-	if err := wsConn.job.SendSignal(msg.Signal); err != nil {
+	if err := session.job.SendSignal(msg.Signal); err != nil {
 		wsConn.logger.WithError(err).Error("Failed to send signal")
-		wsConn.sendError("Failed to send signal: " + err.Error())
-		return err
+		return wsConn.sessionFailed(session, "Failed to send signal: ", err)
 	}
 
 	return nil
 }
 
-// executeJob executes the job and sends events
-func (wsConn *WebSocketConnection) executeJob(ctx context.Context) {
-	defer func() {
-		wsConn.close(4999, "Job Completed")
-	}()
-
-	// Start listening to job events
-	go func() {
-		for event := range wsConn.job.EventChannel {
-			wsConn.handleJobEvent(event)
+// handleJobEvent handles events from job execution
+func (session *wsSession) handleJobEvent(event types.StreamEvent) {
+	if event.Type == "lsp" {
+		session.send(wsFrame{text: []byte(event.Data)})
+		return
+	}
+	if session.binary && event.Type == "data" {
+		stream := binaryStdout
+		if event.Stream == "stderr" {
+			stream = binaryStderr
 		}
-	}()
-
-	// Execute the job with streaming
-	if err := wsConn.job.ExecuteStream(ctx); err != nil {
-		wsConn.sendError("Execution failed: " + err.Error())
+		session.send(wsFrame{data: append([]byte{stream}, event.Data...)})
 		return
 	}
+
+	if msg, ok := streamEventToMessage(session.job, event); ok {
+		session.send(wsFrame{msg: msg})
+	}
 }
 
-// handleJobEvent handles events from job execution
-func (wsConn *WebSocketConnection) handleJobEvent(event types.StreamEvent) {
+// streamEventToMessage converts a job stream event into the client message envelope
+func streamEventToMessage(j *job.Job, event types.StreamEvent) (types.WebSocketMessage, bool) {
 	switch event.Type {
 	case "runtime":
-		wsConn.sendMessage(types.WebSocketMessage{
+		return types.WebSocketMessage{
 			Type:     "runtime",
-			Language: wsConn.job.Runtime.Language,
-			Version:  wsConn.job.Runtime.Version.String(),
-		})
+			Language: j.Runtime.Language,
+			Version:  j.Runtime.Version.String(),
+		}, true
 	case "stage_start":
-		wsConn.sendMessage(types.WebSocketMessage{Type: "stage_start", Stage: event.Stage})
+		return types.WebSocketMessage{Type: "stage_start", Stage: event.Stage}, true
 	case "stage_end":
 		// include exit code (always present as pointer)
 		code := event.Code
-		wsConn.sendMessage(types.WebSocketMessage{Type: "stage_end", Stage: event.Stage, Code: &code})
+		return types.WebSocketMessage{Type: "stage_end", Stage: event.Stage, Code: &code, Truncated: event.Truncated}, true
+	case "result":
+		result, err := job.ShapeResult(event.Result, j.Omit)
+		if err != nil {
+			return types.WebSocketMessage{}, false
+		}
+		return types.WebSocketMessage{Type: "result", Result: result}, true
+	case "stats":
+		return types.WebSocketMessage{Type: "stats", Stage: event.Stage, StageStats: event.Stats}, true
 	case "data":
-		wsConn.sendMessage(types.WebSocketMessage{
+		return types.WebSocketMessage{
 			Type:   "data",
+			Stage:  event.Stage,
 			Stream: event.Stream,
 			Data:   event.Data,
-		})
+		}, true
 	case "exit":
-		wsConn.sendMessage(types.WebSocketMessage{
+		code := event.Code
+		return types.WebSocketMessage{
 			Type:  "exit",
 			Stage: event.Stage,
-			Code:  &event.Code,
-		})
+			Code:  &code,
+		}, true
 	case "error":
 		if event.Error != nil {
-			wsConn.sendError(event.Error.Error())
+			message := event.Error.Error()
+			return types.WebSocketMessage{Type: "error", Message: message, Error: message}, true
 		}
 	}
+	return types.WebSocketMessage{}, false
 }
 
 // sendStageResult sends stage execution result
@@ -497,7 +1355,12 @@ func (wsConn *WebSocketConnection) sendStageResult(stage string, result *types.S
 
 // eventSender sends events to the WebSocket client
 func (wsConn *WebSocketConnection) eventSender() {
-	for event := range wsConn.eventBus {
+	for frame := range wsConn.eventBus {
+		if frame.closeCode != 0 {
+			wsConn.close(frame.closeCode, frame.closeReason)
+			break
+		}
+
 		wsConn.mutex.Lock()
 		if wsConn.closed {
 			wsConn.mutex.Unlock()
@@ -505,17 +1368,57 @@ func (wsConn *WebSocketConnection) eventSender() {
 		}
 
 		wsConn.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-		if err := wsConn.conn.WriteJSON(event); err != nil {
+		var err error
+		if frame.data != nil {
+			err = wsConn.conn.WriteMessage(websocket.BinaryMessage, frame.data)
+		} else if frame.text != nil {
+			err = wsConn.conn.WriteMessage(websocket.TextMessage, frame.text)
+		} else if frame.batch != nil {
+			for _, msg := range frame.batch {
+				if err = wsConn.conn.WriteJSON(msg); err != nil {
+					break
+				}
+			}
+		} else {
+			err = wsConn.conn.WriteJSON(frame.msg)
+		}
+		if err != nil {
 			wsConn.logger.WithError(err).Error("Failed to send WebSocket message")
 			wsConn.mutex.Unlock()
+			// A client that stopped reading is gone; do not wait for the read timeout to notice
+			wsConn.close(1011, "Write failed")
 			break
 		}
 		wsConn.mutex.Unlock()
 	}
 }
 
+// pinger pings the client every interval until the connection closes; the pongs extend the
+// read deadline. Control frames may be written concurrently with the event sender.
+func (wsConn *WebSocketConnection) pinger(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wsConn.done:
+			return
+		case <-ticker.C:
+			if err := wsConn.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				wsConn.logger.WithError(err).Debug("Failed to ping WebSocket client")
+				return
+			}
+		}
+	}
+}
+
 // sendMessage sends a message to the client
 func (wsConn *WebSocketConnection) sendMessage(msg types.WebSocketMessage) {
+	wsConn.enqueue(wsFrame{msg: msg})
+}
+
+// enqueue queues a frame for the event sender
+func (wsConn *WebSocketConnection) enqueue(frame wsFrame) {
 	// Ensure we don't send on a closed channel; guard with mutex to avoid race with close()
 	wsConn.mutex.Lock()
 	if wsConn.closed {
@@ -523,7 +1426,7 @@ func (wsConn *WebSocketConnection) sendMessage(msg types.WebSocketMessage) {
 		return
 	}
 	select {
-	case wsConn.eventBus <- msg:
+	case wsConn.eventBus <- frame:
 		// sent
 	default:
 		wsConn.logger.Warn("Event bus full, dropping message")
@@ -533,12 +1436,59 @@ func (wsConn *WebSocketConnection) sendMessage(msg types.WebSocketMessage) {
 
 // sendError sends an error message
 func (wsConn *WebSocketConnection) sendError(message string) error {
-	wsConn.sendMessage(types.WebSocketMessage{
+	return wsConn.sendSessionError("", message)
+}
+
+// sendSessionError sends an error message about one session
+func (wsConn *WebSocketConnection) sendSessionError(id, message string) error {
+	msg := errorMessage(message)
+	msg.SessionID = id
+	wsConn.sendMessage(msg)
+	return nil
+}
+
+// errorMessage builds an error message
+func errorMessage(message string) types.WebSocketMessage {
+	return types.WebSocketMessage{
 		Type:    "error",
 		Message: message,
 		Error:   message, // keep for backward-compat with existing tests/clients
-	})
-	return nil
+	}
+}
+
+// sessionFailed reports a client request a session could not carry out. Only the single-job
+// protocol closes the connection over it; a named session's failure leaves the others running.
+func (wsConn *WebSocketConnection) sessionFailed(session *wsSession, message string, err error) error {
+	wsConn.sendSessionError(session.id, message+err.Error())
+	if session.id != "" {
+		return nil
+	}
+	return err
+}
+
+// isClosed reports whether the connection has been closed
+func (wsConn *WebSocketConnection) isClosed() bool {
+	wsConn.mutex.Lock()
+	defer wsConn.mutex.Unlock()
+	return wsConn.closed
+}
+
+// closeWhenSent closes the connection once the frames already queued have been written
+func (wsConn *WebSocketConnection) closeWhenSent(code int, message string) {
+	wsConn.mutex.Lock()
+	queued := false
+	if !wsConn.closed {
+		select {
+		case wsConn.eventBus <- wsFrame{closeCode: code, closeReason: message}:
+			queued = true
+		default:
+		}
+	}
+	wsConn.mutex.Unlock()
+
+	if !queued {
+		wsConn.close(code, message)
+	}
 }
 
 // close closes the WebSocket connection
@@ -552,6 +1502,11 @@ func (wsConn *WebSocketConnection) close(code int, message string) {
 
 	wsConn.closed = true
 	close(wsConn.eventBus)
+	close(wsConn.done)
+	// Kill a job still running for the client; its boxes are cleaned up as it returns
+	if wsConn.cancel != nil {
+		wsConn.cancel()
+	}
 
 	wsConn.conn.WriteControl(websocket.CloseMessage,
 		websocket.FormatCloseMessage(code, message),
@@ -559,26 +1514,3 @@ func (wsConn *WebSocketConnection) close(code int, message string) {
 
 	wsConn.conn.Close()
 }
-
-// validateJobRequest validates the job request for WebSocket
-func (wsConn *WebSocketConnection) validateJobRequest(request *types.JobRequest) error {
-	if request.Language == "" {
-		return wsConn.sendError("language is required")
-	}
-
-	if request.Version == "" {
-		return wsConn.sendError("version is required")
-	}
-
-	if len(request.Files) == 0 {
-		return wsConn.sendError("files array is required")
-	}
-
-	for i, file := range request.Files {
-		if file.Content == "" {
-			return wsConn.sendError("files[" + string(rune(i)) + "].content is required")
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,4 @@
+package history
+
+// The PostgreSQL driver is pure Go, so every build can use history_driver "postgres"
+import _ "github.com/lib/pq"
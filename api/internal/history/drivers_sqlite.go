@@ -0,0 +1,6 @@
+//go:build cgo
+
+package history
+
+// The SQLite driver needs cgo; history_driver "sqlite3" is only available in builds with it
+import _ "github.com/mattn/go-sqlite3"
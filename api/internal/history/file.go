@@ -0,0 +1,172 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore keeps records as JSON lines in a single append-only file. Listing scans the whole
+// file, so it suits small and medium deployments; use the sql backend for heavy analytics.
+type FileStore struct {
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewFileStore opens or creates the history file at path
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	return &FileStore{path: path, file: file}, nil
+}
+
+// Add appends a record
+func (s *FileStore) Add(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// List returns matching records, newest first
+func (s *FileStore) List(query Query) ([]Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var matched []Record
+	err := s.scan(func(record *Record) {
+		if query.matches(record) {
+			matched = append(matched, *record)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are appended in completion order; reverse for newest first
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+
+	if query.Offset >= len(matched) {
+		return []Record{}, nil
+	}
+	matched = matched[query.Offset:]
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+	return matched, nil
+}
+
+// Get returns the record with the given ID
+func (s *FileStore) Get(id string) (*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var found *Record
+	err := s.scan(func(record *Record) {
+		if record.ID == id {
+			found = record
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// Prune rewrites the file without records that started before cutoff
+func (s *FileStore) Prune(cutoff time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmpPath)
+
+	removed := 0
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	err = s.scan(func(record *Record) {
+		if record.StartedAt.Before(cutoff) {
+			removed++
+			return
+		}
+		_ = encoder.Encode(record)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || removed == 0 {
+		return 0, err
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return 0, err
+	}
+
+	// Reopen so appends go to the rewritten file
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return removed, err
+	}
+	s.file.Close()
+	s.file = file
+	return removed, nil
+}
+
+// Close closes the history file
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
+
+// scan calls fn for every readable record; the caller must hold the mutex
+func (s *FileStore) scan(fn func(*Record)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// Skip lines torn by a crash mid-write
+			continue
+		}
+		fn(&record)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,147 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func newTestStore(t *testing.T) *FileStore {
+	t.Helper()
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "history", "executions.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestFileStoreList(t *testing.T) {
+	store := newTestStore(t)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []Record{
+		{ID: "1", Language: "python", Requester: "alice", StartedAt: base},
+		{ID: "2", Language: "go", Requester: "bob", StartedAt: base.Add(time.Hour)},
+		{ID: "3", Language: "python", Requester: "bob", StartedAt: base.Add(2 * time.Hour),
+			Metadata: map[string]string{"assignment": "hw1", "course": "cs101"}},
+		{ID: "4", Language: "python", Requester: "alice", StartedAt: base.Add(3 * time.Hour)},
+	}
+	for i := range records {
+		if err := store.Add(&records[i]); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"all newest first", Query{}, []string{"4", "3", "2", "1"}},
+		{"language", Query{Language: "python"}, []string{"4", "3", "1"}},
+		{"requester", Query{Requester: "bob"}, []string{"3", "2"}},
+		{"metadata", Query{Metadata: map[string]string{"assignment": "hw1"}}, []string{"3"}},
+		{"metadata mismatch", Query{Metadata: map[string]string{"assignment": "hw1", "course": "cs102"}}, []string{}},
+		{"time range", Query{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"3", "2"}},
+		{"limit", Query{Limit: 2}, []string{"4", "3"}},
+		{"offset", Query{Offset: 1, Limit: 2}, []string{"3", "2"}},
+		{"offset past end", Query{Offset: 10}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.query)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			ids := []string{}
+			for _, r := range got {
+				ids = append(ids, r.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileStoreGet(t *testing.T) {
+	store := newTestStore(t)
+
+	submission := &types.JobRequest{Language: "python", Version: "3.12.0", Stdin: "1 2\n"}
+	if err := store.Add(&Record{ID: "a", Language: "python", Submission: submission}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := store.Get("a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Submission == nil || got.Submission.Stdin != "1 2\n" {
+		t.Errorf("Get() submission = %+v, want stdin kept", got.Submission)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of unknown ID error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStorePrune(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now()
+
+	for i, age := range []time.Duration{48 * time.Hour, 2 * time.Hour, time.Minute} {
+		if err := store.Add(&Record{ID: string(rune('a' + i)), StartedAt: now.Add(-age)}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	removed, err := store.Prune(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune() removed %d, want 1", removed)
+	}
+
+	// Appends after a prune land in the rewritten file
+	if err := store.Add(&Record{ID: "d", StartedAt: now}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	got, err := store.List(Query{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 3 || got[0].ID != "d" {
+		t.Errorf("List() after prune = %+v, want 3 records starting with d", got)
+	}
+}
+
+func TestRecorderTruncatesOutput(t *testing.T) {
+	store := newTestStore(t)
+	recorder := NewRecorder(store, 4, 0)
+
+	recorder.Record(&Record{ID: "1", Stdout: "hello world", Stderr: "err"})
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// Records after Close are ignored
+	recorder.Record(&Record{ID: "2"})
+
+	reopened := newTestStore(t)
+	reopened.path = store.path
+	got, err := reopened.List(Query{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("List() returned %d records, want 1", len(got))
+	}
+	if got[0].Stdout != "hell" || got[0].Stderr != "err" {
+		t.Errorf("output = %q/%q, want %q/%q", got[0].Stdout, got[0].Stderr, "hell", "err")
+	}
+}
@@ -0,0 +1,248 @@
+// Package history records every execution for auditing and analytics.
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+// Execution outcomes stored in Record.Status
+const (
+	StatusSuccess      = "success"
+	StatusCompileError = "compile_error"
+	StatusRuntimeError = "runtime_error"
+	StatusError        = "error"
+)
+
+// ErrNotFound is returned by Get for an unknown record ID
+var ErrNotFound = errors.New("history record not found")
+
+// queueSize bounds the number of records waiting to be written
+const queueSize = 1024
+
+// pruneInterval is how often records older than the retention period are deleted
+const pruneInterval = time.Hour
+
+// Record describes one execution
+type Record struct {
+	ID          string    `json:"id"`
+	JobID       string    `json:"job_id"`
+	RequestID   string    `json:"request_id,omitempty"`
+	Requester   string    `json:"requester,omitempty"`
+	Language    string    `json:"language"`
+	Version     string    `json:"version"`
+	Mode        string    `json:"mode"`
+	Status      string    `json:"status"`
+	StartedAt   time.Time `json:"started_at"`
+	DurationMs  int64     `json:"duration_ms"`
+	CompileCode *int      `json:"compile_code,omitempty"`
+	RunCode     *int      `json:"run_code,omitempty"`
+	Signal      string    `json:"signal,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Stdout      string    `json:"stdout,omitempty"`
+	Stderr      string    `json:"stderr,omitempty"`
+	// Metadata is the request's metadata
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Submission is the request as executed, pinned to the runtime version, kept when
+	// history_submissions is enabled so the execution can be replayed
+	Submission *types.JobRequest `json:"submission,omitempty"`
+}
+
+// Query filters records; zero values match everything. Results are newest first.
+type Query struct {
+	Language  string
+	Requester string
+	// Metadata matches records having every listed key with the given value
+	Metadata map[string]string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+	Offset   int
+}
+
+// matches reports whether r passes the query filters
+func (q Query) matches(r *Record) bool {
+	if q.Language != "" && r.Language != q.Language {
+		return false
+	}
+	if q.Requester != "" && r.Requester != q.Requester {
+		return false
+	}
+	for key, value := range q.Metadata {
+		if actual, ok := r.Metadata[key]; !ok || actual != value {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && r.StartedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.StartedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Store persists execution records
+type Store interface {
+	Add(record *Record) error
+	List(query Query) ([]Record, error)
+	// Get returns the record with the given ID, or ErrNotFound
+	Get(id string) (*Record, error)
+	// Prune deletes records that started before cutoff and returns how many were removed
+	Prune(cutoff time.Time) (int, error)
+	Close() error
+}
+
+// Recorder writes records to a store in the background, truncating output and enforcing retention
+type Recorder struct {
+	store       Store
+	outputLimit int
+	retention   time.Duration
+	queue       chan *Record
+	done        chan struct{}
+	closed      bool
+	mutex       sync.Mutex
+	logger      *logrus.Entry
+}
+
+// Open creates the recorder for the configured history backend, or returns nil when history is disabled
+func Open(cfg *config.Config) (*Recorder, error) {
+	var store Store
+	var err error
+
+	switch cfg.HistoryBackend {
+	case "":
+		return nil, nil
+	case "file":
+		store, err = NewFileStore(cfg.GetHistoryPath())
+	case "sql":
+		store, err = NewSQLStore(cfg.HistoryDriver, cfg.HistoryDSN)
+	default:
+		return nil, fmt.Errorf("unknown history backend %q", cfg.HistoryBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRecorder(store, cfg.HistoryOutputLimit, cfg.HistoryRetention), nil
+}
+
+// NewRecorder creates a recorder for store and starts its writer
+func NewRecorder(store Store, outputLimit int, retention time.Duration) *Recorder {
+	r := &Recorder{
+		store:       store,
+		outputLimit: outputLimit,
+		retention:   retention,
+		queue:       make(chan *Record, queueSize),
+		done:        make(chan struct{}),
+		logger:      logrus.WithField("component", "history"),
+	}
+	go r.run()
+	return r
+}
+
+// Record queues rec for writing without blocking; records are dropped when the queue is full.
+// A nil or closed recorder ignores records.
+func (r *Recorder) Record(rec *Record) {
+	if r == nil {
+		return
+	}
+
+	rec.Stdout = truncate(rec.Stdout, r.outputLimit)
+	rec.Stderr = truncate(rec.Stderr, r.outputLimit)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.queue <- rec:
+	default:
+		r.logger.WithField("job_id", rec.JobID).Warn("Dropping history record, queue is full")
+	}
+}
+
+// List returns records matching query
+func (r *Recorder) List(query Query) ([]Record, error) {
+	return r.store.List(query)
+}
+
+// Get returns the record with the given ID, or ErrNotFound
+func (r *Recorder) Get(id string) (*Record, error) {
+	return r.store.Get(id)
+}
+
+// Close flushes queued records and closes the store
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.queue)
+	r.mutex.Unlock()
+
+	<-r.done
+	return r.store.Close()
+}
+
+// run writes queued records and prunes expired ones
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	r.prune()
+
+	for {
+		select {
+		case rec, ok := <-r.queue:
+			if !ok {
+				return
+			}
+			if err := r.store.Add(rec); err != nil {
+				r.logger.WithError(err).WithField("job_id", rec.JobID).Error("Failed to write history record")
+			}
+		case <-ticker.C:
+			r.prune()
+		}
+	}
+}
+
+// prune deletes records older than the retention period; a retention of 0 keeps everything
+func (r *Recorder) prune() {
+	if r.retention <= 0 {
+		return
+	}
+
+	removed, err := r.store.Prune(time.Now().Add(-r.retention))
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to prune history")
+		return
+	}
+	if removed > 0 {
+		r.logger.Infof("Pruned %d history records older than %s", removed, r.retention)
+	}
+}
+
+// truncate shortens s to at most limit bytes; a limit of 0 drops the output entirely
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit]
+}
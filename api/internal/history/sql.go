@@ -0,0 +1,248 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// schema creates the history table; the types are understood by both SQLite and PostgreSQL
+const schema = `CREATE TABLE IF NOT EXISTS execution_history (
+	id TEXT PRIMARY KEY,
+	job_id TEXT NOT NULL,
+	request_id TEXT,
+	requester TEXT,
+	language TEXT NOT NULL,
+	version TEXT NOT NULL,
+	mode TEXT NOT NULL,
+	status TEXT NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	duration_ms BIGINT NOT NULL,
+	compile_code INTEGER,
+	run_code INTEGER,
+	signal TEXT,
+	error TEXT,
+	stdout TEXT,
+	stderr TEXT,
+	submission TEXT,
+	metadata TEXT
+)`
+
+// columns are the record columns in the order scanRecord reads them
+const columns = `id, job_id, request_id, requester, language, version, mode, status, started_at,
+	duration_ms, compile_code, run_code, signal, error, stdout, stderr, submission, metadata`
+
+// SQLStore keeps records in a database/sql database, through the "postgres" driver or, in
+// builds with cgo, "sqlite3"
+type SQLStore struct {
+	db       *sql.DB
+	postgres bool
+}
+
+// NewSQLStore connects to the database and creates the history table if needed
+func NewSQLStore(driver, dsn string) (*SQLStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history table: %w", err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS execution_history_started_at ON execution_history (started_at)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history index: %w", err)
+	}
+	// Tables created before submissions and metadata were recorded lack their columns
+	for _, column := range []string{"submission", "metadata"} {
+		if _, err := db.Exec(`SELECT ` + column + ` FROM execution_history WHERE 1 = 0`); err != nil {
+			if _, err := db.Exec(`ALTER TABLE execution_history ADD COLUMN ` + column + ` TEXT`); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("failed to add history %s column: %w", column, err)
+			}
+		}
+	}
+
+	return &SQLStore{db: db, postgres: driver == "postgres" || driver == "pgx"}, nil
+}
+
+// bind rewrites ? placeholders to $n for PostgreSQL
+func (s *SQLStore) bind(query string) string {
+	if !s.postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Add inserts a record
+func (s *SQLStore) Add(r *Record) error {
+	submission, err := nullJSON(r.Submission, r.Submission != nil)
+	if err != nil {
+		return err
+	}
+	metadata, err := nullJSON(r.Metadata, len(r.Metadata) > 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(s.bind(`INSERT INTO execution_history (`+columns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		r.ID, r.JobID, r.RequestID, r.Requester, r.Language, r.Version, r.Mode, r.Status,
+		r.StartedAt.UTC(), r.DurationMs, r.CompileCode, r.RunCode, r.Signal, r.Error, r.Stdout, r.Stderr,
+		submission, metadata)
+	return err
+}
+
+// nullJSON encodes v for a TEXT column, or NULL when present is false
+func nullJSON(v interface{}, present bool) (sql.NullString, error) {
+	if !present {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// Get returns the record with the given ID
+func (s *SQLStore) Get(id string) (*Record, error) {
+	row := s.db.QueryRow(s.bind(`SELECT `+columns+` FROM execution_history WHERE id = ?`), id)
+	record, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return record, err
+}
+
+// List returns matching records, newest first
+func (s *SQLStore) List(q Query) ([]Record, error) {
+	var where []string
+	var args []interface{}
+	if q.Language != "" {
+		where = append(where, "language = ?")
+		args = append(args, q.Language)
+	}
+	if q.Requester != "" {
+		where = append(where, "requester = ?")
+		args = append(args, q.Requester)
+	}
+	// Metadata is stored as a JSON object; a "key":"value" pair is matched as a substring of
+	// it, which is exact since quotes inside keys and values are escaped
+	for key, value := range q.Metadata {
+		pair, err := json.Marshal(map[string]string{key: value})
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, `metadata LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(string(pair[1:len(pair)-1]))+"%")
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "started_at < ?")
+		args = append(args, q.Until.UTC())
+	}
+
+	query := `SELECT ` + columns + ` FROM execution_history`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC"
+	if q.Limit > 0 || q.Offset > 0 {
+		limit := q.Limit
+		if limit <= 0 {
+			limit = math.MaxInt32
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, q.Offset)
+	}
+
+	rows, err := s.db.Query(s.bind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *r)
+	}
+	return records, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards, and the escape character itself
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// scanRecord reads a row selected with columns
+func scanRecord(row interface{ Scan(...interface{}) error }) (*Record, error) {
+	var r Record
+	var requestID, requester, signal, errMsg, stdout, stderr, submission, metadata sql.NullString
+	var compileCode, runCode sql.NullInt64
+	if err := row.Scan(&r.ID, &r.JobID, &requestID, &requester, &r.Language, &r.Version, &r.Mode,
+		&r.Status, &r.StartedAt, &r.DurationMs, &compileCode, &runCode, &signal, &errMsg,
+		&stdout, &stderr, &submission, &metadata); err != nil {
+		return nil, err
+	}
+	r.RequestID, r.Requester, r.Signal = requestID.String, requester.String, signal.String
+	r.Error, r.Stdout, r.Stderr = errMsg.String, stdout.String, stderr.String
+	r.CompileCode = nullInt(compileCode)
+	r.RunCode = nullInt(runCode)
+	if submission.Valid && submission.String != "" {
+		if err := json.Unmarshal([]byte(submission.String), &r.Submission); err != nil {
+			return nil, fmt.Errorf("invalid submission of history record %s: %w", r.ID, err)
+		}
+	}
+	if metadata.Valid && metadata.String != "" {
+		if err := json.Unmarshal([]byte(metadata.String), &r.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata of history record %s: %w", r.ID, err)
+		}
+	}
+	return &r, nil
+}
+
+// Prune deletes records that started before cutoff
+func (s *SQLStore) Prune(cutoff time.Time) (int, error) {
+	result, err := s.db.Exec(s.bind(`DELETE FROM execution_history WHERE started_at < ?`), cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// Close closes the database
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// nullInt converts a nullable column to an optional int
+func nullInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
@@ -0,0 +1,122 @@
+//go:build cgo
+
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func newTestSQLStore(t *testing.T) *SQLStore {
+	t.Helper()
+	store, err := NewSQLStore("sqlite3", filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("NewSQLStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLStoreList(t *testing.T) {
+	store := newTestSQLStore(t)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []Record{
+		{ID: "1", Language: "python", Requester: "alice", StartedAt: base},
+		{ID: "2", Language: "go", Requester: "bob", StartedAt: base.Add(time.Hour)},
+		{ID: "3", Language: "python", Requester: "bob", StartedAt: base.Add(2 * time.Hour),
+			Metadata: map[string]string{"assignment": "hw_1", "course": "cs101"}},
+		{ID: "4", Language: "python", Requester: "alice", StartedAt: base.Add(3 * time.Hour),
+			Metadata: map[string]string{"assignment": "hwx1"}},
+	}
+	for i := range records {
+		if err := store.Add(&records[i]); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"all newest first", Query{}, []string{"4", "3", "2", "1"}},
+		{"language", Query{Language: "python"}, []string{"4", "3", "1"}},
+		{"requester", Query{Requester: "bob"}, []string{"3", "2"}},
+		// The _ must not act as a LIKE wildcard and match hwx1
+		{"metadata", Query{Metadata: map[string]string{"assignment": "hw_1"}}, []string{"3"}},
+		{"metadata mismatch", Query{Metadata: map[string]string{"assignment": "hw_1", "course": "cs102"}}, []string{}},
+		{"time range", Query{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, []string{"3", "2"}},
+		{"limit", Query{Limit: 2}, []string{"4", "3"}},
+		{"offset", Query{Offset: 1}, []string{"3", "2", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.query)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			ids := []string{}
+			for _, r := range got {
+				ids = append(ids, r.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLStoreGet(t *testing.T) {
+	store := newTestSQLStore(t)
+
+	code := 3
+	submission := &types.JobRequest{Language: "python", Version: "3.12.0", Stdin: "1 2\n"}
+	record := &Record{ID: "a", JobID: "job", Language: "python", Version: "3.12.0", RunCode: &code,
+		StartedAt: time.Now(), Stdout: "out", Submission: submission}
+	if err := store.Add(record); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	got, err := store.Get("a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.JobID != "job" || got.Stdout != "out" || got.RunCode == nil || *got.RunCode != 3 || got.CompileCode != nil {
+		t.Errorf("Get() = %+v, want the added record", got)
+	}
+	if got.Submission == nil || got.Submission.Stdin != "1 2\n" {
+		t.Errorf("Get() submission = %+v, want stdin kept", got.Submission)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of unknown ID error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLStorePrune(t *testing.T) {
+	store := newTestSQLStore(t)
+	now := time.Now()
+
+	for i, age := range []time.Duration{48 * time.Hour, 2 * time.Hour, time.Minute} {
+		if err := store.Add(&Record{ID: string(rune('a' + i)), StartedAt: now.Add(-age)}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	removed, err := store.Prune(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune() removed %d, want 1", removed)
+	}
+	if got, _ := store.List(Query{}); len(got) != 2 {
+		t.Errorf("List() after prune returned %d records, want 2", len(got))
+	}
+}
@@ -0,0 +1,18 @@
+package history
+
+import "testing"
+
+func TestSQLStoreBind(t *testing.T) {
+	query := `SELECT id FROM execution_history WHERE language = ? AND started_at >= ? LIMIT ? OFFSET ?`
+
+	sqlite := &SQLStore{}
+	if got := sqlite.bind(query); got != query {
+		t.Errorf("bind() for SQLite = %q, want the query unchanged", got)
+	}
+
+	postgres := &SQLStore{postgres: true}
+	want := `SELECT id FROM execution_history WHERE language = $1 AND started_at >= $2 LIMIT $3 OFFSET $4`
+	if got := postgres.bind(query); got != want {
+		t.Errorf("bind() for PostgreSQL = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,245 @@
+package job
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats accepted in the archive field, detected from the content
+const (
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// errArchiveTooLarge is returned when an archive exceeds archive_max_size or archive_max_files
+var errArchiveTooLarge = errors.New("archive exceeds the configured size or file count limit")
+
+// decodeArchive decodes a base64 archive and detects whether it is a tar.gz or a zip
+func decodeArchive(encoded string) ([]byte, string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("archive must be base64 encoded")
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return data, archiveTarGz, nil
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return data, archiveZip, nil
+	}
+	return nil, "", fmt.Errorf("archive must be a tar.gz or zip file")
+}
+
+// ValidateArchive checks that a request archive is a base64 tar.gz or zip whose entries are
+// plain files and directories inside the submission directory. Size limits are enforced when
+// the archive is extracted.
+func ValidateArchive(encoded string) error {
+	if encoded == "" {
+		return nil
+	}
+	data, format, err := decodeArchive(encoded)
+	if err != nil {
+		return err
+	}
+
+	e := &archiveExtractor{dryRun: true}
+	if format == archiveZip {
+		return e.extractZip(data)
+	}
+	return e.extractTarGz(data)
+}
+
+// archiveExtractor writes archive entries below dir within a size and file count budget
+type archiveExtractor struct {
+	dir string
+	// dryRun only checks entry names, without writing anything
+	dryRun   bool
+	maxSize  int64 // <=0 means unlimited
+	maxFiles int   // <=0 means unlimited
+	size     int64
+	// files lists the regular files written, slash-separated and relative to dir
+	files []string
+}
+
+// target validates an entry name and returns where it is extracted to
+func (e *archiveExtractor) target(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("invalid archive entry: %s", name)
+		}
+	}
+	// Leading slashes and "./" prefixes are dropped, so entries always land inside dir
+	rel := strings.TrimPrefix(path.Clean("/"+slashed), "/")
+	if rel == "" {
+		return "", nil
+	}
+	return filepath.Join(e.dir, filepath.FromSlash(rel)), nil
+}
+
+// mkdir creates a directory entry
+func (e *archiveExtractor) mkdir(name string) error {
+	target, err := e.target(name)
+	if err != nil || target == "" || e.dryRun {
+		return err
+	}
+	return os.MkdirAll(target, 0700)
+}
+
+// writeFile creates a regular file entry, keeping its executable bits
+func (e *archiveExtractor) writeFile(name string, mode fs.FileMode, r io.Reader) error {
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		return fmt.Errorf("invalid archive entry: %s", name)
+	}
+	if e.dryRun {
+		return nil
+	}
+
+	if e.maxFiles > 0 && len(e.files) >= e.maxFiles {
+		return errArchiveTooLarge
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Only permission bits are kept; like the files array, every file is at least 0644
+	perm := mode.Perm() | 0644
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	defer f.Close()
+
+	if e.maxSize > 0 {
+		r = io.LimitReader(r, e.maxSize-e.size+1)
+	}
+	n, err := io.Copy(f, r)
+	e.size += n
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	if e.maxSize > 0 && e.size > e.maxSize {
+		return errArchiveTooLarge
+	}
+	// OpenFile does not change the mode of a file the files array or an earlier entry created
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+
+	e.files = append(e.files, filepath.ToSlash(strings.TrimPrefix(target, e.dir+string(filepath.Separator))))
+	return nil
+}
+
+// extractTarGz extracts a gzip-compressed tarball
+func (e *archiveExtractor) extractTarGz(data []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid tar.gz archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar.gz archive: %w", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = e.mkdir(header.Name)
+		case tar.TypeReg:
+			err = e.writeFile(header.Name, header.FileInfo().Mode(), tr)
+		case tar.TypeXGlobalHeader:
+			// pax metadata, nothing to extract
+		default:
+			// Links and device files could point outside the submission directory
+			err = fmt.Errorf("unsupported archive entry type for %s", header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts a zip file
+func (e *archiveExtractor) extractZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	for _, file := range zr.File {
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			err = e.mkdir(file.Name)
+		case mode.IsRegular():
+			err = e.extractZipFile(file)
+		default:
+			err = fmt.Errorf("unsupported archive entry type for %s", file.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile extracts a single regular file from a zip
+func (e *archiveExtractor) extractZipFile(file *zip.File) error {
+	if e.dryRun {
+		return e.writeFile(file.Name, file.Mode(), nil)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	defer rc.Close()
+
+	// Zips made on systems without Unix modes report 0666 for files, which is fine
+	return e.writeFile(file.Name, file.Mode(), rc)
+}
+
+// extractArchive extracts the job's archive into the submission directory and returns the
+// regular files it contained, in archive order
+func (j *Job) extractArchive(submissionDir string) ([]string, error) {
+	data, format, err := decodeArchive(j.Archive)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &archiveExtractor{
+		dir:      submissionDir,
+		maxSize:  j.manager.config.ArchiveMaxSize,
+		maxFiles: j.manager.config.ArchiveMaxFiles,
+	}
+	if format == archiveZip {
+		err = e.extractZip(data)
+	} else {
+		err = e.extractTarGz(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.files, nil
+}
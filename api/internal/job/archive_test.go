@@ -0,0 +1,167 @@
+package job
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+// archiveEntry is a file or directory (trailing slash) in a test archive
+type archiveEntry struct {
+	name    string
+	content string
+	mode    int64
+}
+
+func tarGzArchive(t *testing.T, entries []archiveEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: entry.mode, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.name[len(entry.name)-1] == '/' {
+			header.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func zipArchive(t *testing.T, entries []archiveEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		header.SetMode(os.FileMode(entry.mode))
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zw.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestValidateArchive(t *testing.T) {
+	files := []archiveEntry{{name: "main.py", content: "print(1)", mode: 0644}}
+
+	var symlink bytes.Buffer
+	gz := gzip.NewWriter(&symlink)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "link", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink})
+	tw.Close()
+	gz.Close()
+
+	tests := []struct {
+		name    string
+		archive string
+		wantErr bool
+	}{
+		{"Empty", "", false},
+		{"Tar Gz", tarGzArchive(t, files), false},
+		{"Zip", zipArchive(t, files), false},
+		{"Not Base64", "not base64!", true},
+		{"Unknown Format", base64.StdEncoding.EncodeToString([]byte("plain text")), true},
+		{"Path Traversal", tarGzArchive(t, []archiveEntry{{name: "../escape.py", content: "x", mode: 0644}}), true},
+		{"Zip Path Traversal", zipArchive(t, []archiveEntry{{name: "a/../../escape.py", content: "x", mode: 0644}}), true},
+		{"Symlink", base64.StdEncoding.EncodeToString(symlink.Bytes()), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArchive(tt.archive)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	entries := []archiveEntry{
+		{name: "src/", mode: 0755},
+		{name: "src/main.c", content: "int main() {}", mode: 0644},
+		{name: "src/lib/util.c", content: "void f() {}", mode: 0644},
+		{name: "build.sh", content: "#!/bin/sh", mode: 0755},
+	}
+
+	for name, archive := range map[string]string{"tar.gz": tarGzArchive(t, entries), "zip": zipArchive(t, entries)} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			j := &Job{Archive: archive, manager: &Manager{config: &config.Config{}}}
+
+			files, err := j.extractArchive(dir)
+			if err != nil {
+				t.Fatalf("Failed to extract: %v", err)
+			}
+			want := []string{"src/main.c", "src/lib/util.c", "build.sh"}
+			if !reflect.DeepEqual(files, want) {
+				t.Errorf("Expected files %v, got %v", want, files)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, "src", "lib", "util.c"))
+			if err != nil || string(content) != "void f() {}" {
+				t.Errorf("Expected nested file content, got %q (%v)", content, err)
+			}
+
+			info, err := os.Stat(filepath.Join(dir, "build.sh"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm()&0100 == 0 {
+				t.Errorf("Expected build.sh to stay executable, got %v", info.Mode())
+			}
+		})
+	}
+}
+
+func TestExtractArchiveLimits(t *testing.T) {
+	entries := []archiveEntry{
+		{name: "a.txt", content: "0123456789", mode: 0644},
+		{name: "b.txt", content: "0123456789", mode: 0644},
+	}
+	archive := tarGzArchive(t, entries)
+
+	tests := []struct {
+		name     string
+		maxSize  int64
+		maxFiles int
+		wantErr  bool
+	}{
+		{"Unlimited", 0, 0, false},
+		{"Within Limits", 20, 2, false},
+		{"Too Large", 15, 0, true},
+		{"Too Many Files", 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ArchiveMaxSize: tt.maxSize, ArchiveMaxFiles: tt.maxFiles}
+			j := &Job{Archive: archive, manager: &Manager{config: cfg}}
+			_, err := j.extractArchive(t.TempDir())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
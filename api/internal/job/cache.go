@@ -0,0 +1,279 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// CompileCache stores compiled submission directories keyed by a content hash
+// so repeated executions of the same program can skip the compile stage.
+type CompileCache struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+	size    int64
+	mutex   sync.RWMutex
+	logger  *logrus.Entry
+}
+
+// cacheEntry is a single cached compile output
+type cacheEntry struct {
+	path     string
+	size     int64
+	created  time.Time
+	lastUsed atomic.Int64 // unix nanoseconds
+	result   types.StageResult
+}
+
+// NewCompileCache creates a compile cache rooted at dir. The index lives in memory,
+// so any leftovers from a previous run are removed.
+func NewCompileCache(dir string, maxSize int64, ttl time.Duration) (*CompileCache, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear compile cache directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create compile cache directory: %w", err)
+	}
+
+	return &CompileCache{
+		dir:     dir,
+		maxSize: maxSize,
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+		logger:  logrus.WithField("component", "compile_cache"),
+	}, nil
+}
+
+// compileCacheKey hashes everything that can influence the compile output
+func compileCacheKey(j *Job) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", j.Runtime.Language, j.Runtime.Version.String(), j.Runtime.PkgDir)
+	fmt.Fprintf(h, "entrypoint\x00%s\x00", j.entrypoint())
+	for _, flag := range j.CompileFlags {
+		fmt.Fprintf(h, "flag\x00%s\x00", flag)
+	}
+	for _, name := range sortedKeys(j.Env) {
+		fmt.Fprintf(h, "env\x00%s\x00%s\x00", name, j.Env[name])
+	}
+	for _, file := range j.Files {
+		fmt.Fprintf(h, "file\x00%s\x00%s\x00%d\x00", file.Name, file.Encoding, len(file.Content))
+		io.WriteString(h, file.Content)
+	}
+	fmt.Fprintf(h, "archive\x00%d\x00", len(j.Archive))
+	io.WriteString(h, j.Archive)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get restores the cached submission directory for key into dest and returns the
+// original compile result
+func (c *CompileCache) Get(key, dest string) (*types.StageResult, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.created) > c.ttl {
+		return nil, false
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		c.logger.WithError(err).Warn("Failed to clear submission directory for cache restore")
+		return nil, false
+	}
+	if _, err := copyTree(entry.path, dest); err != nil {
+		c.logger.WithError(err).Warn("Failed to restore compiled submission from cache")
+		return nil, false
+	}
+
+	entry.lastUsed.Store(time.Now().UnixNano())
+	result := entry.result
+	return &result, true
+}
+
+// Put copies the compiled submission directory into the cache
+func (c *CompileCache) Put(key, src string, result *types.StageResult) {
+	c.mutex.RLock()
+	_, exists := c.entries[key]
+	c.mutex.RUnlock()
+	if exists {
+		return
+	}
+
+	path := filepath.Join(c.dir, key)
+	tmp, err := os.MkdirTemp(c.dir, key+".tmp-")
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to create compile cache entry")
+		return
+	}
+
+	size, err := copyTree(src, filepath.Join(tmp, "submission"))
+	if err != nil || (c.maxSize > 0 && size > c.maxSize) {
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to copy compiled submission into cache")
+		}
+		os.RemoveAll(tmp)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Another job may have cached the same key while we were copying
+	if _, exists := c.entries[key]; exists {
+		os.RemoveAll(tmp)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		c.logger.WithError(err).Warn("Failed to commit compile cache entry")
+		os.RemoveAll(tmp)
+		return
+	}
+
+	entry := &cacheEntry{
+		path:    filepath.Join(path, "submission"),
+		size:    size,
+		created: time.Now(),
+		result:  *result,
+	}
+	entry.lastUsed.Store(entry.created.UnixNano())
+	c.entries[key] = entry
+	c.size += size
+
+	c.evictLocked(time.Now())
+}
+
+// Evict removes expired entries and, if still over budget, the least recently used ones
+func (c *CompileCache) Evict(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evictLocked(now)
+}
+
+// evictLocked performs eviction; callers must hold the write lock
+func (c *CompileCache) evictLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.created) > c.ttl {
+			c.removeLocked(key)
+		}
+	}
+
+	if c.maxSize <= 0 || c.size <= c.maxSize {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return c.entries[keys[a]].lastUsed.Load() < c.entries[keys[b]].lastUsed.Load()
+	})
+
+	for _, key := range keys {
+		if c.size <= c.maxSize {
+			break
+		}
+		c.removeLocked(key)
+	}
+}
+
+// removeLocked deletes a single entry; callers must hold the write lock
+func (c *CompileCache) removeLocked(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+
+	delete(c.entries, key)
+	c.size -= entry.size
+	if err := os.RemoveAll(filepath.Dir(entry.path)); err != nil {
+		c.logger.WithError(err).Warnf("Failed to remove compile cache entry %s", key)
+	}
+}
+
+// copyTree recursively copies src to dst preserving modes and ownership, returning the bytes copied
+func copyTree(src, dst string) (int64, error) {
+	var total int64
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			n, err := copyFile(path, target, info.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			total += n
+		default:
+			// Skip sockets, devices and other special files
+			return nil
+		}
+
+		copyOwner(target, info)
+		if info.Mode()&fs.ModeSymlink == 0 {
+			// Creation is subject to the umask, so apply the exact mode afterwards
+			_ = os.Chmod(target, info.Mode().Perm())
+		}
+		return nil
+	})
+
+	return total, err
+}
+
+// copyFile copies a single regular file
+func copyFile(src, dst string, mode fs.FileMode) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
@@ -0,0 +1,58 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestCompileCache(t *testing.T) {
+	root := t.TempDir()
+	cache, err := NewCompileCache(filepath.Join(root, "cache"), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	src := filepath.Join(root, "src")
+	if err := os.MkdirAll(filepath.Join(src, "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "main"), []byte("binary"), 0755); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	code := 0
+	cache.Put("a", src, &types.StageResult{Stdout: "compiled", Code: &code})
+
+	dest := filepath.Join(root, "dest")
+	result, ok := cache.Get("a", dest)
+	if !ok || result.Stdout != "compiled" {
+		t.Fatalf("Expected cache hit with original result, got %+v, %v", result, ok)
+	}
+
+	info, err := os.Stat(filepath.Join(dest, "bin", "main"))
+	if err != nil {
+		t.Fatalf("Expected restored file: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("Expected mode 0755, got %v", info.Mode().Perm())
+	}
+
+	// A second entry pushes the cache over its 10 byte budget, evicting the older one
+	cache.Put("b", src, &types.StageResult{Code: &code})
+	if _, ok := cache.Get("b", dest); !ok {
+		t.Error("Expected newest entry to be cached")
+	}
+	if _, ok := cache.Get("a", dest); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+
+	// Entries expire after the TTL
+	cache.Evict(time.Now().Add(2 * time.Minute))
+	if _, ok := cache.Get("b", dest); ok {
+		t.Error("Expected expired entry to be evicted")
+	}
+}
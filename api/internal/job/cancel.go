@@ -0,0 +1,59 @@
+package job
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+var (
+	// ErrJobNotFound is returned when cancelling an async job that does not exist, has expired
+	// or was submitted with another API key
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling an async job that has already ended
+	ErrJobFinished = errors.New("job has already finished")
+)
+
+// CancelAsyncJob cancels a queued or running async job. A queued job leaves the queue; a
+// running one has its sandbox killed with SIGKILL and its boxes cleaned up in the background.
+// The returned record is already marked cancelled. Only the job's submitter may cancel it.
+func (m *Manager) CancelAsyncJob(id, submitter string) (*types.AsyncJob, error) {
+	if m.store == nil {
+		return nil, ErrJobNotFound
+	}
+
+	record, err := m.store.Cancel(id, submitter)
+	if err != nil {
+		return nil, err
+	}
+
+	m.cancelMutex.Lock()
+	cancel := m.cancels[id]
+	m.cancelMutex.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	return record, nil
+}
+
+// trackAsync returns a context for an async job that CancelAsyncJob cancels, and a function
+// to call once the job has ended
+func (m *Manager) trackAsync(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	m.cancelMutex.Lock()
+	if m.cancels == nil {
+		m.cancels = make(map[string]context.CancelFunc)
+	}
+	m.cancels[id] = cancel
+	m.cancelMutex.Unlock()
+
+	return ctx, func() {
+		m.cancelMutex.Lock()
+		delete(m.cancels, id)
+		m.cancelMutex.Unlock()
+		cancel()
+	}
+}
@@ -0,0 +1,50 @@
+package job
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// applyDiskUsage records the box's disk usage in result and flags a stage that filled its quota,
+// reporting whether it did
+func (j *Job) applyDiskUsage(box *types.IsolateBox, result *types.StageResult) bool {
+	usage, err := diskUsage(box.Dir)
+	if err != nil {
+		j.logger.WithError(err).Warn("Failed to measure disk usage")
+		return false
+	}
+
+	result.DiskUsage = usage
+	if j.DiskQuota > 0 && usage >= j.DiskQuota && result.Status != "" {
+		result.Message = "Disk quota exceeded"
+		return true
+	}
+	return false
+}
+
+// diskUsage returns the total size in bytes of the regular files under dir
+func diskUsage(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files removed while walking are not counted
+			if path != dir {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,56 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "submission", "out"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int{
+		"submission/main.py":      100,
+		"submission/out/data.bin": 4096,
+		"tmp.txt":                 1,
+	}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := diskUsage(dir)
+	if err != nil {
+		t.Fatalf("diskUsage() error = %v", err)
+	}
+	if usage != 4197 {
+		t.Errorf("diskUsage() = %d, want 4197", usage)
+	}
+
+	if _, err := diskUsage(filepath.Join(dir, "missing")); err == nil {
+		t.Error("diskUsage() of a missing directory should fail")
+	}
+}
+
+func TestQuotaBlocks(t *testing.T) {
+	tests := []struct {
+		quota int64
+		want  int64
+	}{
+		{-1, 0},
+		{0, 0},
+		{1, 1},
+		{1024, 1},
+		{1025, 2},
+		{1 << 20, 1024},
+	}
+
+	for _, tt := range tests {
+		if got := quotaBlocks(tt.quota); got != tt.want {
+			t.Errorf("quotaBlocks(%d) = %d, want %d", tt.quota, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDraining is returned for new executions once the server has started shutting down
+var ErrDraining = errors.New("server is shutting down")
+
+// drainLogInterval is how often drain progress is logged
+const drainLogInterval = 5 * time.Second
+
+// drainKillTimeout bounds the wait for killed jobs to clean up after the drain timeout
+const drainKillTimeout = 10 * time.Second
+
+// begin registers a job as in flight. The returned context is cancelled, killing the job's
+// sandbox, if draining times out; done must be called once the job has cleaned up.
+func (m *Manager) begin(ctx context.Context) (context.Context, func(), error) {
+	m.drainMutex.Lock()
+	if m.draining {
+		m.drainMutex.Unlock()
+		return nil, nil, ErrDraining
+	}
+	m.inflight.Add(1)
+	m.running.Add(1)
+	m.drainMutex.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(m.abort, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		m.running.Add(-1)
+		m.inflight.Done()
+	}, nil
+}
+
+// Draining reports whether the manager has stopped accepting new jobs
+func (m *Manager) Draining() bool {
+	m.drainMutex.Lock()
+	defer m.drainMutex.Unlock()
+
+	return m.draining
+}
+
+// Drain stops accepting new jobs and waits for in-flight ones, including queued jobs, to finish.
+// Jobs still running when ctx is done are killed. Pooled boxes are torn down before Drain returns.
+func (m *Manager) Drain(ctx context.Context) {
+	m.drainMutex.Lock()
+	m.draining = true
+	m.drainMutex.Unlock()
+
+	idle := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(idle)
+	}()
+
+	ticker := time.NewTicker(drainLogInterval)
+	defer ticker.Stop()
+
+	m.logger.Infof("Draining %d in-flight jobs", m.running.Load())
+	for waiting := true; waiting; {
+		select {
+		case <-idle:
+			waiting = false
+		case <-ticker.C:
+			m.logger.Infof("Waiting for %d in-flight jobs", m.running.Load())
+		case <-ctx.Done():
+			m.logger.Warnf("Drain timeout reached, killing %d jobs", m.running.Load())
+			m.abortJobs()
+			select {
+			case <-idle:
+			case <-time.After(drainKillTimeout):
+				m.logger.Errorf("%d jobs did not stop after being killed", m.running.Load())
+			}
+			waiting = false
+		}
+	}
+
+	m.warm.Close()
+	m.sandbox.Close()
+	m.logger.Info("Drain complete")
+}
@@ -0,0 +1,87 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newDrainTestManager() *Manager {
+	m := &Manager{
+		logger:  logrus.NewEntry(logrus.New()),
+		sandbox: &isolateSandbox{pool: &BoxPool{}},
+	}
+	m.abort, m.abortJobs = context.WithCancel(context.Background())
+	return m
+}
+
+func TestDrainWaitsForInFlightJobs(t *testing.T) {
+	m := newDrainTestManager()
+	_, done, err := m.begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin() error = %v", err)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		m.Drain(context.Background())
+		close(drained)
+	}()
+
+	// New jobs are rejected as soon as draining starts
+	deadline := time.Now().Add(time.Second)
+	for !m.Draining() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, err := m.begin(context.Background()); !errors.Is(err, ErrDraining) {
+		t.Errorf("begin() while draining error = %v, want ErrDraining", err)
+	}
+
+	select {
+	case <-drained:
+		t.Fatal("Drain returned with a job in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	done()
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return after the last job finished")
+	}
+}
+
+func TestDrainTimeoutKillsJobs(t *testing.T) {
+	m := newDrainTestManager()
+	ctx, done, err := m.begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin() error = %v", err)
+	}
+
+	// The job finishes once its context is cancelled, as a killed sandbox would
+	go func() {
+		<-ctx.Done()
+		done()
+	}()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		m.Drain(drainCtx)
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not kill the job after the timeout")
+	}
+	if n := m.running.Load(); n != 0 {
+		t.Errorf("running = %d after drain, want 0", n)
+	}
+}
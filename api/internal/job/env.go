@@ -0,0 +1,55 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envNamePattern matches portable POSIX environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnv checks request environment variables against the configured allow/deny lists
+func (m *Manager) ValidateEnv(env map[string]string) error {
+	for _, name := range sortedKeys(env) {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("env variable name %q is invalid", name)
+		}
+		if strings.ContainsRune(env[name], 0) {
+			return fmt.Errorf("env variable %s must not contain NUL bytes", name)
+		}
+		if matchesEnvPattern(m.config.EnvDenylist, name) {
+			return fmt.Errorf("env variable %s is not allowed", name)
+		}
+		if len(m.config.EnvAllowlist) > 0 && !matchesEnvPattern(m.config.EnvAllowlist, name) {
+			return fmt.Errorf("env variable %s is not in the allowlist", name)
+		}
+	}
+	return nil
+}
+
+// matchesEnvPattern reports whether name matches any pattern; a trailing "*" matches a prefix.
+// Build flags are matched against build_flag_allowlist the same way.
+func matchesEnvPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns map keys in a stable order so isolate arguments are deterministic
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
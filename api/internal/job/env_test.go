@@ -0,0 +1,40 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestValidateEnv(t *testing.T) {
+	m := &Manager{config: &config.Config{EnvDenylist: []string{"PATH", "LD_*"}}}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"Empty", nil, false},
+		{"Allowed", map[string]string{"DEBUG": "1", "_X": "y"}, false},
+		{"Denied Exact", map[string]string{"PATH": "/tmp"}, true},
+		{"Denied Prefix", map[string]string{"LD_PRELOAD": "evil.so"}, true},
+		{"Invalid Name", map[string]string{"1BAD": "x"}, true},
+		{"Name With Equals", map[string]string{"A=B": "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.ValidateEnv(tt.env); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	m.config.EnvAllowlist = []string{"APP_*"}
+	if err := m.ValidateEnv(map[string]string{"APP_MODE": "test"}); err != nil {
+		t.Errorf("Expected allowlisted variable to pass, got %v", err)
+	}
+	if err := m.ValidateEnv(map[string]string{"DEBUG": "1"}); err == nil {
+		t.Error("Expected variable outside the allowlist to be rejected")
+	}
+}
@@ -0,0 +1,128 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// fixtureNamePattern matches fixture names. They never start with a dot, which keeps uploads in
+// progress out of listings.
+var fixtureNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+var (
+	// ErrFixtureNotFound is returned for a fixture that was never uploaded or has been deleted
+	ErrFixtureNotFound = errors.New("fixture not found")
+	// ErrFixtureTooLarge is returned for an upload larger than stdin_max_size
+	ErrFixtureTooLarge = errors.New("fixture exceeds stdin_max_size")
+)
+
+// ValidFixtureName reports whether name may name a fixture
+func ValidFixtureName(name string) bool {
+	return fixtureNamePattern.MatchString(name)
+}
+
+// FixtureStore keeps the stdin fixtures uploaded through the admin API, one file each
+type FixtureStore struct {
+	dir     string
+	maxSize int64
+}
+
+// NewFixtureStore creates a fixture store rooted at dir, accepting fixtures of up to maxSize bytes
+func NewFixtureStore(dir string, maxSize int64) (*FixtureStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	return &FixtureStore{dir: dir, maxSize: maxSize}, nil
+}
+
+// Put stores the content of r as the named fixture, replacing any fixture of that name once the
+// upload is complete. Jobs already reading the old content are not affected.
+func (s *FixtureStore) Put(name string, r io.Reader) (*types.Fixture, error) {
+	if !ValidFixtureName(name) {
+		return nil, fmt.Errorf("invalid fixture name %q", name)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fixture: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(r, s.maxSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write fixture: %w", err)
+	}
+	if n > s.maxSize {
+		return nil, ErrFixtureTooLarge
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return nil, fmt.Errorf("failed to store fixture: %w", err)
+	}
+	return s.Stat(name)
+}
+
+// Stat returns the named fixture
+func (s *FixtureStore) Stat(name string) (*types.Fixture, error) {
+	if !ValidFixtureName(name) {
+		return nil, ErrFixtureNotFound
+	}
+	info, err := os.Lstat(filepath.Join(s.dir, name))
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, ErrFixtureNotFound
+	}
+	return &types.Fixture{Name: name, Size: info.Size(), Modified: info.ModTime().UTC()}, nil
+}
+
+// Open opens the named fixture for reading
+func (s *FixtureStore) Open(name string) (*os.File, error) {
+	if _, err := s.Stat(name); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrFixtureNotFound
+	}
+	return file, err
+}
+
+// List returns the stored fixtures ordered by name
+func (s *FixtureStore) List() ([]types.Fixture, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture directory: %w", err)
+	}
+
+	fixtures := []types.Fixture{}
+	for _, entry := range entries {
+		if fixture, err := s.Stat(entry.Name()); err == nil {
+			fixtures = append(fixtures, *fixture)
+		}
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
+
+// Delete removes the named fixture
+func (s *FixtureStore) Delete(name string) error {
+	if _, err := s.Stat(name); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrFixtureNotFound
+		}
+		return fmt.Errorf("failed to delete fixture: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// maxBuildFlags bounds the compile_flags and run_flags of a request
+const maxBuildFlags = 32
+
+// buildFlagPattern matches flags that are safe to expand unquoted in a stage script: no
+// whitespace, quotes, glob characters or other shell syntax
+var buildFlagPattern = regexp.MustCompile(`^[A-Za-z0-9_=+,.:/@%-]{1,256}$`)
+
+// ValidateFlags checks the request's compile_flags and run_flags against build_flag_allowlist
+// for the runtime's language
+func (m *Manager) ValidateFlags(request *types.JobRequest, rt *types.Runtime) error {
+	if len(request.CompileFlags) > 0 && !rt.Compiled {
+		return fmt.Errorf("compile_flags require a compiled language, %s is interpreted", rt.Language)
+	}
+
+	allowed := append([]string{}, m.config.BuildFlagAllowlist["*"]...)
+	allowed = append(allowed, m.config.BuildFlagAllowlist[rt.Language]...)
+
+	for field, flags := range map[string][]string{"compile_flags": request.CompileFlags, "run_flags": request.RunFlags} {
+		if len(flags) > maxBuildFlags {
+			return fmt.Errorf("%s must not have more than %d flags", field, maxBuildFlags)
+		}
+		for _, flag := range flags {
+			if !buildFlagPattern.MatchString(flag) {
+				return fmt.Errorf("%s: %q may only contain letters, digits and - _ = + , . : / @ %%", field, flag)
+			}
+			if !matchesEnvPattern(allowed, flag) {
+				return fmt.Errorf("%s: %s is not allowed for %s", field, flag, rt.Language)
+			}
+		}
+	}
+	return nil
+}
+
+// flagEnv returns the environment variable carrying the job's flags for stage, if it has any
+func (j *Job) flagEnv(stage string) []string {
+	var name string
+	var flags []string
+	switch stage {
+	case "compile":
+		name, flags = "CODERUNR_COMPILE_FLAGS", j.CompileFlags
+	case "run", "repl":
+		name, flags = "CODERUNR_RUN_FLAGS", j.RunFlags
+	}
+	if len(flags) == 0 {
+		return nil
+	}
+	return []string{name + "=" + strings.Join(flags, " ")}
+}
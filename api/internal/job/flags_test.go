@@ -0,0 +1,57 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestValidateFlags(t *testing.T) {
+	m := &Manager{config: &config.Config{BuildFlagAllowlist: map[string][]string{
+		"*":   {"-O2"},
+		"c++": {"-std=*"},
+	}}}
+	compiled := &types.Runtime{Language: "c++", Compiled: true}
+	interpreted := &types.Runtime{Language: "python"}
+
+	tests := []struct {
+		name    string
+		rt      *types.Runtime
+		request types.JobRequest
+		wantErr bool
+	}{
+		{"Empty", interpreted, types.JobRequest{}, false},
+		{"Global Pattern", interpreted, types.JobRequest{RunFlags: []string{"-O2"}}, false},
+		{"Language Prefix", compiled, types.JobRequest{CompileFlags: []string{"-std=c++17", "-O2"}}, false},
+		{"Other Language", interpreted, types.JobRequest{RunFlags: []string{"-std=c++17"}}, true},
+		{"Not Allowed", compiled, types.JobRequest{CompileFlags: []string{"-O3"}}, true},
+		{"Shell Syntax", compiled, types.JobRequest{CompileFlags: []string{"-std=$(id)"}}, true},
+		{"Whitespace", compiled, types.JobRequest{CompileFlags: []string{"-std=c++17 -O3"}}, true},
+		{"Compile Flags Interpreted", interpreted, types.JobRequest{CompileFlags: []string{"-O2"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.ValidateFlags(&tt.request, tt.rt); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlagEnv(t *testing.T) {
+	j := &Job{CompileFlags: []string{"-O2", "-Wall"}, RunFlags: []string{"-X", "dev"}}
+
+	for stage, want := range map[string][]string{
+		"compile": {"CODERUNR_COMPILE_FLAGS=-O2 -Wall"},
+		"run":     {"CODERUNR_RUN_FLAGS=-X dev"},
+		"repl":    {"CODERUNR_RUN_FLAGS=-X dev"},
+		"setup":   nil,
+	} {
+		if got := j.flagEnv(stage); !reflect.DeepEqual(got, want) {
+			t.Errorf("flagEnv(%q) = %v, want %v", stage, got, want)
+		}
+	}
+}
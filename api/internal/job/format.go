@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// Format runs the runtime's format script on the job's files and returns them as it left them.
+// The script gets the file names as arguments and rewrites the files in place, as gofmt -w and
+// black do. It runs with the compile limits.
+func (j *Job) Format(ctx context.Context) (*types.FormatResult, error) {
+	if !j.Runtime.Format {
+		return nil, fmt.Errorf("%s-%s has no formatter", j.Runtime.Language, j.Runtime.Version.String())
+	}
+
+	result := &types.FormatResult{Language: j.Runtime.Language, Version: j.Runtime.Version.String()}
+	stage, err := j.runTool(ctx, "format", func(box *types.IsolateBox, stage *types.StageResult) (err error) {
+		if stage.Outcome == types.OutcomeOK {
+			result.Files, err = j.readFormatted(box)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Format = stage
+	return result, nil
+}
+
+// readFormatted reads the job's files back from the submission directory, each in the encoding
+// it was submitted in. Together they may not exceed output_files_max_size.
+func (j *Job) readFormatted(box *types.IsolateBox) ([]types.CodeFile, error) {
+	submissionDir := filepath.Join(box.Dir, "submission")
+	limit := j.manager.config.OutputFilesMaxSize
+	var used int64
+
+	files := make([]types.CodeFile, 0, len(j.Files))
+	for _, file := range j.Files {
+		path := filepath.Join(submissionDir, file.Name)
+		// Never follow a symlink the formatter left in place of a file
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("formatter did not leave %s as a regular file", file.Name)
+		}
+		if used += info.Size(); limit > 0 && used > limit {
+			return nil, fmt.Errorf("formatted files exceed output_files_max_size")
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read formatted %s: %w", file.Name, err)
+		}
+		formatted := types.CodeFile{Name: file.Name, Encoding: file.Encoding}
+		switch file.Encoding {
+		case "base64":
+			formatted.Content = base64.StdEncoding.EncodeToString(content)
+		case "hex":
+			formatted.Content = hex.EncodeToString(content)
+		default:
+			formatted.Content = string(content)
+		}
+		files = append(files, formatted)
+	}
+	return files, nil
+}
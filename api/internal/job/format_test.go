@@ -0,0 +1,70 @@
+package job
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// upcaseFormatter is a format script rewriting each file in upper case, failing on "error"
+const upcaseFormatter = `for file; do
+  if grep -q error "$file"; then echo "$file: syntax error" >&2; exit 1; fi
+  tr a-z A-Z < "$file" > "$file.tmp" && mv "$file.tmp" "$file"
+done
+`
+
+func newFormatTestManager(t *testing.T) (*Manager, *types.Runtime) {
+	t.Helper()
+	m, rt := newLocalTestManager(t, "true\n")
+	if err := os.WriteFile(filepath.Join(rt.PkgDir, "format"), []byte(upcaseFormatter), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rt.Format = true
+	return m, rt
+}
+
+func TestFormat(t *testing.T) {
+	m, rt := newFormatTestManager(t)
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{Files: []types.CodeFile{
+		{Name: "main.sh", Content: "echo hi\n"},
+		{Name: "lib/util.sh", Content: base64.StdEncoding.EncodeToString([]byte("x=1\n")), Encoding: "base64"},
+	}})
+	result, err := j.Format(context.Background())
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	if result.Format.Outcome != types.OutcomeOK || len(result.Files) != 2 {
+		t.Fatalf("Format() = %+v, want both files formatted", result)
+	}
+	if file := result.Files[0]; file.Name != "main.sh" || file.Content != "ECHO HI\n" {
+		t.Errorf("Files[0] = %+v, want main.sh in upper case", file)
+	}
+	want := base64.StdEncoding.EncodeToString([]byte("X=1\n"))
+	if file := result.Files[1]; file.Encoding != "base64" || file.Content != want {
+		t.Errorf("Files[1] = %+v, want lib/util.sh in upper case and base64", file)
+	}
+}
+
+func TestFormatFailure(t *testing.T) {
+	m, rt := newFormatTestManager(t)
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{Files: []types.CodeFile{{Name: "main.sh", Content: "error(\n"}}})
+	result, err := j.Format(context.Background())
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if result.Files != nil || result.Format.Stderr != "main.sh: syntax error\n" {
+		t.Errorf("Format() = %+v, want the formatter's error and no files", result)
+	}
+
+	rt.Format = false
+	if _, err := m.NewJob(context.Background(), rt, &types.JobRequest{}).Format(context.Background()); err == nil {
+		t.Error("Expected an error for a runtime without a formatter")
+	}
+}
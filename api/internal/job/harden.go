@@ -0,0 +1,95 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// HardenCommand is the hidden server subcommand that hardens its own process and then
+// replaces itself with isolate: harden-exec [--no-new-privs] [--block-ptrace] -- isolate args...
+const HardenCommand = "harden-exec"
+
+// Options understood by the hardening helper
+const (
+	hardenNoNewPrivs  = "--no-new-privs"
+	hardenBlockPtrace = "--block-ptrace"
+)
+
+// selfExecutable re-executes the running server binary, even if it was replaced on disk
+var selfExecutable = "/proc/self/exe"
+
+// hardening is the process hardening a helper applies before starting isolate
+type hardening struct {
+	noNewPrivs  bool
+	blockPtrace bool
+}
+
+// parseHardening splits the helper's arguments into its options and the command to run
+func parseHardening(args []string) (hardening, []string, error) {
+	var h hardening
+	for i, arg := range args {
+		switch arg {
+		case hardenNoNewPrivs:
+			h.noNewPrivs = true
+		case hardenBlockPtrace:
+			h.blockPtrace = true
+		case "--":
+			if i+1 == len(args) {
+				return h, nil, fmt.Errorf("%s: missing command", HardenCommand)
+			}
+			return h, args[i+1:], nil
+		default:
+			return h, nil, fmt.Errorf("%s: unknown option %s", HardenCommand, arg)
+		}
+	}
+	return h, nil, fmt.Errorf("%s: missing command", HardenCommand)
+}
+
+// isolateCommand returns the command running isolate with args. When no_new_privs or ptrace
+// blocking is configured, isolate is started through the server's hardening helper so the
+// restrictions are in place before isolate, and the program it runs, start. isolate gets a
+// process group of its own, which cancellation kills as a whole.
+func (j *Job) isolateCommand(ctx context.Context, args []string) *exec.Cmd {
+	cfg := j.manager.config
+	name := cfg.IsolatePath
+	if cfg.SandboxNoNewPrivs || cfg.SandboxBlockPtrace {
+		helperArgs := []string{HardenCommand}
+		if cfg.SandboxNoNewPrivs {
+			helperArgs = append(helperArgs, hardenNoNewPrivs)
+		}
+		if cfg.SandboxBlockPtrace {
+			helperArgs = append(helperArgs, hardenBlockPtrace)
+		}
+		helperArgs = append(helperArgs, "--", cfg.IsolatePath)
+		name, args = selfExecutable, append(helperArgs, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	killProcessGroup(cmd)
+	return cmd
+}
+
+// sandboxArgs returns the isolate options for the configured /proc and /etc visibility
+func (j *Job) sandboxArgs() []string {
+	var args []string
+	if j.manager.config.SandboxHideProc {
+		// An empty target removes isolate's default /proc rule
+		args = append(args, "--dir=/proc=")
+	}
+	if j.manager.config.SandboxMountEtc {
+		args = append(args, "--dir=/etc:noexec")
+	}
+	return args
+}
+
+// extraSandboxArgs returns the operator's extra isolate options for the job's language, those
+// for every language first. They come after the server's own options so they take precedence.
+func (j *Job) extraSandboxArgs() []string {
+	extra := j.manager.config.SandboxExtraArgs
+	args := append([]string{}, extra["*"]...)
+	if j.Runtime.Language != "*" {
+		args = append(args, extra[j.Runtime.Language]...)
+	}
+	return args
+}
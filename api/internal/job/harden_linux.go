@@ -0,0 +1,77 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// x32 system calls on amd64 have this bit set in their number
+const x32SyscallBit = 0x40000000
+
+// auditArch is the seccomp architecture of the server's own system calls
+var auditArch = map[string]uint32{
+	"amd64": unix.AUDIT_ARCH_X86_64,
+	"arm64": unix.AUDIT_ARCH_AARCH64,
+}
+
+// RunHardened applies the hardening options in args to the current process and replaces it
+// with the command that follows "--". It only returns on failure.
+func RunHardened(args []string) error {
+	h, command, err := parseHardening(args)
+	if err != nil {
+		return err
+	}
+
+	// The settings must hold for the thread that execs
+	runtime.LockOSThread()
+
+	if h.noNewPrivs {
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return fmt.Errorf("failed to set no_new_privs: %w", err)
+		}
+	}
+	if h.blockPtrace {
+		if err := blockPtrace(); err != nil {
+			return err
+		}
+	}
+
+	return unix.Exec(command[0], command, os.Environ())
+}
+
+// blockPtrace installs a seccomp filter failing ptrace and cross-process memory access with
+// EPERM. System calls through another ABI (32-bit or x32) are refused outright, as they would
+// otherwise bypass the filter.
+func blockPtrace() error {
+	arch, ok := auditArch[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("blocking ptrace is not supported on %s", runtime.GOARCH)
+	}
+
+	deny := uint32(unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM))
+	filter := []unix.SockFilter{
+		// seccomp_data.arch
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: 4},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: arch},
+		{Code: unix.BPF_RET | unix.BPF_K, K: deny},
+		// seccomp_data.nr
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: 0},
+		{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, Jt: 4, K: x32SyscallBit},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 3, K: unix.SYS_PTRACE},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 2, K: unix.SYS_PROCESS_VM_READV},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: unix.SYS_PROCESS_VM_WRITEV},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ALLOW},
+		{Code: unix.BPF_RET | unix.BPF_K, K: deny},
+	}
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0); err != nil {
+		return fmt.Errorf("failed to install ptrace filter: %w", err)
+	}
+	runtime.KeepAlive(filter)
+	return nil
+}
@@ -0,0 +1,35 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunHardened(t *testing.T) {
+	// Re-executed as the helper process
+	if os.Getenv("CODERUNR_TEST_HARDEN") == "1" {
+		err := RunHardened([]string{"--no-new-privs", "--block-ptrace", "--",
+			"/bin/sh", "-c", "grep -E '^(NoNewPrivs|Seccomp):' /proc/self/status"})
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if _, ok := auditArch[runtime.GOARCH]; !ok {
+		t.Skipf("ptrace blocking is not supported on %s", runtime.GOARCH)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunHardened$")
+	cmd.Env = append(os.Environ(), "CODERUNR_TEST_HARDEN=1")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Hardened command failed: %v\n%s", err, output)
+	}
+
+	status := strings.Fields(string(output))
+	if want := []string{"NoNewPrivs:", "1", "Seccomp:", "2"}; strings.Join(status, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected no_new_privs and a seccomp filter on the command, got %q", output)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package job
+
+import "errors"
+
+// RunHardened is only supported on Linux, where isolate runs
+func RunHardened(args []string) error {
+	return errors.New("sandbox hardening requires Linux")
+}
@@ -0,0 +1,76 @@
+package job
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestParseHardening(t *testing.T) {
+	h, command, err := parseHardening([]string{"--block-ptrace", "--", "/usr/local/bin/isolate", "--run"})
+	if err != nil {
+		t.Fatalf("parseHardening() error = %v", err)
+	}
+	if h.noNewPrivs || !h.blockPtrace {
+		t.Errorf("parseHardening() options = %+v, want only blockPtrace", h)
+	}
+	if want := []string{"/usr/local/bin/isolate", "--run"}; !reflect.DeepEqual(command, want) {
+		t.Errorf("parseHardening() command = %v, want %v", command, want)
+	}
+
+	for _, args := range [][]string{{"--no-new-privs"}, {"--no-new-privs", "--"}, {"--seccomp", "--", "isolate"}} {
+		if _, _, err := parseHardening(args); err == nil {
+			t.Errorf("parseHardening(%v) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestIsolateCommand(t *testing.T) {
+	cfg := &config.Config{IsolatePath: "/usr/local/bin/isolate"}
+	j := &Job{manager: &Manager{config: cfg}}
+
+	cmd := j.isolateCommand(context.Background(), []string{"--run"})
+	if want := []string{"/usr/local/bin/isolate", "--run"}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Expected isolate to run directly, got %v", cmd.Args)
+	}
+
+	cfg.SandboxNoNewPrivs = true
+	cfg.SandboxBlockPtrace = true
+	cmd = j.isolateCommand(context.Background(), []string{"--run"})
+	want := []string{selfExecutable, HardenCommand, "--no-new-privs", "--block-ptrace", "--", "/usr/local/bin/isolate", "--run"}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Expected isolate to run through the hardening helper, got %v", cmd.Args)
+	}
+}
+
+func TestSandboxArgs(t *testing.T) {
+	cfg := &config.Config{
+		SandboxHideProc: true,
+		SandboxMountEtc: true,
+		SandboxExtraArgs: map[string][]string{
+			"*":    {"--stack=8192"},
+			"java": {"--processes=64"},
+		},
+	}
+	j := &Job{Runtime: &types.Runtime{Language: "java"}, manager: &Manager{config: cfg}}
+
+	if want := []string{"--dir=/proc=", "--dir=/etc:noexec"}; !reflect.DeepEqual(j.sandboxArgs(), want) {
+		t.Errorf("sandboxArgs() = %v, want %v", j.sandboxArgs(), want)
+	}
+	if want := []string{"--stack=8192", "--processes=64"}; !reflect.DeepEqual(j.extraSandboxArgs(), want) {
+		t.Errorf("extraSandboxArgs() = %v, want %v", j.extraSandboxArgs(), want)
+	}
+
+	cfg.SandboxHideProc = false
+	cfg.SandboxMountEtc = false
+	j.Runtime.Language = "python"
+	if args := j.sandboxArgs(); len(args) != 0 {
+		t.Errorf("sandboxArgs() = %v, want none", args)
+	}
+	if want := []string{"--stack=8192"}; !reflect.DeepEqual(j.extraSandboxArgs(), want) {
+		t.Errorf("extraSandboxArgs() = %v, want %v", j.extraSandboxArgs(), want)
+	}
+}
@@ -0,0 +1,84 @@
+package job
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coderunr/api/internal/history"
+	"github.com/coderunr/api/internal/types"
+)
+
+// recordHistory adds the finished job to the execution history, if enabled, and counts it
+// under its metadata
+func (j *Job) recordHistory(mode string, started time.Time, compile, run *types.StageResult, err error) {
+	if j.manager == nil {
+		return
+	}
+	status := historyStatus(compile, run, err)
+	duration := time.Since(started)
+	j.manager.metadata.Count(j.Metadata, status)
+	j.manager.runtimeCounts.Count(j.Runtime.Language, j.Runtime.Version.String(), duration, status)
+	if j.manager.history == nil {
+		return
+	}
+
+	record := &history.Record{
+		ID:         uuid.New().String(),
+		JobID:      j.ID,
+		RequestID:  j.requestID,
+		Requester:  j.requester,
+		Language:   j.Runtime.Language,
+		Version:    j.Runtime.Version.String(),
+		Mode:       mode,
+		Status:     status,
+		StartedAt:  started,
+		DurationMs: duration.Milliseconds(),
+		Metadata:   j.Metadata,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if compile != nil {
+		record.CompileCode = compile.Code
+		record.Signal = compile.Signal
+		record.Stdout, record.Stderr = compile.Stdout, compile.Stderr
+	}
+	if run != nil {
+		record.RunCode = run.Code
+		record.Signal = run.Signal
+		record.Stdout, record.Stderr = run.Stdout, run.Stderr
+	}
+	if j.manager.config != nil && j.manager.config.HistorySubmissions && j.request != nil {
+		// Pinned to the version that ran; the callback is not repeated on replay
+		submission := *j.request
+		submission.Language = j.Runtime.Language
+		submission.Version = j.Runtime.Version.String()
+		submission.CallbackURL = ""
+		submission.PTY = nil
+		record.Submission = &submission
+	}
+
+	j.recorded = record
+	j.manager.history.Record(record)
+}
+
+// historyStatus classifies an execution outcome
+func historyStatus(compile, run *types.StageResult, err error) string {
+	failed := func(stage *types.StageResult) bool {
+		return stage.Signal != "" || (stage.Code != nil && *stage.Code != 0)
+	}
+
+	switch {
+	case err != nil:
+		return history.StatusError
+	case compile != nil && failed(compile):
+		return history.StatusCompileError
+	case run == nil:
+		return history.StatusError
+	case failed(run):
+		return history.StatusRuntimeError
+	default:
+		return history.StatusSuccess
+	}
+}
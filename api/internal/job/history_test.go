@@ -0,0 +1,36 @@
+package job
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coderunr/api/internal/history"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestHistoryStatus(t *testing.T) {
+	zero, one := 0, 1
+
+	tests := []struct {
+		name    string
+		compile *types.StageResult
+		run     *types.StageResult
+		err     error
+		want    string
+	}{
+		{"success", nil, &types.StageResult{Code: &zero}, nil, history.StatusSuccess},
+		{"compiled success", &types.StageResult{Code: &zero}, &types.StageResult{Code: &zero}, nil, history.StatusSuccess},
+		{"compile error", &types.StageResult{Code: &one}, nil, nil, history.StatusCompileError},
+		{"runtime error", nil, &types.StageResult{Code: &one}, nil, history.StatusRuntimeError},
+		{"killed", nil, &types.StageResult{Signal: "SIGKILL"}, nil, history.StatusRuntimeError},
+		{"internal error", nil, nil, errors.New("boom"), history.StatusError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := historyStatus(tt.compile, tt.run, tt.err); got != tt.want {
+				t.Errorf("historyStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// requiredControllers are the cgroup v2 controllers isolate --cg needs for its limits
+var requiredControllers = []string{"cpuset", "memory", "pids"}
+
+// IsolateInfo describes the isolate binary and kernel features found at startup
+type IsolateInfo struct {
+	Path        string
+	Version     string
+	CgroupMode  string
+	Controllers []string
+}
+
+// DetectIsolate checks that the isolate binary at path runs and that the kernel provides
+// the cgroup features it needs. The error lists every missing feature at once.
+func DetectIsolate(path string) (*IsolateInfo, error) {
+	info := &IsolateInfo{Path: path}
+	var missing []string
+
+	if stat, err := os.Stat(path); err != nil {
+		missing = append(missing, fmt.Sprintf("isolate binary at %s (%v)", path, err))
+	} else if stat.IsDir() || stat.Mode().Perm()&0111 == 0 {
+		missing = append(missing, fmt.Sprintf("executable isolate binary at %s", path))
+	} else {
+		output, err := exec.Command(path, "--version").CombinedOutput()
+		if err != nil {
+			missing = append(missing, fmt.Sprintf("working isolate binary at %s (--version: %v)", path, err))
+		} else {
+			info.Version = strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+		}
+	}
+
+	info.CgroupMode, info.Controllers = detectCgroups(cgroupRoot)
+	switch info.CgroupMode {
+	case "v2":
+		for _, controller := range requiredControllers {
+			if !contains(info.Controllers, controller) {
+				missing = append(missing, fmt.Sprintf("cgroup v2 %s controller", controller))
+			}
+		}
+	case "hybrid":
+		missing = append(missing, "pure cgroup v2 (found combined cgroup v1+v2 mode)")
+	case "v1":
+		missing = append(missing, "cgroup v2 (found cgroup v1)")
+	default:
+		missing = append(missing, fmt.Sprintf("cgroup filesystem at %s", cgroupRoot))
+	}
+
+	if len(missing) > 0 {
+		return info, fmt.Errorf("isolate cannot run, missing: %s", strings.Join(missing, "; "))
+	}
+	return info, nil
+}
+
+// detectCgroups reports the cgroup mode mounted at root ("v2", "hybrid", "v1" or "")
+// and, for cgroup v2, the controllers available to child groups
+func detectCgroups(root string) (string, []string) {
+	if _, err := os.Stat(filepath.Join(root, "unified")); err == nil {
+		return "hybrid", nil
+	}
+
+	if data, err := os.ReadFile(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return "v2", strings.Fields(string(data))
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "memory")); err == nil {
+		return "v1", nil
+	}
+
+	return "", nil
+}
+
+// contains checks if a slice contains a string
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
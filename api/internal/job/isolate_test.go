@@ -0,0 +1,94 @@
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestDetectCgroups(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		dirs        []string
+		mode        string
+		controllers []string
+	}{
+		{"V2", map[string]string{"cgroup.controllers": "cpuset cpu io memory pids\n"}, nil, "v2",
+			[]string{"cpuset", "cpu", "io", "memory", "pids"}},
+		{"Hybrid", map[string]string{"cgroup.controllers": "memory"}, []string{"unified"}, "hybrid", nil},
+		{"V1", nil, []string{"memory", "cpu"}, "v1", nil},
+		{"None", nil, nil, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, dir := range tt.dirs {
+				if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mode, controllers := detectCgroups(root)
+			if mode != tt.mode {
+				t.Errorf("Expected mode %q, got %q", tt.mode, mode)
+			}
+			if !reflect.DeepEqual(controllers, tt.controllers) {
+				t.Errorf("Expected controllers %v, got %v", tt.controllers, controllers)
+			}
+		})
+	}
+}
+
+func TestDetectIsolateListsMissingFeatures(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(prev string) { cgroupRoot = prev }(cgroupRoot)
+	cgroupRoot = root
+
+	_, err := DetectIsolate(filepath.Join(root, "missing-isolate"))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{"missing-isolate", "cpuset controller", "pids controller"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %q", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "memory controller") {
+		t.Errorf("Did not expect memory controller to be reported missing: %q", err)
+	}
+}
+
+func TestIsolateTimeLimits(t *testing.T) {
+	m, rt := newLocalTestManager(t, "true\n")
+	j := &Job{Runtime: rt, manager: m}
+	args := j.buildIsolateArgs(&types.IsolateBox{ID: 1}, "run", nil, 250*time.Millisecond, 1500*time.Microsecond, -1)
+	if !contains(args, "--wall-time=0.25") || !contains(args, "--time=0.002") {
+		t.Errorf("Expected millisecond time limits in the isolate arguments, got %v", args)
+	}
+
+	m.config.TimeLimitResolution = 100 * time.Millisecond
+	runTimeout, cpuTime := 250, 1000
+	j = m.NewJob(context.Background(), rt, &types.JobRequest{RunTimeout: &runTimeout, RunCPUTime: &cpuTime})
+	if j.Timeouts.Run != 300*time.Millisecond || j.CPUTimes.Run != time.Second {
+		t.Errorf("Expected limits rounded up to 100ms, got run timeout %v and CPU time %v", j.Timeouts.Run, j.CPUTimes.Run)
+	}
+	if limits := j.newResult().Limits; limits.Timeouts.Run != 300 || limits.CPUTimes.Run != 1000 {
+		t.Errorf("Expected the applied limits in the result, got %+v", limits)
+	}
+}
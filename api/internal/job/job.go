@@ -8,10 +8,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,78 +19,401 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/coderunr/api/internal/artifact"
 	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/history"
+	apiKeys "github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/tracing"
 	"github.com/coderunr/api/internal/types"
+	"github.com/coderunr/api/internal/webhook"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
-	IsolatePath = "/usr/local/bin/isolate"
-	MaxBoxID    = 999
+	MaxBoxID = 999
 )
 
-var (
-	boxIDCounter   int32
-	remainingSlots int32
-	jobQueue       = make(chan func(), 1000)
-	queueMutex     sync.Mutex
-	queueCondition = sync.NewCond(&queueMutex)
+// Values of output_limit_action
+const (
+	// OutputLimitKill kills a stage as soon as it exceeds its output limit
+	OutputLimitKill = "kill"
+	// OutputLimitTruncate lets the stage run on and drops the output past the limit
+	OutputLimitTruncate = "truncate"
 )
 
 // Manager handles job execution
 type Manager struct {
-	config *config.Config
-	logger *logrus.Entry
+	config    *config.Config
+	logger    *logrus.Entry
+	runtimes  *runtime.Manager
+	store     *Store
+	cache     *CompileCache
+	results   *ResultCache
+	sandbox   Sandbox
+	queue     *Queue
+	webhooks  *webhook.Dispatcher
+	history   *history.Recorder
+	artifacts *artifact.Sink
+	fixtures  *FixtureStore
+	tenants   *Tenants
+	metadata  *MetadataCounters
+	// Executions by runtime, for GET /runtimes/stats
+	runtimeCounts *RuntimeCounters
+	warm          *WarmPool
+
+	// Graceful draining; see Drain
+	draining   bool
+	drainMutex sync.Mutex
+	inflight   sync.WaitGroup
+	running    atomic.Int64
+	abort      context.Context
+	abortJobs  context.CancelFunc
+
+	// Cancel functions of unfinished async jobs; see CancelAsyncJob
+	cancels     map[string]context.CancelFunc
+	cancelMutex sync.Mutex
 }
 
-// NewManager creates a new job manager
-func NewManager(cfg *config.Config) *Manager {
-	atomic.StoreInt32(&remainingSlots, int32(cfg.MaxConcurrentJobs))
-
+// NewManager creates a new job manager resolving requests against the given runtimes
+func NewManager(cfg *config.Config, runtimes *runtime.Manager) *Manager {
 	manager := &Manager{
-		config: cfg,
-		logger: logrus.WithField("component", "job"),
+		config:        cfg,
+		logger:        logrus.WithField("component", "job"),
+		runtimes:      runtimes,
+		queue:         NewQueue(cfg.MaxConcurrentJobs, cfg.MaxQueueDepth),
+		webhooks:      webhook.NewDispatcher(cfg),
+		tenants:       NewTenants(cfg.Tenants),
+		metadata:      NewMetadataCounters(cfg.MetricsMetadataKeys, cfg.MetricsMetadataMaxValues),
+		runtimeCounts: NewRuntimeCounters(),
+	}
+	manager.abort, manager.abortJobs = context.WithCancel(context.Background())
+
+	sandbox, err := NewSandbox(cfg)
+	if err != nil {
+		manager.logger.WithError(err).Error("Failed to initialize sandbox backend, using isolate")
+		sandbox = newIsolateSandbox(cfg)
+	}
+	manager.sandbox = sandbox
+	manager.warm = NewWarmPool(manager)
+
+	// Working directories of jobs that were running when the server stopped
+	if err := removeStaleWorkDirs(workRoot(cfg.DataDirectory)); err != nil {
+		manager.logger.WithError(err).Warn("Failed to remove stale job directories")
+	}
+
+	// Orphaned box garbage collection
+	if cfg.BoxReapInterval > 0 {
+		go manager.reapBoxes(cfg.BoxReapInterval)
 	}
 
-	// Start job queue processor
-	go manager.processJobQueue()
+	// Async job store (results survive restarts until they expire)
+	store, err := NewStore(filepath.Join(cfg.DataDirectory, "jobs"), cfg.JobResultTTL)
+	if err != nil {
+		manager.logger.WithError(err).Error("Failed to initialize async job store, async jobs disabled")
+	} else {
+		manager.store = store
+		go manager.expireJobs()
+	}
+
+	// Execution history (nil when disabled)
+	recorder, err := history.Open(cfg)
+	if err != nil {
+		manager.logger.WithError(err).Error("Failed to initialize execution history, history disabled")
+	} else {
+		manager.history = recorder
+	}
+
+	// Output file uploads (nil when no artifact sink is configured)
+	sink, err := artifact.Open(cfg)
+	if err != nil {
+		manager.logger.WithError(err).Error("Failed to initialize artifact sink, output files will be inlined")
+	} else {
+		manager.artifacts = sink
+	}
+
+	// Stdin fixtures (nil when the directory cannot be created)
+	fixtures, err := NewFixtureStore(filepath.Join(cfg.DataDirectory, "fixtures"), cfg.StdinMaxSize)
+	if err != nil {
+		manager.logger.WithError(err).Error("Failed to initialize fixture store, fixtures disabled")
+	} else {
+		manager.fixtures = fixtures
+	}
+
+	// Compile artifact cache
+	if cfg.CompileCacheEnabled {
+		cache, err := NewCompileCache(filepath.Join(cfg.DataDirectory, "cache", "compile"),
+			cfg.CompileCacheMaxSize, cfg.CompileCacheTTL)
+		if err != nil {
+			manager.logger.WithError(err).Error("Failed to initialize compile cache, caching disabled")
+		} else {
+			manager.cache = cache
+			go manager.evictCompileCache()
+		}
+	}
+
+	// Result cache for identical submissions
+	if cfg.ResultCacheEnabled {
+		manager.results = NewResultCache(cfg.ResultCacheTTL, cfg.ResultCacheMaxEntries)
+		go manager.evictResultCache()
+	}
 
 	return manager
 }
 
+// PoolStats returns the sandbox's box pool counters
+func (m *Manager) PoolStats() BoxPoolStats {
+	return m.sandbox.Stats()
+}
+
+// WarmPoolStats returns the warm process pool counters
+func (m *Manager) WarmPoolStats() WarmPoolStats {
+	return m.warm.Stats()
+}
+
+// SetMaxConcurrentJobs changes how many jobs may run at once
+func (m *Manager) SetMaxConcurrentJobs(n int) {
+	m.queue.SetCapacity(n)
+}
+
+// History returns the execution history recorder, or nil when history is disabled
+func (m *Manager) History() *history.Recorder {
+	return m.history
+}
+
+// Fixtures returns the stdin fixture store, or nil when fixtures are unavailable
+func (m *Manager) Fixtures() *FixtureStore {
+	return m.fixtures
+}
+
+// Close flushes the execution history
+func (m *Manager) Close() error {
+	return m.history.Close()
+}
+
+// QueueStats returns the job queue counters
+func (m *Manager) QueueStats() QueueStats {
+	return m.queue.Stats()
+}
+
+// Submit starts a job in the background and returns its async record immediately
+func (m *Manager) Submit(ctx context.Context, runtime *types.Runtime, request *types.JobRequest) (*types.AsyncJob, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("async job store is unavailable")
+	}
+	if m.Draining() {
+		return nil, ErrDraining
+	}
+	if m.queue.Full() {
+		return nil, ErrQueueFull
+	}
+
+	job := m.NewJob(ctx, runtime, request)
+	// Quotas are checked now so the client is told at submission rather than in the result
+	if err := job.admitTenant(); err != nil {
+		return nil, err
+	}
+	record := m.store.Create(job.ID, runtime.Language, runtime.Version.String(), job.requester)
+	job.onStart = func() {
+		m.store.MarkRunning(job.ID)
+	}
+	// The API key's in-flight slot stays taken until the job finishes, not just the request
+	releaseKey := apiKeys.KeepAPIKeySlot(ctx)
+
+	// The job outlives the request but stays in its trace
+	ctx, done := m.trackAsync(tracing.Detach(ctx), job.ID)
+
+	go func() {
+		defer done()
+		defer releaseKey()
+
+		result, err := job.Execute(ctx)
+		if ctx.Err() != nil {
+			job.logger.Info("Async job cancelled")
+		} else if err != nil {
+			job.logger.WithError(err).Error("Async job execution failed")
+		} else if result.Run == nil && result.Compile != nil {
+			// Backward compatibility (Piston behavior), same as the sync endpoint
+			result.Run = result.Compile
+		}
+		m.store.MarkFinished(job.ID, result, err)
+
+		if request.CallbackURL != "" {
+			if finished, ok := m.store.Get(job.ID); ok {
+				if err := m.webhooks.Dispatch(request.CallbackURL, job.ID, finished); err != nil {
+					job.logger.WithError(err).Error("Failed to queue result webhook")
+				}
+			}
+		}
+	}()
+
+	return record, nil
+}
+
+// GetAsyncJob returns the current state of an async job. A job submitted with another API key
+// is not found.
+func (m *Manager) GetAsyncJob(id, submitter string) (*types.AsyncJob, bool) {
+	if m.store == nil {
+		return nil, false
+	}
+	record, ok := m.store.Get(id)
+	if !ok || record.Submitter != submitter {
+		return nil, false
+	}
+	return record, true
+}
+
+// expireJobs periodically expires finished async job results
+func (m *Manager) expireJobs() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.store.Expire(now)
+	}
+}
+
+// evictCompileCache periodically drops expired compile cache entries
+func (m *Manager) evictCompileCache() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.cache.Evict(now)
+	}
+}
+
+// evictResultCache periodically drops expired result cache entries
+func (m *Manager) evictResultCache() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.results.Evict(now)
+	}
+}
+
 // Job represents a code execution job
 type Job struct {
-	ID           string
-	Runtime      *types.Runtime
-	Files        []types.CodeFile
+	ID      string
+	Runtime *types.Runtime
+	Files   []types.CodeFile
+	// Archive is a base64 tar.gz or zip extracted into the submission directory before Files
+	Archive      string
+	Entrypoint   string
 	Args         []string
 	Stdin        string
+	Env          map[string]string
+	OutputFiles  []string
+	Priority     int
 	Timeouts     types.Timeouts
 	CPUTimes     types.CPUTimes
 	MemoryLimits types.MemoryLimits
-	State        types.JobState
-	dirtyBoxes   []*types.IsolateBox
+	DiskQuota    int64
+	Network      bool
+	PTY          *types.PTYSize
+	// OutputMaxSize caps each captured output stream, and streamed output as a whole
+	OutputMaxSize int
+	// OutputLimitAction is what happens past OutputMaxSize, OutputLimitKill or OutputLimitTruncate
+	OutputLimitAction string
+	// Omit lists result fields to strip, applied to the final result of streaming jobs too
+	Omit []string
+	// Mounts are the scratch and dataset directories added to the sandbox
+	Mounts []types.Mount
+	// CompileFlags and RunFlags are passed to the stage scripts, validated against build_flag_allowlist
+	CompileFlags []string
+	RunFlags     []string
+	// Metadata is the request's opaque metadata, echoed in the result
+	Metadata map[string]string
+	// StdinURL and StdinFixture are the request's other stdin sources; see resolveStdin
+	StdinURL     string
+	StdinFixture string
+	// ExpectedOutput, CompareMode and Epsilon judge the run's stdout; see judge
+	ExpectedOutput *string
+	CompareMode    string
+	Epsilon        float64
+	// flushInterval is how long streamed output is coalesced before it is sent
+	flushInterval time.Duration
+	State         types.JobState
+	dirtyBoxes    []*types.IsolateBox
+	// archiveFiles are the regular files extracted from Archive
+	archiveFiles []string
 	logger       *logrus.Entry
 	manager      *Manager
 
+	// Recorded in the execution history
+	requestID string
+	requester string
+	request   *types.JobRequest
+	// recorded is the job's history record once it has finished
+	recorded *history.Record
+	// skipResultCache makes Execute run the job even when an identical result is cached
+	skipResultCache bool
+
+	// stdinFile holds the stdin of a StdinURL or StdinFixture job, opened by begin
+	stdinFile *os.File
+
+	// tenant is the API key's tenant; releaseTenant ends its quota reservation
+	tenant        string
+	releaseTenant func()
+
+	// warm is the warm process the job runs in instead of a box of its own
+	warm *warmProcess
+
 	// Streaming support
 	EventChannel chan types.StreamEvent
 	StdinChannel chan string
-	runningCmd   *exec.Cmd
-	cmdMutex     sync.RWMutex
+	// stdinClosed is set once the client signalled end of input and StdinChannel is closed
+	stdinClosed bool
+	stdinMutex  sync.Mutex
+	runningCmd  *exec.Cmd
+	runningBox  *types.IsolateBox // box of runningCmd, for signals
+	pty         *os.File          // pty master of the running stage in pty mode
+	cmdMutex    sync.RWMutex
 
 	// Streaming output limit (combined stdout+stderr)
 	outputBudget int
 	outputSent   int
 	outputMu     sync.Mutex
 	killOnce     sync.Once
+	// outputLimited is set when the running stage was killed for exceeding an output limit
+	outputLimited atomic.Bool
+	// outputTruncated is set when output of the running stage was dropped at the limit
+	outputTruncated atomic.Bool
+
+	// onStart is invoked once a job slot has been acquired
+	onStart func()
+
+	// lastActivity is the unix nano time of the last stdin or output, used by sessions
+	lastActivity atomic.Int64
+	// lsp decodes the stdout of a language server session into its messages
+	lsp *lspDecoder
 }
 
-// NewJob creates a new job from a request
-func (m *Manager) NewJob(runtime *types.Runtime, request *types.JobRequest) *Job {
+// NewJob creates a new job from a request. The request ID in ctx, if any, is attached to the job's logs.
+func (m *Manager) NewJob(ctx context.Context, runtime *types.Runtime, request *types.JobRequest) *Job {
 	jobID := uuid.New().String()
 
+	logger := logrus.WithField("job_id", jobID)
+	requestID := middleware.GetReqID(ctx)
+	if requestID != "" {
+		logger = logger.WithField("request_id", requestID)
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		logger = logger.WithField("trace_id", traceID)
+	}
+	var requester, tenant string
+	if key, ok := apiKeys.APIKeyFromContext(ctx); ok {
+		requester = key.Name
+		tenant = key.TenantName()
+	}
+
+	// A profile's limits stand in for the runtime's defaults
+	runtime = m.profileRuntime(request.Profile, runtime)
+
 	// Process files
 	files := make([]types.CodeFile, len(request.Files))
 	for i, file := range request.Files {
@@ -149,45 +472,155 @@ func (m *Manager) NewJob(runtime *types.Runtime, request *types.JobRequest) *Job
 	if request.RunCPUTime != nil {
 		cpuTimes.Run = time.Duration(*request.RunCPUTime) * time.Millisecond
 	}
+	// isolate enforces time limits to the millisecond; coarser steps are the operator's choice
+	resolution := m.config.TimeLimitResolution
+	timeouts.Compile = roundLimit(timeouts.Compile, resolution)
+	timeouts.Run = roundLimit(timeouts.Run, resolution)
+	cpuTimes.Compile = roundLimit(cpuTimes.Compile, resolution)
+	cpuTimes.Run = roundLimit(cpuTimes.Run, resolution)
 	if request.CompileMemoryLimit != nil {
 		memoryLimits.Compile = *request.CompileMemoryLimit
 	}
 	if request.RunMemoryLimit != nil {
 		memoryLimits.Run = *request.RunMemoryLimit
 	}
+	diskQuota := runtime.DiskQuota
+	if request.DiskQuota != nil {
+		diskQuota = *request.DiskQuota
+	}
+	outputMaxSize := runtime.OutputMaxSize
+	if request.MaxOutput != nil {
+		outputMaxSize = *request.MaxOutput
+	}
+	outputLimitAction := m.config.OutputLimitAction
+	if request.OutputLimitAction != "" {
+		outputLimitAction = request.OutputLimitAction
+	}
+	epsilon := defaultEpsilon
+	if request.Epsilon != nil {
+		epsilon = *request.Epsilon
+	}
+	network := m.config.NetworkEnabled(runtime.Language)
+	if enabled, ok := m.profileNetwork(request.Profile); ok {
+		network = enabled
+	}
+	if request.EnableNetwork != nil {
+		network = *request.EnableNetwork
+	}
 
 	return &Job{
-		ID:           jobID,
-		Runtime:      runtime,
-		Files:        files,
-		Args:         request.Args,
-		Stdin:        stdin,
-		Timeouts:     timeouts,
-		CPUTimes:     cpuTimes,
-		MemoryLimits: memoryLimits,
-		State:        types.JobStateReady,
-		dirtyBoxes:   []*types.IsolateBox{},
-		logger:       logrus.WithField("job_id", jobID),
-		manager:      m,
+		ID:                jobID,
+		Runtime:           runtime,
+		Files:             files,
+		Archive:           request.Archive,
+		Entrypoint:        request.Entrypoint,
+		Args:              request.Args,
+		Stdin:             stdin,
+		StdinURL:          request.StdinURL,
+		StdinFixture:      request.StdinFixture,
+		ExpectedOutput:    request.ExpectedOutput,
+		CompareMode:       request.CompareMode,
+		Epsilon:           epsilon,
+		Env:               withLocale(request.Env, request.Locale, request.Timezone),
+		OutputFiles:       request.OutputFiles,
+		Priority:          request.Priority,
+		Timeouts:          timeouts,
+		CPUTimes:          cpuTimes,
+		MemoryLimits:      memoryLimits,
+		DiskQuota:         diskQuota,
+		Network:           network,
+		PTY:               request.PTY,
+		OutputMaxSize:     outputMaxSize,
+		OutputLimitAction: outputLimitAction,
+		Omit:              request.Omit,
+		Mounts:            request.Mounts,
+		CompileFlags:      request.CompileFlags,
+		RunFlags:          request.RunFlags,
+		Metadata:          request.Metadata,
+		flushInterval:     m.config.StreamFlushInterval,
+		State:             types.JobStateReady,
+		dirtyBoxes:        []*types.IsolateBox{},
+		logger:            logger,
+		requestID:         requestID,
+		requester:         requester,
+		request:           request,
+		tenant:            tenant,
+		manager:           m,
 
 		// Initialize streaming channels
 		EventChannel: make(chan types.StreamEvent, 100),
 		StdinChannel: make(chan string, 10),
 
 		// Initialize output budget (<=0 means unlimited)
-		outputBudget: runtime.OutputMaxSize,
+		outputBudget: outputMaxSize,
 	}
 }
 
 // Execute executes the job and returns the result
 func (j *Job) Execute(ctx context.Context) (*types.ExecutionResult, error) {
+	started := time.Now()
+	key := j.resultCacheKey()
+
+	result, err := j.cachedResult(key)
+	if result == nil && err == nil {
+		result, err = j.execute(ctx)
+		if err == nil && cacheableOutcome(result) {
+			j.manager.results.Put(key, result)
+		}
+	}
+
+	var compile, run *types.StageResult
+	if result != nil {
+		j.judge(result)
+		compile, run = result.Compile, result.Run
+	}
+	j.recordHistory("execute", started, compile, run, err)
+	return result, err
+}
+
+// cachedResult returns the result of an identical submission from the result cache, or nil.
+// Hits still count against the tenant's quotas but take no job slot.
+func (j *Job) cachedResult(key string) (*types.ExecutionResult, error) {
+	result, ok := j.manager.results.Get(key)
+	if !ok {
+		return nil, nil
+	}
+	if err := j.admitTenant(); err != nil {
+		return nil, err
+	}
+	j.endTenant()
+
+	j.logger.Debug("Result cache hit")
+	result.Cached = true
+	result.Metadata = j.Metadata
+	return result, nil
+}
+
+// execute runs the job's stages for Execute
+func (j *Job) execute(ctx context.Context) (*types.ExecutionResult, error) {
+	ctx, done, err := j.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 	defer j.cleanup()
 
 	// Wait for available slot
-	if err := j.waitForSlot(); err != nil {
+	if err := j.waitForSlot(ctx); err != nil {
 		return nil, fmt.Errorf("failed to acquire job slot: %w", err)
 	}
 	defer j.releaseSlot()
+	// Packages with running jobs are never evicted
+	defer j.manager.runtimes.Use(j.Runtime.PkgDir)()
+
+	if j.onStart != nil {
+		j.onStart()
+	}
+
+	// Interpreted programs may run in a process started ahead of time
+	if w := j.manager.warm.Take(j); w != nil {
+		return j.executeWarm(ctx, w)
+	}
 
 	j.logger.Info("Executing job")
 
@@ -197,34 +630,22 @@ func (j *Job) Execute(ctx context.Context) (*types.ExecutionResult, error) {
 		return nil, fmt.Errorf("failed to prime job: %w", err)
 	}
 
-	result := &types.ExecutionResult{
-		Language: j.Runtime.Language,
-		Version:  j.Runtime.Version.String(),
+	// Packages may declare a pipeline of their own in place of compile and run
+	if len(j.Runtime.Stages) > 0 {
+		stages, err := j.executePipeline(ctx, box, false)
+		if err != nil {
+			return nil, err
+		}
+		j.State = types.JobStateExecuted
+		return j.pipelineResult(ctx, box, stages), nil
 	}
-	// Fill in effective limits (optional)
-	result.Limits = &struct {
-		Timeouts struct {
-			Compile int `json:"compile"`
-			Run     int `json:"run"`
-		} `json:"timeouts"`
-		CPUTimes struct {
-			Compile int `json:"compile"`
-			Run     int `json:"run"`
-		} `json:"cpu_times"`
-		MemoryLimits struct {
-			Compile int64 `json:"compile"`
-			Run     int64 `json:"run"`
-		} `json:"memory_limits"`
-	}{}
-	result.Limits.Timeouts.Compile = int(j.Timeouts.Compile.Milliseconds())
-	result.Limits.Timeouts.Run = int(j.Timeouts.Run.Milliseconds())
-	result.Limits.CPUTimes.Compile = int(j.CPUTimes.Compile.Milliseconds())
-	result.Limits.CPUTimes.Run = int(j.CPUTimes.Run.Milliseconds())
-	result.Limits.MemoryLimits.Compile = j.MemoryLimits.Compile
-	result.Limits.MemoryLimits.Run = j.MemoryLimits.Run
 
-	// Compile stage (if needed)
-	if j.Runtime.Compiled {
+	result := j.newResult()
+
+	// Compile stage (if needed and not cached)
+	if cached, ok := j.restoreCompiled(box); ok {
+		result.Compile = cached
+	} else if j.Runtime.Compiled {
 		j.logger.Debug("Running compile stage")
 		compileResult, err := j.safeCall(ctx, box, "compile", j.getCodeFileNames(),
 			j.Timeouts.Compile, j.CPUTimes.Compile, j.MemoryLimits.Compile)
@@ -237,6 +658,7 @@ func (j *Job) Execute(ctx context.Context) (*types.ExecutionResult, error) {
 		if compileResult.Signal != "" || (compileResult.Code != nil && *compileResult.Code != 0) {
 			return result, nil
 		}
+		j.storeCompiled(box, compileResult)
 
 		// Create new box for run stage
 		if newBox, err := j.createIsolateBox(); err != nil {
@@ -254,7 +676,7 @@ func (j *Job) Execute(ctx context.Context) (*types.ExecutionResult, error) {
 
 	// Run stage
 	j.logger.Debug("Running execution stage")
-	args := []string{j.Files[0].Name}
+	args := []string{j.entrypoint()}
 	args = append(args, j.Args...)
 
 	runResult, err := j.safeCall(ctx, box, "run", args,
@@ -263,22 +685,76 @@ func (j *Job) Execute(ctx context.Context) (*types.ExecutionResult, error) {
 		return nil, fmt.Errorf("run stage failed: %w", err)
 	}
 	result.Run = runResult
+	result.Files = j.collectOutputFiles(ctx, box)
 
 	j.State = types.JobStateExecuted
 	return result, nil
 }
 
+// newResult returns an execution result carrying the runtime and the effective limits
+func (j *Job) newResult() *types.ExecutionResult {
+	result := &types.ExecutionResult{
+		Language: j.Runtime.Language,
+		Version:  j.Runtime.Version.String(),
+		Metadata: j.Metadata,
+	}
+	result.Limits = &struct {
+		Timeouts struct {
+			Compile int `json:"compile"`
+			Run     int `json:"run"`
+		} `json:"timeouts"`
+		CPUTimes struct {
+			Compile int `json:"compile"`
+			Run     int `json:"run"`
+		} `json:"cpu_times"`
+		MemoryLimits struct {
+			Compile int64 `json:"compile"`
+			Run     int64 `json:"run"`
+		} `json:"memory_limits"`
+	}{}
+	result.Limits.Timeouts.Compile = int(j.Timeouts.Compile.Milliseconds())
+	result.Limits.Timeouts.Run = int(j.Timeouts.Run.Milliseconds())
+	result.Limits.CPUTimes.Compile = int(j.CPUTimes.Compile.Milliseconds())
+	result.Limits.CPUTimes.Run = int(j.CPUTimes.Run.Milliseconds())
+	result.Limits.MemoryLimits.Compile = j.MemoryLimits.Compile
+	result.Limits.MemoryLimits.Run = j.MemoryLimits.Run
+	return result
+}
+
 // ExecuteStream executes the job with streaming support
 func (j *Job) ExecuteStream(ctx context.Context) error {
+	started := time.Now()
+	compile, run, err := j.executeStream(ctx)
+	j.recordHistory("stream", started, compile, run, err)
+	return err
+}
+
+// executeStream runs the job's stages for ExecuteStream, returning the stage results it reached
+func (j *Job) executeStream(ctx context.Context) (compileResult, runResult *types.StageResult, err error) {
+	ctx, done, err := j.begin(ctx)
+	if err != nil {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: err})
+		close(j.EventChannel)
+		return nil, nil, err
+	}
+	defer done()
 	defer j.cleanup()
 	defer close(j.EventChannel)
 
 	// Wait for available slot
-	if err := j.waitForSlot(); err != nil {
+	if err := j.waitForSlot(ctx); err != nil {
 		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("failed to acquire job slot: %w", err)})
-		return fmt.Errorf("failed to acquire job slot: %w", err)
+		return nil, nil, fmt.Errorf("failed to acquire job slot: %w", err)
 	}
 	defer j.releaseSlot()
+	// Packages with running jobs are never evicted
+	defer j.manager.runtimes.Use(j.Runtime.PkgDir)()
+
+	// Interpreted programs may run in a process started ahead of time
+	if w := j.manager.warm.Take(j); w != nil {
+		runResult, err = j.executeWarmStream(ctx, w)
+		return nil, runResult, err
+	}
 
 	j.logger.Info("Executing job with streaming")
 
@@ -286,21 +762,37 @@ func (j *Job) ExecuteStream(ctx context.Context) error {
 	box, err := j.prime(ctx)
 	if err != nil {
 		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("failed to prime job: %w", err)})
-		return fmt.Errorf("failed to prime job: %w", err)
+		return nil, nil, fmt.Errorf("failed to prime job: %w", err)
 	}
 
 	// Runtime information is sent by the websocket handler upon init_ack
 
-	// Compile stage (if needed)
-	if j.Runtime.Compiled {
+	if len(j.Runtime.Stages) > 0 {
+		stages, err := j.executePipeline(ctx, box, true)
+		if err != nil {
+			j.sendEvent(types.StreamEvent{Type: "error", Error: err})
+			return nil, nil, err
+		}
+		result := j.pipelineResult(ctx, box, stages)
+		j.judge(result)
+		j.sendEvent(types.StreamEvent{Type: "result", Result: result})
+		j.State = types.JobStateExecuted
+		return nil, result.Run, nil
+	}
+
+	// Compile stage (if needed and not cached)
+	if cached, ok := j.restoreCompiled(box); ok {
+		compileResult = cached
+		j.logger.Debug("Skipping compile stage, using cached artifacts")
+	} else if j.Runtime.Compiled {
 		j.logger.Debug("Running compile stage")
 		j.sendEvent(types.StreamEvent{Type: "stage_start", Stage: "compile"})
 
-		compileResult, err := j.safeCallStream(ctx, box, "compile", j.getCodeFileNames(),
+		compileResult, err = j.safeCallStream(ctx, box, "compile", j.getCodeFileNames(),
 			j.Timeouts.Compile, j.CPUTimes.Compile, j.MemoryLimits.Compile)
 		if err != nil {
 			j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("compile stage failed: %w", err)})
-			return fmt.Errorf("compile stage failed: %w", err)
+			return nil, nil, fmt.Errorf("compile stage failed: %w", err)
 		}
 
 		// Send stage end after compile completes
@@ -309,24 +801,26 @@ func (j *Job) ExecuteStream(ctx context.Context) error {
 		if compileResult.Code != nil {
 			compCode = *compileResult.Code
 		}
-		j.sendEvent(types.StreamEvent{Type: "stage_end", Stage: "compile", Code: compCode})
+		j.sendEvent(types.StreamEvent{Type: "stage_end", Stage: "compile", Code: compCode, Truncated: compileResult.Truncated})
 
 		// If compilation failed, don't run
 		if compileResult.Signal != "" || (compileResult.Code != nil && *compileResult.Code != 0) {
-			return nil
+			j.sendResult(compileResult, nil, nil)
+			return compileResult, nil, nil
 		}
+		j.storeCompiled(box, compileResult)
 
 		// Create new box for run stage
 		if newBox, err := j.createIsolateBox(); err != nil {
 			j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("failed to create run box: %w", err)})
-			return fmt.Errorf("failed to create run box: %w", err)
+			return compileResult, nil, fmt.Errorf("failed to create run box: %w", err)
 		} else {
 			// Move compiled files to new box
 			oldSubmissionDir := filepath.Join(box.Dir, "submission")
 			newSubmissionDir := filepath.Join(newBox.Dir, "submission")
 			if err := os.Rename(oldSubmissionDir, newSubmissionDir); err != nil {
 				j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("failed to move compiled files: %w", err)})
-				return fmt.Errorf("failed to move compiled files: %w", err)
+				return compileResult, nil, fmt.Errorf("failed to move compiled files: %w", err)
 			}
 			box = newBox
 		}
@@ -336,14 +830,14 @@ func (j *Job) ExecuteStream(ctx context.Context) error {
 	j.logger.Debug("Running execution stage")
 	j.sendEvent(types.StreamEvent{Type: "stage_start", Stage: "run"})
 
-	args := []string{j.Files[0].Name}
+	args := []string{j.entrypoint()}
 	args = append(args, j.Args...)
 
-	runResult, err := j.safeCallStream(ctx, box, "run", args,
+	runResult, err = j.safeCallStream(ctx, box, "run", args,
 		j.Timeouts.Run, j.CPUTimes.Run, j.MemoryLimits.Run)
 	if err != nil {
 		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("run stage failed: %w", err)})
-		return fmt.Errorf("run stage failed: %w", err)
+		return compileResult, nil, fmt.Errorf("run stage failed: %w", err)
 	}
 
 	// Send stage end for run stage
@@ -351,14 +845,52 @@ func (j *Job) ExecuteStream(ctx context.Context) error {
 	if runResult.Code != nil {
 		runCode = *runResult.Code
 	}
-	j.sendEvent(types.StreamEvent{Type: "stage_end", Stage: "run", Code: runCode})
+	j.sendEvent(types.StreamEvent{Type: "stage_end", Stage: "run", Code: runCode, Truncated: runResult.Truncated})
+	j.sendResult(compileResult, runResult, j.collectOutputFiles(ctx, box))
 
 	j.State = types.JobStateExecuted
-	return nil
+	return compileResult, runResult, nil
+}
+
+// sendResult sends the final result of a streaming job, shaped like the REST response
+func (j *Job) sendResult(compile, run *types.StageResult, files []types.OutputFile) {
+	result := j.newResult()
+	result.Compile = compile
+	result.Run = run
+	result.Files = files
+
+	// Piston behavior: a failed compile is also reported as the run stage
+	if result.Run == nil {
+		result.Run = result.Compile
+	}
+	j.judge(result)
+	j.sendEvent(types.StreamEvent{Type: "result", Result: result})
+}
+
+// restoreCompiled fills the box with cached compile output, returning the original compile result
+func (j *Job) restoreCompiled(box *types.IsolateBox) (*types.StageResult, bool) {
+	if !j.Runtime.Compiled || j.manager.cache == nil {
+		return nil, false
+	}
+
+	result, ok := j.manager.cache.Get(compileCacheKey(j), filepath.Join(box.Dir, "submission"))
+	if ok {
+		j.logger.Debug("Compile cache hit")
+	}
+	return result, ok
+}
+
+// storeCompiled saves a successful compile output to the cache
+func (j *Job) storeCompiled(box *types.IsolateBox, result *types.StageResult) {
+	if j.manager.cache == nil {
+		return
+	}
+	j.manager.cache.Put(compileCacheKey(j), filepath.Join(box.Dir, "submission"), result)
 }
 
 // sendEvent sends a stream event
 func (j *Job) sendEvent(event types.StreamEvent) {
+	j.touch()
 	select {
 	case j.EventChannel <- event:
 	default:
@@ -368,6 +900,12 @@ func (j *Job) sendEvent(event types.StreamEvent) {
 
 // WriteStdin writes data to the running process stdin
 func (j *Job) WriteStdin(data string) error {
+	j.touch()
+	j.stdinMutex.Lock()
+	defer j.stdinMutex.Unlock()
+	if j.stdinClosed {
+		return fmt.Errorf("stdin is closed")
+	}
 	select {
 	case j.StdinChannel <- data:
 		return nil
@@ -376,50 +914,96 @@ func (j *Job) WriteStdin(data string) error {
 	}
 }
 
-// SendSignal sends a signal to the running process
+// CloseStdin signals end of input: once the data already written is delivered, the stage's
+// stdin is closed, and so is that of every later stage. Closing it again is a no-op.
+func (j *Job) CloseStdin() {
+	j.touch()
+	j.stdinMutex.Lock()
+	defer j.stdinMutex.Unlock()
+	if !j.stdinClosed {
+		j.stdinClosed = true
+		close(j.StdinChannel)
+	}
+}
+
+// Resize changes the terminal size of a pty-mode job
+func (j *Job) Resize(rows, cols uint16) error {
+	if j.PTY == nil {
+		return fmt.Errorf("job is not in pty mode")
+	}
+	if rows == 0 || cols == 0 {
+		return fmt.Errorf("rows and cols must be positive")
+	}
+
+	j.cmdMutex.Lock()
+	defer j.cmdMutex.Unlock()
+
+	j.PTY = &types.PTYSize{Rows: rows, Cols: cols}
+	if j.pty == nil {
+		// Applied when the run stage starts
+		return nil
+	}
+	return resizePTY(j.pty, j.PTY)
+}
+
+// usesPTY reports whether stage runs attached to a pseudo-terminal
+func (j *Job) usesPTY(stage string) bool {
+	return j.PTY != nil && (stage == "run" || stage == "repl")
+}
+
+// SendSignal sends a signal named in allowed_signals to the running program and the processes
+// it started
 func (j *Job) SendSignal(signal string) error {
+	sig, ok := j.manager.signal(signal)
+	if !ok {
+		return fmt.Errorf("invalid signal: %s", signal)
+	}
+
 	j.cmdMutex.RLock()
 	defer j.cmdMutex.RUnlock()
-
-	if j.runningCmd == nil || j.runningCmd.Process == nil {
+	if j.runningBox == nil {
 		return fmt.Errorf("no running process")
 	}
+	return j.manager.sandbox.Signal(j.runningBox, sig)
+}
 
-	var sig os.Signal
-	switch signal {
-	case "SIGTERM":
-		sig = syscall.SIGTERM
-	case "SIGKILL":
-		sig = syscall.SIGKILL
-	case "SIGINT":
-		sig = syscall.SIGINT
-	default:
-		return fmt.Errorf("invalid signal: %s", signal)
-	}
+// SignalAllowed reports whether clients may send the named signal
+func (m *Manager) SignalAllowed(name string) bool {
+	_, ok := m.signal(name)
+	return ok
+}
 
-	return j.runningCmd.Process.Signal(sig)
+// signal returns the signal with the given name if allowed_signals lists it
+func (m *Manager) signal(name string) (syscall.Signal, bool) {
+	if !slices.Contains(m.config.AllowedSignals, name) {
+		return 0, false
+	}
+	return signalNumber(name)
 }
 
 // prime prepares the job for execution
-func (j *Job) prime(ctx context.Context) (*types.IsolateBox, error) {
+func (j *Job) prime(ctx context.Context) (box *types.IsolateBox, err error) {
+	_, span := j.startSpan(ctx, "job.prime")
+	defer func() { tracing.End(span, err) }()
+
 	j.logger.Info("Priming job")
 
+	if err := j.prepareWorkDir(); err != nil {
+		return nil, err
+	}
+
 	// Create isolate box
-	box, err := j.createIsolateBox()
+	box, err = j.createIsolateBox()
 	if err != nil {
 		return nil, err
 	}
 
-	// Create submission directory and write files
-	submissionDir := filepath.Join(box.Dir, "submission")
-	if err := os.MkdirAll(submissionDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create submission directory: %w", err)
+	if err := j.prepareMounts(); err != nil {
+		return nil, err
 	}
 
-	for _, file := range j.Files {
-		if err := j.writeFile(submissionDir, file); err != nil {
-			return nil, fmt.Errorf("failed to write file %s: %w", file.Name, err)
-		}
+	if err := j.writeSubmission(box); err != nil {
+		return nil, err
 	}
 
 	j.State = types.JobStatePrimed
@@ -427,27 +1011,43 @@ func (j *Job) prime(ctx context.Context) (*types.IsolateBox, error) {
 	return box, nil
 }
 
-// createIsolateBox creates a new isolate sandbox
-func (j *Job) createIsolateBox() (*types.IsolateBox, error) {
-	boxID := int(atomic.AddInt32(&boxIDCounter, 1) % MaxBoxID)
-	metadataPath := fmt.Sprintf("/tmp/%d-metadata.txt", boxID)
+// writeSubmission creates the box's submission directory and writes the job's files into it
+func (j *Job) writeSubmission(box *types.IsolateBox) error {
+	submissionDir := filepath.Join(box.Dir, "submission")
+	if err := os.MkdirAll(submissionDir, 0700); err != nil {
+		return fmt.Errorf("failed to create submission directory: %w", err)
+	}
 
-	cmd := exec.Command(IsolatePath, "--init", "--cg", fmt.Sprintf("-b%d", boxID))
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("isolate init failed: %w", err)
+	// Files from the files array overwrite archive entries of the same name
+	if j.Archive != "" {
+		var err error
+		j.archiveFiles, err = j.extractArchive(submissionDir)
+		if err != nil {
+			return fmt.Errorf("failed to extract archive: %w", err)
+		}
 	}
 
-	outputStr := strings.TrimSpace(string(output))
-	if outputStr == "" {
-		return nil, fmt.Errorf("received empty output from isolate --init")
+	for _, file := range j.Files {
+		if err := j.writeFile(submissionDir, file); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", file.Name, err)
+		}
 	}
 
-	box := &types.IsolateBox{
-		ID:           boxID,
-		MetadataPath: metadataPath,
-		Dir:          outputStr + "/box",
+	if j.Archive != "" {
+		if info, err := os.Stat(filepath.Join(submissionDir, j.entrypoint())); err != nil || !info.Mode().IsRegular() {
+			return fmt.Errorf("entrypoint %s is not one of the submitted files", j.entrypoint())
+		}
 	}
+	return nil
+}
+
+// createIsolateBox takes a box from the sandbox backend
+func (j *Job) createIsolateBox() (*types.IsolateBox, error) {
+	box, err := j.manager.sandbox.Acquire(j.DiskQuota)
+	if err != nil {
+		return nil, err
+	}
+	box.MetadataPath = j.boxMetadataPath(box.ID)
 
 	j.dirtyBoxes = append(j.dirtyBoxes, box)
 	return box, nil
@@ -496,11 +1096,10 @@ func (j *Job) writeFile(submissionDir string, file types.CodeFile) error {
 	return nil
 }
 
-// safeCall executes a stage (compile or run) safely within isolate
-func (j *Job) safeCall(ctx context.Context, box *types.IsolateBox, stage string, args []string,
-	timeout, cpuTime time.Duration, memoryLimit int64) (*types.StageResult, error) {
+// buildIsolateArgs builds the isolate --run arguments for a stage
+func (j *Job) buildIsolateArgs(box *types.IsolateBox, stage string, args []string,
+	timeout, cpuTime time.Duration, memoryLimit int64) []string {
 
-	// Build isolate command
 	isolateArgs := []string{
 		"--run",
 		fmt.Sprintf("-b%d", box.ID),
@@ -516,46 +1115,86 @@ func (j *Job) safeCall(ctx context.Context, box *types.IsolateBox, stage string,
 		isolateArgs = append(isolateArgs, "-E", envVar)
 	}
 
+	// Add request environment variables (validated against the allow/deny lists)
+	for _, name := range sortedKeys(j.Env) {
+		isolateArgs = append(isolateArgs, "-E", fmt.Sprintf("%s=%s", name, j.Env[name]))
+	}
+
 	// Add coderunr language env var
 	isolateArgs = append(isolateArgs, "-E", fmt.Sprintf("CODERUNR_LANGUAGE=%s", j.Runtime.Language))
+	for _, env := range j.flagEnv(stage) {
+		isolateArgs = append(isolateArgs, "-E", env)
+	}
+	for _, env := range memoryEnv(memoryLimit) {
+		isolateArgs = append(isolateArgs, "-E", env)
+	}
 
 	// Add directories
 	isolateArgs = append(isolateArgs, fmt.Sprintf("--dir=%s", j.Runtime.PkgDir))
-	isolateArgs = append(isolateArgs, "--dir=/etc:noexec")
+	isolateArgs = append(isolateArgs, j.sandboxArgs()...)
+	isolateArgs = append(isolateArgs, j.mountArgs()...)
 
 	// Add resource limits
 	isolateArgs = append(isolateArgs, fmt.Sprintf("--processes=%d", j.Runtime.MaxProcessCount))
 	isolateArgs = append(isolateArgs, fmt.Sprintf("--open-files=%d", j.Runtime.MaxOpenFiles))
 	isolateArgs = append(isolateArgs, fmt.Sprintf("--fsize=%d", j.Runtime.MaxFileSize/1000))
-	// Round sub-second timeouts up to 1s so isolate enforces them
-	wt := int(math.Ceil(timeout.Seconds()))
-	ct := int(math.Ceil(cpuTime.Seconds()))
-	if timeout > 0 && wt == 0 {
-		wt = 1
-	}
-	if cpuTime > 0 && ct == 0 {
-		ct = 1
-	}
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--wall-time=%d", wt))
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--time=%d", ct))
+	isolateArgs = append(isolateArgs, "--wall-time="+isolateSeconds(timeout))
+	isolateArgs = append(isolateArgs, "--time="+isolateSeconds(cpuTime))
 	isolateArgs = append(isolateArgs, "--extra-time=0")
 
 	// Add memory limit if specified
 	if memoryLimit >= 0 {
-		isolateArgs = append(isolateArgs, fmt.Sprintf("--cg-mem=%d", memoryLimit/1000))
+		isolateArgs = append(isolateArgs, fmt.Sprintf("--cg-mem=%d", j.sandboxMemory(memoryLimit)/1000))
+	}
+
+	// Make the program the terminal's foreground process so job control and readline work
+	if j.usesPTY(stage) {
+		isolateArgs = append(isolateArgs, "--tty-hack")
 	}
 
 	// Add networking option
-	if !j.manager.config.DisableNetworking {
+	if j.Network {
 		isolateArgs = append(isolateArgs, "--share-net")
+		isolateArgs = append(isolateArgs, proxyEnvArgs(j.manager.config.GetNetworkProxy())...)
 	}
 
+	isolateArgs = append(isolateArgs, j.extraSandboxArgs()...)
+
 	// Add execution command
 	isolateArgs = append(isolateArgs, "--", "/bin/bash", filepath.Join(j.Runtime.PkgDir, stage))
 	isolateArgs = append(isolateArgs, args...)
 
+	return isolateArgs
+}
+
+// roundLimit rounds a time limit up to a multiple of resolution; 0 stays unlimited
+func roundLimit(limit, resolution time.Duration) time.Duration {
+	if limit <= 0 || resolution <= 0 {
+		return limit
+	}
+	return (limit + resolution - 1) / resolution * resolution
+}
+
+// isolateSeconds formats a time limit as the fractional seconds isolate takes, rounded up to
+// the millisecond isolate measures in
+func isolateSeconds(limit time.Duration) string {
+	return strconv.FormatFloat(roundLimit(limit, time.Millisecond).Seconds(), 'f', -1, 64)
+}
+
+// safeCall executes a stage (compile or run) safely within the sandbox
+func (j *Job) safeCall(ctx context.Context, box *types.IsolateBox, stage string, args []string,
+	timeout, cpuTime time.Duration, memoryLimit int64) (result *types.StageResult, err error) {
+
+	ctx, span := j.startStageSpan(ctx, stage, timeout, cpuTime, memoryLimit)
+	defer func() { endStageSpan(span, result, err) }()
+
+	// Kill the sandbox ourselves should it outlive its wall-time limit
+	started := time.Now()
+	ctx, expired, stopWatchdog := j.stageWatchdog(ctx, timeout)
+	defer stopWatchdog()
+
 	// Create command with context
-	cmd := exec.CommandContext(ctx, IsolatePath, isolateArgs...)
+	cmd := j.manager.sandbox.Command(ctx, j, box, stage, args, timeout, cpuTime, memoryLimit)
 
 	// Set up pipes
 	stdin, err := cmd.StdinPipe()
@@ -573,135 +1212,101 @@ func (j *Job) safeCall(ctx context.Context, box *types.IsolateBox, stage string,
 		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
+	// Store running command so an output limit can kill it
+	j.cmdMutex.Lock()
+	j.runningCmd = cmd
+	j.runningBox = box
+	j.cmdMutex.Unlock()
+
 	// Start command
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start isolate: %w", err)
+		return nil, &SandboxError{Stage: stage, Err: err}
 	}
 
 	// Write stdin and close
 	go func() {
 		defer stdin.Close()
-		if j.Stdin != "" {
-			stdin.Write([]byte(j.Stdin))
-		}
+		j.writeStdin(stdin)
 	}()
 
-	// Read output with size limits
+	// Stop reading once the job is cancelled, as safeCallStream does
+	stopReading := context.AfterFunc(ctx, func() {
+		stdout.Close()
+		stderr.Close()
+	})
+	defer stopReading()
+
+	// Read output with size limits. The pipes are read to the end before Wait closes them,
+	// or output still buffered when a fast stage exits would be lost.
 	var stdoutBuf, stderrBuf bytes.Buffer
-	var outputBuf bytes.Buffer
+	var outputBuf combinedOutput
 
-	go j.readWithLimit(stdout, &stdoutBuf, &outputBuf)
-	go j.readWithLimit(stderr, &stderrBuf, &outputBuf)
+	var streams sync.WaitGroup
+	streams.Add(2)
+	go func() {
+		defer streams.Done()
+		j.readWithLimit(stdout, &stdoutBuf, &outputBuf)
+	}()
+	go func() {
+		defer streams.Done()
+		j.readWithLimit(stderr, &stderrBuf, &outputBuf)
+	}()
+	streams.Wait()
 
 	// Wait for command to finish
 	err = cmd.Wait()
 
-	// Parse metadata
-	metadata, parseErr := j.parseMetadata(box.MetadataPath)
-	if parseErr != nil {
-		j.logger.WithError(parseErr).Warn("Failed to parse metadata")
-	}
-
-	exitCode := cmd.ProcessState.ExitCode()
-	result := &types.StageResult{
-		Stdout: stdoutBuf.String(),
-		Stderr: stderrBuf.String(),
-		Output: outputBuf.String(),
-		Code:   &exitCode,
-	}
-
-	// Apply metadata if available
-	if metadata != nil {
-		result.Memory = metadata.Memory
-		result.CPUTime = metadata.CPUTime.Milliseconds()
-		result.WallTime = metadata.WallTime.Milliseconds()
-		result.Status = metadata.Status
-		result.Message = metadata.Message
-		result.Signal = metadata.Signal
-	}
-
-	// Override signal for certain statuses
-	if result.Status == "TO" || result.Status == "OL" || result.Status == "EL" {
-		result.Signal = "SIGKILL"
-	}
+	j.cmdMutex.Lock()
+	j.runningCmd = nil
+	j.runningBox = nil
+	j.cmdMutex.Unlock()
 
-	// Piston semantics: if a signal is present, code must be null
-	if result.Signal != "" {
-		result.Code = nil
+	result = j.stageResult(box, cmd, err)
+	if expired() {
+		j.watchdogTimeout(result, time.Since(started))
 	}
-
-	// Handle command execution error
-	if err != nil {
-		if result.Status == "" {
-			result.Status = "RE"
-			result.Message = "Runtime error"
-		}
+	result.Stdout = stdoutBuf.String()
+	result.Stderr = stderrBuf.String()
+	result.Output = outputBuf.String()
+	if err := sandboxFailure(stage, result); err != nil {
+		return nil, err
 	}
-
 	return result, nil
 }
 
 // safeCallStream executes a stage with streaming support
 func (j *Job) safeCallStream(ctx context.Context, box *types.IsolateBox, stage string, args []string,
-	timeout, cpuTime time.Duration, memoryLimit int64) (*types.StageResult, error) {
+	timeout, cpuTime time.Duration, memoryLimit int64) (result *types.StageResult, err error) {
 
-	// Build isolate command (same as safeCall)
-	isolateArgs := []string{
-		"--run",
-		fmt.Sprintf("-b%d", box.ID),
-		fmt.Sprintf("--meta=%s", box.MetadataPath),
-		"--cg",
-		"-s",
-		"-c", "/box/submission",
-		"-E", "HOME=/tmp",
-	}
+	ctx, span := j.startStageSpan(ctx, stage, timeout, cpuTime, memoryLimit)
+	defer func() { endStageSpan(span, result, err) }()
 
-	// Add environment variables
-	for _, envVar := range j.Runtime.EnvVars {
-		isolateArgs = append(isolateArgs, "-E", envVar)
-	}
+	// Kill the sandbox ourselves should it outlive its wall-time limit
+	started := time.Now()
+	ctx, expired, stopWatchdog := j.stageWatchdog(ctx, timeout)
+	defer stopWatchdog()
 
-	// Add coderunr language env var
-	isolateArgs = append(isolateArgs, "-E", fmt.Sprintf("CODERUNR_LANGUAGE=%s", j.Runtime.Language))
-
-	// Add directories
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--dir=%s", j.Runtime.PkgDir))
-	isolateArgs = append(isolateArgs, "--dir=/etc:noexec")
-
-	// Add resource limits
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--processes=%d", j.Runtime.MaxProcessCount))
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--open-files=%d", j.Runtime.MaxOpenFiles))
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--fsize=%d", j.Runtime.MaxFileSize/1000))
-	// Round sub-second timeouts up to 1s so isolate enforces them
-	wt := int(math.Ceil(timeout.Seconds()))
-	ct := int(math.Ceil(cpuTime.Seconds()))
-	if timeout > 0 && wt == 0 {
-		wt = 1
-	}
-	if cpuTime > 0 && ct == 0 {
-		ct = 1
-	}
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--wall-time=%d", wt))
-	isolateArgs = append(isolateArgs, fmt.Sprintf("--time=%d", ct))
-	isolateArgs = append(isolateArgs, "--extra-time=0")
+	// Create command with context
+	cmd := j.manager.sandbox.Command(ctx, j, box, stage, args, timeout, cpuTime, memoryLimit)
 
-	// Add memory limit if specified
-	if memoryLimit >= 0 {
-		isolateArgs = append(isolateArgs, fmt.Sprintf("--cg-mem=%d", memoryLimit/1000))
+	if stage == "run" {
+		defer j.sampleStats(box, stage)()
 	}
 
-	// Add networking option
-	if !j.manager.config.DisableNetworking {
-		isolateArgs = append(isolateArgs, "--share-net")
+	if j.usesPTY(stage) {
+		result, err = j.callPTY(ctx, cmd, box, stage)
+		if err != nil {
+			return nil, err
+		}
+		if expired() {
+			j.watchdogTimeout(result, time.Since(started))
+		}
+		if err := sandboxFailure(stage, result); err != nil {
+			return nil, err
+		}
+		return result, nil
 	}
 
-	// Add execution command
-	isolateArgs = append(isolateArgs, "--", "/bin/bash", filepath.Join(j.Runtime.PkgDir, stage))
-	isolateArgs = append(isolateArgs, args...)
-
-	// Create command with context
-	cmd := exec.CommandContext(ctx, IsolatePath, isolateArgs...)
-
 	// Set up pipes
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -721,11 +1326,12 @@ func (j *Job) safeCallStream(ctx context.Context, box *types.IsolateBox, stage s
 	// Store running command for signal handling
 	j.cmdMutex.Lock()
 	j.runningCmd = cmd
+	j.runningBox = box
 	j.cmdMutex.Unlock()
 
 	// Start command
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start isolate: %w", err)
+		return nil, &SandboxError{Stage: stage, Err: err}
 	}
 
 	// Handle stdin in goroutine (with streaming support)
@@ -733,9 +1339,7 @@ func (j *Job) safeCallStream(ctx context.Context, box *types.IsolateBox, stage s
 		defer stdin.Close()
 
 		// Write initial stdin if provided
-		if j.Stdin != "" {
-			stdin.Write([]byte(j.Stdin))
-		}
+		j.writeStdin(stdin)
 
 		// Listen for streaming stdin
 		for {
@@ -751,11 +1355,28 @@ func (j *Job) safeCallStream(ctx context.Context, box *types.IsolateBox, stage s
 		}
 	}()
 
-	// Handle stdout streaming
-	go j.streamOutput(stdout, "stdout")
+	// Once the job is cancelled (client gone, drain timeout) isolate is killed, but stop
+	// reading too so sandboxed processes still holding the pipes cannot delay the cleanup
+	stopReading := context.AfterFunc(ctx, func() {
+		stdout.Close()
+		stderr.Close()
+	})
+	defer stopReading()
 
-	// Handle stderr streaming
-	go j.streamOutput(stderr, "stderr")
+	// Stream stdout and stderr, keeping a copy for the stage result. The pipes are read to
+	// the end before Wait closes them.
+	capture := newOutputCapture(j.OutputMaxSize)
+	var streams sync.WaitGroup
+	streams.Add(2)
+	go func() {
+		defer streams.Done()
+		j.streamOutput(stdout, stage, "stdout", capture)
+	}()
+	go func() {
+		defer streams.Done()
+		j.streamOutput(stderr, stage, "stderr", capture)
+	}()
+	streams.Wait()
 
 	// Wait for command to finish
 	err = cmd.Wait()
@@ -763,10 +1384,28 @@ func (j *Job) safeCallStream(ctx context.Context, box *types.IsolateBox, stage s
 	// Clear running command
 	j.cmdMutex.Lock()
 	j.runningCmd = nil
+	j.runningBox = nil
 	j.cmdMutex.Unlock()
 
-	// Parse metadata
-	metadata, parseErr := j.parseMetadata(box.MetadataPath)
+	result = j.stageResult(box, cmd, err)
+	if expired() {
+		j.watchdogTimeout(result, time.Since(started))
+	}
+	capture.apply(result)
+	if err := sandboxFailure(stage, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// stageResult builds a stage result from the finished command and the usage the sandbox measured
+func (j *Job) stageResult(box *types.IsolateBox, cmd *exec.Cmd, err error) *types.StageResult {
+	// The stage has ended only once nothing it started is left running
+	if killErr := j.manager.sandbox.KillRemaining(box); killErr != nil {
+		j.logger.WithError(killErr).Warn("Failed to kill the stage's remaining processes")
+	}
+
+	metadata, parseErr := j.manager.sandbox.Usage(box)
 	if parseErr != nil {
 		j.logger.WithError(parseErr).Warn("Failed to parse metadata")
 	}
@@ -785,6 +1424,7 @@ func (j *Job) safeCallStream(ctx context.Context, box *types.IsolateBox, stage s
 		result.Message = metadata.Message
 		result.Signal = metadata.Signal
 	}
+	diskFull := j.applyDiskUsage(box, result)
 
 	// Override signal for certain statuses
 	if result.Status == "TO" || result.Status == "OL" || result.Status == "EL" {
@@ -804,188 +1444,157 @@ func (j *Job) safeCallStream(ctx context.Context, box *types.IsolateBox, stage s
 		}
 	}
 
-	return result, nil
+	result.Outcome = stageOutcome(result, metadata, j.outputLimited.Swap(false), diskFull)
+	result.Truncated = j.outputTruncated.Swap(false)
+	return result
 }
 
-// streamOutput reads output and sends it as events
-func (j *Job) streamOutput(reader io.Reader, streamType string) {
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := scanner.Text() // without trailing newline
-
-		// Enforce combined stdout/stderr budget if enabled
-		if j.outputBudget > 0 {
-			j.outputMu.Lock()
-			remaining := j.outputBudget - j.outputSent
-			if remaining <= 0 {
-				j.outputMu.Unlock()
-				j.triggerOutputLimitExceeded()
-				return
-			}
-
-			// Trim line if it exceeds remaining budget
-			if len(line) > remaining {
-				line = line[:remaining]
-				j.outputSent += len(line)
-				j.outputMu.Unlock()
+// sendOutput sends a data event tagged with its stage, enforcing the combined stdout/stderr budget if enabled.
+// It returns false once the budget is exhausted and the process has been killed; in truncate
+// mode it keeps returning true so the caller drains the output.
+func (j *Job) sendOutput(stage, streamType, data string) bool {
+	if j.lsp != nil && streamType == "stdout" {
+		return j.sendLSP(stage, data)
+	}
+	if j.outputBudget > 0 {
+		j.outputMu.Lock()
+		remaining := j.outputBudget - j.outputSent
+
+		// Trim data if it exceeds remaining budget
+		if remaining <= 0 || len(data) > remaining {
+			data = data[:remaining]
+			j.outputSent += len(data)
+			j.outputMu.Unlock()
 
-				// Send truncated data then terminate
-				j.sendEvent(types.StreamEvent{Type: "data", Stream: streamType, Data: line})
-				j.triggerOutputLimitExceeded()
-				return
+			// Send what fits, then apply the output limit action
+			if data != "" {
+				j.sendEvent(types.StreamEvent{Type: "data", Stage: stage, Stream: streamType, Data: data})
 			}
-
-			// Send and account
-			j.outputSent += len(line)
-			j.outputMu.Unlock()
+			return !j.outputLimitExceeded()
 		}
 
-		// Budget disabled or accounted: send normally
-		j.sendEvent(types.StreamEvent{Type: "data", Stream: streamType, Data: line})
+		// Send and account
+		j.outputSent += len(data)
+		j.outputMu.Unlock()
+	}
+
+	// Budget disabled or accounted: send normally
+	j.sendEvent(types.StreamEvent{Type: "data", Stage: stage, Stream: streamType, Data: data})
+	return true
+}
+
+// outputLimitExceeded marks the stage's output as truncated and, unless the job truncates,
+// kills it. It reports whether the process was killed.
+func (j *Job) outputLimitExceeded() bool {
+	j.outputTruncated.Store(true)
+	if j.OutputLimitAction == OutputLimitTruncate {
+		return false
 	}
+	j.triggerOutputLimitExceeded()
+	return true
 }
 
 // triggerOutputLimitExceeded sends an error once and terminates the running process
 func (j *Job) triggerOutputLimitExceeded() {
+	j.outputLimited.Store(true)
 	j.killOnce.Do(func() {
 		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("output limit exceeded")})
-		j.cmdMutex.RLock()
-		defer j.cmdMutex.RUnlock()
-		if j.runningCmd != nil && j.runningCmd.Process != nil {
-			_ = j.runningCmd.Process.Kill()
-		}
+		j.killRunning()
 	})
 }
 
-// readWithLimit reads from a reader with size limit
-func (j *Job) readWithLimit(reader io.Reader, targetBuf, outputBuf *bytes.Buffer) {
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := scanner.Text() + "\n"
-
-		if targetBuf.Len()+len(line) <= j.Runtime.OutputMaxSize {
-			targetBuf.WriteString(line)
-			outputBuf.WriteString(line)
-		} else {
-			break // Stop reading if limit exceeded
-		}
+// killRunning kills the running stage: the process group of its command and every process in
+// its box, so nothing it started keeps running until the sandbox notices
+func (j *Job) killRunning() {
+	j.cmdMutex.RLock()
+	defer j.cmdMutex.RUnlock()
+	if j.runningCmd != nil && j.runningCmd.Process != nil {
+		_ = killGroup(j.runningCmd)
 	}
-}
-
-// parseMetadata parses the isolate metadata file
-func (j *Job) parseMetadata(metadataPath string) (*isolateMetadata, error) {
-	content, err := os.ReadFile(metadataPath)
-	if err != nil {
-		return nil, err
+	if j.runningBox != nil {
+		_ = j.manager.sandbox.Signal(j.runningBox, syscall.SIGKILL)
 	}
+}
 
-	metadata := &isolateMetadata{}
-	lines := strings.Split(string(content), "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
+// readWithLimit reads from a reader with size limit, also adding each line to output. Past the
+// limit the process is killed, or in truncate mode the rest of the output is read and dropped.
+func (j *Job) readWithLimit(reader io.Reader, targetBuf *bytes.Buffer, output *combinedOutput) {
+	scanner := bufio.NewScanner(reader)
+	truncated := false
+	for scanner.Scan() {
+		if truncated {
 			continue
 		}
+		line := scanner.Text() + "\n"
 
-		key, value := parts[0], parts[1]
-		switch key {
-		case "cg-mem":
-			if mem, err := strconv.ParseInt(value, 10, 64); err == nil {
-				metadata.Memory = mem * 1000
-			}
-		case "exitcode":
-			if code, err := strconv.Atoi(value); err == nil {
-				metadata.ExitCode = code
-			}
-		case "exitsig":
-			if sig, err := strconv.Atoi(value); err == nil {
-				metadata.Signal = signalToString(sig)
-			}
-		case "message":
-			metadata.Message = value
-		case "status":
-			metadata.Status = value
-		case "time":
-			if t, err := strconv.ParseFloat(value, 64); err == nil {
-				metadata.CPUTime = time.Duration(t * float64(time.Second))
-			}
-		case "time-wall":
-			if t, err := strconv.ParseFloat(value, 64); err == nil {
-				metadata.WallTime = time.Duration(t * float64(time.Second))
+		if targetBuf.Len()+len(line) <= j.OutputMaxSize {
+			targetBuf.WriteString(line)
+			output.write(line)
+		} else {
+			truncated = true
+			if j.outputLimitExceeded() {
+				break
 			}
 		}
 	}
-
-	return metadata, nil
 }
 
-// isolateMetadata represents metadata from isolate
-type isolateMetadata struct {
-	Memory   int64
-	ExitCode int
-	Signal   string
-	Message  string
-	Status   string
-	CPUTime  time.Duration
-	WallTime time.Duration
-}
-
-// getCodeFileNames returns the names of code files
+// getCodeFileNames returns the names of code files, starting with the entrypoint
 func (j *Job) getCodeFileNames() []string {
-	names := make([]string, len(j.Files))
-	for i, file := range j.Files {
-		names[i] = file.Name
+	entrypoint := j.entrypoint()
+	names := []string{entrypoint}
+	seen := map[string]bool{entrypoint: true}
+	for _, file := range j.Files {
+		if !seen[file.Name] {
+			seen[file.Name] = true
+			names = append(names, file.Name)
+		}
+	}
+	for _, name := range j.archiveFiles {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
 	}
 	return names
 }
 
-// waitForSlot waits for an available job slot
-func (j *Job) waitForSlot() error {
-	queueMutex.Lock()
-	defer queueMutex.Unlock()
-
-	for atomic.LoadInt32(&remainingSlots) <= 0 {
-		j.logger.Info("Waiting for available job slot")
-		queueCondition.Wait()
+// entrypoint returns the file the run stage executes, the first file unless one was named
+func (j *Job) entrypoint() string {
+	if j.Entrypoint != "" {
+		return j.Entrypoint
 	}
+	return j.Files[0].Name
+}
 
-	atomic.AddInt32(&remainingSlots, -1)
-	return nil
+// waitForSlot waits in the job queue for an available job slot
+func (j *Job) waitForSlot(ctx context.Context) error {
+	ctx, span := j.startSpan(ctx, "job.queue", attribute.Int("job.priority", j.Priority))
+	err := j.manager.queue.Acquire(ctx, j.Priority)
+	tracing.End(span, err)
+	return err
 }
 
 // releaseSlot releases a job slot
 func (j *Job) releaseSlot() {
-	atomic.AddInt32(&remainingSlots, 1)
-	queueCondition.Signal()
+	j.manager.queue.Release()
 }
 
 // cleanup cleans up job resources
 func (j *Job) cleanup() {
 	j.logger.Info("Cleaning up job")
+	defer j.removeWorkDir()
+	defer j.closeStdinFile()
+	defer j.releaseMounts()
 
-	for _, box := range j.dirtyBoxes {
-		cmd := exec.Command(IsolatePath, "--cleanup", "--cg", fmt.Sprintf("-b%d", box.ID))
-		if err := cmd.Run(); err != nil {
-			j.logger.WithError(err).Errorf("Failed to cleanup isolate box %d", box.ID)
-		}
-
-		if err := os.Remove(box.MetadataPath); err != nil {
-			j.logger.WithError(err).Errorf("Failed to remove metadata file %s", box.MetadataPath)
-		}
+	if j.warm != nil {
+		j.warm.stop()
 	}
-}
 
-// processJobQueue processes the job queue (placeholder for future use)
-func (m *Manager) processJobQueue() {
-	// This can be extended later for more sophisticated job queuing
-	for range jobQueue {
-		// Process queued jobs
+	for _, box := range j.dirtyBoxes {
+		if err := j.manager.sandbox.Release(box); err != nil {
+			j.logger.WithError(err).Errorf("Failed to cleanup box %d", box.ID)
+		}
 	}
 }
 
@@ -0,0 +1,167 @@
+package job
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// Comparison modes of expected_output
+const (
+	CompareExact   = "exact"
+	CompareTrimmed = "trimmed"
+	CompareToken   = "token"
+	CompareFloat   = "float"
+)
+
+// defaultEpsilon is the tolerance of float comparisons without an epsilon
+const defaultEpsilon = 1e-6
+
+// maxVerdictQuote bounds the output quoted in a verdict message
+const maxVerdictQuote = 64
+
+// ValidateJudge checks the expected output options of a request
+func ValidateJudge(request *types.JobRequest) error {
+	if request.ExpectedOutput == nil {
+		if request.CompareMode != "" || request.Epsilon != nil {
+			return fmt.Errorf("compare_mode and epsilon require expected_output")
+		}
+		return nil
+	}
+
+	switch request.CompareMode {
+	case "", CompareExact, CompareTrimmed, CompareToken, CompareFloat:
+	default:
+		return fmt.Errorf("compare_mode must be %s, %s, %s or %s", CompareExact, CompareTrimmed, CompareToken, CompareFloat)
+	}
+	if request.Epsilon != nil {
+		if request.CompareMode != CompareFloat {
+			return fmt.Errorf("epsilon requires compare_mode %s", CompareFloat)
+		}
+		if e := *request.Epsilon; e < 0 || math.IsNaN(e) || math.IsInf(e, 0) {
+			return fmt.Errorf("epsilon must be a non-negative number")
+		}
+	}
+	return nil
+}
+
+// judge sets the verdict of a result when the job has an expected output. Failed compiles and
+// runs are judged by their outcome; only runs that ended normally are compared.
+func (j *Job) judge(result *types.ExecutionResult) {
+	if j.ExpectedOutput == nil || result.Run == nil {
+		return
+	}
+
+	compileFailed := result.Compile != nil && result.Compile.Outcome != types.OutcomeOK
+	if len(j.Runtime.Stages) > 0 {
+		compileFailed = len(result.Stages) < len(j.Runtime.Stages)
+	}
+	if compileFailed {
+		result.Verdict = types.VerdictCompileError
+		return
+	}
+
+	run := result.Run
+	switch run.Outcome {
+	case types.OutcomeTimeout:
+		result.Verdict = types.VerdictTimeLimit
+	case types.OutcomeMemoryLimit:
+		result.Verdict = types.VerdictMemoryLimit
+	case types.OutcomeOutputLimit:
+		result.Verdict = types.VerdictOutputLimit
+	case types.OutcomeRuntimeError, types.OutcomeDiskLimit:
+		result.Verdict = types.VerdictRuntimeError
+	case types.OutcomeOK:
+		if run.Truncated {
+			result.Verdict = types.VerdictOutputLimit
+			return
+		}
+		result.VerdictMessage = compareOutput(j.CompareMode, *j.ExpectedOutput, run.Stdout, j.Epsilon)
+		result.Verdict = types.VerdictAccepted
+		if result.VerdictMessage != "" {
+			result.Verdict = types.VerdictWrongAnswer
+		}
+	}
+}
+
+// compareOutput compares a run's output with the expected output as mode says, returning where
+// they first differ or "" when they match
+func compareOutput(mode, expected, actual string, epsilon float64) string {
+	switch mode {
+	case CompareTrimmed:
+		return compareItems("line", trimmedLines(expected), trimmedLines(actual), equalStrings)
+	case CompareToken:
+		return compareItems("token", strings.Fields(expected), strings.Fields(actual), equalStrings)
+	case CompareFloat:
+		return compareItems("token", strings.Fields(expected), strings.Fields(actual), func(e, a string) bool {
+			return equalFloats(e, a, epsilon)
+		})
+	default:
+		if expected == actual {
+			return ""
+		}
+		if message := compareItems("line", strings.Split(expected, "\n"), strings.Split(actual, "\n"), equalStrings); message != "" {
+			return message
+		}
+		return "output differs in line endings"
+	}
+}
+
+// compareItems compares the lines or tokens of two outputs, describing the first difference
+func compareItems(kind string, expected, actual []string, equal func(e, a string) bool) string {
+	for i := 0; i < len(expected) && i < len(actual); i++ {
+		if !equal(expected[i], actual[i]) {
+			return fmt.Sprintf("%s %d: expected %s, got %s", kind, i+1, quote(expected[i]), quote(actual[i]))
+		}
+	}
+	if len(expected) != len(actual) {
+		return fmt.Sprintf("expected %d %ss, got %d", len(expected), kind, len(actual))
+	}
+	return ""
+}
+
+// trimmedLines splits output into lines without trailing whitespace, dropping trailing blank lines
+func trimmedLines(output string) []string {
+	lines := strings.Split(strings.TrimRight(output, " \t\r\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
+
+// equalStrings compares lines and tokens exactly
+func equalStrings(e, a string) bool {
+	return e == a
+}
+
+// equalFloats compares tokens as numbers when both are, within epsilon absolute or relative to
+// the expected value, and as strings otherwise
+func equalFloats(expected, actual string, epsilon float64) bool {
+	if expected == actual {
+		return true
+	}
+	e, err := strconv.ParseFloat(expected, 64)
+	if err != nil {
+		return false
+	}
+	a, err := strconv.ParseFloat(actual, 64)
+	if err != nil || math.IsNaN(e) || math.IsNaN(a) || math.IsInf(e, 0) || math.IsInf(a, 0) {
+		return false
+	}
+	diff := math.Abs(e - a)
+	return diff <= epsilon || diff <= epsilon*math.Abs(e)
+}
+
+// quote quotes output for a verdict message, shortened to maxVerdictQuote bytes
+func quote(s string) string {
+	if len(s) > maxVerdictQuote {
+		return strconv.Quote(s[:maxVerdictQuote]) + "..."
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,118 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestCompareOutput(t *testing.T) {
+	tests := []struct {
+		mode, expected, actual string
+		match                  bool
+	}{
+		{CompareExact, "1 2\n", "1 2\n", true},
+		{CompareExact, "1 2\n", "1 2", false},
+		{CompareExact, "1 2\n", "1 2\r\n", false},
+		{CompareTrimmed, "1 2\n3\n", "1 2  \r\n3\n\n\n", true},
+		{CompareTrimmed, "1 2\n3\n", "1  2\n3\n", false},
+		{CompareTrimmed, "", "\n \n", true},
+		{CompareToken, "1 2\n3\n", " 1\n2 3", true},
+		{CompareToken, "1 2 3", "1 2", false},
+		{CompareFloat, "0.3333333 yes", "0.33333334 yes", true},
+		{CompareFloat, "1000000", "1000000.5", true},
+		{CompareFloat, "0.5", "0.51", false},
+		{CompareFloat, "yes", "no", false},
+		{CompareFloat, "NaN", "NaN", true},
+	}
+	for _, tt := range tests {
+		message := compareOutput(tt.mode, tt.expected, tt.actual, defaultEpsilon)
+		if (message == "") != tt.match {
+			t.Errorf("compareOutput(%s, %q, %q) = %q, want match %t", tt.mode, tt.expected, tt.actual, message, tt.match)
+		}
+	}
+
+	if got, want := compareOutput(CompareToken, "1 2 3", "1 5 3", 0), `token 2: expected "2", got "5"`; got != want {
+		t.Errorf("compareOutput() = %q, want %q", got, want)
+	}
+}
+
+func TestJudgeVerdicts(t *testing.T) {
+	expected := "42\n"
+	j := &Job{Runtime: &types.Runtime{}, ExpectedOutput: &expected, CompareMode: CompareExact}
+	failed := &types.StageResult{Outcome: types.OutcomeRuntimeError}
+
+	tests := []struct {
+		name    string
+		result  *types.ExecutionResult
+		verdict types.Verdict
+	}{
+		{"accepted", &types.ExecutionResult{Run: &types.StageResult{Stdout: "42\n", Outcome: types.OutcomeOK}}, types.VerdictAccepted},
+		{"wrong", &types.ExecutionResult{Run: &types.StageResult{Stdout: "41\n", Outcome: types.OutcomeOK}}, types.VerdictWrongAnswer},
+		{"truncated", &types.ExecutionResult{Run: &types.StageResult{Stdout: "42\n", Outcome: types.OutcomeOK, Truncated: true}}, types.VerdictOutputLimit},
+		{"timeout", &types.ExecutionResult{Run: &types.StageResult{Outcome: types.OutcomeTimeout}}, types.VerdictTimeLimit},
+		{"memory", &types.ExecutionResult{Run: &types.StageResult{Outcome: types.OutcomeMemoryLimit}}, types.VerdictMemoryLimit},
+		{"crash", &types.ExecutionResult{Run: &types.StageResult{Outcome: types.OutcomeRuntimeError}}, types.VerdictRuntimeError},
+		{"compile", &types.ExecutionResult{Compile: failed, Run: failed}, types.VerdictCompileError},
+	}
+	for _, tt := range tests {
+		j.judge(tt.result)
+		if tt.result.Verdict != tt.verdict {
+			t.Errorf("%s: verdict = %s, want %s", tt.name, tt.result.Verdict, tt.verdict)
+		}
+	}
+
+	unjudged := &types.ExecutionResult{Run: &types.StageResult{Outcome: types.OutcomeOK}}
+	(&Job{Runtime: &types.Runtime{}}).judge(unjudged)
+	if unjudged.Verdict != "" {
+		t.Errorf("Expected no verdict without expected_output, got %s", unjudged.Verdict)
+	}
+}
+
+func TestValidateJudge(t *testing.T) {
+	expected := "1\n"
+	epsilon := 0.01
+	negative := -1.0
+	valid := []types.JobRequest{
+		{},
+		{ExpectedOutput: &expected},
+		{ExpectedOutput: &expected, CompareMode: CompareFloat, Epsilon: &epsilon},
+	}
+	for _, request := range valid {
+		if err := ValidateJudge(&request); err != nil {
+			t.Errorf("ValidateJudge(%+v) error = %v", request, err)
+		}
+	}
+
+	invalid := []types.JobRequest{
+		{CompareMode: CompareToken},
+		{ExpectedOutput: &expected, CompareMode: "fuzzy"},
+		{ExpectedOutput: &expected, Epsilon: &epsilon},
+		{ExpectedOutput: &expected, CompareMode: CompareFloat, Epsilon: &negative},
+	}
+	for _, request := range invalid {
+		if err := ValidateJudge(&request); err == nil {
+			t.Errorf("ValidateJudge(%+v) succeeded, want an error", request)
+		}
+	}
+}
+
+func TestExecuteJudged(t *testing.T) {
+	m, rt := newLocalTestManager(t, "read a b\necho $((a + b))\n")
+
+	expected := "5\n"
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files:          []types.CodeFile{{Name: "main.sh", Content: "true"}},
+		Stdin:          "2 3\n",
+		ExpectedOutput: &expected,
+		CompareMode:    CompareTrimmed,
+	})
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Verdict != types.VerdictAccepted || result.VerdictMessage != "" {
+		t.Errorf("Expected AC, got %s %q", result.Verdict, result.VerdictMessage)
+	}
+}
@@ -0,0 +1,80 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// maxDiagnostics bounds the diagnostics returned for a lint request
+const maxDiagnostics = 1000
+
+// diagnosticPattern matches the file:line[:column]: [severity:] message lines compilers and
+// most linters print, and lint scripts are expected to
+var diagnosticPattern = regexp.MustCompile(`^(.+?):(\d+)(?::(\d+))?:\s*(?:(?i:(error|warning|info|note|hint))(?:\[[^\]]*\])?:\s*)?(.+)$`)
+
+// Lint runs the runtime's lint script on the job's files and returns the diagnostics it reported.
+// The script gets the file names as arguments and runs with the compile limits.
+func (j *Job) Lint(ctx context.Context) (*types.LintResult, error) {
+	if !j.Runtime.Lint {
+		return nil, fmt.Errorf("%s-%s has no linter", j.Runtime.Language, j.Runtime.Version.String())
+	}
+
+	result := &types.LintResult{Language: j.Runtime.Language, Version: j.Runtime.Version.String()}
+	stage, err := j.runTool(ctx, "lint", func(box *types.IsolateBox, stage *types.StageResult) error {
+		// Tools may name files by their path inside the sandbox rather than relative to it
+		prefixes := []string{"/box/submission/", filepath.Join(box.Dir, "submission") + "/", "./"}
+		result.Diagnostics = parseDiagnostics(stage.Stdout+"\n"+stage.Stderr, prefixes)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Lint = stage
+	return result, nil
+}
+
+// parseDiagnostics returns the diagnostics in a linter's output, with prefixes trimmed from their
+// file names. Lines that are not diagnostics are skipped, and so are those past maxDiagnostics.
+// A diagnostic without a severity is a warning; notes and hints are info.
+func parseDiagnostics(output string, prefixes []string) []types.Diagnostic {
+	diagnostics := []types.Diagnostic{}
+	for _, line := range strings.Split(output, "\n") {
+		if len(diagnostics) == maxDiagnostics {
+			break
+		}
+		match := diagnosticPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+
+		file := match[1]
+		for _, prefix := range prefixes {
+			file = strings.TrimPrefix(file, prefix)
+		}
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+
+		severity := strings.ToLower(match[4])
+		switch severity {
+		case "":
+			severity = "warning"
+		case "note", "hint":
+			severity = "info"
+		}
+
+		diagnostics = append(diagnostics, types.Diagnostic{
+			File:     file,
+			Line:     lineNumber,
+			Column:   column,
+			Severity: severity,
+			Message:  strings.TrimSpace(match[5]),
+		})
+	}
+	return diagnostics
+}
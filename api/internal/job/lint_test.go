@@ -0,0 +1,59 @@
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	output := "./main.py:3:1: E302 expected 2 blank lines\n" +
+		"/box/submission/lib/util.go:12:5: error: undefined: x\n" +
+		"main.c:7: Warning[-Wunused]: unused variable\n" +
+		"main.c:8:2: note: declared here\n" +
+		"Found 3 problems\n"
+
+	got := parseDiagnostics(output, []string{"/box/submission/", "./"})
+	want := []types.Diagnostic{
+		{File: "main.py", Line: 3, Column: 1, Severity: "warning", Message: "E302 expected 2 blank lines"},
+		{File: "lib/util.go", Line: 12, Column: 5, Severity: "error", Message: "undefined: x"},
+		{File: "main.c", Line: 7, Severity: "warning", Message: "unused variable"},
+		{File: "main.c", Line: 8, Column: 2, Severity: "info", Message: "declared here"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDiagnostics() = %+v, want %+v", got, want)
+	}
+
+	if got := parseDiagnostics("all good\n", nil); got == nil || len(got) != 0 {
+		t.Errorf("parseDiagnostics() = %#v, want an empty list", got)
+	}
+}
+
+func TestLint(t *testing.T) {
+	m, rt := newLocalTestManager(t, "true\n")
+	script := "for file; do grep -n TODO \"$file\" | sed \"s|^\\([0-9]*\\):.*|$PWD/$file:\\1: info: TODO left|\" >&2; done\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(rt.PkgDir, "lint"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rt.Lint = true
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{Files: []types.CodeFile{
+		{Name: "main.sh", Content: "echo hi\n# TODO: more\n"},
+	}})
+	result, err := j.Lint(context.Background())
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+
+	want := []types.Diagnostic{{File: "main.sh", Line: 2, Severity: "info", Message: "TODO left"}}
+	if !reflect.DeepEqual(result.Diagnostics, want) {
+		t.Errorf("Diagnostics = %+v, want %+v", result.Diagnostics, want)
+	}
+	if result.Lint.Code == nil || *result.Lint.Code != 1 {
+		t.Errorf("Lint = %+v, want the linter's exit code", result.Lint)
+	}
+}
@@ -0,0 +1,255 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// localWaitDelay bounds how long Wait waits for output held open by a stage's orphaned children
+const localWaitDelay = time.Second
+
+// localSandbox runs stages as plain subprocesses of the server, for development machines
+// without isolate. It provides no isolation at all: programs run as the server's user with
+// its network and file system access. Only the wall-clock timeout and the output limits are
+// enforced; CPU time, memory, process, file size and disk limits are not.
+type localSandbox struct {
+	root   string
+	logger *logrus.Entry
+
+	nextID   atomic.Int64
+	acquired atomic.Int64
+	reaped   atomic.Int64
+
+	// runs holds the state of each box's last command, and marks the box as in use
+	runs  map[string]*localRun
+	mutex sync.Mutex
+}
+
+// localRun is a stage command started in a local box
+type localRun struct {
+	cmd      *exec.Cmd
+	created  time.Time
+	cancel   context.CancelFunc
+	deadline context.Context
+	parent   context.Context
+}
+
+// newLocalSandbox creates the unsafe local backend, keeping boxes under the data directory
+func newLocalSandbox(cfg *config.Config) *localSandbox {
+	return &localSandbox{
+		root:   filepath.Join(cfg.DataDirectory, "local-boxes"),
+		logger: logrus.WithField("component", "local_sandbox"),
+		runs:   make(map[string]*localRun),
+	}
+}
+
+func (s *localSandbox) Name() string {
+	return "unsafe_local"
+}
+
+// Acquire creates an empty box directory; quotas are not enforced
+func (s *localSandbox) Acquire(quota int64) (*types.IsolateBox, error) {
+	if err := os.MkdirAll(s.root, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create box root: %w", err)
+	}
+
+	id := int(s.nextID.Add(1) % MaxBoxID)
+	dir, err := os.MkdirTemp(s.root, fmt.Sprintf("box-%d-", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create box: %w", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "tmp"), 0700); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to create box: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.runs[dir] = nil
+	s.mutex.Unlock()
+	s.acquired.Add(1)
+
+	return &types.IsolateBox{ID: id, Dir: dir}, nil
+}
+
+// Command runs the stage script with bash from the box's submission directory. The process
+// is killed, with its process group where the platform has one, once timeout passes.
+func (s *localSandbox) Command(ctx context.Context, j *Job, box *types.IsolateBox, stage string, args []string,
+	timeout, cpuTime time.Duration, memoryLimit int64) *exec.Cmd {
+	run := &localRun{created: time.Now(), parent: ctx}
+	run.deadline, run.cancel = ctx, func() {}
+	if timeout > 0 {
+		run.deadline, run.cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	cmdArgs := append([]string{filepath.Join(j.Runtime.PkgDir, stage)}, args...)
+	cmd := exec.CommandContext(run.deadline, "bash", cmdArgs...)
+	cmd.Dir = filepath.Join(box.Dir, "submission")
+	cmd.Env = j.localEnv(box, stage, memoryLimit)
+	cmd.WaitDelay = localWaitDelay
+	killProcessGroup(cmd)
+	run.cmd = cmd
+
+	s.mutex.Lock()
+	if previous := s.runs[box.Dir]; previous != nil {
+		previous.cancel()
+	}
+	s.runs[box.Dir] = run
+	s.mutex.Unlock()
+	return cmd
+}
+
+// localEnv is the environment of a local stage: the server's own, so host toolchains are found,
+// followed by what isolate would set
+func (j *Job) localEnv(box *types.IsolateBox, stage string, memoryLimit int64) []string {
+	home := filepath.Join(box.Dir, "tmp")
+	env := append(os.Environ(), "HOME="+home, "TMPDIR="+home)
+	env = append(env, j.Runtime.EnvVars...)
+	for _, name := range sortedKeys(j.Env) {
+		env = append(env, fmt.Sprintf("%s=%s", name, j.Env[name]))
+	}
+	env = append(env, fmt.Sprintf("CODERUNR_LANGUAGE=%s", j.Runtime.Language))
+	env = append(env, j.flagEnv(stage)...)
+	env = append(env, memoryEnv(memoryLimit)...)
+	if j.Network {
+		env = append(env, proxyEnv(j.manager.config.GetNetworkProxy())...)
+	}
+	return env
+}
+
+// Usage reports the exit status and times of the box's last command, in isolate's terms
+func (s *localSandbox) Usage(box *types.IsolateBox) (*StageUsage, error) {
+	s.mutex.Lock()
+	run := s.runs[box.Dir]
+	s.mutex.Unlock()
+	if run == nil || run.cmd.ProcessState == nil {
+		return nil, errors.New("no finished command in box")
+	}
+	defer run.cancel()
+
+	state := run.cmd.ProcessState
+	usage := &StageUsage{
+		ExitCode: state.ExitCode(),
+		CPUTime:  state.UserTime() + state.SystemTime(),
+		WallTime: time.Since(run.created),
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		usage.Signal = signalToString(int(status.Signal()))
+	}
+
+	switch {
+	case errors.Is(run.deadline.Err(), context.DeadlineExceeded) && run.parent.Err() == nil:
+		usage.Status = "TO"
+		usage.Message = "Time limit exceeded (wall clock)"
+	case usage.Signal != "":
+		usage.Status = "SG"
+		usage.Message = "Caught fatal signal " + strings.TrimPrefix(usage.Signal, "SIG")
+	case usage.ExitCode != 0:
+		usage.Status = "RE"
+		usage.Message = fmt.Sprintf("Exited with error status %d", usage.ExitCode)
+	}
+	return usage, nil
+}
+
+// Sample is not supported; live stats are skipped
+func (s *localSandbox) Sample(box *types.IsolateBox) (int64, time.Duration, error) {
+	return 0, 0, errors.New("live stats are not available with the unsafe_local backend")
+}
+
+// Signal signals the stage's process group where the platform has one, otherwise its process
+func (s *localSandbox) Signal(box *types.IsolateBox, sig syscall.Signal) error {
+	s.mutex.Lock()
+	run := s.runs[box.Dir]
+	s.mutex.Unlock()
+	if run == nil || run.cmd.Process == nil {
+		return errors.New("no running process")
+	}
+	return signalGroup(run.cmd, sig)
+}
+
+// KillRemaining kills what is left of the stage's process group where the platform has one
+func (s *localSandbox) KillRemaining(box *types.IsolateBox) error {
+	s.mutex.Lock()
+	run := s.runs[box.Dir]
+	s.mutex.Unlock()
+	if run == nil || run.cmd.Process == nil {
+		return nil
+	}
+	// The stage's own process has been waited for, so only its group is signalled
+	err := signalGroup(run.cmd, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) || errors.Is(err, os.ErrProcessDone) {
+		return nil
+	}
+	return err
+}
+
+// Release deletes the box directory
+func (s *localSandbox) Release(box *types.IsolateBox) error {
+	s.mutex.Lock()
+	if run := s.runs[box.Dir]; run != nil {
+		run.cancel()
+	}
+	delete(s.runs, box.Dir)
+	s.mutex.Unlock()
+
+	return os.RemoveAll(box.Dir)
+}
+
+// Reap deletes box directories no job holds, such as those of a previous server instance
+func (s *localSandbox) Reap(grace time.Duration) int {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return 0
+	}
+
+	cutoff := time.Now().Add(-grace)
+	reaped := 0
+	for _, entry := range entries {
+		dir := filepath.Join(s.root, entry.Name())
+		info, err := entry.Info()
+		if err != nil || !strings.HasPrefix(entry.Name(), "box-") || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		s.mutex.Lock()
+		_, active := s.runs[dir]
+		s.mutex.Unlock()
+		if active {
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			s.logger.WithError(err).Warnf("Failed to reap orphaned box %s", entry.Name())
+			continue
+		}
+		reaped++
+	}
+
+	if reaped > 0 {
+		s.reaped.Add(int64(reaped))
+		s.logger.Infof("Reaped %d orphaned local boxes", reaped)
+	}
+	return reaped
+}
+
+// Stats reports every box as a pool miss, since boxes are created on demand
+func (s *localSandbox) Stats() BoxPoolStats {
+	return BoxPoolStats{
+		Misses: s.acquired.Load(),
+		Reaped: s.reaped.Load(),
+	}
+}
+
+func (s *localSandbox) Close() {}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package job
+
+import "os/exec"
+
+// killProcessGroup leaves cmd as is; cancellation kills only the stage's own process
+func killProcessGroup(cmd *exec.Cmd) {}
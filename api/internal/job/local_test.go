@@ -0,0 +1,121 @@
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/types"
+)
+
+// newLocalTestManager returns a manager on the unsafe_local backend with a "shell" runtime
+// whose run script is script
+func newLocalTestManager(t *testing.T, script string) (*Manager, *types.Runtime) {
+	t.Helper()
+	pkgDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(pkgDir, "run"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		DataDirectory:     t.TempDir(),
+		SandboxBackend:    "unsafe_local",
+		MaxConcurrentJobs: 2,
+	}
+	m := NewManager(cfg, runtime.NewManager(cfg))
+	t.Cleanup(func() { m.sandbox.Close() })
+
+	rt := &types.Runtime{
+		Language:      "shell",
+		Version:       semver.MustParse("1.0.0"),
+		PkgDir:        pkgDir,
+		Timeouts:      types.Timeouts{Compile: time.Second, Run: time.Second},
+		MemoryLimits:  types.MemoryLimits{Compile: -1, Run: -1},
+		OutputMaxSize: 1024,
+	}
+	return m, rt
+}
+
+func TestLocalSandboxExecute(t *testing.T) {
+	m, rt := newLocalTestManager(t, "read line\necho \"$line from $1\"\necho oops >&2\nexit 3\n")
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+		Stdin: "hello\n",
+	})
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	run := result.Run
+	if run.Stdout != "hello from main.sh\n" || run.Stderr != "oops\n" {
+		t.Errorf("Expected the program's output, got stdout %q stderr %q", run.Stdout, run.Stderr)
+	}
+	if run.Code == nil || *run.Code != 3 || run.Status != "RE" || run.Outcome != types.OutcomeRuntimeError {
+		t.Errorf("Expected exit code 3 as a runtime error, got %+v", run)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(m.config.DataDirectory, "local-boxes"))
+	if len(entries) != 0 {
+		t.Errorf("Expected the box to be removed after the job, found %d", len(entries))
+	}
+}
+
+func TestLocalSandboxTimeout(t *testing.T) {
+	m, rt := newLocalTestManager(t, "echo started\nsleep 10\n")
+	runTimeout := 200
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files:      []types.CodeFile{{Name: "main.sh", Content: "true"}},
+		RunTimeout: &runTimeout,
+	})
+	started := time.Now()
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected the stage to be killed at its timeout, took %s", elapsed)
+	}
+	run := result.Run
+	if run.Status != "TO" || run.Outcome != types.OutcomeTimeout || run.Signal != "SIGKILL" {
+		t.Errorf("Expected a timeout, got %+v", run)
+	}
+	if !strings.HasPrefix(run.Stdout, "started") {
+		t.Errorf("Expected output before the timeout to be kept, got %q", run.Stdout)
+	}
+}
+
+func TestExecuteStreamStdinEOF(t *testing.T) {
+	m, rt := newLocalTestManager(t, "cat\n")
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+	})
+	if err := j.WriteStdin("line\n"); err != nil {
+		t.Fatal(err)
+	}
+	j.CloseStdin()
+	j.CloseStdin()
+	if err := j.WriteStdin("late\n"); err == nil {
+		t.Error("Expected writing after EOF to fail")
+	}
+
+	go j.ExecuteStream(context.Background())
+	var result *types.ExecutionResult
+	for event := range j.EventChannel {
+		if event.Type == "result" {
+			result = event.Result
+		}
+	}
+	if result == nil || result.Run.Outcome != types.OutcomeOK || result.Run.Stdout != "line\n" {
+		t.Errorf("Expected cat to echo its input and exit at EOF, got %+v", result)
+	}
+}
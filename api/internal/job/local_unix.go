@@ -0,0 +1,17 @@
+//go:build unix
+
+package job
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup starts cmd in its own process group and makes cancellation kill the whole
+// group, so programs the stage started do not outlive it
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return killGroup(cmd)
+	}
+}
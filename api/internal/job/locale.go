@@ -0,0 +1,45 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// localePattern matches POSIX locale names such as C, C.UTF-8, en_US.UTF-8 or sr_RS@latin
+var localePattern = regexp.MustCompile(`^(C|POSIX|[a-z]{2,3}(_[A-Z]{2})?)(\.[A-Za-z0-9-]+)?(@[A-Za-z0-9]+)?$`)
+
+// timezonePattern matches tz database names such as UTC, Etc/GMT+3 or America/Argentina/Buenos_Aires
+var timezonePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+-]*(/[A-Za-z][A-Za-z0-9_+-]*)*$`)
+
+// ValidateLocale checks the request's locale and timezone names. Whether the sandbox has the
+// locale or the zone installed is not checked; programs fall back to C and UTC without them.
+func ValidateLocale(locale, timezone string) error {
+	if locale != "" && (len(locale) > 64 || !localePattern.MatchString(locale)) {
+		return fmt.Errorf("locale %q is not a locale name such as en_US.UTF-8", locale)
+	}
+	if timezone != "" && (len(timezone) > 64 || !timezonePattern.MatchString(timezone)) {
+		return fmt.Errorf("timezone %q is not a tz database name such as Europe/Berlin", timezone)
+	}
+	return nil
+}
+
+// withLocale returns env with the variables selecting locale and timezone added, overriding
+// any the request set itself. env is copied rather than modified.
+func withLocale(env map[string]string, locale, timezone string) map[string]string {
+	if locale == "" && timezone == "" {
+		return env
+	}
+
+	merged := make(map[string]string, len(env)+3)
+	for name, value := range env {
+		merged[name] = value
+	}
+	if locale != "" {
+		merged["LANG"] = locale
+		merged["LC_ALL"] = locale
+	}
+	if timezone != "" {
+		merged["TZ"] = timezone
+	}
+	return merged
+}
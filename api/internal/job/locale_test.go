@@ -0,0 +1,50 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		timezone string
+		wantErr  bool
+	}{
+		{"Empty", "", "", false},
+		{"C", "C.UTF-8", "UTC", false},
+		{"Region", "de_DE.UTF-8", "Europe/Berlin", false},
+		{"Modifier", "sr_RS@latin", "America/Argentina/Buenos_Aires", false},
+		{"Offset Zone", "", "Etc/GMT+3", false},
+		{"Bad Locale", "en US", "", true},
+		{"Shell Locale", "$(id)", "", true},
+		{"Traversal Zone", "", "../../etc/passwd", true},
+		{"Absolute Zone", "", "/usr/share/zoneinfo/UTC", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateLocale(tt.locale, tt.timezone); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLocale() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithLocale(t *testing.T) {
+	env := map[string]string{"DEBUG": "1", "TZ": "UTC"}
+
+	got := withLocale(env, "fr_FR.UTF-8", "Europe/Paris")
+	want := map[string]string{"DEBUG": "1", "LANG": "fr_FR.UTF-8", "LC_ALL": "fr_FR.UTF-8", "TZ": "Europe/Paris"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if env["TZ"] != "UTC" {
+		t.Error("Expected the request environment to be left unchanged")
+	}
+
+	if got := withLocale(nil, "", ""); got != nil {
+		t.Errorf("Expected no environment without a locale or timezone, got %v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+const (
+	// maxLSPMessage bounds a message from a language server; a larger one ends the session
+	maxLSPMessage = 16 << 20
+	// maxLSPHeader bounds the header block before a message's content
+	maxLSPHeader = 1024
+	// lspStdinBuffer is the number of client messages queued for a language server, enough
+	// for the burst of didOpen notifications an editor sends when it attaches
+	lspStdinBuffer = 256
+)
+
+// lspHeaderEnd separates the headers of an LSP message from its content
+var lspHeaderEnd = []byte("\r\n\r\n")
+
+// NewLanguageServer creates a job running the runtime's lsp script, a language server speaking
+// LSP over stdio. Files are optional and written to the workspace the server indexes. Output is
+// not budgeted, since the server runs until the client leaves or it goes idle.
+func (m *Manager) NewLanguageServer(ctx context.Context, runtime *types.Runtime, request *types.JobRequest) (*Job, error) {
+	if !runtime.LSP {
+		return nil, fmt.Errorf("%s-%s does not provide a language server",
+			runtime.Language, runtime.Version.String())
+	}
+	if request.PTY != nil {
+		return nil, fmt.Errorf("pty is not supported for language servers")
+	}
+
+	j := m.NewJob(ctx, runtime, request)
+	j.outputBudget = 0
+	j.lsp = &lspDecoder{}
+	j.StdinChannel = make(chan string, lspStdinBuffer)
+	j.touch()
+	return j, nil
+}
+
+// ExecuteLanguageServer runs the runtime's lsp script, sending each message it writes to stdout
+// as an "lsp" event, until the process exits, the context is cancelled or no activity is seen
+// for the idle timeout
+func (j *Job) ExecuteLanguageServer(ctx context.Context) error {
+	return j.executeInteractive(ctx, "lsp")
+}
+
+// WriteLSP sends a JSON-RPC message from the client to the language server, framed with the
+// Content-Length header LSP uses on stdio
+func (j *Job) WriteLSP(message []byte) error {
+	if j.lsp == nil {
+		return fmt.Errorf("job is not a language server")
+	}
+	return j.WriteStdin(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(message), message))
+}
+
+// sendLSP decodes the language server's stdout into messages and sends each as an "lsp" event.
+// A server that breaks the framing is killed, as nothing after it could be decoded.
+func (j *Job) sendLSP(stage, data string) bool {
+	messages, err := j.lsp.feed(data)
+	for _, message := range messages {
+		j.sendEvent(types.StreamEvent{Type: "lsp", Stage: stage, Data: message})
+	}
+	if err != nil {
+		j.logger.WithError(err).Warn("Language server sent an invalid message")
+		j.killOnce.Do(func() {
+			j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("language server sent an invalid message: %w", err)})
+			j.killRunning()
+		})
+		return false
+	}
+	return true
+}
+
+// lspDecoder splits a language server's output into the contents of its messages
+type lspDecoder struct {
+	buf []byte
+}
+
+// feed appends data and returns the messages it completed. Headers other than Content-Length
+// are ignored.
+func (d *lspDecoder) feed(data string) ([]string, error) {
+	d.buf = append(d.buf, data...)
+
+	var messages []string
+	for {
+		end := bytes.Index(d.buf, lspHeaderEnd)
+		if end < 0 {
+			if len(d.buf) > maxLSPHeader {
+				return messages, fmt.Errorf("no message header in the first %d bytes", maxLSPHeader)
+			}
+			return messages, nil
+		}
+
+		length, err := lspContentLength(string(d.buf[:end]))
+		if err != nil {
+			return messages, err
+		}
+		start := end + len(lspHeaderEnd)
+		if len(d.buf)-start < length {
+			return messages, nil
+		}
+		messages = append(messages, string(d.buf[start:start+length]))
+		d.buf = d.buf[start+length:]
+	}
+}
+
+// lspContentLength returns the Content-Length of an LSP header block
+func lspContentLength(header string) (int, error) {
+	for _, line := range strings.Split(header, "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			continue
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || length < 0 {
+			return 0, fmt.Errorf("invalid Content-Length %q", strings.TrimSpace(value))
+		}
+		if length > maxLSPMessage {
+			return 0, fmt.Errorf("message of %d bytes exceeds %d", length, maxLSPMessage)
+		}
+		return length, nil
+	}
+	return 0, fmt.Errorf("message header has no Content-Length")
+}
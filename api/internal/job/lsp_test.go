@@ -0,0 +1,97 @@
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// echoServer is an lsp script answering each message with the message itself
+const echoServer = `while IFS= read -r header; do
+  length=${header#Content-Length: }
+  length=${length%$'\r'}
+  IFS= read -r blank
+  body=$(head -c "$length")
+  printf 'Content-Length: %d\r\n\r\n%s' "${#body}" "$body"
+done
+`
+
+func TestLanguageServer(t *testing.T) {
+	m, rt := newLocalTestManager(t, "true\n")
+	if err := os.WriteFile(filepath.Join(rt.PkgDir, "lsp"), []byte(echoServer), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rt.LSP = true
+	m.config.SessionTimeout = 10 * time.Second
+	m.config.SessionIdleTimeout = 10 * time.Second
+
+	j, err := m.NewLanguageServer(context.Background(), rt, &types.JobRequest{})
+	if err != nil {
+		t.Fatalf("NewLanguageServer() error = %v", err)
+	}
+	go func() {
+		if err := j.ExecuteLanguageServer(context.Background()); err != nil {
+			t.Errorf("ExecuteLanguageServer() error = %v", err)
+		}
+	}()
+
+	message := `{"jsonrpc":"2.0","id":1,"method":"initialize"}`
+	if err := j.WriteLSP([]byte(message)); err != nil {
+		t.Fatalf("WriteLSP() error = %v", err)
+	}
+
+	var received []string
+	for event := range j.EventChannel {
+		switch event.Type {
+		case "lsp":
+			received = append(received, event.Data)
+			j.CloseStdin()
+		case "error":
+			t.Errorf("Unexpected error event: %v", event.Error)
+		}
+	}
+	if len(received) != 1 || received[0] != message {
+		t.Errorf("Received %q, want the echoed message", received)
+	}
+}
+
+func TestNewLanguageServerRequiresPackageScript(t *testing.T) {
+	m, rt := newLocalTestManager(t, "true\n")
+	if _, err := m.NewLanguageServer(context.Background(), rt, &types.JobRequest{}); err == nil {
+		t.Error("Expected an error for a runtime without a language server")
+	}
+
+	rt.LSP = true
+	if _, err := m.NewLanguageServer(context.Background(), rt, &types.JobRequest{PTY: &types.PTYSize{Rows: 24, Cols: 80}}); err == nil {
+		t.Error("Expected an error for a language server on a pty")
+	}
+}
+
+func TestLSPDecoder(t *testing.T) {
+	d := &lspDecoder{}
+
+	messages, err := d.feed("Content-Length: 7\r\nContent-Type: application/vscode-jsonrpc\r\n\r\n{\"a\":")
+	if err != nil || len(messages) != 0 {
+		t.Fatalf("feed() = %q, %v; want nothing until the content is complete", messages, err)
+	}
+	messages, err = d.feed("1}Content-Length: 2\r\n\r\n{}")
+	if err != nil || !reflect.DeepEqual(messages, []string{`{"a":1}`, `{}`}) {
+		t.Errorf("feed() = %q, %v; want both messages", messages, err)
+	}
+
+	for _, output := range []string{
+		"Content-Type: text/plain\r\n\r\n{}",
+		"Content-Length: many\r\n\r\n{}",
+		"Content-Length: 99999999999\r\n\r\n",
+		string(make([]byte, maxLSPHeader+1)),
+	} {
+		if _, err := (&lspDecoder{}).feed(output); err == nil {
+			t.Errorf("feed(%.40q) succeeded, want an error", output)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package job
+
+import "fmt"
+
+// sandboxMemory returns the memory the sandbox grants a stage limited to memoryLimit bytes: the
+// limit plus the runtime's memory_overhead, so a JVM's metaspace, JIT and thread stacks do not
+// count against the heap the submission was given. -1 stays unlimited.
+func (j *Job) sandboxMemory(memoryLimit int64) int64 {
+	if memoryLimit < 0 {
+		return memoryLimit
+	}
+	return memoryLimit + j.Runtime.MemoryOverhead
+}
+
+// memoryEnv returns the variables telling a stage script its memory limit, in bytes and as the
+// JVM maximum heap flag the run scripts of JVM languages pass to java
+func memoryEnv(memoryLimit int64) []string {
+	if memoryLimit <= 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("CODERUNR_MEMORY_LIMIT=%d", memoryLimit),
+		fmt.Sprintf("CODERUNR_JVM_HEAP=-Xmx%dk", max(memoryLimit/1024, 1)),
+	}
+}
@@ -0,0 +1,47 @@
+package job
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestIsolateMemoryOverhead(t *testing.T) {
+	m, rt := newLocalTestManager(t, "true\n")
+	rt.MemoryOverhead = 64_000_000
+	j := &Job{Runtime: rt, manager: m}
+
+	args := j.buildIsolateArgs(&types.IsolateBox{ID: 1}, "run", nil, time.Second, time.Second, 256*1024*1024)
+	for _, want := range []string{"--cg-mem=332435", "CODERUNR_MEMORY_LIMIT=268435456", "CODERUNR_JVM_HEAP=-Xmx262144k"} {
+		if !contains(args, want) {
+			t.Errorf("Expected %s in the isolate arguments, got %v", want, args)
+		}
+	}
+
+	args = j.buildIsolateArgs(&types.IsolateBox{ID: 1}, "run", nil, time.Second, time.Second, -1)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--cg-mem") || strings.HasPrefix(arg, "CODERUNR_MEMORY_LIMIT") {
+			t.Errorf("Expected no memory limit for an unlimited stage, got %s", arg)
+		}
+	}
+}
+
+func TestLocalMemoryEnv(t *testing.T) {
+	m, rt := newLocalTestManager(t, "echo \"$CODERUNR_JVM_HEAP\"\n")
+	memoryLimit := int64(128 * 1024 * 1024)
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files:          []types.CodeFile{{Name: "Main.java", Content: "class Main {}"}},
+		RunMemoryLimit: &memoryLimit,
+	})
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Run.Stdout != "-Xmx131072k\n" {
+		t.Errorf("Run stdout = %q, want the heap flag derived from the run memory limit", result.Run.Stdout)
+	}
+}
@@ -0,0 +1,129 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/coderunr/api/internal/history"
+)
+
+// Bounds of a request's metadata
+const (
+	maxMetadataKeys       = 16
+	maxMetadataValueBytes = 256
+)
+
+// metadataKeyPattern matches metadata keys
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// metadataOther counts the values of a key beyond metrics_metadata_max_values
+const metadataOther = "_other"
+
+// ValidateMetadata checks the size and keys of a request's metadata
+func ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataKeys {
+		return fmt.Errorf("metadata must not have more than %d keys", maxMetadataKeys)
+	}
+	for _, key := range sortedKeys(metadata) {
+		if !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("metadata key %q may only contain letters, digits and - _ . (at most 64)", key)
+		}
+		if len(metadata[key]) > maxMetadataValueBytes {
+			return fmt.Errorf("metadata.%s must not be longer than %d bytes", key, maxMetadataValueBytes)
+		}
+	}
+	return nil
+}
+
+// MetadataUsage counts the executions of one value of a metadata key
+type MetadataUsage struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	Executions uint64 `json:"executions"`
+	// Failed counts executions that did not succeed: compile or runtime errors and server errors
+	Failed uint64 `json:"failed"`
+}
+
+// MetadataCounters counts executions by the values of selected metadata keys. Each key tracks
+// at most maxValues distinct values; later ones are counted together as "_other".
+type MetadataCounters struct {
+	maxValues int
+	counts    map[string]map[string]*MetadataUsage
+	mutex     sync.Mutex
+}
+
+// NewMetadataCounters returns counters for keys, or nil when there are none
+func NewMetadataCounters(keys []string, maxValues int) *MetadataCounters {
+	if len(keys) == 0 {
+		return nil
+	}
+	c := &MetadataCounters{
+		maxValues: maxValues,
+		counts:    make(map[string]map[string]*MetadataUsage, len(keys)),
+	}
+	for _, key := range keys {
+		c.counts[key] = make(map[string]*MetadataUsage)
+	}
+	return c
+}
+
+// Count records an execution with metadata that ended with the history status
+func (c *MetadataCounters) Count(metadata map[string]string, status string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, value := range metadata {
+		values, ok := c.counts[key]
+		if !ok {
+			continue
+		}
+		usage, ok := values[value]
+		if !ok {
+			if len(values) >= c.maxValues {
+				value = metadataOther
+			}
+			if usage, ok = values[value]; !ok {
+				usage = &MetadataUsage{Key: key, Value: value}
+				values[value] = usage
+			}
+		}
+		usage.Executions++
+		if status != history.StatusSuccess {
+			usage.Failed++
+		}
+	}
+}
+
+// MetadataUsage returns the execution counts by metadata value, nil unless
+// metrics_metadata_keys is set
+func (m *Manager) MetadataUsage() []MetadataUsage {
+	return m.metadata.Usage()
+}
+
+// Usage returns the counters sorted by key and value
+func (c *MetadataCounters) Usage() []MetadataUsage {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	usage := []MetadataUsage{}
+	for _, values := range c.counts {
+		for _, u := range values {
+			usage = append(usage, *u)
+		}
+	}
+	sort.Slice(usage, func(i, k int) bool {
+		if usage[i].Key != usage[k].Key {
+			return usage[i].Key < usage[k].Key
+		}
+		return usage[i].Value < usage[k].Value
+	})
+	return usage
+}
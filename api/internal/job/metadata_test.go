@@ -0,0 +1,59 @@
+package job
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coderunr/api/internal/history"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= maxMetadataKeys; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		wantErr  bool
+	}{
+		{"Empty", nil, false},
+		{"Valid", map[string]string{"assignment": "hw-3", "student.id": "42"}, false},
+		{"Bad Key", map[string]string{"assign ment": "x"}, true},
+		{"Long Value", map[string]string{"notes": strings.Repeat("x", maxMetadataValueBytes+1)}, true},
+		{"Too Many Keys", tooMany, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateMetadata(tt.metadata); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMetadataCounters(t *testing.T) {
+	if c := NewMetadataCounters(nil, 10); c != nil || c.Usage() != nil {
+		t.Fatal("Expected no counters without keys")
+	}
+
+	c := NewMetadataCounters([]string{"assignment"}, 2)
+	c.Count(map[string]string{"assignment": "hw1", "student": "alice"}, history.StatusSuccess)
+	c.Count(map[string]string{"assignment": "hw1"}, history.StatusRuntimeError)
+	c.Count(map[string]string{"assignment": "hw2"}, history.StatusSuccess)
+	c.Count(map[string]string{"assignment": "hw3"}, history.StatusSuccess)
+	c.Count(map[string]string{"assignment": "hw4"}, history.StatusError)
+	c.Count(nil, history.StatusSuccess)
+
+	want := []MetadataUsage{
+		{Key: "assignment", Value: "_other", Executions: 2, Failed: 1},
+		{Key: "assignment", Value: "hw1", Executions: 2, Failed: 1},
+		{Key: "assignment", Value: "hw2", Executions: 1},
+	}
+	if got := c.Usage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,134 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// Values of a mount's type
+const (
+	MountScratch = "scratch"
+	MountDataset = "dataset"
+)
+
+// maxMounts caps the number of mounts per request
+const maxMounts = 8
+
+// reservedMountPaths are sandbox directories isolate and the runtimes already provide
+var reservedMountPaths = []string{"/box", "/bin", "/dev", "/etc", "/lib", "/lib64", "/proc", "/sys", "/tmp", "/usr", "/var"}
+
+// ValidateMounts checks that requested mounts use free sandbox paths, that scratch sizes are
+// within scratch_max_size and that datasets exist under dataset_directory
+func (m *Manager) ValidateMounts(mounts []types.Mount) error {
+	if len(mounts) > maxMounts {
+		return fmt.Errorf("mounts cannot contain more than %d entries", maxMounts)
+	}
+
+	reserved := append([]string{m.config.DataDirectory}, reservedMountPaths...)
+	for i, mount := range mounts {
+		p := mount.Path
+		if !path.IsAbs(p) || path.Clean(p) != p || p == "/" {
+			return fmt.Errorf("mounts[%d].path must be a clean absolute path such as /scratch", i)
+		}
+		for _, r := range reserved {
+			if pathOverlaps(p, r) {
+				return fmt.Errorf("mounts[%d].path %s overlaps %s", i, p, r)
+			}
+		}
+		for _, other := range mounts[:i] {
+			if pathOverlaps(p, other.Path) {
+				return fmt.Errorf("mounts[%d].path %s overlaps another mount", i, p)
+			}
+		}
+
+		switch mount.Type {
+		case MountScratch:
+			if m.config.ScratchMaxSize <= 0 {
+				return fmt.Errorf("scratch mounts are disabled")
+			}
+			if mount.Size < 0 || mount.Size > m.config.ScratchMaxSize {
+				return fmt.Errorf("mounts[%d].size must be between 0 and %d", i, m.config.ScratchMaxSize)
+			}
+		case MountDataset:
+			if m.config.DatasetDirectory == "" {
+				return fmt.Errorf("dataset mounts are disabled")
+			}
+			name := mount.Dataset
+			if name == "" || name == "." || name == ".." || path.IsAbs(name) || path.Clean(name) != name || strings.HasPrefix(name, "../") {
+				return fmt.Errorf("mounts[%d].dataset must name a dataset", i)
+			}
+			if info, err := os.Stat(filepath.Join(m.config.DatasetDirectory, filepath.FromSlash(name))); err != nil || !info.IsDir() {
+				return fmt.Errorf("dataset %s not found", name)
+			}
+		default:
+			return fmt.Errorf("mounts[%d].type must be %s or %s", i, MountScratch, MountDataset)
+		}
+	}
+	return nil
+}
+
+// pathOverlaps reports whether a and b are the same directory or one contains the other
+func pathOverlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// scratchRoot is where the job's scratch mounts live on the host
+func (j *Job) scratchRoot() string {
+	return filepath.Join(j.workDir(), "scratch")
+}
+
+// prepareMounts mounts a sized tmpfs for each scratch mount
+func (j *Job) prepareMounts() error {
+	for i, mount := range j.Mounts {
+		if mount.Type != MountScratch {
+			continue
+		}
+		dir := filepath.Join(j.scratchRoot(), strconv.Itoa(i))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create scratch directory: %w", err)
+		}
+		size := mount.Size
+		if size == 0 {
+			size = j.manager.config.ScratchMaxSize
+		}
+		if err := mountScratch(dir, size); err != nil {
+			return fmt.Errorf("failed to mount scratch directory %s: %w", mount.Path, err)
+		}
+	}
+	return nil
+}
+
+// mountArgs returns the isolate --dir rules for the job's mounts
+func (j *Job) mountArgs() []string {
+	var args []string
+	for i, mount := range j.Mounts {
+		switch mount.Type {
+		case MountScratch:
+			args = append(args, fmt.Sprintf("--dir=%s=%s:rw", mount.Path, filepath.Join(j.scratchRoot(), strconv.Itoa(i))))
+		case MountDataset:
+			// isolate binds directories read-only unless told otherwise
+			dir := filepath.Join(j.manager.config.DatasetDirectory, filepath.FromSlash(mount.Dataset))
+			args = append(args, fmt.Sprintf("--dir=%s=%s", mount.Path, dir))
+		}
+	}
+	return args
+}
+
+// releaseMounts unmounts the job's scratch directories; they are removed with the job's
+// working directory
+func (j *Job) releaseMounts() {
+	for i, mount := range j.Mounts {
+		if mount.Type != MountScratch {
+			continue
+		}
+		if err := unmountScratch(filepath.Join(j.scratchRoot(), strconv.Itoa(i))); err != nil {
+			j.logger.WithError(err).Errorf("Failed to unmount scratch directory %s", mount.Path)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// mountScratch mounts a tmpfs of size bytes on dir, writable by the sandbox user
+func mountScratch(dir string, size int64) error {
+	return unix.Mount("tmpfs", dir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, fmt.Sprintf("size=%d,mode=0777", size))
+}
+
+// unmountScratch unmounts a scratch tmpfs; a directory that is not mounted is left alone
+func unmountScratch(dir string) error {
+	err := unix.Unmount(dir, unix.MNT_DETACH)
+	if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	return err
+}
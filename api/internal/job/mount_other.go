@@ -0,0 +1,15 @@
+//go:build !linux
+
+package job
+
+import "errors"
+
+// mountScratch is only supported on Linux, where isolate runs
+func mountScratch(dir string, size int64) error {
+	return errors.New("scratch mounts require Linux")
+}
+
+// unmountScratch is only supported on Linux
+func unmountScratch(dir string) error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestValidateMounts(t *testing.T) {
+	datasets := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(datasets, "mnist"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{config: &config.Config{
+		DataDirectory:    "/coderunr",
+		DatasetDirectory: datasets,
+		ScratchMaxSize:   1 << 20,
+	}}
+
+	tests := []struct {
+		name    string
+		mounts  []types.Mount
+		wantErr bool
+	}{
+		{"None", nil, false},
+		{"Scratch", []types.Mount{{Path: "/scratch", Type: MountScratch, Size: 1024}}, false},
+		{"Scratch Default Size", []types.Mount{{Path: "/scratch", Type: MountScratch}}, false},
+		{"Dataset", []types.Mount{{Path: "/data", Type: MountDataset, Dataset: "mnist"}}, false},
+		{"Both", []types.Mount{{Path: "/scratch", Type: MountScratch}, {Path: "/data", Type: MountDataset, Dataset: "mnist"}}, false},
+		{"Scratch Too Large", []types.Mount{{Path: "/scratch", Type: MountScratch, Size: 2 << 20}}, true},
+		{"Relative Path", []types.Mount{{Path: "scratch", Type: MountScratch}}, true},
+		{"Unclean Path", []types.Mount{{Path: "/scratch/../etc", Type: MountScratch}}, true},
+		{"Root", []types.Mount{{Path: "/", Type: MountScratch}}, true},
+		{"Reserved Path", []types.Mount{{Path: "/usr/share", Type: MountScratch}}, true},
+		{"Over The Box", []types.Mount{{Path: "/box", Type: MountScratch}}, true},
+		{"Over Packages", []types.Mount{{Path: "/coderunr/packages", Type: MountScratch}}, true},
+		{"Nested Mounts", []types.Mount{{Path: "/work", Type: MountScratch}, {Path: "/work/data", Type: MountDataset, Dataset: "mnist"}}, true},
+		{"Unknown Dataset", []types.Mount{{Path: "/data", Type: MountDataset, Dataset: "cifar"}}, true},
+		{"Dataset Traversal", []types.Mount{{Path: "/data", Type: MountDataset, Dataset: "../etc"}}, true},
+		{"Unknown Type", []types.Mount{{Path: "/data", Type: "nfs"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.ValidateMounts(tt.mounts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMountsDisabled(t *testing.T) {
+	m := &Manager{config: &config.Config{DataDirectory: "/coderunr"}}
+
+	if err := m.ValidateMounts([]types.Mount{{Path: "/scratch", Type: MountScratch}}); err == nil {
+		t.Error("Expected scratch mounts to be rejected when scratch_max_size is 0")
+	}
+	if err := m.ValidateMounts([]types.Mount{{Path: "/data", Type: MountDataset, Dataset: "mnist"}}); err == nil {
+		t.Error("Expected dataset mounts to be rejected without a dataset_directory")
+	}
+}
+
+func TestMountArgs(t *testing.T) {
+	j := &Job{
+		ID: "job-1",
+		Mounts: []types.Mount{
+			{Path: "/scratch", Type: MountScratch},
+			{Path: "/data", Type: MountDataset, Dataset: "courses/mnist"},
+		},
+		manager: &Manager{config: &config.Config{DataDirectory: "/coderunr", DatasetDirectory: "/srv/datasets"}},
+	}
+
+	want := []string{
+		"--dir=/scratch=/coderunr/run/job-1/scratch/0:rw",
+		"--dir=/data=/srv/datasets/courses/mnist",
+	}
+	if got := j.mountArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
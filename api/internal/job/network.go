@@ -0,0 +1,55 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	apiKeys "github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/types"
+)
+
+// ValidateNetwork checks that the caller may turn networking on with enable_network.
+// Turning it off is always allowed, as is turning it on under a profile that enables it.
+func (m *Manager) ValidateNetwork(ctx context.Context, request *types.JobRequest) error {
+	if request.EnableNetwork == nil || !*request.EnableNetwork {
+		return nil
+	}
+	if enabled, ok := m.profileNetwork(request.Profile); ok && enabled {
+		return nil
+	}
+
+	if slices.Contains(m.config.NetworkAllowlist, "*") {
+		return nil
+	}
+	if key, ok := apiKeys.APIKeyFromContext(ctx); ok && slices.Contains(m.config.NetworkAllowlist, key.Name) {
+		return nil
+	}
+	return fmt.Errorf("enable_network is not allowed for this caller")
+}
+
+// proxyEnvArgs returns isolate -E arguments pointing common HTTP clients at proxy
+func proxyEnvArgs(proxy string) []string {
+	if proxy == "" {
+		return nil
+	}
+
+	var args []string
+	for _, env := range proxyEnv(proxy) {
+		args = append(args, "-E", env)
+	}
+	return args
+}
+
+// proxyEnv returns the proxy environment variables pointing at proxy, none when it is empty
+func proxyEnv(proxy string) []string {
+	if proxy == "" {
+		return nil
+	}
+
+	var env []string
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		env = append(env, fmt.Sprintf("%s=%s", name, proxy))
+	}
+	return env
+}
@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestValidateNetwork(t *testing.T) {
+	enabled, disabled := true, false
+	withKey := middleware.WithAPIKey(context.Background(), &config.APIKey{Key: "k", Name: "ci"})
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		ctx       context.Context
+		enable    *bool
+		wantErr   bool
+	}{
+		{"not requested", nil, context.Background(), nil, false},
+		{"disable always allowed", nil, context.Background(), &disabled, false},
+		{"empty allowlist", nil, withKey, &enabled, true},
+		{"key allowed", []string{"ci"}, withKey, &enabled, false},
+		{"key not allowed", []string{"other"}, withKey, &enabled, true},
+		{"anonymous not allowed", []string{"ci"}, context.Background(), &enabled, true},
+		{"wildcard", []string{"*"}, context.Background(), &enabled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{config: &config.Config{NetworkAllowlist: tt.allowlist}}
+			err := m.ValidateNetwork(tt.ctx, &types.JobRequest{EnableNetwork: tt.enable})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNetwork() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProxyEnvArgs(t *testing.T) {
+	if args := proxyEnvArgs(""); len(args) != 0 {
+		t.Errorf("proxyEnvArgs(\"\") = %v, want none", args)
+	}
+
+	args := proxyEnvArgs("http://127.0.0.1:2003")
+	if len(args) != 8 || args[0] != "-E" || args[1] != "HTTP_PROXY=http://127.0.0.1:2003" {
+		t.Errorf("proxyEnvArgs() = %v", args)
+	}
+}
@@ -0,0 +1,24 @@
+package job
+
+import "github.com/coderunr/api/internal/types"
+
+// stageOutcome classifies why a stage ended. Limits are checked before the generic isolate
+// status, since hitting one usually also shows up as a kill or a timeout.
+func stageOutcome(result *types.StageResult, metadata *StageUsage, outputLimited, diskFull bool) types.StageOutcome {
+	switch {
+	case result.Status == "XX":
+		return types.OutcomeSandboxError
+	case metadata != nil && metadata.OOMKilled:
+		return types.OutcomeMemoryLimit
+	case outputLimited:
+		return types.OutcomeOutputLimit
+	case result.Status == "TO":
+		return types.OutcomeTimeout
+	case diskFull:
+		return types.OutcomeDiskLimit
+	case result.Status != "" || result.Signal != "" || (result.Code != nil && *result.Code != 0):
+		return types.OutcomeRuntimeError
+	default:
+		return types.OutcomeOK
+	}
+}
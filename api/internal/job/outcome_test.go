@@ -0,0 +1,39 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestStageOutcome(t *testing.T) {
+	zero, one := 0, 1
+
+	tests := []struct {
+		name          string
+		result        types.StageResult
+		metadata      *StageUsage
+		outputLimited bool
+		diskFull      bool
+		want          types.StageOutcome
+	}{
+		{"success", types.StageResult{Code: &zero}, &StageUsage{}, false, false, types.OutcomeOK},
+		{"nonzero exit", types.StageResult{Code: &one, Status: "RE"}, &StageUsage{}, false, false, types.OutcomeRuntimeError},
+		{"crash", types.StageResult{Signal: "SIGSEGV", Status: "SG"}, &StageUsage{}, false, false, types.OutcomeRuntimeError},
+		{"timeout", types.StageResult{Signal: "SIGKILL", Status: "TO"}, &StageUsage{}, false, false, types.OutcomeTimeout},
+		{"out of memory", types.StageResult{Signal: "SIGKILL", Status: "SG"}, &StageUsage{OOMKilled: true}, false, false, types.OutcomeMemoryLimit},
+		{"output limit", types.StageResult{Signal: "SIGKILL", Status: "SG"}, &StageUsage{}, true, false, types.OutcomeOutputLimit},
+		{"output limit before timeout", types.StageResult{Status: "TO"}, &StageUsage{}, true, false, types.OutcomeOutputLimit},
+		{"disk quota", types.StageResult{Code: &one, Status: "RE"}, &StageUsage{}, false, true, types.OutcomeDiskLimit},
+		{"sandbox failure", types.StageResult{Status: "XX"}, nil, false, false, types.OutcomeSandboxError},
+		{"missing metadata", types.StageResult{Code: &zero}, nil, false, false, types.OutcomeOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stageOutcome(&tt.result, tt.metadata, tt.outputLimited, tt.diskFull); got != tt.want {
+				t.Errorf("stageOutcome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package job
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/coderunr/api/internal/artifact"
+	"github.com/coderunr/api/internal/types"
+)
+
+// maxOutputFilePatterns caps the number of output_files patterns per request
+const maxOutputFilePatterns = 32
+
+// ValidateOutputFiles checks that output file patterns are well-formed relative globs
+func ValidateOutputFiles(patterns []string) error {
+	if len(patterns) > maxOutputFilePatterns {
+		return fmt.Errorf("output_files cannot contain more than %d patterns", maxOutputFilePatterns)
+	}
+
+	for i, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("output_files[%d] must not be empty", i)
+		}
+		if path.IsAbs(pattern) || strings.Contains(pattern, "..") {
+			return fmt.Errorf("output_files[%d] must be a relative path inside the submission directory", i)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("output_files[%d] is not a valid glob pattern", i)
+		}
+	}
+
+	return nil
+}
+
+// matchesOutputFile reports whether a slash-separated relative path matches any pattern.
+// Patterns without a slash also match against the base name, so "*.png" finds nested files.
+func matchesOutputFile(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collectOutputFiles copies files matching the requested patterns out of the box.
+// Only regular files are returned; files that would exceed the size budget are skipped.
+// With an artifact sink, files of at least artifact_min_size are uploaded instead of inlined.
+func (j *Job) collectOutputFiles(ctx context.Context, box *types.IsolateBox) []types.OutputFile {
+	if len(j.OutputFiles) == 0 {
+		return nil
+	}
+
+	submissionDir := filepath.Join(box.Dir, "submission")
+	cfg := j.manager.config
+	limit := cfg.OutputFilesMaxSize
+	var used, uploaded int64
+	files := []types.OutputFile{}
+
+	err := filepath.WalkDir(submissionDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Never follow symlinks or read special files created by the program
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(submissionDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !matchesOutputFile(j.OutputFiles, rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if sink := j.manager.artifacts; sink != nil && info.Size() >= cfg.ArtifactMinSize {
+			if uploaded+info.Size() > cfg.ArtifactMaxSize {
+				j.logger.Warnf("Skipping output file %s: exceeds remaining artifact_max_size", rel)
+				return nil
+			}
+			url, err := j.uploadArtifact(ctx, sink, p, rel, info.Size())
+			if err == nil {
+				uploaded += info.Size()
+				files = append(files, types.OutputFile{Name: rel, URL: url, Size: info.Size()})
+				return nil
+			}
+			j.logger.WithError(err).Warnf("Failed to upload output file %s, inlining it", rel)
+		}
+
+		if limit > 0 && used+info.Size() > limit {
+			j.logger.Warnf("Skipping output file %s: exceeds remaining output_files_max_size", rel)
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		used += int64(len(content))
+
+		files = append(files, types.OutputFile{
+			Name:     rel,
+			Content:  base64.StdEncoding.EncodeToString(content),
+			Encoding: "base64",
+			Size:     int64(len(content)),
+		})
+		return nil
+	})
+	if err != nil {
+		j.logger.WithError(err).Warn("Failed to collect output files")
+	}
+
+	return files
+}
+
+// uploadArtifact uploads the file at p as <job ID>/<rel> and returns its download URL
+func (j *Job) uploadArtifact(ctx context.Context, sink *artifact.Sink, p, rel string, size int64) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return sink.Upload(ctx, j.ID+"/"+rel, io.LimitReader(file, size), size)
+}
+
+// combinedOutput interleaves the lines of stdout and stderr, which are read concurrently
+type combinedOutput struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+// write appends a line
+func (o *combinedOutput) write(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf.WriteString(line)
+}
+
+// String returns the output so far
+func (o *combinedOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// outputCapture keeps a copy of a streaming stage's output for its result. Like the
+// non-streaming capture, stdout and stderr are each capped at limit bytes (<=0 means
+// unlimited) and output interleaves the two.
+type outputCapture struct {
+	mu     sync.Mutex
+	limit  int
+	stdout strings.Builder
+	stderr strings.Builder
+	output strings.Builder
+}
+
+// newOutputCapture returns a capture with the given per-stream limit
+func newOutputCapture(limit int) *outputCapture {
+	return &outputCapture{limit: limit}
+}
+
+// write records data written to stream, dropping whatever exceeds the limit
+func (c *outputCapture) write(stream, data string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := &c.stdout
+	if stream == "stderr" {
+		target = &c.stderr
+	}
+	if c.limit > 0 {
+		remaining := c.limit - target.Len()
+		if remaining <= 0 {
+			return
+		}
+		if len(data) > remaining {
+			data = data[:remaining]
+		}
+	}
+	target.WriteString(data)
+	c.output.WriteString(data)
+}
+
+// apply fills in the stage result's stdout, stderr and output
+func (c *outputCapture) apply(result *types.StageResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result.Stdout = c.stdout.String()
+	result.Stderr = c.stderr.String()
+	result.Output = c.output.String()
+}
@@ -0,0 +1,174 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidateOutputFiles(t *testing.T) {
+	valid := [][]string{nil, {"*.png"}, {"out/*.csv", "result.txt"}}
+	for _, patterns := range valid {
+		if err := ValidateOutputFiles(patterns); err != nil {
+			t.Errorf("Expected %v to be valid, got %v", patterns, err)
+		}
+	}
+
+	invalid := [][]string{{""}, {"/etc/passwd"}, {"../secret"}, {"[bad"}}
+	for _, patterns := range invalid {
+		if err := ValidateOutputFiles(patterns); err == nil {
+			t.Errorf("Expected %v to be rejected", patterns)
+		}
+	}
+}
+
+func TestCollectOutputFiles(t *testing.T) {
+	box := &types.IsolateBox{Dir: t.TempDir()}
+	submission := filepath.Join(box.Dir, "submission")
+	files := map[string]string{
+		"main.py":       "print(1)",
+		"plot.png":      "png-data",
+		"out/data.csv":  "a,b",
+		"out/large.csv": "0123456789",
+	}
+	for name, content := range files {
+		path := filepath.Join(submission, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Symlinks must never be followed out of the sandbox
+	if err := os.Symlink("/etc/hostname", filepath.Join(submission, "link.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	j := &Job{
+		OutputFiles: []string{"*.png", "out/*.csv"},
+		logger:      logrus.WithField("test", t.Name()),
+		manager:     &Manager{config: &config.Config{OutputFilesMaxSize: 12}},
+	}
+
+	got := map[string]string{}
+	for _, file := range j.collectOutputFiles(context.Background(), box) {
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			t.Fatalf("Invalid base64 for %s: %v", file.Name, err)
+		}
+		got[file.Name] = string(content)
+	}
+
+	// large.csv is skipped once the 12 byte budget is used up
+	expected := map[string]string{"plot.png": "png-data", "out/data.csv": "a,b"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected files %v, got %v", expected, got)
+	}
+	for name, content := range expected {
+		if got[name] != content {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, got[name])
+		}
+	}
+}
+
+func TestSendOutputLimitAction(t *testing.T) {
+	tests := []struct {
+		action     string
+		wantData   string
+		wantKilled bool
+	}{
+		{OutputLimitKill, "hello wor", true},
+		{OutputLimitTruncate, "hello wor", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			j := &Job{
+				OutputLimitAction: tt.action,
+				outputBudget:      9,
+				EventChannel:      make(chan types.StreamEvent, 10),
+				logger:            logrus.WithField("test", t.Name()),
+			}
+
+			var reading bool
+			for _, chunk := range []string{"hello ", "world", "more"} {
+				if reading = j.sendOutput("run", "stdout", chunk); !reading {
+					break
+				}
+			}
+			if reading == tt.wantKilled {
+				t.Errorf("Expected reading to continue: %v, got %v", !tt.wantKilled, reading)
+			}
+			close(j.EventChannel)
+
+			var data string
+			killed := false
+			for event := range j.EventChannel {
+				switch event.Type {
+				case "data":
+					data += event.Data
+				case "error":
+					killed = true
+				}
+			}
+			if data != tt.wantData {
+				t.Errorf("Expected output %q, got %q", tt.wantData, data)
+			}
+			if killed != tt.wantKilled || j.outputLimited.Load() != tt.wantKilled {
+				t.Errorf("Expected killed %v, got error event %v and outputLimited %v", tt.wantKilled, killed, j.outputLimited.Load())
+			}
+			if !j.outputTruncated.Load() {
+				t.Error("Expected the output to be marked truncated")
+			}
+		})
+	}
+}
+
+func TestReadWithLimitTruncate(t *testing.T) {
+	j := &Job{OutputLimitAction: OutputLimitTruncate, OutputMaxSize: 8}
+
+	// The reader must be drained past the limit so the process never blocks on a full pipe
+	reader := strings.NewReader("one\ntwo\nthree\nfour\n")
+	var target bytes.Buffer
+	var combined combinedOutput
+	j.readWithLimit(reader, &target, &combined)
+
+	if target.String() != "one\ntwo\n" {
+		t.Errorf("Expected output cut at the limit, got %q", target.String())
+	}
+	if reader.Len() != 0 {
+		t.Errorf("Expected the rest of the output to be drained, %d bytes left", reader.Len())
+	}
+	if !j.outputTruncated.Load() || j.outputLimited.Load() {
+		t.Error("Expected truncated output without an output_limit kill")
+	}
+}
+
+func TestOutputCapture(t *testing.T) {
+	capture := newOutputCapture(8)
+	capture.write("stdout", "hello\n")
+	capture.write("stderr", "oops\n")
+	capture.write("stdout", "world\n")
+
+	var result types.StageResult
+	capture.apply(&result)
+
+	if result.Stdout != "hello\nwo" {
+		t.Errorf("Expected stdout cut at 8 bytes, got %q", result.Stdout)
+	}
+	if result.Stderr != "oops\n" {
+		t.Errorf("Expected stderr %q, got %q", "oops\n", result.Stderr)
+	}
+	if result.Output != "hello\noops\nwo" {
+		t.Errorf("Expected interleaved output, got %q", result.Output)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package job
+
+import "io/fs"
+
+// copyOwner is a no-op where files have no Unix owner
+func copyOwner(target string, info fs.FileInfo) {}
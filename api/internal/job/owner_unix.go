@@ -0,0 +1,16 @@
+//go:build unix
+
+package job
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// copyOwner gives target the owner and group of the file described by info
+func copyOwner(target string, info fs.FileInfo) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = os.Lchown(target, int(stat.Uid), int(stat.Gid))
+	}
+}
@@ -0,0 +1,81 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// stageLimits returns the limits of pipeline stage i: those the package sets for it, otherwise
+// the job's compile limits, or its run limits for the last stage
+func (j *Job) stageLimits(i int) (timeout, cpuTime time.Duration, memoryLimit int64) {
+	stage := j.Runtime.Stages[i]
+	timeout, cpuTime, memoryLimit = j.Timeouts.Compile, j.CPUTimes.Compile, j.MemoryLimits.Compile
+	if i == len(j.Runtime.Stages)-1 {
+		timeout, cpuTime, memoryLimit = j.Timeouts.Run, j.CPUTimes.Run, j.MemoryLimits.Run
+	}
+
+	resolution := j.manager.config.TimeLimitResolution
+	if stage.Timeout > 0 {
+		timeout = roundLimit(stage.Timeout, resolution)
+	}
+	if stage.CPUTime > 0 {
+		cpuTime = roundLimit(stage.CPUTime, resolution)
+	}
+	if stage.MemoryLimit != 0 {
+		memoryLimit = stage.MemoryLimit
+	}
+	return timeout, cpuTime, memoryLimit
+}
+
+// executePipeline runs the stages the package declares in box, in order and each given the
+// entrypoint and the request's arguments, until one fails. With stream, stage events and output
+// go to the event channel.
+func (j *Job) executePipeline(ctx context.Context, box *types.IsolateBox, stream bool) ([]types.PipelineStageResult, error) {
+	args := append([]string{j.entrypoint()}, j.Args...)
+	call := j.safeCall
+	if stream {
+		call = j.safeCallStream
+	}
+
+	results := make([]types.PipelineStageResult, 0, len(j.Runtime.Stages))
+	for i, stage := range j.Runtime.Stages {
+		j.logger.Debugf("Running %s stage", stage.Name)
+		if stream {
+			j.sendEvent(types.StreamEvent{Type: "stage_start", Stage: stage.Name})
+		}
+
+		timeout, cpuTime, memoryLimit := j.stageLimits(i)
+		result, err := call(ctx, box, stage.Name, args, timeout, cpuTime, memoryLimit)
+		if err != nil {
+			return results, fmt.Errorf("%s stage failed: %w", stage.Name, err)
+		}
+		results = append(results, types.PipelineStageResult{Name: stage.Name, StageResult: *result})
+
+		if stream {
+			code := 0
+			if result.Code != nil {
+				code = *result.Code
+			}
+			j.sendEvent(types.StreamEvent{Type: "stage_end", Stage: stage.Name, Code: code, Truncated: result.Truncated})
+		}
+		if result.Signal != "" || (result.Code != nil && *result.Code != 0) {
+			break
+		}
+	}
+	return results, nil
+}
+
+// pipelineResult returns the execution result of a pipeline that ran stages. Output files are
+// collected only once every stage has run, as they are after the run stage.
+func (j *Job) pipelineResult(ctx context.Context, box *types.IsolateBox, stages []types.PipelineStageResult) *types.ExecutionResult {
+	result := j.newResult()
+	result.Stages = stages
+	result.Run = &stages[len(stages)-1].StageResult
+	if len(stages) == len(j.Runtime.Stages) {
+		result.Files = j.collectOutputFiles(ctx, box)
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// newPipelineTestManager returns a local test manager whose runtime runs build, test and run,
+// with the given test script
+func newPipelineTestManager(t *testing.T, testScript string) (*Manager, *types.Runtime) {
+	t.Helper()
+	m, rt := newLocalTestManager(t, "cat built\n")
+	for name, script := range map[string]string{"build": "echo ok > built\n", "test": testScript} {
+		if err := os.WriteFile(filepath.Join(rt.PkgDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rt.Stages = []types.PipelineStage{{Name: "build", Timeout: 2 * time.Second}, {Name: "test"}, {Name: "run"}}
+	return m, rt
+}
+
+func TestExecutePipeline(t *testing.T) {
+	m, rt := newPipelineTestManager(t, "grep -q ok built && echo passed\n")
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+	})
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if len(result.Stages) != 3 {
+		t.Fatalf("Stages = %+v, want build, test and run", result.Stages)
+	}
+	for i, name := range []string{"build", "test", "run"} {
+		if result.Stages[i].Name != name || result.Stages[i].Outcome != types.OutcomeOK {
+			t.Errorf("Stages[%d] = %s with outcome %q, want %s succeeding", i, result.Stages[i].Name, result.Stages[i].Outcome, name)
+		}
+	}
+	if result.Stages[1].Stdout != "passed\n" || result.Run.Stdout != "ok\n" {
+		t.Errorf("test stdout = %q, run stdout = %q", result.Stages[1].Stdout, result.Run.Stdout)
+	}
+	if result.Compile != nil {
+		t.Error("Expected no compile stage for a pipeline")
+	}
+}
+
+func TestExecutePipelineStopsAtFailure(t *testing.T) {
+	m, rt := newPipelineTestManager(t, "echo failed >&2\nexit 1\n")
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+	})
+	go func() {
+		if err := j.ExecuteStream(context.Background()); err != nil {
+			t.Errorf("ExecuteStream() error = %v", err)
+		}
+	}()
+
+	var started []string
+	var result *types.ExecutionResult
+	for event := range j.EventChannel {
+		switch event.Type {
+		case "stage_start":
+			started = append(started, event.Stage)
+		case "result":
+			result = event.Result
+		}
+	}
+
+	if len(started) != 2 || started[0] != "build" || started[1] != "test" {
+		t.Errorf("Started stages = %v, want build then test", started)
+	}
+	if result == nil || len(result.Stages) != 2 {
+		t.Fatalf("Result = %+v, want the build and test stages", result)
+	}
+	if result.Run.Stderr != "failed\n" || result.Run.Code == nil || *result.Run.Code != 1 {
+		t.Errorf("Run = %+v, want the failed test stage", result.Run)
+	}
+}
+
+func TestStageLimits(t *testing.T) {
+	m, rt := newPipelineTestManager(t, "true\n")
+	m.config.TimeLimitResolution = time.Millisecond
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{})
+
+	if timeout, _, _ := j.stageLimits(0); timeout != 2*time.Second {
+		t.Errorf("build timeout = %v, want its own 2s", timeout)
+	}
+	if timeout, _, memory := j.stageLimits(1); timeout != j.Timeouts.Compile || memory != j.MemoryLimits.Compile {
+		t.Errorf("test limits = %v, %d; want the compile limits", timeout, memory)
+	}
+	if timeout, _, _ := j.stageLimits(2); timeout != j.Timeouts.Run {
+		t.Errorf("run timeout = %v, want the run timeout", timeout)
+	}
+}
@@ -0,0 +1,281 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoFreeBox is returned when every on-demand box ID is owned by a running job
+var ErrNoFreeBox = errors.New("no free isolate box IDs")
+
+// BoxPool keeps a set of pre-initialized isolate boxes so jobs can skip isolate --init.
+// Pooled boxes own the IDs [0, size); boxes created on demand when the pool is empty
+// use the remaining IDs and are cleaned up normally after use. An on-demand ID is never
+// handed out again until its box has been released.
+//
+// Pooled boxes are initialized with the default disk quota; jobs asking for a different
+// quota get an on-demand box, since isolate applies quotas at --init time.
+type BoxPool struct {
+	isolatePath string
+	size        int
+	quota       int64
+	inodes      int
+	boxes       chan *types.IsolateBox
+	logger      *logrus.Entry
+
+	// Close stops recycling and waits for recycles in progress
+	closed    bool
+	recycling sync.WaitGroup
+	mutex     sync.Mutex
+
+	// active holds the on-demand box IDs handed to jobs, which are neither reallocated nor reaped
+	active map[int]bool
+	// nextID is where the search for a free on-demand ID starts, so freed IDs are reused last
+	nextID int
+	// boxRoot is isolate's box directory, learned from the first successful --init
+	boxRoot string
+
+	// Counters exposed through Stats
+	hits           atomic.Int64
+	misses         atomic.Int64
+	reinitFailures atomic.Int64
+	reaped         atomic.Int64
+}
+
+// BoxPoolStats is a snapshot of the box pool counters
+type BoxPoolStats struct {
+	Size           int   `json:"size"`
+	Available      int   `json:"available"`
+	Hits           int64 `json:"hits"`
+	Misses         int64 `json:"misses"`
+	ReinitFailures int64 `json:"reinit_failures"`
+	Reaped         int64 `json:"reaped"`
+}
+
+// NewBoxPool creates a pool of size boxes using the isolate binary at isolatePath and starts
+// warming them in the background. A size of zero disables pooling. Boxes get a disk quota of
+// quota bytes and inodes files when quota is positive.
+func NewBoxPool(isolatePath string, size int, quota int64, inodes int) *BoxPool {
+	if size < 0 {
+		size = 0
+	}
+	if size > MaxBoxID/2 {
+		size = MaxBoxID / 2
+	}
+
+	p := &BoxPool{
+		isolatePath: isolatePath,
+		size:        size,
+		quota:       quota,
+		inodes:      inodes,
+		boxes:       make(chan *types.IsolateBox, size),
+		active:      make(map[int]bool),
+		logger:      logrus.WithField("component", "box_pool"),
+	}
+
+	p.recycling.Add(size)
+	for id := 0; id < size; id++ {
+		go p.recycle(id)
+	}
+
+	return p
+}
+
+// Get returns a ready box with the given disk quota, falling back to initializing a fresh
+// one when the pool is empty or pooled boxes have a different quota
+func (p *BoxPool) Get(quota int64) (*types.IsolateBox, error) {
+	if quotaBlocks(quota) != quotaBlocks(p.quota) {
+		p.misses.Add(1)
+		return p.onDemand(quota)
+	}
+
+	select {
+	case box := <-p.boxes:
+		p.hits.Add(1)
+		return box, nil
+	default:
+	}
+
+	p.misses.Add(1)
+	return p.onDemand(p.quota)
+}
+
+// onDemand initializes a box outside the pooled range and marks it active until released
+func (p *BoxPool) onDemand(quota int64) (*types.IsolateBox, error) {
+	id, err := p.allocateID()
+	if err != nil {
+		return nil, err
+	}
+
+	box, err := p.initBox(id, quota)
+	if err != nil {
+		p.setActive(id, false)
+		return nil, err
+	}
+	return box, nil
+}
+
+// setActive marks an on-demand box ID as in use or free
+func (p *BoxPool) setActive(id int, active bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if active {
+		p.active[id] = true
+	} else {
+		delete(p.active, id)
+	}
+}
+
+// Release cleans up a used box. Pooled boxes are re-initialized and returned to the pool
+// in the background; on-demand boxes, and every box once the pool is closed, are cleaned up
+// before Release returns.
+func (p *BoxPool) Release(box *types.IsolateBox) error {
+	p.mutex.Lock()
+	if !p.owns(box.ID) || p.closed {
+		p.mutex.Unlock()
+		defer p.setActive(box.ID, false)
+		return p.cleanupBox(box.ID)
+	}
+	p.recycling.Add(1)
+	p.mutex.Unlock()
+
+	go p.recycle(box.ID)
+	return nil
+}
+
+// Close stops recycling and cleans up every pooled box, so no sandboxes are left behind
+func (p *BoxPool) Close() {
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	p.recycling.Wait()
+	for {
+		select {
+		case box := <-p.boxes:
+			if err := p.cleanupBox(box.ID); err != nil {
+				p.logger.WithError(err).Warnf("Failed to clean up pooled box %d", box.ID)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Stats returns the current pool counters
+func (p *BoxPool) Stats() BoxPoolStats {
+	return BoxPoolStats{
+		Size:           p.size,
+		Available:      len(p.boxes),
+		Hits:           p.hits.Load(),
+		Misses:         p.misses.Load(),
+		ReinitFailures: p.reinitFailures.Load(),
+		Reaped:         p.reaped.Load(),
+	}
+}
+
+// recycle wipes a pooled box and puts a freshly initialized copy back into the pool.
+// On failure the box is dropped; jobs then fall back to on-demand boxes.
+func (p *BoxPool) recycle(id int) {
+	defer p.recycling.Done()
+
+	// Clear anything left from a previous run or a previous server instance
+	_ = p.cleanupBox(id)
+
+	p.mutex.Lock()
+	closed := p.closed
+	p.mutex.Unlock()
+	if closed {
+		return
+	}
+
+	box, err := p.initBox(id, p.quota)
+	if err != nil {
+		p.reinitFailures.Add(1)
+		p.logger.WithError(err).Warnf("Failed to initialize pooled box %d", id)
+		return
+	}
+
+	p.boxes <- box
+}
+
+// owns reports whether the box ID belongs to the pool
+func (p *BoxPool) owns(id int) bool {
+	return id < p.size
+}
+
+// allocateID reserves a free box ID outside the pooled range
+func (p *BoxPool) allocateID() (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	span := MaxBoxID - p.size
+	for i := 0; i < span; i++ {
+		offset := (p.nextID + i) % span
+		id := p.size + offset
+		if !p.active[id] {
+			p.active[id] = true
+			p.nextID = (offset + 1) % span
+			return id, nil
+		}
+	}
+	return 0, ErrNoFreeBox
+}
+
+// initBox runs isolate --init for the given box ID, applying a disk quota when quota is positive
+func (p *BoxPool) initBox(id int, quota int64) (*types.IsolateBox, error) {
+	args := []string{"--init", "--cg", fmt.Sprintf("-b%d", id)}
+	if blocks := quotaBlocks(quota); blocks > 0 {
+		args = append(args, fmt.Sprintf("--quota=%d,%d", blocks, p.inodes))
+	}
+
+	cmd := exec.Command(p.isolatePath, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		// Output keeps isolate's stderr, which says why it could not set the box up
+		var detail string
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			detail = sandboxDetail("", string(exitErr.Stderr))
+		}
+		return nil, &SandboxError{Stage: "init", Detail: detail, Err: err}
+	}
+
+	outputStr := strings.TrimSpace(string(output))
+	if outputStr == "" {
+		return nil, fmt.Errorf("received empty output from isolate --init")
+	}
+
+	p.mutex.Lock()
+	p.boxRoot = filepath.Dir(outputStr)
+	p.mutex.Unlock()
+
+	// The metadata path is per job and set by the job that gets the box
+	return &types.IsolateBox{
+		ID:  id,
+		Dir: outputStr + "/box",
+	}, nil
+}
+
+// quotaBlocks converts a quota in bytes to isolate's 1KB blocks, rounding up; 0 means no quota
+func quotaBlocks(quota int64) int64 {
+	if quota <= 0 {
+		return 0
+	}
+	return (quota + 1023) / 1024
+}
+
+// cleanupBox runs isolate --cleanup for the given box ID
+func (p *BoxPool) cleanupBox(id int) error {
+	cmd := exec.Command(p.isolatePath, "--cleanup", "--cg", fmt.Sprintf("-b%d", id))
+	return cmd.Run()
+}
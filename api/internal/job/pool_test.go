@@ -0,0 +1,74 @@
+package job
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBoxPoolOnDemandIDs(t *testing.T) {
+	pool := &BoxPool{size: 16, active: map[int]bool{}}
+
+	seen := map[int]bool{}
+	for i := 0; i < MaxBoxID-pool.size; i++ {
+		id, err := pool.allocateID()
+		if err != nil {
+			t.Fatalf("allocateID() error = %v after %d IDs", err, i)
+		}
+		if id < pool.size || id >= MaxBoxID {
+			t.Fatalf("On-demand box ID %d outside [%d, %d)", id, pool.size, MaxBoxID)
+		}
+		if pool.owns(id) {
+			t.Fatalf("On-demand box ID %d must not belong to the pool", id)
+		}
+		if seen[id] {
+			t.Fatalf("On-demand box ID %d handed out twice while in use", id)
+		}
+		seen[id] = true
+	}
+
+	if _, err := pool.allocateID(); !errors.Is(err, ErrNoFreeBox) {
+		t.Fatalf("allocateID() with every ID in use error = %v, want ErrNoFreeBox", err)
+	}
+
+	// A released ID becomes available again
+	pool.setActive(500, false)
+	if id, err := pool.allocateID(); err != nil || id != 500 {
+		t.Errorf("allocateID() after release = %d, %v, want 500", id, err)
+	}
+
+	if !pool.owns(0) || !pool.owns(15) {
+		t.Error("Expected pool to own IDs below its size")
+	}
+}
+
+func TestBoxPoolReusesFreedIDsLast(t *testing.T) {
+	pool := &BoxPool{size: 16, active: map[int]bool{}}
+
+	first, _ := pool.allocateID()
+	pool.setActive(first, false)
+	second, _ := pool.allocateID()
+	if second == first {
+		t.Errorf("allocateID() reused freed ID %d immediately", first)
+	}
+}
+
+func TestInitBoxReportsIsolateDiagnostics(t *testing.T) {
+	isolate := filepath.Join(t.TempDir(), "isolate")
+	script := "#!/bin/sh\necho 'Cannot write /sys/fs/cgroup/isolate/cgroup.subtree_control: Permission denied' >&2\nexit 2\n"
+	if err := os.WriteFile(isolate, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pool := &BoxPool{isolatePath: isolate, active: map[int]bool{}}
+
+	_, err := pool.initBox(1, 0)
+	var sandboxErr *SandboxError
+	if !errors.As(err, &sandboxErr) {
+		t.Fatalf("initBox() error = %v, want a SandboxError", err)
+	}
+	if sandboxErr.Stage != "init" || !strings.Contains(sandboxErr.Detail, "Permission denied") {
+		t.Errorf("SandboxError = %+v, want isolate's stderr for the init stage", sandboxErr)
+	}
+}
@@ -0,0 +1,93 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	apiKeys "github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/types"
+)
+
+var (
+	// ErrUnknownProfile is returned for a request naming a profile that is not configured
+	ErrUnknownProfile = errors.New("unknown profile")
+	// ErrProfileNotAllowed is returned when the caller's API key may not select the profile
+	ErrProfileNotAllowed = errors.New("profile is not allowed for this caller")
+)
+
+// ApplyProfile checks that the caller may use the profile the request names and returns rt with
+// the profile's limits, against which the request's own limits are then validated. It returns
+// rt unchanged for a request without a profile.
+func (m *Manager) ApplyProfile(ctx context.Context, request *types.JobRequest, rt *types.Runtime) (*types.Runtime, error) {
+	if request.Profile == "" {
+		return rt, nil
+	}
+
+	profile, ok := m.config.Profiles[request.Profile]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProfile, request.Profile)
+	}
+	allowed := slices.Contains(profile.Allow, "*")
+	if key, ok := apiKeys.APIKeyFromContext(ctx); ok && slices.Contains(profile.Allow, key.Name) {
+		allowed = true
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotAllowed, request.Profile)
+	}
+
+	if profile.Network != nil && !*profile.Network && request.EnableNetwork != nil && *request.EnableNetwork {
+		return nil, fmt.Errorf("profile %s does not allow enable_network", request.Profile)
+	}
+	return m.profileRuntime(request.Profile, rt), nil
+}
+
+// profileRuntime returns a copy of rt with the limits the named profile sets, or rt itself when
+// the profile is empty or unknown
+func (m *Manager) profileRuntime(name string, rt *types.Runtime) *types.Runtime {
+	profile, ok := m.config.Profiles[name]
+	if name == "" || !ok {
+		return rt
+	}
+
+	limited := *rt
+	if profile.CompileTimeout > 0 {
+		limited.Timeouts.Compile = profile.CompileTimeout
+	}
+	if profile.RunTimeout > 0 {
+		limited.Timeouts.Run = profile.RunTimeout
+	}
+	if profile.CompileCPUTime > 0 {
+		limited.CPUTimes.Compile = profile.CompileCPUTime
+	}
+	if profile.RunCPUTime > 0 {
+		limited.CPUTimes.Run = profile.RunCPUTime
+	}
+	if profile.CompileMemoryLimit > 0 {
+		limited.MemoryLimits.Compile = profile.CompileMemoryLimit
+	}
+	if profile.RunMemoryLimit > 0 {
+		limited.MemoryLimits.Run = profile.RunMemoryLimit
+	}
+	if profile.MaxProcessCount > 0 {
+		limited.MaxProcessCount = profile.MaxProcessCount
+	}
+	if profile.OutputMaxSize > 0 {
+		limited.OutputMaxSize = profile.OutputMaxSize
+	}
+	if profile.DiskQuota > 0 {
+		limited.DiskQuota = profile.DiskQuota
+	}
+	return &limited
+}
+
+// profileNetwork reports whether the named profile turns networking on or off, and ok false when
+// it leaves the language default
+func (m *Manager) profileNetwork(name string) (enabled, ok bool) {
+	profile, found := m.config.Profiles[name]
+	if name == "" || !found || profile.Network == nil {
+		return false, false
+	}
+	return *profile.Network, true
+}
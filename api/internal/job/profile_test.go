@@ -0,0 +1,100 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/middleware"
+	"github.com/coderunr/api/internal/types"
+)
+
+func newProfileTestManager() *Manager {
+	enabled, disabled := true, false
+	return &Manager{config: &config.Config{
+		TimeLimitResolution: time.Millisecond,
+		Profiles: map[string]config.Profile{
+			"contest":  {RunCPUTime: 2 * time.Second, RunMemoryLimit: 256 << 20, Network: &disabled, Allow: []string{"*"}},
+			"notebook": {RunTimeout: 30 * time.Second, RunMemoryLimit: 2 << 30, Network: &enabled, Allow: []string{"research"}},
+		},
+	}}
+}
+
+func TestApplyProfile(t *testing.T) {
+	enabled := true
+	research := middleware.WithAPIKey(context.Background(), &config.APIKey{Key: "k", Name: "research"})
+	student := middleware.WithAPIKey(context.Background(), &config.APIKey{Key: "k", Name: "student"})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		request types.JobRequest
+		wantErr bool
+		is      error
+	}{
+		{"no profile", student, types.JobRequest{}, false, nil},
+		{"wildcard", student, types.JobRequest{Profile: "contest"}, false, nil},
+		{"key allowed", research, types.JobRequest{Profile: "notebook"}, false, nil},
+		{"key not allowed", student, types.JobRequest{Profile: "notebook"}, true, ErrProfileNotAllowed},
+		{"anonymous not allowed", context.Background(), types.JobRequest{Profile: "notebook"}, true, ErrProfileNotAllowed},
+		{"unknown", research, types.JobRequest{Profile: "missing"}, true, ErrUnknownProfile},
+		{"network disabled", student, types.JobRequest{Profile: "contest", EnableNetwork: &enabled}, true, nil},
+	}
+
+	m := newProfileTestManager()
+	rt := &types.Runtime{Language: "python", Timeouts: types.Timeouts{Run: 3 * time.Second}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.ApplyProfile(tt.ctx, &tt.request, rt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("ApplyProfile() error = %v, want %v", err, tt.is)
+			}
+		})
+	}
+}
+
+func TestApplyProfileLimits(t *testing.T) {
+	m := newProfileTestManager()
+	rt := &types.Runtime{
+		Language:     "python",
+		Timeouts:     types.Timeouts{Compile: 10 * time.Second, Run: 3 * time.Second},
+		CPUTimes:     types.CPUTimes{Compile: 10 * time.Second, Run: 3 * time.Second},
+		MemoryLimits: types.MemoryLimits{Compile: -1, Run: 128 << 20},
+	}
+	ctx := middleware.WithAPIKey(context.Background(), &config.APIKey{Key: "k", Name: "research"})
+
+	request := &types.JobRequest{Profile: "notebook"}
+	limited, err := m.ApplyProfile(ctx, request, rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limited.Timeouts.Run != 30*time.Second || limited.MemoryLimits.Run != 2<<30 {
+		t.Errorf("Profile limits = %v, %d; want 30s, 2 GiB", limited.Timeouts.Run, limited.MemoryLimits.Run)
+	}
+	if limited.Timeouts.Compile != rt.Timeouts.Compile || limited.CPUTimes.Run != rt.CPUTimes.Run {
+		t.Error("Limits the profile leaves out should keep the runtime's")
+	}
+	if rt.Timeouts.Run != 3*time.Second {
+		t.Error("ApplyProfile() modified the runtime")
+	}
+
+	// Request limits up to the profile's are accepted, even above the runtime's
+	runTimeout := 20000
+	request.RunTimeout = &runTimeout
+	if err := ValidateConstraints(request, limited); err != nil {
+		t.Errorf("ValidateConstraints() error = %v", err)
+	}
+
+	j := m.NewJob(ctx, rt, &types.JobRequest{Profile: "notebook"})
+	if j.Timeouts.Run != 30*time.Second || !j.Network {
+		t.Errorf("Job run timeout = %v, network = %v; want 30s with networking", j.Timeouts.Run, j.Network)
+	}
+	if err := m.ValidateNetwork(ctx, &types.JobRequest{Profile: "notebook", EnableNetwork: &j.Network}); err != nil {
+		t.Errorf("ValidateNetwork() under a networked profile error = %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// ptyDrainTimeout bounds the wait for the last output after the program exits
+const ptyDrainTimeout = time.Second
+
+// ptyEOF is Ctrl-D, the terminal's default VEOF character
+const ptyEOF = 0x04
+
+// callPTY starts a stage command attached to a pseudo-terminal and waits for it.
+// stdout and stderr share the terminal and are streamed as raw stdout chunks.
+func (j *Job) callPTY(ctx context.Context, cmd *exec.Cmd, box *types.IsolateBox, stage string) (*types.StageResult, error) {
+	j.cmdMutex.RLock()
+	size := *j.PTY
+	j.cmdMutex.RUnlock()
+
+	master, slave, err := openPTY(&size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pty: %w", err)
+	}
+	defer master.Close()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = ptyProcAttr()
+
+	j.cmdMutex.Lock()
+	j.runningCmd = cmd
+	j.runningBox = box
+	j.pty = master
+	j.cmdMutex.Unlock()
+
+	err = cmd.Start()
+	// The sandbox holds its own copy; closing ours lets reads end with EIO when it exits
+	slave.Close()
+	if err != nil {
+		j.clearRunning()
+		return nil, &SandboxError{Stage: stage, Err: err}
+	}
+
+	go func() {
+		j.writeStdin(master)
+		for {
+			select {
+			case data, ok := <-j.StdinChannel:
+				if !ok {
+					// A terminal has no end of file; type the EOF character as a user would
+					master.Write([]byte{ptyEOF})
+					return
+				}
+				master.Write([]byte(data))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	capture := newOutputCapture(j.OutputMaxSize)
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		j.streamOutput(master, stage, "stdout", capture)
+	}()
+
+	err = cmd.Wait()
+	select {
+	case <-outputDone:
+	case <-time.After(ptyDrainTimeout):
+	}
+	j.clearRunning()
+
+	result := j.stageResult(box, cmd, err)
+	capture.apply(result)
+	return result, nil
+}
+
+// clearRunning forgets the running command and its pty
+func (j *Job) clearRunning() {
+	j.cmdMutex.Lock()
+	j.runningCmd = nil
+	j.runningBox = nil
+	j.pty = nil
+	j.cmdMutex.Unlock()
+}
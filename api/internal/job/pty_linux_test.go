@@ -0,0 +1,63 @@
+package job
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestOpenPTY(t *testing.T) {
+	master, slave, err := openPTY(&types.PTYSize{Rows: 30, Cols: 100})
+	if err != nil {
+		t.Skipf("pseudo-terminals unavailable: %v", err)
+	}
+	defer master.Close()
+	defer slave.Close()
+
+	size, err := unix.IoctlGetWinsize(int(slave.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		t.Fatalf("IoctlGetWinsize() error = %v", err)
+	}
+	if size.Row != 30 || size.Col != 100 {
+		t.Errorf("window size = %dx%d, want 30x100", size.Row, size.Col)
+	}
+
+	j := &Job{PTY: &types.PTYSize{Rows: 30, Cols: 100}, pty: master}
+	if err := j.Resize(50, 132); err != nil {
+		t.Fatalf("Resize() error = %v", err)
+	}
+	size, _ = unix.IoctlGetWinsize(int(slave.Fd()), unix.TIOCGWINSZ)
+	if size.Row != 50 || size.Col != 132 {
+		t.Errorf("window size after resize = %dx%d, want 50x132", size.Row, size.Col)
+	}
+
+	// Output without a trailing newline arrives as soon as it is written
+	j.EventChannel = make(chan types.StreamEvent, 10)
+	j.logger = logrus.NewEntry(logrus.New())
+	go j.streamOutput(master, "run", "stdout", newOutputCapture(0))
+	slave.Write([]byte("name? "))
+
+	event := <-j.EventChannel
+	if event.Type != "data" || !strings.Contains(event.Data, "name? ") {
+		t.Errorf("event = %+v, want data containing the prompt", event)
+	}
+}
+
+func TestResizeRequiresPTY(t *testing.T) {
+	j := &Job{}
+	if err := j.Resize(24, 80); err == nil {
+		t.Error("Resize() on a job without pty mode should fail")
+	}
+
+	j.PTY = &types.PTYSize{Rows: 24, Cols: 80}
+	if err := j.Resize(0, 80); err == nil {
+		t.Error("Resize() with zero rows should fail")
+	}
+	if err := j.Resize(40, 120); err != nil || j.PTY.Rows != 40 || j.PTY.Cols != 120 {
+		t.Errorf("Resize() before start = %v, size %+v", err, j.PTY)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package job
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// openPTY is only supported on Linux, where isolate runs
+func openPTY(size *types.PTYSize) (master, slave *os.File, err error) {
+	return nil, nil, errors.New("pty mode requires Linux")
+}
+
+// ptyProcAttr is only supported on Linux
+func ptyProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// resizePTY is only supported on Linux
+func resizePTY(master *os.File, size *types.PTYSize) error {
+	return errors.New("pty mode requires Linux")
+}
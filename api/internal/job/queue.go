@@ -0,0 +1,171 @@
+package job
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+)
+
+// Job priority bounds; higher priorities are scheduled first
+const (
+	MinPriority = -10
+	MaxPriority = 10
+)
+
+// ErrQueueFull is returned when a job cannot be queued because the queue is at its maximum depth
+var ErrQueueFull = errors.New("job queue is full")
+
+// QueueStats reports the state of the job queue
+type QueueStats struct {
+	Running  int `json:"running"`
+	Queued   int `json:"queued"`
+	Capacity int `json:"capacity"`
+	MaxDepth int `json:"max_depth"`
+}
+
+// Queue hands out a fixed number of execution slots, ordering waiters by priority
+// and then by arrival. Waiters beyond the maximum depth are rejected with ErrQueueFull.
+type Queue struct {
+	capacity int
+	maxDepth int
+	running  int
+	seq      uint64
+	waiters  waiterHeap
+	mutex    sync.Mutex
+}
+
+// waiter is a job waiting for a slot; ready is closed once the slot is handed over
+type waiter struct {
+	priority int
+	seq      uint64
+	index    int
+	ready    chan struct{}
+}
+
+// NewQueue creates a queue with capacity slots. A maxDepth of 0 allows unlimited waiters.
+func NewQueue(capacity, maxDepth int) *Queue {
+	return &Queue{
+		capacity: capacity,
+		maxDepth: maxDepth,
+	}
+}
+
+// Acquire blocks until a slot is available or ctx is done
+func (q *Queue) Acquire(ctx context.Context, priority int) error {
+	q.mutex.Lock()
+	if q.running < q.capacity && len(q.waiters) == 0 {
+		q.running++
+		q.mutex.Unlock()
+		return nil
+	}
+	if q.maxDepth > 0 && len(q.waiters) >= q.maxDepth {
+		q.mutex.Unlock()
+		return ErrQueueFull
+	}
+
+	w := &waiter{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	q.seq++
+	heap.Push(&q.waiters, w)
+	q.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+
+		select {
+		case <-w.ready:
+			// The slot was handed over while cancelling; pass it on
+			q.running--
+			q.dispatch()
+		default:
+			heap.Remove(&q.waiters, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot, handing it to the highest priority waiter
+func (q *Queue) Release() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.running--
+	q.dispatch()
+}
+
+// dispatch hands free slots to waiters; the caller must hold the mutex
+func (q *Queue) dispatch() {
+	for q.running < q.capacity && len(q.waiters) > 0 {
+		w := heap.Pop(&q.waiters).(*waiter)
+		q.running++
+		close(w.ready)
+	}
+}
+
+// SetCapacity changes the number of slots. Raising it hands the new slots to waiters right away;
+// lowering it lets running jobs finish and only hands out slots once below the new capacity.
+func (q *Queue) SetCapacity(capacity int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.capacity = capacity
+	q.dispatch()
+}
+
+// Stats returns the current queue counters
+func (q *Queue) Stats() QueueStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return QueueStats{
+		Running:  q.running,
+		Queued:   len(q.waiters),
+		Capacity: q.capacity,
+		MaxDepth: q.maxDepth,
+	}
+}
+
+// Full reports whether a new job would be rejected
+func (q *Queue) Full() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.maxDepth > 0 && q.running >= q.capacity && len(q.waiters) >= q.maxDepth
+}
+
+// waiterHeap implements heap.Interface ordered by priority, then arrival
+type waiterHeap []*waiter
+
+func (h waiterHeap) Len() int { return len(h) }
+
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waiterHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waiterHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return w
+}
@@ -0,0 +1,111 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueuePriorityOrder(t *testing.T) {
+	queue := NewQueue(1, 0)
+	if err := queue.Acquire(context.Background(), 0); err != nil {
+		t.Fatalf("Failed to acquire free slot: %v", err)
+	}
+
+	order := make(chan int, 3)
+	for i, priority := range []int{0, 5, -5} {
+		priority := priority
+		go func() {
+			if err := queue.Acquire(context.Background(), priority); err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			order <- priority
+			queue.Release()
+		}()
+		waitForQueued(t, queue, i+1)
+	}
+
+	queue.Release()
+	for _, expected := range []int{5, 0, -5} {
+		select {
+		case got := <-order:
+			if got != expected {
+				t.Fatalf("Expected priority %d to run next, got %d", expected, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for queued job")
+		}
+	}
+}
+
+func TestQueueBackpressure(t *testing.T) {
+	queue := NewQueue(1, 1)
+	if err := queue.Acquire(context.Background(), 0); err != nil {
+		t.Fatalf("Failed to acquire free slot: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- queue.Acquire(ctx, 0) }()
+	waitForQueued(t, queue, 1)
+
+	if !queue.Full() {
+		t.Error("Expected queue to report full")
+	}
+	if err := queue.Acquire(context.Background(), 0); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+
+	// A cancelled waiter leaves the queue without taking a slot
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	stats := queue.Stats()
+	if stats.Running != 1 || stats.Queued != 0 {
+		t.Errorf("Expected 1 running and 0 queued, got %+v", stats)
+	}
+}
+
+// waitForQueued waits until the queue has n waiters
+func waitForQueued(t *testing.T, queue *Queue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for queue.Stats().Queued < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d queued jobs", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueSetCapacity(t *testing.T) {
+	queue := NewQueue(1, 0)
+	if err := queue.Acquire(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if err := queue.Acquire(context.Background(), 0); err == nil {
+			close(acquired)
+		}
+	}()
+	waitForQueued(t, queue, 1)
+
+	queue.SetCapacity(2)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiter did not get the added slot")
+	}
+
+	queue.SetCapacity(1)
+	queue.Release()
+	if stats := queue.Stats(); stats.Running != 1 || stats.Capacity != 1 {
+		t.Errorf("stats = %+v, want 1 running with capacity 1", stats)
+	}
+}
@@ -0,0 +1,88 @@
+package job
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// reapGracePeriod protects boxes that were just initialized or written from being reaped
+// while a job is still setting them up
+const reapGracePeriod = time.Minute
+
+// Reap cleans up on-demand boxes that no job owns, such as those left behind by a crashed job
+// or a previous server instance. Boxes touched within the grace period are skipped. It returns
+// the number of boxes reaped.
+func (p *BoxPool) Reap(grace time.Duration) int {
+	cutoff := time.Now().Add(-grace)
+
+	p.mutex.Lock()
+	boxRoot := p.boxRoot
+	closed := p.closed
+	p.mutex.Unlock()
+	if closed || boxRoot == "" {
+		return 0
+	}
+
+	// Collect candidate IDs with their last modification times
+	orphans := map[int]time.Time{}
+	entries, err := os.ReadDir(boxRoot)
+	if err != nil {
+		return 0
+	}
+	for _, entry := range entries {
+		id, err := strconv.Atoi(entry.Name())
+		if err != nil || id < 0 || id >= MaxBoxID {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		orphans[id] = info.ModTime()
+	}
+
+	reaped := 0
+	for id, modified := range orphans {
+		// Pooled boxes are wiped on every recycle, so only on-demand IDs can leak
+		if p.owns(id) || modified.After(cutoff) {
+			continue
+		}
+
+		p.mutex.Lock()
+		active := p.active[id]
+		if !active {
+			// Hold the ID while cleaning so it is not handed out mid-cleanup
+			p.active[id] = true
+		}
+		p.mutex.Unlock()
+		if active {
+			continue
+		}
+
+		if err := p.cleanupBox(id); err != nil {
+			p.logger.WithError(err).Warnf("Failed to reap orphaned box %d", id)
+		} else {
+			reaped++
+		}
+		p.setActive(id, false)
+	}
+
+	if reaped > 0 {
+		p.reaped.Add(int64(reaped))
+		p.logger.Infof("Reaped %d orphaned isolate boxes", reaped)
+	}
+	return reaped
+}
+
+// reapBoxes periodically cleans up orphaned sandbox boxes
+func (m *Manager) reapBoxes(interval time.Duration) {
+	m.sandbox.Reap(reapGracePeriod)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.sandbox.Reap(reapGracePeriod)
+	}
+}
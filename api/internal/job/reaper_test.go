@@ -0,0 +1,51 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBoxPoolReap(t *testing.T) {
+	boxRoot := filepath.Join(t.TempDir(), "isolate")
+
+	old := time.Now().Add(-time.Hour)
+	box := func(name string, modified time.Time) {
+		t.Helper()
+		dir := filepath.Join(boxRoot, name)
+		if err := os.MkdirAll(filepath.Join(dir, "box"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	box("3", old)         // pooled box, wiped on recycle
+	box("20", old)        // orphaned box
+	box("21", old)        // orphaned box
+	box("22", old)        // box still owned by a job
+	box("23", time.Now()) // box that was just initialized
+	box("notabox", old)   // unrelated entry
+
+	pool := &BoxPool{
+		isolatePath: "true",
+		size:        16,
+		active:      map[int]bool{22: true},
+		boxRoot:     boxRoot,
+		logger:      logrus.NewEntry(logrus.New()),
+	}
+
+	if reaped := pool.Reap(reapGracePeriod); reaped != 2 {
+		t.Errorf("Reap() = %d, want 2", reaped)
+	}
+	if !pool.active[22] || len(pool.active) != 1 {
+		t.Errorf("active = %v, want only box 22", pool.active)
+	}
+	if pool.Stats().Reaped != 2 {
+		t.Errorf("Stats().Reaped = %d, want 2", pool.Stats().Reaped)
+	}
+}
@@ -0,0 +1,94 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coderunr/api/internal/history"
+	"github.com/coderunr/api/internal/types"
+)
+
+// ErrNoSubmission is returned when replaying a history record that has no stored submission
+var ErrNoSubmission = errors.New("history record has no stored submission, enable history_submissions to record them")
+
+// Replay is a past execution run again, with both outcomes side by side
+type Replay struct {
+	Original *history.Record `json:"original"`
+	// Replay is the new execution's history record. Its output is truncated like the
+	// original's so the two compare fairly; Result holds the complete output.
+	Replay *history.Record        `json:"replay"`
+	Result *types.ExecutionResult `json:"result"`
+	// Changed names the fields that differ: version, status, compile_code, run_code, signal,
+	// error, stdout or stderr
+	Changed []string `json:"changed"`
+}
+
+// ReplayRequest returns the submission of original to run again, pinned to version, or to
+// the original version when version is empty
+func ReplayRequest(original *history.Record, version string) (*types.JobRequest, error) {
+	if original.Submission == nil {
+		return nil, ErrNoSubmission
+	}
+
+	request := *original.Submission
+	if version != "" {
+		request.Version = version
+	}
+	return &request, nil
+}
+
+// Replay runs request, built by ReplayRequest, on runtime and compares the outcome with original.
+// The replay is recorded in the history like any other execution.
+func (m *Manager) Replay(ctx context.Context, original *history.Record, runtime *types.Runtime,
+	request *types.JobRequest) (*Replay, error) {
+	if m.history == nil {
+		return nil, fmt.Errorf("execution history is disabled")
+	}
+
+	j := m.NewJob(ctx, runtime, request)
+	j.skipResultCache = true
+	result, err := j.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if result.Run == nil && result.Compile != nil {
+		result.Run = result.Compile
+	}
+
+	replay := *j.recorded
+	replay.Submission = nil
+	return &Replay{
+		Original: original,
+		Replay:   &replay,
+		Result:   result,
+		Changed:  changedFields(original, &replay),
+	}, nil
+}
+
+// changedFields lists the outcome fields that differ between two records
+func changedFields(a, b *history.Record) []string {
+	changed := []string{}
+	intEqual := func(x, y *int) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+	}
+
+	for _, field := range []struct {
+		name  string
+		equal bool
+	}{
+		{"version", a.Version == b.Version},
+		{"status", a.Status == b.Status},
+		{"compile_code", intEqual(a.CompileCode, b.CompileCode)},
+		{"run_code", intEqual(a.RunCode, b.RunCode)},
+		{"signal", a.Signal == b.Signal},
+		{"error", a.Error == b.Error},
+		{"stdout", a.Stdout == b.Stdout},
+		{"stderr", a.Stderr == b.Stderr},
+	} {
+		if !field.equal {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,43 @@
+package job
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/coderunr/api/internal/history"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestReplayRequest(t *testing.T) {
+	original := &history.Record{Submission: &types.JobRequest{Language: "python", Version: "3.11.0"}}
+
+	request, err := ReplayRequest(original, "")
+	if err != nil || request.Version != "3.11.0" {
+		t.Fatalf("ReplayRequest() = %+v, %v, want the original version", request, err)
+	}
+	request, err = ReplayRequest(original, "3.12.x")
+	if err != nil || request.Version != "3.12.x" {
+		t.Fatalf("ReplayRequest() = %+v, %v, want the requested version", request, err)
+	}
+	if original.Submission.Version != "3.11.0" {
+		t.Error("ReplayRequest() modified the stored submission")
+	}
+
+	if _, err := ReplayRequest(&history.Record{}, ""); !errors.Is(err, ErrNoSubmission) {
+		t.Errorf("ReplayRequest() without submission error = %v, want ErrNoSubmission", err)
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	zero, one := 0, 1
+	a := &history.Record{Version: "3.11.0", Status: history.StatusSuccess, RunCode: &zero, Stdout: "ok\n"}
+	b := &history.Record{Version: "3.12.0", Status: history.StatusRuntimeError, RunCode: &one, Stdout: "ok\n"}
+
+	if got := strings.Join(changedFields(a, b), ","); got != "version,status,run_code" {
+		t.Errorf("changedFields() = %s, want version,status,run_code", got)
+	}
+	if got := changedFields(a, a); len(got) != 0 {
+		t.Errorf("changedFields() of identical records = %v, want none", got)
+	}
+}
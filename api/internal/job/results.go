@@ -0,0 +1,169 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// ResultCache keeps recent execution results keyed by a hash of the submission, so identical
+// submissions within the TTL are answered without running again. Entries live in memory only.
+type ResultCache struct {
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*resultEntry
+	mutex      sync.Mutex
+}
+
+// resultEntry is a single cached execution result
+type resultEntry struct {
+	result  types.ExecutionResult
+	created time.Time
+}
+
+// NewResultCache creates a result cache holding up to maxEntries results for ttl
+func NewResultCache(ttl time.Duration, maxEntries int) *ResultCache {
+	return &ResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*resultEntry),
+	}
+}
+
+// resultCacheKey hashes everything that can influence the result of a job. It returns "" when
+// the cache is disabled or the job's result must not be reused: jobs whose outcome depends on
+// more than the submission (network access, mounted datasets, collected output files, stdin
+// fetched from a URL or fixture that may change).
+func (j *Job) resultCacheKey() string {
+	if j.manager.results == nil || j.skipResultCache || j.Network || len(j.Mounts) > 0 || len(j.OutputFiles) > 0 ||
+		j.StdinURL != "" || j.StdinFixture != "" {
+		return ""
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", j.Runtime.Language, j.Runtime.Version.String(), j.Runtime.PkgDir)
+	fmt.Fprintf(h, "entrypoint\x00%s\x00", j.entrypoint())
+	for _, name := range sortedKeys(j.Env) {
+		fmt.Fprintf(h, "env\x00%s\x00%s\x00", name, j.Env[name])
+	}
+	for _, file := range j.Files {
+		fmt.Fprintf(h, "file\x00%s\x00%s\x00%d\x00", file.Name, file.Encoding, len(file.Content))
+		io.WriteString(h, file.Content)
+	}
+	fmt.Fprintf(h, "archive\x00%d\x00", len(j.Archive))
+	io.WriteString(h, j.Archive)
+	fmt.Fprintf(h, "stdin\x00%d\x00", len(j.Stdin))
+	io.WriteString(h, j.Stdin)
+	for _, arg := range j.Args {
+		fmt.Fprintf(h, "arg\x00%d\x00%s", len(arg), arg)
+	}
+	for _, flag := range j.CompileFlags {
+		fmt.Fprintf(h, "compile_flag\x00%s\x00", flag)
+	}
+	for _, flag := range j.RunFlags {
+		fmt.Fprintf(h, "run_flag\x00%s\x00", flag)
+	}
+	fmt.Fprintf(h, "limits\x00%d\x00%d\x00%d\x00%d\x00%d\x00%d\x00%d\x00%d\x00%s\x00",
+		j.Timeouts.Compile, j.Timeouts.Run, j.CPUTimes.Compile, j.CPUTimes.Run,
+		j.MemoryLimits.Compile, j.MemoryLimits.Run, j.DiskQuota, j.OutputMaxSize, j.OutputLimitAction)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns a copy of the cached result for key
+func (c *ResultCache) Get(key string) (*types.ExecutionResult, bool) {
+	if c == nil || key == "" {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(entry.created) > c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return copyResult(&entry.result), true
+}
+
+// Put stores a copy of result under key. When the cache is full, expired entries are dropped
+// and then the oldest one.
+func (c *ResultCache) Put(key string, result *types.ExecutionResult) {
+	if c == nil || key == "" {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictLocked(now)
+		if len(c.entries) >= c.maxEntries {
+			var oldest string
+			for k, entry := range c.entries {
+				if oldest == "" || entry.created.Before(c.entries[oldest].created) {
+					oldest = k
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = &resultEntry{result: *copyResult(result), created: now}
+}
+
+// Evict drops expired entries
+func (c *ResultCache) Evict(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evictLocked(now)
+}
+
+func (c *ResultCache) evictLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.created) > c.ttl {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns the number of cached results
+func (c *ResultCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+// copyResult copies result and its stage results, so callers adjusting the response (such as
+// the compile result standing in for a missing run result) do not change the cached one
+func copyResult(result *types.ExecutionResult) *types.ExecutionResult {
+	copied := *result
+	if result.Compile != nil {
+		compile := *result.Compile
+		copied.Compile = &compile
+	}
+	if result.Run != nil {
+		run := *result.Run
+		copied.Run = &run
+	}
+	return &copied
+}
+
+// cacheableOutcome reports whether a result may be reused. Stages cut short by the server's
+// load or state rather than by the program are not.
+func cacheableOutcome(result *types.ExecutionResult) bool {
+	for _, stage := range []*types.StageResult{result.Compile, result.Run} {
+		if stage != nil && (stage.Outcome == types.OutcomeTimeout || stage.Outcome == types.OutcomeSandboxError) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestResultCache(t *testing.T) {
+	c := NewResultCache(time.Minute, 2)
+
+	c.Put("a", &types.ExecutionResult{Run: &types.StageResult{Stdout: "a"}})
+	got, ok := c.Get("a")
+	if !ok || got.Run.Stdout != "a" {
+		t.Fatalf("Expected cached result, got %+v %v", got, ok)
+	}
+	got.Run.Stdout = "changed"
+	if again, _ := c.Get("a"); again.Run.Stdout != "a" {
+		t.Error("Expected callers to receive a copy of the cached result")
+	}
+
+	c.Put("b", &types.ExecutionResult{})
+	c.Put("c", &types.ExecutionResult{})
+	if _, ok := c.Get("a"); ok || c.Len() != 2 {
+		t.Errorf("Expected the oldest entry to be dropped when full, have %d entries", c.Len())
+	}
+
+	c.Evict(time.Now().Add(2 * time.Minute))
+	if c.Len() != 0 {
+		t.Errorf("Expected expired entries to be evicted, have %d", c.Len())
+	}
+	if _, ok := c.Get(""); ok {
+		t.Error("Expected no result for an uncacheable job")
+	}
+}
+
+func TestExecuteResultCache(t *testing.T) {
+	m, rt := newLocalTestManager(t, "date +%s%N\n")
+	m.results = NewResultCache(time.Minute, 10)
+	m.config.DisableNetworking = true
+
+	execute := func(stdin string) *types.ExecutionResult {
+		t.Helper()
+		j := m.NewJob(context.Background(), rt, &types.JobRequest{
+			Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+			Stdin: stdin,
+		})
+		result, err := j.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return result
+	}
+
+	first := execute("")
+	second := execute("")
+	if first.Cached || !second.Cached || second.Run.Stdout != first.Run.Stdout {
+		t.Errorf("Expected the identical submission to be answered from the cache, got %+v then %+v", first.Run, second.Run)
+	}
+	if other := execute("input"); other.Cached {
+		t.Error("Expected a submission with different stdin to run")
+	}
+
+	m.config.DisableNetworking = false
+	if networked := execute(""); networked.Cached {
+		t.Error("Expected a job with network access to run")
+	}
+}
@@ -0,0 +1,132 @@
+package job
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/coderunr/api/internal/history"
+)
+
+// RuntimeUsage reports how much one runtime has been used since startup
+type RuntimeUsage struct {
+	Language string `json:"language"`
+	Version  string `json:"version"`
+	// Installed is false for runtimes that ran jobs but have since been uninstalled
+	Installed  bool   `json:"installed"`
+	Executions uint64 `json:"executions"`
+	// Failed counts executions that did not succeed: compile or runtime errors and server errors
+	Failed            uint64 `json:"failed"`
+	AverageDurationMs int64  `json:"average_duration_ms"`
+	// LastUsed is when an execution on the runtime last finished, unset if none has
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// runtimeCount accumulates the executions of one runtime
+type runtimeCount struct {
+	executions uint64
+	failed     uint64
+	duration   time.Duration
+	lastUsed   time.Time
+}
+
+// RuntimeCounters counts executions by language and version
+type RuntimeCounters struct {
+	counts map[runtimeKey]*runtimeCount
+	mutex  sync.Mutex
+}
+
+// runtimeKey identifies a runtime by language and version
+type runtimeKey struct {
+	language string
+	version  string
+}
+
+// NewRuntimeCounters returns empty runtime counters
+func NewRuntimeCounters() *RuntimeCounters {
+	return &RuntimeCounters{counts: make(map[runtimeKey]*runtimeCount)}
+}
+
+// Count records an execution on language and version that took duration and ended with the
+// history status
+func (c *RuntimeCounters) Count(language, version string, duration time.Duration, status string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := runtimeKey{language, version}
+	count, ok := c.counts[key]
+	if !ok {
+		count = &runtimeCount{}
+		c.counts[key] = count
+	}
+	count.executions++
+	if status != history.StatusSuccess {
+		count.failed++
+	}
+	count.duration += duration
+	count.lastUsed = time.Now()
+}
+
+// each calls fn for every counted runtime while holding the lock
+func (c *RuntimeCounters) each(fn func(runtimeKey, *runtimeCount)) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, count := range c.counts {
+		fn(key, count)
+	}
+}
+
+// RuntimeUsage returns the usage of every loaded runtime, including those never used, and of
+// runtimes that ran jobs before being uninstalled, sorted by language and newest version first
+func (m *Manager) RuntimeUsage() []RuntimeUsage {
+	usage := make(map[runtimeKey]*RuntimeUsage)
+	for _, rt := range m.runtimes.GetRuntimes() {
+		key := runtimeKey{rt.Language, rt.Version.String()}
+		usage[key] = &RuntimeUsage{Language: key.language, Version: key.version, Installed: true}
+	}
+
+	m.runtimeCounts.each(func(key runtimeKey, count *runtimeCount) {
+		u, ok := usage[key]
+		if !ok {
+			u = &RuntimeUsage{Language: key.language, Version: key.version}
+			usage[key] = u
+		}
+		lastUsed := count.lastUsed
+		u.Executions = count.executions
+		u.Failed = count.failed
+		u.AverageDurationMs = (count.duration / time.Duration(count.executions)).Milliseconds()
+		u.LastUsed = &lastUsed
+	})
+
+	list := make([]RuntimeUsage, 0, len(usage))
+	for _, u := range usage {
+		list = append(list, *u)
+	}
+	sort.Slice(list, func(i, k int) bool {
+		if list[i].Language != list[k].Language {
+			return list[i].Language < list[k].Language
+		}
+		return newerVersion(list[i].Version, list[k].Version)
+	})
+	return list
+}
+
+// newerVersion reports whether semver a is greater than b, comparing strings when either
+// does not parse
+func newerVersion(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return va.GreaterThan(vb)
+}
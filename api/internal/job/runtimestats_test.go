@@ -0,0 +1,60 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/history"
+	"github.com/coderunr/api/internal/runtime"
+)
+
+func TestRuntimeUsage(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, version := range []string{"1.2.0", "1.10.0"} {
+		packageDir := filepath.Join(dataDir, "packages", "statslang", version)
+		if err := os.MkdirAll(packageDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		info := `{"language":"statslang","version":"` + version + `"}`
+		if err := os.WriteFile(filepath.Join(packageDir, "pkg-info.json"), []byte(info), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(packageDir, ".ppman-installed"), []byte("0"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runtimes := runtime.NewManager(&config.Config{DataDirectory: dataDir})
+	if err := runtimes.LoadPackages(); err != nil {
+		t.Fatalf("Failed to load packages: %v", err)
+	}
+
+	m := &Manager{config: &config.Config{}, runtimes: runtimes, runtimeCounts: NewRuntimeCounters()}
+	m.runtimeCounts.Count("statslang", "1.10.0", 100*time.Millisecond, history.StatusSuccess)
+	m.runtimeCounts.Count("statslang", "1.10.0", 300*time.Millisecond, history.StatusRuntimeError)
+	m.runtimeCounts.Count("statslang", "0.9.0", time.Second, history.StatusSuccess)
+
+	var got []RuntimeUsage
+	for _, u := range m.RuntimeUsage() {
+		if u.Language == "statslang" {
+			got = append(got, u)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 statslang runtimes, got %+v", got)
+	}
+
+	newest, unused, removed := got[0], got[1], got[2]
+	if newest.Version != "1.10.0" || !newest.Installed || newest.Executions != 2 || newest.Failed != 1 ||
+		newest.AverageDurationMs != 200 || newest.LastUsed == nil {
+		t.Errorf("Unexpected usage of 1.10.0: %+v", newest)
+	}
+	if unused.Version != "1.2.0" || !unused.Installed || unused.Executions != 0 || unused.LastUsed != nil {
+		t.Errorf("Unexpected usage of unused 1.2.0: %+v", unused)
+	}
+	if removed.Version != "0.9.0" || removed.Installed || removed.Executions != 1 {
+		t.Errorf("Unexpected usage of uninstalled 0.9.0: %+v", removed)
+	}
+}
@@ -0,0 +1,250 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+// Sandbox is a backend that provides the boxes jobs run in. A job holds a box from Acquire
+// until Release; the box's Dir is the host directory the stages see as /box, with the
+// submission in Dir/submission.
+type Sandbox interface {
+	// Name identifies the backend, the value of sandbox_backend
+	Name() string
+	// Acquire returns a ready box whose disk use is limited to quota bytes (0 for no quota)
+	Acquire(quota int64) (*types.IsolateBox, error)
+	// Command returns the unstarted command running the package's stage script for j in box
+	// with args, stopped after timeout or cpuTime and limited to memoryLimit bytes (-1 for none)
+	Command(ctx context.Context, j *Job, box *types.IsolateBox, stage string, args []string,
+		timeout, cpuTime time.Duration, memoryLimit int64) *exec.Cmd
+	// Usage returns the resources used by the last command run in box once it has exited,
+	// or nil when the backend does not measure them
+	Usage(box *types.IsolateBox) (*StageUsage, error)
+	// Sample returns the memory and CPU time used so far by the command running in box
+	Sample(box *types.IsolateBox) (memory int64, cpuTime time.Duration, err error)
+	// Signal sends sig to the processes of the command running in box, the program and those
+	// it started, rather than to a wrapper around them
+	Signal(box *types.IsolateBox, sig syscall.Signal) error
+	// KillRemaining kills the processes the last command in box left behind, such as background
+	// children, and returns once none is left
+	KillRemaining(box *types.IsolateBox) error
+	// Release cleans up a box after use
+	Release(box *types.IsolateBox) error
+	// Reap cleans up boxes no job owns that were last touched before grace ago, such as those
+	// left behind by a crash, and returns how many it removed
+	Reap(grace time.Duration) int
+	// Stats returns the backend's box counters
+	Stats() BoxPoolStats
+	// Close cleans up every box the backend keeps ready
+	Close()
+}
+
+// StageUsage is what a sandbox measured about a finished stage
+type StageUsage struct {
+	Memory    int64
+	OOMKilled bool
+	ExitCode  int
+	Signal    string
+	// Message and Status follow isolate's meta file: Status is "RE", "SG", "TO" or "XX"
+	// for a run that did not end normally
+	Message  string
+	Status   string
+	CPUTime  time.Duration
+	WallTime time.Duration
+}
+
+// NewSandbox creates the sandbox backend selected by sandbox_backend
+func NewSandbox(cfg *config.Config) (Sandbox, error) {
+	switch cfg.SandboxBackend {
+	case "", "isolate":
+		return newIsolateSandbox(cfg), nil
+	case "unsafe_local":
+		return newLocalSandbox(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown sandbox backend %q", cfg.SandboxBackend)
+	}
+}
+
+// How long KillRemaining waits for killed processes to leave a box's cgroup, and how often it
+// checks
+const (
+	processExitTimeout = 2 * time.Second
+	processExitPoll    = 10 * time.Millisecond
+)
+
+// isolateSandbox runs stages with isolate, taking boxes from a pool of initialized ones
+type isolateSandbox struct {
+	config *config.Config
+	pool   *BoxPool
+}
+
+// newIsolateSandbox creates the isolate backend and starts filling its box pool
+func newIsolateSandbox(cfg *config.Config) *isolateSandbox {
+	return &isolateSandbox{
+		config: cfg,
+		pool:   NewBoxPool(cfg.IsolatePath, cfg.BoxPoolSize, cfg.DiskQuota, cfg.DiskQuotaInodes),
+	}
+}
+
+func (s *isolateSandbox) Name() string {
+	return "isolate"
+}
+
+func (s *isolateSandbox) Acquire(quota int64) (*types.IsolateBox, error) {
+	return s.pool.Get(quota)
+}
+
+func (s *isolateSandbox) Command(ctx context.Context, j *Job, box *types.IsolateBox, stage string, args []string,
+	timeout, cpuTime time.Duration, memoryLimit int64) *exec.Cmd {
+	return j.isolateCommand(ctx, j.buildIsolateArgs(box, stage, args, timeout, cpuTime, memoryLimit))
+}
+
+func (s *isolateSandbox) Usage(box *types.IsolateBox) (*StageUsage, error) {
+	return parseIsolateMetadata(box.MetadataPath)
+}
+
+// Sample reads the box's cgroup, which exists only while isolate runs a command in it
+func (s *isolateSandbox) Sample(box *types.IsolateBox) (int64, time.Duration, error) {
+	root, err := resolveCgroupRoot(s.config.IsolateCgroupRoot)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readCgroupStats(filepath.Join(root, fmt.Sprintf("box-%d", box.ID)))
+}
+
+// Signal signals every process in the box's cgroup. The isolate process itself stays outside it
+// and would only tear the box down.
+func (s *isolateSandbox) Signal(box *types.IsolateBox, sig syscall.Signal) error {
+	root, err := resolveCgroupRoot(s.config.IsolateCgroupRoot)
+	if err != nil {
+		return err
+	}
+	pids, err := readCgroupProcs(filepath.Join(root, fmt.Sprintf("box-%d", box.ID)))
+	if err != nil {
+		return err
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("no running process")
+	}
+	for _, pid := range pids {
+		if err := signalProcess(pid, sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KillRemaining kills the processes left in the box's cgroup until it is empty. A cgroup that
+// isolate has already removed has nothing left in it.
+func (s *isolateSandbox) KillRemaining(box *types.IsolateBox) error {
+	root, err := resolveCgroupRoot(s.config.IsolateCgroupRoot)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(root, fmt.Sprintf("box-%d", box.ID))
+	deadline := time.Now().Add(processExitTimeout)
+	for {
+		pids, err := readCgroupProcs(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(pids) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d processes still running in box %d after SIGKILL", len(pids), box.ID)
+		}
+		for _, pid := range pids {
+			if err := signalProcess(pid, syscall.SIGKILL); err != nil {
+				return err
+			}
+		}
+		time.Sleep(processExitPoll)
+	}
+}
+
+func (s *isolateSandbox) Release(box *types.IsolateBox) error {
+	return s.pool.Release(box)
+}
+
+func (s *isolateSandbox) Reap(grace time.Duration) int {
+	return s.pool.Reap(grace)
+}
+
+func (s *isolateSandbox) Stats() BoxPoolStats {
+	return s.pool.Stats()
+}
+
+func (s *isolateSandbox) Close() {
+	s.pool.Close()
+}
+
+// parseIsolateMetadata parses the meta file isolate writes after a run
+func parseIsolateMetadata(metadataPath string) (*StageUsage, error) {
+	content, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &StageUsage{}
+	lines := strings.Split(string(content), "\n")
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key, value := parts[0], parts[1]
+		switch key {
+		case "cg-mem":
+			if mem, err := strconv.ParseInt(value, 10, 64); err == nil {
+				metadata.Memory = mem * 1000
+			}
+		case "exitcode":
+			if code, err := strconv.Atoi(value); err == nil {
+				metadata.ExitCode = code
+			}
+		case "exitsig":
+			if sig, err := strconv.Atoi(value); err == nil {
+				metadata.Signal = signalToString(sig)
+			}
+		case "cg-oom-killed":
+			metadata.OOMKilled = value == "1"
+		case "message":
+			metadata.Message = value
+		case "status":
+			metadata.Status = value
+		case "time":
+			if t, err := strconv.ParseFloat(value, 64); err == nil {
+				metadata.CPUTime = time.Duration(t * float64(time.Second))
+			}
+		case "time-wall":
+			if t, err := strconv.ParseFloat(value, 64); err == nil {
+				metadata.WallTime = time.Duration(t * float64(time.Second))
+			}
+		}
+	}
+
+	return metadata, nil
+}
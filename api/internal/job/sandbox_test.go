@@ -0,0 +1,71 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestNewSandbox(t *testing.T) {
+	sandbox, err := NewSandbox(&config.Config{SandboxBackend: "isolate", IsolatePath: "/nonexistent/isolate"})
+	if err != nil {
+		t.Fatalf("NewSandbox() error = %v", err)
+	}
+	defer sandbox.Close()
+	if sandbox.Name() != "isolate" {
+		t.Errorf("Name() = %s, want isolate", sandbox.Name())
+	}
+
+	if _, err := NewSandbox(&config.Config{SandboxBackend: "chroot"}); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestParseIsolateMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta")
+	meta := "time:0.120\ntime-wall:0.350\ncg-mem:2048\ncg-oom-killed:1\nexitsig:9\nstatus:SG\nmessage:Caught fatal signal 9\n"
+	if err := os.WriteFile(path, []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := parseIsolateMetadata(path)
+	if err != nil {
+		t.Fatalf("parseIsolateMetadata() error = %v", err)
+	}
+	want := StageUsage{
+		Memory:    2048000,
+		OOMKilled: true,
+		Signal:    "SIGKILL",
+		Message:   "Caught fatal signal 9",
+		Status:    "SG",
+		CPUTime:   120 * time.Millisecond,
+		WallTime:  350 * time.Millisecond,
+	}
+	if *usage != want {
+		t.Errorf("parseIsolateMetadata() = %+v, want %+v", *usage, want)
+	}
+}
+
+func TestIsolateKillRemaining(t *testing.T) {
+	root := t.TempDir()
+	s := &isolateSandbox{config: &config.Config{IsolateCgroupRoot: root}}
+
+	if err := s.KillRemaining(&types.IsolateBox{ID: 1}); err != nil {
+		t.Errorf("Expected a removed cgroup to have nothing left, got %v", err)
+	}
+
+	dir := filepath.Join(root, "box-2")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.KillRemaining(&types.IsolateBox{ID: 2}); err != nil {
+		t.Errorf("Expected an empty cgroup to have nothing left, got %v", err)
+	}
+}
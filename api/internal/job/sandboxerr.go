@@ -0,0 +1,66 @@
+package job
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// maxSandboxDetail caps the isolate diagnostics kept in a SandboxError, keeping the end where
+// isolate reports why it gave up
+const maxSandboxDetail = 2048
+
+// SandboxError is returned when the sandbox itself failed, such as isolate finding no usable
+// cgroup or lacking permissions, rather than the program it ran. Detail is isolate's own
+// diagnostic, kept apart from program output.
+type SandboxError struct {
+	// Stage is "init" when the box could not be created, otherwise the stage isolate failed in
+	Stage  string `json:"stage"`
+	Detail string `json:"detail"`
+	Err    error  `json:"-"`
+}
+
+func (e *SandboxError) Error() string {
+	message := fmt.Sprintf("sandbox failed in %s stage", e.Stage)
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	if e.Err != nil {
+		message += fmt.Sprintf(" (%v)", e.Err)
+	}
+	return message
+}
+
+func (e *SandboxError) Unwrap() error {
+	return e.Err
+}
+
+// sandboxFailure returns a SandboxError for a stage isolate failed in (status XX), with the meta
+// file's message and what isolate wrote to stderr, or nil for a stage whose program ran. isolate
+// fails before the program starts, so the stage's stderr is its own.
+func sandboxFailure(stage string, result *types.StageResult) error {
+	if result.Outcome != types.OutcomeSandboxError {
+		return nil
+	}
+	return &SandboxError{Stage: stage, Detail: sandboxDetail(result.Message, result.Stderr)}
+}
+
+// sandboxDetail joins isolate's message and stderr into one diagnostic, dropping the stderr line
+// that repeats the message
+func sandboxDetail(message, stderr string) string {
+	message = strings.TrimSpace(message)
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) > maxSandboxDetail {
+		stderr = "..." + stderr[len(stderr)-maxSandboxDetail:]
+	}
+
+	switch {
+	case stderr == "" || strings.Contains(message, stderr):
+		return message
+	case message == "" || strings.Contains(stderr, message):
+		return stderr
+	default:
+		return message + ": " + stderr
+	}
+}
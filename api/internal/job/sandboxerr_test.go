@@ -0,0 +1,49 @@
+package job
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestSandboxFailure(t *testing.T) {
+	if err := sandboxFailure("run", &types.StageResult{Outcome: types.OutcomeRuntimeError, Stderr: "boom"}); err != nil {
+		t.Errorf("sandboxFailure() for a program failure = %v, want nil", err)
+	}
+
+	err := sandboxFailure("compile", &types.StageResult{
+		Outcome: types.OutcomeSandboxError,
+		Message: "Cannot run proxy, clone failed: Operation not permitted",
+		Stderr:  "Cannot run proxy, clone failed: Operation not permitted\n",
+	})
+	var sandboxErr *SandboxError
+	if !errors.As(err, &sandboxErr) {
+		t.Fatalf("sandboxFailure() = %v, want a SandboxError", err)
+	}
+	if sandboxErr.Stage != "compile" || sandboxErr.Detail != "Cannot run proxy, clone failed: Operation not permitted" {
+		t.Errorf("SandboxError = %+v, want the message once", sandboxErr)
+	}
+}
+
+func TestSandboxDetail(t *testing.T) {
+	tests := []struct {
+		message, stderr, want string
+	}{
+		{"", "", ""},
+		{"Internal error", "", "Internal error"},
+		{"", "mount failed\n", "mount failed"},
+		{"Internal error", "cgroup root not found", "Internal error: cgroup root not found"},
+	}
+	for _, tt := range tests {
+		if got := sandboxDetail(tt.message, tt.stderr); got != tt.want {
+			t.Errorf("sandboxDetail(%q, %q) = %q, want %q", tt.message, tt.stderr, got, tt.want)
+		}
+	}
+
+	long := sandboxDetail("", strings.Repeat("x", 3*maxSandboxDetail)+"the cause")
+	if !strings.HasSuffix(long, "the cause") || len(long) > maxSandboxDetail+3 {
+		t.Errorf("sandboxDetail() of long stderr kept %d bytes, want the last %d", len(long), maxSandboxDetail)
+	}
+}
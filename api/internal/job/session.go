@@ -0,0 +1,119 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// NewSession creates an interactive REPL job. Files are optional and output is not
+// budgeted, since the session streams until the client leaves or it goes idle.
+func (m *Manager) NewSession(ctx context.Context, runtime *types.Runtime, request *types.JobRequest) (*Job, error) {
+	if !runtime.REPL {
+		return nil, fmt.Errorf("%s-%s does not support interactive sessions",
+			runtime.Language, runtime.Version.String())
+	}
+
+	j := m.NewJob(ctx, runtime, request)
+	j.outputBudget = 0
+	j.touch()
+	return j, nil
+}
+
+// ExecuteSession runs the runtime's repl script, streaming stdin and output until the
+// process exits, the context is cancelled or no activity is seen for the idle timeout
+func (j *Job) ExecuteSession(ctx context.Context) error {
+	return j.executeInteractive(ctx, "repl")
+}
+
+// executeInteractive runs the package script of stage, a repl or a language server, for as
+// long as a session lasts
+func (j *Job) executeInteractive(ctx context.Context, stage string) error {
+	ctx, done, err := j.begin(ctx)
+	if err != nil {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: err})
+		close(j.EventChannel)
+		return err
+	}
+	defer done()
+	defer j.cleanup()
+	defer close(j.EventChannel)
+
+	if err := j.waitForSlot(ctx); err != nil {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("failed to acquire job slot: %w", err)})
+		return fmt.Errorf("failed to acquire job slot: %w", err)
+	}
+	defer j.releaseSlot()
+
+	j.logger.Infof("Starting interactive %s session", stage)
+
+	box, err := j.prime(ctx)
+	if err != nil {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("failed to prime job: %w", err)})
+		return fmt.Errorf("failed to prime job: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var idle atomic.Bool
+	go j.watchIdle(ctx, j.manager.config.SessionIdleTimeout, func() {
+		idle.Store(true)
+		cancel()
+	})
+
+	j.sendEvent(types.StreamEvent{Type: "stage_start", Stage: stage})
+
+	cfg := j.manager.config
+	result, err := j.safeCallStream(ctx, box, stage, j.Args,
+		cfg.SessionTimeout, cfg.SessionCPUTime, j.MemoryLimits.Run)
+	if err != nil {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("session failed: %w", err)})
+		return fmt.Errorf("session failed: %w", err)
+	}
+
+	if idle.Load() {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("session closed after %s of inactivity",
+			cfg.SessionIdleTimeout)})
+	}
+
+	code := 0
+	if result.Code != nil {
+		code = *result.Code
+	}
+	j.sendEvent(types.StreamEvent{Type: "stage_end", Stage: stage, Code: code})
+
+	j.State = types.JobStateExecuted
+	return nil
+}
+
+// watchIdle calls onIdle once no activity has been recorded for timeout
+func (j *Job) watchIdle(ctx context.Context, timeout time.Duration, onIdle func()) {
+	interval := timeout / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if now.Sub(time.Unix(0, j.lastActivity.Load())) >= timeout {
+				j.logger.Info("Closing idle session")
+				onIdle()
+				return
+			}
+		}
+	}
+}
+
+// touch records session activity
+func (j *Job) touch() {
+	j.lastActivity.Store(time.Now().UnixNano())
+}
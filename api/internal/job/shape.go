@@ -0,0 +1,80 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// Result fields that can be omitted, by JSON name
+var (
+	resultFields = jsonFields(reflect.TypeOf(types.ExecutionResult{}))
+	stageFields  = jsonFields(reflect.TypeOf(types.StageResult{}))
+)
+
+// ValidateOmit checks that every omit entry names a result field. Entries are a top-level
+// field ("files"), a stage field applying to both stages ("output") or a stage field of one
+// stage ("compile.stdout").
+func ValidateOmit(omit []string) error {
+	for _, path := range omit {
+		stage, field, nested := strings.Cut(path, ".")
+		switch {
+		case nested && (stage == "compile" || stage == "run") && stageFields[field]:
+		case !nested && (resultFields[path] || stageFields[path]):
+		default:
+			return fmt.Errorf("omit: unknown result field %q", path)
+		}
+	}
+	return nil
+}
+
+// ShapeResult returns result with the omitted fields stripped. Without omissions the
+// result itself is returned.
+func ShapeResult(result *types.ExecutionResult, omit []string) (interface{}, error) {
+	if len(omit) == 0 {
+		return result, nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var shaped map[string]interface{}
+	if err := json.Unmarshal(data, &shaped); err != nil {
+		return nil, err
+	}
+
+	deleteStageField := func(stage, field string) {
+		if fields, ok := shaped[stage].(map[string]interface{}); ok {
+			delete(fields, field)
+		}
+	}
+	for _, path := range omit {
+		if stage, field, nested := strings.Cut(path, "."); nested {
+			deleteStageField(stage, field)
+			continue
+		}
+		if resultFields[path] {
+			delete(shaped, path)
+			continue
+		}
+		deleteStageField("compile", path)
+		deleteStageField("run", path)
+	}
+	return shaped, nil
+}
+
+// jsonFields returns the JSON names of a struct's fields
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestValidateOmit(t *testing.T) {
+	tests := []struct {
+		omit    []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"output", "compile.stdout", "run.stderr", "files", "limits"}, false},
+		{[]string{"compile"}, false},
+		{[]string{"bogus"}, true},
+		{[]string{"run.bogus"}, true},
+		{[]string{"files.name"}, true},
+		{[]string{"run.stdout.extra"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateOmit(tt.omit); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateOmit(%v) error = %v, wantErr %v", tt.omit, err, tt.wantErr)
+		}
+	}
+}
+
+func TestShapeResult(t *testing.T) {
+	code := 0
+	result := &types.ExecutionResult{
+		Language: "python",
+		Version:  "3.12.0",
+		Compile:  &types.StageResult{Stdout: "compiling", Stderr: "warning", Output: "compiling", Code: &code},
+		Run:      &types.StageResult{Stdout: "hi", Stderr: "", Output: "hi", Code: &code},
+	}
+
+	unchanged, err := ShapeResult(result, nil)
+	if err != nil || unchanged != result {
+		t.Fatalf("ShapeResult() without omissions = %v, %v, want the result itself", unchanged, err)
+	}
+
+	shaped, err := ShapeResult(result, []string{"output", "compile.stdout", "version"})
+	if err != nil {
+		t.Fatalf("ShapeResult() error = %v", err)
+	}
+	fields := shaped.(map[string]interface{})
+
+	if _, ok := fields["version"]; ok {
+		t.Error("Expected version to be omitted")
+	}
+	if fields["language"] != "python" {
+		t.Errorf("language = %v, want python", fields["language"])
+	}
+
+	keys := func(stage string) map[string]bool {
+		present := map[string]bool{}
+		for k := range fields[stage].(map[string]interface{}) {
+			if k == "stdout" || k == "stderr" || k == "output" || k == "code" {
+				present[k] = true
+			}
+		}
+		return present
+	}
+	if got, want := keys("compile"), map[string]bool{"stderr": true, "code": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("compile fields = %v, want %v", got, want)
+	}
+	if got, want := keys("run"), map[string]bool{"stdout": true, "stderr": true, "code": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("run fields = %v, want %v", got, want)
+	}
+}
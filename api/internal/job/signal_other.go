@@ -0,0 +1,42 @@
+//go:build !unix
+
+package job
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// signalNumber returns the signal with a name such as "SIGINT"; only the signals Go can
+// deliver on this platform are known
+func signalNumber(name string) (syscall.Signal, bool) {
+	sig, ok := map[string]syscall.Signal{
+		"SIGINT":  syscall.SIGINT,
+		"SIGKILL": syscall.SIGKILL,
+	}[name]
+	return sig, ok
+}
+
+// signalProcess sends sig to the process pid
+func signalProcess(pid int, sig syscall.Signal) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
+
+// signalGroup sends sig to cmd's own process, as there are no process groups
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return cmd.Process.Signal(sig)
+}
+
+// killGroup kills cmd's own process, as there are no process groups
+func killGroup(cmd *exec.Cmd) error {
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package job
+
+import (
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// signalNumber returns the signal with a name such as "SIGUSR1" on this platform
+func signalNumber(name string) (syscall.Signal, bool) {
+	sig := unix.SignalNum(name)
+	return sig, sig != 0
+}
+
+// signalProcess sends sig to the process pid; a process that has already exited is not an error
+func signalProcess(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(pid, sig); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// signalGroup sends sig to the process group cmd leads
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// killGroup kills the process group cmd leads, or cmd's process alone if it leads none
+func killGroup(cmd *exec.Cmd) error {
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		return signalProcess(cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+//go:build unix
+
+package job
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestSendSignal(t *testing.T) {
+	m, rt := newLocalTestManager(t, "trap 'echo got USR1; exit 0' USR1\necho ready\nwhile :; do read -t 0.01; done\n")
+	m.config.AllowedSignals = []string{"SIGUSR1"}
+	rt.Timeouts.Run = 5 * time.Second
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+	})
+
+	if err := j.SendSignal("SIGUSR1"); err == nil {
+		t.Error("Expected an error without a running process")
+	}
+
+	go j.ExecuteStream(context.Background())
+	var output strings.Builder
+	var result *types.ExecutionResult
+	for event := range j.EventChannel {
+		switch event.Type {
+		case "data":
+			output.WriteString(event.Data)
+			if event.Data == "ready\n" {
+				if err := j.SendSignal("SIGTERM"); err == nil {
+					t.Error("Expected a signal missing from allowed_signals to be rejected")
+				}
+				if err := j.SendSignal("SIGUSR1"); err != nil {
+					t.Errorf("SendSignal() error = %v", err)
+				}
+			}
+		case "result":
+			result = event.Result
+		}
+	}
+
+	if !strings.Contains(output.String(), "got USR1") {
+		t.Errorf("Expected the program to handle SIGUSR1, got output %q", output.String())
+	}
+	if result == nil || result.Run.Outcome != types.OutcomeOK {
+		t.Errorf("Expected the program to exit after the signal, got %+v", result)
+	}
+}
+
+// processAlive reports whether pid runs and is not a zombie waiting to be reaped
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	return err != nil || !strings.Contains(string(stat), ") Z ")
+}
+
+func TestStageKillsRemainingProcesses(t *testing.T) {
+	m, rt := newLocalTestManager(t, "sleep 30 >/dev/null 2>&1 &\necho $!\n")
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+	})
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(result.Run.Stdout))
+	if err != nil {
+		t.Fatalf("Expected the background pid, got %q", result.Run.Stdout)
+	}
+	deadline := time.Now().Add(time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatal("Expected the background process to be killed when the stage ended")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,122 @@
+package job
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// resolveCgroupRoot returns the directory holding isolate's box cgroups. Like isolate's
+// cg_root, "auto:<file>" reads the directory from file, which isolate-cg-keeper writes.
+func resolveCgroupRoot(root string) (string, error) {
+	file, ok := strings.CutPrefix(root, "auto:")
+	if !ok {
+		return root, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cgroup root from %s: %w", file, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readCgroupStats reads the memory and CPU time charged to a cgroup v2 directory
+func readCgroupStats(dir string) (memory int64, cpuTime time.Duration, err error) {
+	data, err := os.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+	memory, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid memory.current: %w", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		if key == "usage_usec" {
+			usec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid cpu.stat usage_usec: %w", err)
+			}
+			return memory, time.Duration(usec) * time.Microsecond, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("cpu.stat has no usage_usec")
+}
+
+// readCgroupProcs returns the IDs of the processes in a cgroup v2 directory
+func readCgroupProcs(dir string) ([]int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cgroup.procs entry %q", field)
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// sampleStats sends a stats event for the stage every stats_interval until the returned
+// function is called. Samples are skipped while the sandbox cannot measure the stage yet.
+func (j *Job) sampleStats(box *types.IsolateBox, stage string) (stop func()) {
+	interval := j.manager.config.StatsInterval
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		started := time.Now()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				memory, cpuTime, err := j.manager.sandbox.Sample(box)
+				if err != nil {
+					continue
+				}
+				event := types.StreamEvent{Type: "stats", Stage: stage, Stats: &types.StageStats{
+					Memory:   memory,
+					CPUTime:  cpuTime.Milliseconds(),
+					WallTime: time.Since(started).Milliseconds(),
+				}}
+				// Unlike sendEvent this is not activity, and a sample is simply dropped when
+				// the client falls behind since the next one supersedes it
+				select {
+				case j.EventChannel <- event:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Wait for the sampler so no stats event follows stage_end
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,107 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+func writeCgroupFiles(t *testing.T, dir, memory, cpuStat string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "memory.current"), []byte(memory), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cpu.stat"), []byte(cpuStat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveCgroupRoot(t *testing.T) {
+	if got, err := resolveCgroupRoot("/sys/fs/cgroup/isolate"); err != nil || got != "/sys/fs/cgroup/isolate" {
+		t.Errorf("Expected a plain path unchanged, got %q (%v)", got, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "cgroup")
+	if err := os.WriteFile(file, []byte("/sys/fs/cgroup/isolate.slice\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolveCgroupRoot("auto:" + file); err != nil || got != "/sys/fs/cgroup/isolate.slice" {
+		t.Errorf("Expected the path read from the auto file, got %q (%v)", got, err)
+	}
+
+	if _, err := resolveCgroupRoot("auto:/nonexistent/cgroup"); err == nil {
+		t.Error("Expected an error for a missing auto file")
+	}
+}
+
+func TestReadCgroupStats(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupFiles(t, dir, "2097152\n", "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n")
+
+	memory, cpuTime, err := readCgroupStats(dir)
+	if err != nil {
+		t.Fatalf("Failed to read stats: %v", err)
+	}
+	if memory != 2097152 {
+		t.Errorf("Expected memory 2097152, got %d", memory)
+	}
+	if cpuTime != 1500*time.Millisecond {
+		t.Errorf("Expected CPU time 1.5s, got %v", cpuTime)
+	}
+
+	if _, _, err := readCgroupStats(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing cgroup")
+	}
+}
+
+func TestReadCgroupProcs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte("41\n42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pids, err := readCgroupProcs(dir)
+	if err != nil || len(pids) != 2 || pids[0] != 41 || pids[1] != 42 {
+		t.Errorf("Expected pids [41 42], got %v, %v", pids, err)
+	}
+}
+
+func TestSampleStats(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, filepath.Join(root, "box-7"), "4096", "usage_usec 2000\n")
+
+	cfg := &config.Config{StatsInterval: 10 * time.Millisecond, IsolateCgroupRoot: root}
+	j := &Job{
+		EventChannel: make(chan types.StreamEvent, 10),
+		logger:       logrus.WithField("test", t.Name()),
+		manager:      &Manager{config: cfg, sandbox: &isolateSandbox{config: cfg}},
+	}
+
+	stop := j.sampleStats(&types.IsolateBox{ID: 7}, "run")
+	event := <-j.EventChannel
+	stop()
+
+	if event.Type != "stats" || event.Stage != "run" || event.Stats == nil {
+		t.Fatalf("Expected a run stats event, got %+v", event)
+	}
+	if event.Stats.Memory != 4096 || event.Stats.CPUTime != 2 {
+		t.Errorf("Expected memory 4096 and CPU time 2ms, got %+v", *event.Stats)
+	}
+
+	// Nothing is sent once stopped
+	for len(j.EventChannel) > 0 {
+		<-j.EventChannel
+	}
+	time.Sleep(30 * time.Millisecond)
+	if len(j.EventChannel) != 0 {
+		t.Error("Expected no stats events after stop")
+	}
+}
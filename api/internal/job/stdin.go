@@ -0,0 +1,179 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// maxStdinRedirects bounds the redirects followed when fetching a stdin_url
+const maxStdinRedirects = 5
+
+// ErrStdinUnavailable is returned for a job whose stdin_url cannot be fetched or whose fixture
+// cannot be read
+var ErrStdinUnavailable = errors.New("stdin unavailable")
+
+// ValidateStdin checks that a request names at most one stdin source, that a stdin_url is on a
+// host in stdin_url_hosts and that a stdin_fixture has been uploaded
+func (m *Manager) ValidateStdin(request *types.JobRequest) error {
+	sources := 0
+	for _, source := range []string{request.Stdin, request.StdinURL, request.StdinFixture} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of stdin, stdin_url and stdin_fixture may be given")
+	}
+
+	if request.StdinURL != "" {
+		if len(m.config.StdinURLHosts) == 0 {
+			return fmt.Errorf("stdin_url is disabled")
+		}
+		u, err := url.Parse(request.StdinURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("stdin_url must be an absolute http or https URL")
+		}
+		if !stdinHostAllowed(m.config.StdinURLHosts, u.Hostname()) {
+			return fmt.Errorf("stdin_url host %s is not allowed", u.Hostname())
+		}
+	}
+
+	if request.StdinFixture != "" {
+		if !ValidFixtureName(request.StdinFixture) {
+			return fmt.Errorf("stdin_fixture must name a fixture")
+		}
+		if m.fixtures == nil {
+			return fmt.Errorf("fixtures are unavailable")
+		}
+		if _, err := m.fixtures.Stat(request.StdinFixture); err != nil {
+			return fmt.Errorf("fixture %s not found", request.StdinFixture)
+		}
+	}
+	return nil
+}
+
+// stdinHostAllowed reports whether host matches one of hosts, exactly or, for an entry such as
+// "*.example.com", as a subdomain
+func stdinHostAllowed(hosts []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// openStdin opens the job's fixture, or fetches its stdin_url into the job directory. Jobs with
+// inline stdin have nothing to open.
+func (j *Job) openStdin(ctx context.Context) error {
+	switch {
+	case j.StdinFixture != "":
+		if j.manager.fixtures == nil {
+			return fmt.Errorf("%w: fixtures are unavailable", ErrStdinUnavailable)
+		}
+		file, err := j.manager.fixtures.Open(j.StdinFixture)
+		if err != nil {
+			return fmt.Errorf("%w: fixture %s: %v", ErrStdinUnavailable, j.StdinFixture, err)
+		}
+		j.stdinFile = file
+	case j.StdinURL != "":
+		file, err := j.fetchStdin(ctx)
+		if err != nil {
+			j.removeWorkDir()
+			return fmt.Errorf("%w: %v", ErrStdinUnavailable, err)
+		}
+		j.stdinFile = file
+	}
+	return nil
+}
+
+// fetchStdin downloads the job's stdin_url, of at most stdin_max_size bytes, into the job
+// directory. Redirects are followed only to allowed hosts.
+func (j *Job) fetchStdin(ctx context.Context) (*os.File, error) {
+	cfg := j.manager.config
+	ctx, cancel := context.WithTimeout(ctx, cfg.StdinURLTimeout)
+	defer cancel()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxStdinRedirects {
+				return fmt.Errorf("stdin_url redirected more than %d times", maxStdinRedirects)
+			}
+			if !stdinHostAllowed(cfg.StdinURLHosts, req.URL.Hostname()) {
+				return fmt.Errorf("stdin_url redirected to %s, which is not allowed", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.StdinURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stdin_url: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stdin_url: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("stdin_url returned %s", resp.Status)
+	}
+	if resp.ContentLength > cfg.StdinMaxSize {
+		return nil, fmt.Errorf("stdin_url exceeds stdin_max_size")
+	}
+
+	if err := j.prepareWorkDir(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(j.workDir(), "stdin"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin file: %w", err)
+	}
+	n, err := io.Copy(file, io.LimitReader(resp.Body, cfg.StdinMaxSize+1))
+	if err == nil && n > cfg.StdinMaxSize {
+		err = fmt.Errorf("stdin_url exceeds stdin_max_size")
+	} else if err != nil {
+		err = fmt.Errorf("failed to fetch stdin_url: %w", err)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	j.logger.Debugf("Fetched %d bytes of stdin", n)
+	return file, nil
+}
+
+// writeStdin writes the job's stdin to w, from its fixture or stdin_url if it has one. A process
+// that exits before reading all of it is not an error.
+func (j *Job) writeStdin(w io.Writer) {
+	if j.stdinFile != nil {
+		io.Copy(w, io.NewSectionReader(j.stdinFile, 0, math.MaxInt64))
+		return
+	}
+	if j.Stdin != "" {
+		w.Write([]byte(j.Stdin))
+	}
+}
+
+// closeStdinFile closes the job's stdin file; a downloaded one is removed with the job directory
+func (j *Job) closeStdinFile() {
+	if j.stdinFile != nil {
+		j.stdinFile.Close()
+	}
+}
@@ -0,0 +1,156 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// catScript is a run script echoing its stdin
+const catScript = "cat\n"
+
+func TestFixtureStore(t *testing.T) {
+	store, err := NewFixtureStore(t.TempDir(), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture, err := store.Put("case-1.in", strings.NewReader("1 2 3\n"))
+	if err != nil || fixture.Name != "case-1.in" || fixture.Size != 6 {
+		t.Fatalf("Put() = %+v, %v; want a 6 byte fixture", fixture, err)
+	}
+	if _, err := store.Put("big.in", strings.NewReader("123456789")); !errors.Is(err, ErrFixtureTooLarge) {
+		t.Errorf("Put() error = %v, want ErrFixtureTooLarge", err)
+	}
+	for _, name := range []string{"", ".hidden", "../escape", "a/b"} {
+		if _, err := store.Put(name, strings.NewReader("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", name)
+		}
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 1 || list[0].Name != "case-1.in" {
+		t.Errorf("List() = %+v, %v; want only case-1.in", list, err)
+	}
+
+	if err := store.Delete("case-1.in"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete("case-1.in"); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("Delete() error = %v, want ErrFixtureNotFound", err)
+	}
+}
+
+func TestStdinFixture(t *testing.T) {
+	m, rt := newLocalTestManager(t, catScript)
+	store, err := NewFixtureStore(filepath.Join(m.config.DataDirectory, "fixtures"), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.fixtures = store
+	if _, err := store.Put("judge.in", strings.NewReader("from a fixture\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	request := &types.JobRequest{
+		Files:        []types.CodeFile{{Name: "main.sh", Content: "true"}},
+		StdinFixture: "judge.in",
+	}
+	if err := m.ValidateStdin(request); err != nil {
+		t.Fatalf("ValidateStdin() error = %v", err)
+	}
+	result, err := m.NewJob(context.Background(), rt, request).Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Run.Stdout != "from a fixture\n" {
+		t.Errorf("Expected the fixture as stdin, got %q", result.Run.Stdout)
+	}
+
+	for _, bad := range []*types.JobRequest{
+		{StdinFixture: "missing.in"},
+		{StdinFixture: "judge.in", Stdin: "inline"},
+	} {
+		if err := m.ValidateStdin(bad); err == nil {
+			t.Errorf("ValidateStdin(%+v) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestStdinURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/input":
+			w.Write([]byte("from a url\n"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 64)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	m, rt := newLocalTestManager(t, catScript)
+	m.config.StdinURLHosts = []string{"127.0.0.1"}
+	m.config.StdinURLTimeout = 5 * time.Second
+	m.config.StdinMaxSize = 32
+
+	request := &types.JobRequest{
+		Files:    []types.CodeFile{{Name: "main.sh", Content: "true"}},
+		StdinURL: server.URL + "/input",
+	}
+	if err := m.ValidateStdin(request); err != nil {
+		t.Fatalf("ValidateStdin() error = %v", err)
+	}
+	j := m.NewJob(context.Background(), rt, request)
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Run.Stdout != "from a url\n" {
+		t.Errorf("Expected the download as stdin, got %q", result.Run.Stdout)
+	}
+	if _, err := os.Stat(j.workDir()); !os.IsNotExist(err) {
+		t.Errorf("Expected the download to be removed with the job directory, got %v", err)
+	}
+
+	for _, path := range []string{"/large", "/missing"} {
+		request.StdinURL = server.URL + path
+		j := m.NewJob(context.Background(), rt, request)
+		if _, err := j.Execute(context.Background()); !errors.Is(err, ErrStdinUnavailable) {
+			t.Errorf("Execute() with %s error = %v, want ErrStdinUnavailable", path, err)
+		}
+		if _, err := os.Stat(j.workDir()); !os.IsNotExist(err) {
+			t.Errorf("Expected no job directory after a failed download of %s, got %v", path, err)
+		}
+	}
+
+	if err := m.ValidateStdin(&types.JobRequest{StdinURL: "http://example.com/input"}); err == nil {
+		t.Error("Expected a host outside stdin_url_hosts to be rejected")
+	}
+}
+
+func TestStdinHostAllowed(t *testing.T) {
+	hosts := []string{"data.example.com", "*.judge.example.org"}
+	for host, want := range map[string]bool{
+		"data.example.com":         true,
+		"DATA.example.com":         true,
+		"other.example.com":        false,
+		"a.judge.example.org":      true,
+		"judge.example.org":        false,
+		"evil-judge.example.org":   false,
+		"a.judge.example.org.evil": false,
+	} {
+		if got := stdinHostAllowed(hosts, host); got != want {
+			t.Errorf("stdinHostAllowed(%q) = %t, want %t", host, got, want)
+		}
+	}
+}
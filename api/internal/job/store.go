@@ -0,0 +1,230 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// Store keeps track of asynchronous jobs and persists them to disk
+type Store struct {
+	dir    string
+	ttl    time.Duration
+	jobs   map[string]*types.AsyncJob
+	mutex  sync.RWMutex
+	logger *logrus.Entry
+}
+
+// NewStore creates a job store rooted at dir, restoring any previously persisted jobs
+func NewStore(dir string, ttl time.Duration) (*Store, error) {
+	store := &Store{
+		dir:    dir,
+		ttl:    ttl,
+		jobs:   make(map[string]*types.AsyncJob),
+		logger: logrus.WithField("component", "job_store"),
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+
+	if err := store.restore(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// restore loads persisted jobs; jobs interrupted by a restart are finished with an error
+func (s *Store) restore() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read job store directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			s.logger.WithError(err).Warnf("Failed to read persisted job %s", path)
+			continue
+		}
+
+		var job types.AsyncJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			s.logger.WithError(err).Warnf("Failed to parse persisted job %s", path)
+			continue
+		}
+
+		if job.Status == types.AsyncJobQueued || job.Status == types.AsyncJobRunning {
+			s.finish(&job, nil, "job interrupted by server restart")
+		}
+
+		s.jobs[job.ID] = &job
+	}
+
+	s.logger.Infof("Restored %d async jobs", len(s.jobs))
+	return nil
+}
+
+// Create registers a new queued job
+func (s *Store) Create(id, language, version, submitter string) *types.AsyncJob {
+	job := &types.AsyncJob{
+		ID:        id,
+		Status:    types.AsyncJobQueued,
+		Language:  language,
+		Version:   version,
+		CreatedAt: time.Now(),
+		Submitter: submitter,
+	}
+
+	s.mutex.Lock()
+	s.jobs[id] = job
+	s.persist(job)
+	snapshot := *job
+	s.mutex.Unlock()
+
+	return &snapshot
+}
+
+// MarkRunning transitions a queued job to running
+func (s *Store) MarkRunning(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Status != types.AsyncJobQueued {
+		return
+	}
+
+	now := time.Now()
+	job.Status = types.AsyncJobRunning
+	job.StartedAt = &now
+	s.persist(job)
+}
+
+// MarkFinished records the result (or error) of a job. Cancelled jobs keep their status.
+func (s *Store) MarkFinished(id string, result *types.ExecutionResult, execErr error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Status == types.AsyncJobCancelled {
+		return
+	}
+
+	message := ""
+	if execErr != nil {
+		message = execErr.Error()
+	}
+	s.finish(job, result, message)
+	s.persist(job)
+}
+
+// Cancel marks a queued or running job of submitter as cancelled and returns a snapshot of it
+func (s *Store) Cancel(id, submitter string) (*types.AsyncJob, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Submitter != submitter {
+		return nil, ErrJobNotFound
+	}
+	if job.Status != types.AsyncJobQueued && job.Status != types.AsyncJobRunning {
+		return nil, ErrJobFinished
+	}
+
+	s.finish(job, nil, "job cancelled")
+	job.Status = types.AsyncJobCancelled
+	s.persist(job)
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// finish sets the terminal fields of a job
+func (s *Store) finish(job *types.AsyncJob, result *types.ExecutionResult, message string) {
+	now := time.Now()
+	expires := now.Add(s.ttl)
+	job.Status = types.AsyncJobFinished
+	job.FinishedAt = &now
+	job.ExpiresAt = &expires
+	job.Result = result
+	job.Error = message
+}
+
+// Get returns a snapshot of the job with the given ID
+func (s *Store) Get(id string) (*types.AsyncJob, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+
+	snapshot := *job
+	return &snapshot, true
+}
+
+// Expire drops results whose TTL has passed and forgets expired jobs after a second TTL
+func (s *Store) Expire(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, job := range s.jobs {
+		if job.ExpiresAt == nil || now.Before(*job.ExpiresAt) {
+			continue
+		}
+
+		switch job.Status {
+		case types.AsyncJobFinished, types.AsyncJobCancelled:
+			// Keep a tombstone so pollers see "expired" rather than "not found"
+			expires := job.ExpiresAt.Add(s.ttl)
+			job.Status = types.AsyncJobExpired
+			job.Result = nil
+			job.ExpiresAt = &expires
+			s.persist(job)
+		case types.AsyncJobExpired:
+			delete(s.jobs, id)
+			if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+				s.logger.WithError(err).Warnf("Failed to remove persisted job %s", id)
+			}
+		}
+	}
+}
+
+// persist writes the job to disk; callers must hold the mutex
+func (s *Store) persist(job *types.AsyncJob) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to encode job %s", job.ID)
+		return
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated record
+	tmp := s.path(job.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		s.logger.WithError(err).Errorf("Failed to persist job %s", job.ID)
+		return
+	}
+	if err := os.Rename(tmp, s.path(job.ID)); err != nil {
+		s.logger.WithError(err).Errorf("Failed to persist job %s", job.ID)
+	}
+}
+
+// path returns the on-disk location of a job record
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,171 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestStoreLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	record := store.Create("job-1", "python", "3.12.0", "")
+	if record.Status != types.AsyncJobQueued {
+		t.Fatalf("Expected status %s, got %s", types.AsyncJobQueued, record.Status)
+	}
+
+	store.MarkRunning("job-1")
+	if job, _ := store.Get("job-1"); job.Status != types.AsyncJobRunning || job.StartedAt == nil {
+		t.Fatalf("Expected running job with start time, got %+v", job)
+	}
+
+	store.MarkFinished("job-1", &types.ExecutionResult{Language: "python"}, nil)
+	job, _ := store.Get("job-1")
+	if job.Status != types.AsyncJobFinished || job.Result == nil || job.ExpiresAt == nil {
+		t.Fatalf("Expected finished job with result, got %+v", job)
+	}
+
+	// Results are dropped after the TTL, then the tombstone is removed
+	store.Expire(job.ExpiresAt.Add(time.Second))
+	job, ok := store.Get("job-1")
+	if !ok || job.Status != types.AsyncJobExpired || job.Result != nil {
+		t.Fatalf("Expected expired job without result, got %+v", job)
+	}
+
+	store.Expire(job.ExpiresAt.Add(time.Second))
+	if _, ok := store.Get("job-1"); ok {
+		t.Fatal("Expected expired job to be removed")
+	}
+}
+
+func TestStoreRestore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	store.Create("queued", "python", "3.12.0", "")
+	store.Create("failed", "python", "3.12.0", "")
+	store.MarkFinished("failed", nil, errors.New("boom"))
+
+	restored, err := NewStore(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to restore store: %v", err)
+	}
+
+	job, ok := restored.Get("queued")
+	if !ok || job.Status != types.AsyncJobFinished || job.Error == "" {
+		t.Errorf("Expected interrupted job to be finished with an error, got %+v", job)
+	}
+
+	job, ok = restored.Get("failed")
+	if !ok || job.Error != "boom" {
+		t.Errorf("Expected persisted error to survive restore, got %+v", job)
+	}
+}
+
+func TestStoreCancel(t *testing.T) {
+	store, err := NewStore(t.TempDir(), time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	store.Create("job-1", "python", "3.12.0", "")
+	store.MarkRunning("job-1")
+
+	job, err := store.Cancel("job-1", "")
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if job.Status != types.AsyncJobCancelled || job.FinishedAt == nil || job.ExpiresAt == nil {
+		t.Fatalf("Expected cancelled job with finish and expiry times, got %+v", job)
+	}
+
+	// The killed execution finishing afterwards does not overwrite the cancellation
+	store.MarkFinished("job-1", &types.ExecutionResult{Language: "python"}, errors.New("killed"))
+	if job, _ := store.Get("job-1"); job.Status != types.AsyncJobCancelled || job.Result != nil {
+		t.Errorf("Expected job to stay cancelled, got %+v", job)
+	}
+
+	if _, err := store.Cancel("job-1", ""); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() of a cancelled job error = %v, want ErrJobFinished", err)
+	}
+	if _, err := store.Cancel("missing", ""); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel() of an unknown job error = %v, want ErrJobNotFound", err)
+	}
+
+	store.Create("job-2", "python", "3.12.0", "")
+	store.MarkFinished("job-2", &types.ExecutionResult{Language: "python"}, nil)
+	if _, err := store.Cancel("job-2", ""); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() of a finished job error = %v, want ErrJobFinished", err)
+	}
+}
+
+func TestCancelAsyncJob(t *testing.T) {
+	store, err := NewStore(t.TempDir(), time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	m := &Manager{store: store}
+
+	store.Create("job-1", "python", "3.12.0", "")
+	ctx, done := m.trackAsync(context.Background(), "job-1")
+	defer done()
+
+	if _, err := m.CancelAsyncJob("job-1", "team-a"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("CancelAsyncJob() by another key error = %v, want ErrJobNotFound", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected another key's cancel to leave the job running")
+	}
+
+	job, err := m.CancelAsyncJob("job-1", "")
+	if err != nil {
+		t.Fatalf("CancelAsyncJob() error = %v", err)
+	}
+	if job.Status != types.AsyncJobCancelled {
+		t.Errorf("Expected cancelled status, got %s", job.Status)
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the job's context to be cancelled")
+	}
+
+	done()
+	if len(m.cancels) != 0 {
+		t.Errorf("Expected the cancel function to be dropped once the job ends, got %d", len(m.cancels))
+	}
+}
+
+func TestGetAsyncJobSubmitter(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.Create("job-1", "python", "3.12.0", "team-a")
+
+	// The submitter is persisted, so ownership survives a restart
+	restored, err := NewStore(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to restore store: %v", err)
+	}
+	m := &Manager{store: restored}
+
+	if job, ok := m.GetAsyncJob("job-1", "team-a"); !ok || job.Submitter != "team-a" {
+		t.Errorf("Expected the submitting key to find its job, got %+v, %v", job, ok)
+	}
+	if _, ok := m.GetAsyncJob("job-1", "team-b"); ok {
+		t.Error("Expected another key not to find the job")
+	}
+	if _, ok := m.GetAsyncJob("job-1", ""); ok {
+		t.Error("Expected a request without a key not to find the job")
+	}
+}
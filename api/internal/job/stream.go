@@ -0,0 +1,95 @@
+package job
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// streamChunkSize is the read size, and the amount of coalesced output sent without waiting
+// for the flush interval
+const streamChunkSize = 32 * 1024
+
+// streamOutput forwards a stage's raw output as it arrives, including partial lines such as prompts and
+// progress bars, and records it in capture. Reads are coalesced for up to the job's flush
+// interval so a chatty program does not produce one event per write.
+func (j *Job) streamOutput(reader io.Reader, stage, streamType string, capture *outputCapture) {
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		for {
+			buf := make([]byte, streamChunkSize)
+			n, err := reader.Read(buf)
+			if n > 0 {
+				chunks <- buf[:n]
+			}
+			if err != nil {
+				// A pty master reports EIO once the terminal has no more writers
+				if !errors.Is(err, io.EOF) && !errors.Is(err, syscall.EIO) && !errors.Is(err, os.ErrClosed) {
+					j.logger.WithError(err).Warnf("Failed to read %s", streamType)
+				}
+				return
+			}
+		}
+	}()
+
+	var pending []byte
+	var flushTimer <-chan time.Time
+	// forwarding stops once the output limit killed the process; the rest is still read so
+	// the reader never blocks
+	forwarding := true
+	flush := func(final bool) {
+		flushTimer = nil
+		n := len(pending)
+		if !final {
+			// Hold back a multi-byte character split across reads until the rest arrives
+			n = completeUTF8(pending)
+		}
+		if n == 0 {
+			return
+		}
+		data := string(pending[:n])
+		pending = append(pending[:0], pending[n:]...)
+		if !forwarding {
+			return
+		}
+		capture.write(streamType, data)
+		forwarding = j.sendOutput(stage, streamType, data)
+	}
+
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				flush(true)
+				return
+			}
+			pending = append(pending, chunk...)
+			if j.flushInterval <= 0 || len(pending) >= streamChunkSize {
+				flush(false)
+			} else if flushTimer == nil {
+				flushTimer = time.After(j.flushInterval)
+			}
+		case <-flushTimer:
+			flush(false)
+		}
+	}
+}
+
+// completeUTF8 returns the length of b without a trailing incomplete UTF-8 sequence
+func completeUTF8(b []byte) int {
+	// A sequence is at most utf8.UTFMax bytes, so only the tail needs checking
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+		if !utf8.FullRune(b[i:]) {
+			return i
+		}
+		break
+	}
+	return len(b)
+}
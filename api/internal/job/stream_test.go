@@ -0,0 +1,151 @@
+package job
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+func newStreamJob(t *testing.T, flushInterval time.Duration) *Job {
+	return &Job{
+		EventChannel:  make(chan types.StreamEvent, 100),
+		flushInterval: flushInterval,
+		logger:        logrus.WithField("test", t.Name()),
+	}
+}
+
+// collectData joins the data events sent so far
+func collectData(j *Job) string {
+	var data strings.Builder
+	for len(j.EventChannel) > 0 {
+		if event := <-j.EventChannel; event.Type == "data" {
+			data.WriteString(event.Data)
+		}
+	}
+	return data.String()
+}
+
+func TestStreamOutputPartialLine(t *testing.T) {
+	j := newStreamJob(t, 0)
+	r, w := io.Pipe()
+	capture := newOutputCapture(0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		j.streamOutput(r, "run", "stdout", capture)
+	}()
+
+	// A prompt without a newline is forwarded before the program exits
+	w.Write([]byte("name? "))
+	select {
+	case event := <-j.EventChannel:
+		if event.Data != "name? " {
+			t.Errorf("Expected the prompt, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the partial line to be sent")
+	}
+
+	w.Write([]byte("bob\n"))
+	w.Close()
+	<-done
+
+	var result types.StageResult
+	capture.apply(&result)
+	if result.Stdout != "name? bob\n" {
+		t.Errorf("Expected the exact output to be captured, got %q", result.Stdout)
+	}
+}
+
+func TestStreamOutputLongLine(t *testing.T) {
+	j := newStreamJob(t, 10*time.Millisecond)
+	line := strings.Repeat("x", 200*1024) + "\n"
+
+	j.streamOutput(strings.NewReader(line), "run", "stdout", newOutputCapture(0))
+
+	if got := collectData(j); got != line {
+		t.Errorf("Expected the %d byte line intact, got %d bytes", len(line), len(got))
+	}
+}
+
+func TestStreamOutputCoalesces(t *testing.T) {
+	j := newStreamJob(t, 50*time.Millisecond)
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		j.streamOutput(r, "run", "stdout", newOutputCapture(0))
+	}()
+
+	for i := 0; i < 5; i++ {
+		w.Write([]byte("tick "))
+	}
+	w.Close()
+	<-done
+
+	if len(j.EventChannel) != 1 {
+		t.Errorf("Expected writes within the flush interval in one event, got %d events", len(j.EventChannel))
+	}
+	if got := collectData(j); got != strings.Repeat("tick ", 5) {
+		t.Errorf("Expected all output, got %q", got)
+	}
+}
+
+func TestStreamOutputSplitRune(t *testing.T) {
+	j := newStreamJob(t, 0)
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		j.streamOutput(r, "run", "stdout", newOutputCapture(0))
+	}()
+
+	// "é" is two bytes; the first must not be sent on its own
+	w.Write([]byte("caf\xc3"))
+	w.Write([]byte("\xa9\n"))
+	w.Close()
+	<-done
+
+	for len(j.EventChannel) > 0 {
+		event := <-j.EventChannel
+		if !strings.HasSuffix(event.Data, "\n") && strings.HasSuffix(event.Data, "\xc3") {
+			t.Errorf("Expected no event ending in a partial character, got %q", event.Data)
+		}
+	}
+}
+
+func TestCompleteUTF8(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"caf\xc3\xa9", 5},
+		{"caf\xc3", 3},
+		{"\xe2\x82", 0},
+		{"a\xf0\x9f\x98", 1},
+		// Invalid bytes are passed through rather than held forever
+		{"a\xff", 2},
+	}
+
+	for _, tt := range tests {
+		if got := completeUTF8([]byte(tt.input)); got != tt.want {
+			t.Errorf("completeUTF8(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStreamOutputTagsStage(t *testing.T) {
+	j := newStreamJob(t, 0)
+	j.streamOutput(strings.NewReader("main.c:1: error\n"), "compile", "stderr", newOutputCapture(0))
+
+	event := <-j.EventChannel
+	if event.Type != "data" || event.Stage != "compile" || event.Stream != "stderr" {
+		t.Errorf("Expected compile stderr data, got %+v", event)
+	}
+}
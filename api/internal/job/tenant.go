@@ -0,0 +1,263 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/tracing"
+)
+
+// Quotas reported in QuotaError.Quota
+const (
+	QuotaConcurrentJobs  = "max_concurrent_jobs"
+	QuotaDailyExecutions = "daily_executions"
+)
+
+// QuotaError is returned when a tenant is over one of its quotas
+type QuotaError struct {
+	Tenant string `json:"tenant"`
+	Quota  string `json:"quota"`
+	Limit  int    `json:"limit"`
+	Used   int    `json:"used"`
+	// Reset is when a daily quota starts over
+	Reset *time.Time `json:"reset,omitempty"`
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("tenant %s exceeded its %s quota (%d of %d)", e.Tenant, e.Quota, e.Used, e.Limit)
+}
+
+// StatusCode is 429 for the concurrency limit, which clears as jobs finish, and 403 for the
+// daily quota, which only clears at Reset
+func (e *QuotaError) StatusCode() int {
+	if e.Quota == QuotaDailyExecutions {
+		return http.StatusForbidden
+	}
+	return http.StatusTooManyRequests
+}
+
+// TenantUsage reports a tenant's current usage against its quotas
+type TenantUsage struct {
+	Tenant            string    `json:"tenant"`
+	Running           int       `json:"running"`
+	MaxConcurrentJobs int       `json:"max_concurrent_jobs"`
+	ExecutionsToday   int       `json:"executions_today"`
+	DailyExecutions   int       `json:"daily_executions"`
+	Reset             time.Time `json:"reset"`
+	// Totals since the server started
+	Executions uint64 `json:"executions"`
+	Rejected   uint64 `json:"rejected"`
+}
+
+// tenantCounters are the live counters of one tenant
+type tenantCounters struct {
+	running    int
+	today      int
+	day        time.Time
+	executions uint64
+	rejected   uint64
+}
+
+// Tenants enforces per-tenant quotas and keeps per-tenant usage
+type Tenants struct {
+	quotas   map[string]config.Tenant
+	counters map[string]*tenantCounters
+	mutex    sync.Mutex
+	now      func() time.Time
+}
+
+// NewTenants creates the tenant tracker for the configured tenants
+func NewTenants(tenants []config.Tenant) *Tenants {
+	t := &Tenants{
+		quotas:   make(map[string]config.Tenant, len(tenants)),
+		counters: make(map[string]*tenantCounters),
+		now:      time.Now,
+	}
+	for _, tenant := range tenants {
+		t.quotas[tenant.Name] = tenant
+	}
+	return t
+}
+
+// Acquire counts a new execution for tenant and returns the function that ends it. It fails
+// with a QuotaError when the tenant is at its concurrency limit or out of daily executions.
+func (t *Tenants) Acquire(tenant string) (func(), error) {
+	if t == nil {
+		return func() {}, nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	quota := t.quotas[tenant]
+	c := t.counter(tenant)
+
+	if quota.MaxConcurrentJobs > 0 && c.running >= quota.MaxConcurrentJobs {
+		c.rejected++
+		return nil, &QuotaError{Tenant: tenant, Quota: QuotaConcurrentJobs, Limit: quota.MaxConcurrentJobs, Used: c.running}
+	}
+	if quota.DailyExecutions > 0 && c.today >= quota.DailyExecutions {
+		c.rejected++
+		reset := c.day.AddDate(0, 0, 1)
+		return nil, &QuotaError{Tenant: tenant, Quota: QuotaDailyExecutions, Limit: quota.DailyExecutions,
+			Used: c.today, Reset: &reset}
+	}
+
+	c.running++
+	c.today++
+	c.executions++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			c.running--
+		})
+	}, nil
+}
+
+// counter returns the tenant's counters, starting a new day when the UTC date changed.
+// The caller must hold the mutex.
+func (t *Tenants) counter(tenant string) *tenantCounters {
+	day := today(t.now())
+
+	c, ok := t.counters[tenant]
+	if !ok {
+		c = &tenantCounters{day: day}
+		t.counters[tenant] = c
+	}
+	if !c.day.Equal(day) {
+		c.day = day
+		c.today = 0
+	}
+	return c
+}
+
+// Usage returns the usage of every configured tenant and every tenant that has run jobs,
+// sorted by name
+func (t *Tenants) Usage() []TenantUsage {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	names := make([]string, 0, len(t.counters))
+	for name := range t.counters {
+		names = append(names, name)
+	}
+	for name := range t.quotas {
+		if _, ok := t.counters[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	usage := make([]TenantUsage, 0, len(names))
+	for _, name := range names {
+		usage = append(usage, t.usage(name))
+	}
+	return usage
+}
+
+// Get returns the usage of one tenant
+func (t *Tenants) Get(tenant string) TenantUsage {
+	if t == nil {
+		return TenantUsage{Tenant: tenant}
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.usage(tenant)
+}
+
+// usage reports a tenant's counters, which need not exist yet. The caller must hold the mutex.
+func (t *Tenants) usage(tenant string) TenantUsage {
+	c, ok := t.counters[tenant]
+	if ok {
+		c = t.counter(tenant)
+	} else {
+		c = &tenantCounters{day: today(t.now())}
+	}
+	quota := t.quotas[tenant]
+	return TenantUsage{
+		Tenant:            tenant,
+		Running:           c.running,
+		MaxConcurrentJobs: quota.MaxConcurrentJobs,
+		ExecutionsToday:   c.today,
+		DailyExecutions:   quota.DailyExecutions,
+		Reset:             c.day.AddDate(0, 0, 1),
+		Executions:        c.executions,
+		Rejected:          c.rejected,
+	}
+}
+
+// today returns the start of now's UTC day
+func today(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// TenantUsage returns per-tenant usage and quotas
+func (m *Manager) TenantUsage() []TenantUsage {
+	return m.tenants.Usage()
+}
+
+// TenantUsageOf returns the usage and quotas of one tenant
+func (m *Manager) TenantUsageOf(tenant string) TenantUsage {
+	return m.tenants.Get(tenant)
+}
+
+// admitTenant reserves the job's execution against its tenant's quotas, unless Submit already
+// did. Jobs without a tenant are not limited.
+func (j *Job) admitTenant() error {
+	if j.tenant == "" || j.releaseTenant != nil {
+		return nil
+	}
+	release, err := j.manager.tenants.Acquire(j.tenant)
+	if err != nil {
+		return err
+	}
+	j.releaseTenant = release
+	return nil
+}
+
+// begin admits the job under its tenant's quotas, registers it with the manager, starts the
+// job's span and opens its stdin fixture or fetches its stdin_url. The returned function ends
+// the first three; cleanup closes the stdin.
+func (j *Job) begin(ctx context.Context) (context.Context, func(), error) {
+	ctx, span := j.startSpan(ctx, "job")
+	if err := j.admitTenant(); err != nil {
+		tracing.End(span, err)
+		return nil, nil, err
+	}
+	ctx, done, err := j.manager.begin(ctx)
+	if err != nil {
+		j.endTenant()
+		tracing.End(span, err)
+		return nil, nil, err
+	}
+	if err := j.openStdin(ctx); err != nil {
+		done()
+		j.endTenant()
+		tracing.End(span, err)
+		return nil, nil, err
+	}
+	return ctx, func() {
+		done()
+		j.endTenant()
+		span.End()
+	}, nil
+}
+
+// endTenant releases the job's tenant reservation, if any
+func (j *Job) endTenant() {
+	if j.releaseTenant != nil {
+		j.releaseTenant()
+	}
+}
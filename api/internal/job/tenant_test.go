@@ -0,0 +1,113 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestTenantsConcurrentJobs(t *testing.T) {
+	tenants := NewTenants([]config.Tenant{{Name: "acme", MaxConcurrentJobs: 2}})
+
+	first, err := tenants.Acquire("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tenants.Acquire("acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tenants.Acquire("acme")
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || quotaErr.Quota != QuotaConcurrentJobs || quotaErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("Expected a concurrency quota error, got %v", err)
+	}
+
+	// Other tenants are unaffected, and releasing twice frees one slot
+	if _, err := tenants.Acquire("globex"); err != nil {
+		t.Errorf("Expected an unconfigured tenant to be unlimited, got %v", err)
+	}
+	first()
+	first()
+	if _, err := tenants.Acquire("acme"); err != nil {
+		t.Errorf("Expected a freed slot, got %v", err)
+	}
+	if _, err := tenants.Acquire("acme"); err == nil {
+		t.Error("Expected a double release to free only one slot")
+	}
+
+	usage := tenants.Get("acme")
+	if usage.Running != 2 || usage.Executions != 3 || usage.Rejected != 2 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
+
+func TestTenantsDailyExecutions(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	tenants := NewTenants([]config.Tenant{{Name: "acme", DailyExecutions: 1}})
+	tenants.now = func() time.Time { return now }
+
+	release, err := tenants.Acquire("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	_, err = tenants.Acquire("acme")
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || quotaErr.Quota != QuotaDailyExecutions || quotaErr.StatusCode() != http.StatusForbidden {
+		t.Fatalf("Expected a daily quota error, got %v", err)
+	}
+	if want := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC); quotaErr.Reset == nil || !quotaErr.Reset.Equal(want) {
+		t.Errorf("Expected the quota to reset at %v, got %v", want, quotaErr.Reset)
+	}
+
+	// The count starts over the next UTC day
+	now = now.Add(2 * time.Hour)
+	if _, err := tenants.Acquire("acme"); err != nil {
+		t.Errorf("Expected a new day to reset the quota, got %v", err)
+	}
+	if usage := tenants.Get("acme"); usage.ExecutionsToday != 1 || usage.Executions != 2 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
+
+func TestTenantsUsage(t *testing.T) {
+	tenants := NewTenants([]config.Tenant{{Name: "b", DailyExecutions: 10}, {Name: "a"}})
+	if _, err := tenants.Acquire("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := tenants.Usage()
+	if len(usage) != 3 || usage[0].Tenant != "a" || usage[1].Tenant != "b" || usage[2].Tenant != "c" {
+		t.Fatalf("Expected configured and active tenants sorted by name, got %+v", usage)
+	}
+	if usage[1].DailyExecutions != 10 || usage[2].Running != 1 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
+
+func TestJobBeginTenant(t *testing.T) {
+	m := newDrainTestManager()
+	m.tenants = NewTenants([]config.Tenant{{Name: "acme", MaxConcurrentJobs: 1}})
+
+	first := &Job{tenant: "acme", manager: m}
+	_, done, err := first.begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := &Job{tenant: "acme", manager: m}
+	if _, _, err := second.begin(context.Background()); err == nil {
+		t.Fatal("Expected the second job to be over the tenant's quota")
+	}
+
+	done()
+	if _, _, err := second.begin(context.Background()); err != nil {
+		t.Errorf("Expected the slot to be free once the first job ended, got %v", err)
+	}
+}
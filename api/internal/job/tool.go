@@ -0,0 +1,49 @@
+package job
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// runTool runs the package script named by stage, a formatter or linter, with the job's file
+// names as arguments and the compile limits. collect is called with the box and the stage
+// result before the box is cleaned up.
+func (j *Job) runTool(ctx context.Context, stage string, collect func(*types.IsolateBox, *types.StageResult) error) (*types.StageResult, error) {
+	ctx, done, err := j.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	defer j.cleanup()
+
+	if err := j.waitForSlot(ctx); err != nil {
+		return nil, fmt.Errorf("failed to acquire job slot: %w", err)
+	}
+	defer j.releaseSlot()
+	defer j.manager.runtimes.Use(j.Runtime.PkgDir)()
+
+	j.logger.Infof("Running %s", stage)
+
+	box, err := j.prime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prime job: %w", err)
+	}
+
+	names := make([]string, len(j.Files))
+	for i, file := range j.Files {
+		names[i] = file.Name
+	}
+	result, err := j.safeCall(ctx, box, stage, names,
+		j.Timeouts.Compile, j.CPUTimes.Compile, j.MemoryLimits.Compile)
+	if err != nil {
+		return nil, fmt.Errorf("%s stage failed: %w", stage, err)
+	}
+	if err := collect(box, result); err != nil {
+		return nil, err
+	}
+
+	j.State = types.JobStateExecuted
+	return result, nil
+}
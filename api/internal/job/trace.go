@@ -0,0 +1,54 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/coderunr/api/internal/tracing"
+	"github.com/coderunr/api/internal/types"
+)
+
+// startSpan starts a span for part of the job, tagged with the job and its runtime
+func (j *Job) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("job.id", j.ID))
+	if j.Runtime != nil {
+		attrs = append(attrs,
+			attribute.String("runtime.language", j.Runtime.Language),
+			attribute.String("runtime.version", j.Runtime.Version.String()))
+	}
+	return tracing.Start(ctx, name, attrs...)
+}
+
+// startStageSpan starts the span of a compile or run stage
+func (j *Job) startStageSpan(ctx context.Context, stage string, timeout, cpuTime time.Duration, memoryLimit int64) (context.Context, trace.Span) {
+	return j.startSpan(ctx, "job."+stage,
+		attribute.Int64("stage.timeout_ms", timeout.Milliseconds()),
+		attribute.Int64("stage.cpu_time_ms", cpuTime.Milliseconds()),
+		attribute.Int64("stage.memory_limit", memoryLimit),
+	)
+}
+
+// endStageSpan records how a stage ended and ends its span. A program failing is not a span
+// error; only failing to run it is.
+func endStageSpan(span trace.Span, result *types.StageResult, err error) {
+	if result != nil {
+		span.SetAttributes(
+			attribute.String("stage.outcome", string(result.Outcome)),
+			attribute.String("stage.status", result.Status),
+			attribute.Int64("stage.wall_time_ms", result.WallTime),
+			attribute.Int64("stage.cpu_time_ms_used", result.CPUTime),
+			attribute.Int64("stage.memory", result.Memory),
+		)
+		if result.Code != nil {
+			span.SetAttributes(attribute.Int("stage.exit_code", *result.Code))
+		}
+		if result.Outcome == types.OutcomeSandboxError {
+			span.SetStatus(codes.Error, result.Message)
+		}
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,165 @@
+package job
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// ValidateRequest checks that a job request carries the required fields
+func ValidateRequest(request *types.JobRequest) error {
+	if request.Language == "" {
+		return fmt.Errorf("language is required as a string")
+	}
+
+	if len(request.Files) == 0 && request.Archive == "" {
+		return fmt.Errorf("files is required as an array")
+	}
+
+	if err := ValidateArchive(request.Archive); err != nil {
+		return err
+	}
+
+	// The entrypoint of an archive-only submission cannot default to the first file
+	if len(request.Files) == 0 && request.Entrypoint == "" {
+		return fmt.Errorf("entrypoint is required when submitting only an archive")
+	}
+
+	for i, file := range request.Files {
+		if file.Content == "" {
+			return fmt.Errorf("files[%d].content is required as a string", i)
+		}
+	}
+
+	// An entrypoint inside the archive is checked once it is extracted
+	if request.Entrypoint != "" && request.Archive == "" && !hasFile(request.Files, request.Entrypoint) {
+		return fmt.Errorf("entrypoint %s is not one of the submitted files", request.Entrypoint)
+	}
+
+	if request.CallbackURL != "" {
+		u, err := url.Parse(request.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("callback_url must be an absolute http or https URL")
+		}
+	}
+
+	if request.Priority < MinPriority || request.Priority > MaxPriority {
+		return fmt.Errorf("priority must be between %d and %d", MinPriority, MaxPriority)
+	}
+
+	switch request.OutputLimitAction {
+	case "", OutputLimitKill, OutputLimitTruncate:
+	default:
+		return fmt.Errorf("output_limit_action must be %s or %s", OutputLimitKill, OutputLimitTruncate)
+	}
+
+	if err := ValidateOmit(request.Omit); err != nil {
+		return err
+	}
+
+	if err := ValidateLocale(request.Locale, request.Timezone); err != nil {
+		return err
+	}
+
+	if err := ValidateMetadata(request.Metadata); err != nil {
+		return err
+	}
+
+	if err := ValidateJudge(request); err != nil {
+		return err
+	}
+
+	return ValidateOutputFiles(request.OutputFiles)
+}
+
+// ValidateConstraints validates resource constraints against runtime limits
+func ValidateConstraints(request *types.JobRequest, rt *types.Runtime) error {
+	// Check if files include at least one utf8 encoded file (except for 'file' language)
+	if rt.Language != "file" && len(request.Files) > 0 {
+		hasUTF8 := false
+		for _, file := range request.Files {
+			if file.Encoding == "" || file.Encoding == "utf8" {
+				hasUTF8 = true
+				break
+			}
+		}
+		if !hasUTF8 {
+			return fmt.Errorf("files must include at least one utf8 encoded file")
+		}
+	}
+
+	// Validate constraints
+	constraints := []struct {
+		name        string
+		value       *int
+		configLimit int64
+	}{
+		{"compile_timeout", request.CompileTimeout, rt.Timeouts.Compile.Milliseconds()},
+		{"run_timeout", request.RunTimeout, rt.Timeouts.Run.Milliseconds()},
+		{"compile_cpu_time", request.CompileCPUTime, rt.CPUTimes.Compile.Milliseconds()},
+		{"run_cpu_time", request.RunCPUTime, rt.CPUTimes.Run.Milliseconds()},
+		{"max_output", request.MaxOutput, int64(rt.OutputMaxSize)},
+	}
+
+	for _, constraint := range constraints {
+		if constraint.value == nil {
+			continue
+		}
+
+		if constraint.configLimit <= 0 {
+			continue
+		}
+
+		if int64(*constraint.value) > constraint.configLimit {
+			return fmt.Errorf("%s cannot exceed the configured limit of %d",
+				constraint.name, constraint.configLimit)
+		}
+
+		if *constraint.value < 0 {
+			return fmt.Errorf("%s must be non-negative", constraint.name)
+		}
+	}
+
+	// Validate memory and disk constraints
+	memoryConstraints := []struct {
+		name        string
+		value       *int64
+		configLimit int64
+	}{
+		{"compile_memory_limit", request.CompileMemoryLimit, rt.MemoryLimits.Compile},
+		{"run_memory_limit", request.RunMemoryLimit, rt.MemoryLimits.Run},
+		{"disk_quota", request.DiskQuota, rt.DiskQuota},
+	}
+
+	for _, constraint := range memoryConstraints {
+		if constraint.value == nil {
+			continue
+		}
+
+		if constraint.configLimit <= 0 {
+			continue
+		}
+
+		if *constraint.value > constraint.configLimit {
+			return fmt.Errorf("%s cannot exceed the configured limit of %d",
+				constraint.name, constraint.configLimit)
+		}
+
+		if *constraint.value < 0 {
+			return fmt.Errorf("%s must be non-negative", constraint.name)
+		}
+	}
+
+	return nil
+}
+
+// hasFile reports whether a file with the given name was submitted
+func hasFile(files []types.CodeFile, name string) bool {
+	for _, file := range files {
+		if file.Name == name {
+			return true
+		}
+	}
+	return false
+}
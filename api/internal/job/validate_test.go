@@ -0,0 +1,73 @@
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestValidateRequestEntrypoint(t *testing.T) {
+	files := []types.CodeFile{{Name: "tests.py", Content: "x"}, {Name: "main.py", Content: "y"}}
+
+	tests := []struct {
+		name       string
+		entrypoint string
+		wantErr    bool
+	}{
+		{"Default", "", false},
+		{"Second File", "main.py", false},
+		{"Unknown File", "other.py", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &types.JobRequest{Language: "python", Version: "*", Files: files, Entrypoint: tt.entrypoint}
+			if err := ValidateRequest(request); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCodeFileNamesStartWithEntrypoint(t *testing.T) {
+	j := &Job{
+		Files:      []types.CodeFile{{Name: "a.c"}, {Name: "b.c"}, {Name: "main.c"}},
+		Entrypoint: "main.c",
+	}
+
+	if got, want := j.getCodeFileNames(), []string{"main.c", "a.c", "b.c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	j.Entrypoint = ""
+	if got := j.entrypoint(); got != "a.c" {
+		t.Errorf("Expected first file as default entrypoint, got %s", got)
+	}
+}
+
+func TestValidateRequestArchive(t *testing.T) {
+	archive := tarGzArchive(t, []archiveEntry{{name: "cmd/main.go", content: "package main", mode: 0644}})
+
+	tests := []struct {
+		name       string
+		files      []types.CodeFile
+		entrypoint string
+		wantErr    bool
+	}{
+		{"Archive Only", nil, "cmd/main.go", false},
+		{"Archive Without Entrypoint", nil, "", true},
+		{"Archive And Files", []types.CodeFile{{Name: "go.mod", Content: "module x"}}, "", false},
+		// Entries are only known after extraction, so the entrypoint is checked then
+		{"Entrypoint In Archive", []types.CodeFile{{Name: "go.mod", Content: "module x"}}, "cmd/main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &types.JobRequest{Language: "go", Version: "*", Files: tt.files, Archive: archive, Entrypoint: tt.entrypoint}
+			if err := ValidateRequest(request); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package job
+
+import (
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/types"
+)
+
+// ResolveRuntime finds the runtime a request for language and version runs on. A request for no
+// particular version (empty, "*" or "latest") gets the language's default_versions constraint,
+// or the newest installed version when none is configured.
+func (m *Manager) ResolveRuntime(language, version string) (*types.Runtime, error) {
+	if runtime.IsDefaultVersion(version) {
+		// Defaults are keyed by language name, so resolve an alias first
+		if installed := m.runtimes.GetLanguageRuntimes(language); len(installed) > 0 {
+			if constraint := m.config.DefaultVersion(installed[0].Language); constraint != "" {
+				version = constraint
+			}
+		}
+	}
+	return m.runtimes.GetLatestRuntimeMatchingLanguageVersion(language, version)
+}
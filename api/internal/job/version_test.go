@@ -0,0 +1,69 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/runtime"
+)
+
+func TestResolveRuntime(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, version := range []string{"3.11.0", "3.12.0"} {
+		packageDir := filepath.Join(dataDir, "packages", "versionlang", version)
+		if err := os.MkdirAll(packageDir, 0755); err != nil {
+			t.Fatalf("Failed to create package directory: %v", err)
+		}
+		info := `{"language":"versionlang","version":"` + version + `","aliases":["vl"]}`
+		if err := os.WriteFile(filepath.Join(packageDir, "pkg-info.json"), []byte(info), 0644); err != nil {
+			t.Fatalf("Failed to write pkg-info.json: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(packageDir, ".ppman-installed"), []byte("0"), 0644); err != nil {
+			t.Fatalf("Failed to mark package installed: %v", err)
+		}
+	}
+	runtimes := runtime.NewManager(&config.Config{DataDirectory: dataDir})
+	if err := runtimes.LoadPackages(); err != nil {
+		t.Fatalf("Failed to load packages: %v", err)
+	}
+	defer func() {
+		for _, version := range []string{"3.11.0", "3.12.0"} {
+			runtimes.UnloadPackage(filepath.Join(dataDir, "packages", "versionlang", version))
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		defaults map[string]string
+		language string
+		version  string
+		want     string
+	}{
+		{"empty picks newest", nil, "versionlang", "", "3.12.0"},
+		{"latest picks newest", nil, "versionlang", "latest", "3.12.0"},
+		{"wildcard picks newest", nil, "versionlang", "*", "3.12.0"},
+		{"configured default", map[string]string{"versionlang": "3.11.x"}, "versionlang", "", "3.11.0"},
+		{"default through alias", map[string]string{"versionlang": "3.11.x"}, "vl", "LATEST", "3.11.0"},
+		{"explicit version wins", map[string]string{"versionlang": "3.11.x"}, "versionlang", "3.12.0", "3.12.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{config: &config.Config{DefaultVersions: tt.defaults}, runtimes: runtimes}
+			rt, err := m.ResolveRuntime(tt.language, tt.version)
+			if err != nil {
+				t.Fatalf("ResolveRuntime() error = %v", err)
+			}
+			if rt.Version.String() != tt.want {
+				t.Errorf("ResolveRuntime() = %s, want %s", rt.Version, tt.want)
+			}
+		})
+	}
+
+	m := &Manager{config: &config.Config{}, runtimes: runtimes}
+	if _, err := m.ResolveRuntime("versionlang", "4.x"); err == nil {
+		t.Error("Expected an error for an uninstalled version")
+	}
+}
@@ -0,0 +1,539 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// warmStartupGrace is added to a warm process's isolate wall clock for the interpreter to start
+// before its idle timeout begins
+const warmStartupGrace = 10 * time.Second
+
+// WarmPool keeps interpreter processes started ahead of submissions so interpreted languages
+// skip their startup cost. Each process runs the runtime's warm script in a box of its own with
+// the runtime's default limits; the script waits for a JSON array of the program's arguments on
+// the first line of stdin and runs the entrypoint with the rest of stdin.
+//
+// A runtime's processes are started on its first request, which runs cold, and topped up after
+// every hit. Jobs asking for anything a process was not started with run cold as well.
+type WarmPool struct {
+	manager     *Manager
+	sizes       map[string]int
+	idleTimeout time.Duration
+	logger      *logrus.Entry
+
+	mutex    sync.Mutex
+	sets     map[string]*warmSet
+	closed   bool
+	starting sync.WaitGroup
+
+	// Counters exposed through Stats
+	hits     atomic.Int64
+	misses   atomic.Int64
+	failures atomic.Int64
+	retired  atomic.Int64
+}
+
+// warmSet holds the processes of one runtime
+type warmSet struct {
+	runtime *types.Runtime
+	ready   []*warmProcess
+	// pending counts processes being started
+	pending int
+}
+
+// warmProcess is a started warm script waiting for its program. Its placeholder job owns the
+// box and working directory, which are released by stop.
+type warmProcess struct {
+	job    *Job
+	box    *types.IsolateBox
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *os.File
+	stderr *os.File
+	// done is closed once the process has exited, with err set to its Wait error
+	done   chan struct{}
+	err    error
+	retire *time.Timer
+}
+
+// WarmPoolStats is a snapshot of the warm pool counters
+type WarmPoolStats struct {
+	Ready    int   `json:"ready"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+	Failures int64 `json:"failures"`
+	Retired  int64 `json:"retired"`
+}
+
+// NewWarmPool creates the warm pool of m, or returns nil when no language has one configured
+func NewWarmPool(m *Manager) *WarmPool {
+	if len(m.config.WarmPool) == 0 {
+		return nil
+	}
+	return &WarmPool{
+		manager:     m,
+		sizes:       m.config.WarmPool,
+		idleTimeout: m.config.WarmPoolIdleTimeout,
+		logger:      logrus.WithField("component", "warm_pool"),
+		sets:        make(map[string]*warmSet),
+	}
+}
+
+// Take hands j a warm process of its runtime, or returns nil when the job must run cold.
+// Processes are started without request environment, run flags, mounts or a terminal, so jobs
+// asking for one never take a process.
+func (p *WarmPool) Take(j *Job) *warmProcess {
+	if p == nil || !j.Runtime.Warm || j.Runtime.Compiled || len(j.Runtime.Stages) > 0 || p.sizes[j.Runtime.Language] == 0 {
+		return nil
+	}
+	if j.PTY != nil || len(j.Env) > 0 || len(j.RunFlags) > 0 || len(j.Mounts) > 0 {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return nil
+	}
+
+	key := warmKey(j.Runtime)
+	set, ok := p.sets[key]
+	if !ok {
+		set = &warmSet{runtime: j.Runtime}
+		p.sets[key] = set
+	}
+	defer p.topUp(key, set)
+
+	for i, w := range set.ready {
+		select {
+		case <-w.done:
+			// Exited while idle; watch releases it
+			continue
+		default:
+		}
+		if j.warmable(w.job) {
+			set.ready = slices.Delete(set.ready, i, i+1)
+			w.retire.Stop()
+			p.hits.Add(1)
+			return w
+		}
+	}
+	p.misses.Add(1)
+	return nil
+}
+
+// Close stops starting processes and stops every idle one
+func (p *WarmPool) Close() {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	p.closed = true
+	var idle []*warmProcess
+	for _, set := range p.sets {
+		idle = append(idle, set.ready...)
+		set.ready = nil
+	}
+	p.mutex.Unlock()
+
+	for _, w := range idle {
+		w.retire.Stop()
+		w.stop()
+	}
+	p.starting.Wait()
+}
+
+// Stats returns the current warm pool counters
+func (p *WarmPool) Stats() WarmPoolStats {
+	if p == nil {
+		return WarmPoolStats{}
+	}
+
+	p.mutex.Lock()
+	ready := 0
+	for _, set := range p.sets {
+		ready += len(set.ready)
+	}
+	p.mutex.Unlock()
+
+	return WarmPoolStats{
+		Ready:    ready,
+		Hits:     p.hits.Load(),
+		Misses:   p.misses.Load(),
+		Failures: p.failures.Load(),
+		Retired:  p.retired.Load(),
+	}
+}
+
+// warmKey identifies a runtime; packages providing several languages share a directory
+func warmKey(rt *types.Runtime) string {
+	return rt.PkgDir + ":" + rt.Language
+}
+
+// topUp starts processes until the set has its configured size. The caller holds the mutex.
+func (p *WarmPool) topUp(key string, set *warmSet) {
+	for len(set.ready)+set.pending < p.sizes[set.runtime.Language] {
+		set.pending++
+		p.starting.Add(1)
+		go p.add(key, set.runtime)
+	}
+}
+
+// add starts a process for rt and makes it available, retiring it after the idle timeout
+func (p *WarmPool) add(key string, rt *types.Runtime) {
+	defer p.starting.Done()
+
+	w, err := p.start(rt)
+
+	p.mutex.Lock()
+	set := p.sets[key]
+	set.pending--
+	if err != nil || p.closed {
+		p.mutex.Unlock()
+		if err != nil {
+			p.failures.Add(1)
+			p.logger.WithError(err).Warnf("Failed to start warm process for %s-%s", rt.Language, rt.Version)
+		} else {
+			w.stop()
+		}
+		return
+	}
+	w.retire = time.AfterFunc(p.idleTimeout, func() { p.retireIdle(key, w) })
+	set.ready = append(set.ready, w)
+	p.mutex.Unlock()
+
+	go p.watch(key, w)
+}
+
+// watch releases a process that exits before a job takes it, such as a crashed warm script.
+// It is replaced by the next request rather than immediately, so a broken script cannot spin.
+func (p *WarmPool) watch(key string, w *warmProcess) {
+	<-w.done
+
+	p.mutex.Lock()
+	removed := p.remove(key, w)
+	p.mutex.Unlock()
+	if !removed {
+		return
+	}
+
+	w.retire.Stop()
+	p.failures.Add(1)
+	w.job.logger.WithError(w.err).Warn("Warm process exited before use")
+	w.stop()
+}
+
+// retireIdle replaces a process that went unused for the idle timeout, before isolate's wall
+// clock runs out. Runtimes that were uninstalled meanwhile are dropped instead.
+func (p *WarmPool) retireIdle(key string, w *warmProcess) {
+	p.mutex.Lock()
+	removed := p.remove(key, w)
+	if removed && !p.closed {
+		set := p.sets[key]
+		if rt, err := p.manager.runtimes.GetRuntimeByNameAndVersion(set.runtime.Runtime, set.runtime.Version.String()); err == nil &&
+			rt.PkgDir == set.runtime.PkgDir {
+			p.topUp(key, set)
+		} else if len(set.ready) == 0 && set.pending == 0 {
+			delete(p.sets, key)
+		}
+	}
+	p.mutex.Unlock()
+
+	if removed {
+		p.retired.Add(1)
+		w.stop()
+	}
+}
+
+// remove takes w out of the idle processes of key, reporting whether it was idle. The caller
+// holds the mutex.
+func (p *WarmPool) remove(key string, w *warmProcess) bool {
+	set, ok := p.sets[key]
+	if !ok {
+		return false
+	}
+	i := slices.Index(set.ready, w)
+	if i < 0 {
+		return false
+	}
+	set.ready = slices.Delete(set.ready, i, i+1)
+	return true
+}
+
+// start primes a placeholder job with the runtime's defaults and starts its warm script
+func (p *WarmPool) start(rt *types.Runtime) (w *warmProcess, err error) {
+	j := p.manager.NewJob(context.Background(), rt, &types.JobRequest{})
+	j.logger = j.logger.WithField("warm", true)
+	defer func() {
+		if err != nil {
+			j.cleanup()
+		}
+	}()
+
+	if err := j.prepareWorkDir(); err != nil {
+		return nil, err
+	}
+	box, err := j.createIsolateBox()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(box.Dir, "submission"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create submission directory: %w", err)
+	}
+
+	// The run timeout is enforced by callWarm from the moment the program arrives, so isolate
+	// only stops a process that outlives its idle timeout as well
+	wallTime := warmStartupGrace + p.idleTimeout + rt.Timeouts.Run
+	cmd := p.manager.sandbox.Command(context.Background(), j, box, "warm", nil, wallTime,
+		rt.CPUTimes.Run, rt.MemoryLimits.Run)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	// Plain pipes rather than StdoutPipe, so Wait can run while the output is still unread
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	stderr, stderrWriter, err := os.Pipe()
+	if err != nil {
+		stdout.Close()
+		stdoutWriter.Close()
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	cmd.Stdout, cmd.Stderr = stdoutWriter, stderrWriter
+
+	err = cmd.Start()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		return nil, &SandboxError{Stage: "run", Err: err}
+	}
+
+	w = &warmProcess{
+		job:    j,
+		box:    box,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		w.err = cmd.Wait()
+		close(w.done)
+	}()
+	j.logger.Debugf("Started warm process in box %d", box.ID)
+	return w, nil
+}
+
+// kill kills the process and its process group if they are still running
+func (w *warmProcess) kill() {
+	if w.cmd != nil && w.cmd.Process != nil {
+		_ = killGroup(w.cmd)
+	}
+}
+
+// stop kills the process and releases its box and working directory
+func (w *warmProcess) stop() {
+	w.kill()
+	<-w.done
+	w.stdout.Close()
+	w.stderr.Close()
+	w.job.cleanup()
+}
+
+// warmable reports whether j can run in warm, whose process was started with the runtime's
+// defaults. The job may shorten the run timeout but must otherwise ask for the same limits.
+func (j *Job) warmable(warm *Job) bool {
+	return j.Network == warm.Network &&
+		j.DiskQuota == warm.DiskQuota &&
+		j.Timeouts.Run <= warm.Timeouts.Run &&
+		j.CPUTimes.Run == warm.CPUTimes.Run &&
+		j.MemoryLimits.Run == warm.MemoryLimits.Run &&
+		j.Runtime.MaxProcessCount == warm.Runtime.MaxProcessCount &&
+		j.Runtime.MaxOpenFiles == warm.Runtime.MaxOpenFiles &&
+		j.Runtime.MaxFileSize == warm.Runtime.MaxFileSize
+}
+
+// executeWarm runs the job in a warm process for Execute
+func (j *Job) executeWarm(ctx context.Context, w *warmProcess) (*types.ExecutionResult, error) {
+	j.warm = w
+	j.logger.Info("Executing job in a warm process")
+
+	if err := j.writeSubmission(w.box); err != nil {
+		return nil, fmt.Errorf("failed to prime job: %w", err)
+	}
+	j.State = types.JobStatePrimed
+
+	result := j.newResult()
+	runResult, err := j.callWarm(ctx, w, false)
+	if err != nil {
+		return nil, fmt.Errorf("run stage failed: %w", err)
+	}
+	result.Run = runResult
+	result.Files = j.collectOutputFiles(ctx, w.box)
+
+	j.State = types.JobStateExecuted
+	return result, nil
+}
+
+// executeWarmStream runs the job in a warm process for ExecuteStream
+func (j *Job) executeWarmStream(ctx context.Context, w *warmProcess) (*types.StageResult, error) {
+	j.warm = w
+	j.logger.Info("Executing job with streaming in a warm process")
+
+	if err := j.writeSubmission(w.box); err != nil {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("failed to prime job: %w", err)})
+		return nil, fmt.Errorf("failed to prime job: %w", err)
+	}
+	j.State = types.JobStatePrimed
+
+	j.sendEvent(types.StreamEvent{Type: "stage_start", Stage: "run"})
+	runResult, err := j.callWarm(ctx, w, true)
+	if err != nil {
+		j.sendEvent(types.StreamEvent{Type: "error", Error: fmt.Errorf("run stage failed: %w", err)})
+		return nil, fmt.Errorf("run stage failed: %w", err)
+	}
+
+	runCode := 0
+	if runResult.Code != nil {
+		runCode = *runResult.Code
+	}
+	j.sendEvent(types.StreamEvent{Type: "stage_end", Stage: "run", Code: runCode, Truncated: runResult.Truncated})
+	j.sendResult(nil, runResult, j.collectOutputFiles(ctx, w.box))
+
+	j.State = types.JobStateExecuted
+	return runResult, nil
+}
+
+// callWarm hands the job's program to warm process w: a JSON array of the entrypoint and
+// arguments on the first line, then the job's stdin. Since isolate's wall clock started with
+// the process, the run timeout is enforced here and the wall time measured from the hand-off.
+func (j *Job) callWarm(ctx context.Context, w *warmProcess, stream bool) (result *types.StageResult, err error) {
+	ctx, span := j.startStageSpan(ctx, "run", j.Timeouts.Run, j.CPUTimes.Run, j.MemoryLimits.Run)
+	defer func() { endStageSpan(span, result, err) }()
+
+	header, err := json.Marshal(append([]string{j.entrypoint()}, j.Args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	if stream {
+		defer j.sampleStats(w.box, "run")()
+	}
+
+	j.cmdMutex.Lock()
+	j.runningCmd = w.cmd
+	j.runningBox = w.box
+	j.cmdMutex.Unlock()
+
+	started := time.Now()
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(j.Timeouts.Run, func() {
+		timedOut.Store(true)
+		w.kill()
+	})
+	defer timer.Stop()
+
+	// Killing the process ends the box, which closes the output pipes
+	stopOnCancel := context.AfterFunc(ctx, w.kill)
+	defer stopOnCancel()
+
+	go func() {
+		defer w.stdin.Close()
+		if _, err := w.stdin.Write(append(header, '\n')); err != nil {
+			return
+		}
+		j.writeStdin(w.stdin)
+		if !stream {
+			return
+		}
+		for {
+			select {
+			case data, ok := <-j.StdinChannel:
+				if !ok {
+					return
+				}
+				w.stdin.Write([]byte(data))
+			case <-ctx.Done():
+				return
+			case <-w.done:
+				return
+			}
+		}
+	}()
+
+	var streams sync.WaitGroup
+	streams.Add(2)
+	var capture *outputCapture
+	var stdoutBuf, stderrBuf bytes.Buffer
+	var outputBuf combinedOutput
+	if stream {
+		capture = newOutputCapture(j.OutputMaxSize)
+		go func() {
+			defer streams.Done()
+			j.streamOutput(w.stdout, "run", "stdout", capture)
+		}()
+		go func() {
+			defer streams.Done()
+			j.streamOutput(w.stderr, "run", "stderr", capture)
+		}()
+	} else {
+		go func() {
+			defer streams.Done()
+			j.readWithLimit(w.stdout, &stdoutBuf, &outputBuf)
+		}()
+		go func() {
+			defer streams.Done()
+			j.readWithLimit(w.stderr, &stderrBuf, &outputBuf)
+		}()
+	}
+	streams.Wait()
+	<-w.done
+	elapsed := time.Since(started)
+
+	j.cmdMutex.Lock()
+	j.runningCmd = nil
+	j.runningBox = nil
+	j.cmdMutex.Unlock()
+
+	result = j.stageResult(w.box, w.cmd, w.err)
+	result.WallTime = elapsed.Milliseconds()
+	if timedOut.Load() {
+		result.Status = "TO"
+		result.Message = "Time limit exceeded (wall clock)"
+		result.Signal = "SIGKILL"
+		result.Code = nil
+		result.Outcome = types.OutcomeTimeout
+	}
+	if stream {
+		capture.apply(result)
+	} else {
+		result.Stdout = stdoutBuf.String()
+		result.Stderr = stderrBuf.String()
+		result.Output = outputBuf.String()
+	}
+	if err := sandboxFailure("run", result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestWarmable(t *testing.T) {
+	rt := &types.Runtime{
+		Language:     "python",
+		Timeouts:     types.Timeouts{Run: 3 * time.Second},
+		CPUTimes:     types.CPUTimes{Run: 3 * time.Second},
+		MemoryLimits: types.MemoryLimits{Run: -1},
+	}
+	m := &Manager{config: &config.Config{DisableNetworking: true}}
+	warm := m.NewJob(context.Background(), rt, &types.JobRequest{})
+
+	shorter, longer := 1000, 5000
+	memory := int64(64 << 20)
+	enabled := true
+
+	tests := []struct {
+		name    string
+		request types.JobRequest
+		want    bool
+	}{
+		{"defaults", types.JobRequest{}, true},
+		{"shorter run timeout", types.JobRequest{RunTimeout: &shorter}, true},
+		{"longer run timeout", types.JobRequest{RunTimeout: &longer}, false},
+		{"other cpu time", types.JobRequest{RunCPUTime: &shorter}, false},
+		{"other memory limit", types.JobRequest{RunMemoryLimit: &memory}, false},
+		{"networking", types.JobRequest{EnableNetwork: &enabled}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := m.NewJob(context.Background(), rt, &tt.request)
+			if got := j.warmable(warm); got != tt.want {
+				t.Errorf("warmable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarmPoolTake(t *testing.T) {
+	var disabled *WarmPool
+	if disabled.Take(nil) != nil {
+		t.Fatal("Expected a disabled pool to hand out nothing")
+	}
+
+	cfg := &config.Config{
+		DataDirectory:       t.TempDir(),
+		IsolatePath:         "/nonexistent/isolate",
+		WarmPool:            map[string]int{"python": 1},
+		WarmPoolIdleTimeout: time.Hour,
+	}
+	m := &Manager{config: cfg, sandbox: &isolateSandbox{config: cfg, pool: NewBoxPool(cfg.IsolatePath, 0, 0, 0)}}
+	p := NewWarmPool(m)
+	defer p.Close()
+
+	rt := &types.Runtime{Language: "python", Version: semver.MustParse("3.12.0"), PkgDir: "/pkg", Warm: true}
+
+	// The first request runs cold and starts the runtime's process, which fails without isolate
+	if p.Take(m.NewJob(context.Background(), rt, &types.JobRequest{})) != nil {
+		t.Fatal("Expected the first request to run cold")
+	}
+	p.starting.Wait()
+	if stats := p.Stats(); stats.Misses != 1 || stats.Failures != 1 || stats.Ready != 0 {
+		t.Fatalf("Expected one miss and one failed start, got %+v", stats)
+	}
+
+	w := &warmProcess{
+		job:    m.NewJob(context.Background(), rt, &types.JobRequest{}),
+		done:   make(chan struct{}),
+		retire: time.AfterFunc(time.Hour, func() {}),
+	}
+	p.mutex.Lock()
+	p.sets[warmKey(rt)].ready = []*warmProcess{w}
+	p.mutex.Unlock()
+
+	withEnv := m.NewJob(context.Background(), rt, &types.JobRequest{Env: map[string]string{"DEBUG": "1"}})
+	if p.Take(withEnv) != nil {
+		t.Error("Expected a job with environment variables to run cold")
+	}
+	if got := p.Take(m.NewJob(context.Background(), rt, &types.JobRequest{})); got != w {
+		t.Fatalf("Expected the idle process, got %v", got)
+	}
+	p.starting.Wait()
+	if stats := p.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Failures != 2 {
+		t.Errorf("Expected a hit and a replacement start, got %+v", stats)
+	}
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// errWatchdog is the cause of a stage context the watchdog cancelled
+var errWatchdog = errors.New("stage outlived its wall-time limit")
+
+// stageWatchdog returns the context of a stage with wall-time limit timeout. It is cancelled,
+// which kills the sandbox and stops the reads of its output, once the stage has run
+// watchdog_grace past the limit, should the sandbox fail to enforce it. expired reports whether
+// the watchdog fired; stop releases its timer once the stage has ended.
+func (j *Job) stageWatchdog(ctx context.Context, timeout time.Duration) (watched context.Context, expired func() bool, stop context.CancelFunc) {
+	grace := j.manager.config.WatchdogGrace
+	if timeout <= 0 || grace <= 0 {
+		return ctx, func() bool { return false }, func() {}
+	}
+
+	watched, stop = context.WithTimeoutCause(ctx, timeout+grace, errWatchdog)
+	return watched, func() bool { return context.Cause(watched) == errWatchdog }, stop
+}
+
+// watchdogTimeout turns the result of a stage the watchdog killed after elapsed into a wall-time
+// limit result, whatever the sandbox reported
+func (j *Job) watchdogTimeout(result *types.StageResult, elapsed time.Duration) {
+	j.logger.WithField("elapsed", elapsed).Warn("Stage outlived its wall-time limit and was killed by the watchdog")
+
+	result.Status = "TO"
+	result.Message = "Time limit exceeded (wall clock)"
+	result.Signal = "SIGKILL"
+	result.Code = nil
+	result.Outcome = types.OutcomeTimeout
+	result.WallTime = max(result.WallTime, elapsed.Milliseconds())
+}
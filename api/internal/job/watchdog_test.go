@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// unenforcedSandbox runs commands without the wall-time limit, like an isolate that hangs
+type unenforcedSandbox struct {
+	Sandbox
+}
+
+func (s unenforcedSandbox) Command(ctx context.Context, j *Job, box *types.IsolateBox, stage string, args []string,
+	timeout, cpuTime time.Duration, memoryLimit int64) *exec.Cmd {
+	return s.Sandbox.Command(ctx, j, box, stage, args, 0, cpuTime, memoryLimit)
+}
+
+func TestStageWatchdog(t *testing.T) {
+	m, rt := newLocalTestManager(t, "echo started\nsleep 30\n")
+	m.sandbox = unenforcedSandbox{m.sandbox}
+	m.config.WatchdogGrace = 200 * time.Millisecond
+
+	j := m.NewJob(context.Background(), rt, &types.JobRequest{
+		Files: []types.CodeFile{{Name: "main.sh", Content: "true"}},
+	})
+	started := time.Now()
+	result, err := j.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("Execute() took %v, want the stage killed after its 1s limit and the grace", elapsed)
+	}
+
+	run := result.Run
+	if run.Status != "TO" || run.Outcome != types.OutcomeTimeout || run.Code != nil || run.Signal != "SIGKILL" {
+		t.Errorf("Run = status %q, outcome %q, code %v, signal %q; want a wall-time timeout",
+			run.Status, run.Outcome, run.Code, run.Signal)
+	}
+	if run.WallTime < 1200 {
+		t.Errorf("Run wall time = %dms, want at least the limit and the grace", run.WallTime)
+	}
+	if run.Stdout != "started\n" {
+		t.Errorf("Run stdout = %q, want the output before the kill", run.Stdout)
+	}
+}
+
+func TestStageWatchdogDisabled(t *testing.T) {
+	m, _ := newLocalTestManager(t, "true\n")
+	j := &Job{manager: m}
+
+	for _, grace := range []time.Duration{0, time.Second} {
+		m.config.WatchdogGrace = grace
+		ctx, expired, stop := j.stageWatchdog(context.Background(), 0)
+		if ctx.Done() != nil || expired() {
+			t.Errorf("Watchdog with grace %v armed for a stage without a wall-time limit", grace)
+		}
+		stop()
+	}
+
+	m.config.WatchdogGrace = 0
+	ctx, expired, stop := j.stageWatchdog(context.Background(), time.Millisecond)
+	defer stop()
+	if ctx.Done() != nil || expired() {
+		t.Error("Watchdog armed with watchdog_grace 0")
+	}
+}
@@ -0,0 +1,61 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// workRoot is where each job's private working directory lives on the host
+func workRoot(dataDirectory string) string {
+	return filepath.Join(dataDirectory, "run")
+}
+
+// workDir is the job's working directory, holding the isolate metadata of its boxes and its
+// scratch mounts. Keying it by job ID keeps jobs, and server restarts, from sharing files even
+// when they are handed the same box ID.
+func (j *Job) workDir() string {
+	return filepath.Join(workRoot(j.manager.config.DataDirectory), j.ID)
+}
+
+// boxMetadataPath returns where isolate writes the run metadata of one of the job's boxes
+func (j *Job) boxMetadataPath(id int) string {
+	return filepath.Join(j.workDir(), fmt.Sprintf("%d-metadata.txt", id))
+}
+
+// prepareWorkDir creates the job's working directory, accessible only to the server
+func (j *Job) prepareWorkDir() error {
+	if err := os.MkdirAll(j.workDir(), 0700); err != nil {
+		return fmt.Errorf("failed to create job directory: %w", err)
+	}
+	return nil
+}
+
+// removeWorkDir removes the job's working directory once its boxes and mounts are released
+func (j *Job) removeWorkDir() {
+	if err := os.RemoveAll(j.workDir()); err != nil {
+		j.logger.WithError(err).Error("Failed to remove job directory")
+	}
+}
+
+// removeStaleWorkDirs unmounts scratch mounts and removes the working directories of jobs that
+// were running when the server stopped
+func removeStaleWorkDirs(root string) error {
+	jobs, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		scratch := filepath.Join(root, job.Name(), "scratch")
+		dirs, _ := os.ReadDir(scratch)
+		for _, dir := range dirs {
+			if err := unmountScratch(filepath.Join(scratch, dir.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return os.RemoveAll(root)
+}
@@ -0,0 +1,42 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobWorkDir(t *testing.T) {
+	dataDir := t.TempDir()
+	j := &Job{
+		ID:      "job-1",
+		logger:  logrus.NewEntry(logrus.New()),
+		manager: &Manager{config: &config.Config{DataDirectory: dataDir}},
+	}
+
+	if want := filepath.Join(dataDir, "run", "job-1", "7-metadata.txt"); j.boxMetadataPath(7) != want {
+		t.Errorf("boxMetadataPath(7) = %s, want %s", j.boxMetadataPath(7), want)
+	}
+
+	if err := j.prepareWorkDir(); err != nil {
+		t.Fatalf("prepareWorkDir() error = %v", err)
+	}
+	info, err := os.Stat(j.workDir())
+	if err != nil {
+		t.Fatalf("Expected the job directory to exist: %v", err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("Expected the job directory to be private, got %v", info.Mode().Perm())
+	}
+
+	if err := os.WriteFile(j.boxMetadataPath(7), []byte("status:TO\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	j.removeWorkDir()
+	if _, err := os.Stat(j.workDir()); !os.IsNotExist(err) {
+		t.Errorf("Expected the job directory to be removed, got %v", err)
+	}
+}
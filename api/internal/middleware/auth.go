@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+type contextKey string
+
+const (
+	apiKeyContextKey     contextKey = "api_key"
+	apiKeySlotContextKey contextKey = "api_key_slot"
+)
+
+// APIKeyStore holds the set of valid API keys and tracks per-key usage
+type APIKeyStore struct {
+	enabled  bool
+	keys     []config.APIKey
+	mutex    sync.Mutex
+	inFlight map[string]int
+}
+
+// NewAPIKeyStore creates a key store from the configured keys and the optional keys file
+func NewAPIKeyStore(cfg *config.Config) (*APIKeyStore, error) {
+	store := &APIKeyStore{
+		enabled:  cfg.AuthEnabled,
+		keys:     append([]config.APIKey{}, cfg.APIKeys...),
+		inFlight: make(map[string]int),
+	}
+
+	if cfg.APIKeysFile != "" {
+		keys, err := loadAPIKeysFile(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		store.keys = append(store.keys, keys...)
+	}
+
+	if store.enabled && len(store.keys) == 0 {
+		return nil, fmt.Errorf("auth is enabled but no api keys are configured")
+	}
+
+	return store, nil
+}
+
+// loadAPIKeysFile reads a JSON array of API keys from disk
+func loadAPIKeysFile(path string) ([]config.APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys file: %w", err)
+	}
+
+	var keys []config.APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys file: %w", err)
+	}
+
+	for i, key := range keys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("api keys file entry %d has an empty key", i)
+		}
+	}
+
+	return keys, nil
+}
+
+// Enabled reports whether authentication is enforced
+func (s *APIKeyStore) Enabled() bool {
+	return s != nil && s.enabled
+}
+
+// Lookup returns the API key matching the given secret
+func (s *APIKeyStore) Lookup(secret string) (*config.APIKey, bool) {
+	if s == nil || secret == "" {
+		return nil, false
+	}
+
+	var match *config.APIKey
+	for i := range s.keys {
+		// Compare every key in constant time to avoid leaking which prefix matched
+		if subtle.ConstantTimeCompare([]byte(s.keys[i].Key), []byte(secret)) == 1 {
+			match = &s.keys[i]
+		}
+	}
+
+	return match, match != nil
+}
+
+// Acquire reserves an in-flight slot for the key, honoring its concurrency limit
+func (s *APIKeyStore) Acquire(key *config.APIKey) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if key.MaxConcurrentJobs > 0 && s.inFlight[key.Key] >= key.MaxConcurrentJobs {
+		return false
+	}
+	s.inFlight[key.Key]++
+	return true
+}
+
+// Release frees an in-flight slot for the key
+func (s *APIKeyStore) Release(key *config.APIKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.inFlight[key.Key] <= 1 {
+		delete(s.inFlight, key.Key)
+		return
+	}
+	s.inFlight[key.Key]--
+}
+
+// apiKeySlot is the in-flight slot Auth reserves for a request. It is freed when the request
+// ends, unless work outliving the request has kept it.
+type apiKeySlot struct {
+	store *APIKeyStore
+	key   *config.APIKey
+	kept  bool
+	once  sync.Once
+}
+
+// release frees the slot; later calls do nothing
+func (s *apiKeySlot) release() {
+	s.once.Do(func() { s.store.Release(s.key) })
+}
+
+// KeepAPIKeySlot keeps the in-flight slot Auth reserved for the request in ctx past the end of
+// the request, for work such as an async job, and returns the function that frees it. It must be
+// called before the handler returns. Without a slot the returned function does nothing.
+func KeepAPIKeySlot(ctx context.Context) func() {
+	slot, ok := ctx.Value(apiKeySlotContextKey).(*apiKeySlot)
+	if !ok {
+		return func() {}
+	}
+	slot.kept = true
+	return slot.release
+}
+
+// Auth rejects requests without a valid API key when authentication is enabled.
+// The matched key is stored in the request context for downstream handlers.
+func Auth(store *APIKeyStore) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !store.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, ok := store.Lookup(ExtractAPIKey(r))
+			if !ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="coderunr"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message":"missing or invalid api key"}`))
+				return
+			}
+
+			if !store.Acquire(key) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"message":"too many concurrent requests for api key"}`))
+				return
+			}
+			slot := &apiKeySlot{store: store, key: key}
+			defer func() {
+				if !slot.kept {
+					slot.release()
+				}
+			}()
+
+			ctx := context.WithValue(WithAPIKey(r.Context(), key), apiKeySlotContextKey, slot)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WithAPIKey returns a copy of ctx carrying the authenticated API key
+func WithAPIKey(ctx context.Context, key *config.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// APIKeyFromContext returns the authenticated API key, if any
+func APIKeyFromContext(ctx context.Context) (*config.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(*config.APIKey)
+	return key, ok
+}
+
+// ExtractAPIKey reads the key from the Authorization or X-API-Key headers
+func ExtractAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// AdminAuth rejects requests that do not carry the admin token as a bearer token or in X-Admin-Token
+func AdminAuth(token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret := r.Header.Get("X-Admin-Token")
+			if secret == "" {
+				secret = ExtractAPIKey(r)
+			}
+
+			if token == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="coderunr-admin"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message":"missing or invalid admin token"}`))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
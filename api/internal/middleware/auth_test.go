@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestAuth(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(keysFile, []byte(`[{"key":"from-file","name":"file"}]`), 0600); err != nil {
+		t.Fatalf("Failed to write keys file: %v", err)
+	}
+
+	store, err := NewAPIKeyStore(&config.Config{
+		AuthEnabled: true,
+		APIKeys:     []config.APIKey{{Key: "from-config", Name: "config"}},
+		APIKeysFile: keysFile,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+
+	var seen string
+	handler := Auth(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := APIKeyFromContext(r.Context()); ok {
+			seen = key.Name
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedKey    string
+	}{
+		{"Missing Key", "", "", http.StatusUnauthorized, ""},
+		{"Invalid Key", "X-API-Key", "nope", http.StatusUnauthorized, ""},
+		{"Bearer Config Key", "Authorization", "Bearer from-config", http.StatusOK, "config"},
+		{"Header File Key", "X-API-Key", "from-file", http.StatusOK, "file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = ""
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/execute", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if seen != tt.expectedKey {
+				t.Errorf("Expected key %q in context, got %q", tt.expectedKey, seen)
+			}
+		})
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	store, err := NewAPIKeyStore(&config.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+
+	handler := Auth(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v2/execute", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	handler := AdminAuth("admin-secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{"Missing Token", "", "", http.StatusUnauthorized},
+		{"Wrong Token", "X-Admin-Token", "nope", http.StatusUnauthorized},
+		{"Header Token", "X-Admin-Token", "admin-secret", http.StatusOK},
+		{"Bearer Token", "Authorization", "Bearer admin-secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v2/admin/config", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAuthKeepSlot(t *testing.T) {
+	store, err := NewAPIKeyStore(&config.Config{
+		AuthEnabled: true,
+		APIKeys:     []config.APIKey{{Key: "secret", Name: "async", MaxConcurrentJobs: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+
+	// The handler keeps its slot, as a submitted async job does until it finishes
+	var release func()
+	handler := Auth(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release = KeepAPIKeySlot(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	serve := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/jobs", nil)
+		req.Header.Set("X-API-Key", "secret")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := serve(); code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if code := serve(); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d while the slot is kept, got %d", http.StatusTooManyRequests, code)
+	}
+
+	release()
+	release()
+	if code := serve(); code != http.StatusAccepted {
+		t.Errorf("Expected status %d once the slot is released, got %d", http.StatusAccepted, code)
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// gzipBody closes both the gzip reader and the underlying request body
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipBody) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
+
+// Decompress transparently inflates request bodies sent with Content-Encoding: gzip. The
+// compressed size is still bounded by BodyLimit; limit bounds the inflated size so a small
+// body cannot expand without end (non-positive disables the limit). Other encodings are
+// rejected with 415.
+func Decompress(limit int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			switch encoding {
+			case "", "identity":
+				next.ServeHTTP(w, r)
+				return
+			case "gzip", "x-gzip":
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				_, _ = w.Write([]byte(`{"message":"unsupported Content-Encoding, only gzip is accepted"}`))
+				return
+			}
+
+			reader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"message":"invalid gzip request body"}`))
+				return
+			}
+
+			var body io.ReadCloser = &gzipBody{Reader: reader, body: r.Body}
+			if limit > 0 {
+				body = http.MaxBytesReader(w, body, limit)
+			}
+			r.Body = body
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	payload := `{"language":"python"}`
+	large := strings.Repeat("a", 4096)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantCode int
+		wantBody string
+	}{
+		{"Plain", "", []byte(payload), http.StatusOK, payload},
+		{"Identity", "identity", []byte(payload), http.StatusOK, payload},
+		{"Gzip", "gzip", gzipBytes(t, payload), http.StatusOK, payload},
+		{"Gzip Upper Case", "GZIP", gzipBytes(t, payload), http.StatusOK, payload},
+		{"Inflated Too Large", "gzip", gzipBytes(t, large), http.StatusRequestEntityTooLarge, ""},
+		{"Invalid Gzip", "gzip", []byte("not gzip"), http.StatusBadRequest, ""},
+		{"Unsupported", "br", []byte(payload), http.StatusUnsupportedMediaType, ""},
+	}
+
+	handler := Decompress(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(strings.ToLower(r.Header.Get("Content-Encoding")), "gzip") {
+			t.Error("Content-Encoding should be removed once the body is inflated")
+		}
+		data, err := io.ReadAll(r.Body)
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			t.Fatalf("Failed to read body: %v", err)
+		}
+		w.Write(data)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/execute", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -23,6 +24,7 @@ type logFormatter struct {
 func (l *logFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
 	entry := &logEntry{
 		logger: l.logger.WithFields(logrus.Fields{
+			"request_id": middleware.GetReqID(r.Context()),
 			"method":     r.Method,
 			"path":       r.URL.Path,
 			"remote_ip":  r.RemoteAddr,
@@ -61,8 +63,8 @@ func CORS() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-API-Key, X-CSRF-Token")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 			if r.Method == "OPTIONS" {
@@ -78,8 +80,10 @@ func CORS() func(next http.Handler) http.Handler {
 // JSON ensures requests have correct content type for JSON endpoints
 func JSON(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip content type check for GET, HEAD, OPTIONS
-		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		// Skip content type check for GET, HEAD, OPTIONS and bodiless DELETE and POST, such as
+		// a replay without overrides
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions ||
+			((r.Method == http.MethodDelete || r.Method == http.MethodPost) && r.ContentLength == 0) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -96,35 +100,17 @@ func JSON(next http.Handler) http.Handler {
 	})
 }
 
-// BodyLimit limits the request body size for JSON-modifying verbs (POST/DELETE)
+// BodyLimit limits the request body size for modifying verbs (POST/PUT/PATCH/DELETE). A
+// Content-Length over the limit is rejected up front; a chunked body is cut off at the limit and
+// the handler reading it answers with WriteBodyTooLarge. Non-positive disables limiting.
 func BodyLimit(limit int64) func(next http.Handler) http.Handler {
-	// sanitize: non-positive disables limiting
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if limit > 0 && (r.Method == http.MethodPost || r.Method == http.MethodDelete) {
-				if cl := r.Header.Get("Content-Length"); cl != "" {
-					// parse content-length safely
-					var n int64
-					for i := 0; i < len(cl); i++ {
-						c := cl[i]
-						if c < '0' || c > '9' {
-							n = -1
-							break
-						}
-					}
-					if n != -1 {
-						// standard parse since digits-only
-						var val int64
-						for i := 0; i < len(cl); i++ {
-							val = val*10 + int64(cl[i]-'0')
-						}
-						if val > limit {
-							w.Header().Set("Content-Type", "application/json")
-							w.WriteHeader(http.StatusRequestEntityTooLarge)
-							_, _ = w.Write([]byte(`{"message":"request body too large"}`))
-							return
-						}
-					}
+			if limit > 0 && (r.Method == http.MethodPost || r.Method == http.MethodPut ||
+				r.Method == http.MethodPatch || r.Method == http.MethodDelete) {
+				if r.ContentLength > limit {
+					WriteBodyTooLarge(w)
+					return
 				}
 				r.Body = http.MaxBytesReader(w, r.Body, limit)
 			}
@@ -133,6 +119,19 @@ func BodyLimit(limit int64) func(next http.Handler) http.Handler {
 	}
 }
 
+// IsBodyTooLarge reports whether err comes from reading a request body past its limit
+func IsBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+// WriteBodyTooLarge writes the 413 response every route uses for an oversized request body
+func WriteBodyTooLarge(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	_, _ = w.Write([]byte(`{"message":"Request body too large","code":413}`))
+}
+
 // Recovery recovers from panics and logs them
 func Recovery(logger *logrus.Logger) func(next http.Handler) http.Handler {
 	return middleware.Recoverer
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		chunked  bool
+		wantCode int
+	}{
+		{"Within Limit", http.MethodPost, strings.Repeat("a", 64), false, http.StatusOK},
+		{"Content-Length Too Large", http.MethodPost, strings.Repeat("a", 65), false, http.StatusRequestEntityTooLarge},
+		{"Chunked Too Large", http.MethodPost, strings.Repeat("a", 65), true, http.StatusRequestEntityTooLarge},
+		{"Chunked Delete Too Large", http.MethodDelete, strings.Repeat("a", 65), true, http.StatusRequestEntityTooLarge},
+		{"GET Not Limited", http.MethodGet, strings.Repeat("a", 65), true, http.StatusOK},
+	}
+
+	handler := BodyLimit(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			if !IsBodyTooLarge(err) {
+				t.Fatalf("Failed to read body: %v", err)
+			}
+			WriteBodyTooLarge(w)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v2/execute", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("Expected %d, got %d", tt.wantCode, rr.Code)
+			}
+			if tt.wantCode == http.StatusRequestEntityTooLarge {
+				if got := rr.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("Expected a JSON error, got Content-Type %q", got)
+				}
+				if want := `{"message":"Request body too large","code":413}`; rr.Body.String() != want {
+					t.Errorf("Expected body %s, got %s", want, rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+		wantCode    int
+	}{
+		{"JSON Body", http.MethodPost, `{}`, "application/json; charset=utf-8", http.StatusOK},
+		{"Form Body", http.MethodPost, `a=1`, "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"Body Without Type", http.MethodPost, `{}`, "", http.StatusUnsupportedMediaType},
+		{"Bodiless POST", http.MethodPost, "", "", http.StatusOK},
+		{"Bodiless DELETE", http.MethodDelete, "", "", http.StatusOK},
+		{"GET", http.MethodGet, "", "", http.StatusOK},
+	}
+
+	handler := JSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v2/history/1/replay", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("Expected %d, got %d", tt.wantCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := CORS()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Preflight reached the handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v2/admin/config", nil)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected %d, got %d", http.StatusOK, rr.Code)
+	}
+	if methods := rr.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, http.MethodPatch) {
+		t.Errorf("Expected PATCH in Access-Control-Allow-Methods, got %q", methods)
+	}
+}
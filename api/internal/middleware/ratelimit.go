@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter applies token-bucket limits per client IP or per API key
+type RateLimiter struct {
+	requestsPerMinute int
+	burst             int
+	buckets           map[string]*bucket
+	mutex             sync.Mutex
+	now               func() time.Time
+}
+
+// bucket is a single token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter with the default limits for anonymous clients and keys
+// without overrides. A non-positive requestsPerMinute disables the default limit.
+func NewRateLimiter(requestsPerMinute, burst int) *RateLimiter {
+	l := &RateLimiter{
+		requestsPerMinute: requestsPerMinute,
+		burst:             burst,
+		buckets:           make(map[string]*bucket),
+		now:               time.Now,
+	}
+
+	go l.sweep()
+	return l
+}
+
+// Allow takes a token from the client's bucket. When the bucket is empty it returns
+// false and how long until the next token is available.
+func (l *RateLimiter) Allow(client string, requestsPerMinute, burst int) (bool, time.Duration) {
+	if requestsPerMinute <= 0 {
+		return true, 0
+	}
+	if burst <= 0 {
+		burst = requestsPerMinute
+	}
+	rate := float64(requestsPerMinute) / 60 // tokens per second
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep periodically drops buckets that have been idle long enough to be full again
+func (l *RateLimiter) sweep() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mutex.Lock()
+		now := l.now()
+		for client, b := range l.buckets {
+			if now.Sub(b.last) > 10*time.Minute {
+				delete(l.buckets, client)
+			}
+		}
+		l.mutex.Unlock()
+	}
+}
+
+// RateLimit rejects requests over the client's limit with 429 and a Retry-After header.
+// Requests carrying a known API key are limited per key, using the key's own limits when set;
+// all other requests are limited per client IP.
+func RateLimit(limiter *RateLimiter, store *APIKeyStore) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := "ip:" + clientIP(r)
+			rpm, burst := limiter.requestsPerMinute, limiter.burst
+
+			secret := ExtractAPIKey(r)
+			if secret == "" {
+				// WebSocket clients may authenticate with a query parameter
+				secret = r.URL.Query().Get("token")
+			}
+			if key, ok := store.Lookup(secret); ok {
+				client = "key:" + key.Key
+				if key.RequestsPerMinute > 0 {
+					rpm = key.RequestsPerMinute
+					burst = key.Burst
+				}
+			}
+
+			allowed, wait := limiter.Allow(client, rpm, burst)
+			if !allowed {
+				retryAfter := int(math.Ceil(wait.Seconds()))
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = fmt.Fprintf(w, `{"message":"rate limit exceeded, retry in %d seconds"}`, retryAfter)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the host part of the request's remote address
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
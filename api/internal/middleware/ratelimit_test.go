@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := NewRateLimiter(60, 2)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("client", 60, 2); !ok {
+			t.Fatalf("Request %d should be allowed within the burst", i)
+		}
+	}
+
+	ok, wait := limiter.Allow("client", 60, 2)
+	if ok {
+		t.Fatal("Request over the burst should be rejected")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("Expected wait of at most 1s, got %v", wait)
+	}
+
+	if ok, _ := limiter.Allow("other", 60, 2); !ok {
+		t.Error("Other clients should have their own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := limiter.Allow("client", 60, 2); !ok {
+		t.Error("Request should be allowed after a token is refilled")
+	}
+
+	if ok, _ := limiter.Allow("unlimited", 0, 0); !ok {
+		t.Error("A zero limit should disable rate limiting")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	store, err := NewAPIKeyStore(&config.Config{
+		APIKeys: []config.APIKey{{Key: "generous", Name: "generous", RequestsPerMinute: 600, Burst: 3}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+
+	limiter := NewRateLimiter(1, 1)
+	handler := RateLimit(limiter, store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/execute", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send(""); rr.Code != http.StatusOK {
+		t.Fatalf("First anonymous request: expected 200, got %d", rr.Code)
+	}
+	rr := send("")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Second anonymous request: expected 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	// The key has its own bucket and limits, independent of the client IP
+	for i := 0; i < 3; i++ {
+		if rr := send("generous"); rr.Code != http.StatusOK {
+			t.Fatalf("Keyed request %d: expected 200, got %d", i, rr.Code)
+		}
+	}
+	if rr := send("generous"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Keyed request over burst: expected 429, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var handlerTraceID string
+	r := chi.NewRouter()
+	r.Use(Tracing)
+	r.Get("/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerTraceID = trace.SpanContextFromContext(r.Context()).TraceID().String()
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name() != "GET /jobs/{id}" {
+		t.Errorf("Expected the span to be named after the route, got %q", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Expected a server span, got %v", span.SpanKind())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" || handlerTraceID != got {
+		t.Errorf("Expected the incoming trace to continue, got %s (handler saw %s)", got, handlerTraceID)
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the caller's span as parent, got %s", span.Parent().SpanID())
+	}
+
+	found := false
+	for _, attr := range span.Attributes() {
+		if attr.Key == "http.response.status_code" && attr == attribute.Int("http.response.status_code", http.StatusNotFound) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the response status on the span, got %v", span.Attributes())
+	}
+}
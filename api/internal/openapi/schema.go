@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Generator derives schemas from Go types. Named struct types become components referenced
+// by $ref; anonymous structs are inlined.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	enums   map[reflect.Type][]string
+}
+
+// NewGenerator creates an empty generator
+func NewGenerator() *Generator {
+	return &Generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+		enums:   make(map[reflect.Type][]string),
+	}
+}
+
+// Enum records the allowed values of a named string type such as a status
+func (g *Generator) Enum(v interface{}, values ...string) {
+	g.enums[reflect.TypeOf(v)] = values
+}
+
+// Named registers v's type under an explicit component name, for unexported or generic types
+func (g *Generator) Named(name string, v interface{}) *Schema {
+	t := reflect.TypeOf(v)
+	g.names[t] = name
+	return g.schema(t)
+}
+
+// Ref returns a schema for v's type, registering any components it needs
+func (g *Generator) Ref(v interface{}) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+// ArrayOf returns an array schema of v's type
+func (g *Generator) ArrayOf(v interface{}) *Schema {
+	return &Schema{Type: "array", Items: g.Ref(v)}
+}
+
+// Schemas returns the registered components
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// schema returns the schema of t
+func (g *Generator) schema(t reflect.Type) *Schema {
+	if values, ok := g.enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case t.Kind() != reflect.Ptr && encodesItself(t):
+		// Types such as semver versions encode themselves, as strings in practice
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		name := g.names[t]
+		if name == "" {
+			name = t.Name()
+		}
+		if name == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		// interface{} and anything else may hold any JSON value
+		return &Schema{}
+	}
+}
+
+// encodesItself reports whether t or *t has a custom JSON or text encoding
+func encodesItself(t reflect.Type) bool {
+	p := reflect.PointerTo(t)
+	return t.Implements(marshalerType) || t.Implements(textType) ||
+		p.Implements(marshalerType) || p.Implements(textType)
+}
+
+// object builds an object schema from a struct's exported, JSON-visible fields. Fields without
+// omitempty are required; embedded structs are flattened like encoding/json does.
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+// addFields adds t's fields to s
+func (g *Generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(s, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+type testStatus string
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testItem struct {
+	testBase
+	Name     string            `json:"name"`
+	Count    *int              `json:"count"`
+	Tags     []string          `json:"tags,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Status   testStatus        `json:"status"`
+	Created  time.Time         `json:"created_at"`
+	Version  *semver.Version   `json:"version"`
+	Children []testItem        `json:"children,omitempty"`
+	Extra    interface{}       `json:"extra,omitempty"`
+	Hidden   string            `json:"-"`
+	internal string
+	Inline   struct {
+		Value int64 `json:"value"`
+	} `json:"inline"`
+}
+
+func TestGeneratorStruct(t *testing.T) {
+	g := NewGenerator()
+	g.Enum(testStatus(""), "ok", "failed")
+
+	ref := g.Ref(testItem{})
+	if ref.Ref != "#/components/schemas/testItem" {
+		t.Fatalf("Ref() = %+v, want a component reference", ref)
+	}
+
+	item := g.Schemas()["testItem"]
+	if item == nil || item.Type != "object" {
+		t.Fatalf("testItem component = %+v", item)
+	}
+
+	wantProps := []string{"id", "name", "count", "tags", "labels", "status", "created_at", "version", "children", "extra", "inline"}
+	for _, name := range wantProps {
+		if item.Properties[name] == nil {
+			t.Errorf("Missing property %q", name)
+		}
+	}
+	if len(item.Properties) != len(wantProps) {
+		t.Errorf("Got %d properties, want %d", len(item.Properties), len(wantProps))
+	}
+
+	wantRequired := []string{"id", "name", "status", "created_at", "inline"}
+	if !reflect.DeepEqual(item.Required, wantRequired) {
+		t.Errorf("Required = %v, want %v", item.Required, wantRequired)
+	}
+
+	checks := map[string]Schema{
+		"status":     {Type: "string", Enum: []string{"ok", "failed"}},
+		"created_at": {Type: "string", Format: "date-time"},
+		"version":    {Type: "string"},
+		"count":      {Type: "integer"},
+	}
+	for name, want := range checks {
+		if got := item.Properties[name]; !reflect.DeepEqual(*got, want) {
+			t.Errorf("Property %q = %+v, want %+v", name, *got, want)
+		}
+	}
+
+	if children := item.Properties["children"]; children.Type != "array" || children.Items.Ref != ref.Ref {
+		t.Errorf("children = %+v, want an array of testItem", children)
+	}
+	if labels := item.Properties["labels"]; labels.AdditionalProperties == nil || labels.AdditionalProperties.Type != "string" {
+		t.Errorf("labels = %+v, want a string map", labels)
+	}
+	if inline := item.Properties["inline"]; inline.Ref != "" || inline.Properties["value"].Format != "int64" {
+		t.Errorf("inline = %+v, want an inlined object", inline)
+	}
+}
+
+func TestGeneratorNamed(t *testing.T) {
+	g := NewGenerator()
+	ref := g.Named("Base", testBase{})
+	if ref.Ref != "#/components/schemas/Base" || g.Schemas()["Base"] == nil {
+		t.Errorf("Named() = %+v, schemas %v", ref, g.Schemas())
+	}
+}
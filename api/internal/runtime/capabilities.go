@@ -0,0 +1,29 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// knownCapabilities are the tools pkg-info.json may declare, each run by the package script
+// of the same name
+var knownCapabilities = []string{"format", "lint"}
+
+// readCapabilities validates the capabilities a package declares and returns them as a set.
+// Each must be known and have its script in the package directory.
+func readCapabilities(packageDir string, declared []string) (map[string]bool, error) {
+	set := make(map[string]bool, len(declared))
+	for _, capability := range declared {
+		if !slices.Contains(knownCapabilities, capability) {
+			return nil, fmt.Errorf("capabilities: unknown capability %q", capability)
+		}
+		info, err := os.Stat(filepath.Join(packageDir, capability))
+		if err != nil || info.IsDir() {
+			return nil, fmt.Errorf("capabilities: package has no %s script", capability)
+		}
+		set[capability] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// AmbiguousLanguageError is returned when a file could be written in several installed languages
+type AmbiguousLanguageError struct {
+	File       string
+	Candidates []string
+}
+
+func (e *AmbiguousLanguageError) Error() string {
+	return fmt.Sprintf("language of %s is ambiguous (%s); set language", e.File, strings.Join(e.Candidates, ", "))
+}
+
+// DetectLanguage infers the language of a request from its main file: the entrypoint, or the
+// first file. Installed runtimes claim file extensions through their package's "extensions";
+// a shebang line naming a language or one of its aliases narrows or decides the match.
+func (m *Manager) DetectLanguage(files []types.CodeFile, entrypoint string) (string, error) {
+	if len(files) == 0 {
+		return "", fmt.Errorf("language is required when submitting only an archive")
+	}
+	main := files[0]
+	if entrypoint != "" {
+		for _, file := range files {
+			if file.Name == entrypoint {
+				main = file
+			}
+		}
+	}
+
+	ext := strings.ToLower(path.Ext(main.Name))
+	interpreter := shebangInterpreter(main)
+
+	m.mutex.RLock()
+	var byExt, byShebang []string
+	for _, rt := range m.runtimes {
+		if ext != "" && slices.Contains(rt.Extensions, ext) && !slices.Contains(byExt, rt.Language) {
+			byExt = append(byExt, rt.Language)
+		}
+		if interpreter != "" && (rt.Language == interpreter || slices.Contains(rt.Aliases, interpreter)) &&
+			!slices.Contains(byShebang, rt.Language) {
+			byShebang = append(byShebang, rt.Language)
+		}
+	}
+	m.mutex.RUnlock()
+
+	candidates := byExt
+	if len(byShebang) > 0 {
+		candidates = byShebang
+		if both := intersect(byExt, byShebang); len(both) > 0 {
+			candidates = both
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("could not detect the language of %s; set language", main.Name)
+	case 1:
+		return candidates[0], nil
+	default:
+		slices.Sort(candidates)
+		return "", &AmbiguousLanguageError{File: main.Name, Candidates: candidates}
+	}
+}
+
+// shebangInterpreter returns the program named by the file's #! line, skipping /usr/bin/env,
+// or "" without one
+func shebangInterpreter(file types.CodeFile) string {
+	content := file.Content
+	if file.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return ""
+		}
+		content = string(decoded)
+	}
+
+	line, _, _ := bufio.NewReader(strings.NewReader(content)).ReadLine()
+	rest, ok := strings.CutPrefix(string(line), "#!")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(rest)
+	if len(fields) > 0 && path.Base(fields[0]) == "env" {
+		fields = fields[1:]
+		// env -S splits the rest of the line itself
+		if len(fields) > 0 && fields[0] == "-S" {
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return path.Base(fields[0])
+}
+
+// intersect returns the elements of a that are also in b
+func intersect(a, b []string) []string {
+	var both []string
+	for _, item := range a {
+		if slices.Contains(b, item) {
+			both = append(both, item)
+		}
+	}
+	return both
+}
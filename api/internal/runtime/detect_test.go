@@ -0,0 +1,61 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	manager := &Manager{runtimes: []types.Runtime{
+		{Language: "python", Aliases: []string{"py", "python3"}, Extensions: []string{".py"}},
+		{Language: "c", Extensions: []string{".c", ".h"}},
+		{Language: "c++", Aliases: []string{"cpp"}, Extensions: []string{".cpp", ".h"}},
+		{Language: "bash", Extensions: []string{".sh"}},
+	}}
+
+	file := func(name, content string) types.CodeFile {
+		return types.CodeFile{Name: name, Content: content}
+	}
+
+	tests := []struct {
+		name       string
+		files      []types.CodeFile
+		entrypoint string
+		want       string
+		candidates []string
+	}{
+		{name: "extension", files: []types.CodeFile{file("main.PY", "print(1)")}, want: "python"},
+		{name: "entrypoint", files: []types.CodeFile{file("lib.c", "int x;"), file("run.sh", "echo")}, entrypoint: "run.sh", want: "bash"},
+		{name: "shebang without extension", files: []types.CodeFile{file("script", "#!/usr/bin/env python3\nprint(1)")}, want: "python"},
+		{name: "shebang decides ambiguity", files: []types.CodeFile{file("x.h", "#!/usr/local/bin/cpp -E\n")}, want: "c++"},
+		{name: "base64 shebang", files: []types.CodeFile{{Name: "tool", Content: base64.StdEncoding.EncodeToString([]byte("#!/bin/bash\n")), Encoding: "base64"}}, want: "bash"},
+		{name: "ambiguous", files: []types.CodeFile{file("util.h", "int f();")}, candidates: []string{"c", "c++"}},
+		{name: "unknown", files: []types.CodeFile{file("main.rs", "fn main() {}")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := manager.DetectLanguage(tt.files, tt.entrypoint)
+			if tt.want != "" {
+				if err != nil || got != tt.want {
+					t.Errorf("DetectLanguage() = %q, %v, want %q", got, err, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("DetectLanguage() = %q, want an error", got)
+			}
+			var ambiguous *AmbiguousLanguageError
+			if errors.As(err, &ambiguous) != (tt.candidates != nil) {
+				t.Fatalf("DetectLanguage() error = %v", err)
+			}
+			if tt.candidates != nil && !reflect.DeepEqual(ambiguous.Candidates, tt.candidates) {
+				t.Errorf("Candidates = %v, want %v", ambiguous.Candidates, tt.candidates)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,18 +13,23 @@ import (
 	"github.com/Masterminds/semver/v3"
 	"github.com/coderunr/api/internal/config"
 	"github.com/coderunr/api/internal/types"
+	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	runtimes []types.Runtime
-	mutex    sync.RWMutex
-	logger   = logrus.WithField("component", "runtime")
-)
+var logger = logrus.WithField("component", "runtime")
 
-// Manager handles runtime operations
+// Manager handles runtime operations and holds the runtimes loaded from its data directory
 type Manager struct {
-	config *config.Config
+	config  *config.Config
+	watcher *fsnotify.Watcher
+
+	mutex    sync.RWMutex
+	runtimes []types.Runtime
+
+	// Job activity by package directory, for evicting unused packages
+	usageMutex sync.Mutex
+	usage      map[string]*packageUse
 }
 
 // NewManager creates a new runtime manager
@@ -37,13 +43,11 @@ func NewManager(cfg *config.Config) *Manager {
 func (m *Manager) LoadPackages() error {
 	packagesDir := filepath.Join(m.config.DataDirectory, "packages")
 
-	// Reset current runtimes before reloading to avoid duplicates and drop removed packages
-	mutex.Lock()
-	runtimes = []types.Runtime{}
-	mutex.Unlock()
-
 	if _, err := os.Stat(packagesDir); os.IsNotExist(err) {
 		logger.Warn("Packages directory does not exist, creating it")
+		m.mutex.Lock()
+		m.runtimes = []types.Runtime{}
+		m.mutex.Unlock()
 		if err := os.MkdirAll(packagesDir, 0755); err != nil {
 			return fmt.Errorf("failed to create packages directory: %w", err)
 		}
@@ -55,6 +59,8 @@ func (m *Manager) LoadPackages() error {
 		return fmt.Errorf("failed to read packages directory: %w", err)
 	}
 
+	// Build the new runtime list first and swap it in, so lookups never see a partial list
+	loaded := []types.Runtime{}
 	for _, lang := range languages {
 		if !lang.IsDir() {
 			continue
@@ -73,14 +79,20 @@ func (m *Manager) LoadPackages() error {
 			}
 
 			packageDir := filepath.Join(langDir, version.Name())
-			if err := m.loadPackage(packageDir); err != nil {
+			packageRuntimes, err := m.readPackage(packageDir)
+			if err != nil {
 				logger.WithError(err).Warnf("Failed to load package: %s", packageDir)
 				continue
 			}
+			loaded = append(loaded, packageRuntimes...)
 		}
 	}
 
-	logger.Infof("Loaded %d runtimes", len(runtimes))
+	m.mutex.Lock()
+	m.runtimes = loaded
+	m.mutex.Unlock()
+
+	logger.Infof("Loaded %d runtimes", len(loaded))
 	return nil
 }
 
@@ -89,19 +101,56 @@ func (m *Manager) LoadPackage(packageDir string) error {
 	return m.loadPackage(packageDir)
 }
 
-// loadPackage loads a single package from the given directory
+// loadPackage loads a single package from the given directory, replacing any runtimes
+// previously loaded from it
 func (m *Manager) loadPackage(packageDir string) error {
+	packageRuntimes, err := m.readPackage(packageDir)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.runtimes = append(withoutPackage(m.runtimes, packageDir), packageRuntimes...)
+	return nil
+}
+
+// UnloadPackage removes the runtimes provided by the package installed at packageDir
+func (m *Manager) UnloadPackage(packageDir string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	before := len(m.runtimes)
+	m.runtimes = withoutPackage(m.runtimes, packageDir)
+	logger.Debugf("Unloaded %d runtimes from %s", before-len(m.runtimes), packageDir)
+}
+
+// withoutPackage returns a copy of list without the runtimes loaded from packageDir
+func withoutPackage(list []types.Runtime, packageDir string) []types.Runtime {
+	kept := make([]types.Runtime, 0, len(list))
+	for _, rt := range list {
+		if rt.PkgDir != packageDir {
+			kept = append(kept, rt)
+		}
+	}
+	return kept
+}
+
+// readPackage reads the runtimes provided by the package in the given directory.
+// Packages that are not marked as installed provide no runtimes.
+func (m *Manager) readPackage(packageDir string) ([]types.Runtime, error) {
 	// Check if package is installed
 	installedFile := filepath.Join(packageDir, ".ppman-installed")
 	if _, err := os.Stat(installedFile); os.IsNotExist(err) {
-		return nil // Package not installed, skip
+		return nil, nil // Package not installed, skip
 	}
 
 	// Read package info
 	infoFile := filepath.Join(packageDir, "pkg-info.json")
 	infoData, err := os.ReadFile(infoFile)
 	if err != nil {
-		return fmt.Errorf("failed to read pkg-info.json: %w", err)
+		return nil, fmt.Errorf("failed to read pkg-info.json: %w", err)
 	}
 
 	var info struct {
@@ -109,21 +158,36 @@ func (m *Manager) loadPackage(packageDir string) error {
 		Version       string   `json:"version"`
 		BuildPlatform string   `json:"build_platform"`
 		Aliases       []string `json:"aliases"`
+		Extensions    []string `json:"extensions"`
 		Provides      []struct {
 			Language       string                 `json:"language"`
 			Aliases        []string               `json:"aliases"`
+			Extensions     []string               `json:"extensions"`
 			LimitOverrides map[string]interface{} `json:"limit_overrides"`
 		} `json:"provides"`
 		LimitOverrides map[string]interface{} `json:"limit_overrides"`
+		// Stages replaces the compile and run scripts with a pipeline run in order
+		Stages []stageInfo `json:"stages"`
+		// Capabilities lists the tools the package ships scripts for, "format" and "lint"
+		Capabilities []string `json:"capabilities"`
 	}
 
 	if err := json.Unmarshal(infoData, &info); err != nil {
-		return fmt.Errorf("failed to parse pkg-info.json: %w", err)
+		return nil, fmt.Errorf("failed to parse pkg-info.json: %w", err)
 	}
 
 	version, err := semver.NewVersion(info.Version)
 	if err != nil {
-		return fmt.Errorf("failed to parse version %s: %w", info.Version, err)
+		return nil, fmt.Errorf("failed to parse version %s: %w", info.Version, err)
+	}
+
+	stages, err := readStages(packageDir, info.Stages)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pkg-info.json: %w", err)
+	}
+	capabilities, err := readCapabilities(packageDir, info.Capabilities)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pkg-info.json: %w", err)
 	}
 
 	// Check if package has compile script
@@ -133,6 +197,24 @@ func (m *Manager) loadPackage(packageDir string) error {
 		compiled = true
 	}
 
+	// Check if package has an interactive session script
+	repl := false
+	if _, err := os.Stat(filepath.Join(packageDir, "repl")); err == nil {
+		repl = true
+	}
+
+	// Check if package ships a language server for editor sessions
+	lsp := false
+	if _, err := os.Stat(filepath.Join(packageDir, "lsp")); err == nil {
+		lsp = true
+	}
+
+	// Check if package can start its interpreter ahead of a submission
+	warm := false
+	if _, err := os.Stat(filepath.Join(packageDir, "warm")); err == nil {
+		warm = true
+	}
+
 	// Load environment variables
 	envVars, err := m.loadEnvVars(packageDir)
 	if err != nil {
@@ -140,8 +222,7 @@ func (m *Manager) loadPackage(packageDir string) error {
 		envVars = []string{}
 	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
+	var packageRuntimes []types.Runtime
 
 	// Handle provides field (multiple languages in one package)
 	if len(info.Provides) > 0 {
@@ -150,6 +231,7 @@ func (m *Manager) loadPackage(packageDir string) error {
 				Language:        provide.Language,
 				Version:         version,
 				Aliases:         provide.Aliases,
+				Extensions:      normalizeExtensions(provide.Extensions),
 				Platform:        info.BuildPlatform,
 				OS:              parseOS(info.BuildPlatform),
 				Arch:            parseArch(info.BuildPlatform),
@@ -162,16 +244,25 @@ func (m *Manager) loadPackage(packageDir string) error {
 				MaxOpenFiles:    m.computeIntLimit(provide.Language, "max_open_files", provide.LimitOverrides),
 				MaxFileSize:     m.computeInt64Limit(provide.Language, "max_file_size", provide.LimitOverrides),
 				OutputMaxSize:   m.computeIntLimit(provide.Language, "output_max_size", provide.LimitOverrides),
+				DiskQuota:       m.computeInt64Limit(provide.Language, "disk_quota", provide.LimitOverrides),
+				MemoryOverhead:  m.computeInt64Limit(provide.Language, "memory_overhead", provide.LimitOverrides),
 				Compiled:        compiled,
+				REPL:            repl,
+				LSP:             lsp,
+				Format:          capabilities["format"],
+				Lint:            capabilities["lint"],
+				Warm:            warm,
 				EnvVars:         envVars,
+				Stages:          stages,
 			}
-			runtimes = append(runtimes, runtime)
+			packageRuntimes = append(packageRuntimes, runtime)
 		}
 	} else {
 		runtime := types.Runtime{
 			Language:        info.Language,
 			Version:         version,
 			Aliases:         info.Aliases,
+			Extensions:      normalizeExtensions(info.Extensions),
 			Platform:        info.BuildPlatform,
 			OS:              parseOS(info.BuildPlatform),
 			Arch:            parseArch(info.BuildPlatform),
@@ -184,14 +275,35 @@ func (m *Manager) loadPackage(packageDir string) error {
 			MaxOpenFiles:    m.computeIntLimit(info.Language, "max_open_files", info.LimitOverrides),
 			MaxFileSize:     m.computeInt64Limit(info.Language, "max_file_size", info.LimitOverrides),
 			OutputMaxSize:   m.computeIntLimit(info.Language, "output_max_size", info.LimitOverrides),
+			DiskQuota:       m.computeInt64Limit(info.Language, "disk_quota", info.LimitOverrides),
+			MemoryOverhead:  m.computeInt64Limit(info.Language, "memory_overhead", info.LimitOverrides),
 			Compiled:        compiled,
+			REPL:            repl,
+			LSP:             lsp,
+			Format:          capabilities["format"],
+			Lint:            capabilities["lint"],
+			Warm:            warm,
 			EnvVars:         envVars,
+			Stages:          stages,
 		}
-		runtimes = append(runtimes, runtime)
+		packageRuntimes = append(packageRuntimes, runtime)
 	}
 
 	logger.Debugf("Loaded package %s-%s", info.Language, info.Version)
-	return nil
+	return packageRuntimes, nil
+}
+
+// normalizeExtensions lower-cases extensions and adds a missing leading dot
+func normalizeExtensions(extensions []string) []string {
+	normalized := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized = append(normalized, ext)
+	}
+	return normalized
 }
 
 // loadEnvVars loads environment variables from the .env file
@@ -215,27 +327,37 @@ func (m *Manager) loadEnvVars(packageDir string) ([]string, error) {
 }
 
 // GetRuntimes returns all loaded runtimes
-func GetRuntimes() []types.Runtime {
-	mutex.RLock()
-	defer mutex.RUnlock()
+func (m *Manager) GetRuntimes() []types.Runtime {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
-	result := make([]types.Runtime, len(runtimes))
-	copy(result, runtimes)
+	result := make([]types.Runtime, len(m.runtimes))
+	copy(result, m.runtimes)
 	return result
 }
 
-// GetLatestRuntimeMatchingLanguageVersion finds the latest runtime matching language and version
-func GetLatestRuntimeMatchingLanguageVersion(language, version string) (*types.Runtime, error) {
+// IsDefaultVersion reports whether version asks for no particular version: empty, "*" or "latest"
+func IsDefaultVersion(version string) bool {
+	version = strings.TrimSpace(version)
+	return version == "" || version == "*" || strings.EqualFold(version, "latest")
+}
+
+// GetLatestRuntimeMatchingLanguageVersion finds the latest runtime matching language and version.
+// A default version matches every version.
+func (m *Manager) GetLatestRuntimeMatchingLanguageVersion(language, version string) (*types.Runtime, error) {
+	if IsDefaultVersion(version) {
+		version = "*"
+	}
 	constraint, err := semver.NewConstraint(version)
 	if err != nil {
 		return nil, fmt.Errorf("invalid version constraint: %w", err)
 	}
 
-	mutex.RLock()
-	defer mutex.RUnlock()
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
 	var candidates []types.Runtime
-	for _, rt := range runtimes {
+	for _, rt := range m.runtimes {
 		// Check if language matches (either language name or alias)
 		if rt.Language == language || contains(rt.Aliases, language) {
 			if constraint.Check(rt.Version) {
@@ -259,17 +381,34 @@ func GetLatestRuntimeMatchingLanguageVersion(language, version string) (*types.R
 	return &latest, nil
 }
 
+// GetLanguageRuntimes returns every runtime of a language or alias, newest version first
+func (m *Manager) GetLanguageRuntimes(language string) []types.Runtime {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var result []types.Runtime
+	for _, rt := range m.runtimes {
+		if rt.Language == language || contains(rt.Aliases, language) {
+			result = append(result, rt)
+		}
+	}
+	sort.SliceStable(result, func(i, k int) bool {
+		return result[i].Version.GreaterThan(result[k].Version)
+	})
+	return result
+}
+
 // GetRuntimeByNameAndVersion finds a runtime by exact name and version
-func GetRuntimeByNameAndVersion(runtime, version string) (*types.Runtime, error) {
+func (m *Manager) GetRuntimeByNameAndVersion(runtime, version string) (*types.Runtime, error) {
 	constraint, err := semver.NewConstraint(version)
 	if err != nil {
 		return nil, fmt.Errorf("invalid version constraint: %w", err)
 	}
 
-	mutex.RLock()
-	defer mutex.RUnlock()
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
-	for _, rt := range runtimes {
+	for _, rt := range m.runtimes {
 		if (rt.Runtime == runtime || (rt.Runtime == "" && rt.Language == runtime)) &&
 			constraint.Check(rt.Version) {
 			return &rt, nil
@@ -396,6 +535,8 @@ func (m *Manager) computeInt64Limit(language, limitName string, overrides map[st
 		return m.config.RunMemoryLimit
 	case "max_file_size":
 		return m.config.MaxFileSize
+	case "disk_quota":
+		return m.config.DiskQuota
 	default:
 		return -1
 	}
@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestUnloadPackage(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := NewManager(&config.Config{DataDirectory: dataDir})
+
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		packageDir := filepath.Join(dataDir, "packages", "unloadlang", version)
+		if err := os.MkdirAll(packageDir, 0755); err != nil {
+			t.Fatalf("Failed to create package directory: %v", err)
+		}
+		info := `{"language":"unloadlang","version":"` + version + `"}`
+		if err := os.WriteFile(filepath.Join(packageDir, "pkg-info.json"), []byte(info), 0644); err != nil {
+			t.Fatalf("Failed to write pkg-info.json: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(packageDir, ".ppman-installed"), []byte("0"), 0644); err != nil {
+			t.Fatalf("Failed to mark package installed: %v", err)
+		}
+	}
+
+	if err := manager.LoadPackages(); err != nil {
+		t.Fatalf("Failed to load packages: %v", err)
+	}
+
+	manager.UnloadPackage(filepath.Join(dataDir, "packages", "unloadlang", "2.0.0"))
+
+	rt, err := manager.GetLatestRuntimeMatchingLanguageVersion("unloadlang", "*")
+	if err != nil {
+		t.Fatalf("Expected remaining runtime, got %v", err)
+	}
+	if rt.Version.String() != "1.0.0" {
+		t.Errorf("Expected 1.0.0 to remain, got %s", rt.Version.String())
+	}
+	if _, err := manager.GetLatestRuntimeMatchingLanguageVersion("unloadlang", "2.0.0"); err == nil {
+		t.Error("Expected unloaded runtime to be gone")
+	}
+}
+
+func TestGetLanguageRuntimes(t *testing.T) {
+	manager := &Manager{runtimes: []types.Runtime{
+		{Language: "python", Version: semver.MustParse("3.11.0"), Aliases: []string{"py"}},
+		{Language: "python", Version: semver.MustParse("3.12.0"), Aliases: []string{"py", "python3"}},
+		{Language: "c", Version: semver.MustParse("10.2.0")},
+	}}
+
+	got := manager.GetLanguageRuntimes("py")
+	if len(got) != 2 {
+		t.Fatalf("Expected two python runtimes, got %d", len(got))
+	}
+	if got[0].Version.String() != "3.12.0" || got[1].Version.String() != "3.11.0" {
+		t.Errorf("Expected newest first, got %s then %s", got[0].Version, got[1].Version)
+	}
+	if got := manager.GetLanguageRuntimes("ruby"); len(got) != 0 {
+		t.Errorf("Expected no runtimes for an unknown language, got %d", len(got))
+	}
+}
+
+func TestManagersDoNotShareRuntimes(t *testing.T) {
+	first := &Manager{runtimes: []types.Runtime{{Language: "python", Version: semver.MustParse("3.12.0")}}}
+	second := &Manager{}
+
+	if _, err := first.GetLatestRuntimeMatchingLanguageVersion("python", "*"); err != nil {
+		t.Fatalf("Expected python in the first manager, got %v", err)
+	}
+	if _, err := second.GetLatestRuntimeMatchingLanguageVersion("python", "*"); err == nil {
+		t.Error("Expected the second manager to have no runtimes")
+	}
+	if got := len(second.GetRuntimes()); got != 0 {
+		t.Errorf("Expected no runtimes in the second manager, got %d", got)
+	}
+}
+
+func TestReadStages(t *testing.T) {
+	packageDir := t.TempDir()
+	for _, name := range []string{"build", "test"} {
+		if err := os.WriteFile(filepath.Join(packageDir, name), []byte("#!/bin/bash\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stages, err := readStages(packageDir, []stageInfo{{Name: "build", Timeout: 1500, MemoryLimit: -1}, {Name: "test"}})
+	if err != nil {
+		t.Fatalf("readStages() error = %v", err)
+	}
+	if len(stages) != 2 || stages[0].Timeout != 1500*time.Millisecond || stages[0].MemoryLimit != -1 || stages[1].Name != "test" {
+		t.Errorf("readStages() = %+v", stages)
+	}
+
+	for name, declared := range map[string][]stageInfo{
+		"missing script": {{Name: "deploy"}},
+		"duplicate":      {{Name: "build"}, {Name: "build"}},
+		"invalid name":   {{Name: "../build"}},
+		"negative limit": {{Name: "build", CPUTime: -1}},
+	} {
+		if _, err := readStages(packageDir, declared); err == nil {
+			t.Errorf("readStages() with %s succeeded", name)
+		}
+	}
+}
+
+func TestReadCapabilities(t *testing.T) {
+	packageDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(packageDir, "format"), []byte("#!/bin/bash\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	capabilities, err := readCapabilities(packageDir, []string{"format"})
+	if err != nil || !capabilities["format"] {
+		t.Errorf("readCapabilities() = %v, %v; want format", capabilities, err)
+	}
+
+	if _, err := readCapabilities(packageDir, []string{"deploy"}); err == nil {
+		t.Error("readCapabilities() with an unknown capability succeeded")
+	}
+	if _, err := readCapabilities(t.TempDir(), []string{"format"}); err == nil {
+		t.Error("readCapabilities() without the format script succeeded")
+	}
+}
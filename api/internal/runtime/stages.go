@@ -0,0 +1,54 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// stageName matches the pipeline stage names pkg-info.json may declare
+var stageName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// stageInfo is a pipeline stage as declared in pkg-info.json, with times in milliseconds
+type stageInfo struct {
+	Name        string `json:"name"`
+	Timeout     int64  `json:"timeout"`
+	CPUTime     int64  `json:"cpu_time"`
+	MemoryLimit int64  `json:"memory_limit"`
+}
+
+// readStages validates the pipeline stages a package declares. Each needs a unique name and an
+// executable script of that name in the package directory.
+func readStages(packageDir string, declared []stageInfo) ([]types.PipelineStage, error) {
+	if len(declared) == 0 {
+		return nil, nil
+	}
+
+	stages := make([]types.PipelineStage, 0, len(declared))
+	seen := make(map[string]bool, len(declared))
+	for i, stage := range declared {
+		if !stageName.MatchString(stage.Name) || seen[stage.Name] {
+			return nil, fmt.Errorf("stages[%d]: name %q must be unique and lowercase", i, stage.Name)
+		}
+		seen[stage.Name] = true
+		if stage.Timeout < 0 || stage.CPUTime < 0 || stage.MemoryLimit < -1 {
+			return nil, fmt.Errorf("stages[%d]: limits of %s must not be negative", i, stage.Name)
+		}
+		info, err := os.Stat(filepath.Join(packageDir, stage.Name))
+		if err != nil || info.IsDir() {
+			return nil, fmt.Errorf("stages[%d]: package has no %s script", i, stage.Name)
+		}
+
+		stages = append(stages, types.PipelineStage{
+			Name:        stage.Name,
+			Timeout:     time.Duration(stage.Timeout) * time.Millisecond,
+			CPUTime:     time.Duration(stage.CPUTime) * time.Millisecond,
+			MemoryLimit: stage.MemoryLimit,
+		})
+	}
+	return stages, nil
+}
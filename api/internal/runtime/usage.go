@@ -0,0 +1,46 @@
+package runtime
+
+import "time"
+
+// packageUse records when jobs last ran on a package's runtimes and how many are running now
+type packageUse struct {
+	lastUsed time.Time
+	running  int
+}
+
+// Use records a job starting on a runtime loaded from packageDir and returns the function that
+// records it finishing
+func (m *Manager) Use(packageDir string) func() {
+	m.usageMutex.Lock()
+	defer m.usageMutex.Unlock()
+
+	if m.usage == nil {
+		m.usage = make(map[string]*packageUse)
+	}
+	use, ok := m.usage[packageDir]
+	if !ok {
+		use = &packageUse{}
+		m.usage[packageDir] = use
+	}
+	use.running++
+	use.lastUsed = time.Now()
+
+	return func() {
+		m.usageMutex.Lock()
+		defer m.usageMutex.Unlock()
+		use.running--
+		use.lastUsed = time.Now()
+	}
+}
+
+// PackageUse returns when a job last ran on a runtime loaded from packageDir, zero if none has
+// since startup, and whether one is running now
+func (m *Manager) PackageUse(packageDir string) (lastUsed time.Time, running bool) {
+	m.usageMutex.Lock()
+	defer m.usageMutex.Unlock()
+
+	if use, ok := m.usage[packageDir]; ok {
+		return use.lastUsed, use.running > 0
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,27 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestPackageUse(t *testing.T) {
+	m := NewManager(&config.Config{})
+
+	if lastUsed, running := m.PackageUse("/pkg/python/3.12.0"); !lastUsed.IsZero() || running {
+		t.Errorf("PackageUse() of an unused package = %v, %v", lastUsed, running)
+	}
+
+	first := m.Use("/pkg/python/3.12.0")
+	second := m.Use("/pkg/python/3.12.0")
+	first()
+	if _, running := m.PackageUse("/pkg/python/3.12.0"); !running {
+		t.Error("Expected the package to be running while a job is")
+	}
+	second()
+	lastUsed, running := m.PackageUse("/pkg/python/3.12.0")
+	if running || lastUsed.IsZero() {
+		t.Errorf("PackageUse() after the jobs finished = %v, %v", lastUsed, running)
+	}
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay is how long the watcher waits for filesystem activity to settle before reloading,
+// so a package being copied in is only picked up once its files are in place
+const reloadDelay = time.Second
+
+// Watch reloads runtimes whenever packages are added to or removed from the packages
+// directory. It watches the packages, language and version directories, since a package
+// becomes loadable once its .ppman-installed marker is written.
+func (m *Manager) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create packages watcher: %w", err)
+	}
+
+	packagesDir := filepath.Join(m.config.DataDirectory, "packages")
+	if err := watcher.Add(packagesDir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch packages directory: %w", err)
+	}
+	addPackageWatches(watcher, packagesDir)
+
+	m.watcher = watcher
+	go m.watch(watcher, packagesDir)
+
+	logger.Infof("Watching %s for package changes", packagesDir)
+	return nil
+}
+
+// StopWatching stops the packages watcher started by Watch
+func (m *Manager) StopWatching() {
+	if m.watcher != nil {
+		m.watcher.Close()
+	}
+}
+
+// watch reloads runtimes once events stop arriving for reloadDelay
+func (m *Manager) watch(watcher *fsnotify.Watcher, packagesDir string) {
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				// New language or version directories need their own watches
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					addPackageWatches(watcher, packagesDir)
+				}
+			}
+			timer.Reset(reloadDelay)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.WithError(err).Warn("Packages watcher error")
+
+		case <-timer.C:
+			before := len(m.GetRuntimes())
+			if err := m.LoadPackages(); err != nil {
+				logger.WithError(err).Error("Failed to reload packages")
+				continue
+			}
+			if after := len(m.GetRuntimes()); after != before {
+				logger.Infof("Packages changed on disk, runtimes %d -> %d", before, after)
+			}
+		}
+	}
+}
+
+// addPackageWatches watches every language and version directory below packagesDir.
+// Adding an already watched path is a no-op; removed directories drop out on their own.
+func addPackageWatches(watcher *fsnotify.Watcher, packagesDir string) {
+	languages, err := os.ReadDir(packagesDir)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read packages directory")
+		return
+	}
+
+	for _, lang := range languages {
+		if !lang.IsDir() {
+			continue
+		}
+
+		langDir := filepath.Join(packagesDir, lang.Name())
+		if err := watcher.Add(langDir); err != nil {
+			logger.WithError(err).Warnf("Failed to watch %s", langDir)
+			continue
+		}
+
+		versions, err := os.ReadDir(langDir)
+		if err != nil {
+			continue
+		}
+		for _, version := range versions {
+			if !version.IsDir() {
+				continue
+			}
+			versionDir := filepath.Join(langDir, version.Name())
+			if err := watcher.Add(versionDir); err != nil {
+				logger.WithError(err).Warnf("Failed to watch %s", versionDir)
+			}
+		}
+	}
+}
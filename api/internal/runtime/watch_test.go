@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestWatchLoadsAndUnloadsPackages(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := NewManager(&config.Config{DataDirectory: dataDir})
+	if err := manager.LoadPackages(); err != nil {
+		t.Fatalf("Failed to load packages: %v", err)
+	}
+	if err := manager.Watch(); err != nil {
+		t.Fatalf("Failed to watch packages: %v", err)
+	}
+	defer manager.StopWatching()
+
+	packageDir := filepath.Join(dataDir, "packages", "watchlang", "1.0.0")
+	if err := os.MkdirAll(packageDir, 0755); err != nil {
+		t.Fatalf("Failed to create package directory: %v", err)
+	}
+	info := `{"language":"watchlang","version":"1.0.0","aliases":["wl"]}`
+	if err := os.WriteFile(filepath.Join(packageDir, "pkg-info.json"), []byte(info), 0644); err != nil {
+		t.Fatalf("Failed to write pkg-info.json: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packageDir, ".ppman-installed"), []byte("0"), 0644); err != nil {
+		t.Fatalf("Failed to mark package installed: %v", err)
+	}
+
+	waitForRuntime(t, manager, "wl", true)
+
+	if err := os.RemoveAll(filepath.Join(dataDir, "packages", "watchlang")); err != nil {
+		t.Fatalf("Failed to remove package: %v", err)
+	}
+
+	waitForRuntime(t, manager, "wl", false)
+}
+
+// waitForRuntime waits until the runtime is (or is no longer) resolvable
+func waitForRuntime(t *testing.T, manager *Manager, language string, present bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := manager.GetLatestRuntimeMatchingLanguageVersion(language, "*")
+		if (err == nil) == present {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for runtime %s (present=%v)", language, present)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
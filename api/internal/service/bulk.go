@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// bulkInstallConcurrency bounds how many packages a bulk install downloads and extracts at once
+const bulkInstallConcurrency = 4
+
+// InstallPackages installs several packages concurrently and reports each entry's outcome in
+// request order. The index is fetched once for all entries, and entries resolving to the same
+// package share one install.
+func (ps *PackageService) InstallPackages(ctx context.Context, requests []types.PackageRequest) ([]types.BulkPackageResult, error) {
+	packages, err := ps.GetPackageList()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]types.BulkPackageResult, len(requests))
+	first := make(map[string]int)
+	duplicates := make(map[int]int)
+	var pending []int
+	resolved := make([]*types.Package, len(requests))
+
+	for i, req := range requests {
+		results[i] = types.BulkPackageResult{Language: req.Language, Version: req.Version}
+		pkg, err := findPackage(packages, req.Language, req.Version)
+		if err != nil {
+			results[i].Status = types.BulkPackageNotFound
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Version = pkg.Version.String()
+
+		key := packageKey(pkg)
+		if j, ok := first[key]; ok {
+			duplicates[i] = j
+			continue
+		}
+		first[key] = i
+		if ps.IsInstalled(pkg) {
+			results[i].Status = types.BulkPackageAlreadyInstalled
+			continue
+		}
+		resolved[i] = pkg
+		pending = append(pending, i)
+	}
+
+	slots := make(chan struct{}, bulkInstallConcurrency)
+	var wg sync.WaitGroup
+	for _, i := range pending {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if err := ps.InstallPackage(ctx, resolved[i], nil); err != nil {
+				results[i].Status = types.BulkPackageFailed
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Status = types.BulkPackageInstalled
+		}(i)
+	}
+	wg.Wait()
+
+	for i, j := range duplicates {
+		results[i].Status = results[j].Status
+		results[i].Error = results[j].Error
+	}
+	return results, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+func TestInstallPackages(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo,
+		writePackage(t, repo, "alpha", "1.0.0", nil),
+		writePackage(t, repo, "beta", "2.1.0", nil),
+		writePackage(t, repo, "gamma", "3.0.0", nil),
+	)
+
+	gamma, err := ps.GetPackage("gamma", "3.0.0")
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+	if err := ps.InstallPackage(context.Background(), gamma, nil); err != nil {
+		t.Fatalf("InstallPackage() error = %v", err)
+	}
+
+	results, err := ps.InstallPackages(context.Background(), []types.PackageRequest{
+		{Language: "alpha", Version: "1.0.0"},
+		{Language: "beta", Version: "2.x"},
+		{Language: "alpha", Version: "*"},
+		{Language: "gamma", Version: "3.0.0"},
+		{Language: "delta", Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("InstallPackages() error = %v", err)
+	}
+
+	want := []types.BulkPackageResult{
+		{Language: "alpha", Version: "1.0.0", Status: types.BulkPackageInstalled},
+		{Language: "beta", Version: "2.1.0", Status: types.BulkPackageInstalled},
+		{Language: "alpha", Version: "1.0.0", Status: types.BulkPackageInstalled},
+		{Language: "gamma", Version: "3.0.0", Status: types.BulkPackageAlreadyInstalled},
+		{Language: "delta", Version: "1.0.0", Status: types.BulkPackageNotFound},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Language != want[i].Language || result.Version != want[i].Version || result.Status != want[i].Status {
+			t.Errorf("results[%d] = %+v, want %+v", i, result, want[i])
+		}
+	}
+	if results[4].Error == "" {
+		t.Error("Expected an error message for the unknown package")
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// DependencyCycleError is returned when a package depends on itself through its dependencies
+type DependencyCycleError struct {
+	// Chain lists the packages from the requested one back to the repeated one
+	Chain []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Chain, " -> ")
+}
+
+// packageKey names a package as language-version
+func packageKey(pkg *types.Package) string {
+	return pkg.Language + "-" + pkg.Version.String()
+}
+
+// readDependencies returns the "dependencies" map of an extracted package's pkg-info.json:
+// language names to semver constraints
+func readDependencies(installPath string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(installPath, "pkg-info.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pkg-info.json: %w", err)
+	}
+
+	var info struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse pkg-info.json: %w", err)
+	}
+	return info.Dependencies, nil
+}
+
+// installDependencies installs the dependencies declared by the package extracted at
+// installPath, in language order, skipping those an installed version already satisfies.
+// A dependency already on the install chain is a cycle.
+func (ps *PackageService) installDependencies(ctx context.Context, pkg *types.Package, installPath string,
+	progress ProgressFunc, chain []string) error {
+	dependencies, err := readDependencies(installPath)
+	if err != nil || len(dependencies) == 0 {
+		return err
+	}
+
+	chain = append(slices.Clone(chain), packageKey(pkg))
+	languages := make([]string, 0, len(dependencies))
+	for language := range dependencies {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	for _, language := range languages {
+		constraint := dependencies[language]
+		installed, err := ps.hasInstalledVersion(language, constraint)
+		if err != nil {
+			return fmt.Errorf("dependency %s %s of %s: %w", language, constraint, packageKey(pkg), err)
+		}
+		if installed {
+			continue
+		}
+
+		dependency, err := ps.GetPackage(language, constraint)
+		if err != nil {
+			return fmt.Errorf("dependency %s %s of %s: %w", language, constraint, packageKey(pkg), err)
+		}
+		key := packageKey(dependency)
+		if slices.Contains(chain, key) {
+			return &DependencyCycleError{Chain: append(chain, key)}
+		}
+
+		ps.requestLogger(ctx).Infof("Installing dependency %s of %s", key, packageKey(pkg))
+		report := func(p types.InstallProgress) {
+			if p.Package == "" {
+				p.Package = key
+			}
+			progress(p)
+		}
+		if err := ps.install(ctx, dependency, report, chain); err != nil {
+			var cycle *DependencyCycleError
+			if errors.As(err, &cycle) {
+				return err
+			}
+			return fmt.Errorf("failed to install dependency %s of %s: %w", key, packageKey(pkg), err)
+		}
+	}
+	return nil
+}
+
+// hasInstalledVersion reports whether an installed version of language satisfies constraint
+func (ps *PackageService) hasInstalledVersion(language, constraint string) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, fmt.Errorf("invalid version constraint: %w", err)
+	}
+
+	versions, err := os.ReadDir(filepath.Join(ps.cfg.DataDirectory, "packages", language))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range versions {
+		version, err := semver.NewVersion(entry.Name())
+		if err != nil || !c.Check(version) {
+			continue
+		}
+		if ps.IsInstalled(&types.Package{Language: language, Version: version}) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
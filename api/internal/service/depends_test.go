@@ -0,0 +1,141 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/types"
+)
+
+// writePackage writes language-version.pkg.tar.gz to dir with a pkg-info.json declaring
+// dependencies, and returns its index line
+func writePackage(t *testing.T, dir, language, version string, dependencies map[string]string) string {
+	t.Helper()
+	info, err := json.Marshal(map[string]interface{}{
+		"language":     language,
+		"version":      version,
+		"dependencies": dependencies,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := language + "-" + version + ".pkg.tar.gz"
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher := sha256.New()
+	zw := gzip.NewWriter(io.MultiWriter(file, hasher))
+	tw := tar.NewWriter(zw)
+	if err := tw.WriteHeader(&tar.Header{Name: "./pkg-info.json", Mode: 0644, Size: int64(len(info))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(info); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	zw.Close()
+	file.Close()
+
+	return strings.Join([]string{language, version, hex.EncodeToString(hasher.Sum(nil)), name}, ",")
+}
+
+// newDependencyTestService returns a package service whose repository lists the index lines
+func newDependencyTestService(t *testing.T, repo string, lines ...string) *PackageService {
+	t.Helper()
+	cfg := &config.Config{
+		DataDirectory: t.TempDir(),
+		RepoURLs:      []string{writeIndex(t, repo, strings.Join(lines, "\n")+"\n")},
+	}
+	return NewPackageService(cfg, logrus.New(), runtime.NewManager(cfg))
+}
+
+func TestInstallPackageInstallsDependencies(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo,
+		writePackage(t, repo, "app", "1.0.0", map[string]string{"base": "1.x"}),
+		writePackage(t, repo, "base", "1.2.0", nil),
+		writePackage(t, repo, "base", "2.0.0", nil),
+	)
+
+	app, err := ps.GetPackage("app", "1.0.0")
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+
+	var dependencyPhases []string
+	progress := func(p types.InstallProgress) {
+		if p.Package != "" && p.Downloaded == 0 {
+			dependencyPhases = append(dependencyPhases, p.Package+":"+p.Phase)
+		}
+	}
+	if err := ps.InstallPackage(context.Background(), app, progress); err != nil {
+		t.Fatalf("InstallPackage() error = %v", err)
+	}
+
+	if !ps.IsInstalled(app) {
+		t.Error("Expected app to be installed")
+	}
+	if !ps.IsInstalled(&types.Package{Language: "base", Version: semver.MustParse("1.2.0")}) {
+		t.Error("Expected the matching base version to be installed")
+	}
+	if ps.IsInstalled(&types.Package{Language: "base", Version: semver.MustParse("2.0.0")}) {
+		t.Error("Expected base 2.0.0 not to be installed")
+	}
+	if len(dependencyPhases) == 0 || dependencyPhases[0] != "base-1.2.0:"+types.InstallPhaseDownload {
+		t.Errorf("Expected progress for the dependency, got %v", dependencyPhases)
+	}
+
+	// An installed dependency is not installed again
+	other := writePackage(t, repo, "tool", "1.0.0", map[string]string{"base": "^1.0.0"})
+	ps.cfg.RepoURLs = []string{writeIndex(t, repo, other+"\n")}
+	tool, err := ps.GetPackage("tool", "1.0.0")
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+	if err := ps.InstallPackage(context.Background(), tool, nil); err != nil {
+		t.Fatalf("InstallPackage() with an installed dependency error = %v", err)
+	}
+}
+
+func TestInstallPackageDependencyCycle(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo,
+		writePackage(t, repo, "alpha", "1.0.0", map[string]string{"beta": "1.0.0"}),
+		writePackage(t, repo, "beta", "1.0.0", map[string]string{"alpha": "*"}),
+	)
+
+	alpha, err := ps.GetPackage("alpha", "1.0.0")
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+
+	err = ps.InstallPackage(context.Background(), alpha, nil)
+	var cycle *DependencyCycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("InstallPackage() error = %v, want a dependency cycle", err)
+	}
+	if want := []string{"alpha-1.0.0", "beta-1.0.0", "alpha-1.0.0"}; !reflect.DeepEqual(cycle.Chain, want) {
+		t.Errorf("Chain = %v, want %v", cycle.Chain, want)
+	}
+	if ps.IsInstalled(alpha) {
+		t.Error("Expected alpha not to be marked installed")
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// extractedSizeRatio estimates how much larger an extracted package is than its tarball
+const extractedSizeRatio = 3
+
+// ErrInsufficientDiskSpace is returned when a package does not fit on disk or within
+// max_packages_disk_usage
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// downloadSize returns the size of the tarball at rawURL, or -1 if it is unknown
+func downloadSize(rawURL string) int64 {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return -1
+	}
+	if parsed.Scheme == "file" {
+		info, err := os.Stat(parsed.Path)
+		if err != nil {
+			return -1
+		}
+		return info.Size()
+	}
+	size, _ := probeDownload(&http.Client{Timeout: time.Minute}, rawURL)
+	return size
+}
+
+// reserveDiskSpace makes room for a package that needs download bytes in the download directory
+// and an estimated extracted bytes once installed: unused packages are evicted until it fits
+// within max_packages_disk_usage, then both directories must have that much free space
+func (ps *PackageService) reserveDiskSpace(ctx context.Context, download, extracted int64) error {
+	if err := ps.evictPackages(ctx, extracted); err != nil {
+		return err
+	}
+
+	for _, need := range []struct {
+		dir   string
+		bytes int64
+	}{
+		{ps.cfg.GetDownloadDirectory(), download},
+		{filepath.Join(ps.cfg.DataDirectory, "packages"), extracted},
+	} {
+		if need.bytes <= 0 {
+			continue
+		}
+		available, err := availableSpace(need.dir)
+		if err != nil {
+			ps.requestLogger(ctx).WithError(err).Warnf("Failed to check free space in %s", need.dir)
+			continue
+		}
+		if available >= 0 && available < need.bytes {
+			return fmt.Errorf("%w: the package needs %s in %s but only %s is free", ErrInsufficientDiskSpace,
+				formatBytes(need.bytes), need.dir, formatBytes(available))
+		}
+	}
+	return nil
+}
+
+// installedPackage is a package in the packages directory and the bytes it takes up
+type installedPackage struct {
+	pkg      *types.Package
+	size     int64
+	lastUsed time.Time
+}
+
+// installedPackages lists the installed packages and their sizes. A package that has not run a
+// job since startup counts as last used when it was installed.
+func (ps *PackageService) installedPackages() ([]installedPackage, error) {
+	packagesDir := filepath.Join(ps.cfg.DataDirectory, "packages")
+	languages, err := os.ReadDir(packagesDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []installedPackage
+	for _, language := range languages {
+		versions, err := os.ReadDir(filepath.Join(packagesDir, language.Name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range versions {
+			version, err := semver.NewVersion(entry.Name())
+			if err != nil {
+				continue
+			}
+			pkg := &types.Package{Language: language.Name(), Version: version}
+			installPath := ps.getInstallPath(pkg)
+			marker, err := os.Stat(filepath.Join(installPath, ".ppman-installed"))
+			if err != nil {
+				continue
+			}
+
+			lastUsed, _ := ps.runtimeManager.PackageUse(installPath)
+			if lastUsed.IsZero() {
+				lastUsed = marker.ModTime()
+			}
+			installed = append(installed, installedPackage{pkg: pkg, size: directorySize(installPath), lastUsed: lastUsed})
+		}
+	}
+	return installed, nil
+}
+
+// evictPackages uninstalls the least recently used packages until need more bytes fit within
+// max_packages_disk_usage. Packages running jobs or being installed stay; freshly installed ones
+// count as just used, so they go last.
+func (ps *PackageService) evictPackages(ctx context.Context, need int64) error {
+	limit := ps.cfg.MaxPackagesDiskUsage
+	if limit <= 0 {
+		return nil
+	}
+
+	installed, err := ps.installedPackages()
+	if err != nil {
+		return fmt.Errorf("failed to measure package disk usage: %w", err)
+	}
+	var used int64
+	for _, p := range installed {
+		used += p.size
+	}
+	if used+need <= limit {
+		return nil
+	}
+
+	sort.Slice(installed, func(i, j int) bool { return installed[i].lastUsed.Before(installed[j].lastUsed) })
+	logger := ps.requestLogger(ctx)
+	for _, p := range installed {
+		if used+need <= limit {
+			return nil
+		}
+		key := packageKey(p.pkg)
+		if _, running := ps.runtimeManager.PackageUse(ps.getInstallPath(p.pkg)); running {
+			continue
+		}
+
+		logger.Infof("Evicting %s (%s, last used %s) to stay within max_packages_disk_usage",
+			key, formatBytes(p.size), p.lastUsed.Format(time.RFC3339))
+		if err := ps.UninstallPackage(ctx, p.pkg); err != nil {
+			logger.WithError(err).Warnf("Failed to evict %s", key)
+			continue
+		}
+		used -= p.size
+	}
+
+	if used+need > limit {
+		return fmt.Errorf("%w: packages use %s of max_packages_disk_usage %s and %s more is needed, but no unused package is left to evict",
+			ErrInsufficientDiskSpace, formatBytes(used), formatBytes(limit), formatBytes(need))
+	}
+	return nil
+}
+
+// directorySize returns the total size in bytes of the regular files under dir
+func directorySize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// installForTest installs language 1.0.0 from the test repository and returns it
+func installForTest(t *testing.T, ps *PackageService, language string) *types.Package {
+	t.Helper()
+	pkg, err := ps.GetPackage(language, "1.0.0")
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+	if err := ps.InstallPackage(context.Background(), pkg, nil); err != nil {
+		t.Fatalf("InstallPackage(%s) error = %v", language, err)
+	}
+	return pkg
+}
+
+func TestInstallPackageEvictsLeastRecentlyUsed(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo,
+		writePackage(t, repo, "alpha", "1.0.0", nil),
+		writePackage(t, repo, "beta", "1.0.0", nil),
+		writePackage(t, repo, "gamma", "1.0.0", nil),
+	)
+	alpha := installForTest(t, ps, "alpha")
+	beta := installForTest(t, ps, "beta")
+	ps.runtimeManager.Use(ps.getInstallPath(beta))()
+
+	// Room for one more package besides beta, but not for alpha as well
+	gamma, err := ps.GetPackage("gamma", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	need := downloadSize(gamma.Download) * extractedSizeRatio
+	ps.cfg.MaxPackagesDiskUsage = directorySize(ps.getInstallPath(alpha)) + directorySize(ps.getInstallPath(beta)) + need - 1
+
+	installForTest(t, ps, "gamma")
+	if ps.IsInstalled(alpha) {
+		t.Error("Expected the least recently used package to be evicted")
+	}
+	if !ps.IsInstalled(beta) {
+		t.Error("Expected the recently used package to be kept")
+	}
+}
+
+func TestInstallPackageKeepsRunningPackages(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo,
+		writePackage(t, repo, "alpha", "1.0.0", nil),
+		writePackage(t, repo, "beta", "1.0.0", nil),
+	)
+	alpha := installForTest(t, ps, "alpha")
+	release := ps.runtimeManager.Use(ps.getInstallPath(alpha))
+	defer release()
+	ps.cfg.MaxPackagesDiskUsage = directorySize(ps.getInstallPath(alpha))
+
+	beta, err := ps.GetPackage("beta", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.InstallPackage(context.Background(), beta, nil); !errors.Is(err, ErrInsufficientDiskSpace) {
+		t.Errorf("InstallPackage() error = %v, want ErrInsufficientDiskSpace", err)
+	}
+	if !ps.IsInstalled(alpha) {
+		t.Error("Expected the package running a job not to be evicted")
+	}
+	if _, err := os.Stat(ps.getInstallPath(beta)); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written for the rejected package")
+	}
+}
+
+func TestInstallPackageRemovesPartialInstall(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo,
+		writePackage(t, repo, "app", "1.0.0", map[string]string{"missing": "1.x"}),
+	)
+	app, err := ps.GetPackage("app", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ps.InstallPackage(context.Background(), app, nil); err == nil {
+		t.Fatal("InstallPackage() with a missing dependency succeeded")
+	}
+	if _, err := os.Stat(ps.getInstallPath(app)); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial install to be removed, stat error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ps.cfg.GetDownloadDirectory(), "app-1.0.0.pkg.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the tarball to be removed, stat error = %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// downloadLogInterval throttles the download speed and ETA log lines
+const downloadLogInterval = 5 * time.Second
+
+// downloadPackage downloads a package to destPath, reporting bytes received to progress.
+// HTTP downloads are written to destPath.part and resumed with a range request when a partial
+// file is left over from an earlier attempt. Large files from servers that accept ranges are
+// fetched in download_chunk_size chunks by download_concurrency parallel requests.
+func (ps *PackageService) downloadPackage(rawURL, destPath string, progress ProgressFunc) error {
+	ps.logger.Debugf("Downloading package from %s to %s", rawURL, destPath)
+
+	client := &http.Client{Timeout: 8 * time.Minute}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid download URL: %w", err)
+	}
+	if parsed.Scheme == "file" {
+		return ps.copyPackage(client, rawURL, destPath, progress)
+	}
+
+	partPath := destPath + ".part"
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	size, acceptsRanges := probeDownload(client, rawURL)
+	tracker := newDownloadTracker(ps.logger.WithField("url", rawURL), progress, size, offset)
+
+	if offset == 0 && acceptsRanges && ps.cfg.DownloadConcurrency > 1 && size > ps.cfg.DownloadChunkSize {
+		err = ps.downloadChunks(client, rawURL, partPath, size, tracker)
+	} else {
+		err = downloadSequential(client, rawURL, partPath, offset, size, tracker)
+	}
+	if err != nil {
+		return err
+	}
+
+	tracker.finish()
+	return os.Rename(partPath, destPath)
+}
+
+// copyPackage copies a file:// package; local copies are neither resumed nor chunked
+func (ps *PackageService) copyPackage(client *http.Client, rawURL, destPath string, progress ProgressFunc) error {
+	body, size, err := openURL(client, rawURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	file, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tracker := newDownloadTracker(ps.logger.WithField("url", rawURL), progress, size, 0)
+	if _, err := io.Copy(&trackingWriter{w: file, tracker: tracker}, body); err != nil {
+		return err
+	}
+	tracker.finish()
+	return nil
+}
+
+// probeDownload asks the server for the download size and whether it accepts range requests.
+// A size of -1 means unknown.
+func probeDownload(client *http.Client, rawURL string) (int64, bool) {
+	resp, err := client.Head(rawURL)
+	if err != nil {
+		return -1, false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return -1, false
+	}
+	return resp.ContentLength, resp.Header.Get("Accept-Ranges") == "bytes"
+}
+
+// downloadSequential downloads rawURL into partPath with one request, continuing from offset
+// when the server honours the range. The server answering 200 restarts the file from scratch.
+func downloadSequential(client *http.Client, rawURL, partPath string, offset, size int64, tracker *downloadTracker) error {
+	if offset > 0 && offset == size {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		flags |= os.O_APPEND
+		tracker.logger.Infof("Resuming download at %s", formatBytes(offset))
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+		tracker.restart(resp.ContentLength)
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file does not match the remote file; start over next time
+		os.Remove(partPath)
+		return fmt.Errorf("cannot resume download at %d bytes, discarded partial file", offset)
+	default:
+		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(&trackingWriter{w: file, tracker: tracker}, resp.Body)
+	return err
+}
+
+// downloadChunks downloads rawURL into partPath with parallel range requests. A failed chunked
+// download removes the partial file, since it may have holes and cannot be resumed by offset.
+func (ps *PackageService) downloadChunks(client *http.Client, rawURL, partPath string, size int64, tracker *downloadTracker) error {
+	file, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := file.Truncate(size); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunks := make(chan int64)
+	errs := make(chan error, ps.cfg.DownloadConcurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < ps.cfg.DownloadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				end := min(start+ps.cfg.DownloadChunkSize, size) - 1
+				if err := downloadRange(ctx, client, rawURL, file, start, end, tracker); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	tracker.logger.Debugf("Downloading %s in %d parallel requests", formatBytes(size), ps.cfg.DownloadConcurrency)
+
+feed:
+	for start := int64(0); start < size; start += ps.cfg.DownloadChunkSize {
+		select {
+		case chunks <- start:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		file.Close()
+		os.Remove(partPath)
+		return err
+	default:
+		return nil
+	}
+}
+
+// downloadRange downloads bytes start through end (inclusive) into file at the same offset
+func downloadRange(ctx context.Context, client *http.Client, rawURL string, file *os.File, start, end int64, tracker *downloadTracker) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("range download failed with status: %d", resp.StatusCode)
+	}
+
+	want := end - start + 1
+	written, err := io.Copy(&trackingWriter{w: io.NewOffsetWriter(file, start), tracker: tracker}, io.LimitReader(resp.Body, want))
+	if err != nil {
+		return err
+	}
+	if written != want {
+		return fmt.Errorf("range %d-%d ended after %d bytes", start, end, written)
+	}
+	return nil
+}
+
+// downloadTracker aggregates bytes written by one or more download requests, sending progress
+// updates and periodically logging speed and ETA
+type downloadTracker struct {
+	logger     *logrus.Entry
+	progress   ProgressFunc
+	total      int64
+	downloaded int64
+	resumed    int64
+	started    time.Time
+	lastReport time.Time
+	lastLog    time.Time
+	mutex      sync.Mutex
+}
+
+func newDownloadTracker(logger *logrus.Entry, progress ProgressFunc, total, resumed int64) *downloadTracker {
+	now := time.Now()
+	return &downloadTracker{
+		logger:     logger,
+		progress:   progress,
+		total:      max(total, 0),
+		downloaded: resumed,
+		resumed:    resumed,
+		started:    now,
+		lastLog:    now,
+	}
+}
+
+// add records n more bytes
+func (t *downloadTracker) add(n int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.downloaded += n
+	now := time.Now()
+	if now.Sub(t.lastReport) >= progressInterval {
+		t.report(now)
+	}
+	if now.Sub(t.lastLog) >= downloadLogInterval {
+		t.lastLog = now
+		speed := t.speed(now)
+		if t.total > 0 && speed > 0 {
+			eta := time.Duration(float64(t.total-t.downloaded) / speed * float64(time.Second))
+			t.logger.Infof("Downloaded %s of %s at %s/s, ETA %s",
+				formatBytes(t.downloaded), formatBytes(t.total), formatBytes(int64(speed)), eta.Round(time.Second))
+		} else {
+			t.logger.Infof("Downloaded %s at %s/s", formatBytes(t.downloaded), formatBytes(int64(speed)))
+		}
+	}
+}
+
+// restart discards resumed bytes when the server ignored the range request
+func (t *downloadTracker) restart(total int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.downloaded = 0
+	t.resumed = 0
+	if total > 0 {
+		t.total = total
+	}
+}
+
+// finish sends the final progress update and logs the average speed
+func (t *downloadTracker) finish() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.report(now)
+	t.logger.Infof("Downloaded %s in %s (%s/s)",
+		formatBytes(t.downloaded), now.Sub(t.started).Round(time.Millisecond), formatBytes(int64(t.speed(now))))
+}
+
+// report sends the current progress; the caller must hold the mutex
+func (t *downloadTracker) report(now time.Time) {
+	t.lastReport = now
+	t.progress(types.InstallProgress{
+		Phase:      types.InstallPhaseDownload,
+		Downloaded: t.downloaded,
+		Total:      t.total,
+	})
+}
+
+// speed returns the bytes per second received by this attempt
+func (t *downloadTracker) speed(now time.Time) float64 {
+	elapsed := now.Sub(t.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(t.downloaded-t.resumed) / elapsed
+}
+
+// trackingWriter counts bytes written into a downloadTracker
+type trackingWriter struct {
+	w       io.Writer
+	tracker *downloadTracker
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.tracker.add(int64(n))
+	return n, err
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
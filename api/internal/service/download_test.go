@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+// rangeServer serves content with range support and records the Range headers it receives
+func rangeServer(t *testing.T, content []byte) (*httptest.Server, func() []string) {
+	var mutex sync.Mutex
+	var ranges []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mutex.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			mutex.Unlock()
+		}
+		http.ServeContent(w, r, "pkg.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), ranges...)
+	}
+}
+
+func TestDownloadPackageChunked(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	server, ranges := rangeServer(t, content)
+
+	cfg := &config.Config{DownloadConcurrency: 3, DownloadChunkSize: 128}
+	ps := NewPackageService(cfg, logrus.New(), nil)
+
+	var mutex sync.Mutex
+	var last types.InstallProgress
+	progress := func(p types.InstallProgress) {
+		mutex.Lock()
+		last = p
+		mutex.Unlock()
+	}
+
+	dest := filepath.Join(t.TempDir(), "pkg.tar.gz")
+	if err := ps.downloadPackage(server.URL, dest, progress); err != nil {
+		t.Fatalf("downloadPackage() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(dest)
+	if !bytes.Equal(data, content) {
+		t.Fatalf("downloaded %d bytes that differ from the %d served", len(data), len(content))
+	}
+	if got := len(ranges()); got != 8 {
+		t.Errorf("got %d range requests, want 8", got)
+	}
+	if last.Downloaded != 1000 || last.Total != 1000 {
+		t.Errorf("last progress = %+v, want 1000 of 1000 bytes", last)
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+}
+
+func TestDownloadPackageResumes(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefghij"), 50)
+	server, ranges := rangeServer(t, content)
+
+	dest := filepath.Join(t.TempDir(), "pkg.tar.gz")
+	if err := os.WriteFile(dest+".part", content[:200], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{DownloadConcurrency: 4, DownloadChunkSize: 64}
+	ps := NewPackageService(cfg, logrus.New(), nil)
+	if err := ps.downloadPackage(server.URL, dest, func(types.InstallProgress) {}); err != nil {
+		t.Fatalf("downloadPackage() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(dest)
+	if !bytes.Equal(data, content) {
+		t.Fatalf("resumed download differs from the served content")
+	}
+	if got := ranges(); len(got) != 1 || got[0] != "bytes=200-" {
+		t.Errorf("range requests = %q, want a single bytes=200-", got)
+	}
+}
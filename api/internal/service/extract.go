@@ -0,0 +1,276 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// extractPackage extracts a tar.gz package into installPath, reporting how much of the archive
+// has been read. Entries that would land outside installPath, through their name, a link target
+// or a link extracted earlier, are rejected.
+func (ps *PackageService) extractPackage(pkgPath, installPath string, progress ProgressFunc) error {
+	ps.logger.Debugf("Extracting package from %s to %s", pkgPath, installPath)
+
+	file, err := os.Open(pkgPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var total int64
+	if info, err := file.Stat(); err == nil {
+		total = info.Size()
+	}
+	counter := &extractCounter{r: file, total: total, progress: progress}
+
+	zr, err := gzip.NewReader(counter)
+	if err != nil {
+		return fmt.Errorf("invalid tar.gz archive: %w", err)
+	}
+	defer zr.Close()
+
+	// Directory modes are applied last, so read-only directories can still be filled
+	type dirMode struct {
+		path string
+		mode os.FileMode
+	}
+	var dirs []dirMode
+
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar.gz archive: %w", err)
+		}
+
+		target, err := packageEntryPath(installPath, header.Name)
+		if err != nil {
+			return err
+		}
+		if target == installPath {
+			if header.Typeflag == tar.TypeDir {
+				dirs = append(dirs, dirMode{target, header.FileInfo().Mode().Perm()})
+			}
+			continue
+		}
+		if err := checkEntryParent(installPath, target, header.Typeflag == tar.TypeDir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := checkResolvedParent(installPath, target); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			dirs = append(dirs, dirMode{target, header.FileInfo().Mode().Perm()})
+		case tar.TypeReg:
+			if err := writePackageFile(target, header, tr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkLinkTarget(installPath, target, header.Linkname); err != nil {
+				return err
+			}
+			if err := replaceWith(target, func() error { return os.Symlink(header.Linkname, target) }); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", header.Name, err)
+			}
+		case tar.TypeLink:
+			source, err := packageEntryPath(installPath, header.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := replaceWith(target, func() error { return os.Link(source, target) }); err != nil {
+				return fmt.Errorf("failed to create hard link %s: %w", header.Name, err)
+			}
+		case tar.TypeXGlobalHeader:
+			// pax metadata, nothing to extract
+		default:
+			return fmt.Errorf("unsupported archive entry type for %s", header.Name)
+		}
+	}
+	counter.report(time.Now())
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packageEntryPath returns where an archive entry is extracted to, rejecting absolute names and
+// names with ".." components
+func packageEntryPath(installPath, name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(slashed) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("invalid archive entry %s: absolute path", name)
+	}
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("invalid archive entry %s: path escapes the package directory", name)
+		}
+	}
+	return filepath.Join(installPath, filepath.FromSlash(path.Clean(slashed))), nil
+}
+
+// checkEntryParent rejects an entry whose path passes through a symlink already extracted, which
+// would write wherever that link points. A directory entry may not be a symlink itself either,
+// since its mode is applied through it.
+func checkEntryParent(installPath, target string, isDir bool) error {
+	dir := filepath.Dir(target)
+	if isDir {
+		dir = target
+	}
+	rel, err := filepath.Rel(installPath, dir)
+	if err != nil || rel == "." {
+		return err
+	}
+
+	current := installPath
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("invalid archive entry %s: path passes through the symlink %s", target, current)
+		}
+	}
+	return nil
+}
+
+// checkResolvedParent rejects an entry whose parent directory resolves outside installPath
+func checkResolvedParent(installPath, target string) error {
+	root, err := filepath.EvalSymlinks(installPath)
+	if err != nil {
+		return err
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if !withinDir(root, parent) {
+		return fmt.Errorf("invalid archive entry %s: path escapes the package directory", target)
+	}
+	return nil
+}
+
+// checkLinkTarget rejects a symlink at target whose destination is absolute or leaves
+// installPath. ".." is only accepted at the start of the destination: the directory it climbs
+// from then contains no links (see checkEntryParent), and any link it descends through was
+// checked the same way, so the destination resolves inside installPath whatever is extracted
+// later. A ".." after a name could climb out of a link pointing higher than its name suggests.
+func checkLinkTarget(installPath, target, linkname string) error {
+	if filepath.IsAbs(linkname) || path.IsAbs(linkname) {
+		return fmt.Errorf("invalid symlink %s -> %s: absolute target", target, linkname)
+	}
+
+	resolved := filepath.Dir(target)
+	climbing := true
+	for _, part := range strings.Split(linkname, "/") {
+		switch part {
+		case "", ".":
+		case "..":
+			if !climbing {
+				return fmt.Errorf("invalid symlink %s -> %s: \"..\" after a name", target, linkname)
+			}
+			resolved = filepath.Dir(resolved)
+		default:
+			climbing = false
+			resolved = filepath.Join(resolved, part)
+		}
+	}
+	if !withinDir(installPath, resolved) {
+		return fmt.Errorf("invalid symlink %s -> %s: target escapes the package directory", target, linkname)
+	}
+	return nil
+}
+
+// withinDir reports whether path is dir or below it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writePackageFile writes a regular file entry with its permissions and modification time.
+// An existing entry is removed first, so a file is never written through an earlier symlink.
+func writePackageFile(target string, header *tar.Header, r io.Reader) error {
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	perm := header.FileInfo().Mode().Perm()
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", header.Name, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+	}
+	// The umask may have narrowed the mode OpenFile created the file with
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// replaceWith removes an existing non-directory entry at target and calls create
+func replaceWith(target string, create func() error) error {
+	if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+	return create()
+}
+
+// extractCounter counts the archive bytes read and reports them every progressInterval
+type extractCounter struct {
+	r          io.Reader
+	read       int64
+	total      int64
+	progress   ProgressFunc
+	lastReport time.Time
+}
+
+func (c *extractCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if now := time.Now(); now.Sub(c.lastReport) >= progressInterval {
+		c.report(now)
+	}
+	return n, err
+}
+
+func (c *extractCounter) report(now time.Time) {
+	c.lastReport = now
+	c.progress(types.InstallProgress{Phase: types.InstallPhaseExtract, Extracted: c.read, Total: c.total})
+}
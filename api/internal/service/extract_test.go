@@ -0,0 +1,161 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/types"
+)
+
+// writeTarGz writes a tar.gz of headers to dir, with body as the content of every regular file
+func writeTarGz(t *testing.T, dir string, body string, headers ...*tar.Header) string {
+	t.Helper()
+	pkgPath := filepath.Join(dir, "pkg.tar.gz")
+	file, err := os.Create(pkgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zw := gzip.NewWriter(file)
+	tw := tar.NewWriter(zw)
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(body))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tw.Close()
+	zw.Close()
+	return pkgPath
+}
+
+func newExtractTestService(t *testing.T) *PackageService {
+	cfg := &config.Config{DataDirectory: t.TempDir()}
+	return NewPackageService(cfg, logrus.New(), runtime.NewManager(cfg))
+}
+
+func TestExtractPackage(t *testing.T) {
+	ps := newExtractTestService(t)
+	pkgPath := writeTarGz(t, t.TempDir(), "#!/bin/sh\n",
+		&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "./bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "./bin/run", Typeflag: tar.TypeReg, Mode: 0750},
+		&tar.Header{Name: "./bin/alias", Typeflag: tar.TypeSymlink, Linkname: "run"},
+		&tar.Header{Name: "./lib/run", Typeflag: tar.TypeLink, Linkname: "./bin/run"},
+		&tar.Header{Name: "./lib/current", Typeflag: tar.TypeSymlink, Linkname: "../bin"},
+	)
+	installPath := t.TempDir()
+
+	var last types.InstallProgress
+	err := ps.extractPackage(pkgPath, installPath, func(p types.InstallProgress) { last = p })
+	if err != nil {
+		t.Fatalf("extractPackage() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(installPath, "bin", "run"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("bin/run mode = %v, want 0750", info.Mode().Perm())
+	}
+	if target, err := os.Readlink(filepath.Join(installPath, "bin", "alias")); err != nil || target != "run" {
+		t.Errorf("bin/alias -> %q, %v; want run", target, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(installPath, "lib", "run")); err != nil || string(data) != "#!/bin/sh\n" {
+		t.Errorf("lib/run = %q, %v", data, err)
+	}
+	if last.Phase != types.InstallPhaseExtract || last.Total == 0 || last.Extracted != last.Total {
+		t.Errorf("Last progress = %+v, want the whole archive extracted", last)
+	}
+}
+
+func TestExtractPackageRejectsEscapingEntries(t *testing.T) {
+	tests := map[string]*tar.Header{
+		"parent directory": {Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644},
+		"nested parent":    {Name: "./bin/../../evil", Typeflag: tar.TypeReg, Mode: 0644},
+		"absolute path":    {Name: "/tmp/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		"symlink outside":  {Name: "./lib/escape", Typeflag: tar.TypeSymlink, Linkname: "../../etc"},
+		"absolute symlink": {Name: "./passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		"hard link":        {Name: "./passwd", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"},
+		"device":           {Name: "./null", Typeflag: tar.TypeChar, Mode: 0666},
+	}
+
+	ps := newExtractTestService(t)
+	for name, header := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			installPath := filepath.Join(root, "pkg")
+			if err := os.Mkdir(installPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			pkgPath := writeTarGz(t, t.TempDir(), "evil", header)
+
+			if err := ps.extractPackage(pkgPath, installPath, func(types.InstallProgress) {}); err == nil {
+				t.Fatal("extractPackage() succeeded, want an error")
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Error("Entry was written outside the install path")
+			}
+		})
+	}
+}
+
+func TestExtractPackageRejectsLinkChains(t *testing.T) {
+	tests := map[string][]*tar.Header{
+		"write through a link": {
+			{Name: "./sub", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "./sub/l", Typeflag: tar.TypeSymlink, Linkname: "../outside"},
+			{Name: "./l/pwned", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"climb out of a link": {
+			{Name: "./here", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "./up", Typeflag: tar.TypeSymlink, Linkname: "here/.."},
+			{Name: "./up/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+		"directory through a link": {
+			{Name: "./lib", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "./lib/", Typeflag: tar.TypeDir, Mode: 0700},
+		},
+	}
+
+	ps := newExtractTestService(t)
+	for name, headers := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			installPath := filepath.Join(root, "pkg")
+			for _, dir := range []string{installPath, filepath.Join(root, "outside")} {
+				if err := os.Mkdir(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pkgPath := writeTarGz(t, t.TempDir(), "evil", headers...)
+
+			if err := ps.extractPackage(pkgPath, installPath, func(types.InstallProgress) {}); err == nil {
+				t.Fatal("extractPackage() succeeded, want an error")
+			}
+			for _, name := range []string{"evil", "outside/pwned"} {
+				if _, err := os.Lstat(filepath.Join(root, name)); err == nil {
+					t.Errorf("%s was written outside the install path", name)
+				}
+			}
+			if info, err := os.Stat(installPath); err != nil || info.Mode().Perm() != 0755 {
+				t.Errorf("Install path mode = %v, %v; want it untouched", info.Mode().Perm(), err)
+			}
+		})
+	}
+}
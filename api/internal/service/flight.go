@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// ErrInstallInProgress is returned when uninstalling or uploading a package that is being installed
+var ErrInstallInProgress = errors.New("package install in progress")
+
+// installCall is an install in progress. Requests for the same package attach to it instead of
+// racing on its directory, and receive its progress and outcome.
+type installCall struct {
+	done chan struct{}
+	err  error
+
+	mutex     sync.Mutex
+	listeners map[int]ProgressFunc
+	nextID    int
+}
+
+// report passes install progress to every attached request
+func (c *installCall) report(p types.InstallProgress) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, listener := range c.listeners {
+		listener(p)
+	}
+}
+
+// listen attaches progress to the install and returns the ID to detach it with
+func (c *installCall) listen(progress ProgressFunc) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.nextID++
+	if progress != nil {
+		c.listeners[c.nextID] = progress
+	}
+	return c.nextID
+}
+
+// wait waits for the install to finish and returns its error. A request that gives up first
+// stops receiving progress; the install itself carries on.
+func (c *installCall) wait(ctx context.Context, id int) error {
+	defer func() {
+		c.mutex.Lock()
+		delete(c.listeners, id)
+		c.mutex.Unlock()
+	}()
+
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// claimInstall attaches progress to the install of key in progress, or starts one when leader
+// is true; the leader must call finishInstall
+func (ps *PackageService) claimInstall(key string, progress ProgressFunc) (call *installCall, id int, leader bool) {
+	ps.installsMutex.Lock()
+	defer ps.installsMutex.Unlock()
+
+	if call, ok := ps.installs[key]; ok {
+		return call, call.listen(progress), false
+	}
+
+	call = &installCall{done: make(chan struct{}), listeners: make(map[int]ProgressFunc)}
+	id = call.listen(progress)
+	ps.installs[key] = call
+	return call, id, true
+}
+
+// claimInstallExclusive starts an install of key, failing with ErrInstallInProgress instead of
+// attaching when one is already running; the caller must call finishInstall
+func (ps *PackageService) claimInstallExclusive(key string, progress ProgressFunc) (*installCall, error) {
+	ps.installsMutex.Lock()
+	defer ps.installsMutex.Unlock()
+
+	if _, ok := ps.installs[key]; ok {
+		return nil, ErrInstallInProgress
+	}
+
+	call := &installCall{done: make(chan struct{}), listeners: make(map[int]ProgressFunc)}
+	call.listen(progress)
+	ps.installs[key] = call
+	return call, nil
+}
+
+// finishInstall records the outcome of an install and releases the requests attached to it
+func (ps *PackageService) finishInstall(key string, call *installCall, err error) {
+	ps.installsMutex.Lock()
+	delete(ps.installs, key)
+	ps.installsMutex.Unlock()
+
+	call.mutex.Lock()
+	call.listeners = nil
+	call.mutex.Unlock()
+
+	call.err = err
+	close(call.done)
+}
+
+// installing reports whether the package with key is being installed
+func (ps *PackageService) installing(key string) bool {
+	ps.installsMutex.Lock()
+	defer ps.installsMutex.Unlock()
+	_, ok := ps.installs[key]
+	return ok
+}
+
+// acquireInstallSlot waits for one of the package_install_concurrency install slots
+func (ps *PackageService) acquireInstallSlot(ctx context.Context) error {
+	select {
+	case ps.installSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseInstallSlot frees a slot taken by acquireInstallSlot
+func (ps *PackageService) releaseInstallSlot() {
+	<-ps.installSlots
+}
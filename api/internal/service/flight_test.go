@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/types"
+)
+
+// gatedRepo holds downloads of the tarballs in dir until release is closed, counting them
+// and the most that were in flight at once
+type gatedRepo struct {
+	release   chan struct{}
+	started   chan string
+	downloads atomic.Int32
+	active    atomic.Int32
+	maxActive atomic.Int32
+}
+
+func newGatedRepo(t *testing.T, dir string) (*gatedRepo, *httptest.Server) {
+	t.Helper()
+	repo := &gatedRepo{release: make(chan struct{}), started: make(chan string, 16)}
+	files := http.FileServer(http.Dir(dir))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			files.ServeHTTP(w, r)
+			return
+		}
+		repo.downloads.Add(1)
+		active := repo.active.Add(1)
+		defer repo.active.Add(-1)
+		for {
+			max := repo.maxActive.Load()
+			if active <= max || repo.maxActive.CompareAndSwap(max, active) {
+				break
+			}
+		}
+		repo.started <- r.URL.Path
+		<-repo.release
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return repo, server
+}
+
+// newGatedTestService returns a package service whose index lists the packages served by server
+func newGatedTestService(t *testing.T, dir string, server *httptest.Server, concurrency int, lines ...string) *PackageService {
+	t.Helper()
+	for i, line := range lines {
+		fields := strings.Split(line, ",")
+		fields[3] = server.URL + "/" + fields[3]
+		lines[i] = strings.Join(fields, ",")
+	}
+	cfg := &config.Config{
+		DataDirectory:             t.TempDir(),
+		RepoURLs:                  []string{writeIndex(t, dir, strings.Join(lines, "\n")+"\n")},
+		DownloadConcurrency:       1,
+		DownloadChunkSize:         1 << 20,
+		PackageInstallConcurrency: concurrency,
+	}
+	return NewPackageService(cfg, logrus.New(), runtime.NewManager(cfg))
+}
+
+func TestInstallPackageAttachesToInstallInProgress(t *testing.T) {
+	dir := t.TempDir()
+	repo, server := newGatedRepo(t, dir)
+	ps := newGatedTestService(t, dir, server, 2, writePackage(t, dir, "alpha", "1.0.0", nil))
+
+	pkg, err := ps.GetPackage("alpha", "1.0.0")
+	if err != nil {
+		t.Fatalf("GetPackage() error = %v", err)
+	}
+
+	errs := make(chan error, 2)
+	var phases sync.Map
+	install := func(name string) {
+		errs <- ps.InstallPackage(context.Background(), pkg, func(p types.InstallProgress) {
+			phases.Store(name+":"+p.Phase, true)
+		})
+	}
+	go install("first")
+	<-repo.started
+	go install("second")
+
+	// The second request attaches instead of starting its own install
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ps.installsMutex.Lock()
+		listeners := len(ps.installs[packageKey(pkg)].listeners)
+		ps.installsMutex.Unlock()
+		if listeners == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the second install to attach")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := ps.UninstallPackage(context.Background(), pkg); !errors.Is(err, ErrInstallInProgress) {
+		t.Errorf("UninstallPackage() during install error = %v, want ErrInstallInProgress", err)
+	}
+
+	close(repo.release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("InstallPackage() error = %v", err)
+		}
+	}
+
+	if got := repo.downloads.Load(); got != 1 {
+		t.Errorf("Expected one download, got %d", got)
+	}
+	if _, ok := phases.Load("second:" + types.InstallPhaseFinalize); !ok {
+		t.Error("Expected the attached request to receive progress")
+	}
+	if !ps.IsInstalled(pkg) {
+		t.Error("Expected the package to be installed")
+	}
+}
+
+func TestInstallPackageConcurrencyLimit(t *testing.T) {
+	dir := t.TempDir()
+	repo, server := newGatedRepo(t, dir)
+	ps := newGatedTestService(t, dir, server, 1,
+		writePackage(t, dir, "alpha", "1.0.0", nil),
+		writePackage(t, dir, "beta", "1.0.0", nil),
+	)
+
+	errs := make(chan error, 2)
+	for _, language := range []string{"alpha", "beta"} {
+		pkg, err := ps.GetPackage(language, "1.0.0")
+		if err != nil {
+			t.Fatalf("GetPackage() error = %v", err)
+		}
+		go func() { errs <- ps.InstallPackage(context.Background(), pkg, nil) }()
+	}
+
+	<-repo.started
+	select {
+	case path := <-repo.started:
+		t.Fatalf("Second download %s started while the only slot was taken", path)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(repo.release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("InstallPackage() error = %v", err)
+		}
+	}
+	if got := repo.maxActive.Load(); got != 1 {
+		t.Errorf("Expected at most one install at a time, got %d", got)
+	}
+}
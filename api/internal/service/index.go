@@ -0,0 +1,179 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// cachedIndex is a fetched http(s) package index and the validators to revalidate it with. It
+// is kept in memory and under <data_directory>/index-cache, so a restarted server revalidates
+// its indexes instead of downloading them again.
+type cachedIndex struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+	Index        string    `json:"index"`
+
+	packages []*types.Package
+}
+
+// fetchCachedIndex returns the index at indexURL from the cache while it is younger than
+// package_index_ttl, and otherwise revalidates it with a conditional GET. When the repository
+// cannot be reached, the cached copy is used however old it is.
+func (ps *PackageService) fetchCachedIndex(client *http.Client, indexURL *url.URL, refresh bool) ([]*types.Package, error) {
+	cached := ps.loadIndex(indexURL)
+	if cached != nil && !refresh && time.Since(cached.Fetched) < ps.cfg.PackageIndexTTL {
+		return cached.packages, nil
+	}
+
+	fetched, err := ps.requestIndex(client, indexURL, cached)
+	if err != nil {
+		if cached == nil || errors.Is(err, errNotFound) {
+			return nil, err
+		}
+		ps.logger.WithError(err).Warnf("Failed to revalidate package index %s, using the copy fetched at %s",
+			indexURL, cached.Fetched.Format(time.RFC3339))
+		return cached.packages, nil
+	}
+
+	ps.storeIndex(fetched)
+	return fetched.packages, nil
+}
+
+// requestIndex fetches the index at indexURL, sending the validators of cached if it is not nil
+// and returning cached with a new fetch time when the server answers 304 Not Modified
+func (ps *PackageService) requestIndex(client *http.Client, indexURL *url.URL, cached *cachedIndex) (*cachedIndex, error) {
+	req, err := http.NewRequest(http.MethodGet, indexURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		ps.logger.Debugf("Package index %s not modified", indexURL)
+		revalidated := *cached
+		revalidated.Fetched = time.Now()
+		return &revalidated, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", indexURL, errNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s returned status: %d", indexURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading package list: %w", err)
+	}
+	packages, err := ps.parseIndex(indexURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachedIndex{
+		URL:          indexURL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+		Index:        string(body),
+		packages:     packages,
+	}, nil
+}
+
+// loadIndex returns the cached index of indexURL from memory or disk, or nil if there is none
+func (ps *PackageService) loadIndex(indexURL *url.URL) *cachedIndex {
+	ps.indexesMutex.Lock()
+	defer ps.indexesMutex.Unlock()
+
+	if cached, ok := ps.indexes[indexURL.String()]; ok {
+		return cached
+	}
+
+	data, err := os.ReadFile(ps.indexCachePath(indexURL))
+	if err != nil {
+		return nil
+	}
+	var cached cachedIndex
+	if err := json.Unmarshal(data, &cached); err != nil || cached.URL != indexURL.String() {
+		ps.logger.Warnf("Ignoring invalid cached package index for %s", indexURL)
+		return nil
+	}
+	if cached.packages, err = ps.parseIndex(indexURL, strings.NewReader(cached.Index)); err != nil {
+		return nil
+	}
+
+	ps.indexes[cached.URL] = &cached
+	return &cached
+}
+
+// storeIndex caches an index in memory and on disk; failing to write it to disk only costs a
+// full download after a restart
+func (ps *PackageService) storeIndex(cached *cachedIndex) {
+	ps.indexesMutex.Lock()
+	ps.indexes[cached.URL] = cached
+	ps.indexesMutex.Unlock()
+
+	indexURL, _ := url.Parse(cached.URL)
+	path := ps.indexCachePath(indexURL)
+	if err := writeIndexCache(path, cached); err != nil {
+		ps.logger.WithError(err).Warnf("Failed to write package index cache %s", path)
+	}
+}
+
+// writeIndexCache writes cached to path through a temporary file, so readers never see a
+// partial entry
+func writeIndexCache(path string, cached *cachedIndex) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "index-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// indexCachePath returns the file caching the index of indexURL
+func (ps *PackageService) indexCachePath(indexURL *url.URL) string {
+	sum := sha256.Sum256([]byte(indexURL.String()))
+	return filepath.Join(ps.cfg.DataDirectory, "index-cache", hex.EncodeToString(sum[:])+".json")
+}
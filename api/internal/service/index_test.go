@@ -0,0 +1,73 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/runtime"
+)
+
+func TestGetPackageListCachesIndex(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("python,3.12.0,abc,python-3.12.0.pkg.tar.gz\n"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DataDirectory:   t.TempDir(),
+		RepoURLs:        []string{server.URL + "/index"},
+		PackageIndexTTL: time.Hour,
+	}
+	newService := func() *PackageService {
+		return NewPackageService(cfg, logrus.New(), runtime.NewManager(cfg))
+	}
+
+	ps := newService()
+	for i := 0; i < 2; i++ {
+		packages, err := ps.GetPackageList()
+		if err != nil || len(packages) != 1 {
+			t.Fatalf("GetPackageList() = %v, %v", packages, err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected a fresh index to be served from the cache, got %d requests", got)
+	}
+
+	packages, err := ps.RefreshPackageList()
+	if err != nil || len(packages) != 1 {
+		t.Fatalf("RefreshPackageList() = %v, %v", packages, err)
+	}
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("Expected a refresh to revalidate the index, got %d requests and %d not modified", requests.Load(), notModified.Load())
+	}
+
+	// A restarted server revalidates the index cached on disk
+	cfg.PackageIndexTTL = 0
+	packages, err = newService().GetPackageList()
+	if err != nil || len(packages) != 1 || packages[0].Download != server.URL+"/python-3.12.0.pkg.tar.gz" {
+		t.Fatalf("GetPackageList() after restart = %v, %v", packages, err)
+	}
+	if notModified.Load() != 2 {
+		t.Errorf("Expected the disk cache to be revalidated, got %d not modified", notModified.Load())
+	}
+
+	// An unreachable repository falls back to the cached index
+	server.Close()
+	if packages, err := ps.RefreshPackageList(); err != nil || len(packages) != 1 {
+		t.Errorf("RefreshPackageList() with the repository down = %v, %v", packages, err)
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/coderunr/api/internal/tracing"
+	"github.com/coderunr/api/internal/types"
+)
+
+var (
+	// ErrInvalidPackage is returned for an uploaded package that fails its checksum or has no
+	// usable pkg-info.json
+	ErrInvalidPackage = errors.New("invalid package")
+	// ErrPackageInstalled is returned for an uploaded package whose version is already installed
+	ErrPackageInstalled = errors.New("package is already installed")
+)
+
+// InstallLocalPackage installs an uploaded package tarball whose SHA256 must equal checksum. Its
+// language and version are read from the pkg-info.json at the archive root. Uploads bypass the
+// repository index and signature checks, but the dependencies they declare are installed from
+// the repository. Uploading a package that is being installed fails with ErrInstallInProgress.
+func (ps *PackageService) InstallLocalPackage(ctx context.Context, archive io.Reader, checksum string, progress ProgressFunc) (pkg *types.Package, err error) {
+	logger := ps.requestLogger(ctx)
+	if progress == nil {
+		progress = func(types.InstallProgress) {}
+	}
+
+	downloadDir := ps.cfg.GetDownloadDirectory()
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	pkgPath, actual, err := saveUpload(downloadDir, archive)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(pkgPath)
+
+	progress(types.InstallProgress{Phase: types.InstallPhaseVerify})
+	if !strings.EqualFold(actual, checksum) {
+		return nil, fmt.Errorf("%w: checksum mismatch: expected %s, got %s", ErrInvalidPackage, checksum, actual)
+	}
+	pkg, err = readArchiveInfo(pkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	pkg.Checksum = actual
+
+	_, span := tracing.Start(ctx, "package.install_local",
+		attribute.String("package.language", pkg.Language),
+		attribute.String("package.version", pkg.Version.String()))
+	defer func() { tracing.End(span, err) }()
+
+	if err := ps.acquireInstallSlot(ctx); err != nil {
+		return nil, err
+	}
+	defer ps.releaseInstallSlot()
+
+	key := packageKey(pkg)
+	call, err := ps.claimInstallExclusive(key, progress)
+	if err != nil {
+		return nil, err
+	}
+	if ps.IsInstalled(pkg) {
+		err = fmt.Errorf("%w: %s", ErrPackageInstalled, key)
+	} else if err = ps.reserveDiskSpace(ctx, 0, uploadSize(pkgPath)*extractedSizeRatio); err == nil {
+		logger.Infof("Installing uploaded package %s", key)
+		err = ps.installArchive(ctx, pkg, pkgPath, call.report, nil)
+	}
+	ps.finishInstall(key, call, err)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// saveUpload copies an uploaded archive into dir and returns its path and SHA256
+func saveUpload(dir string, archive io.Reader) (string, string, error) {
+	file, err := os.CreateTemp(dir, "upload-*.pkg.tar.gz")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create upload file: %w", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hasher), archive); err != nil {
+		os.Remove(file.Name())
+		return "", "", fmt.Errorf("failed to save upload: %w", err)
+	}
+	return file.Name(), hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// uploadSize returns the size of a saved upload
+func uploadSize(pkgPath string) int64 {
+	info, err := os.Stat(pkgPath)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// readArchiveInfo returns the language and version declared by the pkg-info.json at the root
+// of a package archive
+func readArchiveInfo(pkgPath string) (*types.Package, error) {
+	file, err := os.Open(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip archive: %w", err)
+	}
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("pkg-info.json not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || path.Clean(header.Name) != "pkg-info.json" {
+			continue
+		}
+
+		var info struct {
+			Language string `json:"language"`
+			Version  string `json:"version"`
+		}
+		if err := json.NewDecoder(tr).Decode(&info); err != nil {
+			return nil, fmt.Errorf("failed to parse pkg-info.json: %w", err)
+		}
+		// The language names a directory under packages/
+		if info.Language == "" || info.Language != filepath.Base(info.Language) || strings.HasPrefix(info.Language, ".") {
+			return nil, fmt.Errorf("invalid language %q in pkg-info.json", info.Language)
+		}
+		version, err := semver.NewVersion(info.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q in pkg-info.json: %w", info.Version, err)
+		}
+		return &types.Package{Language: info.Language, Version: version}, nil
+	}
+}
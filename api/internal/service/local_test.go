@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openUpload opens the tarball of an index line written by writePackage and returns its checksum
+func openUpload(t *testing.T, dir, line string) (*os.File, string) {
+	t.Helper()
+	fields := strings.Split(line, ",")
+	file, err := os.Open(filepath.Join(dir, fields[3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file, fields[2]
+}
+
+func TestInstallLocalPackage(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo, writePackage(t, repo, "base", "1.0.0", nil))
+	file, checksum := openUpload(t, repo, writePackage(t, repo, "private", "0.3.0", map[string]string{"base": "1.x"}))
+
+	pkg, err := ps.InstallLocalPackage(context.Background(), file, strings.ToUpper(checksum), nil)
+	if err != nil {
+		t.Fatalf("InstallLocalPackage() error = %v", err)
+	}
+	if pkg.Language != "private" || pkg.Version.String() != "0.3.0" {
+		t.Errorf("Installed %s-%s, want private-0.3.0", pkg.Language, pkg.Version)
+	}
+	if !ps.IsInstalled(pkg) {
+		t.Error("Expected the uploaded package to be installed")
+	}
+	if ok, _ := ps.hasInstalledVersion("base", "1.x"); !ok {
+		t.Error("Expected the dependency to be installed from the repository")
+	}
+	if entries, _ := os.ReadDir(ps.cfg.GetDownloadDirectory()); len(entries) != 0 {
+		t.Errorf("Expected the upload to be removed, found %d files", len(entries))
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ps.InstallLocalPackage(context.Background(), file, checksum, nil); !errors.Is(err, ErrPackageInstalled) {
+		t.Errorf("InstallLocalPackage() of an installed package error = %v, want ErrPackageInstalled", err)
+	}
+}
+
+func TestInstallLocalPackageInvalid(t *testing.T) {
+	repo := t.TempDir()
+	ps := newDependencyTestService(t, repo)
+
+	file, checksum := openUpload(t, repo, writePackage(t, repo, "private", "0.3.0", nil))
+	if _, err := ps.InstallLocalPackage(context.Background(), file, strings.Repeat("0", len(checksum)), nil); !errors.Is(err, ErrInvalidPackage) {
+		t.Errorf("InstallLocalPackage() with a wrong checksum error = %v, want ErrInvalidPackage", err)
+	}
+
+	file, checksum = openUpload(t, repo, writePackage(t, repo, "private", "latest", nil))
+	if _, err := ps.InstallLocalPackage(context.Background(), file, checksum, nil); !errors.Is(err, ErrInvalidPackage) {
+		t.Errorf("InstallLocalPackage() with an invalid version error = %v, want ErrInvalidPackage", err)
+	}
+
+	sum := sha256.Sum256([]byte("not a tarball"))
+	if _, err := ps.InstallLocalPackage(context.Background(), strings.NewReader("not a tarball"), hex.EncodeToString(sum[:]), nil); !errors.Is(err, ErrInvalidPackage) {
+		t.Errorf("InstallLocalPackage() of a non-archive error = %v, want ErrInvalidPackage", err)
+	}
+}
@@ -1,9 +1,10 @@
 package service
 
 import (
-	"bufio"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,21 +13,41 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/coderunr/api/internal/config"
 	"github.com/coderunr/api/internal/runtime"
+	"github.com/coderunr/api/internal/tracing"
 	"github.com/coderunr/api/internal/types"
 )
 
+// ProgressFunc receives install progress updates; it is called from the installing goroutine
+type ProgressFunc func(types.InstallProgress)
+
+// progressInterval throttles download progress updates
+const progressInterval = 250 * time.Millisecond
+
 // PackageService handles package management operations
 type PackageService struct {
 	cfg            *config.Config
 	logger         *logrus.Logger
 	runtimeManager *runtime.Manager
+
+	// Installs in progress by package key, and slots bounding concurrent top-level installs
+	installs      map[string]*installCall
+	installsMutex sync.Mutex
+	installSlots  chan struct{}
+
+	// Cached http(s) indexes by URL
+	indexes      map[string]*cachedIndex
+	indexesMutex sync.Mutex
 }
 
 // NewPackageService creates a new package service
@@ -35,60 +56,50 @@ func NewPackageService(cfg *config.Config, logger *logrus.Logger, runtimeManager
 		cfg:            cfg,
 		logger:         logger,
 		runtimeManager: runtimeManager,
+		installs:       make(map[string]*installCall),
+		installSlots:   make(chan struct{}, max(cfg.PackageInstallConcurrency, 1)),
+		indexes:        make(map[string]*cachedIndex),
 	}
 }
 
-// GetPackageList retrieves the list of available packages from the repository
+// GetPackageList retrieves the list of available packages from the configured repositories.
+// Indexes are merged in repo_url order, so when several repositories list the same language and
+// version the earlier one wins. Unreachable repositories are skipped as long as one answers.
+// http(s) indexes are cached for package_index_ttl.
 func (ps *PackageService) GetPackageList() ([]*types.Package, error) {
-	ps.logger.Debug("Fetching package list from repository")
+	return ps.packageList(false)
+}
 
-	client := &http.Client{Timeout: 2 * time.Minute}
-	resp, err := client.Get(ps.cfg.RepoURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch package list: %w", err)
-	}
-	defer resp.Body.Close()
+// RefreshPackageList is GetPackageList revalidating every cached index
+func (ps *PackageService) RefreshPackageList() ([]*types.Package, error) {
+	return ps.packageList(true)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("repository returned status: %d", resp.StatusCode)
-	}
+func (ps *PackageService) packageList(refresh bool) ([]*types.Package, error) {
+	ps.logger.Debug("Fetching package list from repository")
 
+	client := &http.Client{Timeout: 2 * time.Minute}
+	seen := make(map[string]bool)
 	var packages []*types.Package
-	scanner := bufio.NewScanner(resp.Body)
+	var lastErr error
+	fetched := 0
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		parts := strings.Split(line, ",")
-		if len(parts) != 4 {
-			ps.logger.Warnf("Invalid package line format: %s", line)
-			continue
-		}
-
-		version, err := semver.NewVersion(parts[1])
+	for _, repoURL := range ps.cfg.RepoURLs {
+		repoPackages, err := ps.fetchIndex(client, repoURL, refresh)
 		if err != nil {
-			ps.logger.Warnf("Invalid version %s for package %s: %v", parts[1], parts[0], err)
+			ps.logger.WithError(err).Warnf("Failed to fetch package list from %s", repoURL)
+			lastErr = err
 			continue
 		}
-
-		pkg := &types.Package{
-			Language: parts[0],
-			Version:  version,
-			Checksum: parts[2],
-			Download: parts[3],
-		}
-
-		packages = append(packages, pkg)
+		fetched++
+		packages = mergePackages(packages, seen, repoPackages)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading package list: %w", err)
+	if fetched == 0 {
+		return nil, fmt.Errorf("failed to fetch package list: %w", lastErr)
 	}
 
-	ps.logger.Debugf("Found %d packages in repository", len(packages))
+	ps.logger.Debugf("Found %d packages in %d repositories", len(packages), fetched)
 	return packages, nil
 }
 
@@ -98,7 +109,11 @@ func (ps *PackageService) GetPackage(language, versionConstraint string) (*types
 	if err != nil {
 		return nil, err
 	}
+	return findPackage(packages, language, versionConstraint)
+}
 
+// findPackage returns the newest package of language in packages matching versionConstraint
+func findPackage(packages []*types.Package, language, versionConstraint string) (*types.Package, error) {
 	constraint, err := semver.NewConstraint(versionConstraint)
 	if err != nil {
 		return nil, fmt.Errorf("invalid version constraint: %w", err)
@@ -133,48 +148,146 @@ func (ps *PackageService) IsInstalled(pkg *types.Package) bool {
 	return err == nil
 }
 
-// InstallPackage installs a package
-func (ps *PackageService) InstallPackage(pkg *types.Package) error {
-	installPath := ps.getInstallPath(pkg)
+// InstallPackage installs a package, reporting each phase to progress if it is not nil. The
+// dependencies its pkg-info.json declares are installed first. A request for a package that is
+// already being installed attaches to that install, receiving its progress and outcome; at most
+// package_install_concurrency installs run at once and the rest wait for a slot.
+func (ps *PackageService) InstallPackage(ctx context.Context, pkg *types.Package, progress ProgressFunc) error {
+	// The slot is taken before claiming the package, so a waiting install never holds a claim
+	// that a running one could depend on
+	if err := ps.acquireInstallSlot(ctx); err != nil {
+		return err
+	}
+
+	call, id, leader := ps.claimInstall(packageKey(pkg), progress)
+	if !leader {
+		ps.releaseInstallSlot()
+		return call.wait(ctx, id)
+	}
+	defer ps.releaseInstallSlot()
+
+	err := ps.installPackage(ctx, pkg, call.report, nil)
+	ps.finishInstall(packageKey(pkg), call, err)
+	return err
+}
+
+// install installs a dependency within its parent's slot, attaching to an install of it that
+// is already in progress; chain lists the packages whose installs led to this one
+func (ps *PackageService) install(ctx context.Context, pkg *types.Package, progress ProgressFunc, chain []string) error {
+	call, id, leader := ps.claimInstall(packageKey(pkg), progress)
+	if !leader {
+		return call.wait(ctx, id)
+	}
+
+	err := ps.installPackage(ctx, pkg, call.report, chain)
+	ps.finishInstall(packageKey(pkg), call, err)
+	return err
+}
+
+// installPackage downloads and verifies a package, then installs it from the archive
+func (ps *PackageService) installPackage(ctx context.Context, pkg *types.Package, progress ProgressFunc, chain []string) (err error) {
+	logger := ps.requestLogger(ctx)
+	if progress == nil {
+		progress = func(types.InstallProgress) {}
+	}
+
+	_, span := tracing.Start(ctx, "package.install",
+		attribute.String("package.language", pkg.Language),
+		attribute.String("package.version", pkg.Version.String()))
+	defer func() { tracing.End(span, err) }()
+	// Each phase is marked on the span so slow downloads and extractions stand out
+	report := progress
+	progress = func(p types.InstallProgress) {
+		if p.Downloaded == 0 && p.Total == 0 {
+			span.AddEvent(p.Phase)
+		}
+		report(p)
+	}
 
 	if ps.IsInstalled(pkg) {
 		return fmt.Errorf("package %s-%s is already installed", pkg.Language, pkg.Version.String())
 	}
 
-	ps.logger.Infof("Installing %s-%s", pkg.Language, pkg.Version.String())
+	logger.Infof("Installing %s-%s", pkg.Language, pkg.Version.String())
 
-	// Remove any existing directory
-	if _, err := os.Stat(installPath); err == nil {
-		ps.logger.Warnf("%s-%s has residual files. Removing them.", pkg.Language, pkg.Version.String())
-		if err := os.RemoveAll(installPath); err != nil {
-			return fmt.Errorf("failed to remove existing directory: %w", err)
+	// Make room before downloading; a tarball of unknown size is not checked
+	if size := downloadSize(pkg.Download); size >= 0 {
+		if err := ps.reserveDiskSpace(ctx, size, size*extractedSizeRatio); err != nil {
+			return err
 		}
 	}
 
-	// Create install directory
-	if err := os.MkdirAll(installPath, 0755); err != nil {
-		return fmt.Errorf("failed to create install directory: %w", err)
+	// Download package into the download directory, which survives failed installs so
+	// interrupted downloads can be resumed
+	downloadDir := ps.cfg.GetDownloadDirectory()
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return fmt.Errorf("failed to create download directory: %w", err)
 	}
-
-	// Download package
-	pkgPath := filepath.Join(installPath, "pkg.tar.gz")
-	if err := ps.downloadPackage(pkg.Download, pkgPath); err != nil {
+	pkgPath := filepath.Join(downloadDir, fmt.Sprintf("%s-%s.pkg.tar.gz", pkg.Language, pkg.Version.String()))
+	progress(types.InstallProgress{Phase: types.InstallPhaseDownload})
+	if err := ps.downloadPackage(pkg.Download, pkgPath, progress); err != nil {
+		// A partial download is kept to be resumed, unless it filled the disk
+		if errors.Is(err, syscall.ENOSPC) {
+			os.Remove(pkgPath + ".part")
+		}
 		return fmt.Errorf("failed to download package: %w", err)
 	}
+	defer os.Remove(pkgPath)
 
 	// Verify checksum
+	progress(types.InstallProgress{Phase: types.InstallPhaseVerify})
 	if err := ps.verifyChecksum(pkgPath, pkg.Checksum); err != nil {
 		return fmt.Errorf("checksum verification failed: %w", err)
 	}
+	if err := ps.verifySignature(pkg.Download, pkgPath); err != nil {
+		return err
+	}
+
+	return ps.installArchive(ctx, pkg, pkgPath, progress, chain)
+}
+
+// installArchive extracts a verified package archive into the package's install path, installs
+// its dependencies, marks it installed and loads its runtimes. The install path is removed when
+// any step fails.
+func (ps *PackageService) installArchive(ctx context.Context, pkg *types.Package, pkgPath string, progress ProgressFunc, chain []string) (err error) {
+	installPath := ps.getInstallPath(pkg)
+	logger := ps.requestLogger(ctx)
+	defer func() {
+		if err != nil {
+			if removeErr := os.RemoveAll(installPath); removeErr != nil {
+				logger.WithError(removeErr).Warnf("Failed to remove partial install %s", installPath)
+			}
+		}
+	}()
+
+	// Remove any existing directory
+	if _, err := os.Stat(installPath); err == nil {
+		logger.Warnf("%s-%s has residual files. Removing them.", pkg.Language, pkg.Version.String())
+		if err := os.RemoveAll(installPath); err != nil {
+			return fmt.Errorf("failed to remove existing directory: %w", err)
+		}
+	}
+
+	// Create install directory
+	if err := os.MkdirAll(installPath, 0755); err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
 
 	// Extract package
-	if err := ps.extractPackage(pkgPath, installPath); err != nil {
+	progress(types.InstallProgress{Phase: types.InstallPhaseExtract})
+	if err := ps.extractPackage(pkgPath, installPath, progress); err != nil {
 		return fmt.Errorf("failed to extract package: %w", err)
 	}
 
+	// Dependencies must be in place before the package counts as installed
+	if err := ps.installDependencies(ctx, pkg, installPath, progress, chain); err != nil {
+		return err
+	}
+
 	// Cache environment
+	progress(types.InstallProgress{Phase: types.InstallPhaseFinalize})
 	if err := ps.cacheEnvironment(installPath); err != nil {
-		ps.logger.Warnf("Failed to cache environment for %s-%s: %v", pkg.Language, pkg.Version.String(), err)
+		logger.Warnf("Failed to cache environment for %s-%s: %v", pkg.Language, pkg.Version.String(), err)
 	}
 
 	// Mark as installed
@@ -185,45 +298,53 @@ func (ps *PackageService) InstallPackage(pkg *types.Package) error {
 	}
 
 	// Load the package into runtime manager immediately
-	ps.logger.Debug("Loading package into runtime manager")
+	logger.Debug("Loading package into runtime manager")
 	if err := ps.runtimeManager.LoadPackage(installPath); err != nil {
-		ps.logger.WithError(err).Warnf("Failed to load package into runtime manager: %s", installPath)
+		logger.WithError(err).Warnf("Failed to load package into runtime manager: %s", installPath)
 		// Don't fail installation if runtime loading fails
 	}
 
-	ps.logger.Infof("Successfully installed %s-%s", pkg.Language, pkg.Version.String())
+	logger.Infof("Successfully installed %s-%s", pkg.Language, pkg.Version.String())
 
 	// Refresh runtime list to reflect the newly installed package
 	if err := ps.runtimeManager.LoadPackages(); err != nil {
-		ps.logger.WithError(err).Warn("Failed to refresh runtimes after install")
+		logger.WithError(err).Warn("Failed to refresh runtimes after install")
 	}
 	return nil
 }
 
 // UninstallPackage uninstalls a package
-func (ps *PackageService) UninstallPackage(pkg *types.Package) error {
+func (ps *PackageService) UninstallPackage(ctx context.Context, pkg *types.Package) error {
 	installPath := ps.getInstallPath(pkg)
+	logger := ps.requestLogger(ctx)
+
+	if ps.installing(packageKey(pkg)) {
+		return ErrInstallInProgress
+	}
 
 	if !ps.IsInstalled(pkg) {
 		return fmt.Errorf("package %s-%s is not installed", pkg.Language, pkg.Version.String())
 	}
 
-	ps.logger.Infof("Uninstalling %s-%s", pkg.Language, pkg.Version.String())
+	logger.Infof("Uninstalling %s-%s", pkg.Language, pkg.Version.String())
 
 	// Remove package directory
 	if err := os.RemoveAll(installPath); err != nil {
 		return fmt.Errorf("failed to remove package directory: %w", err)
 	}
 
-	ps.logger.Infof("Successfully uninstalled %s-%s", pkg.Language, pkg.Version.String())
+	// Drop the package's runtimes so /runtimes reflects the uninstall immediately
+	ps.runtimeManager.UnloadPackage(installPath)
 
-	// Refresh runtime list to reflect the uninstalled package
-	if err := ps.runtimeManager.LoadPackages(); err != nil {
-		ps.logger.WithError(err).Warn("Failed to refresh runtimes after uninstall")
-	}
+	logger.Infof("Successfully uninstalled %s-%s", pkg.Language, pkg.Version.String())
 	return nil
 }
 
+// requestLogger returns the service logger tagged with the request ID in ctx
+func (ps *PackageService) requestLogger(ctx context.Context) *logrus.Entry {
+	return ps.logger.WithField("request_id", middleware.GetReqID(ctx))
+}
+
 // getInstallPath returns the installation path for a package
 func (ps *PackageService) getInstallPath(pkg *types.Package) string {
 	return filepath.Join(
@@ -234,31 +355,6 @@ func (ps *PackageService) getInstallPath(pkg *types.Package) string {
 	)
 }
 
-// downloadPackage downloads a package from the given URL
-func (ps *PackageService) downloadPackage(url, destPath string) error {
-	ps.logger.Debugf("Downloading package from %s to %s", url, destPath)
-
-	client := &http.Client{Timeout: 8 * time.Minute}
-	resp, err := client.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status: %d", resp.StatusCode)
-	}
-
-	file, err := os.Create(destPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	_, err = io.Copy(file, resp.Body)
-	return err
-}
-
 // verifyChecksum verifies the SHA256 checksum of a file
 func (ps *PackageService) verifyChecksum(filePath, expectedChecksum string) error {
 	ps.logger.Debug("Validating checksums")
@@ -282,18 +378,6 @@ func (ps *PackageService) verifyChecksum(filePath, expectedChecksum string) erro
 	return nil
 }
 
-// extractPackage extracts a tar.gz package
-func (ps *PackageService) extractPackage(pkgPath, installPath string) error {
-	ps.logger.Debugf("Extracting package from %s to %s", pkgPath, installPath)
-
-	cmd := exec.Command("tar", "xzf", pkgPath, "-C", installPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("extraction failed: %w", err)
-	}
-
-	return nil
-}
-
 // cacheEnvironment caches the package environment variables
 func (ps *PackageService) cacheEnvironment(installPath string) error {
 	ps.logger.Debug("Caching environment")
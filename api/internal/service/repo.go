@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/coderunr/api/internal/types"
+)
+
+// errNotFound is returned by openURL when the file or HTTP resource does not exist
+var errNotFound = errors.New("not found")
+
+// openURL opens an http(s) or file:// URL for reading, returning its size or -1 if unknown
+func openURL(client *http.Client, rawURL string) (io.ReadCloser, int64, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		file, err := os.Open(parsed.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, fmt.Errorf("%s: %w", rawURL, errNotFound)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		size := int64(-1)
+		if info, err := file.Stat(); err == nil {
+			size = info.Size()
+		}
+		return file, size, nil
+	case "http", "https":
+		resp, err := client.Get(rawURL)
+		if err != nil {
+			return nil, 0, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return nil, 0, fmt.Errorf("%s: %w", rawURL, errNotFound)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, 0, fmt.Errorf("%s returned status: %d", rawURL, resp.StatusCode)
+		}
+		return resp.Body, resp.ContentLength, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported URL scheme %q", parsed.Scheme)
+	}
+}
+
+// fetchIndex reads the package index at repoURL. http(s) indexes go through the index cache;
+// refresh revalidates a cached index even if it is still fresh.
+func (ps *PackageService) fetchIndex(client *http.Client, repoURL string, refresh bool) ([]*types.Package, error) {
+	base, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL: %w", err)
+	}
+	if base.Scheme == "http" || base.Scheme == "https" {
+		return ps.fetchCachedIndex(client, base, refresh)
+	}
+
+	body, _, err := openURL(client, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ps.parseIndex(base, body)
+}
+
+// parseIndex parses index lines of the form language,version,sha256,download. Relative download
+// entries are resolved against the index URL, so a mirror can serve its index and tarballs from
+// one directory.
+func (ps *PackageService) parseIndex(base *url.URL, body io.Reader) ([]*types.Package, error) {
+	var packages []*types.Package
+	scanner := bufio.NewScanner(body)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		parts := strings.Split(line, ",")
+		if len(parts) != 4 {
+			ps.logger.Warnf("Invalid package line format: %s", line)
+			continue
+		}
+
+		version, err := semver.NewVersion(parts[1])
+		if err != nil {
+			ps.logger.Warnf("Invalid version %s for package %s: %v", parts[1], parts[0], err)
+			continue
+		}
+
+		download, err := base.Parse(parts[3])
+		if err != nil {
+			ps.logger.Warnf("Invalid download URL %s for package %s: %v", parts[3], parts[0], err)
+			continue
+		}
+
+		packages = append(packages, &types.Package{
+			Language: parts[0],
+			Version:  version,
+			Checksum: parts[2],
+			Download: download.String(),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading package list: %w", err)
+	}
+
+	return packages, nil
+}
+
+// mergePackages appends packages not already listed, keyed by language and version
+func mergePackages(merged []*types.Package, seen map[string]bool, packages []*types.Package) []*types.Package {
+	for _, pkg := range packages {
+		key := pkg.Language + "-" + pkg.Version.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, pkg)
+	}
+	return merged
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+	"github.com/coderunr/api/internal/types"
+)
+
+func writeIndex(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "index")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + path
+}
+
+func TestGetPackageListMergesRepositories(t *testing.T) {
+	primary := t.TempDir()
+	mirror := t.TempDir()
+
+	cfg := &config.Config{RepoURLs: []string{
+		"file://" + filepath.Join(t.TempDir(), "missing"),
+		writeIndex(t, primary, "python,3.12.0,aaa,python-3.12.0.pkg.tar.gz\n"),
+		writeIndex(t, mirror, "python,3.12.0,bbb,https://mirror.example/python.tar.gz\ngo,1.22.0,ccc,go-1.22.0.pkg.tar.gz\n"),
+	}}
+	ps := NewPackageService(cfg, logrus.New(), nil)
+
+	packages, err := ps.GetPackageList()
+	if err != nil {
+		t.Fatalf("GetPackageList() error = %v", err)
+	}
+	if len(packages) != 2 {
+		t.Fatalf("got %d packages, want 2", len(packages))
+	}
+
+	python := packages[0]
+	if python.Language != "python" || python.Checksum != "aaa" {
+		t.Errorf("python entry = %+v, want the first repository's entry", python)
+	}
+	if want := "file://" + filepath.Join(primary, "python-3.12.0.pkg.tar.gz"); python.Download != want {
+		t.Errorf("python download = %s, want %s", python.Download, want)
+	}
+	if want := "file://" + filepath.Join(mirror, "go-1.22.0.pkg.tar.gz"); packages[1].Download != want {
+		t.Errorf("go download = %s, want %s", packages[1].Download, want)
+	}
+}
+
+func TestGetPackageListAllRepositoriesFail(t *testing.T) {
+	cfg := &config.Config{RepoURLs: []string{"file://" + filepath.Join(t.TempDir(), "missing")}}
+	ps := NewPackageService(cfg, logrus.New(), nil)
+
+	if _, err := ps.GetPackageList(); err == nil {
+		t.Fatal("GetPackageList() succeeded, want an error")
+	}
+}
+
+func TestDownloadPackageFromFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "pkg.tar.gz")
+	if err := os.WriteFile(src, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ps := NewPackageService(&config.Config{}, logrus.New(), nil)
+	dest := filepath.Join(dir, "out.tar.gz")
+	var last types.InstallProgress
+	progress := func(p types.InstallProgress) { last = p }
+	if err := ps.downloadPackage("file://"+src, dest, progress); err != nil {
+		t.Fatalf("downloadPackage() error = %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "archive" {
+		t.Errorf("downloaded %q, want %q", data, "archive")
+	}
+	if last.Phase != types.InstallPhaseDownload || last.Downloaded != 7 || last.Total != 7 {
+		t.Errorf("last progress = %+v, want 7 of 7 bytes downloaded", last)
+	}
+}
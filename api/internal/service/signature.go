@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// signatureScheme verifies detached signatures published at <download><suffix>
+type signatureScheme struct {
+	name   string
+	suffix string
+	verify func(pkgPath, sigPath string) error
+}
+
+// signatureSchemes returns the schemes with a configured trust key
+func (ps *PackageService) signatureSchemes() []signatureScheme {
+	var schemes []signatureScheme
+	if ps.cfg.PackageMinisignKey != "" {
+		schemes = append(schemes, signatureScheme{
+			name:   "minisign",
+			suffix: ".minisig",
+			verify: func(pkgPath, sigPath string) error {
+				return runVerifier("minisign", "-V", "-q", "-p", ps.cfg.PackageMinisignKey, "-m", pkgPath, "-x", sigPath)
+			},
+		})
+	}
+	if ps.cfg.PackageKeyring != "" {
+		schemes = append(schemes, signatureScheme{
+			name:   "gpg",
+			suffix: ".sig",
+			verify: func(pkgPath, sigPath string) error {
+				return runVerifier("gpgv", "--keyring", ps.cfg.PackageKeyring, sigPath, pkgPath)
+			},
+		})
+	}
+	return schemes
+}
+
+// verifySignature checks pkgPath against the first detached signature found next to downloadURL.
+// A signature that fails to verify always rejects the package; a missing one only does so when
+// require_signed_packages is set.
+func (ps *PackageService) verifySignature(downloadURL, pkgPath string) error {
+	schemes := ps.signatureSchemes()
+	if len(schemes) == 0 {
+		return nil
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	for _, scheme := range schemes {
+		sigPath := pkgPath + scheme.suffix
+		err := fetchFile(client, downloadURL+scheme.suffix, sigPath)
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s signature: %w", scheme.name, err)
+		}
+
+		err = scheme.verify(pkgPath, sigPath)
+		os.Remove(sigPath)
+		if err != nil {
+			return fmt.Errorf("%s signature verification failed: %w", scheme.name, err)
+		}
+
+		ps.logger.Debugf("Verified %s signature for %s", scheme.name, downloadURL)
+		return nil
+	}
+
+	if ps.cfg.RequireSignedPackages {
+		return fmt.Errorf("package is not signed and require_signed_packages is set")
+	}
+	ps.logger.Warnf("No signature found for %s, installing unsigned package", downloadURL)
+	return nil
+}
+
+// fetchFile downloads rawURL to destPath
+func fetchFile(client *http.Client, rawURL, destPath string) error {
+	body, _, err := openURL(client, rawURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	file, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, body)
+	return err
+}
+
+// runVerifier runs a signature verification tool, returning its output on failure
+func runVerifier(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestVerifySignatureMissing(t *testing.T) {
+	dir := t.TempDir()
+	pkgPath := filepath.Join(dir, "pkg.tar.gz")
+	if err := os.WriteFile(pkgPath, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	downloadURL := "file://" + pkgPath
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{"no keys configured", &config.Config{}, false},
+		{"signature optional", &config.Config{PackageKeyring: "/nonexistent"}, false},
+		{"signature required", &config.Config{PackageKeyring: "/nonexistent", RequireSignedPackages: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewPackageService(tt.cfg, logrus.New(), nil)
+			err := ps.verifySignature(downloadURL, pkgPath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureGPG(t *testing.T) {
+	for _, tool := range []string{"gpg", "gpgv"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+
+	home := t.TempDir()
+	t.Cleanup(func() { _ = exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run() })
+	gpg := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("gpg", append([]string{"--homedir", home, "--batch", "--yes"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("gpg %s: %v: %s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	repo := t.TempDir()
+	published := filepath.Join(repo, "pkg.tar.gz")
+	if err := os.WriteFile(published, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	keyring := filepath.Join(home, "trusted.gpg")
+	gpg("--passphrase", "", "--quick-gen-key", "packages@example.com", "ed25519", "sign", "never")
+	gpg("--detach-sign", "--output", published+".sig", published)
+	gpg("--export", "--output", keyring, "packages@example.com")
+
+	ps := NewPackageService(&config.Config{PackageKeyring: keyring, RequireSignedPackages: true}, logrus.New(), nil)
+
+	downloaded := filepath.Join(t.TempDir(), "pkg.tar.gz")
+	if err := os.WriteFile(downloaded, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.verifySignature("file://"+published, downloaded); err != nil {
+		t.Errorf("verifySignature() on a signed package error = %v", err)
+	}
+
+	if err := os.WriteFile(downloaded, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.verifySignature("file://"+published, downloaded); err == nil {
+		t.Error("verifySignature() accepted a tampered package")
+	}
+}
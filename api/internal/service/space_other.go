@@ -0,0 +1,8 @@
+//go:build !linux
+
+package service
+
+// availableSpace returns -1, unknown, where free space is not checked
+func availableSpace(dir string) (int64, error) {
+	return -1, nil
+}
@@ -45,6 +45,8 @@ type Runtime struct {
 	Language string          `json:"language"`
 	Version  *semver.Version `json:"version"`
 	Aliases  []string        `json:"aliases"`
+	// File extensions (".py") the language is detected from when a request omits it
+	Extensions []string `json:"extensions,omitempty"`
 	// Platform information (optional)
 	Platform        string       `json:"platform,omitempty"`
 	OS              string       `json:"os,omitempty"`
@@ -58,22 +60,85 @@ type Runtime struct {
 	MaxOpenFiles    int          `json:"max_open_files"`
 	MaxFileSize     int64        `json:"max_file_size"`
 	OutputMaxSize   int          `json:"output_max_size"`
+	DiskQuota       int64        `json:"disk_quota"`
+	MemoryOverhead  int64        `json:"memory_overhead"`
 	Compiled        bool         `json:"compiled"`
-	EnvVars         []string     `json:"env_vars"`
+	REPL            bool         `json:"repl"`
+	LSP             bool         `json:"lsp"`
+	// Format and Lint are set when the package declares a format or lint script as capabilities
+	Format bool `json:"format"`
+	Lint   bool `json:"lint"`
+	// Warm is set when the package ships a warm script for the warm process pool
+	Warm    bool     `json:"warm"`
+	EnvVars []string `json:"env_vars"`
+	// Stages is the pipeline the package declares in place of the compile and run pair
+	Stages []PipelineStage `json:"stages,omitempty"`
+}
+
+// PipelineStage is a stage of a package-defined pipeline, run by the package script of the same
+// name. Zero limits fall back to the job's compile limits, or its run limits for the last stage.
+type PipelineStage struct {
+	Name        string        `json:"name"`
+	Timeout     time.Duration `json:"timeout"`
+	CPUTime     time.Duration `json:"cpu_time"`
+	MemoryLimit int64         `json:"memory_limit"`
 }
 
 // StageResult represents the result of a compilation or execution stage
 type StageResult struct {
-	Stdout   string `json:"stdout"`
-	Stderr   string `json:"stderr"`
-	Output   string `json:"output"`
-	Code     *int   `json:"code"`
-	Signal   string `json:"signal,omitempty"`
-	Memory   int64  `json:"memory"`
-	Message  string `json:"message,omitempty"`
+	Stdout  string `json:"stdout"`
+	Stderr  string `json:"stderr"`
+	Output  string `json:"output"`
+	Code    *int   `json:"code"`
+	Signal  string `json:"signal,omitempty"`
+	Memory  int64  `json:"memory"`
+	Message string `json:"message,omitempty"`
+	// Raw isolate status (RE, SG, TO, XX), kept for Piston compatibility
 	Status   string `json:"status,omitempty"`
 	CPUTime  int64  `json:"cpu_time"`  // milliseconds
 	WallTime int64  `json:"wall_time"` // milliseconds
+	// Bytes used in the sandbox after the stage
+	DiskUsage int64 `json:"disk_usage"`
+	// Why the stage ended, derived from the isolate metadata
+	Outcome StageOutcome `json:"outcome"`
+	// Truncated is set when output beyond the output limit was dropped
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// StageOutcome is the reason a stage ended
+type StageOutcome string
+
+const (
+	OutcomeOK           StageOutcome = "ok"
+	OutcomeRuntimeError StageOutcome = "runtime_error"
+	OutcomeTimeout      StageOutcome = "timeout"
+	OutcomeMemoryLimit  StageOutcome = "memory_limit"
+	OutcomeOutputLimit  StageOutcome = "output_limit"
+	OutcomeDiskLimit    StageOutcome = "disk_limit"
+	OutcomeSandboxError StageOutcome = "sandbox_error"
+)
+
+// Verdict is the judgement of a run against the request's expected_output
+type Verdict string
+
+const (
+	VerdictAccepted     Verdict = "AC"
+	VerdictWrongAnswer  Verdict = "WA"
+	VerdictTimeLimit    Verdict = "TLE"
+	VerdictMemoryLimit  Verdict = "MLE"
+	VerdictRuntimeError Verdict = "RE"
+	VerdictOutputLimit  Verdict = "OLE"
+	VerdictCompileError Verdict = "CE"
+)
+
+// OutputFile represents a file copied out of the sandbox after execution. Files uploaded to the
+// artifact sink carry a pre-signed URL instead of content.
+type OutputFile struct {
+	Name     string `json:"name"`
+	Content  string `json:"content,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Size     int64  `json:"size"`
 }
 
 // ExecutionResult represents the complete result of job execution
@@ -82,6 +147,19 @@ type ExecutionResult struct {
 	Run      *StageResult `json:"run"`
 	Language string       `json:"language"`
 	Version  string       `json:"version"`
+	// Stages lists the results of a package-defined pipeline in order, up to the first that
+	// failed; Run then repeats the last of them
+	Stages []PipelineStageResult `json:"stages,omitempty"`
+	// Files matching the request's output_files patterns, collected after the run stage
+	Files []OutputFile `json:"files,omitempty"`
+	// Cached is set when the result was reused from an identical earlier submission
+	Cached bool `json:"cached,omitempty"`
+	// Metadata echoes the request's metadata
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Verdict judges the run against the request's expected_output, if it had one;
+	// VerdictMessage locates the first difference of a wrong answer
+	Verdict        Verdict `json:"verdict,omitempty"`
+	VerdictMessage string  `json:"verdict_message,omitempty"`
 	// Optional: echo back the effective limits used for this execution
 	Limits *struct {
 		Timeouts struct {
@@ -99,19 +177,157 @@ type ExecutionResult struct {
 	} `json:"limits,omitempty"`
 }
 
+// PipelineStageResult is the result of one stage of a package-defined pipeline
+type PipelineStageResult struct {
+	Name string `json:"name"`
+	StageResult
+}
+
+// ToolRequest asks for files to be run through a tool of their runtime, its formatter or linter
+type ToolRequest struct {
+	// Language may be omitted to have it detected from the first file
+	Language string     `json:"language,omitempty"`
+	Version  string     `json:"version,omitempty"`
+	Files    []CodeFile `json:"files"`
+}
+
+// FormatResult is the result of a format request. Files hold the formatted content, in the
+// encoding each was submitted in, and are left out when the formatter failed.
+type FormatResult struct {
+	Language string       `json:"language"`
+	Version  string       `json:"version"`
+	Files    []CodeFile   `json:"files,omitempty"`
+	Format   *StageResult `json:"format"`
+}
+
+// LintResult is the result of a lint request: the diagnostics parsed from the linter's output,
+// and the linter's stage result, which fails whenever it reports problems
+type LintResult struct {
+	Language    string       `json:"language"`
+	Version     string       `json:"version"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Lint        *StageResult `json:"lint"`
+}
+
+// Diagnostic is a problem a linter reported. Line and column count from 1; column is 0 when the
+// linter gave none.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
 // JobRequest represents an incoming job execution request
 type JobRequest struct {
-	Language           string     `json:"language" validate:"required"`
-	Version            string     `json:"version" validate:"required"`
-	Files              []CodeFile `json:"files" validate:"required,dive"`
-	Args               []string   `json:"args,omitempty"`
-	Stdin              string     `json:"stdin,omitempty"`
-	CompileMemoryLimit *int64     `json:"compile_memory_limit,omitempty"`
-	RunMemoryLimit     *int64     `json:"run_memory_limit,omitempty"`
-	RunTimeout         *int       `json:"run_timeout,omitempty"`
-	CompileTimeout     *int       `json:"compile_timeout,omitempty"`
-	RunCPUTime         *int       `json:"run_cpu_time,omitempty"`
-	CompileCPUTime     *int       `json:"compile_cpu_time,omitempty"`
+	// Language may be omitted to have it detected from the main file
+	Language string `json:"language,omitempty"`
+	// Version may be omitted, "*" or "latest" for the language's default version
+	Version string     `json:"version,omitempty"`
+	Files   []CodeFile `json:"files" validate:"dive"`
+	// Archive is a base64 tar.gz or zip unpacked into the submission directory, for projects
+	// too large to list file by file
+	Archive            string            `json:"archive,omitempty"`
+	Entrypoint         string            `json:"entrypoint,omitempty"`
+	Args               []string          `json:"args,omitempty"`
+	Stdin              string            `json:"stdin,omitempty"`
+	Env                map[string]string `json:"env,omitempty"`
+	OutputFiles        []string          `json:"output_files,omitempty"`
+	Priority           int               `json:"priority,omitempty"`
+	CallbackURL        string            `json:"callback_url,omitempty"`
+	CompileMemoryLimit *int64            `json:"compile_memory_limit,omitempty"`
+	RunMemoryLimit     *int64            `json:"run_memory_limit,omitempty"`
+	RunTimeout         *int              `json:"run_timeout,omitempty"`
+	CompileTimeout     *int              `json:"compile_timeout,omitempty"`
+	RunCPUTime         *int              `json:"run_cpu_time,omitempty"`
+	CompileCPUTime     *int              `json:"compile_cpu_time,omitempty"`
+	DiskQuota          *int64            `json:"disk_quota,omitempty"`
+	EnableNetwork      *bool             `json:"enable_network,omitempty"`
+	// MaxOutput lowers the runtime's output_max_size for this request
+	MaxOutput *int `json:"max_output,omitempty"`
+	// OutputLimitAction overrides the server's output_limit_action, "kill" or "truncate"
+	OutputLimitAction string `json:"output_limit_action,omitempty"`
+	// Mounts adds scratch directories and read-only datasets to the sandbox
+	Mounts []Mount `json:"mounts,omitempty"`
+	// Omit lists result fields to strip from the response, e.g. "output" or "compile.stdout"
+	Omit []string `json:"omit,omitempty"`
+	// CompileFlags and RunFlags are passed to the package's compile and run scripts in
+	// CODERUNR_COMPILE_FLAGS and CODERUNR_RUN_FLAGS, if build_flag_allowlist permits them
+	CompileFlags []string `json:"compile_flags,omitempty"`
+	RunFlags     []string `json:"run_flags,omitempty"`
+	// Locale sets LANG and LC_ALL, and Timezone sets TZ, in the sandbox
+	Locale   string `json:"locale,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	// Metadata is opaque to the server, such as an assignment ID; it is echoed in the result
+	// and recorded in the history
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Profile names a server-configured set of limits that replaces the runtime's own
+	Profile string `json:"profile,omitempty"`
+	// StdinURL and StdinFixture stream stdin from a URL on an allowed host or from a fixture
+	// uploaded through the admin API, instead of embedding it in stdin
+	StdinURL     string `json:"stdin_url,omitempty"`
+	StdinFixture string `json:"stdin_fixture,omitempty"`
+	// ExpectedOutput has the server judge the run's stdout, compared as CompareMode says:
+	// "exact" (the default), "trimmed", "token" or "float" with tolerance Epsilon
+	ExpectedOutput *string  `json:"expected_output,omitempty"`
+	CompareMode    string   `json:"compare_mode,omitempty"`
+	Epsilon        *float64 `json:"epsilon,omitempty"`
+	// PTY attaches the run stage to a pseudo-terminal; only WebSocket clients can request it
+	PTY *PTYSize `json:"-"`
+}
+
+// Fixture is a stdin file uploaded through the admin API, named by requests' stdin_fixture
+type Fixture struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// Mount is an extra directory in the sandbox
+type Mount struct {
+	// Path is where the directory appears in the sandbox, e.g. "/scratch"
+	Path string `json:"path"`
+	// Type is "scratch" for an empty writable directory or "dataset" for a read-only one
+	Type string `json:"type"`
+	// Dataset names a directory under the server's dataset_directory, for dataset mounts
+	Dataset string `json:"dataset,omitempty"`
+	// Size caps a scratch mount in bytes; 0 uses scratch_max_size
+	Size int64 `json:"size,omitempty"`
+}
+
+// PTYSize is the terminal window size of a pty-mode job
+type PTYSize struct {
+	Rows uint16 `json:"rows"`
+	Cols uint16 `json:"cols"`
+}
+
+// AsyncJobStatus represents the lifecycle state of an asynchronous job
+type AsyncJobStatus string
+
+const (
+	AsyncJobQueued   AsyncJobStatus = "queued"
+	AsyncJobRunning  AsyncJobStatus = "running"
+	AsyncJobFinished AsyncJobStatus = "finished"
+	AsyncJobExpired  AsyncJobStatus = "expired"
+	// AsyncJobCancelled jobs were cancelled through DELETE /api/v2/jobs/{id}
+	AsyncJobCancelled AsyncJobStatus = "cancelled"
+)
+
+// AsyncJob represents an asynchronously submitted job and its result
+type AsyncJob struct {
+	ID         string           `json:"id"`
+	Status     AsyncJobStatus   `json:"status"`
+	Language   string           `json:"language"`
+	Version    string           `json:"version"`
+	CreatedAt  time.Time        `json:"created_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time       `json:"expires_at,omitempty"`
+	Result     *ExecutionResult `json:"result,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	// Submitter is the name of the API key that submitted the job; other keys cannot see it
+	Submitter string `json:"submitter,omitempty"`
 }
 
 // IsolateBox represents an isolate sandbox
@@ -129,6 +345,34 @@ type Package struct {
 	Checksum string          `json:"checksum"`
 }
 
+// PackageRequest names a package to install or uninstall; Version may be a semver constraint
+type PackageRequest struct {
+	Language string `json:"language"`
+	Version  string `json:"version"`
+}
+
+// Bulk package install outcomes reported in BulkPackageResult
+const (
+	BulkPackageInstalled        = "installed"
+	BulkPackageAlreadyInstalled = "already_installed"
+	BulkPackageNotFound         = "not_found"
+	BulkPackageFailed           = "failed"
+)
+
+// BulkPackageResult reports the outcome of one entry of a bulk package install. Version is the
+// resolved version, or the requested one when it could not be resolved.
+type BulkPackageResult struct {
+	Language string `json:"language"`
+	Version  string `json:"version"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BulkPackageResponse lists the outcome of each bulk install entry, in request order
+type BulkPackageResponse struct {
+	Results []BulkPackageResult `json:"results"`
+}
+
 // PackageInfo represents package information for API responses
 type PackageInfo struct {
 	Language        string `json:"language"`
@@ -136,15 +380,82 @@ type PackageInfo struct {
 	Installed       bool   `json:"installed"`
 }
 
+// Package install phases reported in InstallProgress
+const (
+	InstallPhaseDownload = "download"
+	InstallPhaseVerify   = "verify"
+	InstallPhaseExtract  = "extract"
+	InstallPhaseFinalize = "finalize"
+)
+
+// InstallProgress reports the current phase of a package install: while downloading, the bytes
+// received so far, and while extracting, the bytes of the archive read so far. Total is the size
+// of the download or archive, 0 when unknown.
+type InstallProgress struct {
+	// Package names the dependency being installed (language-version); empty for the requested package
+	Package    string `json:"package,omitempty"`
+	Phase      string `json:"phase"`
+	Downloaded int64  `json:"downloaded,omitempty"`
+	Extracted  int64  `json:"extracted,omitempty"`
+	Total      int64  `json:"total,omitempty"`
+}
+
 // RuntimeInfo represents runtime information for API responses
 type RuntimeInfo struct {
 	Language string   `json:"language"`
 	Version  string   `json:"version"`
 	Aliases  []string `json:"aliases"`
-	Runtime  string   `json:"runtime,omitempty"`
-	Platform string   `json:"platform,omitempty"`
-	OS       string   `json:"os,omitempty"`
-	Arch     string   `json:"arch,omitempty"`
+	// Extensions claimed for language detection
+	Extensions []string `json:"extensions,omitempty"`
+	Runtime    string   `json:"runtime,omitempty"`
+	Platform   string   `json:"platform,omitempty"`
+	OS         string   `json:"os,omitempty"`
+	Arch       string   `json:"arch,omitempty"`
+	REPL       bool     `json:"repl,omitempty"`
+	LSP        bool     `json:"lsp,omitempty"`
+	Format     bool     `json:"format,omitempty"`
+	Lint       bool     `json:"lint,omitempty"`
+	// Stages names the package-defined pipeline stages in order, if the package declares any
+	Stages []string `json:"stages,omitempty"`
+}
+
+// RuntimeLimits reports the effective limits of a runtime. Times are in milliseconds and sizes
+// in bytes; a memory limit of -1 means unlimited.
+type RuntimeLimits struct {
+	CompileTimeout     int64 `json:"compile_timeout"`
+	RunTimeout         int64 `json:"run_timeout"`
+	CompileCPUTime     int64 `json:"compile_cpu_time"`
+	RunCPUTime         int64 `json:"run_cpu_time"`
+	CompileMemoryLimit int64 `json:"compile_memory_limit"`
+	RunMemoryLimit     int64 `json:"run_memory_limit"`
+	MaxProcessCount    int   `json:"max_process_count"`
+	MaxOpenFiles       int   `json:"max_open_files"`
+	MaxFileSize        int64 `json:"max_file_size"`
+	OutputMaxSize      int   `json:"output_max_size"`
+	DiskQuota          int64 `json:"disk_quota"`
+}
+
+// RuntimeDetail represents a single runtime with its effective limits
+type RuntimeDetail struct {
+	RuntimeInfo
+	Compiled bool          `json:"compiled"`
+	Limits   RuntimeLimits `json:"limits"`
+}
+
+// LanguageVersion is one installed version of a language
+type LanguageVersion struct {
+	Version  string   `json:"version"`
+	Runtime  string   `json:"runtime"`
+	Aliases  []string `json:"aliases"`
+	Compiled bool     `json:"compiled"`
+}
+
+// LanguageRuntimes lists the installed versions of a language, newest first. Aliases is the
+// union of the aliases of every version.
+type LanguageRuntimes struct {
+	Language string            `json:"language"`
+	Aliases  []string          `json:"aliases"`
+	Versions []LanguageVersion `json:"versions"`
 }
 
 // WebSocketMessage represents a WebSocket message
@@ -154,6 +465,8 @@ type WebSocketMessage struct {
 	Data   string `json:"data,omitempty"`
 	Stage  string `json:"stage,omitempty"`
 	Signal string `json:"signal,omitempty"`
+	// EOF on a stdin data message closes the program's stdin after Data is written
+	EOF bool `json:"eof,omitempty"`
 	// Prefer message for error texts to align with piston; keep Error for backward-compat in clients
 	Message  string      `json:"message,omitempty"`
 	Error    string      `json:"error,omitempty"`
@@ -161,17 +474,45 @@ type WebSocketMessage struct {
 	Language string      `json:"language,omitempty"`
 	Version  string      `json:"version,omitempty"`
 	Payload  interface{} `json:"payload,omitempty"`
+	// Binary is set on init_ack when binary frames were negotiated for stdin/stdout/stderr
+	Binary bool `json:"binary,omitempty"`
+	// Terminal size for resize messages in pty mode
+	Rows uint16 `json:"rows,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+	// Set on stage_end when the stage's output was cut at the output limit, and on resume_ack
+	// when some messages the client missed are no longer buffered
+	Truncated bool `json:"truncated,omitempty"`
+	// The final result of a streaming job, as returned by /api/v2/execute
+	Result interface{} `json:"result,omitempty"`
+	// SessionID names the job a message belongs to when a connection runs several at once
+	SessionID string `json:"session_id,omitempty"`
+	// StreamToken, sent on the init_ack of a resumable job, lets a new connection resume it
+	StreamToken string `json:"stream_token,omitempty"`
+	// Seq numbers the messages of a resumable job; a resume message carries the last one received
+	Seq uint64 `json:"seq,omitempty"`
+	// Resource usage so far, on stats messages
+	*StageStats
+}
+
+// StageStats is a sample of a running stage's resource usage
+type StageStats struct {
+	Memory   int64 `json:"memory"`    // bytes currently charged to the sandbox cgroup
+	CPUTime  int64 `json:"cpu_time"`  // milliseconds
+	WallTime int64 `json:"wall_time"` // milliseconds since the stage started
 }
 
 // StreamEvent represents a streaming execution event
 type StreamEvent struct {
-	Type   string
-	Stream string
-	Data   string
-	Stage  string
-	Signal string
-	Code   int
-	Error  error
+	Type      string
+	Stream    string
+	Data      string
+	Stage     string
+	Signal    string
+	Code      int
+	Error     error
+	Stats     *StageStats
+	Truncated bool
+	Result    *ExecutionResult
 }
 
 // ErrorResponse represents an API error response
@@ -0,0 +1,152 @@
+// Package webhook delivers async job results to client callback URLs.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+// Headers set on every delivery
+const (
+	SignatureHeader = "X-CodeRunr-Signature"
+	TimestampHeader = "X-CodeRunr-Timestamp"
+	JobIDHeader     = "X-CodeRunr-Job-Id"
+)
+
+// queueSize bounds the number of deliveries waiting for a worker
+const queueSize = 1024
+
+// delivery is a single webhook POST and its retry state
+type delivery struct {
+	url     string
+	jobID   string
+	body    []byte
+	attempt int
+}
+
+// Dispatcher POSTs payloads to callback URLs from a fixed pool of workers,
+// retrying failed deliveries with exponential backoff
+type Dispatcher struct {
+	secret      []byte
+	maxAttempts int
+	backoff     time.Duration
+	client      *http.Client
+	queue       chan *delivery
+	logger      *logrus.Entry
+}
+
+// NewDispatcher creates a dispatcher and starts its workers
+func NewDispatcher(cfg *config.Config) *Dispatcher {
+	d := &Dispatcher{
+		secret:      []byte(cfg.WebhookSecret),
+		maxAttempts: cfg.WebhookMaxAttempts,
+		backoff:     time.Second,
+		client:      &http.Client{Timeout: cfg.WebhookTimeout},
+		queue:       make(chan *delivery, queueSize),
+		logger:      logrus.WithField("component", "webhook"),
+	}
+
+	if len(d.secret) == 0 {
+		d.logger.Info("webhook_secret is not set, callbacks will be sent unsigned")
+	}
+
+	for i := 0; i < cfg.WebhookWorkers; i++ {
+		go d.worker()
+	}
+
+	return d
+}
+
+// Dispatch queues payload for delivery to url. It never blocks; deliveries are dropped
+// when the queue is full.
+func (d *Dispatcher) Dispatch(url, jobID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	select {
+	case d.queue <- &delivery{url: url, jobID: jobID, body: body}:
+		return nil
+	default:
+		return fmt.Errorf("webhook queue is full")
+	}
+}
+
+// worker sends queued deliveries, scheduling retries for failures
+func (d *Dispatcher) worker() {
+	for del := range d.queue {
+		del.attempt++
+		err := d.send(del)
+		if err == nil {
+			d.logger.WithField("job_id", del.jobID).Debugf("Delivered webhook to %s", del.url)
+			continue
+		}
+
+		logger := d.logger.WithError(err).WithField("job_id", del.jobID)
+		if del.attempt >= d.maxAttempts {
+			logger.Errorf("Giving up on webhook to %s after %d attempts", del.url, del.attempt)
+			continue
+		}
+
+		wait := d.backoff << (del.attempt - 1)
+		logger.Warnf("Webhook to %s failed (attempt %d), retrying in %s", del.url, del.attempt, wait)
+		retry := del
+		time.AfterFunc(wait, func() {
+			select {
+			case d.queue <- retry:
+			default:
+				d.logger.WithField("job_id", retry.jobID).Errorf("Dropping webhook retry to %s, queue is full", retry.url)
+			}
+		})
+	}
+}
+
+// send performs one delivery attempt; any non-2xx response is a failure
+func (d *Dispatcher) send(del *delivery) error {
+	req, err := http.NewRequest(http.MethodPost, del.url, bytes.NewReader(del.body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CodeRunr-Webhook")
+	req.Header.Set(JobIDHeader, del.jobID)
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(d.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, timestamp, del.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed by secret.
+// Receivers recompute it to verify the payload and reject stale timestamps.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
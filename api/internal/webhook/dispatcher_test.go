@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coderunr/api/internal/config"
+)
+
+func TestDispatchSignsAndRetries(t *testing.T) {
+	secret := "s3cr3t"
+	var attempts atomic.Int32
+	delivered := make(chan string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		expected := "sha256=" + Sign([]byte(secret), r.Header.Get(TimestampHeader), body)
+		if got := r.Header.Get(SignatureHeader); got != expected {
+			t.Errorf("Expected signature %s, got %s", expected, got)
+		}
+		if got := r.Header.Get(JobIDHeader); got != "job-1" {
+			t.Errorf("Expected job ID header job-1, got %s", got)
+		}
+		delivered <- string(body)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(&config.Config{
+		WebhookSecret:      secret,
+		WebhookWorkers:     1,
+		WebhookMaxAttempts: 3,
+		WebhookTimeout:     time.Second,
+	})
+	d.backoff = 10 * time.Millisecond
+
+	if err := d.Dispatch(server.URL, "job-1", map[string]string{"id": "job-1"}); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+
+	select {
+	case body := <-delivered:
+		if body != `{"id":"job-1"}` {
+			t.Errorf("Unexpected body %s", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for webhook delivery")
+	}
+
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+}
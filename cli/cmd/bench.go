@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coderunr/cli/pkg/client"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// benchPrograms are the built-in sample programs by file extension. Each prints one line and
+// exits, so the benchmark measures the server rather than the program.
+var benchPrograms = map[string]string{
+	".sh":   "echo ok\n",
+	".c":    "#include <stdio.h>\n\nint main(void) {\n    puts(\"ok\");\n    return 0;\n}\n",
+	".cpp":  "#include <iostream>\n\nint main() {\n    std::cout << \"ok\\n\";\n}\n",
+	".go":   "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"ok\")\n}\n",
+	".java": "public class Main {\n    public static void main(String[] args) {\n        System.out.println(\"ok\");\n    }\n}\n",
+	".js":   "console.log('ok')\n",
+	".ts":   "console.log('ok')\n",
+	".php":  "<?php\necho \"ok\\n\";\n",
+	".py":   "print('ok')\n",
+	".rb":   "puts 'ok'\n",
+	".rs":   "fn main() {\n    println!(\"ok\");\n}\n",
+}
+
+// benchOptions controls the load a benchmark generates
+type benchOptions struct {
+	concurrency int
+	duration    time.Duration
+	// requests stops the benchmark after this many requests (0 runs for the whole duration)
+	requests int
+	timeout  time.Duration
+}
+
+func NewBenchCommand() *cobra.Command {
+	var (
+		language        string
+		languageVersion string
+		file            string
+		options         benchOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Load test the server and report latency, errors and throughput",
+		Long: `Send a sample program from many concurrent clients for a fixed duration, then report
+latency percentiles, the error rate and throughput. Use it to size max_concurrent_jobs: raise the
+concurrency until latency climbs or the server starts answering 503.
+
+Built-in sample programs print one line; use --file to send your own program instead. Requests
+still running when the duration ends are waited for and counted. Ctrl-C stops early and reports
+what was measured so far.
+
+Examples:
+  # 50 clients for a minute
+  coderunr bench --language python --concurrency 50 --duration 60s
+
+  # Exactly 1000 requests of your own program
+  coderunr bench --language go --file main.go --requests 1000
+
+  # Machine-readable report
+  coderunr bench --language python --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.concurrency <= 0 {
+				return fmt.Errorf("--concurrency must be positive")
+			}
+			if options.duration <= 0 && options.requests <= 0 {
+				return fmt.Errorf("--duration or --requests must be positive")
+			}
+
+			var files []client.File
+			if file != "" {
+				var err error
+				if files, err = readFiles([]string{file}); err != nil {
+					return fmt.Errorf("failed to read files: %w", err)
+				}
+			} else {
+				name := snippetFileName(language)
+				program, ok := benchPrograms[filepath.Ext(name)]
+				if !ok {
+					return fmt.Errorf("no sample program for %s, pass one with --file", language)
+				}
+				files = []client.File{{Name: name, Content: program}}
+			}
+
+			request := client.ExecuteRequest{
+				Language: language,
+				Version:  languageVersion,
+				Files:    files,
+			}
+			return runBench(newClient(cmd), request, options, newOutputMode(cmd))
+		},
+	}
+
+	cmd.Flags().StringVar(&language, "language", "", "Language to benchmark")
+	cmd.Flags().StringVarP(&languageVersion, "language-version", "l", "*", "Language version to use")
+	cmd.Flags().StringVar(&file, "file", "", "Program to send instead of the built-in sample")
+	cmd.Flags().IntVarP(&options.concurrency, "concurrency", "c", 10, "Number of concurrent clients")
+	cmd.Flags().DurationVarP(&options.duration, "duration", "d", 30*time.Second, "How long to send requests")
+	cmd.Flags().IntVarP(&options.requests, "requests", "n", 0, "Stop after this many requests (0 runs for --duration)")
+	cmd.Flags().DurationVar(&options.timeout, "timeout", 60*time.Second, "Timeout of each request")
+	cmd.MarkFlagRequired("language")
+
+	return cmd
+}
+
+// benchSample is the outcome of one request
+type benchSample struct {
+	latency time.Duration
+	// wallTime is the run stage's wall time reported by the server
+	wallTime int64
+	// failure classifies a failed request, "" on success
+	failure string
+}
+
+// benchLatency summarizes a distribution in milliseconds
+type benchLatency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// benchReport is the result of a benchmark, printed as JSON with --output json
+type benchReport struct {
+	Language    string  `json:"language"`
+	Concurrency int     `json:"concurrency"`
+	Elapsed     float64 `json:"elapsed_seconds"`
+	Requests    int     `json:"requests"`
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	ErrorRate   float64 `json:"error_rate"`
+	Throughput  float64 `json:"requests_per_second"`
+	// Latency is measured by the client, from sending the request to reading the response
+	Latency benchLatency `json:"latency_ms"`
+	// RunWallTime is the run stage's wall time of successful requests; the difference to
+	// Latency is time spent queued, preparing the sandbox and on the network
+	RunWallTime benchLatency   `json:"run_wall_time_ms"`
+	Errors      map[string]int `json:"errors,omitempty"`
+}
+
+// runBench sends the request from opts.concurrency workers and prints the report
+func runBench(c *client.Client, request client.ExecuteRequest, opts benchOptions, out outputMode) error {
+	// Workers stop taking new requests at the deadline or on Ctrl-C; requests in flight finish
+	stop, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if opts.duration > 0 {
+		var cancelTimeout context.CancelFunc
+		stop, cancelTimeout = context.WithTimeout(stop, opts.duration)
+		defer cancelTimeout()
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-stop.Done():
+		}
+	}()
+
+	showProgress := !out.json() && !out.quiet
+	if showProgress {
+		color.New(color.Bold).Printf("Benchmarking %s with %d clients\n", request.Language, opts.concurrency)
+	}
+
+	var (
+		samples  []benchSample
+		mutex    sync.Mutex
+		sent     atomic.Int64
+		failures atomic.Int64
+		workers  sync.WaitGroup
+	)
+	started := time.Now()
+	for i := 0; i < opts.concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for stop.Err() == nil {
+				if opts.requests > 0 && sent.Add(1) > int64(opts.requests) {
+					return
+				}
+				sample := benchRequest(c, &request, opts.timeout)
+				if sample.failure != "" {
+					failures.Add(1)
+				}
+				mutex.Lock()
+				samples = append(samples, sample)
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(finished)
+	}()
+	if showProgress {
+		ticker := time.NewTicker(time.Second)
+	progress:
+		for {
+			select {
+			case <-finished:
+				break progress
+			case <-ticker.C:
+				mutex.Lock()
+				count := len(samples)
+				mutex.Unlock()
+				elapsed := time.Since(started)
+				fmt.Fprintf(os.Stderr, "\r\033[K%4ds  %d requests  %.1f req/s  %d errors",
+					int(elapsed.Seconds()), count, float64(count)/elapsed.Seconds(), failures.Load())
+			}
+		}
+		ticker.Stop()
+		clearProgress()
+	}
+	<-finished
+
+	report := newBenchReport(request.Language, opts.concurrency, time.Since(started), samples)
+	if out.json() {
+		return printJSON(report)
+	}
+	printBenchReport(report)
+	if report.Succeeded == 0 {
+		return fmt.Errorf("no request succeeded")
+	}
+	return nil
+}
+
+// benchRequest sends one request and classifies its outcome
+func benchRequest(c *client.Client, request *client.ExecuteRequest, timeout time.Duration) benchSample {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	started := time.Now()
+	response, err := c.Execute(ctx, request)
+	sample := benchSample{latency: time.Since(started)}
+
+	var apiErr *client.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		sample.failure = fmt.Sprintf("http_%d", apiErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		sample.failure = "client_timeout"
+	case err != nil:
+		sample.failure = "connection"
+	case response.Compile != nil && response.Compile.Code != nil && *response.Compile.Code != 0:
+		sample.failure = "compile_error"
+	case response.Run == nil:
+		sample.failure = "no_result"
+	default:
+		sample.wallTime = response.Run.WallTime
+		if response.Run.Outcome != "" && response.Run.Outcome != "ok" {
+			sample.failure = response.Run.Outcome
+		} else if response.Run.Signal != "" || (response.Run.Code != nil && *response.Run.Code != 0) {
+			sample.failure = "runtime_error"
+		}
+	}
+	return sample
+}
+
+// newBenchReport aggregates the samples of a benchmark that ran for elapsed
+func newBenchReport(language string, concurrency int, elapsed time.Duration, samples []benchSample) benchReport {
+	report := benchReport{
+		Language:    language,
+		Concurrency: concurrency,
+		Elapsed:     elapsed.Seconds(),
+		Requests:    len(samples),
+		Errors:      map[string]int{},
+	}
+
+	var latencies, wallTimes []float64
+	for _, sample := range samples {
+		latencies = append(latencies, float64(sample.latency.Microseconds())/1000)
+		if sample.failure != "" {
+			report.Failed++
+			report.Errors[sample.failure]++
+			continue
+		}
+		report.Succeeded++
+		wallTimes = append(wallTimes, float64(sample.wallTime))
+	}
+
+	if report.Requests > 0 {
+		report.ErrorRate = float64(report.Failed) / float64(report.Requests)
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	report.Latency = summarize(latencies)
+	report.RunWallTime = summarize(wallTimes)
+	return report
+}
+
+// summarize computes the distribution of values, using nearest-rank percentiles
+func summarize(values []float64) benchLatency {
+	if len(values) == 0 {
+		return benchLatency{}
+	}
+	sorted := slices.Clone(values)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	percentile := func(p float64) float64 {
+		rank := int(p/100*float64(len(sorted))+0.999999) - 1
+		return sorted[max(0, min(rank, len(sorted)-1))]
+	}
+	return benchLatency{
+		Min:  sorted[0],
+		Mean: sum / float64(len(sorted)),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P95:  percentile(95),
+		P99:  percentile(99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// printBenchReport prints the human-readable report
+func printBenchReport(report benchReport) {
+	bold := color.New(color.Bold)
+	red := color.New(color.FgRed)
+
+	fmt.Printf("%-12s %d (%d ok, %d failed) in %.1fs\n", "Requests:", report.Requests,
+		report.Succeeded, report.Failed, report.Elapsed)
+	fmt.Printf("%-12s %.1f req/s\n", "Throughput:", report.Throughput)
+	errorRate := fmt.Sprintf("%.2f%%", report.ErrorRate*100)
+	if report.Failed > 0 {
+		errorRate = red.Sprint(errorRate)
+	}
+	fmt.Printf("%-12s %s\n", "Error rate:", errorRate)
+
+	kinds := make([]string, 0, len(report.Errors))
+	for kind := range report.Errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Printf("  %-16s %d\n", kind, report.Errors[kind])
+	}
+
+	fmt.Println()
+	bold.Printf("%-10s %8s %8s %8s %8s %8s %8s %8s\n", "(ms)", "min", "mean", "p50", "p90", "p95", "p99", "max")
+	for _, row := range []struct {
+		name string
+		l    benchLatency
+	}{{"latency", report.Latency}, {"run wall", report.RunWallTime}} {
+		fmt.Printf("%-10s %8.1f %8.1f %8.1f %8.1f %8.1f %8.1f %8.1f\n", row.name,
+			row.l.Min, row.l.Mean, row.l.P50, row.l.P90, row.l.P95, row.l.P99, row.l.Max)
+	}
+}
@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultProfile is used when no profile is selected
+const defaultProfile = "default"
+
+// CLIConfig is the contents of ~/.coderunr/config.yaml
+type CLIConfig struct {
+	CurrentProfile string              `yaml:"current_profile,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles,omitempty"`
+}
+
+// Profile holds defaults for the global and execute flags
+type Profile struct {
+	URL             string `yaml:"url,omitempty"`
+	APIKey          string `yaml:"api_key,omitempty"`
+	LanguageVersion string `yaml:"language_version,omitempty"`
+	Output          string `yaml:"output,omitempty"`
+}
+
+// profileKey describes a setting that can be stored in a profile
+type profileKey struct {
+	flag     string
+	field    func(*Profile) *string
+	validate func(string) error
+}
+
+// profileKeys maps config keys to the flags they provide defaults for
+var profileKeys = map[string]profileKey{
+	"url":              {flag: "url", field: func(p *Profile) *string { return &p.URL }, validate: validateURL},
+	"api_key":          {flag: "api-key", field: func(p *Profile) *string { return &p.APIKey }},
+	"language_version": {flag: "language-version", field: func(p *Profile) *string { return &p.LanguageVersion }},
+	"output":           {flag: "output", field: func(p *Profile) *string { return &p.Output }, validate: validateOutput},
+}
+
+// configPath returns the config file location, CODERUNR_CONFIG or ~/.coderunr/config.yaml
+func configPath() string {
+	if path := os.Getenv("CODERUNR_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".coderunr", "config.yaml")
+	}
+	return filepath.Join(home, ".coderunr", "config.yaml")
+}
+
+// loadConfig reads the config file; a missing file is an empty config
+func loadConfig(path string) (*CLIConfig, error) {
+	cfg := &CLIConfig{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// save writes the config file, readable only by the user since it may hold API keys
+func (c *CLIConfig) save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// selectedProfile returns the profile named by --profile, CODERUNR_PROFILE or current_profile,
+// and whether it was chosen explicitly
+func selectedProfile(cmd *cobra.Command, cfg *CLIConfig) (string, bool) {
+	if name, _ := cmd.Flags().GetString("profile"); name != "" {
+		return name, true
+	}
+	if name := os.Getenv("CODERUNR_PROFILE"); name != "" {
+		return name, true
+	}
+	if cfg.CurrentProfile != "" {
+		return cfg.CurrentProfile, false
+	}
+	return defaultProfile, false
+}
+
+// ApplyConfig fills flags that were not given on the command line from the selected profile.
+// Explicit flags win, then CODERUNR_API_KEY for the API key, then the profile.
+func ApplyConfig(cmd *cobra.Command, args []string) error {
+	// The config commands read and write the file themselves
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "config" {
+			return nil
+		}
+	}
+
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return err
+	}
+	name, explicit := selectedProfile(cmd, cfg)
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		if explicit {
+			return fmt.Errorf("profile %q not found in %s", name, configPath())
+		}
+		return nil
+	}
+
+	for _, key := range profileKeys {
+		value := *key.field(profile)
+		flag := cmd.Flags().Lookup(key.flag)
+		if value == "" || flag == nil || flag.Changed {
+			continue
+		}
+		if key.flag == "api-key" && os.Getenv("CODERUNR_API_KEY") != "" {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s in profile %q: %w", key.flag, name, err)
+		}
+	}
+	return nil
+}
+
+func NewConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage CLI configuration profiles",
+		Long: `Manage the CLI configuration file (~/.coderunr/config.yaml, or CODERUNR_CONFIG).
+
+A profile stores defaults for --url, --api-key, --language-version and --output, so they do not
+have to be repeated on every invocation. Flags given on the command line always take precedence.
+The profile is picked by --profile, then CODERUNR_PROFILE, then the current profile.
+
+Keys: url, api_key, language_version, output
+
+Examples:
+  # Point the default profile at a remote server
+  coderunr config set url https://runner.example.com
+  coderunr config set api_key sk-123
+
+  # Create a second profile and switch to it
+  coderunr config set url http://localhost:2000 --profile local
+  coderunr config use local`,
+	}
+
+	cmd.AddCommand(
+		newConfigGetCommand(),
+		newConfigSetCommand(),
+		newConfigUnsetCommand(),
+		newConfigListCommand(),
+		newConfigUseCommand(),
+	)
+	return cmd
+}
+
+// lookupProfileKey returns the definition of a config key
+func lookupProfileKey(name string) (profileKey, error) {
+	key, ok := profileKeys[name]
+	if !ok {
+		return profileKey{}, fmt.Errorf("unknown config key %q (valid keys: url, api_key, language_version, output)", name)
+	}
+	return key, nil
+}
+
+func newConfigGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a value from the selected profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := lookupProfileKey(args[0])
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig(configPath())
+			if err != nil {
+				return err
+			}
+			name, _ := selectedProfile(cmd, cfg)
+			if profile, ok := cfg.Profiles[name]; ok {
+				fmt.Println(*key.field(profile))
+			}
+			return nil
+		},
+	}
+}
+
+func newConfigSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a value in the selected profile, creating it if needed",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateProfile(cmd, args[0], args[1])
+		},
+	}
+}
+
+func newConfigUnsetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a value from the selected profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateProfile(cmd, args[0], "")
+		},
+	}
+}
+
+// updateProfile sets key in the selected profile; an empty value removes it
+func updateProfile(cmd *cobra.Command, keyName, value string) error {
+	key, err := lookupProfileKey(keyName)
+	if err != nil {
+		return err
+	}
+	if value != "" && key.validate != nil {
+		if err := key.validate(value); err != nil {
+			return err
+		}
+	}
+
+	path := configPath()
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	name, _ := selectedProfile(cmd, cfg)
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*Profile)
+	}
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		profile = &Profile{}
+		cfg.Profiles[name] = profile
+	}
+	*key.field(profile) = value
+
+	return cfg.save(path)
+}
+
+func newConfigListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Show all profiles and their values",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(configPath())
+			if err != nil {
+				return err
+			}
+			if len(cfg.Profiles) == 0 {
+				fmt.Printf("No profiles configured in %s\n", configPath())
+				return nil
+			}
+
+			current, _ := selectedProfile(cmd, cfg)
+			names := make([]string, 0, len(cfg.Profiles))
+			for name := range cfg.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			keys := make([]string, 0, len(profileKeys))
+			for key := range profileKeys {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			bold := color.New(color.Bold)
+			for _, name := range names {
+				marker := " "
+				if name == current {
+					marker = "*"
+				}
+				bold.Printf("%s %s\n", marker, name)
+
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				for _, key := range keys {
+					value := *profileKeys[key].field(cfg.Profiles[name])
+					if value == "" {
+						continue
+					}
+					if key == "api_key" {
+						value = maskSecret(value)
+					}
+					fmt.Fprintf(w, "    %s\t%s\n", key, value)
+				}
+				w.Flush()
+			}
+			return nil
+		},
+	}
+}
+
+func newConfigUseCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <profile>",
+		Short: "Make a profile the current one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			cfg, err := loadConfig(path)
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("profile %q not found in %s", args[0], path)
+			}
+			cfg.CurrentProfile = args[0]
+			if err := cfg.save(path); err != nil {
+				return err
+			}
+			fmt.Printf("Switched to profile %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+// validateURL accepts http and https server URLs
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q, expected http(s)://host[:port]", value)
+	}
+	return nil
+}
+
+// validateOutput accepts the values of the --output flag
+func validateOutput(value string) error {
+	switch value {
+	case "auto", "json", "plain":
+		return nil
+	}
+	return fmt.Errorf("invalid output %q, expected auto, json or plain", value)
+}
+
+// maskSecret hides all but the first characters of a secret
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", 8)
+}
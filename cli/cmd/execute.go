@@ -1,55 +1,19 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/coderunr/cli/pkg/client"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
-type ExecuteRequest struct {
-	Language           string     `json:"language"`
-	Version            string     `json:"version"`
-	Files              []FileData `json:"files"`
-	Args               []string   `json:"args,omitempty"`
-	Stdin              string     `json:"stdin,omitempty"`
-	CompileTimeout     *int       `json:"compile_timeout,omitempty"`
-	RunTimeout         *int       `json:"run_timeout,omitempty"`
-	CompileMemoryLimit *int64     `json:"compile_memory_limit,omitempty"`
-	RunMemoryLimit     *int64     `json:"run_memory_limit,omitempty"`
-}
-
-type FileData struct {
-	Name     string `json:"name"`
-	Content  string `json:"content"`
-	Encoding string `json:"encoding,omitempty"`
-}
-
-type ExecuteResponse struct {
-	Language string      `json:"language"`
-	Version  string      `json:"version"`
-	Run      StageResult `json:"run"`
-	Compile  StageResult `json:"compile,omitempty"`
-}
-
-type StageResult struct {
-	Stdout   string `json:"stdout"`
-	Stderr   string `json:"stderr"`
-	Code     *int   `json:"code"`
-	Signal   string `json:"signal,omitempty"`
-	Memory   int64  `json:"memory"`
-	CPUTime  int64  `json:"cpu_time"`
-	WallTime int64  `json:"wall_time"`
-}
-
 func NewExecuteCommand() *cobra.Command {
 	var (
 		languageVersion string
@@ -57,13 +21,20 @@ func NewExecuteCommand() *cobra.Command {
 		runTimeout      int
 		compileTimeout  int
 		additionalFiles []string
+		entrypoint      string
+		code            string
 		interactive     bool
+		raw             bool
+		noTTY           bool
 		status          bool
+		watch           bool
+		detect          bool
+		envVars         []string
 		args            []string
 	)
 
 	cmd := &cobra.Command{
-		Use:     "execute <language> <file> [args...]",
+		Use:     "execute [<language>] [<file> | -] [args...]",
 		Aliases: []string{"run", "exec"},
 		Short:   "Execute code file with specified language",
 		Long: `Execute a code file using CodeRunr execution engine.
@@ -75,27 +46,121 @@ Examples:
   # Execute with specific version
   coderunr execute python script.py -l 3.9.4
 
+  # Let the server detect the language from the extension or shebang
+  coderunr execute --detect script.py
+
+  # Pick the language and version from a searchable list (in a terminal)
+  coderunr execute script.py
+
   # Execute with arguments
   coderunr execute go main.go -- arg1 arg2
 
+  # Execute an inline snippet
+  coderunr run python --code 'print(1)'
+
+  # Read the program from stdin
+  echo 'console.log(1)' | coderunr run javascript -
+
   # Execute interactively with WebSocket
   coderunr execute python script.py -t
 
   # Execute with additional files
-  coderunr execute python main.py -f utils.py -f config.json`,
-		Args: cobra.MinimumNArgs(2),
+  coderunr execute python main.py -f utils.py -f config.json
+
+  # Run a file other than the first one
+  coderunr execute python tests.py -f main.py --entrypoint main.py
+
+  # Execute with environment variables
+  coderunr execute python script.py -e DEBUG=1 -e MODE=test
+
+  # Re-run whenever main.go or util.go is saved
+  coderunr execute go main.go -f util.go --watch
+
+  # Print the result as JSON for scripts
+  coderunr execute python script.py --output json`,
+		Args: func(cmd *cobra.Command, cmdArgs []string) error {
+			// The language argument may be left out, to be picked or detected, and an inline
+			// snippet replaces the file argument
+			if cmd.Flags().Changed("code") {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, cmdArgs)
+		},
 		RunE: func(cmd *cobra.Command, cmdArgs []string) error {
-			language := cmdArgs[0]
-			filename := cmdArgs[1]
-			if len(cmdArgs) > 2 {
-				args = cmdArgs[2:]
+			// An empty language has the server detect it
+			language := ""
+			rest := cmdArgs
+			switch {
+			case detect:
+			case len(cmdArgs) == 0 || (len(cmdArgs) == 1 && !cmd.Flags().Changed("code")):
+				// Only the file was given; check it before asking which language it is in
+				if len(cmdArgs) == 1 && cmdArgs[0] != "-" {
+					if _, err := os.Stat(cmdArgs[0]); err != nil {
+						return fmt.Errorf("failed to read file %s: %w", cmdArgs[0], err)
+					}
+				}
+				var err error
+				language, languageVersion, err = pickRuntime(newClient(cmd), languageVersion,
+					cmd.Flags().Changed("language-version"))
+				if err != nil {
+					return err
+				}
+			default:
+				language = cmdArgs[0]
+				rest = cmdArgs[1:]
+			}
+
+			if watch {
+				if cmd.Flags().Changed("code") || rest[0] == "-" {
+					return fmt.Errorf("--watch needs a program file to watch")
+				}
+				if interactive || raw {
+					return fmt.Errorf("--watch cannot be combined with --interactive or --raw")
+				}
+				if newOutputMode(cmd).json() {
+					return fmt.Errorf("--watch does not support --output json")
+				}
+			}
+
+			// Read the main file from --code, stdin ("-") or disk
+			var files []client.File
+			var mainPath string
+			switch {
+			case cmd.Flags().Changed("code"):
+				files = []client.File{newFileData(snippetFileName(language), []byte(code))}
+			case rest[0] == "-":
+				if readStdin {
+					return fmt.Errorf("cannot read both the program and its input from stdin")
+				}
+				content, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read program from stdin: %w", err)
+				}
+				files = []client.File{newFileData(snippetFileName(language), content)}
+				rest = rest[1:]
+			default:
+				mainPath = rest[0]
+				mainFiles, err := readFiles(rest[:1])
+				if err != nil {
+					return fmt.Errorf("failed to read files: %w", err)
+				}
+				files = mainFiles
+				rest = rest[1:]
+			}
+			if len(rest) > 0 {
+				args = rest
 			}
 
-			// Read main file
-			files, err := readFiles(append([]string{filename}, additionalFiles...))
+			extraFiles, err := readFiles(additionalFiles)
 			if err != nil {
 				return fmt.Errorf("failed to read files: %w", err)
 			}
+			files = append(files, extraFiles...)
+
+			env, err := parseEnvVars(envVars)
+			if err != nil {
+				return err
+			}
 
 			// Read stdin if requested
 			var stdin string
@@ -107,14 +172,28 @@ Examples:
 				stdin = string(stdinBytes)
 			}
 
-			url, _ := cmd.Flags().GetString("url")
-			verbose, _ := cmd.Flags().GetBool("verbose")
+			c := newClient(cmd)
+			out := newOutputMode(cmd)
+
+			if entrypoint != "" && !hasFile(files, entrypoint) {
+				return fmt.Errorf("entrypoint %s is not one of the submitted files", entrypoint)
+			}
 
-			if interactive {
-				return executeInteractive(url, language, languageVersion, files, args, status, verbose)
+			if watch {
+				request := newExecuteRequest(language, languageVersion, files, entrypoint, args, env, stdin,
+					runTimeout, compileTimeout)
+				paths := append([]string{mainPath}, additionalFiles...)
+				return watchAndExecute(c, request, paths, out)
 			}
-			return executeNonInteractive(url, language, languageVersion, files, args, stdin,
-				runTimeout, compileTimeout, verbose)
+			if interactive || raw {
+				// Attach a remote pty when running from a terminal, unless raw byte streams were requested
+				// or messages are printed as JSON
+				tty := !raw && !noTTY && !out.json() && isTerminal()
+				return executeInteractive(c, language, languageVersion, files, entrypoint, args, env,
+					raw, tty, status, out)
+			}
+			return executeNonInteractive(c, language, languageVersion, files, entrypoint, args, env, stdin,
+				runTimeout, compileTimeout, out)
 		},
 	}
 
@@ -122,15 +201,22 @@ Examples:
 	cmd.Flags().BoolVarP(&readStdin, "stdin", "i", false, "Read input from stdin")
 	cmd.Flags().IntVarP(&runTimeout, "run-timeout", "r", 3000, "Run timeout in milliseconds")
 	cmd.Flags().IntVarP(&compileTimeout, "compile-timeout", "c", 10000, "Compile timeout in milliseconds")
+	cmd.Flags().StringVar(&code, "code", "", "Program source to run instead of reading a file")
 	cmd.Flags().StringSliceVarP(&additionalFiles, "files", "f", nil, "Additional files to include")
+	cmd.Flags().StringVar(&entrypoint, "entrypoint", "", "Name of the file to run (defaults to <file>)")
 	cmd.Flags().BoolVarP(&interactive, "interactive", "t", false, "Run interactively using WebSocket")
+	cmd.Flags().BoolVar(&raw, "raw", false, "Pass stdin/stdout through as raw bytes over binary frames (implies -t)")
+	cmd.Flags().BoolVar(&noTTY, "no-tty", false, "Do not attach a pseudo-terminal in interactive mode")
 	cmd.Flags().BoolVarP(&status, "status", "s", false, "Show additional status information")
+	cmd.Flags().StringArrayVarP(&envVars, "env", "e", nil, "Environment variable KEY=VALUE (repeatable)")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Re-run whenever the program or --files change")
+	cmd.Flags().BoolVar(&detect, "detect", false, "Omit <language> and let the server detect it from the file")
 
 	return cmd
 }
 
-func readFiles(filenames []string) ([]FileData, error) {
-	var files []FileData
+func readFiles(filenames []string) ([]client.File, error) {
+	var files []client.File
 
 	for _, filename := range filenames {
 		content, err := os.ReadFile(filename)
@@ -138,20 +224,77 @@ func readFiles(filenames []string) ([]FileData, error) {
 			return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
 		}
 
-		// Detect encoding - simple check for binary content
-		encoding := "utf8"
-		if !isUTF8(content) {
-			encoding = "base64"
+		files = append(files, newFileData(filepath.Base(filename), content))
+	}
+
+	return files, nil
+}
+
+// newFileData wraps file content for a request, detecting binary content
+func newFileData(name string, content []byte) client.File {
+	// Detect encoding - simple check for binary content
+	encoding := "utf8"
+	if !isUTF8(content) {
+		encoding = "base64"
+	}
+
+	return client.File{
+		Name:     name,
+		Content:  string(content),
+		Encoding: encoding,
+	}
+}
+
+// snippetExtensions maps languages and common aliases to source file extensions
+var snippetExtensions = map[string]string{
+	"bash": ".sh", "sh": ".sh",
+	"c": ".c", "c++": ".cpp", "cpp": ".cpp", "csharp": ".cs", "cs": ".cs",
+	"dart": ".dart", "elixir": ".exs", "go": ".go", "golang": ".go",
+	"haskell": ".hs", "java": ".java", "javascript": ".js", "js": ".js", "node": ".js",
+	"kotlin": ".kt", "lua": ".lua", "perl": ".pl", "php": ".php",
+	"python": ".py", "python3": ".py", "py": ".py", "ruby": ".rb", "rb": ".rb",
+	"rust": ".rs", "rs": ".rs", "scala": ".scala", "swift": ".swift",
+	"typescript": ".ts", "ts": ".ts", "zig": ".zig",
+}
+
+// snippetFileName picks a file name for code that did not come from a file
+func snippetFileName(language string) string {
+	language = strings.ToLower(language)
+	if language == "java" {
+		// Java requires the file name to match the public class
+		return "Main.java"
+	}
+	if ext, ok := snippetExtensions[language]; ok {
+		return "main" + ext
+	}
+	return "main.code"
+}
+
+// hasFile reports whether a file with the given name is in the list
+func hasFile(files []client.File, name string) bool {
+	for _, file := range files {
+		if file.Name == name {
+			return true
 		}
+	}
+	return false
+}
 
-		files = append(files, FileData{
-			Name:     filepath.Base(filename),
-			Content:  string(content),
-			Encoding: encoding,
-		})
+// parseEnvVars converts repeated KEY=VALUE flags into a map
+func parseEnvVars(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
 	}
 
-	return files, nil
+	env := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --env value %q, expected KEY=VALUE", pair)
+		}
+		env[name] = value
+	}
+	return env, nil
 }
 
 func isUTF8(data []byte) bool {
@@ -164,62 +307,87 @@ func isUTF8(data []byte) bool {
 	return true
 }
 
-func executeNonInteractive(url, language, version string, files []FileData, args []string,
-	stdin string, runTimeout, compileTimeout int, verbose bool) error {
+func executeNonInteractive(c *client.Client, language, version string, files []client.File, entrypoint string,
+	args []string, env map[string]string, stdin string, runTimeout, compileTimeout int, out outputMode) error {
 
-	request := ExecuteRequest{
-		Language: language,
-		Version:  version,
-		Files:    files,
-		Args:     args,
-		Stdin:    stdin,
-	}
+	request := newExecuteRequest(language, version, files, entrypoint, args, env, stdin, runTimeout, compileTimeout)
 
-	if runTimeout != 3000 {
-		request.RunTimeout = &runTimeout
-	}
-	if compileTimeout != 10000 {
-		request.CompileTimeout = &compileTimeout
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 
-	reqBody, err := json.Marshal(request)
+	response, err := c.Execute(ctx, &request)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("execution failed: %w", err)
 	}
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Post(url+"/api/v2/execute", "application/json", bytes.NewReader(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+	switch {
+	case out.json():
+		return printJSON(response)
+	case out.quiet:
+		printProgramOutput(response)
+		return nil
 	}
-	defer resp.Body.Close()
+	return printExecutionResult(response, out.verbose)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("execution failed with status %d: %s", resp.StatusCode, string(body))
+// newExecuteRequest builds a request, leaving timeouts at the flag defaults to the server
+func newExecuteRequest(language, version string, files []client.File, entrypoint string, args []string,
+	env map[string]string, stdin string, runTimeout, compileTimeout int) client.ExecuteRequest {
+
+	request := client.ExecuteRequest{
+		Language:   language,
+		Version:    version,
+		Files:      files,
+		Entrypoint: entrypoint,
+		Args:       args,
+		Stdin:      stdin,
+		Env:        env,
 	}
 
-	var response ExecuteResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	if runTimeout != 3000 {
+		request.RunTimeout = &runTimeout
 	}
-
-	return printExecutionResult(response, verbose)
+	if compileTimeout != 10000 {
+		request.CompileTimeout = &compileTimeout
+	}
+	return request
 }
 
-func printExecutionResult(response ExecuteResponse, verbose bool) error {
+func printExecutionResult(response *client.ExecuteResponse, verbose bool) error {
 	// Print compile stage if present
-	if response.Compile.Stdout != "" || response.Compile.Stderr != "" || response.Compile.Code != nil || response.Compile.Signal != "" || response.Compile.Memory != 0 || response.Compile.CPUTime != 0 || response.Compile.WallTime != 0 {
+	if response.Compile != nil {
 		printStage("Compile", response.Compile, verbose)
 	}
 
-	// Print run stage
-	printStage("Run", response.Run, verbose)
+	// Print the package's pipeline stages in order, or else the run stage
+	for i := range response.Stages {
+		stage := &response.Stages[i]
+		printStage(strings.ToUpper(stage.Name[:1])+stage.Name[1:], &stage.StageResult, verbose)
+	}
+	if response.Run != nil && len(response.Stages) == 0 {
+		printStage("Run", response.Run, verbose)
+	}
 
+	if verbose && response.Cached {
+		color.New(color.Faint).Println("(result reused from an identical earlier submission)")
+	}
 	return nil
 }
 
-func printStage(stageName string, result StageResult, verbose bool) {
+// printProgramOutput prints only what the program wrote, with stderr going to stderr. Compiler
+// output is shown when compilation failed.
+func printProgramOutput(response *client.ExecuteResponse) {
+	if compile := response.Compile; compile != nil && compile.Code != nil && *compile.Code != 0 {
+		fmt.Print(compile.Stdout)
+		os.Stderr.WriteString(compile.Stderr)
+	}
+	if run := response.Run; run != nil {
+		fmt.Print(run.Stdout)
+		os.Stderr.WriteString(run.Stderr)
+	}
+}
+
+func printStage(stageName string, result *client.StageResult, verbose bool) {
 	bold := color.New(color.Bold)
 	green := color.New(color.FgGreen, color.Bold)
 	red := color.New(color.FgRed, color.Bold)
@@ -254,6 +422,11 @@ func printStage(stageName string, result StageResult, verbose bool) {
 		yellow.Printf("%s\n", result.Signal)
 	}
 
+	if result.Outcome != "" && result.Outcome != "ok" {
+		fmt.Print("Outcome: ")
+		red.Printf("%s\n", result.Outcome)
+	}
+
 	if verbose {
 		fmt.Printf("Memory: %d bytes\n", result.Memory)
 		fmt.Printf("CPU Time: %d ms\n", result.CPUTime)
@@ -272,7 +445,14 @@ func indentLines(text string) string {
 }
 
 // executeInteractive is implemented in websocket.go
-func executeInteractive(url, language, version string, files []FileData, args []string,
-	status, verbose bool) error {
-	return executeInteractiveWS(url, language, version, files, args, status, verbose)
+func executeInteractive(c *client.Client, language, version string, files []client.File, entrypoint string,
+	args []string, env map[string]string, raw, tty, status bool, out outputMode) error {
+	return executeInteractiveWS(c, language, version, files, entrypoint, args, env, raw, tty, status, out)
+}
+
+// newClient creates an API client from the global --url and --api-key flags
+func newClient(cmd *cobra.Command) *client.Client {
+	baseURL, _ := cmd.Flags().GetString("url")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	return client.New(baseURL, client.WithAPIKey(apiKey))
 }
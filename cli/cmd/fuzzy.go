@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fuzzyScore matches pattern against text as a case-insensitive subsequence. Matches at the
+// start of text or of a word, runs of consecutive characters and exact matches score higher;
+// ok is false when text does not contain pattern's characters in order.
+func fuzzyScore(pattern, text string) (score int, ok bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	matched, previous := 0, -2
+	for i := 0; i < len(t) && matched < len(p); i++ {
+		if t[i] != p[matched] {
+			continue
+		}
+		score++
+		if i == previous+1 {
+			score += 5
+		}
+		if i == 0 {
+			score += 10
+		} else if !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			score += 3
+		}
+		previous = i
+		matched++
+	}
+	if matched < len(p) {
+		return 0, false
+	}
+	if len(t) == len(p) {
+		score += 20
+	}
+	return score, true
+}
+
+// bestFuzzyScore returns the best score of pattern against any of texts
+func bestFuzzyScore(pattern string, texts ...string) (best int, ok bool) {
+	for _, text := range texts {
+		if score, matched := fuzzyScore(pattern, text); matched && (!ok || score > best) {
+			best, ok = score, true
+		}
+	}
+	return best, ok
+}
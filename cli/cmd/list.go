@@ -1,31 +1,19 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/coderunr/cli/pkg/client"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
-type Runtime struct {
-	Language string   `json:"language"`
-	Version  string   `json:"version"`
-	Aliases  []string `json:"aliases"`
-	Runtime  string   `json:"runtime,omitempty"`
-}
-
-type RuntimesResponse struct {
-	Runtimes []Runtime `json:"runtimes"`
-}
-
 func NewListCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "list",
@@ -41,48 +29,56 @@ Examples:
   coderunr list
 
   # Show verbose output with additional details
-  coderunr list -v`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			url, _ := cmd.Flags().GetString("url")
-			verbose, _ := cmd.Flags().GetBool("verbose")
+  coderunr list -v
 
-			return listRuntimes(url, verbose)
+  # One "language version" pair per line, for scripts
+  coderunr list -q`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listRuntimes(newClient(cmd), newOutputMode(cmd))
 		},
 	}
 
 	return cmd
 }
 
-func listRuntimes(baseURL string, verbose bool) error {
-	client := &http.Client{Timeout: 30 * time.Second}
+func listRuntimes(c *client.Client, out outputMode) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	resp, err := client.Get(baseURL + "/api/v2/runtimes")
+	runtimes, err := c.ListRuntimes(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch runtimes: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var runtimes []Runtime
-	if err := json.NewDecoder(resp.Body).Decode(&runtimes); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	switch {
+	case out.json():
+		if runtimes == nil {
+			runtimes = []client.Runtime{}
+		}
+		return printJSON(runtimes)
+	case out.quiet:
+		sort.Slice(runtimes, func(i, j int) bool {
+			if runtimes[i].Language != runtimes[j].Language {
+				return runtimes[i].Language < runtimes[j].Language
+			}
+			return runtimes[i].Version < runtimes[j].Version
+		})
+		for _, runtime := range runtimes {
+			fmt.Printf("%s %s\n", runtime.Language, runtime.Version)
+		}
+		return nil
 	}
-
-	return printRuntimeList(runtimes, verbose)
+	return printRuntimeList(runtimes, out.verbose)
 }
 
-func printRuntimeList(runtimes []Runtime, verbose bool) error {
+func printRuntimeList(runtimes []client.Runtime, verbose bool) error {
 	if len(runtimes) == 0 {
 		fmt.Println("No runtimes available")
 		return nil
 	}
 
 	// Group runtimes by language
-	runtimesByLang := make(map[string][]Runtime)
+	runtimesByLang := make(map[string][]client.Runtime)
 	for _, runtime := range runtimes {
 		runtimesByLang[runtime.Language] = append(runtimesByLang[runtime.Language], runtime)
 	}
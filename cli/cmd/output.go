@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Values of the --output flag
+const (
+	outputAuto  = "auto"
+	outputJSON  = "json"
+	outputPlain = "plain"
+)
+
+// ApplyOutput checks the --output flag and turns colors off for the json and plain formats.
+// auto keeps colors when stdout is a terminal.
+func ApplyOutput(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("output")
+	if err := validateOutput(format); err != nil {
+		return err
+	}
+	if format != outputAuto {
+		color.NoColor = true
+	}
+	return nil
+}
+
+// outputMode is how a command prints its results
+type outputMode struct {
+	format string
+	// quiet suppresses decorations such as headers, progress and summaries
+	quiet   bool
+	verbose bool
+}
+
+// newOutputMode reads the --output, --quiet and --verbose flags
+func newOutputMode(cmd *cobra.Command) outputMode {
+	format, _ := cmd.Flags().GetString("output")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	return outputMode{format: format, quiet: quiet, verbose: verbose && !quiet}
+}
+
+// json reports whether results are printed as JSON
+func (o outputMode) json() bool {
+	return o.format == outputJSON
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return nil
+}
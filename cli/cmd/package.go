@@ -2,27 +2,21 @@ package cmd
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/coderunr/cli/pkg/client"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
-type Package struct {
-	Language        string `json:"language"`
-	LanguageVersion string `json:"language_version"`
-	Installed       bool   `json:"installed"`
-}
-
 type PackageSpec struct {
 	Name     string `json:"name"`
 	Version  string `json:"version"`
@@ -35,7 +29,7 @@ type PackageAction struct {
 }
 
 type PackageResponse struct {
-	Packages []Package `json:"packages"`
+	Packages []client.Package `json:"packages"`
 }
 
 type PackageActionResponse struct {
@@ -53,11 +47,13 @@ func NewPackageCommand() *cobra.Command {
 
 Available actions:
   list     - List all available packages
+  search   - Find packages by language name or alias
   install  - Install packages
   uninstall - Uninstall packages`,
 	}
 
 	cmd.AddCommand(NewPackageListCommand())
+	cmd.AddCommand(NewPackageSearchCommand())
 	cmd.AddCommand(NewPackageInstallCommand())
 	cmd.AddCommand(NewPackageUninstallCommand())
 	cmd.AddCommand(NewPackageSpecCommand())
@@ -78,21 +74,25 @@ func NewPackageSpecCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			specPath := args[0]
 			baseURL, _ := cmd.Flags().GetString("url")
+			apiKey, _ := cmd.Flags().GetString("api-key")
 			verbose, _ := cmd.Flags().GetBool("verbose")
 
+			c := client.New(baseURL, client.WithAPIKey(apiKey), client.WithHTTPClient(&http.Client{
+				Transport: &http.Transport{
+					DisableKeepAlives: true, // 禁用连接重用，避免EOF问题
+				},
+			}))
+
 			// Wait for API readiness up to 60s
-			client := &http.Client{Timeout: 2 * time.Second}
 			ready := false
 			for i := 0; i < 60; i++ {
-				resp, err := client.Get(baseURL + "/api/v2/runtimes")
-				if err == nil && resp.StatusCode == http.StatusOK {
-					resp.Body.Close()
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				_, err := c.ListRuntimes(ctx)
+				cancel()
+				if err == nil {
 					ready = true
 					break
 				}
-				if resp != nil {
-					resp.Body.Close()
-				}
 				time.Sleep(1 * time.Second)
 			}
 			if !ready {
@@ -128,7 +128,7 @@ func NewPackageSpecCommand() *cobra.Command {
 					continue
 				}
 				lang, ver := parts[0], parts[1]
-				if err := installLanguageVersion(baseURL, lang, ver); err != nil {
+				if err := installLanguageVersion(c, lang, ver); err != nil {
 					failures++
 					fmt.Fprintf(os.Stderr, "Failed to install %s %s: %v\n", lang, ver, err)
 				} else if verbose {
@@ -147,37 +147,12 @@ func NewPackageSpecCommand() *cobra.Command {
 	return c
 }
 
-func installLanguageVersion(baseURL, language, version string) error {
-	client := &http.Client{
-		Timeout: 9 * time.Minute, // 略小于服务端HTTP路由超时
-		Transport: &http.Transport{
-			DisableKeepAlives: true, // 禁用连接重用，避免EOF问题
-		},
-	}
-	reqObj := map[string]string{
-		"language": language,
-		"version":  version,
-	}
-	reqBody, err := json.Marshal(reqObj)
-	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
-	}
-	resp, err := client.Post(baseURL+"/api/v2/packages", "application/json", strings.NewReader(string(reqBody)))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+func installLanguageVersion(c *client.Client, language, version string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 9*time.Minute) // 略小于服务端HTTP路由超时
+	defer cancel()
 
-	// Always read the response body to ensure complete transfer
-	b, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		return fmt.Errorf("failed to read response body: %w", readErr)
-	}
-
-	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated {
-		return nil
-	}
-	return fmt.Errorf("status %d: %s", resp.StatusCode, string(b))
+	_, err := c.InstallPackage(ctx, language, version, nil)
+	return err
 }
 
 func NewPackageListCommand() *cobra.Command {
@@ -203,10 +178,7 @@ Examples:
 				language = args[0]
 			}
 
-			url, _ := cmd.Flags().GetString("url")
-			verbose, _ := cmd.Flags().GetBool("verbose")
-
-			return listPackages(url, language, verbose)
+			return listPackages(newClient(cmd), language, newOutputMode(cmd))
 		},
 	}
 
@@ -215,6 +187,28 @@ Examples:
 	return cmd
 }
 
+func NewPackageSearchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <term>",
+		Short: "Find packages by language name or alias",
+		Long: `Search the package repository for languages whose name or alias fuzzy-matches
+the term, showing which versions are installed and how to install the newest one that is not.
+
+Examples:
+  # Find the JavaScript packages by an alias
+  coderunr package search js
+
+  # Letters in order are enough
+  coderunr package search pyt`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return searchPackages(newClient(cmd), args[0], newOutputMode(cmd))
+		},
+	}
+
+	return cmd
+}
+
 func NewPackageInstallCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "install <language> <packages...>",
@@ -232,10 +226,7 @@ Examples:
 			language := args[0]
 			packageNames := args[1:]
 
-			url, _ := cmd.Flags().GetString("url")
-			verbose, _ := cmd.Flags().GetBool("verbose")
-
-			return packageAction(url, "install", language, packageNames, verbose)
+			return packageAction(newClient(cmd), "install", language, packageNames, newOutputMode(cmd))
 		},
 	}
 
@@ -256,48 +247,233 @@ Examples:
 			language := args[0]
 			packageNames := args[1:]
 
-			url, _ := cmd.Flags().GetString("url")
-			verbose, _ := cmd.Flags().GetBool("verbose")
-
-			return packageAction(url, "uninstall", language, packageNames, verbose)
+			return packageAction(newClient(cmd), "uninstall", language, packageNames, newOutputMode(cmd))
 		},
 	}
 
 	return cmd
 }
 
-func listPackages(baseURL, language string, verbose bool) error {
-	client := &http.Client{Timeout: 3 * time.Minute} // 略大于服务端包列表获取超时
+func listPackages(c *client.Client, language string, out outputMode) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute) // 略大于服务端包列表获取超时
+	defer cancel()
 
-	// Build URL with optional language filter
-	reqURL := baseURL + "/api/v2/packages"
-	if language != "" {
-		params := url.Values{}
-		params.Add("language", language)
-		reqURL += "?" + params.Encode()
+	packages, err := c.ListPackages(ctx, language)
+	if err != nil {
+		return fmt.Errorf("failed to fetch packages: %w", err)
 	}
 
-	resp, err := client.Get(reqURL)
+	switch {
+	case out.json():
+		if packages == nil {
+			packages = []client.Package{}
+		}
+		return printJSON(packages)
+	case out.quiet:
+		for _, pkg := range packages {
+			state := "available"
+			if pkg.Installed {
+				state = "installed"
+			}
+			fmt.Printf("%s %s %s\n", pkg.Language, pkg.LanguageVersion, state)
+		}
+		return nil
+	}
+	return printPackageList(packages, out.verbose)
+}
+
+// packageMatch is a language in the repository matching a search, with its versions newest first
+type packageMatch struct {
+	Language string           `json:"language"`
+	Aliases  []string         `json:"aliases"`
+	Versions []client.Package `json:"versions"`
+	// Install is the command installing the newest version that is not installed yet
+	Install string `json:"install,omitempty"`
+
+	score int
+}
+
+func searchPackages(c *client.Client, term string, out outputMode) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute) // 略大于服务端包列表获取超时
+	defer cancel()
+
+	packages, err := c.ListPackages(ctx, "")
 	if err != nil {
 		return fmt.Errorf("failed to fetch packages: %w", err)
 	}
-	defer resp.Body.Close()
+	// Installed runtimes name the languages a package provides and their aliases; without them
+	// only the common aliases known to the CLI are searched
+	runtimes, _ := c.ListRuntimes(ctx)
+
+	matches := matchPackages(packages, packageAliases(runtimes), term)
+
+	switch {
+	case out.json():
+		if matches == nil {
+			matches = []packageMatch{}
+		}
+		return printJSON(matches)
+	case out.quiet:
+		for _, match := range matches {
+			for _, pkg := range match.Versions {
+				state := "available"
+				if pkg.Installed {
+					state = "installed"
+				}
+				fmt.Printf("%s %s %s\n", pkg.Language, pkg.LanguageVersion, state)
+			}
+		}
+		return nil
+	}
+	return printPackageMatches(matches, term)
+}
+
+// packageAliases collects other names of each package: the languages it provides and their
+// aliases, from the installed runtimes, and the common names sharing its file extension
+func packageAliases(runtimes []client.Runtime) map[string][]string {
+	aliases := make(map[string][]string)
+	add := func(language, alias string) {
+		if alias == language {
+			return
+		}
+		for _, known := range aliases[language] {
+			if known == alias {
+				return
+			}
+		}
+		aliases[language] = append(aliases[language], alias)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+	for _, runtime := range runtimes {
+		language := runtime.Runtime
+		if language == "" {
+			language = runtime.Language
+		}
+		add(language, runtime.Language)
+		for _, alias := range runtime.Aliases {
+			add(language, alias)
+		}
+	}
+	for language, ext := range snippetExtensions {
+		for alias, aliasExt := range snippetExtensions {
+			if ext == aliasExt {
+				add(language, alias)
+			}
+		}
 	}
+	for language := range aliases {
+		sort.Strings(aliases[language])
+	}
+	return aliases
+}
 
-	var packages []Package
-	if err := json.NewDecoder(resp.Body).Decode(&packages); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+// matchPackages groups the packages of the languages whose name or an alias fuzzy-matches term,
+// best matches first
+func matchPackages(packages []client.Package, aliases map[string][]string, term string) []packageMatch {
+	byLanguage := make(map[string][]client.Package)
+	for _, pkg := range packages {
+		byLanguage[pkg.Language] = append(byLanguage[pkg.Language], pkg)
+	}
+
+	var matches []packageMatch
+	for language, versions := range byLanguage {
+		score, ok := bestFuzzyScore(term, append([]string{language}, aliases[language]...)...)
+		if !ok {
+			continue
+		}
+
+		sort.Slice(versions, func(i, j int) bool {
+			return compareVersions(versions[i].LanguageVersion, versions[j].LanguageVersion) > 0
+		})
+		match := packageMatch{Language: language, Aliases: aliases[language], Versions: versions, score: score}
+		if match.Aliases == nil {
+			match.Aliases = []string{}
+		}
+		for _, pkg := range versions {
+			if !pkg.Installed {
+				match.Install = fmt.Sprintf("coderunr package install %s %s==%s", language, language, pkg.LanguageVersion)
+				break
+			}
+		}
+		matches = append(matches, match)
 	}
 
-	return printPackageList(packages, verbose)
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].Language < matches[j].Language
+	})
+	return matches
+}
+
+// compareVersions orders dotted versions numerically where both parts are numbers, so 3.10
+// follows 3.9
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return len(as) - len(bs)
 }
 
-func packageAction(baseURL, action, language string, packages []string, verbose bool) error {
-	client := &http.Client{Timeout: 9 * time.Minute} // 略小于服务端HTTP路由超时
+func printPackageMatches(matches []packageMatch, term string) error {
+	if len(matches) == 0 {
+		fmt.Printf("No packages match %q\n", term)
+		return nil
+	}
+
+	bold := color.New(color.Bold)
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+	cyan := color.New(color.FgCyan)
+
+	for i, match := range matches {
+		if i > 0 {
+			fmt.Println()
+		}
+		bold.Print(match.Language)
+		if len(match.Aliases) > 0 {
+			fmt.Printf(" (%s)", strings.Join(match.Aliases, ", "))
+		}
+		fmt.Println()
+
+		for _, pkg := range match.Versions {
+			if pkg.Installed {
+				green.Printf("  ● %s (installed)\n", pkg.LanguageVersion)
+			} else {
+				red.Printf("  ○ %s (available)\n", pkg.LanguageVersion)
+			}
+		}
+		if match.Install != "" {
+			fmt.Print("  Install: ")
+			cyan.Println(match.Install)
+		}
+	}
+	return nil
+}
+
+// packageResult is the JSON output for one install or uninstall
+type packageResult struct {
+	Action   string `json:"action"`
+	Language string `json:"language"`
+	Version  string `json:"version"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+func packageAction(c *client.Client, action, language string, packages []string, out outputMode) error {
+	results := []packageResult{}
 	for _, pkgSpec := range packages {
 		// 支持简单的 name 或 name==version / name=version 形式
 		name := pkgSpec
@@ -308,64 +484,54 @@ func packageAction(baseURL, action, language string, packages []string, verbose
 			name, version = parts[0], parts[1]
 		}
 
-		// coderunr API 期望 {language, version}
-		reqObj := map[string]string{
-			"language": language,
-			"version":  version,
-		}
-		reqBody, err := json.Marshal(reqObj)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request: %w", err)
-		}
-
-		var resp *http.Response
-		if action == "install" {
-			resp, err = client.Post(baseURL+"/api/v2/packages", "application/json", strings.NewReader(string(reqBody)))
-		} else if action == "uninstall" {
-			req, reqErr := http.NewRequest("DELETE", baseURL+"/api/v2/packages", strings.NewReader(string(reqBody)))
-			if reqErr != nil {
-				return fmt.Errorf("failed to create request: %w", reqErr)
+		ctx, cancel := context.WithTimeout(context.Background(), 9*time.Minute) // 略小于服务端HTTP路由超时
+		var result *client.PackageVersion
+		var err error
+		switch action {
+		case "install":
+			// Draw a download bar while the server reports progress
+			drawn := false
+			var progress func(client.InstallProgress)
+			if !out.quiet && !out.json() {
+				progress = func(p client.InstallProgress) {
+					drawn = true
+					renderProgress(p)
+				}
+			}
+			result, err = c.InstallPackage(ctx, language, version, progress)
+			if drawn {
+				clearProgress()
 			}
-			req.Header.Set("Content-Type", "application/json")
-			resp, err = client.Do(req)
-		} else {
-			return fmt.Errorf("unsupported action: %s", action)
+		case "uninstall":
+			result, err = c.UninstallPackage(ctx, language, version)
+		default:
+			err = fmt.Errorf("unsupported action: %s", action)
 		}
+		cancel()
 		if err != nil {
-			return fmt.Errorf("failed to execute %s: %w", action, err)
-		}
-		// 处理 201/204/200
-		if resp.StatusCode == http.StatusNoContent { // 204
-			fmt.Printf("Successfully %sed %s %s\n", action, language, version)
-			resp.Body.Close()
+			results = append(results, packageResult{Action: action, Language: language, Version: version, Error: err.Error()})
+			if !out.json() {
+				fmt.Printf("Failed to %s %s: %v\n", action, name, err)
+			}
 			continue
 		}
-		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-			body, _ := io.ReadAll(resp.Body)
-			fmt.Printf("Failed to %s %s: %s\n", action, name, string(body))
-			resp.Body.Close()
-			continue
+
+		lang, ver := result.Language, result.Version
+		if lang == "" {
+			lang = language
 		}
-		// 尝试解析 JSON，若为空体或解析失败，仍视为成功
-		var response map[string]string
-		decErr := json.NewDecoder(resp.Body).Decode(&response)
-		resp.Body.Close()
-		if decErr == nil {
-			lang := response["language"]
-			ver := response["version"]
-			if lang == "" {
-				lang = language
-			}
-			if ver == "" {
-				ver = version
-			}
+		if ver == "" {
+			ver = version
+		}
+		results = append(results, packageResult{Action: action, Language: lang, Version: ver, Success: true})
+		if !out.json() && !out.quiet {
 			fmt.Printf("Successfully %sed %s %s\n", action, lang, ver)
-		} else {
-			// 如 201 但无 body
-			fmt.Printf("Successfully %sed %s %s\n", action, language, version)
 		}
 	}
 
+	if out.json() {
+		return printJSON(results)
+	}
 	return nil
 }
 
@@ -382,14 +548,14 @@ func parsePackageSpec(pkg, language string) PackageSpec {
 	}
 }
 
-func printPackageList(packages []Package, verbose bool) error {
+func printPackageList(packages []client.Package, verbose bool) error {
 	if len(packages) == 0 {
 		fmt.Println("No packages found")
 		return nil
 	}
 
 	// Group packages by language
-	packagesByLang := make(map[string][]Package)
+	packagesByLang := make(map[string][]client.Package)
 	for _, pkg := range packages {
 		packagesByLang[pkg.Language] = append(packagesByLang[pkg.Language], pkg)
 	}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/coderunr/cli/pkg/client"
+	"github.com/fatih/color"
+)
+
+// pickerHeight is the most choices the picker shows at once
+const pickerHeight = 10
+
+// errNoSelection is returned when the picker is dismissed without a choice
+var errNoSelection = errors.New("no runtime selected")
+
+// pickerItem is a choice in the picker: a language, or one version of it
+type pickerItem struct {
+	language string
+	version  string
+	aliases  []string
+}
+
+// label is how the item is listed
+func (item pickerItem) label() string {
+	label := item.language
+	if item.version != "" {
+		label += " " + item.version
+	}
+	if len(item.aliases) > 0 {
+		label += " (" + strings.Join(item.aliases, ", ") + ")"
+	}
+	return label
+}
+
+// score matches a query against the item's language, aliases and version
+func (item pickerItem) score(query string) (int, bool) {
+	texts := append([]string{item.language, item.language + " " + item.version}, item.aliases...)
+	return bestFuzzyScore(query, texts...)
+}
+
+// pickRuntime asks the user to choose from the server's runtimes, listing each installed
+// version unless the version is already given. It needs a terminal on stdin and stdout.
+func pickRuntime(c *client.Client, version string, versionGiven bool) (string, string, error) {
+	if !isTerminal() {
+		return "", "", fmt.Errorf("a language is required when not running in a terminal; pass it or use --detect")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	runtimes, err := c.ListRuntimes(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch runtimes: %w", err)
+	}
+	items := pickerItems(runtimes, versionGiven)
+	if len(items) == 0 {
+		return "", "", fmt.Errorf("no runtimes are installed on the server")
+	}
+
+	item, err := runPicker("Language:", items)
+	if err != nil {
+		return "", "", err
+	}
+	if item.version != "" {
+		version = item.version
+	}
+	fmt.Fprintf(os.Stderr, "Using %s %s\n", item.language, version)
+	return item.language, version, nil
+}
+
+// pickerItems lists runtimes ordered by language and version, or only the languages when
+// versions are not picked
+func pickerItems(runtimes []client.Runtime, languagesOnly bool) []pickerItem {
+	sort.Slice(runtimes, func(i, j int) bool {
+		if runtimes[i].Language != runtimes[j].Language {
+			return runtimes[i].Language < runtimes[j].Language
+		}
+		return runtimes[i].Version < runtimes[j].Version
+	})
+
+	var items []pickerItem
+	for _, runtime := range runtimes {
+		if languagesOnly {
+			if n := len(items); n > 0 && items[n-1].language == runtime.Language {
+				continue
+			}
+			items = append(items, pickerItem{language: runtime.Language, aliases: runtime.Aliases})
+			continue
+		}
+		items = append(items, pickerItem{language: runtime.Language, version: runtime.Version, aliases: runtime.Aliases})
+	}
+	return items
+}
+
+// picker is the state of an interactive fuzzy-search list
+type picker struct {
+	prompt  string
+	items   []pickerItem
+	query   []rune
+	matches []pickerItem
+	cursor  int
+}
+
+// runPicker lets the user narrow items by typing and choose one with the arrow keys and Enter.
+// Esc or Ctrl-C dismisses it. It is drawn on stderr with the terminal in raw mode.
+func runPicker(prompt string, items []pickerItem) (pickerItem, error) {
+	var t terminal
+	if err := t.makeRaw(); err != nil {
+		return pickerItem{}, fmt.Errorf("failed to read from the terminal: %w", err)
+	}
+	defer t.restore()
+
+	p := &picker{prompt: prompt, items: items}
+	p.filter()
+	defer fmt.Fprint(os.Stderr, "\r\033[J")
+
+	buf := make([]byte, 64)
+	for {
+		p.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return pickerItem{}, fmt.Errorf("failed to read from the terminal: %w", err)
+		}
+		if item, done, err := p.handleInput(buf[:n]); done {
+			return item, err
+		}
+	}
+}
+
+// handleInput applies a chunk of keyboard input, reporting when a choice was made or dismissed
+func (p *picker) handleInput(input []byte) (pickerItem, bool, error) {
+	for len(input) > 0 {
+		switch {
+		case input[0] == '\r' || input[0] == '\n':
+			if len(p.matches) == 0 {
+				input = input[1:]
+				continue
+			}
+			return p.matches[p.cursor], true, nil
+		case input[0] == 3: // Ctrl-C
+			return pickerItem{}, true, errNoSelection
+		case strings.HasPrefix(string(input), "\033[A") || strings.HasPrefix(string(input), "\033OA"):
+			p.move(-1)
+			input = input[3:]
+		case strings.HasPrefix(string(input), "\033[B") || strings.HasPrefix(string(input), "\033OB"):
+			p.move(1)
+			input = input[3:]
+		case input[0] == '\033':
+			if len(input) == 1 {
+				return pickerItem{}, true, errNoSelection
+			}
+			// Ignore other escape sequences
+			input = input[len(input):]
+		case input[0] == 16: // Ctrl-P
+			p.move(-1)
+			input = input[1:]
+		case input[0] == 14 || input[0] == '\t': // Ctrl-N
+			p.move(1)
+			input = input[1:]
+		case input[0] == 127 || input[0] == 8: // Backspace
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+			input = input[1:]
+		case input[0] == 21: // Ctrl-U
+			p.query = nil
+			p.filter()
+			input = input[1:]
+		default:
+			r, size := utf8.DecodeRune(input)
+			if unicode.IsPrint(r) {
+				p.query = append(p.query, r)
+				p.filter()
+			}
+			input = input[size:]
+		}
+	}
+	return pickerItem{}, false, nil
+}
+
+// move moves the cursor by delta, wrapping around the matches
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = (p.cursor + delta + len(p.matches)) % len(p.matches)
+}
+
+// filter keeps the items matching the query, best matches first
+func (p *picker) filter() {
+	query := string(p.query)
+	type scored struct {
+		item  pickerItem
+		score int
+	}
+	var matches []scored
+	for _, item := range p.items {
+		if score, ok := item.score(query); ok {
+			matches = append(matches, scored{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	p.matches = p.matches[:0]
+	for _, match := range matches {
+		p.matches = append(p.matches, match.item)
+	}
+	p.cursor = 0
+}
+
+// render draws the prompt and the visible matches below it, leaving the cursor after the query
+func (p *picker) render() {
+	var b strings.Builder
+	b.WriteString("\r\033[J")
+	b.WriteString(color.New(color.Bold).Sprint(p.prompt) + " " + string(p.query))
+
+	// Scroll so the cursor stays in view
+	first := 0
+	if p.cursor >= pickerHeight {
+		first = p.cursor - pickerHeight + 1
+	}
+	lines := 0
+	for i := first; i < len(p.matches) && i < first+pickerHeight; i++ {
+		if i == p.cursor {
+			b.WriteString("\r\n" + color.New(color.FgCyan, color.Bold).Sprint("> "+p.matches[i].label()))
+		} else {
+			b.WriteString("\r\n  " + p.matches[i].label())
+		}
+		lines++
+	}
+	if len(p.matches) == 0 {
+		b.WriteString("\r\n" + color.New(color.Faint).Sprint("  no matching runtimes"))
+		lines++
+	}
+
+	fmt.Fprintf(&b, "\033[%dA\r\033[%dC", lines, utf8.RuneCountInString(p.prompt)+1+len(p.query))
+	fmt.Fprint(os.Stderr, b.String())
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coderunr/cli/pkg/client"
+)
+
+// progressBarWidth is the number of cells in the download and extraction bars
+const progressBarWidth = 30
+
+// renderProgress redraws the current install phase on stderr
+func renderProgress(p client.InstallProgress) {
+	var line string
+	switch p.Phase {
+	case "download":
+		if p.Total > 0 {
+			line = "Downloading " + progressBar(p.Downloaded, p.Total)
+		} else {
+			line = fmt.Sprintf("Downloading %s", formatBytes(p.Downloaded))
+		}
+	case "verify":
+		line = "Verifying checksum..."
+	case "extract":
+		if p.Total > 0 && p.Extracted > 0 {
+			line = "Extracting " + progressBar(p.Extracted, p.Total)
+		} else {
+			line = "Extracting..."
+		}
+	case "finalize":
+		line = "Finalizing..."
+	default:
+		line = p.Phase
+	}
+	if p.Package != "" {
+		line = p.Package + ": " + line
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+}
+
+// progressBar renders done out of total bytes as a bar, a percentage and the byte counts
+func progressBar(done, total int64) string {
+	filled := int(done * progressBarWidth / total)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	return fmt.Sprintf("[%s%s] %3d%% %s / %s",
+		strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled),
+		done*100/total, formatBytes(done), formatBytes(total))
+}
+
+// clearProgress erases the progress line
+func clearProgress() {
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package cmd
+
+import "os"
+
+// notifyResize is a no-op on Windows, which has no SIGWINCH; the initial size is still sent
+func notifyResize(ch chan<- os.Signal) {}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func NewServerCommand() *cobra.Command {
+	var (
+		binary      string
+		dataDir     string
+		bindAddress string
+		isolatePath string
+		envVars     []string
+		docker      bool
+		composeFile string
+		waitTimeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:     "server",
+		Aliases: []string{"up"},
+		Short:   "Start a local CodeRunr API server",
+		Long: `Start a CodeRunr API server for local development.
+
+By default the coderunr-api binary (built with "make build" in api/) is run in the foreground
+with the given data directory. With --docker the full stack, including the isolate environment
+and the local package repository, is started with docker compose instead.
+
+Examples:
+  # Run the API server binary with a local data directory
+  coderunr server --data-dir ./data
+
+  # Use a specific binary and bind address
+  coderunr server --binary ./api/build/coderunr-api --bind 127.0.0.1:2000
+
+  # Start the docker compose stack and wait until the API is healthy
+  coderunr up --docker`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url, _ := cmd.Flags().GetString("url")
+			verbose, _ := cmd.Flags().GetBool("verbose")
+
+			if docker {
+				return startDockerStack(url, composeFile, waitTimeout, verbose)
+			}
+
+			env, err := parseEnvVars(envVars)
+			if err != nil {
+				return err
+			}
+			return runLocalServer(url, binary, dataDir, bindAddress, isolatePath, env, waitTimeout)
+		},
+	}
+
+	cmd.Flags().StringVar(&binary, "binary", "coderunr-api", "Path or name of the API server binary")
+	cmd.Flags().StringVarP(&dataDir, "data-dir", "d", defaultDataDir(), "Data directory for packages and job results")
+	cmd.Flags().StringVarP(&bindAddress, "bind", "b", "0.0.0.0:2000", "Address the API server listens on")
+	cmd.Flags().StringVar(&isolatePath, "isolate-path", "", "Path to the isolate binary (server default if empty)")
+	cmd.Flags().StringArrayVarP(&envVars, "env", "e", nil, "Extra server setting KEY=VALUE, e.g. CODERUNR_LOG_LEVEL=debug (repeatable)")
+	cmd.Flags().BoolVar(&docker, "docker", false, "Start the docker compose stack instead of a local binary")
+	cmd.Flags().StringVar(&composeFile, "compose-file", "docker-compose.yml", "Compose file used with --docker")
+	cmd.Flags().DurationVar(&waitTimeout, "wait", 60*time.Second, "How long to wait for the API to become healthy")
+
+	return cmd
+}
+
+// defaultDataDir returns ~/.coderunr/data, falling back to a relative directory
+func defaultDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".coderunr", "data")
+	}
+	return filepath.Join(home, ".coderunr", "data")
+}
+
+// runLocalServer runs the API server binary in the foreground, forwarding signals to it
+func runLocalServer(url, binary, dataDir, bindAddress, isolatePath string, env map[string]string,
+	waitTimeout time.Duration) error {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return fmt.Errorf("API server binary %q not found (build it with \"make build\" in api/ or pass --binary): %w",
+			binary, err)
+	}
+
+	dataDir, err = filepath.Abs(dataDir)
+	if err != nil {
+		return fmt.Errorf("invalid data directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dataDir, "packages"), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	server := exec.Command(path)
+	server.Stdout = os.Stdout
+	server.Stderr = os.Stderr
+	server.Env = append(os.Environ(),
+		"CODERUNR_DATA_DIRECTORY="+dataDir,
+		"CODERUNR_BIND_ADDRESS="+bindAddress,
+	)
+	if isolatePath != "" {
+		server.Env = append(server.Env, "CODERUNR_ISOLATE_PATH="+isolatePath)
+	}
+	for key, value := range env {
+		server.Env = append(server.Env, key+"="+value)
+	}
+
+	fmt.Printf("Starting %s (data directory %s)\n", path, dataDir)
+	if err := server.Start(); err != nil {
+		return fmt.Errorf("failed to start API server: %w", err)
+	}
+
+	// Forward interrupts so the server can shut down gracefully
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			_ = server.Process.Signal(sig)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := waitForHealthy(ctx, url, waitTimeout); err == nil {
+			color.New(color.FgGreen).Printf("CodeRunr API is ready at %s\n", url)
+		} else if ctx.Err() == nil {
+			color.New(color.FgYellow).Printf("API did not become healthy: %v\n", err)
+		}
+	}()
+
+	if err := server.Wait(); err != nil {
+		// Exiting because of a forwarded signal is a normal shutdown
+		if exitErr, ok := err.(*exec.ExitError); ok && !exitErr.Exited() {
+			return nil
+		}
+		return fmt.Errorf("API server exited: %w", err)
+	}
+	return nil
+}
+
+// startDockerStack starts the compose stack in the background and waits for the API
+func startDockerStack(url, composeFile string, waitTimeout time.Duration, verbose bool) error {
+	if _, err := os.Stat(composeFile); err != nil {
+		return fmt.Errorf("compose file %s not found (run from the repository root or pass --compose-file): %w",
+			composeFile, err)
+	}
+
+	compose := exec.Command("docker", "compose", "-f", composeFile, "up", "-d", "--build")
+	if verbose {
+		fmt.Printf("Running: %s\n", strings.Join(compose.Args, " "))
+	}
+	compose.Stdout = os.Stdout
+	compose.Stderr = os.Stderr
+	if err := compose.Run(); err != nil {
+		return fmt.Errorf("docker compose failed: %w", err)
+	}
+
+	fmt.Printf("Waiting for the API at %s...\n", url)
+	if err := waitForHealthy(context.Background(), url, waitTimeout); err != nil {
+		return fmt.Errorf("API did not become healthy: %w", err)
+	}
+
+	color.New(color.FgGreen).Printf("CodeRunr API is ready at %s\n", url)
+	fmt.Printf("Stop the stack with: docker compose -f %s down\n", composeFile)
+	return nil
+}
+
+// waitForHealthy polls the server's /health endpoint until it answers 200 or the timeout passes
+func waitForHealthy(ctx context.Context, url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 2 * time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		resp, err := client.Get(strings.TrimRight(url, "/") + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("health check returned status %d", resp.StatusCode)
+		}
+
+		if time.Now().After(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
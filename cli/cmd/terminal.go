@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// terminal puts the local terminal into raw mode while a remote pty is attached, so keystrokes
+// (including Ctrl-C) go to the program unprocessed and its output is drawn as-is
+type terminal struct {
+	fd    int
+	state *term.State
+}
+
+// isTerminal reports whether both stdin and stdout are attached to a terminal
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// terminalSize returns the size of the local terminal, falling back to 24x80
+func terminalSize() (rows, cols int) {
+	cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// makeRaw switches the terminal to raw mode; calling it again while raw is a no-op
+func (t *terminal) makeRaw() error {
+	if t.state != nil {
+		return nil
+	}
+	t.fd = int(os.Stdin.Fd())
+	state, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return err
+	}
+	t.state = state
+	return nil
+}
+
+// restore returns the terminal to the mode it had before makeRaw
+func (t *terminal) restore() {
+	if t.state == nil {
+		return
+	}
+	_ = term.Restore(t.fd, t.state)
+	t.state = nil
+}
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coderunr/cli/pkg/client"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// TestCase is one entry of a --cases file
+type TestCase struct {
+	Name           string   `json:"name,omitempty"`
+	Stdin          string   `json:"stdin"`
+	ExpectedStdout string   `json:"expected_stdout"`
+	Args           []string `json:"args,omitempty"`
+}
+
+// Verdicts reported for a test case
+const (
+	verdictPass    = "PASS"
+	verdictWrong   = "WA"
+	verdictRuntime = "RE"
+	verdictTime    = "TLE"
+	verdictMemory  = "MLE"
+	verdictCompile = "CE"
+	verdictError   = "ERR"
+)
+
+func NewTestCommand() *cobra.Command {
+	var (
+		languageVersion string
+		casesPath       string
+		runTimeout      int
+		additionalFiles []string
+		entrypoint      string
+		exact           bool
+		failFast        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test <language> <file>",
+		Short: "Run a program against test cases and compare its output",
+		Long: `Run a program once per test case, feeding the case's stdin and comparing stdout
+with the expected output. Prints a verdict per case and exits non-zero if any case fails.
+
+The cases file is a JSON array:
+
+  [
+    {"name": "sample 1", "stdin": "1 2\n", "expected_stdout": "3\n"},
+    {"stdin": "5 7\n", "expected_stdout": "12\n"}
+  ]
+
+Trailing whitespace on each line and trailing blank lines are ignored unless --exact is set.
+
+Examples:
+  # Check a solution against the samples
+  coderunr test cpp solution.cpp --cases samples.json
+
+  # Stop at the first failing case
+  coderunr test python solution.py --cases cases.json --fail-fast`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			language := args[0]
+
+			cases, err := readTestCases(casesPath)
+			if err != nil {
+				return err
+			}
+
+			files, err := readFiles(append([]string{args[1]}, additionalFiles...))
+			if err != nil {
+				return fmt.Errorf("failed to read files: %w", err)
+			}
+			if entrypoint != "" && !hasFile(files, entrypoint) {
+				return fmt.Errorf("entrypoint %s is not one of the submitted files", entrypoint)
+			}
+
+			request := client.ExecuteRequest{
+				Language:   language,
+				Version:    languageVersion,
+				Files:      files,
+				Entrypoint: entrypoint,
+			}
+			if cmd.Flags().Changed("run-timeout") {
+				request.RunTimeout = &runTimeout
+			}
+
+			// Failing cases are not a usage error
+			cmd.SilenceUsage = true
+
+			return runTestCases(newClient(cmd), request, cases, exact, failFast, newOutputMode(cmd))
+		},
+	}
+
+	cmd.Flags().StringVarP(&languageVersion, "language-version", "l", "*", "Language version to use")
+	cmd.Flags().StringVar(&casesPath, "cases", "", "JSON file with the test cases")
+	cmd.Flags().IntVarP(&runTimeout, "run-timeout", "r", 3000, "Run timeout per case in milliseconds")
+	cmd.Flags().StringSliceVarP(&additionalFiles, "files", "f", nil, "Additional files to include")
+	cmd.Flags().StringVar(&entrypoint, "entrypoint", "", "Name of the file to run (defaults to <file>)")
+	cmd.Flags().BoolVar(&exact, "exact", false, "Compare output byte for byte")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop after the first failing case")
+	cmd.MarkFlagRequired("cases")
+
+	return cmd
+}
+
+// readTestCases loads and checks a cases file
+func readTestCases(path string) ([]TestCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cases: %w", err)
+	}
+
+	var cases []TestCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("failed to parse cases %s: %w", path, err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("%s contains no test cases", path)
+	}
+
+	for i := range cases {
+		if cases[i].Name == "" {
+			cases[i].Name = fmt.Sprintf("#%d", i+1)
+		}
+	}
+	return cases, nil
+}
+
+// testResult is the JSON output for one case
+type testResult struct {
+	Name     string `json:"name"`
+	Verdict  string `json:"verdict"`
+	WallTime int64  `json:"wall_time,omitempty"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// testReport is the JSON output of the test command
+type testReport struct {
+	Passed int          `json:"passed"`
+	Total  int          `json:"total"`
+	Cases  []testResult `json:"cases"`
+}
+
+// runTestCases executes the program once per case and prints a verdict for each
+func runTestCases(c *client.Client, request client.ExecuteRequest, cases []TestCase, exact, failFast bool, out outputMode) error {
+	bold := color.New(color.Bold)
+	green := color.New(color.FgGreen, color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+
+	report := testReport{Total: len(cases), Cases: []testResult{}}
+	var failure error
+	for _, tc := range cases {
+		request.Stdin = tc.Stdin
+		request.Args = tc.Args
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		response, err := c.Execute(ctx, &request)
+		cancel()
+
+		if err != nil {
+			report.Cases = append(report.Cases, testResult{Name: tc.Name, Verdict: verdictError, Error: err.Error()})
+			if !out.json() {
+				red.Printf("%-4s", verdictError)
+				fmt.Printf(" %s: %v\n", tc.Name, err)
+			}
+			if failFast {
+				break
+			}
+			continue
+		}
+
+		result := testResult{Name: tc.Name, Verdict: judge(response, tc, exact)}
+		if run := response.Run; run != nil {
+			result.WallTime, result.Stdout, result.Stderr = run.WallTime, run.Stdout, run.Stderr
+		}
+		if result.Verdict == verdictCompile {
+			result.Stdout, result.Stderr = response.Compile.Stdout, response.Compile.Stderr
+		}
+		report.Cases = append(report.Cases, result)
+		if result.Verdict == verdictPass {
+			report.Passed++
+		}
+
+		if !out.json() {
+			if result.Verdict == verdictPass {
+				green.Printf("%-4s", result.Verdict)
+			} else {
+				red.Printf("%-4s", result.Verdict)
+			}
+			fmt.Printf(" %s", tc.Name)
+			if response.Run != nil {
+				fmt.Printf(" (%d ms)", response.Run.WallTime)
+			}
+			fmt.Println()
+
+			switch result.Verdict {
+			case verdictCompile:
+				fmt.Print(indentLines(response.Compile.Stderr + response.Compile.Stdout))
+			case verdictWrong:
+				if !out.quiet {
+					printOutputDiff(tc.ExpectedStdout, response.Run.Stdout)
+				}
+			case verdictPass:
+			default:
+				if out.verbose && response.Run != nil && response.Run.Stderr != "" {
+					fmt.Print(indentLines(response.Run.Stderr))
+				}
+			}
+		}
+
+		if result.Verdict == verdictCompile {
+			// Every case would fail the same way
+			failure = fmt.Errorf("compilation failed")
+			break
+		}
+		if result.Verdict != verdictPass && failFast {
+			break
+		}
+	}
+
+	if failure == nil && report.Passed != report.Total {
+		failure = fmt.Errorf("%d of %d test cases did not pass", report.Total-report.Passed, report.Total)
+	}
+
+	if out.json() {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else if !out.quiet {
+		bold.Printf("\n%d/%d passed\n", report.Passed, report.Total)
+	}
+	return failure
+}
+
+// judge classifies the result of running one case
+func judge(response *client.ExecuteResponse, tc TestCase, exact bool) string {
+	if compile := response.Compile; compile != nil && compile.Code != nil && *compile.Code != 0 {
+		return verdictCompile
+	}
+
+	run := response.Run
+	if run == nil {
+		return verdictError
+	}
+	switch run.Outcome {
+	case "timeout":
+		return verdictTime
+	case "memory_limit":
+		return verdictMemory
+	case "sandbox_error":
+		return verdictError
+	}
+	if run.Signal != "" || (run.Code != nil && *run.Code != 0) {
+		return verdictRuntime
+	}
+
+	if !outputMatches(tc.ExpectedStdout, run.Stdout, exact) {
+		return verdictWrong
+	}
+	return verdictPass
+}
+
+// outputMatches compares program output with the expected output. Unless exact is set,
+// trailing whitespace on each line and trailing blank lines are ignored.
+func outputMatches(expected, actual string, exact bool) bool {
+	if exact {
+		return expected == actual
+	}
+	return strings.Join(normalizedLines(expected), "\n") == strings.Join(normalizedLines(actual), "\n")
+}
+
+// normalizedLines splits output into lines without trailing whitespace or trailing blank lines
+func normalizedLines(s string) []string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// maxDiffLines bounds how many differing lines are shown for a wrong answer
+const maxDiffLines = 10
+
+// printOutputDiff shows the lines where the output differs from the expected output
+func printOutputDiff(expected, actual string) {
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+
+	want, got := normalizedLines(expected), normalizedLines(actual)
+	n := len(want)
+	if len(got) > n {
+		n = len(got)
+	}
+
+	shown := 0
+	for i := 0; i < n && shown < maxDiffLines; i++ {
+		var w, g string
+		hasWant, hasGot := i < len(want), i < len(got)
+		if hasWant {
+			w = want[i]
+		}
+		if hasGot {
+			g = got[i]
+		}
+		if hasWant && hasGot && w == g {
+			continue
+		}
+
+		fmt.Printf("    line %d:\n", i+1)
+		if hasWant {
+			green.Printf("    - %s\n", w)
+		}
+		if hasGot {
+			red.Printf("    + %s\n", g)
+		}
+		shown++
+	}
+	if shown == 0 {
+		// Only whitespace differs, which matters with --exact
+		fmt.Printf("    expected %q\n    got      %q\n", expected, actual)
+	}
+}
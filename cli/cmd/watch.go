@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/coderunr/cli/pkg/client"
+	"github.com/fatih/color"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is how long file events must settle before re-running, so an editor writing a
+// file in several steps triggers a single run
+const watchDelay = 200 * time.Millisecond
+
+// watchAndExecute runs the program, then re-reads the files and runs it again whenever one of
+// them is saved. A run still in progress is cancelled by the next change. Ctrl-C stops watching.
+func watchAndExecute(c *client.Client, request client.ExecuteRequest, paths []string, out outputMode) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Editors often save by renaming a new file over the old one, which drops a watch on the
+	// file itself, so watch the directories and filter by name
+	watched := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		watched[abs] = true
+		if err := watcher.Add(filepath.Dir(abs)); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	bold := color.New(color.Bold)
+	timer := time.NewTimer(0)
+	var run *watchRun
+	defer func() { run.stop() }()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if watched[filepath.Clean(event.Name)] && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				timer.Reset(watchDelay)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Watch error: %v\n", err)
+
+		case <-timer.C:
+			// Stop the previous run before starting the next one
+			run.stop()
+
+			files, err := readFiles(paths)
+			if err != nil {
+				// The file may be mid-save; the next event retries
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				continue
+			}
+			request.Files = files
+
+			if !out.quiet {
+				bold.Printf("== %s: running %s ==\n", time.Now().Format("15:04:05"), filepath.Base(paths[0]))
+			}
+
+			run = startRun(c, request, out)
+
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+// watchRun is a run started by watchAndExecute
+type watchRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startRun executes the program in the background
+func startRun(c *client.Client, request client.ExecuteRequest, out outputMode) *watchRun {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &watchRun{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(run.done)
+		if err := streamRun(ctx, c, request, out); err != nil && ctx.Err() == nil {
+			color.New(color.FgRed).Printf("Error: %v\n", err)
+		}
+		if ctx.Err() == nil && !out.quiet {
+			fmt.Println("Waiting for changes...")
+		}
+	}()
+	return run
+}
+
+// stop cancels the run and waits for it to finish; a nil run is a no-op
+func (r *watchRun) stop() {
+	if r == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+// streamRun executes the program over a stream, printing output as it arrives
+func streamRun(ctx context.Context, c *client.Client, request client.ExecuteRequest, out outputMode) error {
+	stream, err := c.ExecuteStream(ctx, &request, nil)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	bold := color.New(color.Bold)
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+	yellow := color.New(color.FgYellow)
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch msg.Type {
+		case "data":
+			switch msg.Stream {
+			case "stdout":
+				fmt.Print(msg.Data)
+			case "stderr":
+				os.Stderr.WriteString(msg.Data)
+			}
+
+		case "stage_start":
+			if out.verbose {
+				bold.Printf("== %s ==\n", title(msg.Stage))
+			}
+
+		case "stage_end":
+			failed := msg.Code != nil && *msg.Code != 0
+			if !out.quiet && (out.verbose || failed || msg.Signal != "") {
+				bold.Printf("\n== %s Exit ==\n", title(msg.Stage))
+				if msg.Code != nil {
+					fmt.Print("Exit Code: ")
+					if failed {
+						red.Printf("%d\n", *msg.Code)
+					} else {
+						green.Printf("%d\n", *msg.Code)
+					}
+				}
+				if msg.Signal != "" {
+					fmt.Print("Signal: ")
+					yellow.Printf("%s\n", msg.Signal)
+				}
+			}
+
+		case "error":
+			errMsg := msg.Message
+			if errMsg == "" {
+				errMsg = msg.Error
+			}
+			return errors.New(errMsg)
+		}
+	}
+}
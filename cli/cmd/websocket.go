@@ -3,81 +3,61 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/url"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/coderunr/cli/pkg/client"
 	"github.com/fatih/color"
-	"github.com/gorilla/websocket"
 )
 
-type WSExecuteRequest struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload"`
-}
-
-type WSJobPayload struct {
-	Language           string     `json:"language"`
-	Version            string     `json:"version"`
-	Files              []FileData `json:"files"`
-	Args               []string   `json:"args,omitempty"`
-	Stdin              string     `json:"stdin,omitempty"`
-	CompileTimeout     *int       `json:"compile_timeout,omitempty"`
-	RunTimeout         *int       `json:"run_timeout,omitempty"`
-	CompileMemoryLimit *int64     `json:"compile_memory_limit,omitempty"`
-	RunMemoryLimit     *int64     `json:"run_memory_limit,omitempty"`
-}
+func executeInteractiveWS(c *client.Client, language, version string, files []client.File, entrypoint string,
+	args []string, env map[string]string, raw, tty, showStatus bool, out outputMode) error {
 
-type WSMessage struct {
-	Type     string      `json:"type"`
-	Stream   string      `json:"stream,omitempty"`
-	Data     string      `json:"data,omitempty"`
-	Stage    string      `json:"stage,omitempty"`
-	Signal   string      `json:"signal,omitempty"`
-	Error    string      `json:"error,omitempty"`
-	Code     *int        `json:"code,omitempty"`
-	Language string      `json:"language,omitempty"`
-	Version  string      `json:"version,omitempty"`
-	Message  string      `json:"message,omitempty"`
-	Payload  interface{} `json:"payload,omitempty"`
-}
+	// Quiet and JSON output leave only the program output or the messages themselves
+	verbose := out.verbose && !out.json()
+	showStatus = showStatus && !out.quiet && !out.json()
 
-func executeInteractiveWS(baseURL, language, version string, files []FileData, args []string,
-	showStatus, verbose bool) error {
+	// Setup signal handling and context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	// Convert HTTP URL to WebSocket URL
-	wsURL, err := convertToWebSocketURL(baseURL)
-	if err != nil {
-		return fmt.Errorf("failed to convert URL: %w", err)
+	request := &client.ExecuteRequest{
+		Language:   language,
+		Version:    version,
+		Files:      files,
+		Entrypoint: entrypoint,
+		Args:       args,
+		Env:        env,
+	}
+	opts := &client.StreamOptions{Binary: raw}
+	if tty {
+		rows, cols := terminalSize()
+		opts.PTY = &client.PTYSize{Rows: rows, Cols: cols}
 	}
 
-	// Connect to WebSocket
-	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"/api/v2/connect", nil)
-	if err != nil {
+	// Connect to WebSocket and send the init request
+	stream, err := c.ExecuteStream(ctx, request, opts)
+	if errors.Is(err, client.ErrUnauthorized) {
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
-	defer conn.Close()
-
-	if verbose {
-		fmt.Printf("Connected to WebSocket: %s\n", wsURL+"/api/v2/connect")
+	if err != nil {
+		return err
 	}
+	defer stream.Close()
 
-	// Setup signal handling and context
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// A pty carries its output as raw bytes, so it always uses binary frames
+	raw = stream.Binary()
 
-	// Writer mutex to serialize writes
-	var writeMu sync.Mutex
-	writeJSON := func(v interface{}) error {
-		writeMu.Lock()
-		defer writeMu.Unlock()
-		return conn.WriteJSON(v)
+	if verbose {
+		reqJSON, _ := json.Marshal(request)
+		fmt.Printf("Connected to WebSocket: %s\n", c.BaseURL()+"/api/v2/connect")
+		fmt.Printf("Sent init request for %s %s: %s\n", language, version, string(reqJSON))
 	}
 
 	// System signals forwarding
@@ -88,18 +68,28 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 	signal.Notify(signalsCh, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
 	defer signal.Stop(signalsCh)
 
+	// The local terminal is raw only while the remote pty is attached
+	var local terminal
+	defer local.restore()
+	resize := make(chan os.Signal, 1)
+	if tty {
+		notifyResize(resize)
+		defer signal.Stop(resize)
+	}
+
 	// Channel to receive messages
-	messages := make(chan WSMessage, 10)
+	messages := make(chan *client.Message, 10)
 
 	// Start message reader goroutine
 	go func() {
 		defer close(messages)
 		for {
-			var msg WSMessage
-			err := conn.ReadJSON(&msg)
+			msg, err := stream.Recv()
 			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
-					fmt.Printf("WebSocket error: %v\n", err)
+				if errors.Is(err, client.ErrUnauthorized) {
+					fmt.Fprintln(os.Stderr, "WebSocket closed: invalid or missing API key")
+				} else if err != io.EOF && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "WebSocket error: %v\n", err)
 				}
 				// Connection closed normally, exit quietly
 				return
@@ -118,19 +108,16 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 		for {
 			n, err := os.Stdin.Read(buf)
 			if n > 0 {
-				if werr := writeJSON(map[string]interface{}{
-					"type":   "data",
-					"stream": "stdin",
-					"data":   string(buf[:n]),
-				}); werr != nil {
+				if werr := stream.WriteStdin(buf[:n]); werr != nil {
 					// terminate on write failure
 					cancel()
 					return
 				}
 			}
 			if err != nil {
-				if err != io.EOF {
-					// Non-fatal: just stop forwarding
+				// Let programs that read until EOF finish, as they would with piped input
+				if err == io.EOF {
+					stream.CloseStdin()
 				}
 				return
 			}
@@ -147,11 +134,13 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 		for {
 			select {
 			case sig := <-signalsCh:
-				sigName := toSignalName(sig)
-				if werr := writeJSON(map[string]interface{}{
-					"type":   "signal",
-					"signal": sigName,
-				}); werr != nil {
+				if werr := stream.Signal(toSignalName(sig)); werr != nil {
+					cancel()
+					return
+				}
+			case <-resize:
+				rows, cols := terminalSize()
+				if werr := stream.Resize(rows, cols); werr != nil {
 					cancel()
 					return
 				}
@@ -161,29 +150,8 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 		}
 	}()
 
-	// Send init request
-	payload := WSJobPayload{
-		Language: language,
-		Version:  version,
-		Files:    files,
-		Args:     args,
-	}
-
-	request := WSExecuteRequest{
-		Type:    "init",
-		Payload: payload,
-	}
-
-	if err := writeJSON(request); err != nil {
-		return fmt.Errorf("failed to send execute request: %w", err)
-	}
-
-	if verbose {
-		reqJSON, _ := json.Marshal(request)
-		fmt.Printf("Sent init request for %s %s: %s\n", language, version, string(reqJSON))
-	}
-
 	// Process messages
+	jsonLines := json.NewEncoder(os.Stdout)
 	bold := color.New(color.Bold)
 	green := color.New(color.FgGreen)
 	red := color.New(color.FgRed)
@@ -193,10 +161,7 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 		select {
 		case <-interrupt:
 			// Send SIGINT to remote instead of immediate close
-			_ = writeJSON(map[string]interface{}{
-				"type":   "signal",
-				"signal": "SIGINT",
-			})
+			_ = stream.Signal("SIGINT")
 			// Do not return; let server handle termination
 
 		case msg, ok := <-messages:
@@ -208,6 +173,17 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 				return nil
 			}
 
+			// JSON output prints every message on its own line
+			if out.json() {
+				if err := jsonLines.Encode(msg); err != nil {
+					return err
+				}
+				if msg.Type == "error" {
+					return fmt.Errorf("execution error: %s", msg.Message+msg.Error)
+				}
+				continue
+			}
+
 			switch msg.Type {
 			case "data":
 				// Handle data messages with stream and data fields
@@ -215,7 +191,11 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 				case "stdout":
 					fmt.Print(msg.Data)
 				case "stderr":
-					fmt.Print(msg.Data)
+					if raw {
+						os.Stderr.WriteString(msg.Data)
+					} else {
+						fmt.Print(msg.Data)
+					}
 				default:
 					if verbose && msg.Stream != "" {
 						fmt.Printf("Unknown stream: %s\n", msg.Stream)
@@ -246,8 +226,14 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 				if showStatus || verbose {
 					bold.Printf("== %s ==\n", title(msg.Stage))
 				}
+				if tty && (msg.Stage == "run" || msg.Stage == "repl") {
+					if err := local.makeRaw(); err != nil {
+						return fmt.Errorf("failed to put terminal into raw mode: %w", err)
+					}
+				}
 
 			case "stage_end":
+				local.restore()
 				if showStatus || verbose {
 					bold.Printf("\n== %s Exit ==\n", title(msg.Stage))
 					if msg.Code != nil {
@@ -276,11 +262,15 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 				}
 
 			case "init_ack":
+				if raw && !msg.Binary {
+					return fmt.Errorf("server does not support raw mode")
+				}
 				if showStatus || verbose {
 					bold.Printf("== Initialization Acknowledged ==\n")
 				}
 
 			case "error":
+				local.restore()
 				// Prefer unified {message} field
 				errMsg := msg.Message
 				if errMsg == "" {
@@ -289,6 +279,9 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 				red.Printf("Error: %s\n", errMsg)
 				return fmt.Errorf("execution error: %s", errMsg)
 
+			case "stats", "result":
+				// Live gauges and the aggregated result; output was already printed as it arrived
+
 			default:
 				if verbose {
 					fmt.Printf("Unknown message type: %s\n", msg.Type)
@@ -301,24 +294,6 @@ func executeInteractiveWS(baseURL, language, version string, files []FileData, a
 	}
 }
 
-func convertToWebSocketURL(httpURL string) (string, error) {
-	u, err := url.Parse(httpURL)
-	if err != nil {
-		return "", err
-	}
-
-	switch u.Scheme {
-	case "http":
-		u.Scheme = "ws"
-	case "https":
-		u.Scheme = "wss"
-	default:
-		return "", fmt.Errorf("unsupported URL scheme: %s", u.Scheme)
-	}
-
-	return u.String(), nil
-}
-
 func toSignalName(sig os.Signal) string {
 	// Map common signals to names
 	switch s := sig.(type) {
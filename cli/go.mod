@@ -3,12 +3,18 @@ module github.com/coderunr/cli
 go 1.22.3
 
 require (
-	github.com/fatih/color v1.18.0 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/fatih/color v1.18.0
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/spf13/cobra v1.10.1
+	golang.org/x/term v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/spf13/cobra v1.10.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 )
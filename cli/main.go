@@ -26,13 +26,29 @@ func main() {
 	rootCmd.PersistentFlags().StringP("url", "u", "http://localhost:2000", "CodeRunr API URL")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().String("output", "auto", "Output format (auto, json, plain)")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only print program output and results, without headers or progress")
+	rootCmd.PersistentFlags().String("api-key", os.Getenv("CODERUNR_API_KEY"), "API key for authenticated servers (env CODERUNR_API_KEY)")
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to use (env CODERUNR_PROFILE)")
+
+	// Flags not given on the command line default to the selected profile, then the output
+	// format is checked
+	rootCmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		if err := cmd.ApplyConfig(c, args); err != nil {
+			return err
+		}
+		return cmd.ApplyOutput(c, args)
+	}
 
 	// Add subcommands
 	rootCmd.AddCommand(
 		cmd.NewExecuteCommand(),
 		cmd.NewPackageCommand(),
 		cmd.NewListCommand(),
+		cmd.NewTestCommand(),
+		cmd.NewBenchCommand(),
 		cmd.NewVersionCommand(),
+		cmd.NewServerCommand(),
+		cmd.NewConfigCommand(),
 	)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -0,0 +1,173 @@
+// Package client is a Go client for the CodeRunr API. It runs programs over HTTP, streams
+// interactive executions over WebSocket and manages runtime packages.
+//
+//	c := client.New("http://localhost:2000", client.WithAPIKey(key))
+//	result, err := c.Execute(ctx, &client.ExecuteRequest{
+//		Language: "python",
+//		Version:  "3.x",
+//		Files:    []client.File{{Name: "main.py", Content: "print(1)"}},
+//	})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client talks to one CodeRunr server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey sends key as a bearer token on every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for requests. Deadlines are taken from the
+// request context, so the default client has no timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a client for the server at baseURL, for example "http://localhost:2000"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the server URL the client was created with
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// APIError is returned when the server answers with an unexpected status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsStatus reports whether err is an APIError with the given status code
+func IsStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// newRequest builds an API request, encoding body as JSON when it is not nil
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.setAuth(req.Header)
+	return req, nil
+}
+
+// setAuth attaches the API key as a bearer token when one is configured
+func (c *Client) setAuth(header http.Header) {
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// do sends a request and decodes a JSON response into out. A nil out or an empty body is
+// accepted for any 2xx status.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Always read the whole body so the connection can be reused
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp.StatusCode, data)
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// newAPIError builds an APIError, preferring the message field of a JSON error body
+func newAPIError(statusCode int, body []byte) *APIError {
+	var payload struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &payload) == nil && payload.Message != "" {
+		message = payload.Message
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	return &APIError{StatusCode: statusCode, Message: message}
+}
+
+// Execute runs a program and waits for its result
+func (c *Client) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+	var response ExecuteResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v2/execute", req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ListRuntimes returns the runtimes installed on the server
+func (c *Client) ListRuntimes(ctx context.Context) ([]Runtime, error) {
+	var runtimes []Runtime
+	if err := c.do(ctx, http.MethodGet, "/api/v2/runtimes", nil, &runtimes); err != nil {
+		return nil, err
+	}
+	return runtimes, nil
+}
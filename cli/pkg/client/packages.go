@@ -0,0 +1,138 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ListPackages returns the packages in the repository index, optionally filtered by language
+func (c *Client) ListPackages(ctx context.Context, language string) ([]Package, error) {
+	path := "/api/v2/packages"
+	if language != "" {
+		path += "?" + url.Values{"language": {language}}.Encode()
+	}
+
+	var packages []Package
+	if err := c.do(ctx, http.MethodGet, path, nil, &packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// InstallPackage installs a runtime package; version may be a semver constraint such as "3.x"
+// or "*". When progress is not nil, the install is streamed and progress is called for every
+// phase. Servers without the streaming endpoint fall back to a plain install.
+func (c *Client) InstallPackage(ctx context.Context, language, version string, progress func(InstallProgress)) (*PackageVersion, error) {
+	body := PackageVersion{Language: language, Version: version}
+
+	if progress != nil {
+		result, err := c.installStream(ctx, &body, progress)
+		if !errors.Is(err, errStreamUnsupported) {
+			return result, err
+		}
+	}
+
+	result := body
+	if err := c.do(ctx, http.MethodPost, "/api/v2/packages", &body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UninstallPackage removes an installed runtime package
+func (c *Client) UninstallPackage(ctx context.Context, language, version string) (*PackageVersion, error) {
+	body := PackageVersion{Language: language, Version: version}
+
+	result := body
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/packages", &body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// errStreamUnsupported means the server has no streaming install endpoint
+var errStreamUnsupported = errors.New("streaming install is not supported by the server")
+
+// installStream installs a package through POST /api/v2/packages/stream, which reports
+// progress as Server-Sent Events
+func (c *Client) installStream(ctx context.Context, body *PackageVersion, progress func(InstallProgress)) (*PackageVersion, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v2/packages/stream", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, errStreamUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, data)
+	}
+
+	var result *PackageVersion
+	err = readSSE(resp.Body, func(event string, data []byte) error {
+		switch event {
+		case "progress":
+			var p InstallProgress
+			if err := json.Unmarshal(data, &p); err != nil {
+				return err
+			}
+			progress(p)
+		case "installed":
+			result = &PackageVersion{}
+			return json.Unmarshal(data, result)
+		case "error":
+			var e struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(data, &e)
+			return errors.New(e.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, errors.New("install stream ended without a result")
+	}
+	return result, nil
+}
+
+// readSSE calls handle for every event in a Server-Sent Events stream
+func readSSE(r io.Reader, handle func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event != "" || len(data) > 0 {
+				if err := handle(event, []byte(strings.Join(data, "\n"))); err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return scanner.Err()
+}
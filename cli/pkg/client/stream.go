@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeUnauthorized is the close code the server uses when API key authentication fails
+const closeUnauthorized = 4401
+
+// Stream IDs prefixed to binary frames
+const (
+	binaryStdin  byte = 0
+	binaryStdout byte = 1
+	binaryStderr byte = 2
+)
+
+// ErrUnauthorized is returned when the server rejects the API key
+var ErrUnauthorized = errors.New("invalid or missing API key")
+
+// StreamOptions configures an interactive execution
+type StreamOptions struct {
+	// Binary exchanges stdin, stdout and stderr as raw bytes over binary frames
+	Binary bool
+	// PTY runs the program on a pseudo-terminal of this size; it implies Binary
+	PTY *PTYSize
+}
+
+// PTYSize is the size of a pseudo-terminal
+type PTYSize struct {
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+}
+
+// Message is an event received from an interactive execution. Type is one of "runtime",
+// "init_ack", "stage_start", "data", "stage_end" or "error"; output arrives as "data" messages
+// with Stream set to "stdout" or "stderr" and Stage to the stage that wrote it.
+type Message struct {
+	Type     string      `json:"type"`
+	Stream   string      `json:"stream,omitempty"`
+	Data     string      `json:"data,omitempty"`
+	Stage    string      `json:"stage,omitempty"`
+	Signal   string      `json:"signal,omitempty"`
+	EOF      bool        `json:"eof,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Code     *int        `json:"code,omitempty"`
+	Language string      `json:"language,omitempty"`
+	Version  string      `json:"version,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Payload  interface{} `json:"payload,omitempty"`
+	Binary   bool        `json:"binary,omitempty"`
+	// Truncated is set on stage_end when the stage's output was cut at the limit
+	Truncated bool `json:"truncated,omitempty"`
+	// Result is the final result on result messages, the same as Execute returns
+	Result *ExecuteResponse `json:"result,omitempty"`
+	// Resource usage of the running stage, on stats messages
+	*StageStats
+}
+
+// StageStats is a live sample of a running stage's resource usage
+type StageStats struct {
+	Memory   int64 `json:"memory"`    // bytes
+	CPUTime  int64 `json:"cpu_time"`  // milliseconds
+	WallTime int64 `json:"wall_time"` // milliseconds
+}
+
+// streamPayload is the init payload: a job request plus the optional pty size
+type streamPayload struct {
+	*ExecuteRequest
+	PTY *PTYSize `json:"pty,omitempty"`
+}
+
+// streamInit is the first message sent on the connection
+type streamInit struct {
+	Type    string        `json:"type"`
+	Payload streamPayload `json:"payload"`
+	Binary  bool          `json:"binary,omitempty"`
+}
+
+// Stream is a running interactive execution on the /api/v2/connect WebSocket. Recv must be
+// called from a single goroutine; the send methods may be called concurrently with it.
+type Stream struct {
+	conn   *websocket.Conn
+	binary bool
+	// stage is the stage of the last stage_start, for output in binary frames
+	stage   string
+	writeMu sync.Mutex
+	stop    func() bool
+}
+
+// ExecuteStream starts an interactive execution. The connection is closed when ctx is done or
+// Close is called.
+func (c *Client) ExecuteStream(ctx context.Context, req *ExecuteRequest, opts *StreamOptions) (*Stream, error) {
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+
+	wsURL, err := websocketURL(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert URL: %w", err)
+	}
+
+	header := http.Header{}
+	c.setAuth(header)
+	// Offer permessage-deflate; the server falls back to plain frames if it has it disabled
+	dialer := *websocket.DefaultDialer
+	dialer.EnableCompression = true
+	conn, resp, err := dialer.DialContext(ctx, wsURL+"/api/v2/connect", header)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return nil, ErrUnauthorized
+		}
+		return nil, fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+
+	// A pty carries its output as raw bytes, so it always uses binary frames
+	s := &Stream{
+		conn:   conn,
+		binary: opts.Binary || opts.PTY != nil,
+	}
+	s.stop = context.AfterFunc(ctx, func() { conn.Close() })
+
+	init := streamInit{
+		Type:    "init",
+		Payload: streamPayload{ExecuteRequest: req, PTY: opts.PTY},
+		Binary:  s.binary,
+	}
+	if err := s.writeJSON(init); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to send execute request: %w", err)
+	}
+	return s, nil
+}
+
+// Recv returns the next message. It returns io.EOF once the server closes the connection after
+// the job has finished, and ErrUnauthorized when the API key is rejected.
+func (s *Stream) Recv() (*Message, error) {
+	frameType, data, err := s.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsCloseError(err, closeUnauthorized) {
+			return nil, ErrUnauthorized
+		}
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) && !websocket.IsUnexpectedCloseError(err,
+			websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	if frameType == websocket.BinaryMessage {
+		if len(data) == 0 {
+			return nil, fmt.Errorf("empty binary frame")
+		}
+		stream := "stdout"
+		if data[0] == binaryStderr {
+			stream = "stderr"
+		}
+		return &Message{Type: "data", Stream: stream, Stage: s.stage, Data: string(data[1:])}, nil
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Type == "stage_start" {
+		s.stage = msg.Stage
+	}
+	return &msg, nil
+}
+
+// Binary reports whether stdin and output are exchanged as binary frames
+func (s *Stream) Binary() bool {
+	return s.binary
+}
+
+// WriteStdin sends data to the program's standard input
+func (s *Stream) WriteStdin(data []byte) error {
+	if !s.binary {
+		return s.writeJSON(Message{Type: "data", Stream: "stdin", Data: string(data)})
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteMessage(websocket.BinaryMessage, append([]byte{binaryStdin}, data...))
+}
+
+// CloseStdin signals end of input: the program's standard input is closed once the data sent
+// so far has been delivered, so programs that read until EOF can finish
+func (s *Stream) CloseStdin() error {
+	return s.writeJSON(Message{Type: "data", Stream: "stdin", EOF: true})
+}
+
+// Signal sends a signal such as "SIGINT" or "SIGTERM" to the running program
+func (s *Stream) Signal(name string) error {
+	return s.writeJSON(Message{Type: "signal", Signal: name})
+}
+
+// Resize changes the size of the program's pseudo-terminal
+func (s *Stream) Resize(rows, cols int) error {
+	return s.writeJSON(struct {
+		Type string `json:"type"`
+		PTYSize
+	}{Type: "resize", PTYSize: PTYSize{Rows: rows, Cols: cols}})
+}
+
+// Close closes the connection
+func (s *Stream) Close() error {
+	s.stop()
+	return s.conn.Close()
+}
+
+// writeJSON serializes writes to the connection
+func (s *Stream) writeJSON(v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteJSON(v)
+}
+
+// websocketURL converts an http(s) base URL to the matching ws(s) URL
+func websocketURL(httpURL string) (string, error) {
+	u, err := url.Parse(httpURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported URL scheme: %s", u.Scheme)
+	}
+
+	return u.String(), nil
+}
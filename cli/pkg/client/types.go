@@ -0,0 +1,148 @@
+package client
+
+// File is a source file submitted with a request. Encoding is "utf8" (the default), "base64"
+// or "hex".
+type File struct {
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// ExecuteRequest describes a program to run. Limits left nil use the runtime defaults;
+// timeouts and CPU times are in milliseconds, memory and disk limits in bytes.
+type ExecuteRequest struct {
+	// Language may be empty to have the server detect it from the main file
+	Language string `json:"language,omitempty"`
+	Version  string `json:"version"`
+	Files    []File `json:"files,omitempty"`
+	// Archive is a base64 tar.gz or zip unpacked into the submission directory
+	Archive            string            `json:"archive,omitempty"`
+	Entrypoint         string            `json:"entrypoint,omitempty"`
+	Args               []string          `json:"args,omitempty"`
+	Stdin              string            `json:"stdin,omitempty"`
+	Env                map[string]string `json:"env,omitempty"`
+	OutputFiles        []string          `json:"output_files,omitempty"`
+	CompileTimeout     *int              `json:"compile_timeout,omitempty"`
+	RunTimeout         *int              `json:"run_timeout,omitempty"`
+	CompileCPUTime     *int              `json:"compile_cpu_time,omitempty"`
+	RunCPUTime         *int              `json:"run_cpu_time,omitempty"`
+	CompileMemoryLimit *int64            `json:"compile_memory_limit,omitempty"`
+	RunMemoryLimit     *int64            `json:"run_memory_limit,omitempty"`
+	DiskQuota          *int64            `json:"disk_quota,omitempty"`
+	EnableNetwork      *bool             `json:"enable_network,omitempty"`
+	MaxOutput          *int              `json:"max_output,omitempty"`
+	// OutputLimitAction is "kill" or "truncate"; empty uses the server default
+	OutputLimitAction string   `json:"output_limit_action,omitempty"`
+	Omit              []string `json:"omit,omitempty"`
+	Mounts            []Mount  `json:"mounts,omitempty"`
+	// CompileFlags and RunFlags are forwarded to the package scripts if the server allowlists them
+	CompileFlags []string `json:"compile_flags,omitempty"`
+	RunFlags     []string `json:"run_flags,omitempty"`
+	// Locale sets LANG and LC_ALL, and Timezone sets TZ, e.g. "de_DE.UTF-8" and "Europe/Berlin"
+	Locale   string `json:"locale,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	// Metadata is echoed back in the result and recorded in the server's history
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Profile selects a server-configured set of limits, e.g. "contest"
+	Profile string `json:"profile,omitempty"`
+}
+
+// Mount adds a directory to the sandbox: a writable "scratch" tmpfs of Size bytes (0 uses the
+// server maximum) or a read-only "dataset" from the server's dataset directory.
+type Mount struct {
+	Path    string `json:"path"`
+	Type    string `json:"type"`
+	Dataset string `json:"dataset,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+}
+
+// ExecuteResponse is the result of an execution. Compile is nil for interpreted languages.
+type ExecuteResponse struct {
+	Language string       `json:"language"`
+	Version  string       `json:"version"`
+	Run      *StageResult `json:"run"`
+	Compile  *StageResult `json:"compile,omitempty"`
+	Files    []OutputFile `json:"files,omitempty"`
+	// Stages holds the results of a package-defined pipeline in order; Run repeats the last
+	Stages []PipelineStageResult `json:"stages,omitempty"`
+	// Cached is set when the server reused the result of an identical earlier submission
+	Cached   bool              `json:"cached,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// StageResult is the outcome of the compile or run stage
+type StageResult struct {
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Output    string `json:"output"`
+	Code      *int   `json:"code"`
+	Signal    string `json:"signal,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Memory    int64  `json:"memory"`
+	CPUTime   int64  `json:"cpu_time"`
+	WallTime  int64  `json:"wall_time"`
+	DiskUsage int64  `json:"disk_usage"`
+	// Outcome is "ok", "runtime_error", "timeout", "memory_limit", "output_limit",
+	// "disk_limit" or "sandbox_error"
+	Outcome string `json:"outcome,omitempty"`
+	// Truncated is set when output past the output limit was dropped
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// PipelineStageResult is the outcome of one stage of a package-defined pipeline
+type PipelineStageResult struct {
+	Name string `json:"name"`
+	StageResult
+}
+
+// OutputFile is a file collected from the sandbox after the run
+type OutputFile struct {
+	Name     string `json:"name"`
+	Content  string `json:"content,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	// URL is a pre-signed download link when the server uploaded the file to its artifact sink
+	URL  string `json:"url,omitempty"`
+	Size int64  `json:"size"`
+}
+
+// Runtime is an installed language runtime
+type Runtime struct {
+	Language string   `json:"language"`
+	Version  string   `json:"version"`
+	Aliases  []string `json:"aliases"`
+	// Extensions are the file extensions the server detects the language from
+	Extensions []string `json:"extensions,omitempty"`
+	Runtime    string   `json:"runtime,omitempty"`
+	Platform   string   `json:"platform,omitempty"`
+	OS         string   `json:"os,omitempty"`
+	Arch       string   `json:"arch,omitempty"`
+	REPL       bool     `json:"repl,omitempty"`
+	// Stages names the package's pipeline stages, for packages that replace compile and run
+	Stages []string `json:"stages,omitempty"`
+}
+
+// Package is a runtime package in the repository index
+type Package struct {
+	Language        string `json:"language"`
+	LanguageVersion string `json:"language_version"`
+	Installed       bool   `json:"installed"`
+}
+
+// PackageVersion identifies an installed or removed package
+type PackageVersion struct {
+	Language string `json:"language"`
+	Version  string `json:"version"`
+}
+
+// InstallProgress reports a package install phase: "download", "verify", "extract" or
+// "finalize". Downloaded and Total are set during the download, and Extracted and Total (the
+// archive size) during extraction; Total is 0 when unknown. Package names the dependency being
+// installed, and is empty for the requested package.
+type InstallProgress struct {
+	Package    string `json:"package,omitempty"`
+	Phase      string `json:"phase"`
+	Downloaded int64  `json:"downloaded,omitempty"`
+	Extracted  int64  `json:"extracted,omitempty"`
+	Total      int64  `json:"total,omitempty"`
+}
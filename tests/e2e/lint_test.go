@@ -0,0 +1,49 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLintAPI tests the lint endpoint against the Python package's pyflakes script
+func TestLintAPI(t *testing.T) {
+	t.Run("Lint Python Code", func(t *testing.T) {
+		request := ExecutionRequest{
+			Language: "python",
+			Version:  "3.12.0",
+			Files: []File{
+				{Name: "main.py", Content: "import os\nprint(undefined_name)\n"},
+			},
+		}
+
+		reqBody, _ := json.Marshal(request)
+		resp, err := http.Post(APIBaseURL+"/api/v2/lint", "application/json", bytes.NewBuffer(reqBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result struct {
+			Diagnostics []struct {
+				File     string `json:"file"`
+				Line     int    `json:"line"`
+				Severity string `json:"severity"`
+				Message  string `json:"message"`
+			} `json:"diagnostics"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+		require.Len(t, result.Diagnostics, 2)
+		assert.Equal(t, "main.py", result.Diagnostics[0].File)
+		assert.Equal(t, 1, result.Diagnostics[0].Line)
+		assert.Equal(t, "warning", result.Diagnostics[0].Severity)
+		assert.Contains(t, result.Diagnostics[0].Message, "'os' imported but unused")
+		assert.Equal(t, 2, result.Diagnostics[1].Line)
+		assert.Contains(t, result.Diagnostics[1].Message, "undefined name")
+	})
+}
@@ -3,6 +3,7 @@ package e2e
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 	"testing"
 
@@ -12,8 +13,9 @@ import (
 
 // TestRequestBodyLimit verifies that oversized POST/DELETE bodies are rejected with 413
 func TestRequestBodyLimit(t *testing.T) {
-	// Create a string comfortably larger than the 1MB default limit
-	big := bytes.Repeat([]byte("A"), 2*1024*1024) // 2MB
+	// Create strings comfortably larger than the 1MB execute and 8MB packages default limits
+	big := bytes.Repeat([]byte("A"), 2*1024*1024)          // 2MB
+	bigPackages := bytes.Repeat([]byte("A"), 10*1024*1024) // 10MB
 
 	t.Run("Execute Body Too Large", func(t *testing.T) {
 		// Valid request skeleton with a huge file content to exceed limit
@@ -38,7 +40,7 @@ func TestRequestBodyLimit(t *testing.T) {
 		reqObj := map[string]interface{}{
 			"language": "python",
 			"version":  "3.12.0",
-			"pad":      string(big),
+			"pad":      string(bigPackages),
 		}
 
 		body, _ := json.Marshal(reqObj)
@@ -53,7 +55,7 @@ func TestRequestBodyLimit(t *testing.T) {
 		reqObj := map[string]interface{}{
 			"language": "python",
 			"version":  "3.12.0",
-			"pad":      string(big),
+			"pad":      string(bigPackages),
 		}
 
 		body, _ := json.Marshal(reqObj)
@@ -66,4 +68,28 @@ func TestRequestBodyLimit(t *testing.T) {
 
 		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
 	})
+
+	t.Run("Chunked Execute Body Too Large", func(t *testing.T) {
+		reqObj := map[string]interface{}{
+			"language": "python",
+			"version":  "3.12.0",
+			"files": []map[string]string{
+				{"content": string(big)},
+			},
+		}
+
+		body, _ := json.Marshal(reqObj)
+		// Hide the length so the request is sent chunked without a Content-Length
+		req, err := http.NewRequest(http.MethodPost, APIBaseURL+"/api/v2/execute", io.NopCloser(bytes.NewReader(body)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		var errResp map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.Equal(t, "Request body too large", errResp["message"])
+	})
 }
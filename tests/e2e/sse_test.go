@@ -0,0 +1,84 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExecuteStreamSSE tests the Server-Sent Events streaming endpoint
+func TestExecuteStreamSSE(t *testing.T) {
+	request := ExecutionRequest{
+		Language: "python",
+		Version:  "3.12.0",
+		Files: []File{
+			{Content: "print('hello sse')"},
+		},
+	}
+	reqBody, _ := json.Marshal(request)
+
+	t.Run("POST Stream", func(t *testing.T) {
+		resp, err := http.Post(APIBaseURL+"/api/v2/execute/stream", "application/json", bytes.NewBuffer(reqBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"))
+
+		events, stdout := readSSE(t, resp)
+		assert.Contains(t, events, "runtime")
+		assert.Contains(t, events, "stage_start")
+		assert.Contains(t, events, "stage_end")
+		assert.Equal(t, "done", events[len(events)-1])
+		assert.Contains(t, stdout, "hello sse")
+	})
+
+	t.Run("GET Stream", func(t *testing.T) {
+		resp, err := http.Get(APIBaseURL + "/api/v2/execute/stream?request=" + url.QueryEscape(string(reqBody)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		_, stdout := readSSE(t, resp)
+		assert.Contains(t, stdout, "hello sse")
+	})
+
+	t.Run("Invalid Request", func(t *testing.T) {
+		resp, err := http.Get(APIBaseURL + "/api/v2/execute/stream?request=" + url.QueryEscape(`{"language":""}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+// readSSE collects event names and concatenated stdout data from an SSE response
+func readSSE(t *testing.T, resp *http.Response) ([]string, string) {
+	var events []string
+	var stdout strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			events = append(events, strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			var msg WSMessage
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg))
+			if msg.Type == "data" && msg.Stream == "stdout" {
+				stdout.WriteString(msg.Data)
+			}
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	return events, stdout.String()
+}
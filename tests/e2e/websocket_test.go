@@ -1,6 +1,8 @@
 package e2e
 
 import (
+	"encoding/base64"
+	"fmt"
 	"net/url"
 	"testing"
 	"time"
@@ -23,6 +25,18 @@ type WSMessage struct {
 	Language string      `json:"language,omitempty"`
 	Version  string      `json:"version,omitempty"`
 	Payload  interface{} `json:"payload,omitempty"`
+
+	SessionID   string `json:"session_id,omitempty"`
+	StreamToken string `json:"stream_token,omitempty"`
+	Seq         uint64 `json:"seq,omitempty"`
+	Truncated   bool   `json:"truncated,omitempty"`
+
+	Result *struct {
+		Files []struct {
+			Name    string `json:"name"`
+			Content string `json:"content"`
+		} `json:"files"`
+	} `json:"result,omitempty"`
 }
 
 func TestWebSocketAPI(t *testing.T) {
@@ -93,7 +107,7 @@ func TestWebSocketAPI(t *testing.T) {
 				if msg.Type == "stage_start" && msg.Stage == "run" {
 					foundStageStart = true
 				}
-				if msg.Type == "data" && msg.Stream == "stdout" && msg.Data == "Hello WebSocket!" {
+				if msg.Type == "data" && msg.Stream == "stdout" && msg.Data == "Hello WebSocket!\n" {
 					foundOutput = true
 				}
 				if msg.Type == "stage_end" && msg.Stage == "run" && msg.Code != nil && *msg.Code == 0 {
@@ -198,7 +212,7 @@ func TestWebSocketAPI(t *testing.T) {
 				if msg.Type == "stage_start" && msg.Stage == "run" {
 					gotStageStart = true
 				}
-				if msg.Type == "data" && msg.Stream == "stdout" && msg.Data == "Hello TL!" {
+				if msg.Type == "data" && msg.Stream == "stdout" && msg.Data == "Hello TL!\n" {
 					gotOut = true
 				}
 				if msg.Type == "stage_end" && msg.Stage == "run" && msg.Code != nil && *msg.Code == 0 {
@@ -269,6 +283,56 @@ func TestWebSocketAPI(t *testing.T) {
 		}
 	})
 
+	t.Run("WebSocket Output Files", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		defer conn.Close()
+
+		initMsg := WSMessage{
+			Type: "init",
+			Payload: map[string]interface{}{
+				"language":     "python",
+				"version":      "3.12.0",
+				"files":        []map[string]string{{"content": "open('out.txt', 'w').write('saved')"}},
+				"output_files": []string{"*.txt"},
+			},
+		}
+		require.NoError(t, conn.WriteJSON(initMsg))
+
+		var result WSMessage
+		for result.Type != "result" {
+			result = WSMessage{}
+			require.NoError(t, conn.ReadJSON(&result))
+		}
+		require.NotNil(t, result.Result)
+		require.Len(t, result.Result.Files, 1)
+		assert.Equal(t, "out.txt", result.Result.Files[0].Name)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("saved")), result.Result.Files[0].Content)
+	})
+
+	t.Run("WebSocket Invalid Output Files", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		defer conn.Close()
+
+		initMsg := WSMessage{
+			Type: "init",
+			Payload: map[string]interface{}{
+				"language":     "python",
+				"version":      "3.12.0",
+				"files":        []map[string]string{{"content": "print(1)"}},
+				"output_files": []string{"../secret"},
+			},
+		}
+
+		err := conn.WriteJSON(initMsg)
+		require.NoError(t, err)
+
+		var errorMsg WSMessage
+		err = conn.ReadJSON(&errorMsg)
+		require.NoError(t, err)
+		assert.Equal(t, "error", errorMsg.Type)
+		assert.Contains(t, errorMsg.Message, "output_files")
+	})
+
 	t.Run("WebSocket Python Syntax Error", func(t *testing.T) {
 		conn := connectWebSocket(t)
 		defer conn.Close()
@@ -346,6 +410,213 @@ func TestWebSocketAPI(t *testing.T) {
 	})
 }
 
+func TestWebSocketSessions(t *testing.T) {
+	if !checkServicesRunning() {
+		t.Skip("Services not running, skipping WebSocket tests")
+	}
+
+	// Each session echoes one line of stdin, so it runs until the test writes to it
+	echo := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":       "init",
+			"session_id": id,
+			"language":   "python",
+			"version":    "3.12.0",
+			"files":      []map[string]string{{"content": "print(input() + ' from " + id + "')"}},
+		}
+	}
+	stdin := func(id, data string) WSMessage {
+		return WSMessage{Type: "data", SessionID: id, Stream: "stdin", Data: data}
+	}
+
+	t.Run("Concurrent Sessions", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		defer conn.Close()
+
+		require.NoError(t, conn.WriteJSON(echo("a")))
+		require.NoError(t, conn.WriteJSON(echo("b")))
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "init_ack" && msg.SessionID == "a" })
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "init_ack" && msg.SessionID == "b" })
+
+		// Answer b first; each session only sees its own input
+		require.NoError(t, conn.WriteJSON(stdin("b", "two\n")))
+		require.NoError(t, conn.WriteJSON(stdin("a", "one\n")))
+
+		output := map[string]string{}
+		ended := map[string]string{}
+		readUntil(t, conn, func(msg WSMessage) bool {
+			switch msg.Type {
+			case "data":
+				if msg.Stream == "stdout" {
+					output[msg.SessionID] += msg.Data
+				}
+			case "session_end":
+				ended[msg.SessionID] = msg.Message
+			case "error":
+				t.Fatalf("Unexpected error for session %q: %s", msg.SessionID, msg.Message)
+			}
+			return len(ended) == 2
+		})
+
+		assert.Equal(t, map[string]string{"a": "one from a\n", "b": "two from b\n"}, output)
+		assert.Equal(t, map[string]string{"a": "Job Completed", "b": "Job Completed"}, ended)
+	})
+
+	t.Run("Duplicate Session ID", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		defer conn.Close()
+
+		require.NoError(t, conn.WriteJSON(echo("dup")))
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "init_ack" })
+		require.NoError(t, conn.WriteJSON(echo("dup")))
+
+		msg := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "error" })
+		assert.Equal(t, "dup", msg.SessionID)
+		assert.Contains(t, msg.Message, "already running")
+
+		// The first session is unaffected and the connection stays open
+		require.NoError(t, conn.WriteJSON(stdin("dup", "still here\n")))
+		msg = readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "data" && msg.Stream == "stdout" })
+		assert.Equal(t, "still here from dup\n", msg.Data)
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "session_end" && msg.SessionID == "dup" })
+	})
+
+	t.Run("Too Many Sessions", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		defer conn.Close()
+
+		// ws_max_sessions defaults to 8
+		for i := 0; i < 8; i++ {
+			id := fmt.Sprintf("s%d", i)
+			require.NoError(t, conn.WriteJSON(echo(id)))
+			readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "init_ack" && msg.SessionID == id })
+		}
+		require.NoError(t, conn.WriteJSON(echo("s8")))
+
+		msg := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "error" })
+		assert.Equal(t, "s8", msg.SessionID)
+		assert.Contains(t, msg.Message, "Too many sessions")
+
+		// A session that ends frees its slot
+		require.NoError(t, conn.WriteJSON(stdin("s0", "done\n")))
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "session_end" && msg.SessionID == "s0" })
+		require.NoError(t, conn.WriteJSON(echo("s8")))
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "init_ack" && msg.SessionID == "s8" })
+	})
+
+	t.Run("Unknown Session", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		defer conn.Close()
+
+		require.NoError(t, conn.WriteJSON(stdin("missing", "x\n")))
+		msg := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "error" })
+		assert.Equal(t, "missing", msg.SessionID)
+		assert.Contains(t, msg.Message, "is not running")
+	})
+}
+
+func TestWebSocketResume(t *testing.T) {
+	if !checkServicesRunning() {
+		t.Skip("Services not running, skipping WebSocket tests")
+	}
+
+	resumable := func(code string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":      "init",
+			"resumable": true,
+			"language":  "python",
+			"version":   "3.12.0",
+			"files":     []map[string]string{{"content": code}},
+		}
+	}
+
+	t.Run("Resume After Disconnect", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		require.NoError(t, conn.WriteJSON(resumable("print('before', flush=True)\nprint('after ' + input())")))
+		ack := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "init_ack" })
+		require.NotEmpty(t, ack.StreamToken)
+		before := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "data" && msg.Stream == "stdout" })
+		require.NotZero(t, before.Seq)
+		conn.Close()
+
+		// Resume from just before the output, so it is replayed
+		conn = connectWebSocket(t)
+		defer conn.Close()
+		require.NoError(t, conn.WriteJSON(WSMessage{Type: "resume", StreamToken: ack.StreamToken, Seq: before.Seq - 1}))
+		resumed := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "resume_ack" })
+		assert.Equal(t, "python", resumed.Language)
+		assert.False(t, resumed.Truncated)
+		assert.GreaterOrEqual(t, resumed.Seq, before.Seq)
+		replayed := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "data" })
+		assert.Equal(t, before.Seq, replayed.Seq)
+		assert.Equal(t, "before\n", replayed.Data)
+
+		// The job carries on with the new connection
+		require.NoError(t, conn.WriteJSON(WSMessage{Type: "data", Stream: "stdin", Data: "resumed\n"}))
+		after := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "data" && msg.Stream == "stdout" })
+		assert.Equal(t, "after resumed\n", after.Data)
+		assert.Greater(t, after.Seq, resumed.Seq)
+
+		var msg WSMessage
+		err := conn.ReadJSON(&msg)
+		for err == nil {
+			err = conn.ReadJSON(&msg)
+		}
+		assert.True(t, websocket.IsCloseError(err, 4999), "Expected close 4999 once the job ends, got %v", err)
+	})
+
+	t.Run("Job Ended While Detached", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		require.NoError(t, conn.WriteJSON(resumable("import time\ntime.sleep(0.5)\nprint('finished')")))
+		ack := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "init_ack" })
+		conn.Close()
+		time.Sleep(2 * time.Second)
+
+		conn = connectWebSocket(t)
+		defer conn.Close()
+		require.NoError(t, conn.WriteJSON(WSMessage{Type: "resume", StreamToken: ack.StreamToken}))
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "resume_ack" })
+		output := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "data" && msg.Stream == "stdout" })
+		assert.Equal(t, "finished\n", output.Data)
+		readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "stage_end" && msg.Stage == "run" })
+
+		var msg WSMessage
+		err := conn.ReadJSON(&msg)
+		for err == nil {
+			err = conn.ReadJSON(&msg)
+		}
+		assert.True(t, websocket.IsCloseError(err, 4999), "Expected close 4999 after the replay, got %v", err)
+
+		// The token is spent once the ended job was delivered
+		again := connectWebSocket(t)
+		defer again.Close()
+		require.NoError(t, again.WriteJSON(WSMessage{Type: "resume", StreamToken: ack.StreamToken}))
+		msg = readUntil(t, again, func(msg WSMessage) bool { return msg.Type == "error" })
+		assert.Contains(t, msg.Message, "Unknown or expired stream token")
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		conn := connectWebSocket(t)
+		defer conn.Close()
+
+		require.NoError(t, conn.WriteJSON(WSMessage{Type: "resume", StreamToken: "not-a-token"}))
+		msg := readUntil(t, conn, func(msg WSMessage) bool { return msg.Type == "error" })
+		assert.Contains(t, msg.Message, "Unknown or expired stream token")
+	})
+}
+
+// readUntil reads messages until done accepts one, which it returns
+func readUntil(t *testing.T, conn *websocket.Conn, done func(WSMessage) bool) WSMessage {
+	t.Helper()
+	for {
+		var msg WSMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		if done(msg) {
+			return msg
+		}
+	}
+}
+
 // Helper function to connect to WebSocket
 func connectWebSocket(t *testing.T) *websocket.Conn {
 	u := url.URL{Scheme: "ws", Host: "localhost:2000", Path: "/api/v2/connect"}